@@ -0,0 +1,87 @@
+package api
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/deliveroo/jsonrest-go"
+	"github.com/deliveroo/todo-api/domain"
+	"github.com/jackc/pgx/v4"
+)
+
+type accessTokenParams struct {
+	Name    string     `json:"name"`
+	Scopes  []string   `json:"scopes"`
+	Expires *time.Time `json:"expires"`
+}
+
+func (p accessTokenParams) validate() error {
+	if len(p.Name) == 0 {
+		return errors.New("name is required")
+	}
+	if len(p.Scopes) == 0 {
+		return errors.New("at least one scope is required")
+	}
+	for _, scope := range p.Scopes {
+		if !domain.ValidScope(scope) {
+			return fmt.Errorf("unknown scope %q", scope)
+		}
+	}
+	if p.Expires != nil && !p.Expires.After(time.Now()) {
+		return errors.New("expires must be in the future")
+	}
+	return nil
+}
+
+// createAccessToken is POST /account/tokens
+func (s *Server) createAccessToken(ctx context.Context, req *jsonrest.Request) (interface{}, error) {
+	account := req.Get(requestAccountKey{}).(*domain.Account)
+	var params accessTokenParams
+	if err := req.BindBody(&params); err != nil {
+		return nil, err
+	}
+	if err := params.validate(); err != nil {
+		return nil, jsonrest.BadRequest(err.Error())
+	}
+	at := &domain.AccessToken{
+		AccountID: account.ID,
+		Name:      params.Name,
+		Scopes:    params.Scopes,
+		Expires:   params.Expires,
+	}
+	token := at.NewToken()
+	at, err := s.Repo().CreateAccessToken(ctx, at)
+	if err != nil {
+		return nil, err
+	}
+	return s.Protocol().NewAccessToken(at, token), nil
+}
+
+// getAllAccessTokens is GET /account/tokens
+func (s *Server) getAllAccessTokens(ctx context.Context, req *jsonrest.Request) (interface{}, error) {
+	account := req.Get(requestAccountKey{}).(*domain.Account)
+	tokens, err := s.Repo().GetAllAccessTokensByAccountID(ctx, account.ID)
+	if err != nil {
+		return nil, err
+	}
+	return s.Protocol().AccessTokens(tokens), nil
+}
+
+// deleteAccessToken is DELETE /account/tokens/:id
+func (s *Server) deleteAccessToken(ctx context.Context, req *jsonrest.Request) (interface{}, error) {
+	account := req.Get(requestAccountKey{}).(*domain.Account)
+	id, err := paramPublicID(req, "id")
+	if err != nil {
+		return nil, err
+	}
+	err = s.Repo().DeleteAccessTokenByPublicIDAndAccountID(ctx, id, account.ID)
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return nil, jsonrest.NotFound(fmt.Sprintf("access token not found, id=%s", id))
+		}
+		return nil, err
+	}
+	return nil, nil
+}
@@ -3,10 +3,13 @@ package api
 import (
 	"context"
 	"errors"
+	"net/http"
+	"net/mail"
 	"unicode/utf8"
 
 	"github.com/deliveroo/jsonrest-go"
 	"github.com/deliveroo/todo-api/domain"
+	"github.com/deliveroo/todo-api/repo"
 	"github.com/deliveroo/todo-api/service/session"
 )
 
@@ -16,15 +19,97 @@ type accountParams struct {
 }
 
 func (p accountParams) validate() error {
-	if len(p.Username) == 0 {
+	if err := validateUsername(p.Username); err != nil {
+		return err
+	}
+	return validatePassword(p.Password)
+}
+
+type createAccountParams struct {
+	accountParams
+	Email string `json:"email"`
+}
+
+func (p createAccountParams) validate() error {
+	if err := p.accountParams.validate(); err != nil {
+		return err
+	}
+	return validateEmail(p.Email)
+}
+
+type passwordParams struct {
+	CurrentPassword string `json:"current_password"`
+	Password        string `json:"password"`
+}
+
+type usernameParams struct {
+	Username string `json:"username"`
+}
+
+type deleteAccountParams struct {
+	Password string `json:"password"`
+}
+
+func validateUsername(username string) error {
+	if len(username) == 0 {
 		return errors.New("username is required")
 	}
-	if utf8.RuneCountInString(p.Password) < 8 {
+	return nil
+}
+
+// validateEmail checks that an optional email address is a bare address, e.g.
+// "someone@example.com" rather than "Someone <someone@example.com>".
+func validateEmail(email string) error {
+	if email == "" {
+		return nil
+	}
+	addr, err := mail.ParseAddress(email)
+	if err != nil || addr.Address != email {
+		return errors.New("email is not a valid address")
+	}
+	return nil
+}
+
+func validatePassword(password string) error {
+	if utf8.RuneCountInString(password) < 8 {
 		return errors.New("password must be at least 8 characters")
 	}
 	return nil
 }
 
+// usernameTaken is returned when a username is already in use.
+func usernameTaken() error {
+	return jsonrest.Error(http.StatusConflict, "username_taken", repo.ErrUsernameTaken.Error())
+}
+
+// accountSuspended is returned when a suspended account tries to log in or
+// make a request.
+func accountSuspended() error {
+	return jsonrest.Error(http.StatusForbidden, "account_suspended", "account is suspended")
+}
+
+// emailTaken is returned when an email address is already in use.
+func emailTaken() error {
+	return jsonrest.Error(http.StatusConflict, "email_taken", repo.ErrEmailTaken.Error())
+}
+
+// checkEmailAvailable returns emailTaken if an account other than accountID
+// has verified the email address. Unverified addresses aren't reserved, so
+// that nobody can claim someone else's address before they do.
+func (s *Server) checkEmailAvailable(ctx context.Context, accountID int64, email string) error {
+	if email == "" {
+		return nil
+	}
+	owner, err := s.Accounts().GetAccountByEmail(ctx, email)
+	if err != nil {
+		return err
+	}
+	if owner != nil && owner.ID != accountID {
+		return emailTaken()
+	}
+	return nil
+}
+
 // login is POST /account/login
 func (s *Server) login(ctx context.Context, req *jsonrest.Request) (interface{}, error) {
 	var params accountParams
@@ -34,7 +119,10 @@ func (s *Server) login(ctx context.Context, req *jsonrest.Request) (interface{},
 	if err := params.validate(); err != nil {
 		return nil, jsonrest.BadRequest(err.Error())
 	}
-	account, err := s.Repo().GetAccountByUsername(ctx, params.Username)
+	if err := s.checkLoginThrottle(ctx, params.Username); err != nil {
+		return nil, err
+	}
+	account, err := s.Accounts().GetAccountByUsername(ctx, params.Username)
 	if err != nil {
 		return nil, err
 	}
@@ -43,8 +131,35 @@ func (s *Server) login(ctx context.Context, req *jsonrest.Request) (interface{},
 		return nil, err
 	}
 	if !ok {
+		if err := s.failLogin(ctx, req, params.Username, account); err != nil {
+			return nil, err
+		}
 		return nil, jsonrest.BadRequest("incorrect username or password")
 	}
+	// Only reveal that an account is suspended to someone who knows its
+	// password.
+	if account.Suspended() {
+		return nil, accountSuspended()
+	}
+	if account.TwoFactorEnabled() {
+		challenge, err := s.Sessions().NewChallenge(ctx, account.ID)
+		if err != nil {
+			return nil, err
+		}
+		return s.Protocol().AccountLoginChallenge(challenge), nil
+	}
+	if err := s.resetLoginThrottle(ctx, account.Username); err != nil {
+		return nil, err
+	}
+	return s.newSession(ctx, account)
+}
+
+// newSession starts a new login session for an account, unless it's
+// suspended.
+func (s *Server) newSession(ctx context.Context, account *domain.Account) (interface{}, error) {
+	if account.Suspended() {
+		return nil, accountSuspended()
+	}
 	sess := session.Session{
 		AccountID: account.ID,
 	}
@@ -55,6 +170,27 @@ func (s *Server) login(ctx context.Context, req *jsonrest.Request) (interface{},
 	return s.Protocol().AccountLogin(token), nil
 }
 
+// logout is POST /account/logout
+func (s *Server) logout(ctx context.Context, req *jsonrest.Request) (interface{}, error) {
+	if err := s.Sessions().Revoke(ctx, requestToken(req)); err != nil {
+		return nil, err
+	}
+	return nil, nil
+}
+
+// failedLoginLimit is the number of failed logins shown to an account owner.
+const failedLoginLimit = 50
+
+// getFailedLogins is GET /account/failed-logins
+func (s *Server) getFailedLogins(ctx context.Context, req *jsonrest.Request) (interface{}, error) {
+	account := req.Get(requestAccountKey{}).(*domain.Account)
+	logins, err := s.Repo().GetRecentFailedLoginsByAccountID(ctx, account.ID, failedLoginLimit)
+	if err != nil {
+		return nil, err
+	}
+	return s.Protocol().FailedLogins(logins), nil
+}
+
 // getAccount is GET /account
 func (s *Server) getAccount(ctx context.Context, req *jsonrest.Request) (interface{}, error) {
 	account := req.Get(requestAccountKey{}).(*domain.Account)
@@ -63,22 +199,121 @@ func (s *Server) getAccount(ctx context.Context, req *jsonrest.Request) (interfa
 
 // createAccount is POST /account
 func (s *Server) createAccount(ctx context.Context, req *jsonrest.Request) (interface{}, error) {
-	var params accountParams
+	var params createAccountParams
 	if err := req.BindBody(&params); err != nil {
 		return nil, err
 	}
 	if err := params.validate(); err != nil {
 		return nil, jsonrest.BadRequest(err.Error())
 	}
+	if params.Email == "" && s.emailRequired() {
+		return nil, jsonrest.BadRequest("email is required")
+	}
 	account := &domain.Account{
+		Email:    params.Email,
 		Username: params.Username,
 	}
+	if err := s.checkEmailAvailable(ctx, 0, params.Email); err != nil {
+		return nil, err
+	}
 	if err := account.SetPassword(params.Password); err != nil {
 		return nil, err
 	}
-	account, err := s.Repo().CreateAccount(ctx, account)
+	account, err := s.Accounts().CreateAccount(ctx, account)
 	if err != nil {
+		switch {
+		case errors.Is(err, repo.ErrUsernameTaken):
+			return nil, usernameTaken()
+		case errors.Is(err, repo.ErrEmailTaken):
+			return nil, emailTaken()
+		}
 		return nil, err
 	}
+	if account.Email != "" {
+		if err := s.sendEmailVerification(ctx, account); err != nil {
+			return nil, err
+		}
+	}
 	return s.Protocol().Account(account), nil
 }
+
+// updatePassword is PUT /account/password
+func (s *Server) updatePassword(ctx context.Context, req *jsonrest.Request) (interface{}, error) {
+	account := req.Get(requestAccountKey{}).(*domain.Account)
+	var params passwordParams
+	if err := req.BindBody(&params); err != nil {
+		return nil, err
+	}
+	if err := validatePassword(params.Password); err != nil {
+		return nil, jsonrest.BadRequest(err.Error())
+	}
+	ok, err := account.Authenticate(params.CurrentPassword)
+	if err != nil {
+		return nil, err
+	}
+	if !ok {
+		return nil, jsonrest.BadRequest("incorrect password")
+	}
+	if err := account.SetPassword(params.Password); err != nil {
+		return nil, err
+	}
+	account, err = s.Accounts().UpdateAccountPassword(ctx, account)
+	if err != nil {
+		return nil, err
+	}
+	// Sign out everywhere else, and give the caller a fresh session.
+	if err := s.Sessions().RevokeAll(ctx, account.ID); err != nil {
+		return nil, err
+	}
+	return s.newSession(ctx, account)
+}
+
+// updateUsername is PUT /account/username
+func (s *Server) updateUsername(ctx context.Context, req *jsonrest.Request) (interface{}, error) {
+	account := req.Get(requestAccountKey{}).(*domain.Account)
+	var params usernameParams
+	if err := req.BindBody(&params); err != nil {
+		return nil, err
+	}
+	if err := validateUsername(params.Username); err != nil {
+		return nil, jsonrest.BadRequest(err.Error())
+	}
+	account.Username = params.Username
+	account, err := s.Accounts().UpdateAccountUsername(ctx, account)
+	if err != nil {
+		if errors.Is(err, repo.ErrUsernameTaken) {
+			return nil, usernameTaken()
+		}
+		return nil, err
+	}
+	return s.Protocol().Account(account), nil
+}
+
+// deleteAccount is DELETE /account
+//
+// Organisations the account owns are deleted with it, unless they have other
+// members, in which case they must be transferred or deleted first.
+func (s *Server) deleteAccount(ctx context.Context, req *jsonrest.Request) (interface{}, error) {
+	account := req.Get(requestAccountKey{}).(*domain.Account)
+	var params deleteAccountParams
+	if err := req.BindBody(&params); err != nil {
+		return nil, err
+	}
+	ok, err := account.Authenticate(params.Password)
+	if err != nil {
+		return nil, err
+	}
+	if !ok {
+		return nil, jsonrest.BadRequest("incorrect password")
+	}
+	if err := s.Accounts().DeleteAccount(ctx, account.ID); err != nil {
+		if errors.Is(err, repo.ErrOwnsSharedOrg) {
+			return nil, jsonrest.Error(http.StatusConflict, "org_owner", "transfer or delete the organisations you own with other members first")
+		}
+		return nil, err
+	}
+	if err := s.Sessions().RevokeAll(ctx, account.ID); err != nil {
+		return nil, err
+	}
+	return nil, nil
+}
@@ -0,0 +1,297 @@
+package api
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"strconv"
+
+	"github.com/deliveroo/jsonrest-go"
+	"github.com/deliveroo/todo-api/domain"
+)
+
+// Limits on how many accounts and audit entries are listed at once.
+const (
+	adminDefaultLimit = 50
+	adminMaxLimit     = 500
+)
+
+type suspendParams struct {
+	Reason string `json:"reason"`
+}
+
+type roleParams struct {
+	Role string `json:"role"`
+}
+
+type adminPasswordParams struct {
+	Password string `json:"password"`
+}
+
+// RequireAdminMiddleware rejects requests from accounts which aren't admins.
+// It must be used after AuthMiddleware.
+func RequireAdminMiddleware() jsonrest.Middleware {
+	return func(next jsonrest.Endpoint) jsonrest.Endpoint {
+		return func(ctx context.Context, req *jsonrest.Request) (interface{}, error) {
+			account := req.Get(requestAccountKey{}).(*domain.Account)
+			if !account.IsAdmin() {
+				return nil, jsonrest.Error(http.StatusForbidden, "admin_required", "this endpoint requires an admin account")
+			}
+			return next(ctx, req)
+		}
+	}
+}
+
+// audit records an administrative action taken by the request's account.
+func (s *Server) audit(ctx context.Context, req *jsonrest.Request, action string, accountID int64, details map[string]string) error {
+	actor := req.Get(requestAccountKey{}).(*domain.Account)
+	_, err := s.Repo().CreateAuditEntry(ctx, &domain.AuditEntry{
+		ActorID:   actor.ID,
+		Action:    action,
+		AccountID: accountID,
+		Details:   details,
+	})
+	return err
+}
+
+// adminAccount fetches the account identified by the :id route parameter.
+func (s *Server) adminAccount(ctx context.Context, req *jsonrest.Request) (*domain.Account, error) {
+	id, err := paramPublicID(req, "id")
+	if err != nil {
+		return nil, err
+	}
+	account, err := s.Accounts().GetAccountByPublicID(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	if account == nil {
+		return nil, jsonrest.NotFound(fmt.Sprintf("account not found, id=%s", id))
+	}
+	return account, nil
+}
+
+// queryInt parses an optional non-negative integer query parameter.
+func queryInt(req *jsonrest.Request, name string, def int) (int, error) {
+	s := req.Query(name)
+	if s == "" {
+		return def, nil
+	}
+	n, err := strconv.Atoi(s)
+	if err != nil || n < 0 {
+		return 0, jsonrest.BadRequest(fmt.Sprintf("%s must be a non-negative integer", name))
+	}
+	return n, nil
+}
+
+// adminLimit parses the limit query parameter.
+func adminLimit(req *jsonrest.Request) (int, error) {
+	limit, err := queryInt(req, "limit", adminDefaultLimit)
+	if err != nil {
+		return 0, err
+	}
+	if limit == 0 || limit > adminMaxLimit {
+		return 0, jsonrest.BadRequest(fmt.Sprintf("limit must be between 1 and %d", adminMaxLimit))
+	}
+	return limit, nil
+}
+
+// adminSearchAccounts is GET /admin/accounts
+func (s *Server) adminSearchAccounts(ctx context.Context, req *jsonrest.Request) (interface{}, error) {
+	limit, err := adminLimit(req)
+	if err != nil {
+		return nil, err
+	}
+	offset, err := queryInt(req, "offset", 0)
+	if err != nil {
+		return nil, err
+	}
+	accounts, err := s.Accounts().SearchAccounts(ctx, req.Query("q"), limit, offset)
+	if err != nil {
+		return nil, err
+	}
+	return s.Protocol().AdminAccounts(accounts), nil
+}
+
+// adminGetAccount is GET /admin/accounts/:id
+func (s *Server) adminGetAccount(ctx context.Context, req *jsonrest.Request) (interface{}, error) {
+	account, err := s.adminAccount(ctx, req)
+	if err != nil {
+		return nil, err
+	}
+	return s.Protocol().AdminAccount(account), nil
+}
+
+// adminGetTaskStats is GET /admin/accounts/:id/task-stats
+func (s *Server) adminGetTaskStats(ctx context.Context, req *jsonrest.Request) (interface{}, error) {
+	account, err := s.adminAccount(ctx, req)
+	if err != nil {
+		return nil, err
+	}
+	stats, err := s.Tasks().GetTaskStatsByAccountID(ctx, account.ID)
+	if err != nil {
+		return nil, err
+	}
+	return s.Protocol().TaskStats(stats), nil
+}
+
+// adminSuspendAccount is POST /admin/accounts/:id/suspend
+func (s *Server) adminSuspendAccount(ctx context.Context, req *jsonrest.Request) (interface{}, error) {
+	var params suspendParams
+	if err := req.BindBody(&params); err != nil {
+		return nil, err
+	}
+	if params.Reason == "" {
+		return nil, jsonrest.BadRequest("reason is required")
+	}
+	account, err := s.adminAccount(ctx, req)
+	if err != nil {
+		return nil, err
+	}
+	if account.ID == req.Get(requestAccountKey{}).(*domain.Account).ID {
+		return nil, jsonrest.BadRequest("admins can't suspend themselves")
+	}
+	account, err = s.Accounts().SuspendAccount(ctx, account.ID, params.Reason)
+	if err != nil {
+		return nil, err
+	}
+	if err := s.Sessions().RevokeAll(ctx, account.ID); err != nil {
+		return nil, err
+	}
+	if err := s.audit(ctx, req, domain.AuditSuspend, account.ID, map[string]string{"reason": params.Reason}); err != nil {
+		return nil, err
+	}
+	return s.Protocol().AdminAccount(account), nil
+}
+
+// adminUnsuspendAccount is POST /admin/accounts/:id/unsuspend
+func (s *Server) adminUnsuspendAccount(ctx context.Context, req *jsonrest.Request) (interface{}, error) {
+	account, err := s.adminAccount(ctx, req)
+	if err != nil {
+		return nil, err
+	}
+	account, err = s.Accounts().UnsuspendAccount(ctx, account.ID)
+	if err != nil {
+		return nil, err
+	}
+	if err := s.audit(ctx, req, domain.AuditUnsuspend, account.ID, nil); err != nil {
+		return nil, err
+	}
+	return s.Protocol().AdminAccount(account), nil
+}
+
+// adminLogoutAccount is POST /admin/accounts/:id/logout
+func (s *Server) adminLogoutAccount(ctx context.Context, req *jsonrest.Request) (interface{}, error) {
+	account, err := s.adminAccount(ctx, req)
+	if err != nil {
+		return nil, err
+	}
+	if err := s.Sessions().RevokeAll(ctx, account.ID); err != nil {
+		return nil, err
+	}
+	if err := s.audit(ctx, req, domain.AuditLogout, account.ID, nil); err != nil {
+		return nil, err
+	}
+	return nil, nil
+}
+
+// adminResetPassword is POST /admin/accounts/:id/password-reset
+//
+// If a password is given, it replaces the account's password. Otherwise, a
+// password reset token is emailed to the account owner, so that nobody but
+// them knows the new password.
+func (s *Server) adminResetPassword(ctx context.Context, req *jsonrest.Request) (interface{}, error) {
+	var params adminPasswordParams
+	if err := req.BindBody(&params); err != nil {
+		return nil, err
+	}
+	account, err := s.adminAccount(ctx, req)
+	if err != nil {
+		return nil, err
+	}
+	details := map[string]string{"method": "email"}
+	if params.Password != "" {
+		if err := validatePassword(params.Password); err != nil {
+			return nil, jsonrest.BadRequest(err.Error())
+		}
+		if err := account.SetPassword(params.Password); err != nil {
+			return nil, err
+		}
+		if _, err := s.Accounts().UpdateAccountPassword(ctx, account); err != nil {
+			return nil, err
+		}
+		if err := s.Sessions().RevokeAll(ctx, account.ID); err != nil {
+			return nil, err
+		}
+		details["method"] = "password"
+	} else {
+		if !account.Verified() {
+			return nil, jsonrest.BadRequest("account has no verified email address, so a password is required")
+		}
+		if err := s.sendPasswordReset(ctx, account); err != nil {
+			return nil, err
+		}
+	}
+	if err := s.audit(ctx, req, domain.AuditPasswordReset, account.ID, details); err != nil {
+		return nil, err
+	}
+	return nil, nil
+}
+
+// adminUpdateRole is PUT /admin/accounts/:id/role
+func (s *Server) adminUpdateRole(ctx context.Context, req *jsonrest.Request) (interface{}, error) {
+	var params roleParams
+	if err := req.BindBody(&params); err != nil {
+		return nil, err
+	}
+	if !domain.ValidRole(params.Role) {
+		return nil, jsonrest.BadRequest(fmt.Sprintf("unknown role %q", params.Role))
+	}
+	account, err := s.adminAccount(ctx, req)
+	if err != nil {
+		return nil, err
+	}
+	if account.ID == req.Get(requestAccountKey{}).(*domain.Account).ID {
+		return nil, jsonrest.BadRequest("admins can't change their own role")
+	}
+	previous := account.Role
+	account, err = s.Accounts().UpdateAccountRole(ctx, account.ID, params.Role)
+	if err != nil {
+		return nil, err
+	}
+	if err := s.audit(ctx, req, domain.AuditRoleChange, account.ID, map[string]string{
+		"from": previous,
+		"to":   params.Role,
+	}); err != nil {
+		return nil, err
+	}
+	return s.Protocol().AdminAccount(account), nil
+}
+
+// adminGetAuditLog is GET /admin/audit-log
+func (s *Server) adminGetAuditLog(ctx context.Context, req *jsonrest.Request) (interface{}, error) {
+	limit, err := adminLimit(req)
+	if err != nil {
+		return nil, err
+	}
+	var accountID int64
+	if v := req.Query("account_id"); v != "" {
+		if !domain.ValidPublicID(v) {
+			return nil, jsonrest.BadRequest("account_id is not a valid id")
+		}
+		account, err := s.Accounts().GetAccountByPublicID(ctx, v)
+		if err != nil {
+			return nil, err
+		}
+		// Entries for deleted accounts can't be filtered by account, since
+		// their public ids are gone with them.
+		if account == nil {
+			return s.Protocol().AuditEntries(nil), nil
+		}
+		accountID = account.ID
+	}
+	entries, err := s.Repo().GetRecentAuditEntries(ctx, accountID, limit)
+	if err != nil {
+		return nil, err
+	}
+	return s.Protocol().AuditEntries(entries), nil
+}
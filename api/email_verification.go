@@ -0,0 +1,142 @@
+package api
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/deliveroo/jsonrest-go"
+	"github.com/deliveroo/todo-api/domain"
+	"github.com/deliveroo/todo-api/repo"
+	"github.com/deliveroo/todo-api/service/mail"
+)
+
+// Access given to accounts without a verified email address.
+const (
+	UnverifiedFull     = "full"      // no restrictions
+	UnverifiedReadOnly = "read-only" // tasks can't be changed
+	UnverifiedLimited  = "limited"   // up to UnverifiedTaskLimit tasks
+)
+
+type verifyEmailParams struct {
+	Token string `json:"token"`
+}
+
+type emailParams struct {
+	Email    string `json:"email"`
+	Password string `json:"password"`
+}
+
+// emailRequired reports whether accounts must have an email address. When
+// unverified accounts are restricted, an account without one could never be
+// verified, so it would stay restricted for good.
+func (s *Server) emailRequired() bool {
+	return s.cfg.UnverifiedAccess != "" && s.cfg.UnverifiedAccess != UnverifiedFull
+}
+
+// verificationRequired is returned when an unverified account is restricted
+// from doing something.
+func verificationRequired(msg string) error {
+	return jsonrest.Error(http.StatusForbidden, "verification_required", msg)
+}
+
+// sendEmailVerification emails a verification token to an account's email
+// address.
+func (s *Server) sendEmailVerification(ctx context.Context, account *domain.Account) error {
+	v := &domain.EmailVerification{
+		AccountID: account.ID,
+		Email:     account.Email,
+		Expires:   time.Now().UTC().Add(s.cfg.EmailVerificationExpiry),
+	}
+	token := v.NewToken()
+	if _, err := s.Repo().CreateEmailVerification(ctx, v); err != nil {
+		return err
+	}
+	return s.Mailer().Send(ctx, &mail.Message{
+		To:      account.Email,
+		Subject: "Verify your todo-api email address",
+		Body: fmt.Sprintf(`Hi %s,
+
+Please confirm this is your email address by verifying it with this token
+within %s:
+
+%s
+
+If you didn't create a todo-api account, you can ignore this email.
+`, account.Username, s.cfg.EmailVerificationExpiry, token),
+	})
+}
+
+// verifyEmail is POST /account/verify
+func (s *Server) verifyEmail(ctx context.Context, req *jsonrest.Request) (interface{}, error) {
+	var params verifyEmailParams
+	if err := req.BindBody(&params); err != nil {
+		return nil, err
+	}
+	account, err := s.Repo().UseEmailVerification(ctx, domain.EmailVerificationDigest(params.Token))
+	if err != nil {
+		if errors.Is(err, repo.ErrEmailTaken) {
+			return nil, emailTaken()
+		}
+		return nil, err
+	}
+	if account == nil {
+		return nil, jsonrest.BadRequest("invalid or expired token")
+	}
+	return s.Protocol().Account(account), nil
+}
+
+// resendEmailVerification is POST /account/verify/resend
+func (s *Server) resendEmailVerification(ctx context.Context, req *jsonrest.Request) (interface{}, error) {
+	account := req.Get(requestAccountKey{}).(*domain.Account)
+	if account.Email == "" {
+		return nil, jsonrest.BadRequest("account has no email address")
+	}
+	if account.Verified() {
+		return nil, jsonrest.BadRequest("email address is already verified")
+	}
+	if err := s.sendEmailVerification(ctx, account); err != nil {
+		return nil, err
+	}
+	return nil, nil
+}
+
+// updateEmail is PUT /account/email
+func (s *Server) updateEmail(ctx context.Context, req *jsonrest.Request) (interface{}, error) {
+	account := req.Get(requestAccountKey{}).(*domain.Account)
+	var params emailParams
+	if err := req.BindBody(&params); err != nil {
+		return nil, err
+	}
+	if err := validateEmail(params.Email); err != nil {
+		return nil, jsonrest.BadRequest(err.Error())
+	}
+	if params.Email == "" && s.emailRequired() {
+		return nil, jsonrest.BadRequest("email is required")
+	}
+	// The email address can be used to reset the password, so changing it
+	// needs the password too.
+	ok, err := account.Authenticate(params.Password)
+	if err != nil {
+		return nil, err
+	}
+	if !ok {
+		return nil, jsonrest.BadRequest("incorrect password")
+	}
+	if err := s.checkEmailAvailable(ctx, account.ID, params.Email); err != nil {
+		return nil, err
+	}
+	account.Email = params.Email
+	account, err = s.Accounts().UpdateAccountEmail(ctx, account)
+	if err != nil {
+		return nil, err
+	}
+	if account.Email != "" {
+		if err := s.sendEmailVerification(ctx, account); err != nil {
+			return nil, err
+		}
+	}
+	return s.Protocol().Account(account), nil
+}
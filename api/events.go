@@ -0,0 +1,145 @@
+package api
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/deliveroo/jsonrest-go"
+	"github.com/deliveroo/todo-api/api/protocol"
+	"github.com/deliveroo/todo-api/domain"
+	"github.com/deliveroo/todo-api/service/events"
+	"go.uber.org/zap"
+)
+
+// eventKeepalive is how often a comment is sent on an idle event stream, so
+// that it isn't closed by proxies.
+const eventKeepalive = 15 * time.Second
+
+// eventReset is sent to a client resuming an event stream if events it missed
+// are no longer logged. It should fetch its tasks again.
+const eventReset = "reset"
+
+// streamEvents is GET /events
+//
+// It streams changes to the tasks the account can see as server-sent events.
+// A client which reconnects with the Last-Event-ID header is sent the events
+// it missed, or a reset event if they're no longer available.
+func (s *Server) streamEvents(ctx context.Context, req *jsonrest.Request) (interface{}, error) {
+	account := req.Get(requestAccountKey{}).(*domain.Account)
+	header := req.Header("Last-Event-ID")
+	lastID, err := strconv.ParseInt(header, 10, 64)
+	if header != "" && (err != nil || lastID < 0) {
+		return nil, jsonrest.BadRequest("malformed Last-Event-ID header")
+	}
+
+	// Subscribe before catching up, so that no events are missed in between.
+	live, unsubscribe := s.Events().Subscribe(account.ID)
+	defer unsubscribe()
+	var (
+		backlog []*events.Event
+		latest  int64
+	)
+	if header != "" {
+		backlog, latest, err = s.Events().Since(ctx, account.ID, lastID)
+	} else {
+		latest, err = s.Events().Latest(ctx, account.ID)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	w, flusher, ok := startStream(ctx)
+	if !ok {
+		return nil, errors.New("api.streamEvents: response can't be streamed")
+	}
+	w.Header().Set("Content-Type", "text/event-stream")
+	w.Header().Set("Cache-Control", "no-cache")
+	w.WriteHeader(http.StatusOK)
+
+	switch {
+	case header == "":
+		// Give the client an id to resume from, even if it receives no events.
+		err = writeEvent(w, latest, "", nil)
+	case len(backlog) == 0 && lastID == latest:
+	case len(backlog) > 0 && backlog[0].ID == lastID+1:
+		for _, e := range backlog {
+			if err = writeEvent(w, e.ID, e.Type, e.Data); err != nil {
+				break
+			}
+		}
+	default:
+		err = writeEvent(w, latest, eventReset, []byte("{}"))
+	}
+	if err != nil {
+		return nil, nil // the client has gone away
+	}
+	flusher.Flush()
+
+	keepalive := time.NewTicker(eventKeepalive)
+	defer keepalive.Stop()
+	for {
+		select {
+		case e, ok := <-live:
+			if !ok {
+				// The client was too slow, or the server is shutting down;
+				// either way it can reconnect and catch up.
+				return nil, nil
+			}
+			if e.ID <= latest {
+				continue // already sent
+			}
+			latest = e.ID
+			err = writeEvent(w, e.ID, e.Type, e.Data)
+		case <-keepalive.C:
+			_, err = io.WriteString(w, ": keepalive\n\n")
+		case <-ctx.Done():
+			return nil, nil
+		}
+		if err != nil {
+			return nil, nil
+		}
+		flusher.Flush()
+	}
+}
+
+// writeEvent writes a server-sent event. An event with no type or data only
+// updates the client's last event id.
+func writeEvent(w io.Writer, id int64, typ string, data []byte) error {
+	var b strings.Builder
+	fmt.Fprintf(&b, "id: %d\n", id)
+	if typ != "" {
+		fmt.Fprintf(&b, "event: %s\n", typ)
+	}
+	if data != nil {
+		for _, line := range strings.Split(string(data), "\n") {
+			fmt.Fprintf(&b, "data: %s\n", line)
+		}
+	}
+	b.WriteString("\n")
+	_, err := io.WriteString(w, b.String())
+	return err
+}
+
+// publishStreamEvent streams a task event to the accounts which can see the
+// task. Errors are logged rather than returned, since the task has already
+// changed.
+func (s *Server) publishStreamEvent(ctx context.Context, event string, t *domain.Task, task protocol.Task) {
+	var accountIDs []int64
+	data, err := json.Marshal(task)
+	if err == nil {
+		accountIDs, err = s.Tasks().GetAccountIDsForTask(ctx, t)
+	}
+	if err == nil {
+		err = s.Events().Publish(ctx, accountIDs, event, data)
+	}
+	if err != nil {
+		zap.L().Error("api.publishStreamEvent", zap.String("event", event), zap.Int64("task_id", t.ID), zap.Error(err))
+	}
+}
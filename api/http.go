@@ -0,0 +1,156 @@
+package api
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"errors"
+	"net"
+	"net/http"
+	"strings"
+)
+
+// httpInfoKey is the context key for httpInfo.
+type httpInfoKey struct{}
+
+// httpInfo carries details of the underlying HTTP request and response which
+// jsonrest doesn't expose to endpoints.
+type httpInfo struct {
+	clientIP string
+	header   http.Header
+	writer   *streamWriter
+}
+
+// withHTTPInfo adds details of the HTTP exchange to the request context. The
+// response writer is wrapped so that endpoints can stream their response.
+func (s *Server) withHTTPInfo(w http.ResponseWriter, req *http.Request) (http.ResponseWriter, *http.Request) {
+	sw := &streamWriter{ResponseWriter: w}
+	info := &httpInfo{
+		clientIP: s.clientIP(req),
+		header:   w.Header(),
+		writer:   sw,
+	}
+	return sw, req.WithContext(context.WithValue(req.Context(), httpInfoKey{}, info))
+}
+
+// streamWriter is a response writer which an endpoint can take over from
+// jsonrest, to stream its response. Once it has, whatever jsonrest writes is
+// discarded.
+type streamWriter struct {
+	http.ResponseWriter
+	streaming bool
+}
+
+// WriteHeader implements the http.ResponseWriter interface.
+func (w *streamWriter) WriteHeader(status int) {
+	if !w.streaming {
+		w.ResponseWriter.WriteHeader(status)
+	}
+}
+
+// Write implements the http.ResponseWriter interface.
+func (w *streamWriter) Write(b []byte) (int, error) {
+	if w.streaming {
+		return len(b), nil
+	}
+	return w.ResponseWriter.Write(b)
+}
+
+// Hijack implements the http.Hijacker interface, for endpoints which upgrade
+// the connection to another protocol. Once it's hijacked, whatever jsonrest
+// writes is discarded.
+func (w *streamWriter) Hijack() (net.Conn, *bufio.ReadWriter, error) {
+	h, ok := w.ResponseWriter.(http.Hijacker)
+	if !ok {
+		return nil, nil, errors.New("api: response can't be hijacked")
+	}
+	conn, rw, err := h.Hijack()
+	if err == nil {
+		w.streaming = true
+	}
+	return conn, rw, err
+}
+
+// clientIP returns the IP address of the client that made the request. If
+// the server is behind a proxy, the proxy's client IP header is trusted.
+func (s *Server) clientIP(req *http.Request) string {
+	if s.cfg.ClientIPHeader != "" {
+		if v := req.Header.Get(s.cfg.ClientIPHeader); v != "" {
+			// Proxies append to X-Forwarded-For style headers, so the last
+			// address is the one the trusted proxy saw. Any before it were
+			// sent by the client, and can't be trusted.
+			addrs := strings.Split(v, ",")
+			return strings.TrimSpace(addrs[len(addrs)-1])
+		}
+	}
+	host, _, err := net.SplitHostPort(req.RemoteAddr)
+	if err != nil {
+		return req.RemoteAddr
+	}
+	return host
+}
+
+func getHTTPInfo(ctx context.Context) *httpInfo {
+	if info, ok := ctx.Value(httpInfoKey{}).(*httpInfo); ok {
+		return info
+	}
+	return &httpInfo{header: make(http.Header)}
+}
+
+// clientIP returns the IP address of the client that made the request.
+func clientIP(ctx context.Context) string {
+	return getHTTPInfo(ctx).clientIP
+}
+
+// startStream takes over the response from jsonrest, and returns the
+// underlying writer for the endpoint to stream its response to. It returns
+// false if the response can't be streamed.
+func startStream(ctx context.Context) (http.ResponseWriter, http.Flusher, bool) {
+	sw := getHTTPInfo(ctx).writer
+	if sw == nil {
+		return nil, nil, false
+	}
+	f, ok := sw.ResponseWriter.(http.Flusher)
+	if !ok {
+		return nil, nil, false
+	}
+	sw.streaming = true
+	return sw.ResponseWriter, f, true
+}
+
+// responseWriter returns the writer for the response, for endpoints which
+// upgrade the connection to another protocol.
+func responseWriter(ctx context.Context) http.ResponseWriter {
+	return getHTTPInfo(ctx).writer
+}
+
+// responseHeader returns the header map that will be sent in the response.
+func responseHeader(ctx context.Context) http.Header {
+	return getHTTPInfo(ctx).header
+}
+
+// writeJSON writes a JSON response from a handler which doesn't use jsonrest.
+func writeJSON(w http.ResponseWriter, status int, v interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	_ = json.NewEncoder(w).Encode(v)
+}
+
+// writeError writes an error response, in the same format as jsonrest, from
+// a handler which doesn't use jsonrest.
+func writeError(w http.ResponseWriter, status int, code, msg string) {
+	writeJSON(w, status, errorBody(code, msg))
+}
+
+// errorBody returns an error response body, in the same format as jsonrest.
+func errorBody(code, msg string) interface{} {
+	var body struct {
+		Error struct {
+			Code    string `json:"code"`
+			Message string `json:"message"`
+		} `json:"error"`
+	}
+	body.Error.Code = code
+	body.Error.Message = msg
+	return body
+}
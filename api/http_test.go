@@ -0,0 +1,29 @@
+package api
+
+import (
+	"net/http/httptest"
+	"testing"
+
+	"github.com/deliveroo/assert-go"
+)
+
+func TestClientIP(t *testing.T) {
+	s := NewServer(&Config{ClientIPHeader: "X-Forwarded-For"})
+	for _, tt := range []struct {
+		header string
+		want   string
+	}{
+		{"", "192.0.2.1"},
+		{"203.0.113.7", "203.0.113.7"},
+		// The client can send its own X-Forwarded-For, which the proxy
+		// appends to.
+		{"1.2.3.4, 203.0.113.7", "203.0.113.7"},
+		{"1.2.3.4,5.6.7.8 , 203.0.113.7 ", "203.0.113.7"},
+	} {
+		req := httptest.NewRequest("GET", "/", nil)
+		if tt.header != "" {
+			req.Header.Set("X-Forwarded-For", tt.header)
+		}
+		assert.Equal(t, s.clientIP(req), tt.want)
+	}
+}
@@ -0,0 +1,372 @@
+package api
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+
+	"github.com/deliveroo/jsonrest-go"
+	"github.com/deliveroo/todo-api/domain"
+	"github.com/deliveroo/todo-api/repo"
+	"github.com/jackc/pgx/v4"
+)
+
+type listParams struct {
+	Name string `json:"name"`
+}
+
+func (p listParams) validate() error {
+	if len(p.Name) == 0 {
+		return errors.New("name is required")
+	}
+	return nil
+}
+
+type listMemberParams struct {
+	Role string `json:"role"`
+}
+
+func (p listMemberParams) validate() error {
+	if !domain.ValidMemberRole(p.Role) {
+		return fmt.Errorf("role must be %q or %q", domain.ListRoleEditor, domain.ListRoleViewer)
+	}
+	return nil
+}
+
+type listInvitationParams struct {
+	listMemberParams
+	Username string `json:"username"`
+}
+
+func (p listInvitationParams) validate() error {
+	if len(p.Username) == 0 {
+		return errors.New("username is required")
+	}
+	return p.listMemberParams.validate()
+}
+
+// listReadOnly is returned when an account can see a list, but not change
+// its tasks.
+func listReadOnly() error {
+	return jsonrest.Error(http.StatusForbidden, "list_read_only", "you can't change tasks in this list")
+}
+
+// listOwnerRequired is returned when an account which doesn't own a list
+// tries to manage it.
+func listOwnerRequired() error {
+	return jsonrest.Error(http.StatusForbidden, "list_owner_required", "only the list owner can do this")
+}
+
+// accountList returns the list identified by the :id parameter, if the
+// account can see it.
+func (s *Server) accountList(ctx context.Context, req *jsonrest.Request, account *domain.Account) (*domain.List, error) {
+	id, err := paramPublicID(req, "id")
+	if err != nil {
+		return nil, err
+	}
+	l, err := s.Repo().GetListByPublicIDForAccount(ctx, id, account.ID)
+	if err != nil {
+		return nil, err
+	}
+	if l == nil {
+		return nil, jsonrest.NotFound(fmt.Sprintf("list not found, id=%s", id))
+	}
+	return l, nil
+}
+
+// ownedList returns the list identified by the :id parameter, if the account
+// owns it.
+func (s *Server) ownedList(ctx context.Context, req *jsonrest.Request, account *domain.Account) (*domain.List, error) {
+	l, err := s.accountList(ctx, req, account)
+	if err != nil {
+		return nil, err
+	}
+	if l.Role != domain.ListRoleOwner {
+		return nil, listOwnerRequired()
+	}
+	return l, nil
+}
+
+// editableList returns the list with a public id, if the account can change
+// its tasks.
+func (s *Server) editableList(ctx context.Context, r *repo.Client, id string, account *domain.Account) (*domain.List, error) {
+	if !domain.ValidPublicID(id) {
+		return nil, jsonrest.BadRequest("list_id is not a valid id")
+	}
+	l, err := r.GetListByPublicIDForAccount(ctx, id, account.ID)
+	if err != nil {
+		return nil, err
+	}
+	if l == nil {
+		return nil, jsonrest.NotFound(fmt.Sprintf("list not found, id=%s", id))
+	}
+	if !domain.CanEditList(l.Role) {
+		return nil, listReadOnly()
+	}
+	return l, nil
+}
+
+// createList is POST /lists
+func (s *Server) createList(ctx context.Context, req *jsonrest.Request) (interface{}, error) {
+	account := req.Get(requestAccountKey{}).(*domain.Account)
+	var params listParams
+	if err := req.BindBody(&params); err != nil {
+		return nil, err
+	}
+	if err := params.validate(); err != nil {
+		return nil, jsonrest.BadRequest(err.Error())
+	}
+	l, err := s.Repo().CreateList(ctx, &domain.List{
+		AccountID: account.ID,
+		Name:      params.Name,
+	})
+	if err != nil {
+		return nil, err
+	}
+	return s.Protocol().List(l), nil
+}
+
+// getAllLists is GET /lists
+func (s *Server) getAllLists(ctx context.Context, req *jsonrest.Request) (interface{}, error) {
+	account := req.Get(requestAccountKey{}).(*domain.Account)
+	lists, err := s.Repo().GetAllListsForAccount(ctx, account.ID)
+	if err != nil {
+		return nil, err
+	}
+	return s.Protocol().Lists(lists), nil
+}
+
+// getList is GET /lists/:id
+func (s *Server) getList(ctx context.Context, req *jsonrest.Request) (interface{}, error) {
+	account := req.Get(requestAccountKey{}).(*domain.Account)
+	l, err := s.accountList(ctx, req, account)
+	if err != nil {
+		return nil, err
+	}
+	return s.Protocol().List(l), nil
+}
+
+// updateList is PUT /lists/:id
+func (s *Server) updateList(ctx context.Context, req *jsonrest.Request) (interface{}, error) {
+	account := req.Get(requestAccountKey{}).(*domain.Account)
+	var params listParams
+	if err := req.BindBody(&params); err != nil {
+		return nil, err
+	}
+	if err := params.validate(); err != nil {
+		return nil, jsonrest.BadRequest(err.Error())
+	}
+	l, err := s.ownedList(ctx, req, account)
+	if err != nil {
+		return nil, err
+	}
+	l.Name = params.Name
+	l, err = s.Repo().UpdateListName(ctx, l)
+	if err != nil {
+		return nil, err
+	}
+	return s.Protocol().List(l), nil
+}
+
+// deleteList is DELETE /lists/:id
+//
+// It deletes the list's tasks too.
+func (s *Server) deleteList(ctx context.Context, req *jsonrest.Request) (interface{}, error) {
+	account := req.Get(requestAccountKey{}).(*domain.Account)
+	l, err := s.ownedList(ctx, req, account)
+	if err != nil {
+		return nil, err
+	}
+	return nil, s.Repo().DeleteList(ctx, l.ID)
+}
+
+// getListTasks is GET /lists/:id/tasks
+func (s *Server) getListTasks(ctx context.Context, req *jsonrest.Request) (interface{}, error) {
+	account := req.Get(requestAccountKey{}).(*domain.Account)
+	l, err := s.accountList(ctx, req, account)
+	if err != nil {
+		return nil, err
+	}
+	tasks, err := s.Tasks().GetAllTasksByListIDForAccount(ctx, l.ID, account.ID)
+	if err != nil {
+		return nil, err
+	}
+	return s.renderTasks(ctx, tasks)
+}
+
+// getListMembers is GET /lists/:id/members
+func (s *Server) getListMembers(ctx context.Context, req *jsonrest.Request) (interface{}, error) {
+	account := req.Get(requestAccountKey{}).(*domain.Account)
+	l, err := s.accountList(ctx, req, account)
+	if err != nil {
+		return nil, err
+	}
+	members, err := s.Repo().GetListMembers(ctx, l.ID)
+	if err != nil {
+		return nil, err
+	}
+	return s.Protocol().ListMembers(members), nil
+}
+
+// updateListMember is PUT /lists/:id/members/:account_id
+func (s *Server) updateListMember(ctx context.Context, req *jsonrest.Request) (interface{}, error) {
+	account := req.Get(requestAccountKey{}).(*domain.Account)
+	var params listMemberParams
+	if err := req.BindBody(&params); err != nil {
+		return nil, err
+	}
+	if err := params.validate(); err != nil {
+		return nil, jsonrest.BadRequest(err.Error())
+	}
+	l, err := s.ownedList(ctx, req, account)
+	if err != nil {
+		return nil, err
+	}
+	memberID, err := s.paramAccountID(ctx, req, "account_id")
+	if err != nil {
+		return nil, err
+	}
+	if err := s.Repo().UpdateListMemberRole(ctx, l.ID, memberID, params.Role); err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return nil, jsonrest.NotFound(fmt.Sprintf("list member not found, account_id=%s", req.Param("account_id")))
+		}
+		return nil, err
+	}
+	return nil, nil
+}
+
+// deleteListMember is DELETE /lists/:id/members/:account_id
+//
+// The list owner can remove any member, and members can remove themselves to
+// leave the list.
+func (s *Server) deleteListMember(ctx context.Context, req *jsonrest.Request) (interface{}, error) {
+	account := req.Get(requestAccountKey{}).(*domain.Account)
+	l, err := s.accountList(ctx, req, account)
+	if err != nil {
+		return nil, err
+	}
+	memberID, err := s.paramAccountID(ctx, req, "account_id")
+	if err != nil {
+		return nil, err
+	}
+	if l.Role != domain.ListRoleOwner && memberID != account.ID {
+		return nil, listOwnerRequired()
+	}
+	if err := s.Repo().DeleteListMember(ctx, l.ID, memberID); err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return nil, jsonrest.NotFound(fmt.Sprintf("list member not found, account_id=%s", req.Param("account_id")))
+		}
+		return nil, err
+	}
+	return nil, nil
+}
+
+// createListInvitation is POST /lists/:id/invitations
+func (s *Server) createListInvitation(ctx context.Context, req *jsonrest.Request) (interface{}, error) {
+	account := req.Get(requestAccountKey{}).(*domain.Account)
+	var params listInvitationParams
+	if err := req.BindBody(&params); err != nil {
+		return nil, err
+	}
+	if err := params.validate(); err != nil {
+		return nil, jsonrest.BadRequest(err.Error())
+	}
+	l, err := s.ownedList(ctx, req, account)
+	if err != nil {
+		return nil, err
+	}
+	invitee, err := s.Accounts().GetAccountByUsername(ctx, params.Username)
+	if err != nil {
+		return nil, err
+	}
+	if invitee == nil {
+		return nil, jsonrest.NotFound("account not found")
+	}
+	if invitee.ID == account.ID {
+		return nil, jsonrest.BadRequest("you can't invite yourself")
+	}
+	i, err := s.Repo().CreateListInvitation(ctx, &domain.ListInvitation{
+		ListID:    l.ID,
+		InviterID: account.ID,
+		InviteeID: invitee.ID,
+		Role:      params.Role,
+	})
+	if err != nil {
+		if errors.Is(err, repo.ErrInvitationPending) {
+			return nil, jsonrest.Error(http.StatusConflict, "invitation_pending", "the account has already been invited")
+		}
+		return nil, err
+	}
+	return s.Protocol().ListInvitation(i), nil
+}
+
+// getListInvitations is GET /lists/:id/invitations
+func (s *Server) getListInvitations(ctx context.Context, req *jsonrest.Request) (interface{}, error) {
+	account := req.Get(requestAccountKey{}).(*domain.Account)
+	l, err := s.ownedList(ctx, req, account)
+	if err != nil {
+		return nil, err
+	}
+	invitations, err := s.Repo().GetPendingListInvitationsByListID(ctx, l.ID)
+	if err != nil {
+		return nil, err
+	}
+	return s.Protocol().ListInvitations(invitations), nil
+}
+
+// deleteListInvitation is DELETE /lists/:id/invitations/:invitation_id
+func (s *Server) deleteListInvitation(ctx context.Context, req *jsonrest.Request) (interface{}, error) {
+	account := req.Get(requestAccountKey{}).(*domain.Account)
+	l, err := s.ownedList(ctx, req, account)
+	if err != nil {
+		return nil, err
+	}
+	id, err := paramPublicID(req, "invitation_id")
+	if err != nil {
+		return nil, err
+	}
+	if err := s.Repo().DeleteListInvitationByPublicIDAndListID(ctx, id, l.ID); err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return nil, jsonrest.NotFound(fmt.Sprintf("invitation not found, id=%s", id))
+		}
+		return nil, err
+	}
+	return nil, nil
+}
+
+// getAccountInvitations is GET /account/invitations
+func (s *Server) getAccountInvitations(ctx context.Context, req *jsonrest.Request) (interface{}, error) {
+	account := req.Get(requestAccountKey{}).(*domain.Account)
+	invitations, err := s.Repo().GetPendingListInvitationsByInviteeID(ctx, account.ID)
+	if err != nil {
+		return nil, err
+	}
+	return s.Protocol().ListInvitations(invitations), nil
+}
+
+// acceptInvitation is POST /account/invitations/:id/accept
+func (s *Server) acceptInvitation(ctx context.Context, req *jsonrest.Request) (interface{}, error) {
+	return s.respondToInvitation(ctx, req, true)
+}
+
+// declineInvitation is POST /account/invitations/:id/decline
+func (s *Server) declineInvitation(ctx context.Context, req *jsonrest.Request) (interface{}, error) {
+	return s.respondToInvitation(ctx, req, false)
+}
+
+func (s *Server) respondToInvitation(ctx context.Context, req *jsonrest.Request, accept bool) (interface{}, error) {
+	account := req.Get(requestAccountKey{}).(*domain.Account)
+	id, err := paramPublicID(req, "id")
+	if err != nil {
+		return nil, err
+	}
+	i, err := s.Repo().RespondToListInvitation(ctx, id, account.ID, accept)
+	if err != nil {
+		return nil, err
+	}
+	if i == nil {
+		return nil, jsonrest.NotFound(fmt.Sprintf("pending invitation not found, id=%s", id))
+	}
+	return s.Protocol().ListInvitation(i), nil
+}
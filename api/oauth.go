@@ -0,0 +1,451 @@
+package api
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"time"
+
+	"github.com/deliveroo/jsonrest-go"
+	"github.com/deliveroo/todo-api/api/protocol"
+	"github.com/deliveroo/todo-api/domain"
+	"github.com/jackc/pgx/v4"
+	"go.uber.org/zap"
+)
+
+// maxOAuthFormSize limits the size of form bodies posted to the OAuth token
+// and introspection endpoints.
+const maxOAuthFormSize = 64 << 10
+
+type oauthClientParams struct {
+	Confidential bool     `json:"confidential"`
+	Name         string   `json:"name"`
+	RedirectURIs []string `json:"redirect_uris"`
+}
+
+func (p oauthClientParams) validate() error {
+	if len(p.Name) == 0 {
+		return errors.New("name is required")
+	}
+	if len(p.RedirectURIs) == 0 {
+		return errors.New("at least one redirect URI is required")
+	}
+	for _, uri := range p.RedirectURIs {
+		if err := domain.ValidateRedirectURI(uri); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// createOAuthClient is POST /oauth/clients
+func (s *Server) createOAuthClient(ctx context.Context, req *jsonrest.Request) (interface{}, error) {
+	account := req.Get(requestAccountKey{}).(*domain.Account)
+	var params oauthClientParams
+	if err := req.BindBody(&params); err != nil {
+		return nil, err
+	}
+	if err := params.validate(); err != nil {
+		return nil, jsonrest.BadRequest(err.Error())
+	}
+	oc := &domain.OAuthClient{
+		AccountID:    account.ID,
+		Name:         params.Name,
+		RedirectURIs: params.RedirectURIs,
+	}
+	oc.NewClientID()
+	var secret string
+	if params.Confidential {
+		secret = oc.NewSecret()
+	}
+	oc, err := s.Repo().CreateOAuthClient(ctx, oc)
+	if err != nil {
+		return nil, err
+	}
+	return s.Protocol().NewOAuthClient(oc, secret), nil
+}
+
+// getAllOAuthClients is GET /oauth/clients
+func (s *Server) getAllOAuthClients(ctx context.Context, req *jsonrest.Request) (interface{}, error) {
+	account := req.Get(requestAccountKey{}).(*domain.Account)
+	clients, err := s.Repo().GetAllOAuthClientsByAccountID(ctx, account.ID)
+	if err != nil {
+		return nil, err
+	}
+	return s.Protocol().OAuthClients(clients), nil
+}
+
+// deleteOAuthClient is DELETE /oauth/clients/:client_id
+func (s *Server) deleteOAuthClient(ctx context.Context, req *jsonrest.Request) (interface{}, error) {
+	account := req.Get(requestAccountKey{}).(*domain.Account)
+	clientID := req.Param("client_id")
+	oc, err := s.Repo().GetOAuthClientByClientID(ctx, clientID)
+	if err != nil {
+		return nil, err
+	}
+	if oc == nil || oc.AccountID != account.ID {
+		return nil, jsonrest.NotFound(fmt.Sprintf("oauth client not found, client_id=%s", clientID))
+	}
+	err = s.Repo().DeleteOAuthClientByIDAndAccountID(ctx, oc.ID, account.ID)
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return nil, jsonrest.NotFound(fmt.Sprintf("oauth client not found, client_id=%s", clientID))
+		}
+		return nil, err
+	}
+	return nil, nil
+}
+
+// authorizeParams are the parameters of an OAuth2 authorization request,
+// which the client sends to the consent screen.
+type authorizeParams struct {
+	ClientID            string `json:"client_id"`
+	CodeChallenge       string `json:"code_challenge"`
+	CodeChallengeMethod string `json:"code_challenge_method"`
+	RedirectURI         string `json:"redirect_uri"`
+	ResponseType        string `json:"response_type"`
+	Scope               string `json:"scope"`
+	State               string `json:"state"`
+}
+
+// checkAuthorization validates an authorization request, returning the client
+// and the requested scopes. If the request has no redirect URI, it's set to
+// the client's only redirect URI.
+func (s *Server) checkAuthorization(ctx context.Context, p *authorizeParams) (*domain.OAuthClient, []string, error) {
+	oc, err := s.Repo().GetOAuthClientByClientID(ctx, p.ClientID)
+	if err != nil {
+		return nil, nil, err
+	}
+	if oc == nil {
+		return nil, nil, jsonrest.Error(http.StatusBadRequest, "invalid_client", "unknown client")
+	}
+	if p.RedirectURI == "" && len(oc.RedirectURIs) == 1 {
+		p.RedirectURI = oc.RedirectURIs[0]
+	}
+	if !oc.HasRedirectURI(p.RedirectURI) {
+		return nil, nil, jsonrest.Error(http.StatusBadRequest, "invalid_redirect_uri", "redirect_uri isn't registered for the client")
+	}
+	if p.ResponseType != "code" {
+		return nil, nil, jsonrest.Error(http.StatusBadRequest, "unsupported_response_type", `response_type must be "code"`)
+	}
+	if p.CodeChallenge == "" || p.CodeChallengeMethod != "S256" {
+		return nil, nil, jsonrest.Error(http.StatusBadRequest, "invalid_request", "a PKCE code_challenge with code_challenge_method S256 is required")
+	}
+	scopes, err := domain.ParseScopes(p.Scope)
+	if err != nil {
+		return nil, nil, jsonrest.Error(http.StatusBadRequest, "invalid_scope", err.Error())
+	}
+	return oc, scopes, nil
+}
+
+// getOAuthConsent is GET /oauth/authorize
+//
+// It validates an authorization request and describes it, so the user can be
+// asked whether to allow it. The decision is sent to POST /oauth/authorize.
+func (s *Server) getOAuthConsent(ctx context.Context, req *jsonrest.Request) (interface{}, error) {
+	params := authorizeParams{
+		ClientID:            req.Query("client_id"),
+		CodeChallenge:       req.Query("code_challenge"),
+		CodeChallengeMethod: req.Query("code_challenge_method"),
+		RedirectURI:         req.Query("redirect_uri"),
+		ResponseType:        req.Query("response_type"),
+		Scope:               req.Query("scope"),
+		State:               req.Query("state"),
+	}
+	oc, scopes, err := s.checkAuthorization(ctx, &params)
+	if err != nil {
+		return nil, err
+	}
+	return s.Protocol().OAuthConsent(oc, params.RedirectURI, scopes, params.State), nil
+}
+
+type authorizeDecisionParams struct {
+	authorizeParams
+	Approve bool `json:"approve"`
+}
+
+// authorizeOAuthClient is POST /oauth/authorize
+//
+// It records the user's decision, and returns the URI to redirect the user
+// back to the client with.
+func (s *Server) authorizeOAuthClient(ctx context.Context, req *jsonrest.Request) (interface{}, error) {
+	account := req.Get(requestAccountKey{}).(*domain.Account)
+	var params authorizeDecisionParams
+	if err := req.BindBody(&params); err != nil {
+		return nil, err
+	}
+	oc, scopes, err := s.checkAuthorization(ctx, &params.authorizeParams)
+	if err != nil {
+		return nil, err
+	}
+	response := url.Values{}
+	if params.State != "" {
+		response.Set("state", params.State)
+	}
+	if !params.Approve {
+		response.Set("error", "access_denied")
+		return s.Protocol().OAuthRedirect(params.RedirectURI, response), nil
+	}
+	code := &domain.OAuthCode{
+		AccountID:   account.ID,
+		Challenge:   params.CodeChallenge,
+		ClientID:    oc.ID,
+		Expires:     time.Now().UTC().Add(s.cfg.OAuthCodeExpiry),
+		RedirectURI: params.RedirectURI,
+		Scopes:      scopes,
+	}
+	response.Set("code", code.NewCode())
+	if _, err := s.Repo().CreateOAuthCode(ctx, code); err != nil {
+		return nil, err
+	}
+	return s.Protocol().OAuthRedirect(params.RedirectURI, response), nil
+}
+
+// getOAuthAuthorizations is GET /account/authorizations
+func (s *Server) getOAuthAuthorizations(ctx context.Context, req *jsonrest.Request) (interface{}, error) {
+	account := req.Get(requestAccountKey{}).(*domain.Account)
+	clients, err := s.Repo().GetAuthorizedOAuthClientsByAccountID(ctx, account.ID)
+	if err != nil {
+		return nil, err
+	}
+	return s.Protocol().OAuthClientSummaries(clients), nil
+}
+
+// revokeOAuthAuthorization is DELETE /account/authorizations/:client_id
+func (s *Server) revokeOAuthAuthorization(ctx context.Context, req *jsonrest.Request) (interface{}, error) {
+	account := req.Get(requestAccountKey{}).(*domain.Account)
+	oc, err := s.Repo().GetOAuthClientByClientID(ctx, req.Param("client_id"))
+	if err != nil {
+		return nil, err
+	}
+	if oc == nil {
+		return nil, jsonrest.NotFound("oauth client not found")
+	}
+	return nil, s.Repo().RevokeOAuthAuthorization(ctx, oc.ID, account.ID)
+}
+
+// oauthError is an error response from the token or introspection endpoint,
+// in the format defined by RFC 6749 section 5.2.
+type oauthError struct {
+	status      int
+	code        string
+	description string
+}
+
+func (e *oauthError) Error() string {
+	return e.code + ": " + e.description
+}
+
+func invalidGrant(description string) error {
+	return &oauthError{http.StatusBadRequest, "invalid_grant", description}
+}
+
+// writeOAuthError writes err as an OAuth error response.
+func writeOAuthError(w http.ResponseWriter, err error) {
+	var oe *oauthError
+	if !errors.As(err, &oe) {
+		zap.L().Error("api.oauth", zap.Error(err))
+		oe = &oauthError{http.StatusInternalServerError, "server_error", "internal error"}
+	}
+	if oe.status == http.StatusUnauthorized {
+		w.Header().Set("WWW-Authenticate", `Basic realm="todo-api"`)
+	}
+	writeJSON(w, oe.status, map[string]string{
+		"error":             oe.code,
+		"error_description": oe.description,
+	})
+}
+
+// parseOAuthForm parses the form body of a request to the token or
+// introspection endpoint.
+func parseOAuthForm(w http.ResponseWriter, req *http.Request) error {
+	req.Body = http.MaxBytesReader(w, req.Body, maxOAuthFormSize)
+	if err := req.ParseForm(); err != nil {
+		return &oauthError{http.StatusBadRequest, "invalid_request", "invalid form body"}
+	}
+	return nil
+}
+
+// authenticateOAuthClient authenticates the client making a request to the
+// token or introspection endpoint, using either HTTP basic authentication or
+// the client_id and client_secret form parameters.
+func (s *Server) authenticateOAuthClient(ctx context.Context, req *http.Request) (*domain.OAuthClient, error) {
+	id, secret, ok := req.BasicAuth()
+	if ok {
+		// Credentials are form encoded before basic authentication.
+		id, _ = url.QueryUnescape(id)
+		secret, _ = url.QueryUnescape(secret)
+	} else {
+		id, secret = req.PostForm.Get("client_id"), req.PostForm.Get("client_secret")
+	}
+	invalid := &oauthError{http.StatusUnauthorized, "invalid_client", "client authentication failed"}
+	if id == "" {
+		return nil, invalid
+	}
+	oc, err := s.Repo().GetOAuthClientByClientID(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	if oc == nil || !oc.AuthenticateSecret(secret) {
+		return nil, invalid
+	}
+	return oc, nil
+}
+
+// oauthToken is POST /oauth/token
+func (s *Server) oauthToken(w http.ResponseWriter, req *http.Request) {
+	ctx := req.Context()
+	w.Header().Set("Cache-Control", "no-store")
+	w.Header().Set("Pragma", "no-cache")
+	if err := parseOAuthForm(w, req); err != nil {
+		writeOAuthError(w, err)
+		return
+	}
+	oc, err := s.authenticateOAuthClient(ctx, req)
+	if err != nil {
+		writeOAuthError(w, err)
+		return
+	}
+	var token *protocol.OAuthToken
+	switch grant := req.PostForm.Get("grant_type"); grant {
+	case "authorization_code":
+		token, err = s.exchangeOAuthCode(ctx, oc, req.PostForm)
+	case "refresh_token":
+		token, err = s.refreshOAuthToken(ctx, oc, req.PostForm)
+	default:
+		err = &oauthError{http.StatusBadRequest, "unsupported_grant_type", fmt.Sprintf("unsupported grant_type %q", grant)}
+	}
+	if err != nil {
+		writeOAuthError(w, err)
+		return
+	}
+	writeJSON(w, http.StatusOK, token)
+}
+
+// exchangeOAuthCode implements the authorization code grant.
+func (s *Server) exchangeOAuthCode(ctx context.Context, oc *domain.OAuthClient, form url.Values) (*protocol.OAuthToken, error) {
+	code, err := s.Repo().UseOAuthCode(ctx, domain.OAuthCodeDigest(form.Get("code")), oc.ID)
+	if err != nil {
+		return nil, err
+	}
+	if code == nil {
+		return nil, invalidGrant("invalid or expired code")
+	}
+	if form.Get("redirect_uri") != code.RedirectURI {
+		return nil, invalidGrant("redirect_uri doesn't match the authorization request")
+	}
+	if !code.VerifyChallenge(form.Get("code_verifier")) {
+		return nil, invalidGrant("invalid code_verifier")
+	}
+	return s.issueOAuthTokens(ctx, oc, code.AccountID, code.Scopes)
+}
+
+// refreshOAuthToken implements the refresh token grant. The scopes may be
+// narrowed, but not widened.
+func (s *Server) refreshOAuthToken(ctx context.Context, oc *domain.OAuthClient, form url.Values) (*protocol.OAuthToken, error) {
+	rt, err := s.Repo().UseOAuthRefreshToken(ctx, domain.OAuthRefreshTokenDigest(form.Get("refresh_token")), oc.ID)
+	if err != nil {
+		return nil, err
+	}
+	if rt == nil {
+		return nil, invalidGrant("invalid or expired refresh token")
+	}
+	scopes := rt.Scopes
+	if form.Get("scope") != "" {
+		requested, err := domain.ParseScopes(form.Get("scope"))
+		if err != nil {
+			return nil, &oauthError{http.StatusBadRequest, "invalid_scope", err.Error()}
+		}
+		granted := domain.AccessToken{Scopes: rt.Scopes}
+		for _, scope := range requested {
+			if !granted.HasScope(scope) {
+				return nil, &oauthError{http.StatusBadRequest, "invalid_scope", fmt.Sprintf("scope %q wasn't authorized", scope)}
+			}
+		}
+		scopes = requested
+	}
+	return s.issueOAuthTokens(ctx, oc, rt.AccountID, scopes)
+}
+
+// issueOAuthTokens issues a new access token and refresh token to a client.
+func (s *Server) issueOAuthTokens(ctx context.Context, oc *domain.OAuthClient, accountID int64, scopes []string) (*protocol.OAuthToken, error) {
+	account, err := s.Accounts().GetAccountByID(ctx, accountID)
+	if err != nil {
+		return nil, err
+	}
+	if account == nil || account.Suspended() {
+		return nil, invalidGrant("the account is unavailable")
+	}
+	now := time.Now().UTC()
+	expires := now.Add(s.cfg.OAuthAccessTokenExpiry)
+	at := &domain.AccessToken{
+		AccountID: accountID,
+		ClientID:  &oc.ID,
+		Name:      oc.Name,
+		Scopes:    scopes,
+		Expires:   &expires,
+	}
+	accessToken := at.NewToken()
+	if _, err := s.Repo().CreateAccessToken(ctx, at); err != nil {
+		return nil, err
+	}
+	rt := &domain.OAuthRefreshToken{
+		AccountID: accountID,
+		ClientID:  oc.ID,
+		Expires:   now.Add(s.cfg.OAuthRefreshTokenExpiry),
+		Scopes:    scopes,
+	}
+	refreshToken := rt.NewToken()
+	if _, err := s.Repo().CreateOAuthRefreshToken(ctx, rt); err != nil {
+		return nil, err
+	}
+	token := s.Protocol().OAuthToken(accessToken, s.cfg.OAuthAccessTokenExpiry, refreshToken, scopes)
+	return &token, nil
+}
+
+// introspectOAuthToken is POST /oauth/introspect
+//
+// It implements RFC 7662 token introspection. Clients may only introspect
+// access tokens which were issued to them.
+func (s *Server) introspectOAuthToken(w http.ResponseWriter, req *http.Request) {
+	ctx := req.Context()
+	if err := parseOAuthForm(w, req); err != nil {
+		writeOAuthError(w, err)
+		return
+	}
+	oc, err := s.authenticateOAuthClient(ctx, req)
+	if err != nil {
+		writeOAuthError(w, err)
+		return
+	}
+	result, err := s.introspect(ctx, oc, req.PostForm.Get("token"))
+	if err != nil {
+		writeOAuthError(w, err)
+		return
+	}
+	writeJSON(w, http.StatusOK, result)
+}
+
+func (s *Server) introspect(ctx context.Context, oc *domain.OAuthClient, token string) (protocol.OAuthIntrospection, error) {
+	inactive := protocol.OAuthIntrospection{}
+	if !domain.IsAccessToken(token) {
+		return inactive, nil
+	}
+	at, err := s.Repo().GetAccessTokenByDigest(ctx, domain.AccessTokenDigest(token))
+	if err != nil {
+		return inactive, err
+	}
+	if at == nil || at.Expired(time.Now()) || at.ClientID == nil || *at.ClientID != oc.ID {
+		return inactive, nil
+	}
+	account, err := s.Accounts().GetAccountByID(ctx, at.AccountID)
+	if err != nil {
+		return inactive, err
+	}
+	if account == nil || account.Suspended() {
+		return inactive, nil
+	}
+	return s.Protocol().OAuthIntrospection(at, oc, account), nil
+}
@@ -0,0 +1,192 @@
+package api
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/deliveroo/jsonrest-go"
+	"github.com/deliveroo/todo-api/domain"
+	"github.com/deliveroo/todo-api/pkg/oidc"
+	"github.com/deliveroo/todo-api/repo"
+	"github.com/deliveroo/todo-api/service/session"
+	"go.uber.org/zap"
+)
+
+// usernameAttempts is how many usernames are tried when creating an account
+// for a new external identity, before falling back to a random suffix.
+const usernameAttempts = 5
+
+// startOIDC is GET /auth/oidc/start
+//
+// It redirects to the identity provider, which redirects back to
+// GET /auth/oidc/callback once the user has logged in.
+func (s *Server) startOIDC(w http.ResponseWriter, req *http.Request) {
+	ctx := req.Context()
+	if s.cfg.OIDC == nil {
+		writeError(w, http.StatusNotFound, "not_found", "login with an identity provider isn't configured")
+		return
+	}
+	authReq := &session.AuthRequest{
+		Nonce:    oidc.NewState(),
+		Verifier: oidc.NewVerifier(),
+	}
+	state, err := s.Sessions().NewAuthRequest(ctx, authReq)
+	if err != nil {
+		zap.L().Error("api.startOIDC", zap.Error(err))
+		writeError(w, http.StatusInternalServerError, "internal_error", "could not start login")
+		return
+	}
+	authURL, err := s.cfg.OIDC.AuthCodeURL(ctx, state, authReq.Nonce, authReq.Verifier)
+	if err != nil {
+		zap.L().Error("api.startOIDC", zap.Error(err))
+		writeError(w, http.StatusBadGateway, "oidc_unavailable", "the identity provider is unavailable")
+		return
+	}
+	http.Redirect(w, req, authURL, http.StatusFound)
+}
+
+// loginOIDC is GET /auth/oidc/callback
+func (s *Server) loginOIDC(ctx context.Context, req *jsonrest.Request) (interface{}, error) {
+	if s.cfg.OIDC == nil {
+		return nil, jsonrest.NotFound("login with an identity provider isn't configured")
+	}
+	if e := req.Query("error"); e != "" {
+		return nil, jsonrest.BadRequest(fmt.Sprintf("identity provider error: %s", e))
+	}
+	authReq, err := s.Sessions().ConsumeAuthRequest(ctx, req.Query("state"))
+	if err != nil {
+		return nil, jsonrest.BadRequest("invalid or expired state")
+	}
+	claims, err := s.cfg.OIDC.Exchange(ctx, req.Query("code"), authReq.Verifier, authReq.Nonce)
+	if err != nil {
+		zap.L().Warn("api.loginOIDC", zap.Error(err))
+		return nil, jsonrest.BadRequest("could not log in with the identity provider")
+	}
+	account, err := s.identityAccount(ctx, claims)
+	if err != nil {
+		return nil, err
+	}
+	// Two-factor authentication is the identity provider's responsibility,
+	// so there's no TOTP challenge here.
+	return s.newSession(ctx, account)
+}
+
+// identityAccount returns the account linked to an external identity,
+// creating one if the identity is new and signup is allowed.
+func (s *Server) identityAccount(ctx context.Context, claims *oidc.Claims) (*domain.Account, error) {
+	ident, err := s.Repo().GetAccountIdentity(ctx, claims.Issuer, claims.Subject)
+	if err != nil {
+		return nil, err
+	}
+	if ident == nil {
+		if !s.cfg.OIDCSignup {
+			return nil, jsonrest.Error(http.StatusForbidden, "signup_disabled", "no account is linked to this identity")
+		}
+		ident, err = s.createIdentityAccount(ctx, claims)
+		if err != nil {
+			return nil, err
+		}
+	} else if ident.Email != claims.Email {
+		if err := s.Repo().UpdateAccountIdentityEmail(ctx, ident.ID, claims.Email); err != nil {
+			return nil, err
+		}
+	}
+	account, err := s.Accounts().GetAccountByID(ctx, ident.AccountID)
+	if err != nil {
+		return nil, err
+	}
+	if account == nil {
+		return nil, jsonrest.Unauthorized("unauthorized")
+	}
+	return account, nil
+}
+
+// createIdentityAccount creates an account for a new external identity, and
+// links them. The account gets a random password, which can be changed with
+// a password reset if it has an email address. An email address is required
+// if unverified accounts are restricted, since without a known password the
+// account couldn't add one later.
+func (s *Server) createIdentityAccount(ctx context.Context, claims *oidc.Claims) (*domain.AccountIdentity, error) {
+	account := &domain.Account{}
+	if err := account.SetPassword(oidc.NewState()); err != nil {
+		return nil, err
+	}
+	// The email address is only trusted if the provider verified it. It's
+	// never used to link an existing account, since that would let anyone
+	// who can register the address with the provider take the account over.
+	if claims.EmailVerified && validateEmail(claims.Email) == nil {
+		now := time.Now().UTC()
+		account.Email = claims.Email
+		account.VerifiedAt = &now
+	}
+	if account.Email == "" && s.emailRequired() {
+		return nil, jsonrest.Error(http.StatusForbidden, "email_required", "the identity provider didn't give a verified email address")
+	}
+	base := identityUsername(claims)
+	for i := 0; ; i++ {
+		switch {
+		case i == 0:
+			account.Username = base
+		case i < usernameAttempts:
+			account.Username = fmt.Sprintf("%s-%d", base, i+1)
+		default:
+			account.Username = base + "-" + strings.ToLower(oidc.NewState()[:8])
+		}
+		created, err := s.Accounts().CreateAccount(ctx, account)
+		if errors.Is(err, repo.ErrUsernameTaken) && i <= usernameAttempts {
+			continue
+		}
+		if errors.Is(err, repo.ErrEmailTaken) {
+			if s.emailRequired() {
+				return nil, emailTaken()
+			}
+			account.Email, account.VerifiedAt = "", nil
+			continue
+		}
+		if err != nil {
+			return nil, err
+		}
+		account = created
+		break
+	}
+	ident, err := s.Repo().CreateAccountIdentity(ctx, &domain.AccountIdentity{
+		AccountID: account.ID,
+		Issuer:    claims.Issuer,
+		Subject:   claims.Subject,
+		Email:     claims.Email,
+	})
+	if errors.Is(err, repo.ErrIdentityLinked) {
+		// A concurrent login created an account first, so use that one.
+		if err := s.Accounts().DeleteAccount(ctx, account.ID); err != nil {
+			return nil, err
+		}
+		return s.Repo().GetAccountIdentity(ctx, claims.Issuer, claims.Subject)
+	}
+	return ident, err
+}
+
+// identityUsername chooses a username for an external identity.
+func identityUsername(claims *oidc.Claims) string {
+	switch {
+	case claims.PreferredUsername != "":
+		return claims.PreferredUsername
+	case claims.Email != "":
+		return strings.SplitN(claims.Email, "@", 2)[0]
+	default:
+		return "user"
+	}
+}
+
+// getAccountIdentities is GET /account/identities
+func (s *Server) getAccountIdentities(ctx context.Context, req *jsonrest.Request) (interface{}, error) {
+	account := req.Get(requestAccountKey{}).(*domain.Account)
+	identities, err := s.Repo().GetAllAccountIdentitiesByAccountID(ctx, account.ID)
+	if err != nil {
+		return nil, err
+	}
+	return s.Protocol().AccountIdentities(identities), nil
+}
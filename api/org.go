@@ -0,0 +1,398 @@
+package api
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+
+	"github.com/deliveroo/jsonrest-go"
+	"github.com/deliveroo/todo-api/domain"
+	"github.com/deliveroo/todo-api/repo"
+	"github.com/jackc/pgx/v4"
+)
+
+type orgParams struct {
+	Name string `json:"name"`
+	Slug string `json:"slug"` // only used when creating an organisation
+}
+
+func (p orgParams) validate() error {
+	if len(p.Name) == 0 {
+		return errors.New("name is required")
+	}
+	return nil
+}
+
+type orgMemberParams struct {
+	Role string `json:"role"`
+}
+
+func (p orgMemberParams) validate() error {
+	if !domain.ValidOrgMemberRole(p.Role) {
+		return fmt.Errorf("role must be %q or %q", domain.OrgRoleAdmin, domain.OrgRoleMember)
+	}
+	return nil
+}
+
+type orgOwnerParams struct {
+	AccountID string `json:"account_id"`
+}
+
+func (p orgOwnerParams) validate() error {
+	if !domain.ValidPublicID(p.AccountID) {
+		return errors.New("account_id is not a valid id")
+	}
+	return nil
+}
+
+type orgInvitationParams struct {
+	orgMemberParams
+	Username string `json:"username"`
+}
+
+func (p orgInvitationParams) validate() error {
+	if len(p.Username) == 0 {
+		return errors.New("username is required")
+	}
+	return p.orgMemberParams.validate()
+}
+
+// orgAdminRequired is returned when a member who isn't an owner or admin
+// tries to manage an organisation.
+func orgAdminRequired() error {
+	return jsonrest.Error(http.StatusForbidden, "org_admin_required", "only the organisation's owner and admins can do this")
+}
+
+// orgOwnerRequired is returned when an account which doesn't own an
+// organisation tries to do something only its owner can.
+func orgOwnerRequired() error {
+	return jsonrest.Error(http.StatusForbidden, "org_owner_required", "only the organisation's owner can do this")
+}
+
+// managedOrg returns the request's organisation, if the account can manage
+// it.
+func managedOrg(req *jsonrest.Request) (*domain.Org, error) {
+	org := req.Get(requestOrgKey{}).(*domain.Org)
+	if !domain.CanManageOrg(org.Role) {
+		return nil, orgAdminRequired()
+	}
+	return org, nil
+}
+
+// orgMember returns the member identified by the :account_id parameter.
+func (s *Server) orgMember(ctx context.Context, req *jsonrest.Request, org *domain.Org) (*domain.OrgMember, error) {
+	memberID, err := s.paramAccountID(ctx, req, "account_id")
+	if err != nil {
+		return nil, err
+	}
+	m, err := s.Repo().GetOrgMember(ctx, org.ID, memberID)
+	if err != nil {
+		return nil, err
+	}
+	if m == nil {
+		return nil, jsonrest.NotFound(fmt.Sprintf("organisation member not found, account_id=%s", req.Param("account_id")))
+	}
+	return m, nil
+}
+
+// createOrg is POST /orgs
+//
+// The account which creates an organisation is its owner.
+func (s *Server) createOrg(ctx context.Context, req *jsonrest.Request) (interface{}, error) {
+	account := req.Get(requestAccountKey{}).(*domain.Account)
+	var params orgParams
+	if err := req.BindBody(&params); err != nil {
+		return nil, err
+	}
+	if err := params.validate(); err != nil {
+		return nil, jsonrest.BadRequest(err.Error())
+	}
+	if err := domain.ValidateOrgSlug(params.Slug); err != nil {
+		return nil, jsonrest.BadRequest(err.Error())
+	}
+	org, err := s.Repo().CreateOrg(ctx, &domain.Org{
+		Name: params.Name,
+		Slug: params.Slug,
+	}, account.ID)
+	if err != nil {
+		if errors.Is(err, repo.ErrOrgSlugTaken) {
+			return nil, jsonrest.Error(http.StatusConflict, "slug_taken", "the slug is already taken")
+		}
+		return nil, err
+	}
+	return s.Protocol().Org(org), nil
+}
+
+// getAllOrgs is GET /orgs
+func (s *Server) getAllOrgs(ctx context.Context, req *jsonrest.Request) (interface{}, error) {
+	account := req.Get(requestAccountKey{}).(*domain.Account)
+	orgs, err := s.Repo().GetAllOrgsForAccount(ctx, account.ID)
+	if err != nil {
+		return nil, err
+	}
+	return s.Protocol().Orgs(orgs), nil
+}
+
+// getOrg is GET /orgs/:org
+func (s *Server) getOrg(ctx context.Context, req *jsonrest.Request) (interface{}, error) {
+	org := req.Get(requestOrgKey{}).(*domain.Org)
+	return s.Protocol().Org(org), nil
+}
+
+// updateOrg is PUT /orgs/:org
+func (s *Server) updateOrg(ctx context.Context, req *jsonrest.Request) (interface{}, error) {
+	var params orgParams
+	if err := req.BindBody(&params); err != nil {
+		return nil, err
+	}
+	if err := params.validate(); err != nil {
+		return nil, jsonrest.BadRequest(err.Error())
+	}
+	org, err := managedOrg(req)
+	if err != nil {
+		return nil, err
+	}
+	org.Name = params.Name
+	org, err = s.Repo().UpdateOrgName(ctx, org)
+	if err != nil {
+		return nil, err
+	}
+	return s.Protocol().Org(org), nil
+}
+
+// deleteOrg is DELETE /orgs/:org
+//
+// It deletes the organisation's tasks too.
+func (s *Server) deleteOrg(ctx context.Context, req *jsonrest.Request) (interface{}, error) {
+	org := req.Get(requestOrgKey{}).(*domain.Org)
+	if org.Role != domain.OrgRoleOwner {
+		return nil, orgOwnerRequired()
+	}
+	return nil, s.Repo().DeleteOrg(ctx, org.ID)
+}
+
+// getOrgMembers is GET /orgs/:org/members
+func (s *Server) getOrgMembers(ctx context.Context, req *jsonrest.Request) (interface{}, error) {
+	org := req.Get(requestOrgKey{}).(*domain.Org)
+	members, err := s.Repo().GetOrgMembers(ctx, org.ID)
+	if err != nil {
+		return nil, err
+	}
+	return s.Protocol().OrgMembers(members), nil
+}
+
+// updateOrgMember is PUT /orgs/:org/members/:account_id
+//
+// Only the owner can make or unmake admins, and the owner's role can't be
+// changed.
+func (s *Server) updateOrgMember(ctx context.Context, req *jsonrest.Request) (interface{}, error) {
+	var params orgMemberParams
+	if err := req.BindBody(&params); err != nil {
+		return nil, err
+	}
+	if err := params.validate(); err != nil {
+		return nil, jsonrest.BadRequest(err.Error())
+	}
+	org, err := managedOrg(req)
+	if err != nil {
+		return nil, err
+	}
+	m, err := s.orgMember(ctx, req, org)
+	if err != nil {
+		return nil, err
+	}
+	if m.Role == domain.OrgRoleOwner {
+		return nil, jsonrest.BadRequest("the owner's role can't be changed")
+	}
+	if org.Role != domain.OrgRoleOwner && (m.Role == domain.OrgRoleAdmin || params.Role == domain.OrgRoleAdmin) {
+		return nil, orgOwnerRequired()
+	}
+	if err := s.Repo().UpdateOrgMemberRole(ctx, org.ID, m.AccountID, params.Role); err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return nil, jsonrest.NotFound(fmt.Sprintf("organisation member not found, account_id=%s", m.AccountPublicID))
+		}
+		return nil, err
+	}
+	return nil, nil
+}
+
+// transferOrg is PUT /orgs/:org/owner
+//
+// The owner can hand the organisation to another member, and becomes an admin.
+func (s *Server) transferOrg(ctx context.Context, req *jsonrest.Request) (interface{}, error) {
+	account := req.Get(requestAccountKey{}).(*domain.Account)
+	var params orgOwnerParams
+	if err := req.BindBody(&params); err != nil {
+		return nil, err
+	}
+	if err := params.validate(); err != nil {
+		return nil, jsonrest.BadRequest(err.Error())
+	}
+	org := req.Get(requestOrgKey{}).(*domain.Org)
+	if org.Role != domain.OrgRoleOwner {
+		return nil, orgOwnerRequired()
+	}
+	if params.AccountID == account.PublicID {
+		return nil, jsonrest.BadRequest("you already own the organisation")
+	}
+	notFound := jsonrest.NotFound(fmt.Sprintf("organisation member not found, account_id=%s", params.AccountID))
+	newOwner, err := s.Accounts().GetAccountByPublicID(ctx, params.AccountID)
+	if err != nil {
+		return nil, err
+	}
+	if newOwner == nil {
+		return nil, notFound
+	}
+	if err := s.Repo().TransferOrgOwnership(ctx, org.ID, account.ID, newOwner.ID); err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return nil, notFound
+		}
+		return nil, err
+	}
+	return nil, nil
+}
+
+// deleteOrgMember is DELETE /orgs/:org/members/:account_id
+//
+// The owner and admins can remove members, though only the owner can remove
+// admins, and members can remove themselves to leave the organisation. The
+// owner can't leave.
+func (s *Server) deleteOrgMember(ctx context.Context, req *jsonrest.Request) (interface{}, error) {
+	account := req.Get(requestAccountKey{}).(*domain.Account)
+	org := req.Get(requestOrgKey{}).(*domain.Org)
+	m, err := s.orgMember(ctx, req, org)
+	if err != nil {
+		return nil, err
+	}
+	if m.Role == domain.OrgRoleOwner {
+		return nil, jsonrest.BadRequest("the owner can't be removed")
+	}
+	if m.AccountID != account.ID {
+		if !domain.CanManageOrg(org.Role) {
+			return nil, orgAdminRequired()
+		}
+		if m.Role == domain.OrgRoleAdmin && org.Role != domain.OrgRoleOwner {
+			return nil, orgOwnerRequired()
+		}
+	}
+	if err := s.Repo().DeleteOrgMember(ctx, org.ID, m.AccountID); err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return nil, jsonrest.NotFound(fmt.Sprintf("organisation member not found, account_id=%s", m.AccountPublicID))
+		}
+		return nil, err
+	}
+	return nil, nil
+}
+
+// createOrgInvitation is POST /orgs/:org/invitations
+//
+// Only the owner can invite admins.
+func (s *Server) createOrgInvitation(ctx context.Context, req *jsonrest.Request) (interface{}, error) {
+	account := req.Get(requestAccountKey{}).(*domain.Account)
+	var params orgInvitationParams
+	if err := req.BindBody(&params); err != nil {
+		return nil, err
+	}
+	if err := params.validate(); err != nil {
+		return nil, jsonrest.BadRequest(err.Error())
+	}
+	org, err := managedOrg(req)
+	if err != nil {
+		return nil, err
+	}
+	if params.Role == domain.OrgRoleAdmin && org.Role != domain.OrgRoleOwner {
+		return nil, orgOwnerRequired()
+	}
+	invitee, err := s.Accounts().GetAccountByUsername(ctx, params.Username)
+	if err != nil {
+		return nil, err
+	}
+	if invitee == nil {
+		return nil, jsonrest.NotFound("account not found")
+	}
+	if invitee.ID == account.ID {
+		return nil, jsonrest.BadRequest("you can't invite yourself")
+	}
+	i, err := s.Repo().CreateOrgInvitation(ctx, &domain.OrgInvitation{
+		OrgID:     org.ID,
+		InviterID: account.ID,
+		InviteeID: invitee.ID,
+		Role:      params.Role,
+	})
+	if err != nil {
+		if errors.Is(err, repo.ErrInvitationPending) {
+			return nil, jsonrest.Error(http.StatusConflict, "invitation_pending", "the account has already been invited")
+		}
+		return nil, err
+	}
+	return s.Protocol().OrgInvitation(i), nil
+}
+
+// getOrgInvitations is GET /orgs/:org/invitations
+func (s *Server) getOrgInvitations(ctx context.Context, req *jsonrest.Request) (interface{}, error) {
+	org, err := managedOrg(req)
+	if err != nil {
+		return nil, err
+	}
+	invitations, err := s.Repo().GetPendingOrgInvitationsByOrgID(ctx, org.ID)
+	if err != nil {
+		return nil, err
+	}
+	return s.Protocol().OrgInvitations(invitations), nil
+}
+
+// deleteOrgInvitation is DELETE /orgs/:org/invitations/:invitation_id
+func (s *Server) deleteOrgInvitation(ctx context.Context, req *jsonrest.Request) (interface{}, error) {
+	org, err := managedOrg(req)
+	if err != nil {
+		return nil, err
+	}
+	id, err := paramPublicID(req, "invitation_id")
+	if err != nil {
+		return nil, err
+	}
+	if err := s.Repo().DeleteOrgInvitationByPublicIDAndOrgID(ctx, id, org.ID); err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return nil, jsonrest.NotFound(fmt.Sprintf("invitation not found, id=%s", id))
+		}
+		return nil, err
+	}
+	return nil, nil
+}
+
+// getAccountOrgInvitations is GET /account/org-invitations
+func (s *Server) getAccountOrgInvitations(ctx context.Context, req *jsonrest.Request) (interface{}, error) {
+	account := req.Get(requestAccountKey{}).(*domain.Account)
+	invitations, err := s.Repo().GetPendingOrgInvitationsByInviteeID(ctx, account.ID)
+	if err != nil {
+		return nil, err
+	}
+	return s.Protocol().OrgInvitations(invitations), nil
+}
+
+// acceptOrgInvitation is POST /account/org-invitations/:id/accept
+func (s *Server) acceptOrgInvitation(ctx context.Context, req *jsonrest.Request) (interface{}, error) {
+	return s.respondToOrgInvitation(ctx, req, true)
+}
+
+// declineOrgInvitation is POST /account/org-invitations/:id/decline
+func (s *Server) declineOrgInvitation(ctx context.Context, req *jsonrest.Request) (interface{}, error) {
+	return s.respondToOrgInvitation(ctx, req, false)
+}
+
+func (s *Server) respondToOrgInvitation(ctx context.Context, req *jsonrest.Request, accept bool) (interface{}, error) {
+	account := req.Get(requestAccountKey{}).(*domain.Account)
+	id, err := paramPublicID(req, "id")
+	if err != nil {
+		return nil, err
+	}
+	i, err := s.Repo().RespondToOrgInvitation(ctx, id, account.ID, accept)
+	if err != nil {
+		return nil, err
+	}
+	if i == nil {
+		return nil, jsonrest.NotFound(fmt.Sprintf("pending invitation not found, id=%s", id))
+	}
+	return s.Protocol().OrgInvitation(i), nil
+}
@@ -0,0 +1,36 @@
+package api
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/deliveroo/jsonrest-go"
+	"github.com/deliveroo/todo-api/domain"
+)
+
+// paramPublicID returns a route parameter holding the public id of a
+// resource, such as a task or a webhook, returning a bad request error if
+// it's malformed. Database ids aren't accepted in their place.
+func paramPublicID(req *jsonrest.Request, name string) (string, error) {
+	id := req.Param(name)
+	if !domain.ValidPublicID(id) {
+		return "", jsonrest.BadRequest(fmt.Sprintf("%s is not a valid id", name))
+	}
+	return id, nil
+}
+
+// paramAccountID resolves a route parameter holding the public id of an
+// account to the account's database id, or zero if there's no such account,
+// which matches no rows. It returns a bad request error if the id is
+// malformed.
+func (s *Server) paramAccountID(ctx context.Context, req *jsonrest.Request, name string) (int64, error) {
+	publicID, err := paramPublicID(req, name)
+	if err != nil {
+		return 0, err
+	}
+	account, err := s.Accounts().GetAccountByPublicID(ctx, publicID)
+	if err != nil || account == nil {
+		return 0, err
+	}
+	return account.ID, nil
+}
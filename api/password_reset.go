@@ -0,0 +1,116 @@
+package api
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/deliveroo/jsonrest-go"
+	"github.com/deliveroo/todo-api/domain"
+	"github.com/deliveroo/todo-api/service/mail"
+)
+
+type passwordResetParams struct {
+	Email string `json:"email"`
+}
+
+type confirmPasswordResetParams struct {
+	Token    string `json:"token"`
+	Password string `json:"password"`
+}
+
+// requestPasswordReset is POST /account/password-reset
+//
+// The response is the same whether or not an account has the email address,
+// so that it can't be used to discover which addresses are registered. Tokens
+// are only sent to verified addresses, since anyone could have given an
+// unverified one.
+func (s *Server) requestPasswordReset(ctx context.Context, req *jsonrest.Request) (interface{}, error) {
+	var params passwordResetParams
+	if err := req.BindBody(&params); err != nil {
+		return nil, err
+	}
+	if params.Email == "" {
+		return nil, jsonrest.BadRequest("email is required")
+	}
+	if err := validateEmail(params.Email); err != nil {
+		return nil, jsonrest.BadRequest(err.Error())
+	}
+	account, err := s.Accounts().GetAccountByEmail(ctx, params.Email)
+	if err != nil {
+		return nil, err
+	}
+	if account == nil || !account.Verified() {
+		return nil, nil
+	}
+	if err := s.sendPasswordReset(ctx, account); err != nil {
+		return nil, err
+	}
+	return nil, nil
+}
+
+// sendPasswordReset emails a password reset token to an account's email
+// address, which must have been verified.
+func (s *Server) sendPasswordReset(ctx context.Context, account *domain.Account) error {
+	reset := &domain.PasswordReset{
+		AccountID: account.ID,
+		Expires:   time.Now().UTC().Add(s.cfg.PasswordResetExpiry),
+	}
+	token := reset.NewToken()
+	if _, err := s.Repo().CreatePasswordReset(ctx, reset); err != nil {
+		return err
+	}
+	return s.Mailer().Send(ctx, &mail.Message{
+		To:      account.Email,
+		Subject: "Reset your todo-api password",
+		Body: fmt.Sprintf(`Hi %s,
+
+Someone asked to reset the password for your todo-api account. If it was you,
+use this token to choose a new password within %s:
+
+%s
+
+If it wasn't you, you can ignore this email.
+`, account.Username, s.cfg.PasswordResetExpiry, token),
+	})
+}
+
+// confirmPasswordReset is POST /account/password-reset/confirm
+func (s *Server) confirmPasswordReset(ctx context.Context, req *jsonrest.Request) (interface{}, error) {
+	var params confirmPasswordResetParams
+	if err := req.BindBody(&params); err != nil {
+		return nil, err
+	}
+	if err := validatePassword(params.Password); err != nil {
+		return nil, jsonrest.BadRequest(err.Error())
+	}
+	reset, err := s.Repo().UsePasswordReset(ctx, domain.PasswordResetDigest(params.Token))
+	if err != nil {
+		return nil, err
+	}
+	if reset == nil {
+		return nil, jsonrest.BadRequest("invalid or expired token")
+	}
+	account, err := s.Accounts().GetAccountByID(ctx, reset.AccountID)
+	if err != nil {
+		return nil, err
+	}
+	if account == nil {
+		return nil, jsonrest.BadRequest("invalid or expired token")
+	}
+	if err := account.SetPassword(params.Password); err != nil {
+		return nil, err
+	}
+	if _, err := s.Accounts().UpdateAccountPassword(ctx, account); err != nil {
+		return nil, err
+	}
+	// Whoever knew the old password shouldn't stay signed in, and the owner
+	// shouldn't stay locked out by their own failed attempts.
+	if err := s.Sessions().RevokeAll(ctx, account.ID); err != nil {
+		return nil, err
+	}
+	if err := s.resetLoginThrottle(ctx, account.Username); err != nil {
+		return nil, err
+	}
+	return nil, nil
+}
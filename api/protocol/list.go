@@ -0,0 +1,91 @@
+package protocol
+
+import (
+	"time"
+
+	"github.com/deliveroo/todo-api/domain"
+)
+
+type List struct {
+	ID      string    `json:"id"`
+	Created time.Time `json:"created"`
+	Name    string    `json:"name"`
+	OwnerID string    `json:"owner_id"`
+	Role    string    `json:"role"`
+}
+
+type ListMember struct {
+	AccountID string    `json:"account_id"`
+	Created   time.Time `json:"created"`
+	Role      string    `json:"role"`
+	Username  string    `json:"username"`
+}
+
+type ListInvitation struct {
+	ID        string     `json:"id"`
+	Created   time.Time  `json:"created"`
+	Invitee   string     `json:"invitee"`
+	Inviter   string     `json:"inviter"`
+	ListID    string     `json:"list_id"`
+	ListName  string     `json:"list_name"`
+	Responded *time.Time `json:"responded"`
+	Role      string     `json:"role"`
+	Status    string     `json:"status"`
+}
+
+func (p P) List(v *domain.List) List {
+	return List{
+		ID:      v.PublicID,
+		Created: v.Created,
+		Name:    v.Name,
+		OwnerID: v.AccountPublicID,
+		Role:    v.Role,
+	}
+}
+
+func (p P) Lists(vv []*domain.List) []List {
+	result := make([]List, 0, len(vv))
+	for _, v := range vv {
+		result = append(result, p.List(v))
+	}
+	return result
+}
+
+func (p P) ListMember(v *domain.ListMember) ListMember {
+	return ListMember{
+		AccountID: v.AccountPublicID,
+		Created:   v.Created,
+		Role:      v.Role,
+		Username:  v.Username,
+	}
+}
+
+func (p P) ListMembers(vv []*domain.ListMember) []ListMember {
+	result := make([]ListMember, 0, len(vv))
+	for _, v := range vv {
+		result = append(result, p.ListMember(v))
+	}
+	return result
+}
+
+func (p P) ListInvitation(v *domain.ListInvitation) ListInvitation {
+	return ListInvitation{
+		ID:        v.PublicID,
+		Created:   v.Created,
+		Invitee:   v.InviteeUsername,
+		Inviter:   v.InviterUsername,
+		ListID:    v.ListPublicID,
+		ListName:  v.ListName,
+		Responded: v.Responded,
+		Role:      v.Role,
+		Status:    v.Status,
+	}
+}
+
+func (p P) ListInvitations(vv []*domain.ListInvitation) []ListInvitation {
+	result := make([]ListInvitation, 0, len(vv))
+	for _, v := range vv {
+		result = append(result, p.ListInvitation(v))
+	}
+	return result
+}
@@ -0,0 +1,152 @@
+package protocol
+
+import (
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/deliveroo/todo-api/domain"
+)
+
+type OAuthClient struct {
+	ClientID     string    `json:"client_id"`
+	Confidential bool      `json:"confidential"`
+	Created      time.Time `json:"created"`
+	Name         string    `json:"name"`
+	RedirectURIs []string  `json:"redirect_uris"`
+}
+
+type NewOAuthClient struct {
+	OAuthClient
+	ClientSecret string `json:"client_secret,omitempty"`
+}
+
+// OAuthClientSummary describes a client to accounts other than its owner.
+type OAuthClientSummary struct {
+	ClientID string `json:"client_id"`
+	Name     string `json:"name"`
+}
+
+type OAuthConsent struct {
+	Client      OAuthClientSummary `json:"client"`
+	RedirectURI string             `json:"redirect_uri"`
+	Scopes      []string           `json:"scopes"`
+	State       string             `json:"state"`
+}
+
+type OAuthRedirect struct {
+	RedirectURI string `json:"redirect_uri"`
+}
+
+// OAuthToken is a token endpoint response, as defined by RFC 6749.
+type OAuthToken struct {
+	AccessToken  string `json:"access_token"`
+	ExpiresIn    int64  `json:"expires_in"`
+	RefreshToken string `json:"refresh_token"`
+	Scope        string `json:"scope"`
+	TokenType    string `json:"token_type"`
+}
+
+// OAuthIntrospection is an introspection endpoint response, as defined by
+// RFC 7662. Only Active is set for inactive tokens.
+type OAuthIntrospection struct {
+	Active    bool   `json:"active"`
+	ClientID  string `json:"client_id,omitempty"`
+	Exp       int64  `json:"exp,omitempty"`
+	Iat       int64  `json:"iat,omitempty"`
+	Scope     string `json:"scope,omitempty"`
+	Sub       string `json:"sub,omitempty"`
+	TokenType string `json:"token_type,omitempty"`
+	Username  string `json:"username,omitempty"`
+}
+
+func (p P) OAuthClient(v *domain.OAuthClient) OAuthClient {
+	return OAuthClient{
+		ClientID:     v.ClientID,
+		Confidential: v.Confidential(),
+		Created:      v.Created,
+		Name:         v.Name,
+		RedirectURIs: v.RedirectURIs,
+	}
+}
+
+func (p P) OAuthClients(vv []*domain.OAuthClient) []OAuthClient {
+	result := make([]OAuthClient, 0, len(vv))
+	for _, v := range vv {
+		result = append(result, p.OAuthClient(v))
+	}
+	return result
+}
+
+func (p P) NewOAuthClient(v *domain.OAuthClient, secret string) NewOAuthClient {
+	return NewOAuthClient{
+		OAuthClient:  p.OAuthClient(v),
+		ClientSecret: secret,
+	}
+}
+
+func (p P) OAuthClientSummary(v *domain.OAuthClient) OAuthClientSummary {
+	return OAuthClientSummary{
+		ClientID: v.ClientID,
+		Name:     v.Name,
+	}
+}
+
+func (p P) OAuthClientSummaries(vv []*domain.OAuthClient) []OAuthClientSummary {
+	result := make([]OAuthClientSummary, 0, len(vv))
+	for _, v := range vv {
+		result = append(result, p.OAuthClientSummary(v))
+	}
+	return result
+}
+
+func (p P) OAuthConsent(v *domain.OAuthClient, redirectURI string, scopes []string, state string) OAuthConsent {
+	return OAuthConsent{
+		Client:      p.OAuthClientSummary(v),
+		RedirectURI: redirectURI,
+		Scopes:      scopes,
+		State:       state,
+	}
+}
+
+// OAuthRedirect adds the authorization response parameters to the client's
+// redirect URI, keeping any query it already has.
+func (p P) OAuthRedirect(redirectURI string, params url.Values) OAuthRedirect {
+	u, err := url.Parse(redirectURI)
+	if err != nil {
+		// Redirect URIs are validated when clients are registered.
+		panic(err)
+	}
+	q := u.Query()
+	for k, v := range params {
+		q[k] = v
+	}
+	u.RawQuery = q.Encode()
+	return OAuthRedirect{RedirectURI: u.String()}
+}
+
+func (p P) OAuthToken(accessToken string, expiresIn time.Duration, refreshToken string, scopes []string) OAuthToken {
+	return OAuthToken{
+		AccessToken:  accessToken,
+		ExpiresIn:    int64(expiresIn / time.Second),
+		RefreshToken: refreshToken,
+		Scope:        strings.Join(scopes, " "),
+		TokenType:    "Bearer",
+	}
+}
+
+func (p P) OAuthIntrospection(v *domain.AccessToken, client *domain.OAuthClient, account *domain.Account) OAuthIntrospection {
+	result := OAuthIntrospection{
+		Active:    true,
+		ClientID:  client.ClientID,
+		Iat:       v.Created.Unix(),
+		Scope:     strings.Join(v.Scopes, " "),
+		Sub:       account.PublicID,
+		TokenType: "Bearer",
+		Username:  account.Username,
+	}
+	if v.Expires != nil {
+		result.Exp = v.Expires.Unix()
+	}
+	return result
+}
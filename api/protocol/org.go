@@ -0,0 +1,91 @@
+package protocol
+
+import (
+	"time"
+
+	"github.com/deliveroo/todo-api/domain"
+)
+
+type Org struct {
+	ID      string    `json:"id"`
+	Created time.Time `json:"created"`
+	Name    string    `json:"name"`
+	Role    string    `json:"role"`
+	Slug    string    `json:"slug"`
+}
+
+type OrgMember struct {
+	AccountID string    `json:"account_id"`
+	Created   time.Time `json:"created"`
+	Role      string    `json:"role"`
+	Username  string    `json:"username"`
+}
+
+type OrgInvitation struct {
+	ID        string     `json:"id"`
+	Created   time.Time  `json:"created"`
+	Invitee   string     `json:"invitee"`
+	Inviter   string     `json:"inviter"`
+	OrgName   string     `json:"org_name"`
+	OrgSlug   string     `json:"org_slug"`
+	Responded *time.Time `json:"responded"`
+	Role      string     `json:"role"`
+	Status    string     `json:"status"`
+}
+
+func (p P) Org(v *domain.Org) Org {
+	return Org{
+		ID:      v.PublicID,
+		Created: v.Created,
+		Name:    v.Name,
+		Role:    v.Role,
+		Slug:    v.Slug,
+	}
+}
+
+func (p P) Orgs(vv []*domain.Org) []Org {
+	result := make([]Org, 0, len(vv))
+	for _, v := range vv {
+		result = append(result, p.Org(v))
+	}
+	return result
+}
+
+func (p P) OrgMember(v *domain.OrgMember) OrgMember {
+	return OrgMember{
+		AccountID: v.AccountPublicID,
+		Created:   v.Created,
+		Role:      v.Role,
+		Username:  v.Username,
+	}
+}
+
+func (p P) OrgMembers(vv []*domain.OrgMember) []OrgMember {
+	result := make([]OrgMember, 0, len(vv))
+	for _, v := range vv {
+		result = append(result, p.OrgMember(v))
+	}
+	return result
+}
+
+func (p P) OrgInvitation(v *domain.OrgInvitation) OrgInvitation {
+	return OrgInvitation{
+		ID:        v.PublicID,
+		Created:   v.Created,
+		Invitee:   v.InviteeUsername,
+		Inviter:   v.InviterUsername,
+		OrgName:   v.OrgName,
+		OrgSlug:   v.OrgSlug,
+		Responded: v.Responded,
+		Role:      v.Role,
+		Status:    v.Status,
+	}
+}
+
+func (p P) OrgInvitations(vv []*domain.OrgInvitation) []OrgInvitation {
+	result := make([]OrgInvitation, 0, len(vv))
+	for _, v := range vv {
+		result = append(result, p.OrgInvitation(v))
+	}
+	return result
+}
@@ -16,47 +16,285 @@ type P struct {
 }
 
 type Task struct {
-	ID          int64      `json:"id"`
-	Completed   *time.Time `json:"completed"`
-	Created     time.Time  `json:"created"`
-	Description string     `json:"description"`
+	ID          string          `json:"id"`
+	Assignee    *AccountSummary `json:"assignee"`
+	Completed   *time.Time      `json:"completed"`
+	Created     time.Time       `json:"created"`
+	CreatedBy   *AccountSummary `json:"created_by"`
+	Description string          `json:"description"`
+	ListID      *string         `json:"list_id"`
 }
 
 type Account struct {
-	ID       int64  `json:"id"`
+	ID         string     `json:"id"`
+	Email      string     `json:"email"`
+	Role       string     `json:"role"`
+	Username   string     `json:"username"`
+	VerifiedAt *time.Time `json:"verified_at"`
+}
+
+// AccountSummary is the part of an account other accounts may see.
+type AccountSummary struct {
+	ID       string `json:"id"`
 	Username string `json:"username"`
 }
 
+type AdminAccount struct {
+	Account
+	Created          time.Time  `json:"created"`
+	SuspendedAt      *time.Time `json:"suspended_at"`
+	SuspensionReason string     `json:"suspension_reason"`
+	TwoFactorEnabled bool       `json:"two_factor_enabled"`
+}
+
+type TaskStats struct {
+	Total       int64      `json:"total"`
+	Completed   int64      `json:"completed"`
+	Incomplete  int64      `json:"incomplete"`
+	LastCreated *time.Time `json:"last_created"`
+}
+
+type AuditEntry struct {
+	ID        string            `json:"id"`
+	AccountID *string           `json:"account_id"`
+	Action    string            `json:"action"`
+	ActorID   *string           `json:"actor_id"`
+	Created   time.Time         `json:"created"`
+	Details   map[string]string `json:"details"`
+}
+
+type AccountIdentity struct {
+	ID      string    `json:"id"`
+	Created time.Time `json:"created"`
+	Email   string    `json:"email"`
+	Issuer  string    `json:"issuer"`
+	Subject string    `json:"subject"`
+}
+
+type AccessToken struct {
+	ID       string     `json:"id"`
+	Created  time.Time  `json:"created"`
+	Expires  *time.Time `json:"expires"`
+	LastUsed *time.Time `json:"last_used"`
+	Name     string     `json:"name"`
+	Scopes   []string   `json:"scopes"`
+}
+
+type NewAccessToken struct {
+	AccessToken
+	Token string `json:"token"`
+}
+
+type FailedLogin struct {
+	ID        string    `json:"id"`
+	Created   time.Time `json:"created"`
+	IPAddress string    `json:"ip_address"`
+	UserAgent string    `json:"user_agent"`
+}
+
 type AccountLogin struct {
 	Token string `json:"token"`
 }
 
+type AccountLoginChallenge struct {
+	Challenge string `json:"challenge"`
+}
+
+type TOTPEnrolment struct {
+	Secret string `json:"secret"`
+	URI    string `json:"uri"`
+}
+
+type RecoveryCodes struct {
+	RecoveryCodes []string `json:"recovery_codes"`
+}
+
 func (p P) AccountLogin(token string) AccountLogin {
 	return AccountLogin{
 		Token: token,
 	}
 }
 
+func (p P) AccountLoginChallenge(challenge string) AccountLoginChallenge {
+	return AccountLoginChallenge{
+		Challenge: challenge,
+	}
+}
+
+func (p P) TOTPEnrolment(secret, uri string) TOTPEnrolment {
+	return TOTPEnrolment{
+		Secret: secret,
+		URI:    uri,
+	}
+}
+
+func (p P) RecoveryCodes(codes []string) RecoveryCodes {
+	return RecoveryCodes{
+		RecoveryCodes: codes,
+	}
+}
+
+func (p P) AccessToken(v *domain.AccessToken) AccessToken {
+	return AccessToken{
+		ID:       v.PublicID,
+		Created:  v.Created,
+		Expires:  v.Expires,
+		LastUsed: v.LastUsed,
+		Name:     v.Name,
+		Scopes:   v.Scopes,
+	}
+}
+
+func (p P) AccessTokens(vv []*domain.AccessToken) []AccessToken {
+	result := make([]AccessToken, 0, len(vv))
+	for _, v := range vv {
+		result = append(result, p.AccessToken(v))
+	}
+	return result
+}
+
+func (p P) NewAccessToken(v *domain.AccessToken, token string) NewAccessToken {
+	return NewAccessToken{
+		AccessToken: p.AccessToken(v),
+		Token:       token,
+	}
+}
+
 func (p P) Account(v *domain.Account) Account {
 	return Account{
-		ID:       v.ID,
+		ID:         v.PublicID,
+		Email:      v.Email,
+		Role:       v.Role,
+		Username:   v.Username,
+		VerifiedAt: v.VerifiedAt,
+	}
+}
+
+// AccountSummary returns nil if v is nil.
+func (p P) AccountSummary(v *domain.Account) *AccountSummary {
+	if v == nil {
+		return nil
+	}
+	return &AccountSummary{
+		ID:       v.PublicID,
 		Username: v.Username,
 	}
 }
 
-func (p P) Task(v *domain.Task) Task {
+func (p P) AdminAccount(v *domain.Account) AdminAccount {
+	return AdminAccount{
+		Account:          p.Account(v),
+		Created:          v.Created,
+		SuspendedAt:      v.SuspendedAt,
+		SuspensionReason: v.SuspensionReason,
+		TwoFactorEnabled: v.TwoFactorEnabled(),
+	}
+}
+
+func (p P) AdminAccounts(vv []*domain.Account) []AdminAccount {
+	result := make([]AdminAccount, 0, len(vv))
+	for _, v := range vv {
+		result = append(result, p.AdminAccount(v))
+	}
+	return result
+}
+
+// AuditEntry renders an audit entry, with null in place of accounts which have
+// since been deleted.
+func (p P) AuditEntry(v *domain.AuditEntry) AuditEntry {
+	return AuditEntry{
+		ID:        v.PublicID,
+		AccountID: optionalString(v.AccountPublicID),
+		Action:    v.Action,
+		ActorID:   optionalString(v.ActorPublicID),
+		Created:   v.Created,
+		Details:   v.Details,
+	}
+}
+
+func (p P) AuditEntries(vv []*domain.AuditEntry) []AuditEntry {
+	result := make([]AuditEntry, 0, len(vv))
+	for _, v := range vv {
+		result = append(result, p.AuditEntry(v))
+	}
+	return result
+}
+
+func (p P) AccountIdentity(v *domain.AccountIdentity) AccountIdentity {
+	return AccountIdentity{
+		ID:      v.PublicID,
+		Created: v.Created,
+		Email:   v.Email,
+		Issuer:  v.Issuer,
+		Subject: v.Subject,
+	}
+}
+
+func (p P) AccountIdentities(vv []*domain.AccountIdentity) []AccountIdentity {
+	result := make([]AccountIdentity, 0, len(vv))
+	for _, v := range vv {
+		result = append(result, p.AccountIdentity(v))
+	}
+	return result
+}
+
+func (p P) FailedLogin(v *domain.FailedLogin) FailedLogin {
+	return FailedLogin{
+		ID:        v.PublicID,
+		Created:   v.Created,
+		IPAddress: v.IPAddress,
+		UserAgent: v.UserAgent,
+	}
+}
+
+func (p P) FailedLogins(vv []*domain.FailedLogin) []FailedLogin {
+	result := make([]FailedLogin, 0, len(vv))
+	for _, v := range vv {
+		result = append(result, p.FailedLogin(v))
+	}
+	return result
+}
+
+// Task renders a task, with summaries of its creator and assignee looked up
+// by id in accounts. An account missing from accounts, say because it was
+// deleted, is rendered as null.
+func (p P) Task(v *domain.Task, accounts map[int64]*domain.Account) Task {
+	var assignee *domain.Account
+	if v.AssigneeID != nil {
+		assignee = accounts[*v.AssigneeID]
+	}
 	return Task{
-		ID:          v.ID,
+		ID:          v.PublicID,
+		Assignee:    p.AccountSummary(assignee),
 		Completed:   v.Completed,
 		Created:     v.Created,
+		CreatedBy:   p.AccountSummary(accounts[v.CreatedBy]),
 		Description: v.Description,
+		ListID:      v.ListPublicID,
+	}
+}
+
+func (p P) TaskStats(v *domain.TaskStats) TaskStats {
+	return TaskStats{
+		Total:       v.Total,
+		Completed:   v.Completed,
+		Incomplete:  v.Total - v.Completed,
+		LastCreated: v.LastCreated,
 	}
 }
 
-func (p P) Tasks(vv []*domain.Task) []Task {
+func (p P) Tasks(vv []*domain.Task, accounts map[int64]*domain.Account) []Task {
 	result := make([]Task, 0, len(vv))
 	for _, v := range vv {
-		result = append(result, p.Task(v))
+		result = append(result, p.Task(v, accounts))
 	}
 	return result
 }
+
+// optionalString returns nil if s is empty.
+func optionalString(s string) *string {
+	if s == "" {
+		return nil
+	}
+	return &s
+}
@@ -0,0 +1,47 @@
+package protocol
+
+import "encoding/json"
+
+// Types of message sent to WebSocket clients.
+const (
+	SocketTypeEvent  = "event"
+	SocketTypeResult = "result"
+)
+
+// SocketResult answers a message sent by a WebSocket client, with the status
+// and body of the equivalent REST response.
+type SocketResult struct {
+	Type   string          `json:"type"`
+	ID     string          `json:"id"`
+	Status int             `json:"status"`
+	Body   json.RawMessage `json:"body,omitempty"`
+}
+
+// SocketEvent is a change to a task in a collection the client subscribed
+// to. Task is the task after the event, or before it was deleted.
+type SocketEvent struct {
+	Type       string          `json:"type"`
+	Collection string          `json:"collection"`
+	Event      string          `json:"event"`
+	EventID    int64           `json:"event_id"`
+	Task       json.RawMessage `json:"task"`
+}
+
+func (p P) SocketResult(id string, status int, body []byte) SocketResult {
+	return SocketResult{
+		Type:   SocketTypeResult,
+		ID:     id,
+		Status: status,
+		Body:   body,
+	}
+}
+
+func (p P) SocketEvent(collection, event string, eventID int64, task []byte) SocketEvent {
+	return SocketEvent{
+		Type:       SocketTypeEvent,
+		Collection: collection,
+		Event:      event,
+		EventID:    eventID,
+		Task:       task,
+	}
+}
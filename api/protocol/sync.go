@@ -0,0 +1,52 @@
+package protocol
+
+// Statuses of the changes a client pushes when it syncs.
+const (
+	SyncStatusApplied  = "applied"
+	SyncStatusConflict = "conflict"
+	SyncStatusRejected = "rejected"
+)
+
+// Resolutions of conflicts between a client's changes and the server's.
+const (
+	SyncResolvedClient = "client"
+	SyncResolvedMerged = "merged"
+	SyncResolvedServer = "server"
+)
+
+// Sync is the tasks which changed since a client last synced. Token is passed
+// back as since to fetch the next changes.
+type Sync struct {
+	Token   string       `json:"token"`
+	Tasks   []Task       `json:"tasks"`
+	Deleted []string     `json:"deleted"`
+	Results []SyncResult `json:"results,omitempty"`
+}
+
+// SyncResult is the outcome of a change pushed by a client, identified by the
+// client's ref. Task is the task after the change, or nil if it was deleted.
+type SyncResult struct {
+	Ref      string        `json:"ref"`
+	Status   string        `json:"status"`
+	Task     *Task         `json:"task,omitempty"`
+	Conflict *SyncConflict `json:"conflict,omitempty"`
+	Error    string        `json:"error,omitempty"`
+}
+
+// SyncConflict describes a change pushed by a client which conflicted with
+// one made on the server since the client last synced.
+type SyncConflict struct {
+	Fields     []string `json:"fields"`
+	Resolution string   `json:"resolution"`
+}
+
+func (p P) Sync(token string, tasks []Task, deleted []string) Sync {
+	if deleted == nil {
+		deleted = []string{}
+	}
+	return Sync{
+		Token:   token,
+		Tasks:   tasks,
+		Deleted: deleted,
+	}
+}
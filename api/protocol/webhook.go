@@ -0,0 +1,97 @@
+package protocol
+
+import (
+	"time"
+
+	"github.com/deliveroo/todo-api/domain"
+)
+
+type Webhook struct {
+	ID      string    `json:"id"`
+	Created time.Time `json:"created"`
+	Events  []string  `json:"events"`
+	URL     string    `json:"url"`
+}
+
+type NewWebhook struct {
+	Webhook
+	Secret string `json:"secret"`
+}
+
+type WebhookDelivery struct {
+	ID             string     `json:"id"`
+	Attempts       int        `json:"attempts"`
+	Created        time.Time  `json:"created"`
+	Delivered      *time.Time `json:"delivered"`
+	Event          string     `json:"event"`
+	LastError      string     `json:"last_error"`
+	NextAttempt    *time.Time `json:"next_attempt"`
+	ResponseStatus *int       `json:"response_status"`
+	Status         string     `json:"status"`
+}
+
+// WebhookEvent is the payload posted to webhooks. Task is the task after the
+// event, or before it was deleted, and is omitted from pings.
+type WebhookEvent struct {
+	Created time.Time `json:"created"`
+	Event   string    `json:"event"`
+	Task    *Task     `json:"task,omitempty"`
+}
+
+func (p P) Webhook(v *domain.Webhook) Webhook {
+	return Webhook{
+		ID:      v.PublicID,
+		Created: v.Created,
+		Events:  v.Events,
+		URL:     v.URL,
+	}
+}
+
+func (p P) Webhooks(vv []*domain.Webhook) []Webhook {
+	result := make([]Webhook, 0, len(vv))
+	for _, v := range vv {
+		result = append(result, p.Webhook(v))
+	}
+	return result
+}
+
+func (p P) NewWebhook(v *domain.Webhook) NewWebhook {
+	return NewWebhook{
+		Webhook: p.Webhook(v),
+		Secret:  v.Secret,
+	}
+}
+
+func (p P) WebhookDelivery(v *domain.WebhookDelivery) WebhookDelivery {
+	var next *time.Time
+	if v.Status == domain.DeliveryPending {
+		next = &v.NextAttempt
+	}
+	return WebhookDelivery{
+		ID:             v.PublicID,
+		Attempts:       v.Attempts,
+		Created:        v.Created,
+		Delivered:      v.Delivered,
+		Event:          v.Event,
+		LastError:      v.LastError,
+		NextAttempt:    next,
+		ResponseStatus: v.ResponseStatus,
+		Status:         v.Status,
+	}
+}
+
+func (p P) WebhookDeliveries(vv []*domain.WebhookDelivery) []WebhookDelivery {
+	result := make([]WebhookDelivery, 0, len(vv))
+	for _, v := range vv {
+		result = append(result, p.WebhookDelivery(v))
+	}
+	return result
+}
+
+func (p P) WebhookEvent(event string, task *Task) WebhookEvent {
+	return WebhookEvent{
+		Created: time.Now().UTC(),
+		Event:   event,
+		Task:    task,
+	}
+}
@@ -3,8 +3,14 @@ package api
 import (
 	"context"
 	"fmt"
+	"net/http"
+	"strings"
+	"time"
 
 	"github.com/deliveroo/jsonrest-go"
+	"github.com/deliveroo/todo-api/domain"
+	"github.com/deliveroo/todo-api/repo"
+	"github.com/deliveroo/todo-api/service/session"
 )
 
 func router(s *Server) *jsonrest.Router {
@@ -16,50 +22,364 @@ func router(s *Server) *jsonrest.Router {
 	// Unauthenticated routes.
 	unauthed := r.Group()
 	unauthed.Routes(jsonrest.RouteMap{
-		"POST /account":       s.createAccount,
-		"POST /account/login": s.login,
+		"POST /account":                        s.createAccount,
+		"POST /account/login":                  s.login,
+		"POST /account/login/totp":             s.loginTOTP,
+		"POST /account/password-reset":         s.requestPasswordReset,
+		"POST /account/password-reset/confirm": s.confirmPasswordReset,
+		"POST /account/verify":                 s.verifyEmail,
+		"GET  /auth/oidc/callback":             s.loginOIDC,
 	})
 
 	// Authenticated routes.
 	authed := r.Group()
 	authed.Use(AuthMiddleware(s))
-	authed.Routes(jsonrest.RouteMap{
+
+	// Routes which require a login session, rather than an access token.
+	sessionOnly := authed.Group()
+	sessionOnly.Use(RequireSessionMiddleware())
+	sessionOnly.Routes(jsonrest.RouteMap{
 		// Accounts
-		"GET  /account": s.getAccount,
-
-		// Tasks
-		"GET    /tasks":     s.getAllTasks,
-		"DELETE /tasks/:id": s.deleteTask,
-		"GET    /tasks/:id": s.getTask,
-		"PUT    /tasks/:id": s.updateTask,
-		"POST   /tasks":     s.createTask,
+		"DELETE /account":               s.deleteAccount,
+		"PUT    /account/email":         s.updateEmail,
+		"POST   /account/logout":        s.logout,
+		"PUT    /account/password":      s.updatePassword,
+		"PUT    /account/username":      s.updateUsername,
+		"POST   /account/verify/resend": s.resendEmailVerification,
+
+		// Access tokens
+		"GET    /account/tokens":     s.getAllAccessTokens,
+		"POST   /account/tokens":     s.createAccessToken,
+		"DELETE /account/tokens/:id": s.deleteAccessToken,
+
+		// Two-factor authentication
+		"POST   /account/totp":         s.enrolTOTP,
+		"POST   /account/totp/confirm": s.confirmTOTP,
+		"DELETE /account/totp":         s.disableTOTP,
+
+		// OAuth clients and authorizations
+		"GET    /oauth/clients":                     s.getAllOAuthClients,
+		"POST   /oauth/clients":                     s.createOAuthClient,
+		"DELETE /oauth/clients/:client_id":          s.deleteOAuthClient,
+		"GET    /oauth/authorize":                   s.getOAuthConsent,
+		"POST   /oauth/authorize":                   s.authorizeOAuthClient,
+		"GET    /account/authorizations":            s.getOAuthAuthorizations,
+		"DELETE /account/authorizations/:client_id": s.revokeOAuthAuthorization,
+
+		// Webhooks
+		"GET    /webhooks":                s.getAllWebhooks,
+		"POST   /webhooks":                s.createWebhook,
+		"GET    /webhooks/:id":            s.getWebhook,
+		"PUT    /webhooks/:id":            s.updateWebhook,
+		"DELETE /webhooks/:id":            s.deleteWebhook,
+		"GET    /webhooks/:id/deliveries": s.getWebhookDeliveries,
+		"POST   /webhooks/:id/test":       s.testWebhook,
+	})
+
+	// Administrative routes, which also require a login session.
+	admin := sessionOnly.Group()
+	admin.Use(RequireAdminMiddleware())
+	admin.Routes(jsonrest.RouteMap{
+		"GET  /admin/accounts":                    s.adminSearchAccounts,
+		"GET  /admin/accounts/:id":                s.adminGetAccount,
+		"POST /admin/accounts/:id/logout":         s.adminLogoutAccount,
+		"POST /admin/accounts/:id/password-reset": s.adminResetPassword,
+		"PUT  /admin/accounts/:id/role":           s.adminUpdateRole,
+		"POST /admin/accounts/:id/suspend":        s.adminSuspendAccount,
+		"GET  /admin/accounts/:id/task-stats":     s.adminGetTaskStats,
+		"POST /admin/accounts/:id/unsuspend":      s.adminUnsuspendAccount,
+		"GET  /admin/audit-log":                   s.adminGetAuditLog,
+	})
+
+	accountRead := authed.Group()
+	accountRead.Use(RequireScopeMiddleware(domain.ScopeAccountRead))
+	accountRead.Routes(jsonrest.RouteMap{
+		"GET /account":               s.getAccount,
+		"GET /account/failed-logins": s.getFailedLogins,
+		"GET /account/identities":    s.getAccountIdentities,
+	})
+
+	tasksRead := authed.Group()
+	tasksRead.Use(RequireScopeMiddleware(domain.ScopeTasksRead))
+	tasksRead.Routes(jsonrest.RouteMap{
+		"GET /events":    s.streamEvents,
+		"GET /sync":      s.getSync,
+		"GET /tasks":     s.getAllTasks,
+		"GET /tasks/:id": s.getTask,
+		"GET /ws":        s.connectSocket,
+
+		// Lists
+		"GET /account/invitations":   s.getAccountInvitations,
+		"GET /lists":                 s.getAllLists,
+		"GET /lists/:id":             s.getList,
+		"GET /lists/:id/invitations": s.getListInvitations,
+		"GET /lists/:id/members":     s.getListMembers,
+		"GET /lists/:id/tasks":       s.getListTasks,
+
+		// Organisations
+		"GET /account/org-invitations": s.getAccountOrgInvitations,
+		"GET /orgs":                    s.getAllOrgs,
+	})
+
+	// Routes scoped to the organisation in the :org parameter.
+	orgRead := tasksRead.Group()
+	orgRead.Use(OrgMiddleware(s))
+	orgRead.Routes(jsonrest.RouteMap{
+		"GET /orgs/:org":             s.getOrg,
+		"GET /orgs/:org/invitations": s.getOrgInvitations,
+		"GET /orgs/:org/members":     s.getOrgMembers,
+		"GET /orgs/:org/sync":        s.getSync,
+		"GET /orgs/:org/tasks":       s.getAllTasks,
+		"GET /orgs/:org/tasks/:id":   s.getTask,
+	})
+
+	tasksWrite := authed.Group()
+	tasksWrite.Use(RequireScopeMiddleware(domain.ScopeTasksWrite))
+	tasksWrite.Routes(jsonrest.RouteMap{
+		"DELETE /tasks/:id":          s.deleteTask,
+		"PUT    /tasks/:id":          s.updateTask,
+		"PUT    /tasks/:id/assignee": s.assignTask,
+		"POST   /sync":               s.postSync,
+		"POST   /tasks":              s.createTask,
+
+		// Lists
+		"POST   /account/invitations/:id/accept":  s.acceptInvitation,
+		"POST   /account/invitations/:id/decline": s.declineInvitation,
+		"POST   /lists":                                s.createList,
+		"PUT    /lists/:id":                            s.updateList,
+		"DELETE /lists/:id":                            s.deleteList,
+		"POST   /lists/:id/invitations":                s.createListInvitation,
+		"DELETE /lists/:id/invitations/:invitation_id": s.deleteListInvitation,
+		"PUT    /lists/:id/members/:account_id":        s.updateListMember,
+		"DELETE /lists/:id/members/:account_id":        s.deleteListMember,
+
+		// Organisations
+		"POST   /orgs": s.createOrg,
+		"POST   /account/org-invitations/:id/accept":  s.acceptOrgInvitation,
+		"POST   /account/org-invitations/:id/decline": s.declineOrgInvitation,
+	})
+
+	orgWrite := tasksWrite.Group()
+	orgWrite.Use(OrgMiddleware(s))
+	orgWrite.Routes(jsonrest.RouteMap{
+		"PUT    /orgs/:org":                            s.updateOrg,
+		"DELETE /orgs/:org":                            s.deleteOrg,
+		"POST   /orgs/:org/invitations":                s.createOrgInvitation,
+		"DELETE /orgs/:org/invitations/:invitation_id": s.deleteOrgInvitation,
+		"PUT    /orgs/:org/members/:account_id":        s.updateOrgMember,
+		"DELETE /orgs/:org/members/:account_id":        s.deleteOrgMember,
+		"PUT    /orgs/:org/owner":                      s.transferOrg,
+		"POST   /orgs/:org/sync":                       s.postSync,
+		"POST   /orgs/:org/tasks":                      s.createTask,
+		"PUT    /orgs/:org/tasks/:id":                  s.updateTask,
+		"DELETE /orgs/:org/tasks/:id":                  s.deleteTask,
+		"PUT    /orgs/:org/tasks/:id/assignee":         s.assignTask,
 	})
 
 	return r
 }
 
-type requestAccountKey struct{}
+// rawRoutes are routes served without jsonrest, by handlers which need
+// control of the HTTP request or response, e.g. to redirect or to accept form
+// bodies. Routes must be written with a single space, since they're matched
+// exactly.
+func rawRoutes(s *Server) map[string]http.HandlerFunc {
+	return map[string]http.HandlerFunc{
+		"GET /auth/oidc/start":   s.startOIDC,
+		"POST /oauth/introspect": s.introspectOAuthToken,
+		"POST /oauth/token":      s.oauthToken,
+	}
+}
+
+type (
+	requestAccountKey struct{}
+	requestOrgKey     struct{}
+	requestScopesKey  struct{}
+	requestSessionKey struct{}
+
+	// requestTaskLimitKey holds the maximum number of tasks an unverified
+	// account may have.
+	requestTaskLimitKey struct{}
+
+	// requestUnverifiedKey is set when scopes were withheld because the
+	// account isn't verified.
+	requestUnverifiedKey struct{}
+)
 
 // AuthMiddleware handles account authentication. If a request isn't
 // authenticated, the endpoint handler is not called.
+//
+// Requests are authenticated either by a session token in the x-todo-token
+// header, or by a bearer token in the Authorization header, which may be a
+// session token, a personal access token or an access token issued to an
+// OAuth client.
+//
+// Accounts without a verified email address may be restricted, depending on
+// the server's UnverifiedAccess configuration. Accounts can't be created or
+// left without an email address while they are, so every restricted account
+// can verify one, apart from accounts which had no email address before the
+// restriction was configured; those can add one with PUT /account/email.
 func AuthMiddleware(s *Server) jsonrest.Middleware {
 	return func(next jsonrest.Endpoint) jsonrest.Endpoint {
 		return func(ctx context.Context, req *jsonrest.Request) (interface{}, error) {
-			token := req.Header("x-todo-token")
+			token := requestToken(req)
+			if domain.IsAccessToken(token) {
+				return authenticateAccessToken(ctx, s, req, token, next)
+			}
 			sess, err := s.Sessions().Get(ctx, token)
 			if err != nil {
 				return nil, jsonrest.Unauthorized("unauthorized")
 			}
-			account, err := s.Repo().GetAccountByID(ctx, sess.AccountID)
+			account, err := s.Accounts().GetAccountByID(ctx, sess.AccountID)
 			if err != nil {
 				return nil, err
 			}
-			req.Set(requestAccountKey{}, account)
+			if err := checkAccount(account); err != nil {
+				return nil, err
+			}
+			s.setRequestAccount(req, account, domain.Scopes)
+			req.Set(requestSessionKey{}, sess)
+			return next(ctx, req)
+		}
+	}
+}
+
+// accessTokenTouchInterval limits how often an access token's last used time
+// is written to the database.
+const accessTokenTouchInterval = time.Minute
+
+func authenticateAccessToken(ctx context.Context, s *Server, req *jsonrest.Request, token string, next jsonrest.Endpoint) (interface{}, error) {
+	at, err := s.Repo().GetAccessTokenByDigest(ctx, domain.AccessTokenDigest(token))
+	if err != nil {
+		return nil, err
+	}
+	now := time.Now().UTC()
+	if at == nil || at.Expired(now) {
+		return nil, jsonrest.Unauthorized("unauthorized")
+	}
+	account, err := s.Accounts().GetAccountByID(ctx, at.AccountID)
+	if err != nil {
+		return nil, err
+	}
+	if err := checkAccount(account); err != nil {
+		return nil, err
+	}
+	if at.LastUsed == nil || now.Sub(*at.LastUsed) > accessTokenTouchInterval {
+		if err := s.Repo().TouchAccessToken(ctx, at.ID); err != nil {
+			return nil, err
+		}
+	}
+	s.setRequestAccount(req, account, at.Scopes)
+	return next(ctx, req)
+}
+
+// checkAccount checks that the account a request was authenticated as still
+// exists and isn't suspended. Suspended accounts are rejected even if their
+// sessions haven't expired.
+func checkAccount(account *domain.Account) error {
+	if account == nil {
+		return jsonrest.Unauthorized("unauthorized")
+	}
+	if account.Suspended() {
+		return accountSuspended()
+	}
+	return nil
+}
+
+// setRequestAccount sets the authenticated account and the scopes it was
+// granted, restricting unverified accounts.
+func (s *Server) setRequestAccount(req *jsonrest.Request, account *domain.Account, scopes []string) {
+	if !account.Verified() {
+		switch s.cfg.UnverifiedAccess {
+		case UnverifiedReadOnly:
+			var readOnly []string
+			for _, scope := range scopes {
+				if scope != domain.ScopeTasksWrite {
+					readOnly = append(readOnly, scope)
+				}
+			}
+			scopes = readOnly
+			req.Set(requestUnverifiedKey{}, true)
+		case UnverifiedLimited:
+			req.Set(requestTaskLimitKey{}, s.cfg.UnverifiedTaskLimit)
+		}
+	}
+	req.Set(requestAccountKey{}, account)
+	req.Set(requestScopesKey{}, scopes)
+}
+
+// requestToken returns the token a request was made with.
+func requestToken(req *jsonrest.Request) string {
+	if bearer := bearerToken(req.Header("Authorization")); bearer != "" {
+		return bearer
+	}
+	return req.Header("x-todo-token")
+}
+
+// bearerToken extracts the token from an Authorization header value, or
+// returns an empty string if it isn't a bearer token.
+func bearerToken(header string) string {
+	const prefix = "bearer "
+	if len(header) < len(prefix) || !strings.EqualFold(header[:len(prefix)], prefix) {
+		return ""
+	}
+	return strings.TrimSpace(header[len(prefix):])
+}
+
+// RequireSessionMiddleware rejects requests which weren't authenticated with a
+// login session. It must be used after AuthMiddleware.
+func RequireSessionMiddleware() jsonrest.Middleware {
+	return func(next jsonrest.Endpoint) jsonrest.Endpoint {
+		return func(ctx context.Context, req *jsonrest.Request) (interface{}, error) {
+			if _, ok := req.Get(requestSessionKey{}).(*session.Session); !ok {
+				return nil, jsonrest.Error(http.StatusForbidden, "session_required", "this endpoint requires a login session")
+			}
 			return next(ctx, req)
 		}
 	}
 }
 
+// RequireScopeMiddleware rejects requests whose credentials weren't granted
+// scope. It must be used after AuthMiddleware.
+func RequireScopeMiddleware(scope string) jsonrest.Middleware {
+	return func(next jsonrest.Endpoint) jsonrest.Endpoint {
+		return func(ctx context.Context, req *jsonrest.Request) (interface{}, error) {
+			scopes, _ := req.Get(requestScopesKey{}).([]string)
+			for _, s := range scopes {
+				if s == scope {
+					return next(ctx, req)
+				}
+			}
+			if unverified, _ := req.Get(requestUnverifiedKey{}).(bool); unverified {
+				return nil, verificationRequired("verify your email address first")
+			}
+			return nil, jsonrest.Error(http.StatusForbidden, "insufficient_scope", fmt.Sprintf("the %s scope is required", scope))
+		}
+	}
+}
+
+// OrgMiddleware loads the organisation identified by the :org parameter, and
+// scopes the request's queries to it with repo.WithOrgID, so that the task
+// endpoints serve its tasks. It rejects accounts which don't belong to the
+// organisation. It must be used after AuthMiddleware.
+func OrgMiddleware(s *Server) jsonrest.Middleware {
+	return func(next jsonrest.Endpoint) jsonrest.Endpoint {
+		return func(ctx context.Context, req *jsonrest.Request) (interface{}, error) {
+			account := req.Get(requestAccountKey{}).(*domain.Account)
+			slug := req.Param("org")
+			org, err := s.Repo().GetOrgBySlugForAccount(ctx, slug, account.ID)
+			if err != nil {
+				return nil, err
+			}
+			if org == nil {
+				return nil, jsonrest.NotFound(fmt.Sprintf("organisation not found, slug=%s", slug))
+			}
+			req.Set(requestOrgKey{}, org)
+			return next(repo.WithOrgID(ctx, org.ID), req)
+		}
+	}
+}
+
 // PanicRecoveryMiddleware catches and returns any panics that occur in the
 // endpoint.
 func PanicRecoveryMiddleware() jsonrest.Middleware {
@@ -4,26 +4,54 @@ package api
 
 import (
 	"net/http"
+	"time"
 
 	"github.com/deliveroo/jsonrest-go"
 	"github.com/deliveroo/todo-api/api/protocol"
+	"github.com/deliveroo/todo-api/pkg/oidc"
 	"github.com/deliveroo/todo-api/repo"
+	"github.com/deliveroo/todo-api/service/events"
+	"github.com/deliveroo/todo-api/service/mail"
+	"github.com/deliveroo/todo-api/service/notify"
 	"github.com/deliveroo/todo-api/service/session"
+	"github.com/deliveroo/todo-api/service/throttle"
+	"github.com/deliveroo/todo-api/service/webhook"
 	"github.com/jackc/pgx/v4/pgxpool"
 )
 
 // Config is the server configuration and dependencies.
 type Config struct {
+	Accounts repo.AccountRepo // nil to store accounts in Database
 	Database *pgxpool.Pool
+	Events   *events.Service
+	Mailer   mail.Mailer
+	Notifier notify.Notifier // nil if no notifications are sent
+	OIDC     *oidc.Provider  // nil unless login with an identity provider is configured
 	Sessions *session.Service
+	Tasks    repo.TaskRepo // nil to store tasks in Database
+	Throttle *throttle.Service
+	Webhooks *webhook.Service
 
-	DumpErrors bool // render full error in response
+	ClientIPHeader          string          // trusted proxy header holding the client IP
+	DumpErrors              bool            // render full error in response
+	EmailVerificationExpiry time.Duration   // how long email verification tokens are valid for
+	LoginIPPolicy           throttle.Policy // failed login lockout per client IP
+	LoginPolicy             throttle.Policy // failed login lockout per username
+	OAuthAccessTokenExpiry  time.Duration   // how long access tokens issued to OAuth clients are valid for
+	OAuthCodeExpiry         time.Duration   // how long OAuth authorization codes are valid for
+	OAuthRefreshTokenExpiry time.Duration   // how long OAuth refresh tokens are valid for
+	OIDCSignup              bool            // create accounts for new identity provider users
+	PasswordResetExpiry     time.Duration   // how long password reset tokens are valid for
+	TOTPIssuer              string          // issuer name shown by authenticator apps
+	UnverifiedAccess        string          // access for unverified accounts, e.g. UnverifiedReadOnly; anything but UnverifiedFull requires an email address
+	UnverifiedTaskLimit     int             // task limit for unverified accounts with UnverifiedLimited access
 }
 
 // Server is an API server.
 type Server struct {
 	cfg      *Config
 	protocol protocol.P
+	raw      map[string]http.HandlerFunc
 	router   *jsonrest.Router
 }
 
@@ -33,12 +61,18 @@ func NewServer(cfg *Config) *Server {
 		cfg:      cfg,
 		protocol: protocol.P{},
 	}
+	s.raw = rawRoutes(s)
 	s.router = router(s)
 	return s
 }
 
 // ServeHTTP implements the http.Handler interface.
 func (s *Server) ServeHTTP(w http.ResponseWriter, req *http.Request) {
+	w, req = s.withHTTPInfo(w, req)
+	if h, ok := s.raw[req.Method+" "+req.URL.Path]; ok {
+		h(w, req)
+		return
+	}
 	s.router.ServeHTTP(w, req)
 }
 
@@ -47,12 +81,59 @@ func (s *Server) Protocol() protocol.P {
 	return s.protocol
 }
 
+// Mailer returns the mailer.
+func (s *Server) Mailer() mail.Mailer {
+	return s.cfg.Mailer
+}
+
+// Notifier returns the notification hooks.
+func (s *Server) Notifier() notify.Notifier {
+	if s.cfg.Notifier == nil {
+		return notify.Notifiers(nil)
+	}
+	return s.cfg.Notifier
+}
+
 // Repo returns the repo client.
 func (s *Server) Repo() *repo.Client {
 	return repo.NewClient(s.cfg.Database)
 }
 
+// Accounts returns the account repo. Handlers use it rather than Repo for
+// accounts, outside transactions, so that tests can replace it with
+// repotest.Memory.
+func (s *Server) Accounts() repo.AccountRepo {
+	if s.cfg.Accounts == nil {
+		return s.Repo()
+	}
+	return s.cfg.Accounts
+}
+
+// Tasks returns the task repo. Handlers use it rather than Repo for tasks,
+// outside transactions, so that tests can replace it with repotest.Memory.
+func (s *Server) Tasks() repo.TaskRepo {
+	if s.cfg.Tasks == nil {
+		return s.Repo()
+	}
+	return s.cfg.Tasks
+}
+
+// Events returns the event stream service.
+func (s *Server) Events() *events.Service {
+	return s.cfg.Events
+}
+
 // Sessions returns the sessions service.
 func (s *Server) Sessions() *session.Service {
 	return s.cfg.Sessions
 }
+
+// Throttle returns the throttle service.
+func (s *Server) Throttle() *throttle.Service {
+	return s.cfg.Throttle
+}
+
+// Webhooks returns the webhook delivery service.
+func (s *Server) Webhooks() *webhook.Service {
+	return s.cfg.Webhooks
+}
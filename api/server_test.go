@@ -0,0 +1,156 @@
+package api_test
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/deliveroo/assert-go"
+	"github.com/deliveroo/todo-api/api"
+	"github.com/deliveroo/todo-api/domain"
+	"github.com/deliveroo/todo-api/repo/repotest"
+	"github.com/deliveroo/todo-api/service/mail"
+)
+
+// mailbox is a mailer which keeps the messages it's sent.
+type mailbox struct {
+	sent []*mail.Message
+}
+
+func (m *mailbox) Send(ctx context.Context, msg *mail.Message) error {
+	m.sent = append(m.sent, msg)
+	return nil
+}
+
+// testServer is an API server whose tasks and accounts are kept in memory,
+// for testing handlers which need nothing else.
+type testServer struct {
+	*api.Server
+	mem  *repotest.Memory
+	mail *mailbox
+}
+
+// newTestServer returns a test server, with its configuration changed by
+// opts.
+func newTestServer(opts ...func(*api.Config)) *testServer {
+	ts := &testServer{
+		mem:  repotest.NewMemory(),
+		mail: &mailbox{},
+	}
+	cfg := &api.Config{
+		Accounts: ts.mem,
+		Mailer:   ts.mail,
+		Tasks:    ts.mem,
+	}
+	for _, opt := range opts {
+		opt(cfg)
+	}
+	ts.Server = api.NewServer(cfg)
+	return ts
+}
+
+// post makes a request to the server, returning the response's status code
+// and decoding its body into result, if it isn't nil.
+func (ts *testServer) post(t *testing.T, path string, body, result interface{}) int {
+	t.Helper()
+	b, err := json.Marshal(body)
+	assert.Must(t, err)
+	req := httptest.NewRequest(http.MethodPost, path, bytes.NewReader(b))
+	req.Header.Set("Content-Type", "application/json")
+	rec := httptest.NewRecorder()
+	ts.ServeHTTP(rec, req)
+	if result != nil {
+		assert.Must(t, json.Unmarshal(rec.Body.Bytes(), result))
+	}
+	return rec.Code
+}
+
+type errorResponse struct {
+	Error struct {
+		Code string `json:"code"`
+	} `json:"error"`
+}
+
+func TestCreateAccount(t *testing.T) {
+	var (
+		ts  = newTestServer()
+		ctx = context.Background()
+		now = time.Now().UTC()
+	)
+	_, err := ts.mem.CreateAccount(ctx, &domain.Account{
+		Username:   "verified",
+		Email:      "verified@example.com",
+		VerifiedAt: &now,
+	})
+	assert.Must(t, err)
+
+	t.Run("create", func(t *testing.T) {
+		var account struct {
+			ID       string `json:"id"`
+			Username string `json:"username"`
+		}
+		status := ts.post(t, "/account", map[string]string{"username": "someone", "password": "password123"}, &account)
+		assert.Equal(t, status, http.StatusOK)
+		assert.Equal(t, account.Username, "someone")
+		stored, err := ts.mem.GetAccountByUsername(ctx, "someone")
+		assert.Must(t, err)
+		assert.Equal(t, stored.PublicID, account.ID)
+	})
+	t.Run("username taken", func(t *testing.T) {
+		var resp errorResponse
+		status := ts.post(t, "/account", map[string]string{"username": "SOMEONE", "password": "password123"}, &resp)
+		assert.Equal(t, status, http.StatusConflict)
+		assert.Equal(t, resp.Error.Code, "username_taken")
+	})
+	t.Run("email taken", func(t *testing.T) {
+		var resp errorResponse
+		status := ts.post(t, "/account", map[string]string{
+			"username": "someone-else",
+			"password": "password123",
+			"email":    "Verified@example.com",
+		}, &resp)
+		assert.Equal(t, status, http.StatusConflict)
+		assert.Equal(t, resp.Error.Code, "email_taken")
+	})
+}
+
+func TestCreateAccountEmailRequired(t *testing.T) {
+	// Restricted unverified accounts need an email address to verify, so one
+	// is required.
+	for _, access := range []string{api.UnverifiedReadOnly, api.UnverifiedLimited} {
+		t.Run(access, func(t *testing.T) {
+			ts := newTestServer(func(cfg *api.Config) {
+				cfg.UnverifiedAccess = access
+			})
+			status := ts.post(t, "/account", map[string]string{"username": "someone", "password": "password123"}, nil)
+			assert.Equal(t, status, http.StatusBadRequest)
+			account, err := ts.mem.GetAccountByUsername(context.Background(), "someone")
+			assert.Must(t, err)
+			assert.Nil(t, account)
+		})
+	}
+}
+
+func TestRequestPasswordReset(t *testing.T) {
+	var (
+		ts  = newTestServer()
+		ctx = context.Background()
+	)
+	_, err := ts.mem.CreateAccount(ctx, &domain.Account{
+		Username: "unverified",
+		Email:    "unverified@example.com",
+	})
+	assert.Must(t, err)
+
+	// Unknown and unverified addresses get the same response as verified
+	// ones, but no email.
+	for _, email := range []string{"unknown@example.com", "unverified@example.com"} {
+		status := ts.post(t, "/account/password-reset", map[string]string{"email": email}, nil)
+		assert.Equal(t, status, http.StatusOK)
+	}
+	assert.Equal(t, len(ts.mail.sent), 0)
+}
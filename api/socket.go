@@ -0,0 +1,296 @@
+package api
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/url"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/deliveroo/jsonrest-go"
+	"github.com/deliveroo/todo-api/api/protocol"
+	"github.com/deliveroo/todo-api/domain"
+	"github.com/deliveroo/todo-api/pkg/websocket"
+	"github.com/deliveroo/todo-api/service/events"
+	"go.uber.org/zap"
+)
+
+// Types of message sent by WebSocket clients.
+const (
+	socketSubscribe   = "subscribe"
+	socketUnsubscribe = "unsubscribe"
+	socketCreateTask  = "create_task"
+	socketUpdateTask  = "update_task"
+	socketAssignTask  = "assign_task"
+	socketDeleteTask  = "delete_task"
+)
+
+// socketPingPeriod is how often WebSocket clients are pinged, so that idle
+// connections aren't closed by proxies.
+const socketPingPeriod = 30 * time.Second
+
+// socketMessage is a message sent by a WebSocket client. Each message is
+// answered with a result carrying its id.
+type socketMessage struct {
+	ID         string          `json:"id"`
+	Type       string          `json:"type"`
+	Collection string          `json:"collection"` // subscribe and unsubscribe
+	Org        string          `json:"org"`        // task changes in an organisation
+	TaskID     string          `json:"task_id"`    // task changes other than create_task
+	Body       json.RawMessage `json:"body"`       // task changes
+}
+
+// taskRequest returns the REST method and path of a task change.
+func (m socketMessage) taskRequest() (string, string) {
+	path := "/tasks"
+	if m.Org != "" {
+		path = "/orgs/" + url.PathEscape(m.Org) + "/tasks"
+	}
+	if m.Type == socketCreateTask {
+		return http.MethodPost, path
+	}
+	path += "/" + url.PathEscape(m.TaskID)
+	switch m.Type {
+	case socketAssignTask:
+		return http.MethodPut, path + "/assignee"
+	case socketDeleteTask:
+		return http.MethodDelete, path
+	default:
+		return http.MethodPut, path
+	}
+}
+
+// connectSocket is GET /ws
+//
+// It upgrades the connection to a WebSocket, over which the client can
+// subscribe to changes to collections of tasks, and change tasks. Collections
+// are "tasks", every task the account can see, and "lists/:id". Changes are
+// made by the REST endpoints, with the credentials the socket was opened
+// with.
+func (s *Server) connectSocket(ctx context.Context, req *jsonrest.Request) (interface{}, error) {
+	account := req.Get(requestAccountKey{}).(*domain.Account)
+	conn, err := websocket.Upgrade(responseWriter(ctx), req.Raw())
+	if err != nil {
+		if errors.Is(err, websocket.ErrBadHandshake) {
+			return nil, jsonrest.BadRequest(err.Error())
+		}
+		return nil, err
+	}
+	sock := &socket{
+		server:  s,
+		conn:    conn,
+		account: account,
+		req:     req.Raw(),
+		subs:    make(map[string]taskFilter),
+	}
+	sock.serve(ctx)
+	return nil, nil
+}
+
+// taskFilter reports whether a task belongs to a collection.
+type taskFilter func(*protocol.Task) bool
+
+// socket is an open WebSocket connection.
+type socket struct {
+	server  *Server
+	conn    *websocket.Conn
+	account *domain.Account
+	req     *http.Request // the upgrade request
+
+	mu   sync.Mutex
+	subs map[string]taskFilter // by collection
+}
+
+// serve handles the client's messages until it disconnects.
+func (sock *socket) serve(ctx context.Context) {
+	live, unsubscribe := sock.server.Events().Subscribe(sock.account.ID)
+	defer unsubscribe()
+	done := make(chan struct{})
+	defer close(done)
+	go sock.forward(live, done)
+	for {
+		msg, err := sock.conn.ReadMessage()
+		if err != nil {
+			_ = sock.conn.Close(websocket.CloseNormal, "")
+			return
+		}
+		sock.handle(ctx, msg)
+	}
+}
+
+// forward sends the client changes to the collections it's subscribed to, and
+// pings it, until done is closed.
+func (sock *socket) forward(live <-chan *events.Event, done <-chan struct{}) {
+	ping := time.NewTicker(socketPingPeriod)
+	defer ping.Stop()
+	for {
+		var err error
+		select {
+		case e, ok := <-live:
+			if !ok {
+				// The client may have missed changes, so it should reconnect
+				// and fetch its tasks again.
+				_ = sock.conn.Close(websocket.CloseGoingAway, "events unavailable")
+				return
+			}
+			err = sock.sendEvent(e)
+		case <-ping.C:
+			err = sock.conn.Ping()
+		case <-done:
+			return
+		}
+		if err != nil {
+			_ = sock.conn.Close(websocket.CloseGoingAway, "")
+			return
+		}
+	}
+}
+
+// sendEvent sends an event to the client once for each collection it's
+// subscribed to which contains the task.
+func (sock *socket) sendEvent(e *events.Event) error {
+	var task protocol.Task
+	if err := json.Unmarshal(e.Data, &task); err != nil {
+		zap.L().Error("api.sendEvent", zap.Int64("event_id", e.ID), zap.Error(err))
+		return nil
+	}
+	var collections []string
+	sock.mu.Lock()
+	for c, filter := range sock.subs {
+		if filter(&task) {
+			collections = append(collections, c)
+		}
+	}
+	sock.mu.Unlock()
+	sort.Strings(collections)
+	for _, c := range collections {
+		if err := sock.send(sock.server.Protocol().SocketEvent(c, e.Type, e.ID, e.Data)); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// handle answers a message from the client. Failures to reply are ignored,
+// since the connection will have been closed.
+func (sock *socket) handle(ctx context.Context, data []byte) {
+	var msg socketMessage
+	if err := json.Unmarshal(data, &msg); err != nil {
+		sock.reply("", http.StatusBadRequest, socketError("malformed message"))
+		return
+	}
+	switch msg.Type {
+	case socketSubscribe:
+		status, body := sock.subscribe(ctx, msg.Collection)
+		sock.reply(msg.ID, status, body)
+	case socketUnsubscribe:
+		sock.mu.Lock()
+		delete(sock.subs, msg.Collection)
+		sock.mu.Unlock()
+		sock.reply(msg.ID, http.StatusOK, nil)
+	case socketCreateTask, socketUpdateTask, socketAssignTask, socketDeleteTask:
+		method, path := msg.taskRequest()
+		status, body := sock.do(ctx, method, path, msg.Body)
+		sock.reply(msg.ID, status, body)
+	default:
+		sock.reply(msg.ID, http.StatusBadRequest, socketError("unknown message type"))
+	}
+}
+
+// subscribe subscribes the client to a collection, returning the status and
+// body of the result.
+func (sock *socket) subscribe(ctx context.Context, collection string) (int, json.RawMessage) {
+	var filter taskFilter
+	switch {
+	case collection == "tasks":
+		filter = func(*protocol.Task) bool { return true }
+	case strings.HasPrefix(collection, "lists/"):
+		id := strings.TrimPrefix(collection, "lists/")
+		if !domain.ValidPublicID(id) {
+			return http.StatusBadRequest, socketError("unknown collection")
+		}
+		// The client must be able to see the list.
+		if status, body := sock.do(ctx, http.MethodGet, "/lists/"+id, nil); status != http.StatusOK {
+			return status, body
+		}
+		filter = func(t *protocol.Task) bool { return t.ListID != nil && *t.ListID == id }
+	default:
+		return http.StatusBadRequest, socketError("unknown collection")
+	}
+	sock.mu.Lock()
+	sock.subs[collection] = filter
+	sock.mu.Unlock()
+	return http.StatusOK, nil
+}
+
+// do makes a REST request with the credentials the socket was opened with,
+// returning the response status and body.
+func (sock *socket) do(ctx context.Context, method, path string, body []byte) (int, json.RawMessage) {
+	req, err := http.NewRequest(method, path, bytes.NewReader(body))
+	if err != nil {
+		return http.StatusBadRequest, socketError("malformed request")
+	}
+	req = req.WithContext(ctx)
+	req.Header = sock.req.Header.Clone()
+	req.Header.Set("Content-Type", "application/json")
+	req.RemoteAddr = sock.req.RemoteAddr
+	w := &socketResponse{header: make(http.Header)}
+	sock.server.ServeHTTP(w, req)
+	if w.status == 0 {
+		w.status = http.StatusOK
+	}
+	b := bytes.TrimSpace(w.body.Bytes())
+	if len(b) == 0 || bytes.Equal(b, []byte("null")) {
+		return w.status, nil
+	}
+	return w.status, b
+}
+
+// reply answers a message from the client.
+func (sock *socket) reply(id string, status int, body json.RawMessage) {
+	_ = sock.send(sock.server.Protocol().SocketResult(id, status, body))
+}
+
+func (sock *socket) send(v interface{}) error {
+	b, err := json.Marshal(v)
+	if err != nil {
+		return err
+	}
+	return sock.conn.WriteMessage(b)
+}
+
+// socketError returns the body of a bad request result.
+func socketError(msg string) json.RawMessage {
+	b, _ := json.Marshal(errorBody("bad_request", msg))
+	return b
+}
+
+// socketResponse records the response to a REST request made over a socket.
+type socketResponse struct {
+	header http.Header
+	status int
+	body   bytes.Buffer
+}
+
+// Header implements the http.ResponseWriter interface.
+func (w *socketResponse) Header() http.Header {
+	return w.header
+}
+
+// WriteHeader implements the http.ResponseWriter interface.
+func (w *socketResponse) WriteHeader(status int) {
+	if w.status == 0 {
+		w.status = status
+	}
+}
+
+// Write implements the http.ResponseWriter interface.
+func (w *socketResponse) Write(b []byte) (int, error) {
+	w.WriteHeader(http.StatusOK)
+	return w.body.Write(b)
+}
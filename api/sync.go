@@ -0,0 +1,405 @@
+package api
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strconv"
+	"time"
+
+	"github.com/deliveroo/jsonrest-go"
+	"github.com/deliveroo/todo-api/api/protocol"
+	"github.com/deliveroo/todo-api/domain"
+	"github.com/deliveroo/todo-api/repo"
+)
+
+// maxSyncChanges limits the number of changes a client may push at once.
+const maxSyncChanges = 100
+
+// Operations a client may push when it syncs.
+const (
+	syncCreate = "create"
+	syncUpdate = "update"
+	syncDelete = "delete"
+)
+
+type syncParams struct {
+	Since    string       `json:"since"`
+	Strategy string       `json:"strategy"`
+	Changes  []syncChange `json:"changes"`
+}
+
+// syncChange is a change a client made while offline. Modified, the time the
+// change was made, is required to resolve conflicting updates and deletions
+// by last writer wins. Base, the task as the client last synced it, is
+// required to merge conflicting updates.
+type syncChange struct {
+	Ref         string        `json:"ref"`
+	Op          string        `json:"op"`
+	ID          string        `json:"id"`          // update and delete
+	Description string        `json:"description"` // create and update
+	Completed   *time.Time    `json:"completed"`   // create and update
+	ListID      *string       `json:"list_id"`     // create
+	Modified    *time.Time    `json:"modified"`
+	Base        *syncTaskBase `json:"base"`
+}
+
+type syncTaskBase struct {
+	Description string     `json:"description"`
+	Completed   *time.Time `json:"completed"`
+}
+
+func (p syncParams) validate() error {
+	switch p.Strategy {
+	case "", domain.SyncLastWriterWins, domain.SyncMerge:
+	default:
+		return errors.New(`strategy must be "lww" or "merge"`)
+	}
+	if len(p.Changes) > maxSyncChanges {
+		return fmt.Errorf("at most %d changes may be synced at once", maxSyncChanges)
+	}
+	for i, c := range p.Changes {
+		if c.Ref == "" {
+			return fmt.Errorf("changes[%d].ref is required", i)
+		}
+		switch c.Op {
+		case syncCreate, syncUpdate, syncDelete:
+		default:
+			return fmt.Errorf(`changes[%d].op must be "create", "update" or "delete"`, i)
+		}
+		if c.Op != syncCreate {
+			if c.ID == "" {
+				return fmt.Errorf("changes[%d].id is required", i)
+			}
+			if !domain.ValidPublicID(c.ID) {
+				return fmt.Errorf("changes[%d].id is not a valid id", i)
+			}
+		}
+		if c.Op != syncDelete && c.Description == "" {
+			return fmt.Errorf("changes[%d].description is required", i)
+		}
+		if c.Op == syncCreate {
+			continue
+		}
+		if p.Strategy == domain.SyncMerge {
+			if c.Op == syncUpdate && c.Base == nil {
+				return fmt.Errorf("changes[%d].base is required to merge", i)
+			}
+		} else if c.Modified == nil {
+			return fmt.Errorf("changes[%d].modified is required", i)
+		}
+	}
+	return nil
+}
+
+// getSync is GET /sync and GET /orgs/:org/sync
+//
+// It returns the tasks which were created or changed since the since token,
+// and the ids of those which were deleted or which the account can no longer
+// see. Without a token, it returns every task the account can see. The
+// response's token is passed as since to fetch the next changes.
+func (s *Server) getSync(ctx context.Context, req *jsonrest.Request) (interface{}, error) {
+	account := req.Get(requestAccountKey{}).(*domain.Account)
+	since, err := s.syncSince(ctx, account, req.Query("since"))
+	if err != nil {
+		return nil, err
+	}
+	return s.syncChanges(ctx, account, since)
+}
+
+// postSync is POST /sync and POST /orgs/:org/sync
+//
+// It applies the changes a client made while offline, in order, and returns
+// their results with the changes since the since token, as GET /sync does.
+// A change conflicts if the task was changed on the server since the token.
+// Conflicts are resolved by strategy: "lww", the default, keeps whichever
+// change was made last; "merge" keeps the fields changed on only one side,
+// and the server's value of fields changed on both, and keeps tasks changed on
+// the server which the client deleted. Changes which fail, say because the
+// account can't change the task, are rejected without affecting the others.
+func (s *Server) postSync(ctx context.Context, req *jsonrest.Request) (interface{}, error) {
+	account := req.Get(requestAccountKey{}).(*domain.Account)
+	var params syncParams
+	if err := req.BindBody(&params); err != nil {
+		return nil, err
+	}
+	if err := params.validate(); err != nil {
+		return nil, jsonrest.BadRequest(err.Error())
+	}
+	since, err := s.syncSince(ctx, account, params.Since)
+	if err != nil {
+		return nil, err
+	}
+	// Changes after start were made by this request, so don't conflict with
+	// the client's.
+	start, err := s.Repo().GetChangeSeq(ctx, account.ID)
+	if err != nil {
+		return nil, err
+	}
+	var results []protocol.SyncResult
+	for _, c := range params.Changes {
+		result, err := s.applySyncChange(ctx, req, params.Strategy, since, start, c)
+		if err != nil {
+			var httpErr *jsonrest.HTTPError
+			if !errors.As(err, &httpErr) {
+				return nil, err
+			}
+			result = protocol.SyncResult{Status: protocol.SyncStatusRejected, Error: httpErr.Error()}
+		}
+		result.Ref = c.Ref
+		results = append(results, result)
+	}
+	sync, err := s.syncChanges(ctx, account, since)
+	if err != nil {
+		return nil, err
+	}
+	sync.Results = results
+	return sync, nil
+}
+
+// syncSince parses a sync token, returning -1 for a full sync.
+func (s *Server) syncSince(ctx context.Context, account *domain.Account, token string) (int64, error) {
+	if token == "" {
+		return -1, nil
+	}
+	since, err := strconv.ParseInt(token, 10, 64)
+	if err != nil || since < 0 {
+		return 0, jsonrest.BadRequest("malformed sync token")
+	}
+	seq, err := s.Repo().GetChangeSeq(ctx, account.ID)
+	if err != nil {
+		return 0, err
+	}
+	if since > seq {
+		return 0, jsonrest.BadRequest("unknown sync token")
+	}
+	return since, nil
+}
+
+// syncChanges renders the tasks which changed after since, or every task if
+// since is -1.
+func (s *Server) syncChanges(ctx context.Context, account *domain.Account, since int64) (protocol.Sync, error) {
+	// The sequence is read first, so that changes made meanwhile are sent
+	// again rather than missed.
+	seq, err := s.Repo().GetChangeSeq(ctx, account.ID)
+	if err != nil {
+		return protocol.Sync{}, err
+	}
+	token := strconv.FormatInt(seq, 10)
+	if since < 0 {
+		tasks, err := s.Tasks().GetAllTasksForAccount(ctx, account.ID)
+		if err != nil {
+			return protocol.Sync{}, err
+		}
+		rendered, err := s.renderTasks(ctx, tasks)
+		if err != nil {
+			return protocol.Sync{}, err
+		}
+		return s.Protocol().Sync(token, rendered, nil), nil
+	}
+	changes, err := s.Repo().GetTaskChangesSince(ctx, account.ID, since)
+	if err != nil {
+		return protocol.Sync{}, err
+	}
+	var (
+		changed  []int64
+		deleted  []string
+		publicID = make(map[int64]string, len(changes))
+	)
+	for _, c := range changes {
+		if c.Deleted {
+			deleted = append(deleted, c.TaskPublicID)
+		} else {
+			changed = append(changed, c.TaskID)
+			publicID[c.TaskID] = c.TaskPublicID
+		}
+	}
+	var tasks []*domain.Task
+	if len(changed) > 0 {
+		if tasks, err = s.Tasks().GetTasksByIDsForAccount(ctx, changed, account.ID); err != nil {
+			return protocol.Sync{}, err
+		}
+	}
+	// Tasks which changed but can't be seen, say because they were deleted
+	// after the changes were read, are sent as deleted.
+	visible := make(map[int64]bool, len(tasks))
+	for _, t := range tasks {
+		visible[t.ID] = true
+	}
+	for _, id := range changed {
+		if !visible[id] {
+			deleted = append(deleted, publicID[id])
+		}
+	}
+	rendered, err := s.renderTasks(ctx, tasks)
+	if err != nil {
+		return protocol.Sync{}, err
+	}
+	return s.Protocol().Sync(token, rendered, deleted), nil
+}
+
+// syncWrite is what applying a change pushed by a client did to its task, to
+// be rendered and published once the change has been committed.
+type syncWrite struct {
+	// task is the task after the change, or before it if it was deleted. It's
+	// nil if the task had already been deleted on the server.
+	task *domain.Task
+
+	// event is the event to publish, or empty if the task didn't change.
+	event string
+
+	// conflict is set if the change conflicted with one made on the server.
+	conflict *protocol.SyncConflict
+}
+
+// applySyncChange applies a change pushed by a client in a transaction, and
+// renders its result once it's committed. Changes to the task with sequence
+// numbers after since, up to start, conflict with it.
+func (s *Server) applySyncChange(ctx context.Context, req *jsonrest.Request, strategy string, since, start int64, c syncChange) (protocol.SyncResult, error) {
+	var w *syncWrite
+	err := s.Repo().WithTxOptions(ctx, taskTxOptions, func(tx *repo.Client) error {
+		var err error
+		w, err = s.writeSyncChange(ctx, tx, req, strategy, since, start, c)
+		return err
+	})
+	if err != nil {
+		return protocol.SyncResult{}, err
+	}
+	return s.syncResult(ctx, w)
+}
+
+// writeSyncChange makes the change to the task a client pushed, recording it
+// in the account's change sequence, but doesn't publish it. It's called again
+// if its transaction is retried, so it mustn't have other side effects.
+func (s *Server) writeSyncChange(ctx context.Context, r *repo.Client, req *jsonrest.Request, strategy string, since, start int64, c syncChange) (*syncWrite, error) {
+	account := req.Get(requestAccountKey{}).(*domain.Account)
+	if c.Op == syncCreate {
+		t, _, err := s.addTask(ctx, r, req, taskParams{
+			Description: c.Description,
+			Completed:   c.Completed,
+			ListID:      c.ListID,
+		})
+		if err != nil {
+			return nil, err
+		}
+		if err := r.CreateTaskChange(ctx, t, false); err != nil {
+			return nil, err
+		}
+		return &syncWrite{task: t, event: domain.EventTaskCreated}, nil
+	}
+
+	t, err := r.GetTaskByPublicIDForAccount(ctx, c.ID, account.ID)
+	if err != nil {
+		return nil, err
+	}
+	change, err := r.GetTaskChange(ctx, account.ID, c.ID)
+	if err != nil {
+		return nil, err
+	}
+	conflicted := change != nil && change.Seq > since && change.Seq <= start
+	if t == nil {
+		if change == nil || !change.Deleted {
+			return nil, jsonrest.NotFound(fmt.Sprintf("task not found, id=%s", c.ID))
+		}
+		// The task was deleted on the server.
+		if c.Op == syncDelete || !conflicted {
+			return &syncWrite{}, nil
+		}
+		return &syncWrite{
+			conflict: &protocol.SyncConflict{Fields: []string{}, Resolution: protocol.SyncResolvedServer},
+		}, nil
+	}
+
+	// clientWins reports whether the client's change was made after the
+	// server's, which wins under last writer wins.
+	clientWins := strategy != domain.SyncMerge && conflicted && c.Modified.After(change.Changed)
+
+	if c.Op == syncDelete {
+		if conflicted && !clientWins {
+			// Deleting the task would lose the changes made on the server.
+			return &syncWrite{
+				task:     t,
+				conflict: &protocol.SyncConflict{Fields: []string{}, Resolution: protocol.SyncResolvedServer},
+			}, nil
+		}
+		if err := s.removeTask(ctx, r, t, account); err != nil {
+			return nil, err
+		}
+		if err := r.CreateTaskChange(ctx, t, true); err != nil {
+			return nil, err
+		}
+		w := &syncWrite{task: t, event: domain.EventTaskDeleted}
+		if conflicted {
+			w.conflict = &protocol.SyncConflict{Fields: []string{}, Resolution: protocol.SyncResolvedClient}
+		}
+		return w, nil
+	}
+
+	client := domain.TaskFields{Completed: c.Completed, Description: c.Description}
+	server := t.Fields()
+	fields := client
+	var (
+		conflicts  []string
+		resolution string
+	)
+	switch {
+	case !conflicted:
+	case strategy == domain.SyncMerge:
+		base := domain.TaskFields{Completed: c.Base.Completed, Description: c.Base.Description}
+		fields, conflicts = domain.MergeTaskFields(base, client, server)
+		resolution = protocol.SyncResolvedMerged
+		if len(fields.Diff(server)) == 0 {
+			resolution = protocol.SyncResolvedServer
+		}
+	default:
+		conflicts = client.Diff(server)
+		resolution = protocol.SyncResolvedClient
+		if !clientWins {
+			fields = server
+			resolution = protocol.SyncResolvedServer
+		}
+	}
+	w := &syncWrite{task: t}
+	if len(conflicts) > 0 {
+		w.conflict = &protocol.SyncConflict{Fields: conflicts, Resolution: resolution}
+	}
+	if len(fields.Diff(server)) == 0 {
+		return w, nil
+	}
+	if w.task, w.event, err = s.changeTask(ctx, r, t, account, fields.Description, fields.Completed); err != nil {
+		return nil, err
+	}
+	if err := r.CreateTaskChange(ctx, w.task, false); err != nil {
+		return nil, err
+	}
+	return w, nil
+}
+
+// syncResult renders the result of a change pushed by a client, publishing
+// the change to its task, if any. Deleted tasks aren't rendered.
+func (s *Server) syncResult(ctx context.Context, w *syncWrite) (protocol.SyncResult, error) {
+	result := protocol.SyncResult{Status: protocol.SyncStatusApplied}
+	if w.conflict != nil {
+		result.Status = protocol.SyncStatusConflict
+		result.Conflict = w.conflict
+	}
+	if w.task == nil {
+		return result, nil
+	}
+	var (
+		rendered protocol.Task
+		err      error
+	)
+	if w.event == "" {
+		rendered, err = s.renderTask(ctx, w.task)
+	} else {
+		rendered, err = s.renderTaskEvent(ctx, w.event, w.task)
+	}
+	if err != nil {
+		return protocol.SyncResult{}, err
+	}
+	if w.event != domain.EventTaskDeleted {
+		result.Task = &rendered
+	}
+	return result, nil
+}
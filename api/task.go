@@ -4,17 +4,23 @@ import (
 	"context"
 	"errors"
 	"fmt"
-	"strconv"
+	"net/http"
 	"time"
 
 	"github.com/deliveroo/jsonrest-go"
+	"github.com/deliveroo/todo-api/api/protocol"
 	"github.com/deliveroo/todo-api/domain"
-	"github.com/jackc/pgx"
+	"github.com/deliveroo/todo-api/repo"
+	"github.com/deliveroo/todo-api/service/notify"
+	"github.com/jackc/pgx/v4"
+	"go.uber.org/zap"
 )
 
 type taskParams struct {
 	Description string     `json:"description"`
 	Completed   *time.Time `json:"completed"`
+	AssigneeID  *string    `json:"assignee_id"` // only used when creating a task
+	ListID      *string    `json:"list_id"`     // only used when creating a task
 }
 
 func (p taskParams) validate() error {
@@ -24,6 +30,31 @@ func (p taskParams) validate() error {
 	return nil
 }
 
+type assigneeParams struct {
+	AssigneeID *string `json:"assignee_id"`
+}
+
+// assigneeReadOnly is returned when the assignee of a task tries to change
+// more than whether it's completed.
+func assigneeReadOnly() error {
+	return jsonrest.Error(http.StatusForbidden, "assignee_read_only", "you can only mark tasks assigned to you complete or incomplete")
+}
+
+// taskReadOnly returns the error for an account which can see a task, but not
+// change it.
+func taskReadOnly(t *domain.Task, account *domain.Account) error {
+	if t.AssignedTo(account.ID) {
+		return assigneeReadOnly()
+	}
+	return listReadOnly()
+}
+
+// taskTxOptions are the options for the transactions tasks are changed in.
+// They're serializable, so that a task can't change between being read and
+// being changed, and limits can't be exceeded by concurrent requests;
+// transactions which conflict are retried.
+var taskTxOptions = pgx.TxOptions{IsoLevel: pgx.Serializable}
+
 // createTask is POST /tasks
 func (s *Server) createTask(ctx context.Context, req *jsonrest.Request) (interface{}, error) {
 	account := req.Get(requestAccountKey{}).(*domain.Account)
@@ -34,16 +65,77 @@ func (s *Server) createTask(ctx context.Context, req *jsonrest.Request) (interfa
 	if err := params.validate(); err != nil {
 		return nil, jsonrest.BadRequest(err.Error())
 	}
+	var (
+		t        *domain.Task
+		assignee *domain.Account
+	)
+	err := s.Repo().WithTxOptions(ctx, taskTxOptions, func(tx *repo.Client) error {
+		var err error
+		if t, assignee, err = s.addTask(ctx, tx, req, params); err != nil {
+			return err
+		}
+		return tx.CreateTaskChange(ctx, t, false)
+	})
+	if err != nil {
+		return nil, err
+	}
+	if assignee != nil {
+		s.notifyTaskAssigned(ctx, t, assignee, account)
+	}
+	return s.renderTaskEvent(ctx, domain.EventTaskCreated, t)
+}
+
+// addTask creates a task for the account which made the request, returning
+// the account it's assigned to, if any, to be notified.
+func (s *Server) addTask(ctx context.Context, r *repo.Client, req *jsonrest.Request, params taskParams) (*domain.Task, *domain.Account, error) {
+	account := req.Get(requestAccountKey{}).(*domain.Account)
+	if limit, ok := req.Get(requestTaskLimitKey{}).(int); ok {
+		count, err := r.CountTasksByAccountID(ctx, account.ID)
+		if err != nil {
+			return nil, nil, err
+		}
+		if count >= int64(limit) {
+			return nil, nil, verificationRequired(fmt.Sprintf("verify your email address to create more than %d tasks", limit))
+		}
+	}
+	var listID *int64
+	if params.ListID != nil {
+		if _, ok := repo.OrgIDFromContext(ctx); ok {
+			return nil, nil, jsonrest.BadRequest("tasks in an organisation can't belong to a list")
+		}
+		l, err := s.editableList(ctx, r, *params.ListID, account)
+		if err != nil {
+			return nil, nil, err
+		}
+		listID = &l.ID
+	}
+	var (
+		assignee   *domain.Account
+		assigneeID *int64
+	)
+	if params.AssigneeID != nil {
+		var err error
+		if assignee, err = s.taskAssignee(ctx, r, listID, *params.AssigneeID); err != nil {
+			return nil, nil, err
+		}
+		assigneeID = &assignee.ID
+	}
 	t := &domain.Task{
 		AccountID:   account.ID,
+		AssigneeID:  assigneeID,
+		CreatedBy:   account.ID,
 		Description: params.Description,
 		Completed:   params.Completed,
+		ListID:      listID,
 	}
-	t, err := s.Repo().CreateTask(ctx, t)
+	t, err := r.CreateTask(ctx, t)
 	if err != nil {
-		return nil, err
+		if errors.Is(err, pgx.ErrNoRows) {
+			return nil, nil, listReadOnly()
+		}
+		return nil, nil, err
 	}
-	return s.Protocol().Task(t), nil
+	return t, assignee, nil
 }
 
 // updateTask is PUT /tasks/:id
@@ -56,57 +148,302 @@ func (s *Server) updateTask(ctx context.Context, req *jsonrest.Request) (interfa
 	if err := params.validate(); err != nil {
 		return nil, jsonrest.BadRequest(err.Error())
 	}
-	tid, _ := strconv.ParseInt(req.Param("id"), 10, 64)
-	t, err := s.Repo().GetTaskByIDAndAccountID(ctx, tid, account.ID)
+	tid, err := paramPublicID(req, "id")
 	if err != nil {
 		return nil, err
 	}
-	if t == nil {
-		return nil, jsonrest.NotFound(fmt.Sprintf("task not found, id=%d", tid))
+	var (
+		updated *domain.Task
+		event   string
+	)
+	err = s.Repo().WithTxOptions(ctx, taskTxOptions, func(tx *repo.Client) error {
+		t, err := tx.GetTaskByPublicIDForAccount(ctx, tid, account.ID)
+		if err != nil {
+			return err
+		}
+		if t == nil {
+			return jsonrest.NotFound(fmt.Sprintf("task not found, id=%s", tid))
+		}
+		if updated, event, err = s.changeTask(ctx, tx, t, account, params.Description, params.Completed); err != nil {
+			return err
+		}
+		return tx.CreateTaskChange(ctx, updated, false)
+	})
+	if err != nil {
+		return nil, err
+	}
+	return s.renderTaskEvent(ctx, event, updated)
+}
+
+// changeTask changes a task's description and completion time, returning the
+// updated task and the event to publish. The task's assignee may mark it
+// complete or incomplete, even if they can't otherwise change it.
+func (s *Server) changeTask(ctx context.Context, r *repo.Client, t *domain.Task, account *domain.Account, description string, completed *time.Time) (*domain.Task, string, error) {
+	changed := *t
+	changed.Description = description
+	changed.Completed = completed
+	updated, err := r.UpdateTaskForAccount(ctx, &changed, account.ID)
+	if errors.Is(err, pgx.ErrNoRows) && t.AssignedTo(account.ID) && description == t.Description {
+		updated, err = r.UpdateTaskCompletedForAccount(ctx, t.ID, account.ID, completed)
+	}
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return nil, "", taskReadOnly(t, account)
+		}
+		return nil, "", err
+	}
+	event := domain.EventTaskUpdated
+	if t.Completed == nil && updated.Completed != nil {
+		event = domain.EventTaskCompleted
+	}
+	return updated, event, nil
+}
+
+// assignTask is PUT /tasks/:id/assignee
+//
+// A null assignee_id unassigns the task. Tasks in a list may only be assigned
+// to the list's owner and members.
+func (s *Server) assignTask(ctx context.Context, req *jsonrest.Request) (interface{}, error) {
+	account := req.Get(requestAccountKey{}).(*domain.Account)
+	var params assigneeParams
+	if err := req.BindBody(&params); err != nil {
+		return nil, err
+	}
+	tid, err := paramPublicID(req, "id")
+	if err != nil {
+		return nil, err
+	}
+	var (
+		t, updated *domain.Task
+		assignee   *domain.Account
+	)
+	err = s.Repo().WithTxOptions(ctx, taskTxOptions, func(tx *repo.Client) error {
+		var err error
+		if t, err = tx.GetTaskByPublicIDForAccount(ctx, tid, account.ID); err != nil {
+			return err
+		}
+		if t == nil {
+			return jsonrest.NotFound(fmt.Sprintf("task not found, id=%s", tid))
+		}
+		assignee = nil
+		var assigneeID *int64
+		if params.AssigneeID != nil {
+			if assignee, err = s.taskAssignee(ctx, tx, t.ListID, *params.AssigneeID); err != nil {
+				return err
+			}
+			assigneeID = &assignee.ID
+		}
+		if updated, err = tx.UpdateTaskAssigneeForAccount(ctx, t.ID, account.ID, assigneeID); err != nil {
+			if errors.Is(err, pgx.ErrNoRows) {
+				return taskReadOnly(t, account)
+			}
+			return err
+		}
+		return tx.CreateTaskChange(ctx, updated, false)
+	})
+	if err != nil {
+		return nil, err
 	}
-	t.Description = params.Description
-	t.Completed = params.Completed
-	t, err = s.Repo().UpdateTask(ctx, t)
+	if assignee != nil && !t.AssignedTo(assignee.ID) {
+		s.notifyTaskAssigned(ctx, updated, assignee, account)
+	}
+	return s.renderTaskEvent(ctx, domain.EventTaskUpdated, updated)
+}
+
+// taskAssignee fetches the account a task is to be assigned to. Tasks in a
+// list may only be assigned to accounts which can see the list, and tasks in
+// an organisation to its members. The assignee is identified by its public
+// id.
+func (s *Server) taskAssignee(ctx context.Context, r *repo.Client, listID *int64, assigneeID string) (*domain.Account, error) {
+	if !domain.ValidPublicID(assigneeID) {
+		return nil, jsonrest.BadRequest("assignee_id is not a valid id")
+	}
+	assignee, err := r.GetAccountByPublicID(ctx, assigneeID)
 	if err != nil {
 		return nil, err
 	}
-	return s.Protocol().Task(t), nil
+	if assignee == nil {
+		return nil, jsonrest.BadRequest(fmt.Sprintf("assignee not found, id=%s", assigneeID))
+	}
+	if orgID, ok := repo.OrgIDFromContext(ctx); ok {
+		m, err := r.GetOrgMember(ctx, orgID, assignee.ID)
+		if err != nil {
+			return nil, err
+		}
+		if m == nil {
+			return nil, jsonrest.BadRequest("assignee must be a member of the organisation")
+		}
+	}
+	if listID != nil {
+		l, err := r.GetListForAccount(ctx, *listID, assignee.ID)
+		if err != nil {
+			return nil, err
+		}
+		if l == nil {
+			return nil, jsonrest.BadRequest("assignee must be a member of the list")
+		}
+	}
+	return assignee, nil
+}
+
+// notifyTaskAssigned calls the notification hooks when a task is assigned to
+// someone other than the account assigning it. Errors are logged rather than
+// returned, since the task has already been assigned.
+func (s *Server) notifyTaskAssigned(ctx context.Context, t *domain.Task, assignee, by *domain.Account) {
+	if assignee.ID == by.ID {
+		return
+	}
+	if err := s.Notifier().TaskAssigned(ctx, &notify.TaskAssignment{
+		Task:       t,
+		Assignee:   assignee,
+		AssignedBy: by,
+	}); err != nil {
+		zap.L().Error("api.notifyTaskAssigned", zap.Error(err))
+	}
 }
 
 // deleteTask is DELETE /tasks/:id
 func (s *Server) deleteTask(ctx context.Context, req *jsonrest.Request) (interface{}, error) {
 	account := req.Get(requestAccountKey{}).(*domain.Account)
-	tid, _ := strconv.ParseInt(req.Param("id"), 10, 64)
-	err := s.Repo().DeleteTaskByIDAndAccountID(ctx, tid, account.ID)
+	tid, err := paramPublicID(req, "id")
 	if err != nil {
-		if errors.Is(err, pgx.ErrNoRows) {
-			return nil, jsonrest.NotFound(fmt.Sprintf("task not found, id=%d", tid))
+		return nil, err
+	}
+	var t *domain.Task
+	err = s.Repo().WithTxOptions(ctx, taskTxOptions, func(tx *repo.Client) error {
+		var err error
+		if t, err = tx.GetTaskByPublicIDForAccount(ctx, tid, account.ID); err != nil {
+			return err
+		}
+		if t == nil {
+			return jsonrest.NotFound(fmt.Sprintf("task not found, id=%s", tid))
 		}
+		if err := s.removeTask(ctx, tx, t, account); err != nil {
+			return err
+		}
+		return tx.CreateTaskChange(ctx, t, true)
+	})
+	if err != nil {
+		return nil, err
+	}
+	if _, err := s.renderTaskEvent(ctx, domain.EventTaskDeleted, t); err != nil {
 		return nil, err
 	}
 	return nil, nil
 }
 
-// getAllTasks is GET /tasks
+// removeTask deletes a task.
+func (s *Server) removeTask(ctx context.Context, r *repo.Client, t *domain.Task, account *domain.Account) error {
+	if err := r.DeleteTaskByIDForAccount(ctx, t.ID, account.ID); err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return taskReadOnly(t, account)
+		}
+		return err
+	}
+	return nil
+}
+
+// getAllTasks is GET /tasks and GET /orgs/:org/tasks
+//
+// It includes tasks in lists shared with the account, and tasks assigned to
+// it, or all of an organisation's tasks. The assignee query parameter, either
+// "me" or the public id of an account, returns only the tasks assigned to that
+// account.
 func (s *Server) getAllTasks(ctx context.Context, req *jsonrest.Request) (interface{}, error) {
 	account := req.Get(requestAccountKey{}).(*domain.Account)
-	tasks, err := s.Repo().GetAllTasksByAccountID(ctx, account.ID)
+	var (
+		tasks []*domain.Task
+		err   error
+	)
+	switch v := req.Query("assignee"); v {
+	case "":
+		tasks, err = s.Tasks().GetAllTasksForAccount(ctx, account.ID)
+	case "me":
+		tasks, err = s.Tasks().GetAllTasksByAssigneeIDForAccount(ctx, account.ID, account.ID)
+	default:
+		if !domain.ValidPublicID(v) {
+			return nil, jsonrest.BadRequest(`assignee must be "me" or an account id`)
+		}
+		// An account which doesn't exist has no tasks.
+		var assignee *domain.Account
+		assignee, err = s.Accounts().GetAccountByPublicID(ctx, v)
+		if err == nil && assignee != nil {
+			tasks, err = s.Tasks().GetAllTasksByAssigneeIDForAccount(ctx, assignee.ID, account.ID)
+		}
+	}
 	if err != nil {
 		return nil, err
 	}
-	return s.Protocol().Tasks(tasks), nil
+	return s.renderTasks(ctx, tasks)
 }
 
 // getTask is GET /tasks/:id
 func (s *Server) getTask(ctx context.Context, req *jsonrest.Request) (interface{}, error) {
 	account := req.Get(requestAccountKey{}).(*domain.Account)
-	tid, _ := strconv.ParseInt(req.Param("id"), 10, 64)
-	t, err := s.Repo().GetTaskByIDAndAccountID(ctx, tid, account.ID)
+	tid, err := paramPublicID(req, "id")
+	if err != nil {
+		return nil, err
+	}
+	t, err := s.Tasks().GetTaskByPublicIDForAccount(ctx, tid, account.ID)
 	if err != nil {
 		return nil, err
 	}
 	if t == nil {
-		return nil, jsonrest.NotFound(fmt.Sprintf("task not found, id=%d", tid))
+		return nil, jsonrest.NotFound(fmt.Sprintf("task not found, id=%s", tid))
+	}
+	return s.renderTask(ctx, t)
+}
+
+// renderTask renders a task with summaries of its creator and assignee.
+func (s *Server) renderTask(ctx context.Context, t *domain.Task) (protocol.Task, error) {
+	accounts, err := s.taskAccounts(ctx, []*domain.Task{t})
+	if err != nil {
+		return protocol.Task{}, err
+	}
+	return s.Protocol().Task(t, accounts), nil
+}
+
+// renderTaskEvent renders a task which has changed, and publishes it to
+// webhooks and event streams. The change must already have been recorded for
+// clients to sync, with CreateTaskChange.
+func (s *Server) renderTaskEvent(ctx context.Context, event string, t *domain.Task) (protocol.Task, error) {
+	result, err := s.renderTask(ctx, t)
+	if err != nil {
+		return protocol.Task{}, err
+	}
+	s.publishTaskEvent(ctx, event, t, result)
+	s.publishStreamEvent(ctx, event, t, result)
+	return result, nil
+}
+
+// renderTasks renders tasks with summaries of their creators and assignees.
+func (s *Server) renderTasks(ctx context.Context, tasks []*domain.Task) ([]protocol.Task, error) {
+	accounts, err := s.taskAccounts(ctx, tasks)
+	if err != nil {
+		return nil, err
+	}
+	return s.Protocol().Tasks(tasks, accounts), nil
+}
+
+// taskAccounts fetches the creators and assignees of tasks, by id.
+func (s *Server) taskAccounts(ctx context.Context, tasks []*domain.Task) (map[int64]*domain.Account, error) {
+	var ids []int64
+	for _, t := range tasks {
+		ids = append(ids, t.CreatedBy)
+		if t.AssigneeID != nil {
+			ids = append(ids, *t.AssigneeID)
+		}
+	}
+	result := make(map[int64]*domain.Account)
+	if len(ids) == 0 {
+		return result, nil
+	}
+	accounts, err := s.Accounts().GetAccountsByIDs(ctx, ids)
+	if err != nil {
+		return nil, err
+	}
+	for _, a := range accounts {
+		result[a.ID] = a
 	}
-	return s.Protocol().Task(t), nil
+	return result, nil
 }
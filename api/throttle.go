@@ -0,0 +1,70 @@
+package api
+
+import (
+	"context"
+	"math"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/deliveroo/jsonrest-go"
+	"github.com/deliveroo/todo-api/domain"
+)
+
+func loginUsernameKey(username string) string {
+	return "login:username:" + strings.ToLower(username)
+}
+
+func loginIPKey(ip string) string {
+	return "login:ip:" + ip
+}
+
+// checkLoginThrottle returns an error if logins for the username or from the
+// client IP are locked out.
+func (s *Server) checkLoginThrottle(ctx context.Context, username string) error {
+	for _, key := range []string{loginUsernameKey(username), loginIPKey(clientIP(ctx))} {
+		wait, err := s.Throttle().Check(ctx, key)
+		if err != nil {
+			return err
+		}
+		if wait > 0 {
+			return tooManyAttempts(ctx, wait)
+		}
+	}
+	return nil
+}
+
+// failLogin counts a failed login towards the username and client IP
+// lockouts, and records it against the account, if there is one.
+func (s *Server) failLogin(ctx context.Context, req *jsonrest.Request, username string, account *domain.Account) error {
+	if _, err := s.Throttle().Fail(ctx, loginUsernameKey(username), s.cfg.LoginPolicy); err != nil {
+		return err
+	}
+	if _, err := s.Throttle().Fail(ctx, loginIPKey(clientIP(ctx)), s.cfg.LoginIPPolicy); err != nil {
+		return err
+	}
+	if account == nil {
+		return nil
+	}
+	_, err := s.Repo().CreateFailedLogin(ctx, &domain.FailedLogin{
+		AccountID: account.ID,
+		IPAddress: clientIP(ctx),
+		UserAgent: req.Header("User-Agent"),
+	})
+	return err
+}
+
+// resetLoginThrottle forgets failed logins for the username after a successful
+// login. Failures from the client IP are kept, since one valid login shouldn't
+// let a client keep guessing the passwords of other accounts.
+func (s *Server) resetLoginThrottle(ctx context.Context, username string) error {
+	return s.Throttle().Reset(ctx, loginUsernameKey(username))
+}
+
+// tooManyAttempts returns a 429 error which tells the client when to retry.
+func tooManyAttempts(ctx context.Context, wait time.Duration) error {
+	secs := int(math.Ceil(wait.Seconds()))
+	responseHeader(ctx).Set("Retry-After", strconv.Itoa(secs))
+	return jsonrest.Error(http.StatusTooManyRequests, "too_many_attempts", "too many failed login attempts, try again later")
+}
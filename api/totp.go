@@ -0,0 +1,160 @@
+package api
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"github.com/deliveroo/jsonrest-go"
+	"github.com/deliveroo/todo-api/domain"
+	"github.com/deliveroo/todo-api/pkg/totp"
+)
+
+type totpLoginParams struct {
+	Challenge    string `json:"challenge"`
+	Code         string `json:"code"`
+	RecoveryCode string `json:"recovery_code"`
+}
+
+func (p totpLoginParams) validate() error {
+	if len(p.Challenge) == 0 {
+		return errors.New("challenge is required")
+	}
+	if len(p.Code) == 0 && len(p.RecoveryCode) == 0 {
+		return errors.New("code or recovery_code is required")
+	}
+	return nil
+}
+
+type totpCodeParams struct {
+	Code string `json:"code"`
+}
+
+type totpDisableParams struct {
+	Password string `json:"password"`
+}
+
+// loginTOTP is POST /account/login/totp
+func (s *Server) loginTOTP(ctx context.Context, req *jsonrest.Request) (interface{}, error) {
+	var params totpLoginParams
+	if err := req.BindBody(&params); err != nil {
+		return nil, err
+	}
+	if err := params.validate(); err != nil {
+		return nil, jsonrest.BadRequest(err.Error())
+	}
+	accountID, err := s.Sessions().ConsumeChallenge(ctx, params.Challenge)
+	if err != nil {
+		return nil, jsonrest.BadRequest("invalid or expired challenge")
+	}
+	account, err := s.Accounts().GetAccountByID(ctx, accountID)
+	if err != nil {
+		return nil, err
+	}
+	if account == nil || !account.TwoFactorEnabled() {
+		return nil, jsonrest.BadRequest("invalid or expired challenge")
+	}
+	if err := s.checkLoginThrottle(ctx, account.Username); err != nil {
+		return nil, err
+	}
+	var ok bool
+	if params.Code != "" {
+		var step int64
+		if step, ok = account.VerifyTOTP(params.Code, time.Now()); ok {
+			if ok, err = s.Accounts().UseTOTPStep(ctx, account.ID, step); err != nil {
+				return nil, err
+			}
+		}
+	} else {
+		digest := domain.RecoveryCodeDigest(params.RecoveryCode)
+		ok, err = s.Repo().UseRecoveryCode(ctx, account.ID, digest)
+		if err != nil {
+			return nil, err
+		}
+	}
+	if !ok {
+		if err := s.failLogin(ctx, req, account.Username, account); err != nil {
+			return nil, err
+		}
+		return nil, jsonrest.BadRequest("incorrect code")
+	}
+	if err := s.resetLoginThrottle(ctx, account.Username); err != nil {
+		return nil, err
+	}
+	return s.newSession(ctx, account)
+}
+
+// enrolTOTP is POST /account/totp
+func (s *Server) enrolTOTP(ctx context.Context, req *jsonrest.Request) (interface{}, error) {
+	account := req.Get(requestAccountKey{}).(*domain.Account)
+	if account.TwoFactorEnabled() {
+		return nil, jsonrest.BadRequest("two-factor authentication is already enabled")
+	}
+	account.TOTPSecret = totp.NewSecret()
+	account, err := s.Accounts().UpdateAccountTOTP(ctx, account)
+	if err != nil {
+		return nil, err
+	}
+	uri := totp.URI(s.cfg.TOTPIssuer, account.Username, account.TOTPSecret)
+	return s.Protocol().TOTPEnrolment(account.TOTPSecret, uri), nil
+}
+
+// confirmTOTP is POST /account/totp/confirm
+func (s *Server) confirmTOTP(ctx context.Context, req *jsonrest.Request) (interface{}, error) {
+	account := req.Get(requestAccountKey{}).(*domain.Account)
+	var params totpCodeParams
+	if err := req.BindBody(&params); err != nil {
+		return nil, err
+	}
+	if account.TwoFactorEnabled() {
+		return nil, jsonrest.BadRequest("two-factor authentication is already enabled")
+	}
+	if account.TOTPSecret == "" {
+		return nil, jsonrest.BadRequest("two-factor enrolment has not been started")
+	}
+	now := time.Now().UTC()
+	step, ok := account.VerifyTOTP(params.Code, now)
+	if !ok {
+		return nil, jsonrest.BadRequest("incorrect code")
+	}
+	// The code used to confirm enrolment can't then be used to log in.
+	if ok, err := s.Accounts().UseTOTPStep(ctx, account.ID, step); err != nil {
+		return nil, err
+	} else if !ok {
+		return nil, jsonrest.BadRequest("incorrect code")
+	}
+	codes, digests := domain.NewRecoveryCodes(domain.RecoveryCodeCount)
+	if err := s.Repo().ReplaceRecoveryCodes(ctx, account.ID, digests); err != nil {
+		return nil, err
+	}
+	account.TOTPEnabled = &now
+	if _, err := s.Accounts().UpdateAccountTOTP(ctx, account); err != nil {
+		return nil, err
+	}
+	return s.Protocol().RecoveryCodes(codes), nil
+}
+
+// disableTOTP is DELETE /account/totp
+func (s *Server) disableTOTP(ctx context.Context, req *jsonrest.Request) (interface{}, error) {
+	account := req.Get(requestAccountKey{}).(*domain.Account)
+	var params totpDisableParams
+	if err := req.BindBody(&params); err != nil {
+		return nil, err
+	}
+	ok, err := account.Authenticate(params.Password)
+	if err != nil {
+		return nil, err
+	}
+	if !ok {
+		return nil, jsonrest.BadRequest("incorrect password")
+	}
+	account.TOTPSecret = ""
+	account.TOTPEnabled = nil
+	if _, err := s.Accounts().UpdateAccountTOTP(ctx, account); err != nil {
+		return nil, err
+	}
+	if err := s.Repo().DeleteRecoveryCodesByAccountID(ctx, account.ID); err != nil {
+		return nil, err
+	}
+	return nil, nil
+}
@@ -0,0 +1,206 @@
+package api
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+
+	"github.com/deliveroo/jsonrest-go"
+	"github.com/deliveroo/todo-api/api/protocol"
+	"github.com/deliveroo/todo-api/domain"
+	"github.com/deliveroo/todo-api/service/webhook"
+	"github.com/jackc/pgx/v4"
+	"go.uber.org/zap"
+)
+
+// webhookDeliveryLogSize is how many of a webhook's most recent deliveries are
+// listed.
+const webhookDeliveryLogSize = 50
+
+type webhookParams struct {
+	Events []string `json:"events"`
+	URL    string   `json:"url"`
+}
+
+func (p webhookParams) validate(ctx context.Context, webhooks *webhook.Service) error {
+	if err := webhooks.ValidateURL(ctx, p.URL); err != nil {
+		return err
+	}
+	if len(p.Events) == 0 {
+		return errors.New("at least one event is required")
+	}
+	for _, e := range p.Events {
+		if !domain.ValidWebhookEvent(e) {
+			return fmt.Errorf("unknown event %q", e)
+		}
+	}
+	return nil
+}
+
+// createWebhook is POST /webhooks
+//
+// The webhook's signing secret is only included in this response.
+func (s *Server) createWebhook(ctx context.Context, req *jsonrest.Request) (interface{}, error) {
+	account := req.Get(requestAccountKey{}).(*domain.Account)
+	var params webhookParams
+	if err := req.BindBody(&params); err != nil {
+		return nil, err
+	}
+	if err := params.validate(ctx, s.Webhooks()); err != nil {
+		return nil, jsonrest.BadRequest(err.Error())
+	}
+	w := &domain.Webhook{
+		AccountID: account.ID,
+		Events:    params.Events,
+		URL:       params.URL,
+	}
+	w.NewSecret()
+	w, err := s.Repo().CreateWebhook(ctx, w)
+	if err != nil {
+		return nil, err
+	}
+	return s.Protocol().NewWebhook(w), nil
+}
+
+// getAllWebhooks is GET /webhooks
+func (s *Server) getAllWebhooks(ctx context.Context, req *jsonrest.Request) (interface{}, error) {
+	account := req.Get(requestAccountKey{}).(*domain.Account)
+	webhooks, err := s.Repo().GetAllWebhooksByAccountID(ctx, account.ID)
+	if err != nil {
+		return nil, err
+	}
+	return s.Protocol().Webhooks(webhooks), nil
+}
+
+// getWebhook is GET /webhooks/:id
+func (s *Server) getWebhook(ctx context.Context, req *jsonrest.Request) (interface{}, error) {
+	w, err := s.accountWebhook(ctx, req)
+	if err != nil {
+		return nil, err
+	}
+	return s.Protocol().Webhook(w), nil
+}
+
+// updateWebhook is PUT /webhooks/:id
+func (s *Server) updateWebhook(ctx context.Context, req *jsonrest.Request) (interface{}, error) {
+	account := req.Get(requestAccountKey{}).(*domain.Account)
+	var params webhookParams
+	if err := req.BindBody(&params); err != nil {
+		return nil, err
+	}
+	if err := params.validate(ctx, s.Webhooks()); err != nil {
+		return nil, jsonrest.BadRequest(err.Error())
+	}
+	id, err := paramPublicID(req, "id")
+	if err != nil {
+		return nil, err
+	}
+	w, err := s.Repo().UpdateWebhook(ctx, &domain.Webhook{
+		PublicID:  id,
+		AccountID: account.ID,
+		Events:    params.Events,
+		URL:       params.URL,
+	})
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return nil, jsonrest.NotFound(fmt.Sprintf("webhook not found, id=%s", id))
+		}
+		return nil, err
+	}
+	return s.Protocol().Webhook(w), nil
+}
+
+// deleteWebhook is DELETE /webhooks/:id
+//
+// Deliveries which are still pending are dropped.
+func (s *Server) deleteWebhook(ctx context.Context, req *jsonrest.Request) (interface{}, error) {
+	account := req.Get(requestAccountKey{}).(*domain.Account)
+	id, err := paramPublicID(req, "id")
+	if err != nil {
+		return nil, err
+	}
+	err = s.Repo().DeleteWebhookByPublicIDAndAccountID(ctx, id, account.ID)
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return nil, jsonrest.NotFound(fmt.Sprintf("webhook not found, id=%s", id))
+		}
+		return nil, err
+	}
+	return nil, nil
+}
+
+// getWebhookDeliveries is GET /webhooks/:id/deliveries
+//
+// It lists the webhook's most recent deliveries, newest first.
+func (s *Server) getWebhookDeliveries(ctx context.Context, req *jsonrest.Request) (interface{}, error) {
+	w, err := s.accountWebhook(ctx, req)
+	if err != nil {
+		return nil, err
+	}
+	deliveries, err := s.Repo().GetWebhookDeliveriesByWebhookID(ctx, w.ID, webhookDeliveryLogSize)
+	if err != nil {
+		return nil, err
+	}
+	return s.Protocol().WebhookDeliveries(deliveries), nil
+}
+
+// testWebhook is POST /webhooks/:id/test
+//
+// It sends the webhook a ping event straight away, rather than waiting for
+// the delivery worker, and returns the delivery. If the attempt fails, the
+// ping is retried like any other delivery.
+func (s *Server) testWebhook(ctx context.Context, req *jsonrest.Request) (interface{}, error) {
+	w, err := s.accountWebhook(ctx, req)
+	if err != nil {
+		return nil, err
+	}
+	payload, err := json.Marshal(s.Protocol().WebhookEvent(domain.EventPing, nil))
+	if err != nil {
+		return nil, err
+	}
+	d, err := s.Repo().CreateWebhookDelivery(ctx, &domain.WebhookDelivery{
+		WebhookID: w.ID,
+		Event:     domain.EventPing,
+		Payload:   payload,
+	})
+	if err != nil {
+		return nil, err
+	}
+	d, err = s.Webhooks().Deliver(ctx, d)
+	if err != nil {
+		return nil, err
+	}
+	return s.Protocol().WebhookDelivery(d), nil
+}
+
+// accountWebhook fetches the webhook in the :id parameter, which must have
+// been registered by the request's account.
+func (s *Server) accountWebhook(ctx context.Context, req *jsonrest.Request) (*domain.Webhook, error) {
+	account := req.Get(requestAccountKey{}).(*domain.Account)
+	id, err := paramPublicID(req, "id")
+	if err != nil {
+		return nil, err
+	}
+	w, err := s.Repo().GetWebhookByPublicIDAndAccountID(ctx, id, account.ID)
+	if err != nil {
+		return nil, err
+	}
+	if w == nil {
+		return nil, jsonrest.NotFound(fmt.Sprintf("webhook not found, id=%s", id))
+	}
+	return w, nil
+}
+
+// publishTaskEvent queues a task event for delivery to the webhooks of the
+// account which owns the task. Errors are logged rather than returned, since
+// the task has already changed.
+func (s *Server) publishTaskEvent(ctx context.Context, event string, t *domain.Task, task protocol.Task) {
+	payload, err := json.Marshal(s.Protocol().WebhookEvent(event, &task))
+	if err == nil {
+		_, err = s.Repo().CreateWebhookDeliveries(ctx, t.AccountID, event, payload)
+	}
+	if err != nil {
+		zap.L().Error("api.publishTaskEvent", zap.String("event", event), zap.Int64("task_id", t.ID), zap.Error(err))
+	}
+}
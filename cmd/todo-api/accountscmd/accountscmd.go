@@ -0,0 +1,151 @@
+// The accountscmd package implements the accounts subcommand, which lets
+// operators without access to the admin API manage accounts.
+package accountscmd
+
+import (
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"io/ioutil"
+
+	"github.com/deliveroo/todo-api/conf"
+	"github.com/deliveroo/todo-api/domain"
+	"github.com/deliveroo/todo-api/repo"
+)
+
+const usage = `usage:
+  todo-api accounts suspend -reason <reason> <username>
+  todo-api accounts unsuspend <username>
+  todo-api accounts set-role <username> <role>`
+
+// Command runs account subcommands.
+type Command struct {
+	dep *conf.Dependencies
+	out io.Writer
+}
+
+// Run resolves the application dependencies and runs the accounts subcommand
+// given by args, writing its output to out.
+func Run(ctx context.Context, cfg *conf.Config, args []string, out io.Writer) error {
+	if len(args) == 0 {
+		return errors.New(usage)
+	}
+	dep, err := conf.Resolve(ctx, cfg)
+	if err != nil {
+		return err
+	}
+	defer dep.Database.Close()
+	defer dep.RedisPool.Close()
+	c := &Command{dep: dep, out: out}
+	switch args[0] {
+	case "suspend":
+		return c.suspend(ctx, args[1:])
+	case "unsuspend":
+		return c.unsuspend(ctx, args[1:])
+	case "set-role":
+		return c.setRole(ctx, args[1:])
+	default:
+		return fmt.Errorf("unknown accounts command %q\n%s", args[0], usage)
+	}
+}
+
+func (c *Command) repo() *repo.Client {
+	return repo.NewClient(c.dep.Database)
+}
+
+// suspend suspends an account and signs it out everywhere.
+func (c *Command) suspend(ctx context.Context, args []string) error {
+	fs := flag.NewFlagSet("suspend", flag.ContinueOnError)
+	fs.SetOutput(ioutil.Discard)
+	reason := fs.String("reason", "", "why the account is suspended")
+	if err := fs.Parse(args); err != nil || fs.NArg() != 1 || *reason == "" {
+		return errors.New(usage)
+	}
+	account, err := c.account(ctx, fs.Arg(0))
+	if err != nil {
+		return err
+	}
+	if _, err := c.repo().SuspendAccount(ctx, account.ID, *reason); err != nil {
+		return err
+	}
+	if err := c.dep.Sessions.RevokeAll(ctx, account.ID); err != nil {
+		return err
+	}
+	if err := c.audit(ctx, domain.AuditSuspend, account.ID, map[string]string{"reason": *reason}); err != nil {
+		return err
+	}
+	fmt.Fprintf(c.out, "suspended %s (id %d)\n", account.Username, account.ID)
+	return nil
+}
+
+// unsuspend lifts an account's suspension.
+func (c *Command) unsuspend(ctx context.Context, args []string) error {
+	if len(args) != 1 {
+		return errors.New(usage)
+	}
+	account, err := c.account(ctx, args[0])
+	if err != nil {
+		return err
+	}
+	if _, err := c.repo().UnsuspendAccount(ctx, account.ID); err != nil {
+		return err
+	}
+	if err := c.audit(ctx, domain.AuditUnsuspend, account.ID, nil); err != nil {
+		return err
+	}
+	fmt.Fprintf(c.out, "unsuspended %s (id %d)\n", account.Username, account.ID)
+	return nil
+}
+
+// setRole changes an account's role, e.g. to create the first admin.
+func (c *Command) setRole(ctx context.Context, args []string) error {
+	if len(args) != 2 {
+		return errors.New(usage)
+	}
+	role := args[1]
+	if !domain.ValidRole(role) {
+		return fmt.Errorf("unknown role %q", role)
+	}
+	account, err := c.account(ctx, args[0])
+	if err != nil {
+		return err
+	}
+	if _, err := c.repo().UpdateAccountRole(ctx, account.ID, role); err != nil {
+		return err
+	}
+	if err := c.audit(ctx, domain.AuditRoleChange, account.ID, map[string]string{
+		"from": account.Role,
+		"to":   role,
+	}); err != nil {
+		return err
+	}
+	fmt.Fprintf(c.out, "%s (id %d) is now %s\n", account.Username, account.ID, role)
+	return nil
+}
+
+func (c *Command) account(ctx context.Context, username string) (*domain.Account, error) {
+	account, err := c.repo().GetAccountByUsername(ctx, username)
+	if err != nil {
+		return nil, err
+	}
+	if account == nil {
+		return nil, fmt.Errorf("account %q not found", username)
+	}
+	return account, nil
+}
+
+// audit records an action taken from the command line, which has no actor.
+func (c *Command) audit(ctx context.Context, action string, accountID int64, details map[string]string) error {
+	if details == nil {
+		details = map[string]string{}
+	}
+	details["via"] = "cli"
+	_, err := c.repo().CreateAuditEntry(ctx, &domain.AuditEntry{
+		Action:    action,
+		AccountID: accountID,
+		Details:   details,
+	})
+	return err
+}
@@ -5,10 +5,12 @@ package apicmd
 
 import (
 	"context"
+	"fmt"
 	"net/http"
 
 	"github.com/deliveroo/todo-api/api"
 	"github.com/deliveroo/todo-api/conf"
+	"github.com/deliveroo/todo-api/service/throttle"
 	"go.uber.org/zap"
 )
 
@@ -21,6 +23,11 @@ type Command struct {
 
 // New generates a new API server command for the given config.
 func New(cfg *conf.Config) (*Command, error) {
+	switch cfg.UnverifiedAccess {
+	case "", api.UnverifiedFull, api.UnverifiedReadOnly, api.UnverifiedLimited:
+	default:
+		return nil, fmt.Errorf("unknown unverified access %q", cfg.UnverifiedAccess)
+	}
 	ctx, cancel := context.WithCancel(context.Background())
 	dep, err := conf.Resolve(ctx, cfg)
 	if err != nil {
@@ -28,10 +35,36 @@ func New(cfg *conf.Config) (*Command, error) {
 		return nil, err
 	}
 	api := api.NewServer(&api.Config{
-		Database:   dep.Database,
-		DumpErrors: cfg.Debug,
-		Sessions:   dep.Sessions,
+		Database: dep.Database,
+		Events:   dep.Events,
+		Mailer:   dep.Mailer,
+		Notifier: dep.Notifier,
+		OIDC:     dep.OIDC,
+		Sessions: dep.Sessions,
+		Throttle: dep.Throttle,
+		Webhooks: dep.Webhooks,
+
+		ClientIPHeader:          cfg.ClientIPHeader,
+		DumpErrors:              cfg.Debug,
+		EmailVerificationExpiry: cfg.EmailVerificationExpiry,
+		LoginIPPolicy:           loginPolicy(cfg, cfg.LoginMaxAttemptsIP),
+		LoginPolicy:             loginPolicy(cfg, cfg.LoginMaxAttempts),
+		OAuthAccessTokenExpiry:  cfg.OAuthAccessTokenExpiry,
+		OAuthCodeExpiry:         cfg.OAuthCodeExpiry,
+		OAuthRefreshTokenExpiry: cfg.OAuthRefreshTokenExpiry,
+		OIDCSignup:              cfg.OIDCSignup,
+		PasswordResetExpiry:     cfg.PasswordResetExpiry,
+		TOTPIssuer:              cfg.TOTPIssuer,
+		UnverifiedAccess:        cfg.UnverifiedAccess,
+		UnverifiedTaskLimit:     cfg.UnverifiedTaskLimit,
 	})
+	// Streamed events are passed on to subscribers until shutdown, which
+	// closes any streams still open.
+	go func() {
+		if err := dep.Events.Run(ctx); err != nil {
+			zap.L().Error("apicmd.New", zap.Error(err))
+		}
+	}()
 	return &Command{
 		cancel: cancel,
 		dep:    dep,
@@ -42,6 +75,15 @@ func New(cfg *conf.Config) (*Command, error) {
 	}, nil
 }
 
+func loginPolicy(cfg *conf.Config, maxAttempts int) throttle.Policy {
+	return throttle.Policy{
+		MaxAttempts: maxAttempts,
+		Lockout:     cfg.LoginLockout,
+		MaxLockout:  cfg.LoginMaxLockout,
+		Window:      cfg.LoginAttemptWindow,
+	}
+}
+
 // Run starts the API server.
 func (c *Command) Run() error {
 	zap.L().Info("apicmd.Run", zap.String("addr", c.server.Addr))
@@ -52,6 +94,18 @@ func (c *Command) Run() error {
 	return nil
 }
 
+// RunWebhooks delivers queued webhook events until ctx is cancelled.
+func (c *Command) RunWebhooks(ctx context.Context) error {
+	zap.L().Info("apicmd.RunWebhooks")
+	return c.dep.Webhooks.Run(ctx)
+}
+
+// RunOutbox publishes events from the outbox until ctx is cancelled.
+func (c *Command) RunOutbox(ctx context.Context) error {
+	zap.L().Info("apicmd.RunOutbox")
+	return c.dep.Outbox.Run(ctx)
+}
+
 // Shutdown commences graceful shutdown of the API server.
 func (c *Command) Shutdown(ctx context.Context) error {
 	c.cancel()
@@ -7,12 +7,20 @@ import (
 	"os"
 	"os/signal"
 	"syscall"
+	"time"
 
+	"github.com/deliveroo/todo-api/cmd/todo-api/accountscmd"
+	"github.com/deliveroo/todo-api/cmd/todo-api/apicmd"
+	"github.com/deliveroo/todo-api/cmd/todo-api/migratecmd"
+	"github.com/deliveroo/todo-api/cmd/todo-api/schemacmd"
 	"github.com/deliveroo/todo-api/conf"
 	"github.com/oklog/run"
 	"go.uber.org/zap"
 )
 
+// shutdownTimeout limits how long graceful shutdown waits for requests.
+const shutdownTimeout = 10 * time.Second
+
 func main() {
 	var (
 		logger *zap.Logger
@@ -37,6 +45,56 @@ func main() {
 	}()
 	_ = zap.ReplaceGlobals(logger)
 
+	// Subcommands run to completion instead of starting the server.
+	if len(os.Args) > 1 {
+		if err := runCommand(cfg, os.Args[1], os.Args[2:]); err != nil {
+			log.Fatalln(err)
+		}
+		return
+	}
+
+	if cfg.MigrateOnStart {
+		if err := migratecmd.Up(context.Background(), cfg, os.Stdout); err != nil {
+			zap.L().Fatal("migratecmd.Up", zap.Error(err))
+		}
+	}
+
+	api, err := apicmd.New(cfg)
+	if err != nil {
+		zap.L().Fatal("apicmd.New", zap.Error(err))
+	}
+
+	// API server.
+	{
+		g.Add(api.Run, func(error) {
+			ctx, cancel := context.WithTimeout(context.Background(), shutdownTimeout)
+			defer cancel()
+			if err := api.Shutdown(ctx); err != nil {
+				zap.L().Error("apicmd.Shutdown", zap.Error(err))
+			}
+		})
+	}
+
+	// Webhook delivery worker.
+	{
+		ctx, cancel := context.WithCancel(context.Background())
+		g.Add(func() error {
+			return api.RunWebhooks(ctx)
+		}, func(error) {
+			cancel()
+		})
+	}
+
+	// Outbox dispatcher.
+	{
+		ctx, cancel := context.WithCancel(context.Background())
+		g.Add(func() error {
+			return api.RunOutbox(ctx)
+		}, func(error) {
+			cancel()
+		})
+	}
+
 	// Signal handler.
 	{
 		ctx, cancel := context.WithCancel(context.Background())
@@ -58,3 +116,18 @@ func main() {
 		zap.L().Fatal("run error", zap.Error(err))
 	}
 }
+
+// runCommand runs the named subcommand.
+func runCommand(cfg *conf.Config, name string, args []string) error {
+	ctx := context.Background()
+	switch name {
+	case "accounts":
+		return accountscmd.Run(ctx, cfg, args, os.Stdout)
+	case "migrate":
+		return migratecmd.Run(ctx, cfg, args, os.Stdout)
+	case "schema":
+		return schemacmd.Run(ctx, cfg, args, os.Stdout)
+	default:
+		return fmt.Errorf("unknown command %q", name)
+	}
+}
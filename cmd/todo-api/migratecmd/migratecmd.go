@@ -0,0 +1,150 @@
+// The migratecmd package implements the migrate subcommand, which applies the
+// migrations embedded in the binary.
+package migratecmd
+
+import (
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"time"
+
+	"github.com/deliveroo/todo-api/conf"
+	"github.com/deliveroo/todo-api/migrations"
+	"github.com/deliveroo/todo-api/pkg/migrate"
+	"github.com/jackc/pgx/v4"
+)
+
+const usage = `usage:
+  todo-api migrate up
+  todo-api migrate down [-n <count>]
+  todo-api migrate status
+  todo-api migrate create [-dir <dir>] <name>`
+
+// Run runs the migrate subcommand given by args, writing its output to out.
+func Run(ctx context.Context, cfg *conf.Config, args []string, out io.Writer) error {
+	if len(args) == 0 {
+		return errors.New(usage)
+	}
+	switch args[0] {
+	case "up":
+		return up(ctx, cfg, args[1:], out)
+	case "down":
+		return down(ctx, cfg, args[1:], out)
+	case "status":
+		return status(ctx, cfg, args[1:], out)
+	case "create":
+		return create(args[1:], out)
+	default:
+		return fmt.Errorf("unknown migrate command %q\n%s", args[0], usage)
+	}
+}
+
+// Up applies the pending migrations, writing their names to out.
+func Up(ctx context.Context, cfg *conf.Config, out io.Writer) error {
+	m, err := newMigrator(ctx, cfg)
+	if err != nil {
+		return err
+	}
+	defer m.Conn.Close(context.Background())
+	names, err := m.Up(ctx)
+	for _, name := range names {
+		fmt.Fprintf(out, "applied %s\n", name)
+	}
+	return err
+}
+
+// up applies the pending migrations.
+func up(ctx context.Context, cfg *conf.Config, args []string, out io.Writer) error {
+	if len(args) != 0 {
+		return errors.New(usage)
+	}
+	return Up(ctx, cfg, out)
+}
+
+// down reverts the latest applied migrations.
+func down(ctx context.Context, cfg *conf.Config, args []string, out io.Writer) error {
+	fs := flag.NewFlagSet("down", flag.ContinueOnError)
+	fs.SetOutput(ioutil.Discard)
+	n := fs.Int("n", 1, "number of migrations to revert")
+	if err := fs.Parse(args); err != nil || fs.NArg() != 0 || *n < 1 {
+		return errors.New(usage)
+	}
+	m, err := newMigrator(ctx, cfg)
+	if err != nil {
+		return err
+	}
+	defer m.Conn.Close(context.Background())
+	names, err := m.Down(ctx, *n)
+	for _, name := range names {
+		fmt.Fprintf(out, "reverted %s\n", name)
+	}
+	return err
+}
+
+// status lists the migrations and whether they've been applied.
+func status(ctx context.Context, cfg *conf.Config, args []string, out io.Writer) error {
+	if len(args) != 0 {
+		return errors.New(usage)
+	}
+	m, err := newMigrator(ctx, cfg)
+	if err != nil {
+		return err
+	}
+	defer m.Conn.Close(context.Background())
+	statuses, err := m.Status(ctx)
+	if err != nil {
+		return err
+	}
+	var modified bool
+	for _, s := range statuses {
+		state := "pending"
+		switch {
+		case s.Missing:
+			state = "missing"
+		case s.Modified:
+			state = "modified"
+			modified = true
+		case s.Applied != nil:
+			state = "applied"
+		}
+		fmt.Fprintf(out, "%-8s  %s\n", state, s.Name)
+	}
+	if modified {
+		return errors.New("applied migrations were modified")
+	}
+	return nil
+}
+
+// create writes a new, empty migration.
+func create(args []string, out io.Writer) error {
+	fs := flag.NewFlagSet("create", flag.ContinueOnError)
+	fs.SetOutput(ioutil.Discard)
+	dir := fs.String("dir", "migrations", "directory the migration is written to")
+	if err := fs.Parse(args); err != nil || fs.NArg() != 1 {
+		return errors.New(usage)
+	}
+	p, err := migrate.Create(*dir, fs.Arg(0), time.Now())
+	if err != nil {
+		return err
+	}
+	fmt.Fprintf(out, "created %s\n", p)
+	return nil
+}
+
+// newMigrator connects to the database and loads the embedded migrations.
+func newMigrator(ctx context.Context, cfg *conf.Config) (*migrate.Migrator, error) {
+	ms, err := migrate.Load(migrations.FS)
+	if err != nil {
+		return nil, err
+	}
+	connCtx, cancel := context.WithTimeout(ctx, cfg.DatabaseConnTimeout)
+	defer cancel()
+	conn, err := pgx.Connect(connCtx, cfg.DatabaseURL)
+	if err != nil {
+		return nil, err
+	}
+	return &migrate.Migrator{Conn: conn, Migrations: ms}, nil
+}
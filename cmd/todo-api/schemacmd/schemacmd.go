@@ -0,0 +1,141 @@
+// The schemacmd package implements the schema subcommand, which checks that
+// schema.sql matches the schema the migrations create.
+package schemacmd
+
+import (
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"strings"
+	"time"
+
+	"github.com/deliveroo/todo-api/conf"
+	"github.com/deliveroo/todo-api/migrations"
+	"github.com/deliveroo/todo-api/pkg/migrate"
+	"github.com/deliveroo/todo-api/pkg/pgschema"
+	"github.com/jackc/pgx/v4"
+)
+
+const usage = `usage:
+  todo-api schema check [-file <schema.sql>]`
+
+// Run runs the schema subcommand given by args, writing its output to out.
+func Run(ctx context.Context, cfg *conf.Config, args []string, out io.Writer) error {
+	if len(args) == 0 {
+		return errors.New(usage)
+	}
+	switch args[0] {
+	case "check":
+		return check(ctx, cfg, args[1:], out)
+	default:
+		return fmt.Errorf("unknown schema command %q\n%s", args[0], usage)
+	}
+}
+
+// check loads schema.sql and applies the migrations to scratch databases on
+// the database server, and compares their schemas.
+func check(ctx context.Context, cfg *conf.Config, args []string, out io.Writer) error {
+	fs := flag.NewFlagSet("check", flag.ContinueOnError)
+	fs.SetOutput(ioutil.Discard)
+	file := fs.String("file", "schema.sql", "schema dump to check")
+	if err := fs.Parse(args); err != nil || fs.NArg() != 0 {
+		return errors.New(usage)
+	}
+	dump, err := ioutil.ReadFile(*file)
+	if err != nil {
+		return err
+	}
+	ms, err := migrate.Load(migrations.FS)
+	if err != nil {
+		return err
+	}
+
+	want, err := withScratch(ctx, cfg, func(conn *pgx.Conn) (pgschema.Schema, error) {
+		if _, err := conn.Exec(ctx, stripMetaCommands(string(dump))); err != nil {
+			return nil, fmt.Errorf("%s: %w", *file, err)
+		}
+		return pgschema.Inspect(ctx, conn, "public")
+	})
+	if err != nil {
+		return err
+	}
+	got, err := withScratch(ctx, cfg, func(conn *pgx.Conn) (pgschema.Schema, error) {
+		m := &migrate.Migrator{Conn: conn, Migrations: ms}
+		if _, err := m.Up(ctx); err != nil {
+			return nil, fmt.Errorf("migrations: %w", err)
+		}
+		return pgschema.Inspect(ctx, conn, "public")
+	})
+	if err != nil {
+		return err
+	}
+
+	diff := pgschema.Diff(want, got)
+	if len(diff) == 0 {
+		fmt.Fprintf(out, "%s matches the migrations\n", *file)
+		return nil
+	}
+	fmt.Fprintf(out, "--- %s\n+++ migrations\n", *file)
+	for _, line := range diff {
+		fmt.Fprintln(out, line)
+	}
+	return fmt.Errorf("%s doesn't match the migrations: run rake db:migrate to update it", *file)
+}
+
+// withScratch creates an empty database on the server cfg.DatabaseURL points
+// to, calls fn with a connection to it, and then drops it.
+func withScratch(ctx context.Context, cfg *conf.Config, fn func(conn *pgx.Conn) (pgschema.Schema, error)) (_ pgschema.Schema, err error) {
+	admin, err := connect(ctx, cfg, "")
+	if err != nil {
+		return nil, err
+	}
+	defer admin.Close(context.Background())
+
+	name := fmt.Sprintf("todo_api_schema_check_%d", time.Now().UnixNano())
+	if _, err := admin.Exec(ctx, `CREATE DATABASE `+pgx.Identifier{name}.Sanitize()+`;`); err != nil {
+		return nil, err
+	}
+	defer func() {
+		if _, dropErr := admin.Exec(context.Background(), `DROP DATABASE `+pgx.Identifier{name}.Sanitize()+`;`); err == nil {
+			err = dropErr
+		}
+	}()
+
+	conn, err := connect(ctx, cfg, name)
+	if err != nil {
+		return nil, err
+	}
+	// The connection must be closed before the database can be dropped.
+	defer conn.Close(context.Background())
+	return fn(conn)
+}
+
+// connect connects to database on the server cfg.DatabaseURL points to, or
+// to the database it names if database is empty.
+func connect(ctx context.Context, cfg *conf.Config, database string) (*pgx.Conn, error) {
+	connCfg, err := pgx.ParseConfig(cfg.DatabaseURL)
+	if err != nil {
+		return nil, err
+	}
+	if database != "" {
+		connCfg.Database = database
+	}
+	ctx, cancel := context.WithTimeout(ctx, cfg.DatabaseConnTimeout)
+	defer cancel()
+	return pgx.ConnectConfig(ctx, connCfg)
+}
+
+// stripMetaCommands removes psql meta-commands, e.g. \restrict, which newer
+// versions of pg_dump write, from a schema dump.
+func stripMetaCommands(dump string) string {
+	lines := strings.Split(dump, "\n")
+	for i, line := range lines {
+		if strings.HasPrefix(line, `\`) {
+			lines[i] = ""
+		}
+	}
+	return strings.Join(lines, "\n")
+}
@@ -14,16 +14,58 @@ import (
 // Config is the configuration needed to bootstrap the application's
 // dependencies.
 type Config struct {
-	Addr                string        `env:"ADDR" envDefault:":4000"`                       // Server listen address
-	DatabaseConnTimeout time.Duration `env:"DATABASE_CONN_TIMEOUT" envDefault:"10s"`        // Postgres connection timeout
-	DatabaseMaxConn     int32         `env:"DATABASE_MAX_CONN" envDefault:"10"`             // Postgres connection pool limit
-	DatabaseURL         string        `env:"DATABASE_URL"`                                  // Postgres connection string
-	Debug               bool          `env:"DEBUG"`                                         // Enable debug mode
-	MaxSessionDuration  time.Duration `env:"MAX_SESSION_DURATION" envDefault:"24h"`         // The maximum duration of a login session.
-	RedisMaxActive      int           `env:"REDIS_MAX_ACTIVE" envDefault:"5"`               // Max active redis pool connections
-	RedisMaxIdle        int           `env:"REDIS_MAX_IDLE" envDefault:"5"`                 // Maximum idle redis pool connections
-	RedisURL            string        `env:"REDIS_URL" envDefault:"redis://127.0.0.1:6379"` // Redis connection string
-	SuppressLogging     bool          `env:"SUPPRESS_LOGGING"`                              // Suppress logging, useful for testing
+	Addr                    string        `env:"ADDR" envDefault:":4000"`                         // Server listen address
+	ClientIPHeader          string        `env:"CLIENT_IP_HEADER"`                                // Trusted proxy header holding the client IP, e.g. X-Forwarded-For
+	DatabaseConnTimeout     time.Duration `env:"DATABASE_CONN_TIMEOUT" envDefault:"10s"`          // Postgres connection timeout
+	DatabaseMaxConn         int32         `env:"DATABASE_MAX_CONN" envDefault:"10"`               // Postgres connection pool limit
+	DatabaseURL             string        `env:"DATABASE_URL"`                                    // Postgres connection string
+	Debug                   bool          `env:"DEBUG"`                                           // Enable debug mode
+	EmailVerificationExpiry time.Duration `env:"EMAIL_VERIFICATION_EXPIRY" envDefault:"24h"`      // How long email verification tokens are valid for
+	EventLogSize            int           `env:"EVENT_LOG_SIZE" envDefault:"1000"`                // Recent events kept per account for reconnecting event stream clients
+	EventLogTTL             time.Duration `env:"EVENT_LOG_TTL" envDefault:"24h"`                  // How long an account's events are kept once it stops receiving new ones
+	LoginAttemptWindow      time.Duration `env:"LOGIN_ATTEMPT_WINDOW" envDefault:"1h"`            // How long failed logins are counted for
+	LoginLockout            time.Duration `env:"LOGIN_LOCKOUT" envDefault:"30s"`                  // Lockout after too many failed logins, doubled per further failure
+	LoginMaxAttempts        int           `env:"LOGIN_MAX_ATTEMPTS" envDefault:"5"`               // Failed logins per username before lockout
+	LoginMaxAttemptsIP      int           `env:"LOGIN_MAX_ATTEMPTS_IP" envDefault:"50"`           // Failed logins per client IP before lockout
+	LoginMaxLockout         time.Duration `env:"LOGIN_MAX_LOCKOUT" envDefault:"1h"`               // Maximum lockout after failed logins
+	MailFrom                string        `env:"MAIL_FROM" envDefault:"todo-api@localhost"`       // Sender address of emails
+	MailerURL               string        `env:"MAILER_URL" envDefault:"log:"`                    // Mailer as smtp://, smtps://, file:// or log: URL
+	MaxSessionDuration      time.Duration `env:"MAX_SESSION_DURATION" envDefault:"24h"`           // The maximum duration of a login session.
+	MigrateOnStart          bool          `env:"MIGRATE_ON_START"`                                // Apply pending migrations before starting the server
+	OAuthAccessTokenExpiry  time.Duration `env:"OAUTH_ACCESS_TOKEN_EXPIRY" envDefault:"1h"`       // How long access tokens issued to OAuth clients are valid for
+	OAuthCodeExpiry         time.Duration `env:"OAUTH_CODE_EXPIRY" envDefault:"1m"`               // How long OAuth authorization codes are valid for
+	OAuthRefreshTokenExpiry time.Duration `env:"OAUTH_REFRESH_TOKEN_EXPIRY" envDefault:"720h"`    // How long OAuth refresh tokens are valid for
+	OIDCClientID            string        `env:"OIDC_CLIENT_ID"`                                  // Client id registered with the identity provider
+	OIDCClientSecret        string        `env:"OIDC_CLIENT_SECRET"`                              // Client secret registered with the identity provider
+	OIDCIssuer              string        `env:"OIDC_ISSUER"`                                     // Identity provider issuer URL; login with it is disabled if empty
+	OIDCRedirectURL         string        `env:"OIDC_REDIRECT_URL"`                               // Public URL of GET /auth/oidc/callback
+	OIDCScopes              []string      `env:"OIDC_SCOPES" envDefault:"openid,email,profile"`   // Scopes requested from the identity provider
+	OIDCSignup              bool          `env:"OIDC_SIGNUP" envDefault:"true"`                   // Create accounts for new identity provider users
+	OutboxBackoff           time.Duration `env:"OUTBOX_BACKOFF" envDefault:"5s"`                  // Delay before retrying an outbox event, doubled per further attempt
+	OutboxHTTPURL           string        `env:"OUTBOX_HTTP_URL"`                                 // Endpoint outbox events are posted to by the "http" sink
+	OutboxMaxBackoff        time.Duration `env:"OUTBOX_MAX_BACKOFF" envDefault:"10m"`             // Maximum delay between outbox event attempts
+	OutboxPollInterval      time.Duration `env:"OUTBOX_POLL_INTERVAL" envDefault:"1s"`            // How often the outbox is checked for events to publish
+	OutboxRedisStream       string        `env:"OUTBOX_REDIS_STREAM" envDefault:"todo:outbox"`    // Redis stream outbox events are added to by the "redis" sink
+	OutboxRedisStreamMaxLen int64         `env:"OUTBOX_REDIS_STREAM_MAX_LEN" envDefault:"100000"` // Approximate length limit of the outbox Redis stream
+	OutboxRetention         time.Duration `env:"OUTBOX_RETENTION" envDefault:"168h"`              // How long published outbox events are kept
+	OutboxSinks             []string      `env:"OUTBOX_SINKS" envDefault:"log"`                   // Sinks outbox events are published to: "log", "redis" and "http"
+	OutboxTimeout           time.Duration `env:"OUTBOX_TIMEOUT" envDefault:"10s"`                 // How long publishing an outbox event may take
+	PasswordResetExpiry     time.Duration `env:"PASSWORD_RESET_EXPIRY" envDefault:"1h"`           // How long password reset tokens are valid for
+	RedisMaxActive          int           `env:"REDIS_MAX_ACTIVE" envDefault:"5"`                 // Max active redis pool connections
+	RedisMaxIdle            int           `env:"REDIS_MAX_IDLE" envDefault:"5"`                   // Maximum idle redis pool connections
+	RedisURL                string        `env:"REDIS_URL" envDefault:"redis://127.0.0.1:6379"`   // Redis connection string
+	SessionMode             string        `env:"SESSION_MODE" envDefault:"redis"`                 // Session storage: "redis" or stateless "signed" tokens
+	SessionSigningKeys      []string      `env:"SESSION_SIGNING_KEYS"`                            // Signed session keys as <key id>:<base64 secret>, first one signs
+	SuppressLogging         bool          `env:"SUPPRESS_LOGGING"`                                // Suppress logging, useful for testing
+	TOTPIssuer              string        `env:"TOTP_ISSUER" envDefault:"todo-api"`               // Issuer name shown by authenticator apps
+	UnverifiedAccess        string        `env:"UNVERIFIED_ACCESS" envDefault:"full"`             // Access for accounts without a verified email: "full", "read-only" or "limited"; the last two require an email address
+	UnverifiedTaskLimit     int           `env:"UNVERIFIED_TASK_LIMIT" envDefault:"10"`           // Task limit for unverified accounts with "limited" access
+	WebhookAllowPrivate     bool          `env:"WEBHOOK_ALLOW_PRIVATE"`                           // Allow webhooks to localhost and private addresses, for development
+	WebhookBackoff          time.Duration `env:"WEBHOOK_BACKOFF" envDefault:"30s"`                // Delay before retrying a webhook delivery, doubled per further attempt
+	WebhookMaxAttempts      int           `env:"WEBHOOK_MAX_ATTEMPTS" envDefault:"10"`            // Attempts at a webhook delivery before it's given up on
+	WebhookMaxBackoff       time.Duration `env:"WEBHOOK_MAX_BACKOFF" envDefault:"6h"`             // Maximum delay between webhook delivery attempts
+	WebhookPollInterval     time.Duration `env:"WEBHOOK_POLL_INTERVAL" envDefault:"5s"`           // How often queued webhook deliveries are checked for
+	WebhookTimeout          time.Duration `env:"WEBHOOK_TIMEOUT" envDefault:"10s"`                // How long webhook endpoints have to respond
 }
 
 // Load loads the application configuration from command line flags and
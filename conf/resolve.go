@@ -4,9 +4,18 @@ import (
 	"context"
 	"errors"
 	"fmt"
+	"net/http"
 	"time"
 
+	"github.com/deliveroo/todo-api/pkg/oidc"
+	"github.com/deliveroo/todo-api/repo"
+	"github.com/deliveroo/todo-api/service/events"
+	"github.com/deliveroo/todo-api/service/mail"
+	"github.com/deliveroo/todo-api/service/notify"
+	"github.com/deliveroo/todo-api/service/outbox"
 	"github.com/deliveroo/todo-api/service/session"
+	"github.com/deliveroo/todo-api/service/throttle"
+	"github.com/deliveroo/todo-api/service/webhook"
 	"github.com/gomodule/redigo/redis"
 	"github.com/jackc/pgx/v4/pgxpool"
 )
@@ -14,8 +23,15 @@ import (
 // Dependencies are the resolved dependencies.
 type Dependencies struct {
 	Database  *pgxpool.Pool
+	Events    *events.Service
+	Mailer    mail.Mailer
+	Notifier  notify.Notifier
+	OIDC      *oidc.Provider
+	Outbox    *outbox.Service
 	RedisPool *redis.Pool
 	Sessions  *session.Service
+	Throttle  *throttle.Service
+	Webhooks  *webhook.Service
 }
 
 // Resolve resolves the application dependencies using its config.
@@ -30,13 +46,32 @@ func Resolve(ctx context.Context, c *Config) (*Dependencies, error) {
 		return nil, fmt.Errorf("redisPool: %w", err)
 	}
 
+	sessions, err := resolveSessions(c, redisPool)
+	if err != nil {
+		return nil, fmt.Errorf("sessions: %w", err)
+	}
+
+	mailer, err := mail.Open(c.MailerURL, c.MailFrom)
+	if err != nil {
+		return nil, fmt.Errorf("mailer: %w", err)
+	}
+
+	outboxService, err := resolveOutbox(c, db, redisPool)
+	if err != nil {
+		return nil, fmt.Errorf("outbox: %w", err)
+	}
+
 	return &Dependencies{
 		Database:  db,
+		Events:    &events.Service{Redis: redisPool, LogSize: c.EventLogSize, LogTTL: c.EventLogTTL},
+		Mailer:    mailer,
+		Notifier:  notify.Notifiers{notify.LogNotifier{}, &notify.MailNotifier{Mailer: mailer}},
+		OIDC:      resolveOIDC(c),
+		Outbox:    outboxService,
 		RedisPool: redisPool,
-		Sessions: &session.Service{
-			Redis:              redisPool,
-			MaxSessionDuration: c.MaxSessionDuration,
-		},
+		Sessions:  sessions,
+		Throttle:  &throttle.Service{Redis: redisPool},
+		Webhooks:  resolveWebhooks(c, db),
 	}, nil
 }
 
@@ -47,6 +82,7 @@ func resolveDatabase(ctx context.Context, c *Config) (*pgxpool.Pool, error) {
 	}
 	cfg.MaxConns = c.DatabaseMaxConn
 	cfg.MaxConnLifetime = c.DatabaseConnTimeout
+	cfg.AfterConnect = repo.AfterConnect
 	pool, err := pgxpool.ConnectConfig(ctx, cfg)
 	if err != nil {
 		return nil, err
@@ -58,6 +94,92 @@ func resolveDatabase(ctx context.Context, c *Config) (*pgxpool.Pool, error) {
 	return pool, nil
 }
 
+func resolveSessions(c *Config, redisPool *redis.Pool) (*session.Service, error) {
+	s := &session.Service{
+		Redis:              redisPool,
+		MaxSessionDuration: c.MaxSessionDuration,
+	}
+	switch c.SessionMode {
+	case "", "redis":
+	case "signed":
+		keys, err := session.ParseKeySet(c.SessionSigningKeys)
+		if err != nil {
+			return nil, err
+		}
+		s.Keys = keys
+	default:
+		return nil, fmt.Errorf("unknown session mode %q", c.SessionMode)
+	}
+	return s, nil
+}
+
+// resolveOIDC configures the identity provider, if any. Its endpoints are
+// discovered when it's first used.
+func resolveOIDC(c *Config) *oidc.Provider {
+	if c.OIDCIssuer == "" {
+		return nil
+	}
+	return &oidc.Provider{
+		Issuer:       c.OIDCIssuer,
+		ClientID:     c.OIDCClientID,
+		ClientSecret: c.OIDCClientSecret,
+		RedirectURL:  c.OIDCRedirectURL,
+		Scopes:       c.OIDCScopes,
+		HTTPClient:   &http.Client{Timeout: 10 * time.Second},
+	}
+}
+
+func resolveWebhooks(c *Config, db *pgxpool.Pool) *webhook.Service {
+	return &webhook.Service{
+		Repo:         repo.NewClient(db),
+		HTTPClient:   webhook.NewHTTPClient(c.WebhookTimeout, c.WebhookAllowPrivate),
+		AllowPrivate: c.WebhookAllowPrivate,
+		MaxAttempts:  c.WebhookMaxAttempts,
+		Backoff:      c.WebhookBackoff,
+		MaxBackoff:   c.WebhookMaxBackoff,
+		PollInterval: c.WebhookPollInterval,
+		Timeout:      c.WebhookTimeout,
+	}
+}
+
+// resolveOutbox configures the outbox dispatcher with the sinks named in the
+// config.
+func resolveOutbox(c *Config, db *pgxpool.Pool, redisPool *redis.Pool) (*outbox.Service, error) {
+	var sinks []outbox.Sink
+	for _, name := range c.OutboxSinks {
+		switch name {
+		case "log":
+			sinks = append(sinks, outbox.LogSink{})
+		case "redis":
+			sinks = append(sinks, &outbox.RedisSink{
+				Redis:    redisPool,
+				Stream:   c.OutboxRedisStream,
+				MaxLen:   c.OutboxRedisStreamMaxLen,
+				DedupTTL: c.OutboxRetention,
+			})
+		case "http":
+			if c.OutboxHTTPURL == "" {
+				return nil, errors.New("OutboxHTTPURL is required by the http sink")
+			}
+			sinks = append(sinks, &outbox.HTTPSink{
+				URL:        c.OutboxHTTPURL,
+				HTTPClient: &http.Client{Timeout: c.OutboxTimeout},
+			})
+		default:
+			return nil, fmt.Errorf("unknown outbox sink %q", name)
+		}
+	}
+	return &outbox.Service{
+		Repo:         repo.NewClient(db),
+		Sinks:        sinks,
+		Backoff:      c.OutboxBackoff,
+		MaxBackoff:   c.OutboxMaxBackoff,
+		PollInterval: c.OutboxPollInterval,
+		Retention:    c.OutboxRetention,
+		Timeout:      c.OutboxTimeout,
+	}, nil
+}
+
 func resolveRedisPool(c *Config) (*redis.Pool, error) {
 	if c.RedisURL == "" {
 		return nil, errors.New("RedisURL is required")
@@ -0,0 +1,111 @@
+package domain
+
+import (
+	"encoding/base64"
+	"strings"
+	"time"
+)
+
+// Access token scopes.
+const (
+	ScopeAccountRead = "account:read"
+	ScopeTasksRead   = "tasks:read"
+	ScopeTasksWrite  = "tasks:write"
+)
+
+// Scopes are all of the scopes that can be granted to an access token.
+var Scopes = []string{
+	ScopeAccountRead,
+	ScopeTasksRead,
+	ScopeTasksWrite,
+}
+
+// Access token prefixes identify access tokens, which makes them easy to tell
+// apart from session tokens and to detect when leaked.
+const (
+	accessTokenPrefix      = "todo_pat_"
+	oauthAccessTokenPrefix = "todo_oat_"
+)
+
+// AccessToken is a personal access token, which lets scripts authenticate as
+// an account without using its password.
+type AccessToken struct {
+	// ID is the database id for the access token.
+	ID int64
+
+	// PublicID is the id for the access token in the API.
+	PublicID string
+
+	// AccountID is the database foreign key to the account.
+	AccountID int64
+
+	// ClientID is the database foreign key to the OAuth client the access
+	// token was issued to, or nil if it's a personal access token.
+	ClientID *int64
+
+	// Created is when the access token was created.
+	Created time.Time
+
+	// Digest is the SHA256 digest of the token. The token itself is only
+	// known to its owner.
+	Digest string
+
+	// Expires is when the access token stops working, if ever.
+	Expires *time.Time
+
+	// LastUsed is when the access token last authenticated a request.
+	LastUsed *time.Time
+
+	// Name describes what the access token is used for.
+	Name string
+
+	// Scopes are the scopes granted to the access token.
+	Scopes []string
+}
+
+// NewToken generates a new random token and sets its digest. The token
+// is returned, and should be shown to its owner exactly once.
+func (t *AccessToken) NewToken() string {
+	prefix := accessTokenPrefix
+	if t.ClientID != nil {
+		prefix = oauthAccessTokenPrefix
+	}
+	token := prefix + base64.RawURLEncoding.EncodeToString(randomBytes(32))
+	t.Digest = AccessTokenDigest(token)
+	return token
+}
+
+// HasScope reports whether the access token was granted scope.
+func (t *AccessToken) HasScope(scope string) bool {
+	for _, s := range t.Scopes {
+		if s == scope {
+			return true
+		}
+	}
+	return false
+}
+
+// Expired reports whether the access token has expired at the given time.
+func (t *AccessToken) Expired(now time.Time) bool {
+	return t.Expires != nil && !now.Before(*t.Expires)
+}
+
+// IsAccessToken reports whether token looks like an access token.
+func IsAccessToken(token string) bool {
+	return strings.HasPrefix(token, accessTokenPrefix) || strings.HasPrefix(token, oauthAccessTokenPrefix)
+}
+
+// AccessTokenDigest returns the digest under which token is stored.
+func AccessTokenDigest(token string) string {
+	return tokenDigest(token)
+}
+
+// ValidScope reports whether scope is a known access token scope.
+func ValidScope(scope string) bool {
+	for _, s := range Scopes {
+		if s == scope {
+			return true
+		}
+	}
+	return false
+}
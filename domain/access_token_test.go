@@ -0,0 +1,42 @@
+package domain_test
+
+import (
+	"testing"
+	"time"
+
+	"github.com/deliveroo/assert-go"
+	"github.com/deliveroo/todo-api/domain"
+)
+
+func TestAccessToken(t *testing.T) {
+	t.Run("NewToken", func(t *testing.T) {
+		var at domain.AccessToken
+		token := at.NewToken()
+		assert.True(t, domain.IsAccessToken(token))
+		assert.Equal(t, at.Digest, domain.AccessTokenDigest(token))
+		assert.False(t, at.Digest == token)
+
+		clientID := int64(1)
+		oauth := domain.AccessToken{ClientID: &clientID}
+		assert.True(t, domain.IsAccessToken(oauth.NewToken()))
+		assert.False(t, domain.IsAccessToken("session-token"))
+	})
+	t.Run("HasScope", func(t *testing.T) {
+		at := domain.AccessToken{Scopes: []string{domain.ScopeTasksRead}}
+		assert.True(t, at.HasScope(domain.ScopeTasksRead))
+		assert.False(t, at.HasScope(domain.ScopeTasksWrite))
+	})
+	t.Run("Expired", func(t *testing.T) {
+		now := time.Now()
+		past := now.Add(-time.Minute)
+		assert.False(t, (&domain.AccessToken{}).Expired(now))
+		assert.True(t, (&domain.AccessToken{Expires: &past}).Expired(now))
+		assert.True(t, (&domain.AccessToken{Expires: &now}).Expired(now))
+	})
+	t.Run("ValidScope", func(t *testing.T) {
+		for _, s := range domain.Scopes {
+			assert.True(t, domain.ValidScope(s))
+		}
+		assert.False(t, domain.ValidScope("tasks:delete"))
+	})
+}
@@ -7,6 +7,14 @@ import (
 	"encoding/base64"
 	"errors"
 	"time"
+
+	"github.com/deliveroo/todo-api/pkg/totp"
+)
+
+// Account roles.
+const (
+	RoleUser  = "user"
+	RoleAdmin = "admin"
 )
 
 // Account is a user account.
@@ -14,9 +22,17 @@ type Account struct {
 	// ID is the database id for the account.
 	ID int64
 
+	// PublicID is the id for the account in the API, where its database id
+	// is never shown. See NewPublicID.
+	PublicID string
+
 	// Created is when the account was created.
 	Created time.Time
 
+	// Email is the account's email address, used to recover the account. It
+	// is empty if the account has no email address.
+	Email string
+
 	// PasswordDigest is the SHA512 digest of the salt combined with the
 	// plain-text password.
 	PasswordDigest string
@@ -24,8 +40,32 @@ type Account struct {
 	// PasswordSalt is random bytes for securing the password digest.
 	PasswordSalt string
 
+	// Role is the account's role, which is RoleUser unless the account
+	// belongs to an operator.
+	Role string
+
+	// SuspendedAt is when the account was suspended by an operator, or nil if
+	// it isn't suspended.
+	SuspendedAt *time.Time
+
+	// SuspensionReason is why the account was suspended.
+	SuspensionReason string
+
+	// TOTPEnabled is when two-factor authentication was enabled, or nil if
+	// it isn't enabled.
+	TOTPEnabled *time.Time
+
+	// TOTPSecret is the base32 encoded secret shared with the account's
+	// authenticator app. It may be set before TOTPEnabled while enrolment is
+	// waiting to be confirmed.
+	TOTPSecret string
+
 	// Username is the account username.
 	Username string
+
+	// VerifiedAt is when the account's email address was verified, or nil if
+	// it hasn't been.
+	VerifiedAt *time.Time
 }
 
 // SetPassword creates and sets a new password digest and salt.
@@ -69,6 +109,42 @@ func (a *Account) Authenticate(password string) (bool, error) {
 	return ok, err
 }
 
+// IsAdmin reports whether the account may use the administrative API.
+func (a *Account) IsAdmin() bool {
+	return a.Role == RoleAdmin
+}
+
+// Suspended reports whether the account has been suspended.
+func (a *Account) Suspended() bool {
+	return a.SuspendedAt != nil
+}
+
+// ValidRole reports whether role is a known account role.
+func ValidRole(role string) bool {
+	return role == RoleUser || role == RoleAdmin
+}
+
+// TwoFactorEnabled reports whether logging in requires a TOTP code.
+func (a *Account) TwoFactorEnabled() bool {
+	return a.TOTPEnabled != nil
+}
+
+// Verified reports whether the account has a verified email address.
+func (a *Account) Verified() bool {
+	return a.Email != "" && a.VerifiedAt != nil
+}
+
+// VerifyTOTP reports whether code is a valid TOTP code for the account's
+// secret at time t, and returns the time step it's for. The code must only be
+// accepted if the step is later than the last one the account used; see
+// repo.Client.UseTOTPStep.
+func (a *Account) VerifyTOTP(code string, t time.Time) (int64, bool) {
+	if a.TOTPSecret == "" {
+		return 0, false
+	}
+	return totp.ValidateStep(a.TOTPSecret, code, t)
+}
+
 func randomBytes(n int) []byte {
 	b := make([]byte, n)
 	if _, err := rand.Read(b); err != nil {
@@ -0,0 +1,30 @@
+package domain
+
+import "time"
+
+// AccountIdentity links an account to a user of an external identity
+// provider, so that they can log in with the provider instead of a password.
+type AccountIdentity struct {
+	// ID is the database id for the account identity.
+	ID int64
+
+	// PublicID is the id for the account identity in the API.
+	PublicID string
+
+	// AccountID is the database foreign key to the account.
+	AccountID int64
+
+	// Created is when the identity was linked.
+	Created time.Time
+
+	// Email is the email address the provider gave for the user when they
+	// last logged in, for display only.
+	Email string
+
+	// Issuer identifies the identity provider.
+	Issuer string
+
+	// Subject is the provider's identifier for the user, which is unique and
+	// never reassigned for an issuer.
+	Subject string
+}
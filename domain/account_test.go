@@ -2,9 +2,11 @@ package domain_test
 
 import (
 	"testing"
+	"time"
 
 	"github.com/deliveroo/assert-go"
 	"github.com/deliveroo/todo-api/domain"
+	"github.com/deliveroo/todo-api/pkg/totp"
 )
 
 func TestAccountAuthenticate(t *testing.T) {
@@ -30,3 +32,22 @@ func TestAccountAuthenticate(t *testing.T) {
 		assert.False(t, ok)
 	})
 }
+
+func TestAccountVerifyTOTP(t *testing.T) {
+	now := time.Now()
+	t.Run("without a secret", func(t *testing.T) {
+		account := &domain.Account{}
+		_, ok := account.VerifyTOTP("000000", now)
+		assert.False(t, ok)
+	})
+	t.Run("with a secret", func(t *testing.T) {
+		account := &domain.Account{TOTPSecret: totp.NewSecret()}
+		code, err := totp.Code(account.TOTPSecret, now)
+		assert.Must(t, err)
+		step, ok := account.VerifyTOTP(code, now)
+		assert.True(t, ok)
+		assert.Equal(t, step, now.Unix()/int64(totp.Period.Seconds()))
+		_, ok = account.VerifyTOTP(code, now.Add(time.Hour))
+		assert.False(t, ok)
+	})
+}
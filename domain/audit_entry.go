@@ -0,0 +1,46 @@
+package domain
+
+import "time"
+
+// Audited administrative actions.
+const (
+	AuditLogout        = "logout"
+	AuditPasswordReset = "password_reset"
+	AuditRoleChange    = "role_change"
+	AuditSuspend       = "suspend"
+	AuditUnsuspend     = "unsuspend"
+)
+
+// AuditEntry records an administrative action taken on an account.
+type AuditEntry struct {
+	// ID is the database id for the audit entry.
+	ID int64
+
+	// PublicID is the id for the audit entry in the API.
+	PublicID string
+
+	// ActorID is the database foreign key to the administrator's account, or
+	// zero if the action wasn't taken through the API.
+	ActorID int64
+
+	// ActorPublicID is the public id of the administrator's account, or
+	// empty if there isn't one or it has been deleted.
+	ActorPublicID string
+
+	// Action is what was done, e.g. AuditSuspend.
+	Action string
+
+	// AccountID is the database foreign key to the account acted on.
+	AccountID int64
+
+	// AccountPublicID is the public id of the account acted on, or empty if
+	// it has been deleted.
+	AccountPublicID string
+
+	// Created is when the action was taken.
+	Created time.Time
+
+	// Details holds extra information about the action, such as the reason
+	// for a suspension.
+	Details map[string]string
+}
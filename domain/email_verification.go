@@ -0,0 +1,47 @@
+package domain
+
+import (
+	"encoding/base64"
+	"time"
+)
+
+// EmailVerification proves that an account owner can receive email at an
+// address. The token is sent to the address and is valid once, until it
+// expires.
+type EmailVerification struct {
+	// ID is the database id for the email verification.
+	ID int64
+
+	// AccountID is the database foreign key to the account.
+	AccountID int64
+
+	// Created is when the email verification was sent.
+	Created time.Time
+
+	// Digest is the SHA256 digest of the token. The token itself is only
+	// known to the recipient of the verification email.
+	Digest string
+
+	// Email is the address being verified.
+	Email string
+
+	// Expires is when the token stops working.
+	Expires time.Time
+
+	// Used is when the token was used to verify the address, if it has been.
+	Used *time.Time
+}
+
+// NewToken generates a new random token and sets its digest. The token is
+// returned, and should only be sent to the address being verified.
+func (v *EmailVerification) NewToken() string {
+	token := base64.RawURLEncoding.EncodeToString(randomBytes(32))
+	v.Digest = EmailVerificationDigest(token)
+	return token
+}
+
+// EmailVerificationDigest returns the digest under which an email verification
+// token is stored.
+func EmailVerificationDigest(token string) string {
+	return tokenDigest(token)
+}
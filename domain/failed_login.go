@@ -0,0 +1,24 @@
+package domain
+
+import "time"
+
+// FailedLogin records a failed attempt to log in to an account.
+type FailedLogin struct {
+	// ID is the database id for the failed login.
+	ID int64
+
+	// PublicID is the id for the failed login in the API.
+	PublicID string
+
+	// AccountID is the database foreign key to the account.
+	AccountID int64
+
+	// Created is when the attempt was made.
+	Created time.Time
+
+	// IPAddress is the IP address the attempt was made from.
+	IPAddress string
+
+	// UserAgent is the user agent the attempt was made with.
+	UserAgent string
+}
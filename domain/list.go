@@ -0,0 +1,121 @@
+package domain
+
+import "time"
+
+// List roles. The owner of a list can share it with other accounts as
+// editors, who can change its tasks, or viewers, who can only see them.
+const (
+	ListRoleOwner  = "owner"
+	ListRoleEditor = "editor"
+	ListRoleViewer = "viewer"
+)
+
+// List invitation statuses.
+const (
+	InvitationPending  = "pending"
+	InvitationAccepted = "accepted"
+	InvitationDeclined = "declined"
+)
+
+// List is a list of tasks which can be shared with other accounts.
+type List struct {
+	// ID is the database id for the list.
+	ID int64
+
+	// PublicID is the id for the list in the API, where its database id is
+	// never shown. See NewPublicID.
+	PublicID string
+
+	// AccountID is the database foreign key to the account which owns the
+	// list.
+	AccountID int64
+
+	// AccountPublicID is the public id of the account which owns the list.
+	AccountPublicID string
+
+	// Created is when the list was created.
+	Created time.Time
+
+	// Name is the list name.
+	Name string
+
+	// Role is the role on the list of the account it was fetched for.
+	Role string
+}
+
+// ListMember is an account a list has been shared with.
+type ListMember struct {
+	// ListID is the database foreign key to the list.
+	ListID int64
+
+	// AccountID is the database foreign key to the member's account.
+	AccountID int64
+
+	// AccountPublicID is the public id of the member's account.
+	AccountPublicID string
+
+	// Created is when the account joined the list.
+	Created time.Time
+
+	// Role is the member's role on the list.
+	Role string
+
+	// Username is the member's username.
+	Username string
+}
+
+// ListInvitation is an invitation for an account to join a list, which it can
+// accept or decline.
+type ListInvitation struct {
+	// ID is the database id for the invitation.
+	ID int64
+
+	// PublicID is the id for the invitation in the API.
+	PublicID string
+
+	// ListID is the database foreign key to the list.
+	ListID int64
+
+	// ListPublicID is the public id of the list.
+	ListPublicID string
+
+	// ListName is the name of the list.
+	ListName string
+
+	// InviterID is the database foreign key to the account which sent the
+	// invitation.
+	InviterID int64
+
+	// InviterUsername is the username of the account which sent the
+	// invitation.
+	InviterUsername string
+
+	// InviteeID is the database foreign key to the invited account.
+	InviteeID int64
+
+	// InviteeUsername is the username of the invited account.
+	InviteeUsername string
+
+	// Created is when the invitation was sent.
+	Created time.Time
+
+	// Responded is when the invitation was accepted or declined, if it has
+	// been.
+	Responded *time.Time
+
+	// Role is the role the invitee will have on the list.
+	Role string
+
+	// Status is whether the invitation is pending, accepted or declined.
+	Status string
+}
+
+// ValidMemberRole reports whether role can be given to a list member.
+func ValidMemberRole(role string) bool {
+	return role == ListRoleEditor || role == ListRoleViewer
+}
+
+// CanEditList reports whether role allows changing a list's tasks.
+func CanEditList(role string) bool {
+	return role == ListRoleOwner || role == ListRoleEditor
+}
@@ -0,0 +1,222 @@
+package domain
+
+import (
+	"crypto/sha256"
+	"crypto/subtle"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"net"
+	"net/url"
+	"strings"
+	"time"
+)
+
+// OAuthClient is a third-party application registered to access accounts
+// through the OAuth2 authorization code grant.
+type OAuthClient struct {
+	// ID is the database id for the client.
+	ID int64
+
+	// AccountID is the database foreign key to the account which registered
+	// the client.
+	AccountID int64
+
+	// ClientID is the client's public identifier.
+	ClientID string
+
+	// Created is when the client was registered.
+	Created time.Time
+
+	// Name is shown to users when they're asked to authorize the client.
+	Name string
+
+	// RedirectURIs are the only URIs authorization responses are sent to.
+	RedirectURIs []string
+
+	// SecretDigest is the SHA256 digest of the client secret, or empty if
+	// the client is public, e.g. a mobile app which can't keep a secret.
+	SecretDigest string
+}
+
+// NewClientID generates a new random public identifier for the client.
+func (c *OAuthClient) NewClientID() {
+	c.ClientID = base64.RawURLEncoding.EncodeToString(randomBytes(16))
+}
+
+// NewSecret generates a new random client secret and sets its digest. The
+// secret is returned, and should be shown to the client's owner exactly once.
+func (c *OAuthClient) NewSecret() string {
+	secret := base64.RawURLEncoding.EncodeToString(randomBytes(32))
+	c.SecretDigest = tokenDigest(secret)
+	return secret
+}
+
+// Confidential reports whether the client has a secret.
+func (c *OAuthClient) Confidential() bool {
+	return c.SecretDigest != ""
+}
+
+// AuthenticateSecret reports whether secret is the client's secret. Public
+// clients only authenticate with an empty secret.
+func (c *OAuthClient) AuthenticateSecret(secret string) bool {
+	if !c.Confidential() {
+		return secret == ""
+	}
+	return subtle.ConstantTimeCompare([]byte(tokenDigest(secret)), []byte(c.SecretDigest)) == 1
+}
+
+// HasRedirectURI reports whether uri is one of the client's redirect URIs.
+// URIs must match exactly.
+func (c *OAuthClient) HasRedirectURI(uri string) bool {
+	for _, u := range c.RedirectURIs {
+		if u == uri {
+			return true
+		}
+	}
+	return false
+}
+
+// ValidateRedirectURI checks that uri may be registered as a redirect URI. It
+// must be an absolute https URI without a fragment, except that http is
+// allowed for loopback addresses during development.
+func ValidateRedirectURI(uri string) error {
+	u, err := url.Parse(uri)
+	if err != nil || !u.IsAbs() || u.Host == "" {
+		return fmt.Errorf("redirect URI %q must be an absolute URI", uri)
+	}
+	if u.Fragment != "" {
+		return fmt.Errorf("redirect URI %q must not have a fragment", uri)
+	}
+	switch u.Scheme {
+	case "https":
+		return nil
+	case "http":
+		host := u.Hostname()
+		if ip := net.ParseIP(host); host == "localhost" || (ip != nil && ip.IsLoopback()) {
+			return nil
+		}
+	}
+	return fmt.Errorf("redirect URI %q must use https", uri)
+}
+
+// ParseScopes parses a space separated OAuth scope parameter.
+func ParseScopes(scope string) ([]string, error) {
+	var result []string
+	seen := make(map[string]bool)
+	for _, s := range strings.Fields(scope) {
+		if !ValidScope(s) {
+			return nil, fmt.Errorf("unknown scope %q", s)
+		}
+		if !seen[s] {
+			seen[s] = true
+			result = append(result, s)
+		}
+	}
+	if len(result) == 0 {
+		return nil, errors.New("at least one scope is required")
+	}
+	return result, nil
+}
+
+// OAuthCode is an authorization code, which a client exchanges for tokens once
+// an account has authorized it. Codes are bound to a PKCE challenge, so only
+// the client which started the authorization can use them.
+type OAuthCode struct {
+	// ID is the database id for the code.
+	ID int64
+
+	// AccountID is the database foreign key to the account which authorized
+	// the client.
+	AccountID int64
+
+	// Challenge is the client's S256 PKCE code challenge.
+	Challenge string
+
+	// ClientID is the database foreign key to the client.
+	ClientID int64
+
+	// Created is when the client was authorized.
+	Created time.Time
+
+	// Digest is the SHA256 digest of the code.
+	Digest string
+
+	// Expires is when the code stops working.
+	Expires time.Time
+
+	// RedirectURI is the URI the code was sent to, which must be repeated
+	// when the code is exchanged.
+	RedirectURI string
+
+	// Scopes are the scopes the account authorized.
+	Scopes []string
+
+	// Used is when the code was exchanged, if it has been.
+	Used *time.Time
+}
+
+// NewCode generates a new random code and sets its digest. The code is
+// returned, and should only be sent to the client's redirect URI.
+func (c *OAuthCode) NewCode() string {
+	code := base64.RawURLEncoding.EncodeToString(randomBytes(32))
+	c.Digest = OAuthCodeDigest(code)
+	return code
+}
+
+// VerifyChallenge reports whether verifier is the PKCE code verifier the
+// code's challenge was derived from.
+func (c *OAuthCode) VerifyChallenge(verifier string) bool {
+	digest := sha256.Sum256([]byte(verifier))
+	got := base64.RawURLEncoding.EncodeToString(digest[:])
+	return c.Challenge != "" && subtle.ConstantTimeCompare([]byte(got), []byte(c.Challenge)) == 1
+}
+
+// OAuthCodeDigest returns the digest under which an authorization code is
+// stored.
+func OAuthCodeDigest(code string) string {
+	return tokenDigest(code)
+}
+
+// OAuthRefreshToken lets a client get new access tokens without asking the
+// account to authorize it again. Refresh tokens are rotated: each one can only
+// be used once, and using it issues a new one.
+type OAuthRefreshToken struct {
+	// ID is the database id for the refresh token.
+	ID int64
+
+	// AccountID is the database foreign key to the account.
+	AccountID int64
+
+	// ClientID is the database foreign key to the client.
+	ClientID int64
+
+	// Created is when the refresh token was issued.
+	Created time.Time
+
+	// Digest is the SHA256 digest of the refresh token.
+	Digest string
+
+	// Expires is when the refresh token stops working.
+	Expires time.Time
+
+	// Revoked is when the refresh token was used or revoked, if it has been.
+	Revoked *time.Time
+
+	// Scopes are the scopes the account authorized.
+	Scopes []string
+}
+
+// NewToken generates a new random refresh token and sets its digest. The
+// token is returned, and should only be sent to the client.
+func (t *OAuthRefreshToken) NewToken() string {
+	token := base64.RawURLEncoding.EncodeToString(randomBytes(32))
+	t.Digest = OAuthRefreshTokenDigest(token)
+	return token
+}
+
+// OAuthRefreshTokenDigest returns the digest under which a refresh token is
+// stored.
+func OAuthRefreshTokenDigest(token string) string {
+	return tokenDigest(token)
+}
@@ -0,0 +1,63 @@
+package domain_test
+
+import (
+	"testing"
+
+	"github.com/deliveroo/assert-go"
+	"github.com/deliveroo/todo-api/domain"
+)
+
+func TestOAuthClient(t *testing.T) {
+	t.Run("AuthenticateSecret", func(t *testing.T) {
+		var c domain.OAuthClient
+		assert.True(t, c.AuthenticateSecret(""))
+		secret := c.NewSecret()
+		assert.True(t, c.Confidential())
+		assert.True(t, c.AuthenticateSecret(secret))
+		assert.False(t, c.AuthenticateSecret(""))
+		assert.False(t, c.AuthenticateSecret(secret+"x"))
+	})
+	t.Run("HasRedirectURI", func(t *testing.T) {
+		c := domain.OAuthClient{RedirectURIs: []string{"https://example.com/callback"}}
+		assert.True(t, c.HasRedirectURI("https://example.com/callback"))
+		assert.False(t, c.HasRedirectURI("https://example.com/callback/"))
+		assert.False(t, c.HasRedirectURI("https://example.com/callback?x=1"))
+	})
+}
+
+func TestValidateRedirectURI(t *testing.T) {
+	for uri, valid := range map[string]bool{
+		"https://example.com/callback":     true,
+		"https://example.com/callback?a=b": true,
+		"http://localhost:8080/callback":   true,
+		"http://127.0.0.1/callback":        true,
+		"http://[::1]/callback":            true,
+		"http://example.com/callback":      false,
+		"https://example.com/callback#x":   false,
+		"/callback":                        false,
+		"example.com/callback":             false,
+		"javascript:alert(1)":              false,
+	} {
+		err := domain.ValidateRedirectURI(uri)
+		assert.Equal(t, err == nil, valid)
+	}
+}
+
+func TestParseScopes(t *testing.T) {
+	scopes, err := domain.ParseScopes("tasks:read  account:read tasks:read")
+	assert.Must(t, err)
+	assert.Equal(t, scopes, []string{domain.ScopeTasksRead, domain.ScopeAccountRead})
+
+	_, err = domain.ParseScopes("")
+	assert.NotNil(t, err)
+	_, err = domain.ParseScopes("tasks:read tasks:delete")
+	assert.NotNil(t, err)
+}
+
+func TestOAuthCodeVerifyChallenge(t *testing.T) {
+	// Test vector from RFC 7636 appendix B.
+	code := domain.OAuthCode{Challenge: "E9Melhoa2OwvFrEMTJguCHaoeK1t8URWbuGJSstw-cM"}
+	assert.True(t, code.VerifyChallenge("dBjftJeZ4CVP-mB92K27uhbUJU1p1r_wW1gFWFOEjXk"))
+	assert.False(t, code.VerifyChallenge("dBjftJeZ4CVP-mB92K27uhbUJU1p1r_wW1gFWFOEjXl"))
+	assert.False(t, (&domain.OAuthCode{}).VerifyChallenge(""))
+}
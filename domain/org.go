@@ -0,0 +1,130 @@
+package domain
+
+import (
+	"errors"
+	"regexp"
+	"time"
+)
+
+// Organisation roles. Owners and admins manage an organisation's members and
+// invitations, and only the owner can make or unmake admins. Every member can
+// see and change the organisation's tasks.
+const (
+	OrgRoleOwner  = "owner"
+	OrgRoleAdmin  = "admin"
+	OrgRoleMember = "member"
+)
+
+// Org is an organisation, a workspace whose tasks are shared by all of its
+// member accounts.
+type Org struct {
+	// ID is the database id for the organisation.
+	ID int64
+
+	// PublicID is the id for the organisation in the API.
+	PublicID string
+
+	// Created is when the organisation was created.
+	Created time.Time
+
+	// Name is the organisation's display name.
+	Name string
+
+	// Role is the role in the organisation of the account it was fetched
+	// for.
+	Role string
+
+	// Slug is the unique name which identifies the organisation in URLs.
+	Slug string
+}
+
+// OrgMember is an account which belongs to an organisation.
+type OrgMember struct {
+	// OrgID is the database foreign key to the organisation.
+	OrgID int64
+
+	// AccountID is the database foreign key to the member's account.
+	AccountID int64
+
+	// AccountPublicID is the public id of the member's account.
+	AccountPublicID string
+
+	// Created is when the account joined the organisation.
+	Created time.Time
+
+	// Role is the member's role in the organisation.
+	Role string
+
+	// Username is the member's username.
+	Username string
+}
+
+// OrgInvitation is an invitation for an account to join an organisation,
+// which it can accept or decline.
+type OrgInvitation struct {
+	// ID is the database id for the invitation.
+	ID int64
+
+	// PublicID is the id for the invitation in the API.
+	PublicID string
+
+	// OrgID is the database foreign key to the organisation.
+	OrgID int64
+
+	// OrgName is the name of the organisation.
+	OrgName string
+
+	// OrgSlug is the slug of the organisation.
+	OrgSlug string
+
+	// InviterID is the database foreign key to the account which sent the
+	// invitation.
+	InviterID int64
+
+	// InviterUsername is the username of the account which sent the
+	// invitation.
+	InviterUsername string
+
+	// InviteeID is the database foreign key to the invited account.
+	InviteeID int64
+
+	// InviteeUsername is the username of the invited account.
+	InviteeUsername string
+
+	// Created is when the invitation was sent.
+	Created time.Time
+
+	// Responded is when the invitation was accepted or declined, if it has
+	// been.
+	Responded *time.Time
+
+	// Role is the role the invitee will have in the organisation.
+	Role string
+
+	// Status is whether the invitation is pending, accepted or declined.
+	Status string
+}
+
+var orgSlugPattern = regexp.MustCompile(`^[a-z0-9]+(-[a-z0-9]+)*$`)
+
+// ValidateOrgSlug checks that slug may identify an organisation. Slugs are 2
+// to 40 lowercase letters, digits and single hyphens, and can't start or end
+// with a hyphen.
+func ValidateOrgSlug(slug string) error {
+	if len(slug) < 2 || len(slug) > 40 || !orgSlugPattern.MatchString(slug) {
+		return errors.New("slug must be 2 to 40 lowercase letters, digits and hyphens")
+	}
+	return nil
+}
+
+// ValidOrgMemberRole reports whether role can be given to an organisation
+// member. There is only ever one owner.
+func ValidOrgMemberRole(role string) bool {
+	return role == OrgRoleAdmin || role == OrgRoleMember
+}
+
+// CanManageOrg reports whether role allows managing an organisation's members
+// and invitations.
+func CanManageOrg(role string) bool {
+	return role == OrgRoleOwner || role == OrgRoleAdmin
+}
@@ -0,0 +1,28 @@
+package domain_test
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/deliveroo/assert-go"
+	"github.com/deliveroo/todo-api/domain"
+)
+
+func TestValidateOrgSlug(t *testing.T) {
+	for slug, valid := range map[string]bool{
+		"acme":                  true,
+		"acme-corp":             true,
+		"a1":                    true,
+		"a":                     false,
+		"Acme":                  false,
+		"-acme":                 false,
+		"acme-":                 false,
+		"acme--corp":            false,
+		"acme corp":             false,
+		strings.Repeat("a", 40): true,
+		strings.Repeat("a", 41): false,
+	} {
+		err := domain.ValidateOrgSlug(slug)
+		assert.Equal(t, err == nil, valid)
+	}
+}
@@ -0,0 +1,67 @@
+package domain
+
+import (
+	"strconv"
+	"time"
+)
+
+// Aggregates whose changes are written to the outbox.
+const (
+	AggregateAccount = "account"
+	AggregateTask    = "task"
+)
+
+// Events written to the outbox for changes to accounts. Changes to tasks are
+// written as EventTaskCreated, EventTaskUpdated and EventTaskDeleted.
+const (
+	EventAccountCreated = "account.created"
+	EventAccountUpdated = "account.updated"
+	EventAccountDeleted = "account.deleted"
+)
+
+// OutboxEvent is a change to a task or account, written to the outbox in the
+// same transaction as the change, and published from there to integrations.
+type OutboxEvent struct {
+	// ID is the database id for the event.
+	ID int64
+
+	// Aggregate is the kind of entity which changed, e.g. AggregateTask.
+	Aggregate string
+
+	// AggregateID is the database id of the entity which changed.
+	AggregateID int64
+
+	// AggregatePublicID is the public id of the entity which changed, which
+	// identifies it to the sinks the event is published to.
+	AggregatePublicID string
+
+	// Attempts is how many times publishing the event has failed.
+	Attempts int
+
+	// Created is when the change was made.
+	Created time.Time
+
+	// Event is the event name, e.g. EventTaskCreated.
+	Event string
+
+	// LastError describes why the last attempt to publish the event failed,
+	// or is empty if none has.
+	LastError string
+
+	// NextAttempt is when the event will next be published, until it has
+	// been.
+	NextAttempt time.Time
+
+	// Payload is the JSON encoded entity after the change, or before it was
+	// deleted.
+	Payload []byte
+
+	// Published is when the event was published, or nil if it hasn't been.
+	Published *time.Time
+}
+
+// DedupKey identifies the event to the sinks it's published to, which may
+// receive it more than once.
+func (e *OutboxEvent) DedupKey() string {
+	return "outbox:" + strconv.FormatInt(e.ID, 10)
+}
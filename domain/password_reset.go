@@ -0,0 +1,44 @@
+package domain
+
+import (
+	"encoding/base64"
+	"time"
+)
+
+// PasswordReset is a request to reset an account's password, made by someone
+// who has forgotten it. The token is sent to the account's email address and is
+// valid once, until it expires.
+type PasswordReset struct {
+	// ID is the database id for the password reset.
+	ID int64
+
+	// AccountID is the database foreign key to the account.
+	AccountID int64
+
+	// Created is when the password reset was requested.
+	Created time.Time
+
+	// Digest is the SHA256 digest of the token. The token itself is only
+	// known to the recipient of the reset email.
+	Digest string
+
+	// Expires is when the token stops working.
+	Expires time.Time
+
+	// Used is when the token was used to reset the password, if it has been.
+	Used *time.Time
+}
+
+// NewToken generates a new random token and sets its digest. The token is
+// returned, and should only be sent to the account's email address.
+func (r *PasswordReset) NewToken() string {
+	token := base64.RawURLEncoding.EncodeToString(randomBytes(32))
+	r.Digest = PasswordResetDigest(token)
+	return token
+}
+
+// PasswordResetDigest returns the digest under which a password reset token is
+// stored.
+func PasswordResetDigest(token string) string {
+	return tokenDigest(token)
+}
@@ -0,0 +1,61 @@
+package domain
+
+import (
+	"encoding/binary"
+	"encoding/hex"
+	"time"
+)
+
+// NewPublicID returns a new public id, which identifies a resource, such as a
+// task, an account or a list, in the API in place of its database id, so
+// that ids don't reveal how many there are or can be guessed. Public ids are
+// version 7 UUIDs: they start with the time they were created, to the
+// millisecond, so that they sort and index well, and the rest is random.
+func NewPublicID() string {
+	var b [16]byte
+	binary.BigEndian.PutUint64(b[:8], uint64(time.Now().UnixNano()/int64(time.Millisecond))<<16)
+	copy(b[6:], randomBytes(10))
+	b[6] = b[6]&0x0f | 0x70 // version 7
+	b[8] = b[8]&0x3f | 0x80 // RFC 4122 variant
+	var s [36]byte
+	hex.Encode(s[0:8], b[0:4])
+	s[8] = '-'
+	hex.Encode(s[9:13], b[4:6])
+	s[13] = '-'
+	hex.Encode(s[14:18], b[6:8])
+	s[18] = '-'
+	hex.Encode(s[19:23], b[8:10])
+	s[23] = '-'
+	hex.Encode(s[24:], b[10:])
+	return string(s[:])
+}
+
+// ValidPublicID reports whether s is a public id, in the form NewPublicID
+// returns: a version 7 UUID in lower case, with hyphens.
+func ValidPublicID(s string) bool {
+	if len(s) != 36 {
+		return false
+	}
+	for i := 0; i < len(s); i++ {
+		c := s[i]
+		switch i {
+		case 8, 13, 18, 23:
+			if c != '-' {
+				return false
+			}
+		case 14:
+			if c != '7' {
+				return false
+			}
+		case 19:
+			if c != '8' && c != '9' && c != 'a' && c != 'b' {
+				return false
+			}
+		default:
+			if (c < '0' || c > '9') && (c < 'a' || c > 'f') {
+				return false
+			}
+		}
+	}
+	return true
+}
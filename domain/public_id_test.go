@@ -0,0 +1,42 @@
+package domain_test
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/deliveroo/assert-go"
+	"github.com/deliveroo/todo-api/domain"
+)
+
+func TestNewPublicID(t *testing.T) {
+	a, b := domain.NewPublicID(), domain.NewPublicID()
+	assert.True(t, domain.ValidPublicID(a))
+	assert.True(t, domain.ValidPublicID(b))
+	assert.True(t, a != b)
+	// Ids start with the time they were created, so later ids sort after
+	// earlier ones, to the millisecond.
+	assert.True(t, a[:8] <= b[:8])
+}
+
+func TestValidPublicID(t *testing.T) {
+	tests := []struct {
+		id   string
+		want bool
+	}{
+		{"01890a5d-ac96-774b-bcce-b302099a8057", true},
+		{"01890a5d-ac96-774b-8cce-b302099a8057", true},
+		{"", false},
+		{"42", false},
+		{"01890A5D-AC96-774B-BCCE-B302099A8057", false},  // upper case
+		{"01890a5d-ac96-474b-bcce-b302099a8057", false},  // version 4
+		{"01890a5d-ac96-774b-ccce-b302099a8057", false},  // wrong variant
+		{"01890a5dac96774bbcceb302099a8057", false},      // no hyphens
+		{"01890a5d-ac96-774b-bcce-b302099a805g", false},  // not hex
+		{"01890a5d-ac96-774b-bcce-b302099a80577", false}, // too long
+		{"{01890a5d-ac96-774b-bcce-b302099a8057}", false},
+	}
+	for _, tt := range tests {
+		assert.Equal(t, domain.ValidPublicID(tt.id), tt.want)
+	}
+	assert.False(t, domain.ValidPublicID(strings.Repeat("-", 36)))
+}
@@ -0,0 +1,34 @@
+package domain
+
+import (
+	"encoding/base32"
+	"strings"
+)
+
+// RecoveryCodeCount is the number of recovery codes issued when two-factor
+// authentication is enabled.
+const RecoveryCodeCount = 10
+
+var recoveryCodeEncoding = base32.StdEncoding.WithPadding(base32.NoPadding)
+
+// NewRecoveryCodes generates n single-use recovery codes, which can be used
+// instead of a TOTP code if an authenticator app is lost. It returns the codes,
+// to be shown to the account owner exactly once, and the digests under which
+// they are stored.
+func NewRecoveryCodes(n int) (codes, digests []string) {
+	for i := 0; i < n; i++ {
+		s := strings.ToLower(recoveryCodeEncoding.EncodeToString(randomBytes(5)))
+		code := s[:4] + "-" + s[4:]
+		codes = append(codes, code)
+		digests = append(digests, RecoveryCodeDigest(code))
+	}
+	return codes, digests
+}
+
+// RecoveryCodeDigest returns the digest under which a recovery code is stored.
+// Codes are normalized first, so they may be entered in any case, with or
+// without the separator.
+func RecoveryCodeDigest(code string) string {
+	code = strings.ToLower(strings.NewReplacer("-", "", " ", "").Replace(code))
+	return tokenDigest(code)
+}
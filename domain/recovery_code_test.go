@@ -0,0 +1,23 @@
+package domain_test
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/deliveroo/assert-go"
+	"github.com/deliveroo/todo-api/domain"
+)
+
+func TestNewRecoveryCodes(t *testing.T) {
+	codes, digests := domain.NewRecoveryCodes(domain.RecoveryCodeCount)
+	assert.Equal(t, len(codes), domain.RecoveryCodeCount)
+	assert.Equal(t, len(digests), domain.RecoveryCodeCount)
+	seen := make(map[string]bool)
+	for i, code := range codes {
+		assert.False(t, seen[code])
+		seen[code] = true
+		assert.Equal(t, digests[i], domain.RecoveryCodeDigest(code))
+		normalized := strings.ToUpper(strings.ReplaceAll(code, "-", ""))
+		assert.Equal(t, digests[i], domain.RecoveryCodeDigest(normalized))
+	}
+}
@@ -0,0 +1,98 @@
+package domain
+
+import "time"
+
+// Strategies for resolving conflicts between changes a client made while
+// offline and changes made on the server since it last synced.
+const (
+	// SyncLastWriterWins keeps whichever change was made last, in full.
+	SyncLastWriterWins = "lww"
+
+	// SyncMerge keeps the fields changed on only one side, and the server's
+	// value of fields changed on both.
+	SyncMerge = "merge"
+)
+
+// TaskChange is the latest change to a task in an account's sequence of
+// changes, from which its clients sync.
+type TaskChange struct {
+	// AccountID is the database foreign key to the account whose sequence
+	// the change is in.
+	AccountID int64
+
+	// Changed is the time of the change.
+	Changed time.Time
+
+	// Deleted is true if the task was deleted.
+	Deleted bool
+
+	// OrgID is the database foreign key to the organisation the task belongs
+	// to, or nil if it doesn't belong to one.
+	OrgID *int64
+
+	// Seq is the change's number in the account's sequence.
+	Seq int64
+
+	// TaskID is the database foreign key to the task.
+	TaskID int64
+
+	// TaskPublicID is the public id of the task, which is kept so that
+	// clients can be told about deleted tasks.
+	TaskPublicID string
+}
+
+// TaskFields are the fields of a task which clients can change while
+// offline.
+type TaskFields struct {
+	Completed   *time.Time
+	Description string
+}
+
+// Fields returns the fields of the task which clients can change while
+// offline.
+func (t *Task) Fields() TaskFields {
+	return TaskFields{Completed: t.Completed, Description: t.Description}
+}
+
+// Diff returns the names of the fields which differ, as they're named in the
+// API.
+func (f TaskFields) Diff(other TaskFields) []string {
+	var result []string
+	if !timesEqual(f.Completed, other.Completed) {
+		result = append(result, "completed")
+	}
+	if f.Description != other.Description {
+		result = append(result, "description")
+	}
+	return result
+}
+
+// MergeTaskFields merges the fields of a task changed by a client with those
+// on the server. base is the fields as the client last synced them. Fields
+// changed on only one side take that side's value. Fields changed on both
+// sides to different values are conflicts, and keep the server's value.
+func MergeTaskFields(base, client, server TaskFields) (merged TaskFields, conflicts []string) {
+	merged = server
+	if !timesEqual(client.Completed, base.Completed) {
+		if timesEqual(server.Completed, base.Completed) {
+			merged.Completed = client.Completed
+		} else if !timesEqual(server.Completed, client.Completed) {
+			conflicts = append(conflicts, "completed")
+		}
+	}
+	if client.Description != base.Description {
+		if server.Description == base.Description {
+			merged.Description = client.Description
+		} else if server.Description != client.Description {
+			conflicts = append(conflicts, "description")
+		}
+	}
+	return merged, conflicts
+}
+
+func timesEqual(a, b *time.Time) bool {
+	if a == nil || b == nil {
+		return a == b
+	}
+	return a.Equal(*b)
+}
@@ -0,0 +1,60 @@
+package domain_test
+
+import (
+	"testing"
+	"time"
+
+	"github.com/deliveroo/assert-go"
+	"github.com/deliveroo/todo-api/domain"
+)
+
+func TestMergeTaskFields(t *testing.T) {
+	done := time.Date(2026, 10, 19, 12, 0, 0, 0, time.UTC)
+	later := done.Add(time.Hour)
+	base := domain.TaskFields{Description: "buy milk"}
+	for name, tt := range map[string]struct {
+		client, server domain.TaskFields
+		want           domain.TaskFields
+		conflicts      []string
+	}{
+		"client only": {
+			client: domain.TaskFields{Description: "buy oat milk"},
+			server: base,
+			want:   domain.TaskFields{Description: "buy oat milk"},
+		},
+		"server only": {
+			client: base,
+			server: domain.TaskFields{Description: "buy oat milk", Completed: &done},
+			want:   domain.TaskFields{Description: "buy oat milk", Completed: &done},
+		},
+		"different fields": {
+			client: domain.TaskFields{Description: "buy milk", Completed: &done},
+			server: domain.TaskFields{Description: "buy oat milk"},
+			want:   domain.TaskFields{Description: "buy oat milk", Completed: &done},
+		},
+		"same change": {
+			client: domain.TaskFields{Description: "buy oat milk", Completed: &done},
+			server: domain.TaskFields{Description: "buy oat milk", Completed: &done},
+			want:   domain.TaskFields{Description: "buy oat milk", Completed: &done},
+		},
+		"conflict": {
+			client:    domain.TaskFields{Description: "buy soy milk", Completed: &later},
+			server:    domain.TaskFields{Description: "buy oat milk", Completed: &done},
+			want:      domain.TaskFields{Description: "buy oat milk", Completed: &done},
+			conflicts: []string{"completed", "description"},
+		},
+	} {
+		t.Run(name, func(t *testing.T) {
+			got, conflicts := domain.MergeTaskFields(base, tt.client, tt.server)
+			assert.Equal(t, got, tt.want)
+			assert.Equal(t, conflicts, tt.conflicts)
+		})
+	}
+}
+
+func TestTaskFieldsDiff(t *testing.T) {
+	done := time.Now()
+	a := domain.TaskFields{Description: "buy milk"}
+	assert.Equal(t, len(a.Diff(a)), 0)
+	assert.Equal(t, a.Diff(domain.TaskFields{Description: "buy milk", Completed: &done}), []string{"completed"})
+}
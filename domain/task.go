@@ -2,20 +2,65 @@ package domain
 
 import "time"
 
-// Task is a single todo item which belongs to an account.
+// Task is a single todo item which belongs to an account, and optionally to
+// a list shared with other accounts. It may be assigned to another account to
+// do.
 type Task struct {
 	// ID is the database id for the task.
 	ID int64
 
-	// AccountID is the database foreign key to the account.
+	// PublicID is the id for the task in the API, where its database id is
+	// never shown. See NewPublicID.
+	PublicID string
+
+	// AccountID is the database foreign key to the account which owns the
+	// task. Tasks in a list are owned by the list's owner.
 	AccountID int64
 
+	// AssigneeID is the database foreign key to the account the task is
+	// assigned to, or nil if it is unassigned.
+	AssigneeID *int64
+
 	// Completed is the time when the task was marked completed.
 	Completed *time.Time
 
 	// Created is the time when the task was created.
 	Created time.Time
 
+	// CreatedBy is the database foreign key to the account which created the
+	// task.
+	CreatedBy int64
+
 	// Description is the task description.
 	Description string
+
+	// ListID is the database foreign key to the list the task belongs to, or
+	// nil if the task is private to its account.
+	ListID *int64
+
+	// ListPublicID is the public id of the list the task belongs to, or nil
+	// if it doesn't belong to one.
+	ListPublicID *string
+
+	// OrgID is the database foreign key to the organisation the task belongs
+	// to, or nil if it doesn't belong to one.
+	OrgID *int64
+}
+
+// AssignedTo reports whether the task is assigned to an account.
+func (t *Task) AssignedTo(accountID int64) bool {
+	return t.AssigneeID != nil && *t.AssigneeID == accountID
+}
+
+// TaskStats summarises an account's tasks.
+type TaskStats struct {
+	// Total is the number of tasks.
+	Total int64
+
+	// Completed is the number of completed tasks.
+	Completed int64
+
+	// LastCreated is when the newest task was created, or nil if there are
+	// no tasks.
+	LastCreated *time.Time
 }
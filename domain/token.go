@@ -0,0 +1,13 @@
+package domain
+
+import (
+	"crypto/sha256"
+	"encoding/base64"
+)
+
+// tokenDigest returns the SHA256 digest of a random token. Unlike passwords,
+// tokens are long and random, so they don't need to be salted.
+func tokenDigest(token string) string {
+	digest := sha256.Sum256([]byte(token))
+	return base64.RawURLEncoding.EncodeToString(digest[:])
+}
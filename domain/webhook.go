@@ -0,0 +1,218 @@
+package domain
+
+import (
+	"encoding/base64"
+	"fmt"
+	"net"
+	"net/url"
+	"strings"
+	"time"
+)
+
+// Webhook events.
+const (
+	EventTaskCreated   = "task.created"
+	EventTaskUpdated   = "task.updated"
+	EventTaskCompleted = "task.completed"
+	EventTaskDeleted   = "task.deleted"
+
+	// EventPing is sent by the test endpoint, whatever events a webhook is
+	// subscribed to.
+	EventPing = "ping"
+)
+
+// WebhookEvents are the events webhooks may subscribe to.
+var WebhookEvents = []string{
+	EventTaskCreated,
+	EventTaskUpdated,
+	EventTaskCompleted,
+	EventTaskDeleted,
+}
+
+// Webhook delivery statuses.
+const (
+	DeliveryPending   = "pending"
+	DeliveryDelivered = "delivered"
+	DeliveryFailed    = "failed"
+)
+
+// Webhook is an endpoint registered by an account to be sent events about its
+// tasks.
+type Webhook struct {
+	// ID is the database id for the webhook.
+	ID int64
+
+	// PublicID is the id for the webhook in the API.
+	PublicID string
+
+	// AccountID is the database foreign key to the account which registered
+	// the webhook.
+	AccountID int64
+
+	// Created is when the webhook was registered.
+	Created time.Time
+
+	// Events are the events the webhook is subscribed to.
+	Events []string
+
+	// Secret is the key payloads are signed with, so that the endpoint can
+	// check they came from us. Unlike other secrets it isn't digested, since
+	// it's needed to sign every payload.
+	Secret string
+
+	// URL is where events are posted.
+	URL string
+}
+
+// NewSecret generates a new random signing secret for the webhook.
+func (w *Webhook) NewSecret() {
+	w.Secret = "whsec_" + base64.RawURLEncoding.EncodeToString(randomBytes(32))
+}
+
+// Subscribed reports whether the webhook is subscribed to an event.
+func (w *Webhook) Subscribed(event string) bool {
+	for _, e := range w.Events {
+		if e == event {
+			return true
+		}
+	}
+	return false
+}
+
+// ValidWebhookEvent reports whether event is one webhooks may subscribe to.
+func ValidWebhookEvent(event string) bool {
+	for _, e := range WebhookEvents {
+		if e == event {
+			return true
+		}
+	}
+	return false
+}
+
+// ValidateWebhookURL checks that uri may be registered as a webhook. Like
+// redirect URIs, it must be an absolute https URI, except that http is allowed
+// for loopback addresses during development. Unless allowPrivate is true, it
+// can't be localhost or a private or reserved address, so that webhooks can't
+// be used to reach the server's own network. Host names are resolved when
+// webhooks are delivered, not here.
+func ValidateWebhookURL(uri string, allowPrivate bool) error {
+	u, err := url.Parse(uri)
+	if err != nil || !u.IsAbs() || u.Host == "" {
+		return fmt.Errorf("webhook URL %q must be an absolute URL", uri)
+	}
+	host := strings.ToLower(u.Hostname())
+	ip := net.ParseIP(host)
+	localhost := host == "localhost" || strings.HasSuffix(host, ".localhost")
+	if !allowPrivate && (localhost || (ip != nil && !PublicAddress(ip))) {
+		return fmt.Errorf("webhook URL %q must not refer to a private address", uri)
+	}
+	switch u.Scheme {
+	case "https":
+		return nil
+	case "http":
+		if localhost || (ip != nil && ip.IsLoopback()) {
+			return nil
+		}
+	}
+	return fmt.Errorf("webhook URL %q must use https", uri)
+}
+
+// reservedNetworks are the loopback, private, link-local, shared, multicast
+// and otherwise reserved networks, which webhooks may not be delivered to.
+// Cloud metadata services are on link-local addresses.
+var reservedNetworks = parseCIDRs(
+	"0.0.0.0/8",
+	"10.0.0.0/8",
+	"100.64.0.0/10",
+	"127.0.0.0/8",
+	"169.254.0.0/16",
+	"172.16.0.0/12",
+	"192.0.0.0/24",
+	"192.0.2.0/24",
+	"192.88.99.0/24",
+	"192.168.0.0/16",
+	"198.18.0.0/15",
+	"198.51.100.0/24",
+	"203.0.113.0/24",
+	"224.0.0.0/4",
+	"240.0.0.0/4",
+	"::/128",
+	"::1/128",
+	"64:ff9b::/96",
+	"100::/64",
+	"2001::/23",
+	"2001:db8::/32",
+	"2002::/16",
+	"fc00::/7",
+	"fe80::/10",
+	"ff00::/8",
+)
+
+// PublicAddress reports whether ip is a public address, which webhooks may be
+// delivered to, rather than a loopback, private or reserved one.
+func PublicAddress(ip net.IP) bool {
+	for _, n := range reservedNetworks {
+		if n.Contains(ip) {
+			return false
+		}
+	}
+	return true
+}
+
+func parseCIDRs(cidrs ...string) []*net.IPNet {
+	result := make([]*net.IPNet, 0, len(cidrs))
+	for _, cidr := range cidrs {
+		_, n, err := net.ParseCIDR(cidr)
+		if err != nil {
+			panic(err)
+		}
+		result = append(result, n)
+	}
+	return result
+}
+
+// WebhookDelivery is an event queued to be posted to a webhook, and the
+// outcome of posting it.
+type WebhookDelivery struct {
+	// ID is the database id for the delivery.
+	ID int64
+
+	// PublicID is the id for the delivery in the API. It's sent with the
+	// delivery so that endpoints can ignore retries of deliveries they've
+	// already handled.
+	PublicID string
+
+	// WebhookID is the database foreign key to the webhook the event is sent
+	// to.
+	WebhookID int64
+
+	// Attempts is how many times sending the event has been attempted.
+	Attempts int
+
+	// Created is when the event was queued.
+	Created time.Time
+
+	// Delivered is when the event was delivered, or nil if it hasn't been.
+	Delivered *time.Time
+
+	// Event is the event name, e.g. EventTaskCreated.
+	Event string
+
+	// LastError describes why the last attempt failed, or is empty if it
+	// didn't.
+	LastError string
+
+	// NextAttempt is when the event will next be sent, while it's pending.
+	NextAttempt time.Time
+
+	// Payload is the JSON body posted to the webhook.
+	Payload []byte
+
+	// ResponseStatus is the HTTP status the endpoint responded to the last
+	// attempt with, or nil if it didn't respond.
+	ResponseStatus *int
+
+	// Status is DeliveryPending until the event is delivered, or given up on
+	// after too many attempts.
+	Status string
+}
@@ -0,0 +1,96 @@
+package domain_test
+
+import (
+	"net"
+	"testing"
+
+	"github.com/deliveroo/assert-go"
+	"github.com/deliveroo/todo-api/domain"
+)
+
+func TestWebhook(t *testing.T) {
+	t.Run("NewSecret", func(t *testing.T) {
+		var a, b domain.Webhook
+		a.NewSecret()
+		b.NewSecret()
+		assert.True(t, a.Secret != "")
+		assert.True(t, a.Secret != b.Secret)
+	})
+	t.Run("Subscribed", func(t *testing.T) {
+		w := domain.Webhook{Events: []string{domain.EventTaskCreated}}
+		assert.True(t, w.Subscribed(domain.EventTaskCreated))
+		assert.False(t, w.Subscribed(domain.EventTaskDeleted))
+	})
+}
+
+func TestValidWebhookEvent(t *testing.T) {
+	for _, e := range domain.WebhookEvents {
+		assert.True(t, domain.ValidWebhookEvent(e))
+	}
+	assert.False(t, domain.ValidWebhookEvent(domain.EventPing))
+	assert.False(t, domain.ValidWebhookEvent("task.*"))
+}
+
+func TestValidateWebhookURL(t *testing.T) {
+	for uri, valid := range map[string]bool{
+		"https://example.com/hooks":     true,
+		"https://example.com/hooks?a=b": true,
+		"https://93.184.216.34/hooks":   true,
+		"https://[2606:4700::1]/hooks":  true,
+		"http://example.com/hooks":      false,
+		"ftp://example.com/hooks":       false,
+		"/hooks":                        false,
+		"example.com/hooks":             false,
+
+		// Private and reserved addresses.
+		"http://localhost:8080/hooks":              false,
+		"https://localhost/hooks":                  false,
+		"https://app.localhost/hooks":              false,
+		"http://127.0.0.1/hooks":                   false,
+		"https://10.0.0.1/hooks":                   false,
+		"https://172.16.5.4/hooks":                 false,
+		"https://192.168.1.1/hooks":                false,
+		"https://169.254.169.254/latest/meta-data": false,
+		"https://0.0.0.0/hooks":                    false,
+		"https://[::1]/hooks":                      false,
+		"https://[::ffff:10.0.0.1]/hooks":          false,
+		"https://[fd00:ec2::254]/hooks":            false,
+		"https://[fe80::1]/hooks":                  false,
+	} {
+		err := domain.ValidateWebhookURL(uri, false)
+		assert.Equal(t, err == nil, valid)
+	}
+
+	// During development, http is allowed for loopback addresses.
+	for uri, valid := range map[string]bool{
+		"http://localhost:8080/hooks": true,
+		"http://127.0.0.1/hooks":      true,
+		"https://10.0.0.1/hooks":      true,
+		"http://10.0.0.1/hooks":       false,
+	} {
+		err := domain.ValidateWebhookURL(uri, true)
+		assert.Equal(t, err == nil, valid)
+	}
+}
+
+func TestPublicAddress(t *testing.T) {
+	for ip, public := range map[string]bool{
+		"93.184.216.34":    true,
+		"8.8.8.8":          true,
+		"2606:4700::1":     true,
+		"127.0.0.1":        false,
+		"10.1.2.3":         false,
+		"100.64.0.1":       false,
+		"169.254.169.254":  false,
+		"172.31.255.255":   false,
+		"192.168.0.1":      false,
+		"224.0.0.1":        false,
+		"255.255.255.255":  false,
+		"::1":              false,
+		"::ffff:127.0.0.1": false,
+		"fd00:ec2::254":    false,
+		"fe80::1":          false,
+	} {
+		assert.Equal(t, domain.PublicAddress(net.ParseIP(ip)), public)
+	}
+}
@@ -1,6 +1,6 @@
 module github.com/deliveroo/todo-api
 
-go 1.13
+go 1.16
 
 require (
 	github.com/caarlos0/env/v6 v6.1.0
@@ -16,7 +16,6 @@ require (
 	github.com/jackc/pgconn v1.3.2
 	github.com/jackc/pgx v3.6.2+incompatible
 	github.com/jackc/pgx/v4 v4.4.1
-	github.com/johngibb/migrate v0.0.0-20200217212344-e4751791860e
 	github.com/joho/godotenv v1.3.0
 	github.com/jstemmer/go-junit-report v0.9.1
 	github.com/oklog/run v1.1.0
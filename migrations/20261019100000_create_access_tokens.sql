@@ -0,0 +1,13 @@
+CREATE TABLE IF NOT EXISTS access_tokens (
+    id SERIAL PRIMARY KEY,
+    account_id INTEGER NOT NULL,
+    name TEXT NOT NULL,
+    token_digest TEXT NOT NULL,
+    scopes TEXT[] NOT NULL,
+    expires TIMESTAMP WITHOUT TIME ZONE,
+    last_used TIMESTAMP WITHOUT TIME ZONE,
+    created TIMESTAMP WITHOUT TIME ZONE NOT NULL DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE UNIQUE INDEX CONCURRENTLY IF NOT EXISTS access_tokens_token_digest_idx ON access_tokens(token_digest);
+CREATE INDEX CONCURRENTLY IF NOT EXISTS access_tokens_account_id_idx ON access_tokens(account_id);
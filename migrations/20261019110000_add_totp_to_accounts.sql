@@ -0,0 +1,12 @@
+ALTER TABLE accounts ADD COLUMN totp_secret TEXT NOT NULL DEFAULT '';
+ALTER TABLE accounts ADD COLUMN totp_enabled TIMESTAMP WITHOUT TIME ZONE;
+
+CREATE TABLE IF NOT EXISTS recovery_codes (
+    id SERIAL PRIMARY KEY,
+    account_id INTEGER NOT NULL,
+    code_digest TEXT NOT NULL,
+    used TIMESTAMP WITHOUT TIME ZONE,
+    created TIMESTAMP WITHOUT TIME ZONE NOT NULL DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE UNIQUE INDEX CONCURRENTLY IF NOT EXISTS recovery_codes_account_id_code_digest_idx ON recovery_codes(account_id, code_digest);
@@ -0,0 +1,9 @@
+CREATE TABLE IF NOT EXISTS failed_logins (
+    id SERIAL PRIMARY KEY,
+    account_id INTEGER NOT NULL,
+    ip_address TEXT NOT NULL,
+    user_agent TEXT NOT NULL,
+    created TIMESTAMP WITHOUT TIME ZONE NOT NULL DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE INDEX CONCURRENTLY IF NOT EXISTS failed_logins_account_id_created_idx ON failed_logins(account_id, created);
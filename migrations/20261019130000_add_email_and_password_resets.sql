@@ -0,0 +1,14 @@
+ALTER TABLE accounts ADD COLUMN email TEXT NOT NULL DEFAULT '';
+
+CREATE UNIQUE INDEX CONCURRENTLY IF NOT EXISTS accounts_email_idx ON accounts(lower(email)) WHERE email <> '';
+
+CREATE TABLE IF NOT EXISTS password_resets (
+    id SERIAL PRIMARY KEY,
+    account_id INTEGER NOT NULL,
+    token_digest TEXT NOT NULL,
+    expires TIMESTAMP WITHOUT TIME ZONE NOT NULL,
+    used TIMESTAMP WITHOUT TIME ZONE,
+    created TIMESTAMP WITHOUT TIME ZONE NOT NULL DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE UNIQUE INDEX CONCURRENTLY IF NOT EXISTS password_resets_token_digest_idx ON password_resets(token_digest);
@@ -0,0 +1,13 @@
+ALTER TABLE accounts ADD COLUMN verified_at TIMESTAMP WITHOUT TIME ZONE;
+
+CREATE TABLE IF NOT EXISTS email_verifications (
+    id SERIAL PRIMARY KEY,
+    account_id INTEGER NOT NULL,
+    email TEXT NOT NULL,
+    token_digest TEXT NOT NULL,
+    expires TIMESTAMP WITHOUT TIME ZONE NOT NULL,
+    used TIMESTAMP WITHOUT TIME ZONE,
+    created TIMESTAMP WITHOUT TIME ZONE NOT NULL DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE UNIQUE INDEX CONCURRENTLY IF NOT EXISTS email_verifications_token_digest_idx ON email_verifications(token_digest);
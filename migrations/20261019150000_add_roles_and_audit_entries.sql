@@ -0,0 +1,14 @@
+ALTER TABLE accounts ADD COLUMN role TEXT NOT NULL DEFAULT 'user';
+ALTER TABLE accounts ADD COLUMN suspended_at TIMESTAMP WITHOUT TIME ZONE;
+ALTER TABLE accounts ADD COLUMN suspension_reason TEXT NOT NULL DEFAULT '';
+
+CREATE TABLE IF NOT EXISTS audit_entries (
+    id SERIAL PRIMARY KEY,
+    actor_id INTEGER,
+    action TEXT NOT NULL,
+    account_id INTEGER NOT NULL,
+    details JSONB NOT NULL DEFAULT '{}',
+    created TIMESTAMP WITHOUT TIME ZONE NOT NULL DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE INDEX CONCURRENTLY IF NOT EXISTS audit_entries_account_id_idx ON audit_entries(account_id);
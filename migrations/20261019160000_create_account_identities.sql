@@ -0,0 +1,11 @@
+CREATE TABLE IF NOT EXISTS account_identities (
+    id SERIAL PRIMARY KEY,
+    account_id INTEGER NOT NULL,
+    issuer TEXT NOT NULL,
+    subject TEXT NOT NULL,
+    email TEXT NOT NULL DEFAULT '',
+    created TIMESTAMP WITHOUT TIME ZONE NOT NULL DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE UNIQUE INDEX CONCURRENTLY IF NOT EXISTS account_identities_issuer_subject_idx ON account_identities(issuer, subject);
+CREATE INDEX CONCURRENTLY IF NOT EXISTS account_identities_account_id_idx ON account_identities(account_id);
@@ -0,0 +1,47 @@
+CREATE TABLE IF NOT EXISTS oauth_clients (
+    id SERIAL PRIMARY KEY,
+    account_id INTEGER NOT NULL,
+    client_id TEXT NOT NULL,
+    name TEXT NOT NULL,
+    redirect_uris TEXT[] NOT NULL,
+    secret_digest TEXT NOT NULL DEFAULT '',
+    created TIMESTAMP WITHOUT TIME ZONE NOT NULL DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE UNIQUE INDEX CONCURRENTLY IF NOT EXISTS oauth_clients_client_id_idx ON oauth_clients(client_id);
+CREATE INDEX CONCURRENTLY IF NOT EXISTS oauth_clients_account_id_idx ON oauth_clients(account_id);
+
+CREATE TABLE IF NOT EXISTS oauth_codes (
+    id SERIAL PRIMARY KEY,
+    account_id INTEGER NOT NULL,
+    client_id INTEGER NOT NULL,
+    code_digest TEXT NOT NULL,
+    code_challenge TEXT NOT NULL,
+    redirect_uri TEXT NOT NULL,
+    scopes TEXT[] NOT NULL,
+    expires TIMESTAMP WITHOUT TIME ZONE NOT NULL,
+    used TIMESTAMP WITHOUT TIME ZONE,
+    created TIMESTAMP WITHOUT TIME ZONE NOT NULL DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE UNIQUE INDEX CONCURRENTLY IF NOT EXISTS oauth_codes_code_digest_idx ON oauth_codes(code_digest);
+
+CREATE TABLE IF NOT EXISTS oauth_refresh_tokens (
+    id SERIAL PRIMARY KEY,
+    account_id INTEGER NOT NULL,
+    client_id INTEGER NOT NULL,
+    token_digest TEXT NOT NULL,
+    scopes TEXT[] NOT NULL,
+    expires TIMESTAMP WITHOUT TIME ZONE NOT NULL,
+    revoked TIMESTAMP WITHOUT TIME ZONE,
+    created TIMESTAMP WITHOUT TIME ZONE NOT NULL DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE UNIQUE INDEX CONCURRENTLY IF NOT EXISTS oauth_refresh_tokens_token_digest_idx ON oauth_refresh_tokens(token_digest);
+CREATE INDEX CONCURRENTLY IF NOT EXISTS oauth_refresh_tokens_account_id_idx ON oauth_refresh_tokens(account_id);
+
+-- Access tokens issued to OAuth clients are stored alongside personal access
+-- tokens, so that AuthMiddleware accepts both.
+ALTER TABLE access_tokens ADD COLUMN IF NOT EXISTS client_id INTEGER;
+
+CREATE INDEX CONCURRENTLY IF NOT EXISTS access_tokens_client_id_idx ON access_tokens(client_id);
@@ -0,0 +1,37 @@
+CREATE TABLE IF NOT EXISTS lists (
+    id SERIAL PRIMARY KEY,
+    account_id INTEGER NOT NULL,
+    name TEXT NOT NULL,
+    created TIMESTAMP WITHOUT TIME ZONE NOT NULL DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE INDEX CONCURRENTLY IF NOT EXISTS lists_account_id_idx ON lists(account_id);
+
+CREATE TABLE IF NOT EXISTS list_members (
+    id SERIAL PRIMARY KEY,
+    list_id INTEGER NOT NULL,
+    account_id INTEGER NOT NULL,
+    role TEXT NOT NULL,
+    created TIMESTAMP WITHOUT TIME ZONE NOT NULL DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE UNIQUE INDEX CONCURRENTLY IF NOT EXISTS list_members_list_id_account_id_idx ON list_members(list_id, account_id);
+CREATE INDEX CONCURRENTLY IF NOT EXISTS list_members_account_id_idx ON list_members(account_id);
+
+CREATE TABLE IF NOT EXISTS list_invitations (
+    id SERIAL PRIMARY KEY,
+    list_id INTEGER NOT NULL,
+    inviter_id INTEGER NOT NULL,
+    invitee_id INTEGER NOT NULL,
+    role TEXT NOT NULL,
+    status TEXT NOT NULL DEFAULT 'pending',
+    responded TIMESTAMP WITHOUT TIME ZONE,
+    created TIMESTAMP WITHOUT TIME ZONE NOT NULL DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE UNIQUE INDEX CONCURRENTLY IF NOT EXISTS list_invitations_pending_idx ON list_invitations(list_id, invitee_id) WHERE status = 'pending';
+CREATE INDEX CONCURRENTLY IF NOT EXISTS list_invitations_invitee_id_idx ON list_invitations(invitee_id);
+
+ALTER TABLE tasks ADD COLUMN IF NOT EXISTS list_id INTEGER;
+
+CREATE INDEX CONCURRENTLY IF NOT EXISTS tasks_list_id_idx ON tasks(list_id);
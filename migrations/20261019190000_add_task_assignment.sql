@@ -0,0 +1,15 @@
+ALTER TABLE tasks ADD COLUMN IF NOT EXISTS created_by INTEGER;
+ALTER TABLE tasks ADD COLUMN IF NOT EXISTS assignee_id INTEGER;
+
+UPDATE tasks SET created_by = account_id WHERE created_by IS NULL;
+
+-- Tasks in a list now belong to the list's owner, whoever created them.
+UPDATE tasks SET account_id = lists.account_id
+FROM lists
+WHERE tasks.list_id = lists.id
+AND tasks.account_id <> lists.account_id;
+
+ALTER TABLE tasks ALTER COLUMN created_by SET NOT NULL;
+
+CREATE INDEX CONCURRENTLY IF NOT EXISTS tasks_assignee_id_idx ON tasks(assignee_id);
+CREATE INDEX CONCURRENTLY IF NOT EXISTS tasks_created_by_idx ON tasks(created_by);
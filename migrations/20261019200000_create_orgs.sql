@@ -0,0 +1,49 @@
+CREATE TABLE IF NOT EXISTS orgs (
+    id SERIAL PRIMARY KEY,
+    name TEXT NOT NULL,
+    slug TEXT NOT NULL,
+    created TIMESTAMP WITHOUT TIME ZONE NOT NULL DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE UNIQUE INDEX CONCURRENTLY IF NOT EXISTS orgs_slug_idx ON orgs(slug);
+
+CREATE TABLE IF NOT EXISTS org_members (
+    id SERIAL PRIMARY KEY,
+    org_id INTEGER NOT NULL,
+    account_id INTEGER NOT NULL,
+    role TEXT NOT NULL,
+    created TIMESTAMP WITHOUT TIME ZONE NOT NULL DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE UNIQUE INDEX CONCURRENTLY IF NOT EXISTS org_members_org_id_account_id_idx ON org_members(org_id, account_id);
+CREATE INDEX CONCURRENTLY IF NOT EXISTS org_members_account_id_idx ON org_members(account_id);
+
+CREATE TABLE IF NOT EXISTS org_invitations (
+    id SERIAL PRIMARY KEY,
+    org_id INTEGER NOT NULL,
+    inviter_id INTEGER NOT NULL,
+    invitee_id INTEGER NOT NULL,
+    role TEXT NOT NULL,
+    status TEXT NOT NULL DEFAULT 'pending',
+    responded TIMESTAMP WITHOUT TIME ZONE,
+    created TIMESTAMP WITHOUT TIME ZONE NOT NULL DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE UNIQUE INDEX CONCURRENTLY IF NOT EXISTS org_invitations_pending_idx ON org_invitations(org_id, invitee_id) WHERE status = 'pending';
+CREATE INDEX CONCURRENTLY IF NOT EXISTS org_invitations_invitee_id_idx ON org_invitations(invitee_id);
+
+ALTER TABLE tasks ADD COLUMN IF NOT EXISTS org_id INTEGER;
+
+CREATE INDEX CONCURRENTLY IF NOT EXISTS tasks_org_id_idx ON tasks(org_id);
+
+-- Requests scoped to an organisation set todo.org_id for each transaction.
+-- The policy stops them reaching other organisations' tasks, but it is only
+-- enforced for database roles which don't own the table, so run the API as
+-- such a role to enforce it.
+DROP POLICY IF EXISTS tasks_org_isolation ON tasks;
+CREATE POLICY tasks_org_isolation ON tasks USING (
+    nullif(current_setting('todo.org_id', true), '') IS NULL
+    OR org_id = nullif(current_setting('todo.org_id', true), '')::integer
+);
+
+ALTER TABLE tasks ENABLE ROW LEVEL SECURITY;
@@ -0,0 +1,29 @@
+CREATE TABLE IF NOT EXISTS webhooks (
+    id SERIAL PRIMARY KEY,
+    account_id INTEGER NOT NULL,
+    url TEXT NOT NULL,
+    events TEXT[] NOT NULL,
+    secret TEXT NOT NULL,
+    created TIMESTAMP WITHOUT TIME ZONE NOT NULL DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE INDEX CONCURRENTLY IF NOT EXISTS webhooks_account_id_idx ON webhooks(account_id);
+
+-- Deliveries are both the queue of events waiting to be sent, and the log of
+-- those which were sent or given up on.
+CREATE TABLE IF NOT EXISTS webhook_deliveries (
+    id SERIAL PRIMARY KEY,
+    webhook_id INTEGER NOT NULL,
+    event TEXT NOT NULL,
+    payload TEXT NOT NULL,
+    status TEXT NOT NULL DEFAULT 'pending',
+    attempts INTEGER NOT NULL DEFAULT 0,
+    next_attempt TIMESTAMP WITHOUT TIME ZONE NOT NULL,
+    response_status INTEGER,
+    last_error TEXT NOT NULL DEFAULT '',
+    delivered TIMESTAMP WITHOUT TIME ZONE,
+    created TIMESTAMP WITHOUT TIME ZONE NOT NULL DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE INDEX CONCURRENTLY IF NOT EXISTS webhook_deliveries_pending_idx ON webhook_deliveries(next_attempt) WHERE status = 'pending';
+CREATE INDEX CONCURRENTLY IF NOT EXISTS webhook_deliveries_webhook_id_idx ON webhook_deliveries(webhook_id, created);
@@ -0,0 +1,17 @@
+-- Each account numbers the changes to the tasks it can see, so that clients
+-- can sync the changes made since they last synced.
+ALTER TABLE accounts ADD COLUMN IF NOT EXISTS change_seq BIGINT NOT NULL DEFAULT 0;
+
+-- The latest change to each task in each account's sequence. Deleted tasks
+-- are kept as tombstones.
+CREATE TABLE IF NOT EXISTS task_changes (
+    account_id INTEGER NOT NULL,
+    task_id INTEGER NOT NULL,
+    org_id INTEGER,
+    seq BIGINT NOT NULL,
+    deleted BOOLEAN NOT NULL DEFAULT false,
+    changed TIMESTAMP WITHOUT TIME ZONE NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    PRIMARY KEY (account_id, task_id)
+);
+
+CREATE INDEX CONCURRENTLY IF NOT EXISTS task_changes_account_id_seq_idx ON task_changes(account_id, seq);
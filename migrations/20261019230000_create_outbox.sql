@@ -0,0 +1,18 @@
+-- Events written in the same transaction as the changes to tasks and accounts
+-- they describe, and published from here to the configured sinks, so that an
+-- event is published if and only if its change was committed.
+CREATE TABLE IF NOT EXISTS outbox (
+    id SERIAL PRIMARY KEY,
+    aggregate TEXT NOT NULL,
+    aggregate_id INTEGER NOT NULL,
+    event TEXT NOT NULL,
+    payload TEXT NOT NULL,
+    attempts INTEGER NOT NULL DEFAULT 0,
+    next_attempt TIMESTAMP WITHOUT TIME ZONE NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    last_error TEXT NOT NULL DEFAULT '',
+    published TIMESTAMP WITHOUT TIME ZONE,
+    created TIMESTAMP WITHOUT TIME ZONE NOT NULL DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE INDEX CONCURRENTLY IF NOT EXISTS outbox_pending_idx ON outbox(next_attempt) WHERE published IS NULL;
+CREATE INDEX CONCURRENTLY IF NOT EXISTS outbox_published_idx ON outbox(published) WHERE published IS NOT NULL;
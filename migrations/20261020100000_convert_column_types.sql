@@ -0,0 +1,389 @@
+-- Converts ids to BIGINT, timestamps to TIMESTAMPTZ and usernames to CITEXT,
+-- which makes them NOT NULL and unique ignoring case.
+--
+-- Changing a column's type rewrites the table under an exclusive lock, so
+-- each column is copied to a new column of the new type instead: a trigger
+-- keeps the copy up to date while existing rows are copied in batches, the
+-- indexes on the copies are built concurrently, and then the copies replace
+-- the columns in a short transaction per table. Existing timestamps are UTC.
+--
+-- The migration creates indexes concurrently, so it runs outside a
+-- transaction, and every step can be rerun if it fails. If an index build
+-- fails, drop the invalid index it leaves before rerunning.
+--
+-- The copies are made NOT NULL when they replace the columns, while the
+-- tables are locked. Postgres 12 and later use the validated check
+-- constraints to skip scanning the tables then, but earlier versions scan
+-- them under the lock, so the migration refuses to run on them.
+SET lock_timeout = '5s';
+
+DO $$
+BEGIN
+    IF current_setting('server_version_num')::integer < 120000 THEN
+        RAISE EXCEPTION 'Postgres 12 or later is required to convert columns without long locks, found %',
+            current_setting('server_version');
+    END IF;
+END
+$$;
+
+CREATE EXTENSION IF NOT EXISTS citext;
+
+-- convert_columns adds a copy of each column converted to typ, named
+-- <column>__new, and (re)creates the trigger which keeps the table's copies
+-- up to date. Copies of NOT NULL columns, or of all columns if not_null is
+-- true, get a check constraint, which is validated once they've been filled.
+CREATE OR REPLACE FUNCTION convert_columns(tbl TEXT, typ TEXT, cols TEXT[], not_null BOOLEAN DEFAULT false) RETURNS void AS $$
+DECLARE
+    col TEXT;
+    c RECORD;
+    body TEXT := '';
+BEGIN
+    FOREACH col IN ARRAY cols LOOP
+        IF EXISTS (
+            SELECT 1 FROM information_schema.columns
+            WHERE table_schema = current_schema() AND table_name = tbl AND column_name = col || '__new'
+        ) THEN
+            CONTINUE;
+        END IF;
+        EXECUTE format('ALTER TABLE %I ADD COLUMN %I %s', tbl, col || '__new', typ);
+        IF not_null OR EXISTS (
+            SELECT 1 FROM information_schema.columns
+            WHERE table_schema = current_schema() AND table_name = tbl AND column_name = col AND is_nullable = 'NO'
+        ) THEN
+            EXECUTE format('ALTER TABLE %I ADD CONSTRAINT %I CHECK (%I IS NOT NULL) NOT VALID',
+                tbl, tbl || '_' || col || '__not_null', col || '__new');
+        END IF;
+    END LOOP;
+
+    FOR c IN
+        SELECT old.column_name, old.data_type
+        FROM information_schema.columns old
+        JOIN information_schema.columns copy
+            ON copy.table_schema = old.table_schema
+            AND copy.table_name = old.table_name
+            AND copy.column_name = old.column_name || '__new'
+        WHERE old.table_schema = current_schema() AND old.table_name = tbl
+    LOOP
+        IF c.data_type = 'timestamp without time zone' THEN
+            body := body || format('NEW.%I := NEW.%I AT TIME ZONE ''UTC''; ', c.column_name || '__new', c.column_name);
+        ELSE
+            body := body || format('NEW.%I := NEW.%I; ', c.column_name || '__new', c.column_name);
+        END IF;
+    END LOOP;
+    EXECUTE format('CREATE OR REPLACE FUNCTION %I() RETURNS trigger AS $f$ BEGIN %s RETURN NEW; END $f$ LANGUAGE plpgsql',
+        tbl || '__convert', body);
+    EXECUTE format('DROP TRIGGER IF EXISTS %I ON %I', tbl || '__convert', tbl);
+    EXECUTE format('CREATE TRIGGER %I BEFORE INSERT OR UPDATE ON %I FOR EACH ROW EXECUTE PROCEDURE %I()',
+        tbl || '__convert', tbl, tbl || '__convert');
+END
+$$ LANGUAGE plpgsql;
+
+-- backfill_converted_columns fills the copies for existing rows, by updating
+-- them so the trigger fires, committing each batch so that rows are only
+-- locked briefly.
+CREATE OR REPLACE PROCEDURE backfill_converted_columns(tbl TEXT, batch_size INTEGER DEFAULT 1000) AS $$
+DECLARE
+    col TEXT;
+    pending TEXT;
+    n BIGINT;
+BEGIN
+    SELECT min(left(column_name, -5)), string_agg(format('(%I IS NULL AND %I IS NOT NULL)', column_name, left(column_name, -5)), ' OR ')
+    INTO col, pending
+    FROM information_schema.columns
+    WHERE table_schema = current_schema() AND table_name = tbl AND column_name LIKE '%\_\_new';
+    IF pending IS NULL THEN
+        RETURN;
+    END IF;
+    LOOP
+        EXECUTE format('UPDATE %I SET %I = %I WHERE ctid = ANY(ARRAY(SELECT ctid FROM %I WHERE %s LIMIT %s))',
+            tbl, col, col, tbl, pending, batch_size);
+        GET DIAGNOSTICS n = ROW_COUNT;
+        COMMIT;
+        EXIT WHEN n = 0;
+    END LOOP;
+END
+$$ LANGUAGE plpgsql;
+
+-- validate_converted_columns validates the check constraints on the copies,
+-- which scans the table without blocking writes.
+CREATE OR REPLACE FUNCTION validate_converted_columns(tbl TEXT) RETURNS void AS $$
+DECLARE
+    c RECORD;
+BEGIN
+    FOR c IN
+        SELECT conname FROM pg_constraint
+        WHERE conrelid = tbl::regclass AND conname LIKE '%\_\_not\_null' AND NOT convalidated
+    LOOP
+        EXECUTE format('ALTER TABLE %I VALIDATE CONSTRAINT %I', tbl, c.conname);
+    END LOOP;
+END
+$$ LANGUAGE plpgsql;
+
+-- finish_converted_columns replaces the columns with their copies, keeping
+-- their defaults, sequences and indexes, whose copies are named
+-- <index>__new. It does nothing if the table has no copies.
+CREATE OR REPLACE FUNCTION finish_converted_columns(tbl TEXT) RETURNS void AS $$
+DECLARE
+    c RECORD;
+    i RECORD;
+    seq TEXT;
+BEGIN
+    EXECUTE format('LOCK TABLE %I IN ACCESS EXCLUSIVE MODE', tbl);
+    EXECUTE format('DROP TRIGGER IF EXISTS %I ON %I', tbl || '__convert', tbl);
+    EXECUTE format('DROP FUNCTION IF EXISTS %I()', tbl || '__convert');
+
+    FOR c IN
+        SELECT old.column_name, old.column_default, copy.data_type
+        FROM information_schema.columns old
+        JOIN information_schema.columns copy
+            ON copy.table_schema = old.table_schema
+            AND copy.table_name = old.table_name
+            AND copy.column_name = old.column_name || '__new'
+        WHERE old.table_schema = current_schema() AND old.table_name = tbl
+    LOOP
+        -- Sequences are dropped with the columns which own them.
+        seq := pg_get_serial_sequence(tbl, c.column_name);
+        IF seq IS NOT NULL THEN
+            EXECUTE format('ALTER SEQUENCE %s OWNED BY NONE', seq);
+        END IF;
+        IF EXISTS (
+            SELECT 1 FROM pg_constraint
+            WHERE conrelid = tbl::regclass AND conname = tbl || '_' || c.column_name || '__not_null'
+        ) THEN
+            EXECUTE format('ALTER TABLE %I ALTER COLUMN %I SET NOT NULL', tbl, c.column_name || '__new');
+            EXECUTE format('ALTER TABLE %I DROP CONSTRAINT %I', tbl, tbl || '_' || c.column_name || '__not_null');
+        END IF;
+        EXECUTE format('ALTER TABLE %I DROP COLUMN %I', tbl, c.column_name);
+        EXECUTE format('ALTER TABLE %I RENAME COLUMN %I TO %I', tbl, c.column_name || '__new', c.column_name);
+        IF c.column_default IS NOT NULL THEN
+            EXECUTE format('ALTER TABLE %I ALTER COLUMN %I SET DEFAULT %s', tbl, c.column_name, c.column_default);
+        END IF;
+        IF seq IS NOT NULL THEN
+            EXECUTE format('ALTER SEQUENCE %s AS %s OWNED BY %I.%I', seq, c.data_type, tbl, c.column_name);
+        END IF;
+    END LOOP;
+
+    FOR i IN
+        SELECT indexname FROM pg_indexes
+        WHERE schemaname = current_schema() AND tablename = tbl AND indexname LIKE '%\_\_new'
+    LOOP
+        IF left(i.indexname, -5) = tbl || '_pkey' THEN
+            EXECUTE format('ALTER TABLE %I ADD CONSTRAINT %I PRIMARY KEY USING INDEX %I', tbl, left(i.indexname, -5), i.indexname);
+        ELSE
+            EXECUTE format('ALTER INDEX %I RENAME TO %I', i.indexname, left(i.indexname, -5));
+        END IF;
+    END LOOP;
+END
+$$ LANGUAGE plpgsql;
+
+-- Usernames can't be NULL, or differ only in case, once they're CITEXT.
+-- Accounts without one get a placeholder, but duplicates are left to be
+-- renamed by hand.
+UPDATE accounts SET username = 'account-' || id WHERE username IS NULL;
+
+DO $$
+DECLARE
+    duplicates TEXT;
+BEGIN
+    SELECT string_agg(username, ', ') INTO duplicates
+    FROM (SELECT lower(username) AS username FROM accounts GROUP BY 1 HAVING count(*) > 1) d;
+    IF duplicates IS NOT NULL THEN
+        RAISE EXCEPTION 'usernames differing only in case must be renamed first: %', duplicates;
+    END IF;
+END
+$$;
+
+-- Copy the columns.
+SELECT convert_columns('access_tokens', 'BIGINT', ARRAY['id', 'account_id', 'client_id']);
+SELECT convert_columns('access_tokens', 'TIMESTAMPTZ', ARRAY['expires', 'last_used', 'created']);
+SELECT convert_columns('account_identities', 'BIGINT', ARRAY['id', 'account_id']);
+SELECT convert_columns('account_identities', 'TIMESTAMPTZ', ARRAY['created']);
+SELECT convert_columns('accounts', 'BIGINT', ARRAY['id']);
+SELECT convert_columns('accounts', 'TIMESTAMPTZ', ARRAY['created', 'totp_enabled', 'verified_at', 'suspended_at']);
+SELECT convert_columns('accounts', 'CITEXT', ARRAY['username'], true);
+SELECT convert_columns('audit_entries', 'BIGINT', ARRAY['id', 'actor_id', 'account_id']);
+SELECT convert_columns('audit_entries', 'TIMESTAMPTZ', ARRAY['created']);
+SELECT convert_columns('email_verifications', 'BIGINT', ARRAY['id', 'account_id']);
+SELECT convert_columns('email_verifications', 'TIMESTAMPTZ', ARRAY['expires', 'used', 'created']);
+SELECT convert_columns('failed_logins', 'BIGINT', ARRAY['id', 'account_id']);
+SELECT convert_columns('failed_logins', 'TIMESTAMPTZ', ARRAY['created']);
+SELECT convert_columns('list_invitations', 'BIGINT', ARRAY['id', 'list_id', 'inviter_id', 'invitee_id']);
+SELECT convert_columns('list_invitations', 'TIMESTAMPTZ', ARRAY['responded', 'created']);
+SELECT convert_columns('list_members', 'BIGINT', ARRAY['id', 'list_id', 'account_id']);
+SELECT convert_columns('list_members', 'TIMESTAMPTZ', ARRAY['created']);
+SELECT convert_columns('lists', 'BIGINT', ARRAY['id', 'account_id']);
+SELECT convert_columns('lists', 'TIMESTAMPTZ', ARRAY['created']);
+SELECT convert_columns('oauth_clients', 'BIGINT', ARRAY['id', 'account_id']);
+SELECT convert_columns('oauth_clients', 'TIMESTAMPTZ', ARRAY['created']);
+SELECT convert_columns('oauth_codes', 'BIGINT', ARRAY['id', 'account_id', 'client_id']);
+SELECT convert_columns('oauth_codes', 'TIMESTAMPTZ', ARRAY['expires', 'used', 'created']);
+SELECT convert_columns('oauth_refresh_tokens', 'BIGINT', ARRAY['id', 'account_id', 'client_id']);
+SELECT convert_columns('oauth_refresh_tokens', 'TIMESTAMPTZ', ARRAY['expires', 'revoked', 'created']);
+SELECT convert_columns('org_invitations', 'BIGINT', ARRAY['id', 'org_id', 'inviter_id', 'invitee_id']);
+SELECT convert_columns('org_invitations', 'TIMESTAMPTZ', ARRAY['responded', 'created']);
+SELECT convert_columns('org_members', 'BIGINT', ARRAY['id', 'org_id', 'account_id']);
+SELECT convert_columns('org_members', 'TIMESTAMPTZ', ARRAY['created']);
+SELECT convert_columns('orgs', 'BIGINT', ARRAY['id']);
+SELECT convert_columns('orgs', 'TIMESTAMPTZ', ARRAY['created']);
+SELECT convert_columns('outbox', 'BIGINT', ARRAY['id', 'aggregate_id']);
+SELECT convert_columns('outbox', 'TIMESTAMPTZ', ARRAY['next_attempt', 'published', 'created']);
+SELECT convert_columns('password_resets', 'BIGINT', ARRAY['id', 'account_id']);
+SELECT convert_columns('password_resets', 'TIMESTAMPTZ', ARRAY['expires', 'used', 'created']);
+SELECT convert_columns('recovery_codes', 'BIGINT', ARRAY['id', 'account_id']);
+SELECT convert_columns('recovery_codes', 'TIMESTAMPTZ', ARRAY['used', 'created']);
+SELECT convert_columns('task_changes', 'BIGINT', ARRAY['account_id', 'task_id', 'org_id']);
+SELECT convert_columns('task_changes', 'TIMESTAMPTZ', ARRAY['changed']);
+SELECT convert_columns('tasks', 'BIGINT', ARRAY['id', 'account_id', 'list_id', 'created_by', 'assignee_id', 'org_id']);
+SELECT convert_columns('tasks', 'TIMESTAMPTZ', ARRAY['created', 'completed']);
+SELECT convert_columns('webhook_deliveries', 'BIGINT', ARRAY['id', 'webhook_id']);
+SELECT convert_columns('webhook_deliveries', 'TIMESTAMPTZ', ARRAY['next_attempt', 'delivered', 'created']);
+SELECT convert_columns('webhooks', 'BIGINT', ARRAY['id', 'account_id']);
+SELECT convert_columns('webhooks', 'TIMESTAMPTZ', ARRAY['created']);
+
+-- Fill the copies for existing rows.
+CALL backfill_converted_columns('access_tokens');
+CALL backfill_converted_columns('account_identities');
+CALL backfill_converted_columns('accounts');
+CALL backfill_converted_columns('audit_entries');
+CALL backfill_converted_columns('email_verifications');
+CALL backfill_converted_columns('failed_logins');
+CALL backfill_converted_columns('list_invitations');
+CALL backfill_converted_columns('list_members');
+CALL backfill_converted_columns('lists');
+CALL backfill_converted_columns('oauth_clients');
+CALL backfill_converted_columns('oauth_codes');
+CALL backfill_converted_columns('oauth_refresh_tokens');
+CALL backfill_converted_columns('org_invitations');
+CALL backfill_converted_columns('org_members');
+CALL backfill_converted_columns('orgs');
+CALL backfill_converted_columns('outbox');
+CALL backfill_converted_columns('password_resets');
+CALL backfill_converted_columns('recovery_codes');
+CALL backfill_converted_columns('task_changes');
+CALL backfill_converted_columns('tasks');
+CALL backfill_converted_columns('webhook_deliveries');
+CALL backfill_converted_columns('webhooks');
+
+SELECT validate_converted_columns('access_tokens');
+SELECT validate_converted_columns('account_identities');
+SELECT validate_converted_columns('accounts');
+SELECT validate_converted_columns('audit_entries');
+SELECT validate_converted_columns('email_verifications');
+SELECT validate_converted_columns('failed_logins');
+SELECT validate_converted_columns('list_invitations');
+SELECT validate_converted_columns('list_members');
+SELECT validate_converted_columns('lists');
+SELECT validate_converted_columns('oauth_clients');
+SELECT validate_converted_columns('oauth_codes');
+SELECT validate_converted_columns('oauth_refresh_tokens');
+SELECT validate_converted_columns('org_invitations');
+SELECT validate_converted_columns('org_members');
+SELECT validate_converted_columns('orgs');
+SELECT validate_converted_columns('outbox');
+SELECT validate_converted_columns('password_resets');
+SELECT validate_converted_columns('recovery_codes');
+SELECT validate_converted_columns('task_changes');
+SELECT validate_converted_columns('tasks');
+SELECT validate_converted_columns('webhook_deliveries');
+SELECT validate_converted_columns('webhooks');
+
+-- Index the copies like the columns.
+CREATE UNIQUE INDEX CONCURRENTLY IF NOT EXISTS access_tokens_pkey__new ON access_tokens(id__new);
+CREATE INDEX CONCURRENTLY IF NOT EXISTS access_tokens_account_id_idx__new ON access_tokens(account_id__new);
+CREATE INDEX CONCURRENTLY IF NOT EXISTS access_tokens_client_id_idx__new ON access_tokens(client_id__new);
+CREATE UNIQUE INDEX CONCURRENTLY IF NOT EXISTS account_identities_pkey__new ON account_identities(id__new);
+CREATE INDEX CONCURRENTLY IF NOT EXISTS account_identities_account_id_idx__new ON account_identities(account_id__new);
+CREATE UNIQUE INDEX CONCURRENTLY IF NOT EXISTS accounts_pkey__new ON accounts(id__new);
+CREATE UNIQUE INDEX CONCURRENTLY IF NOT EXISTS accounts_id_idx__new ON accounts(username__new);
+CREATE UNIQUE INDEX CONCURRENTLY IF NOT EXISTS audit_entries_pkey__new ON audit_entries(id__new);
+CREATE INDEX CONCURRENTLY IF NOT EXISTS audit_entries_account_id_idx__new ON audit_entries(account_id__new);
+CREATE UNIQUE INDEX CONCURRENTLY IF NOT EXISTS email_verifications_pkey__new ON email_verifications(id__new);
+CREATE UNIQUE INDEX CONCURRENTLY IF NOT EXISTS failed_logins_pkey__new ON failed_logins(id__new);
+CREATE INDEX CONCURRENTLY IF NOT EXISTS failed_logins_account_id_created_idx__new ON failed_logins(account_id__new, created__new);
+CREATE UNIQUE INDEX CONCURRENTLY IF NOT EXISTS list_invitations_pkey__new ON list_invitations(id__new);
+CREATE INDEX CONCURRENTLY IF NOT EXISTS list_invitations_invitee_id_idx__new ON list_invitations(invitee_id__new);
+CREATE UNIQUE INDEX CONCURRENTLY IF NOT EXISTS list_invitations_pending_idx__new ON list_invitations(list_id__new, invitee_id__new) WHERE status = 'pending';
+CREATE UNIQUE INDEX CONCURRENTLY IF NOT EXISTS list_members_pkey__new ON list_members(id__new);
+CREATE INDEX CONCURRENTLY IF NOT EXISTS list_members_account_id_idx__new ON list_members(account_id__new);
+CREATE UNIQUE INDEX CONCURRENTLY IF NOT EXISTS list_members_list_id_account_id_idx__new ON list_members(list_id__new, account_id__new);
+CREATE UNIQUE INDEX CONCURRENTLY IF NOT EXISTS lists_pkey__new ON lists(id__new);
+CREATE INDEX CONCURRENTLY IF NOT EXISTS lists_account_id_idx__new ON lists(account_id__new);
+CREATE UNIQUE INDEX CONCURRENTLY IF NOT EXISTS oauth_clients_pkey__new ON oauth_clients(id__new);
+CREATE INDEX CONCURRENTLY IF NOT EXISTS oauth_clients_account_id_idx__new ON oauth_clients(account_id__new);
+CREATE UNIQUE INDEX CONCURRENTLY IF NOT EXISTS oauth_codes_pkey__new ON oauth_codes(id__new);
+CREATE UNIQUE INDEX CONCURRENTLY IF NOT EXISTS oauth_refresh_tokens_pkey__new ON oauth_refresh_tokens(id__new);
+CREATE INDEX CONCURRENTLY IF NOT EXISTS oauth_refresh_tokens_account_id_idx__new ON oauth_refresh_tokens(account_id__new);
+CREATE UNIQUE INDEX CONCURRENTLY IF NOT EXISTS org_invitations_pkey__new ON org_invitations(id__new);
+CREATE INDEX CONCURRENTLY IF NOT EXISTS org_invitations_invitee_id_idx__new ON org_invitations(invitee_id__new);
+CREATE UNIQUE INDEX CONCURRENTLY IF NOT EXISTS org_invitations_pending_idx__new ON org_invitations(org_id__new, invitee_id__new) WHERE status = 'pending';
+CREATE UNIQUE INDEX CONCURRENTLY IF NOT EXISTS org_members_pkey__new ON org_members(id__new);
+CREATE INDEX CONCURRENTLY IF NOT EXISTS org_members_account_id_idx__new ON org_members(account_id__new);
+CREATE UNIQUE INDEX CONCURRENTLY IF NOT EXISTS org_members_org_id_account_id_idx__new ON org_members(org_id__new, account_id__new);
+CREATE UNIQUE INDEX CONCURRENTLY IF NOT EXISTS orgs_pkey__new ON orgs(id__new);
+CREATE UNIQUE INDEX CONCURRENTLY IF NOT EXISTS outbox_pkey__new ON outbox(id__new);
+CREATE INDEX CONCURRENTLY IF NOT EXISTS outbox_pending_idx__new ON outbox(next_attempt__new) WHERE published__new IS NULL;
+CREATE INDEX CONCURRENTLY IF NOT EXISTS outbox_published_idx__new ON outbox(published__new) WHERE published__new IS NOT NULL;
+CREATE UNIQUE INDEX CONCURRENTLY IF NOT EXISTS password_resets_pkey__new ON password_resets(id__new);
+CREATE UNIQUE INDEX CONCURRENTLY IF NOT EXISTS recovery_codes_pkey__new ON recovery_codes(id__new);
+CREATE UNIQUE INDEX CONCURRENTLY IF NOT EXISTS recovery_codes_account_id_code_digest_idx__new ON recovery_codes(account_id__new, code_digest);
+CREATE UNIQUE INDEX CONCURRENTLY IF NOT EXISTS task_changes_pkey__new ON task_changes(account_id__new, task_id__new);
+CREATE INDEX CONCURRENTLY IF NOT EXISTS task_changes_account_id_seq_idx__new ON task_changes(account_id__new, seq);
+CREATE UNIQUE INDEX CONCURRENTLY IF NOT EXISTS tasks_pkey__new ON tasks(id__new);
+CREATE INDEX CONCURRENTLY IF NOT EXISTS tasks_assignee_id_idx__new ON tasks(assignee_id__new);
+CREATE INDEX CONCURRENTLY IF NOT EXISTS tasks_created_by_idx__new ON tasks(created_by__new);
+CREATE INDEX CONCURRENTLY IF NOT EXISTS tasks_list_id_idx__new ON tasks(list_id__new);
+CREATE INDEX CONCURRENTLY IF NOT EXISTS tasks_org_id_idx__new ON tasks(org_id__new);
+CREATE UNIQUE INDEX CONCURRENTLY IF NOT EXISTS webhook_deliveries_pkey__new ON webhook_deliveries(id__new);
+CREATE INDEX CONCURRENTLY IF NOT EXISTS webhook_deliveries_pending_idx__new ON webhook_deliveries(next_attempt__new) WHERE status = 'pending';
+CREATE INDEX CONCURRENTLY IF NOT EXISTS webhook_deliveries_webhook_id_idx__new ON webhook_deliveries(webhook_id__new, created__new);
+CREATE UNIQUE INDEX CONCURRENTLY IF NOT EXISTS webhooks_pkey__new ON webhooks(id__new);
+CREATE INDEX CONCURRENTLY IF NOT EXISTS webhooks_account_id_idx__new ON webhooks(account_id__new);
+
+-- Replace the columns with the copies.
+SELECT finish_converted_columns('access_tokens');
+SELECT finish_converted_columns('account_identities');
+SELECT finish_converted_columns('accounts');
+SELECT finish_converted_columns('audit_entries');
+SELECT finish_converted_columns('email_verifications');
+SELECT finish_converted_columns('failed_logins');
+SELECT finish_converted_columns('list_invitations');
+SELECT finish_converted_columns('list_members');
+SELECT finish_converted_columns('lists');
+SELECT finish_converted_columns('oauth_clients');
+SELECT finish_converted_columns('oauth_codes');
+SELECT finish_converted_columns('oauth_refresh_tokens');
+SELECT finish_converted_columns('org_invitations');
+SELECT finish_converted_columns('org_members');
+SELECT finish_converted_columns('orgs');
+SELECT finish_converted_columns('outbox');
+SELECT finish_converted_columns('password_resets');
+SELECT finish_converted_columns('recovery_codes');
+SELECT finish_converted_columns('task_changes');
+SELECT finish_converted_columns('webhook_deliveries');
+SELECT finish_converted_columns('webhooks');
+
+-- The organisation isolation policy depends on tasks.org_id, so it's
+-- replaced along with it.
+DO $$
+BEGIN
+    IF EXISTS (
+        SELECT 1 FROM information_schema.columns
+        WHERE table_schema = current_schema() AND table_name = 'tasks' AND column_name LIKE '%\_\_new'
+    ) THEN
+        LOCK TABLE tasks IN ACCESS EXCLUSIVE MODE;
+        DROP POLICY IF EXISTS tasks_org_isolation ON tasks;
+        PERFORM finish_converted_columns('tasks');
+        CREATE POLICY tasks_org_isolation ON tasks USING (
+            nullif(current_setting('todo.org_id', true), '') IS NULL
+            OR org_id = nullif(current_setting('todo.org_id', true), '')::bigint
+        );
+    END IF;
+END
+$$;
+
+DROP FUNCTION IF EXISTS convert_columns(TEXT, TEXT, TEXT[], BOOLEAN);
+DROP PROCEDURE IF EXISTS backfill_converted_columns(TEXT, INTEGER);
+DROP FUNCTION IF EXISTS validate_converted_columns(TEXT);
+DROP FUNCTION IF EXISTS finish_converted_columns(TEXT);
+
+RESET lock_timeout;
@@ -0,0 +1,141 @@
+-- Adds foreign keys, which delete rows with the rows they refer to, except
+-- that tasks are unassigned when their assignees are deleted. Accounts are
+-- still deleted by the app, which records the changes, so these only stop
+-- rows from being left behind.
+--
+-- Some columns deliberately have no foreign key: audit entries and the
+-- authors of tasks outlive accounts, task changes outlive the tasks they
+-- record the deletion of, and the outbox refers to many tables.
+--
+-- The foreign keys are added without checking existing rows, which blocks
+-- writes only briefly, and then validated without blocking writes. Rows which
+-- would violate them aren't changed: the migration fails before validating
+-- them, listing how many there are, so that they can be deleted or fixed by
+-- hand and the migration rerun. The referencing columns are indexed first, so
+-- that deletes don't scan the referencing tables. The migration creates
+-- indexes concurrently, so it runs outside a transaction; if an index build
+-- fails, drop the invalid index it leaves before rerunning.
+SET lock_timeout = '5s';
+
+CREATE INDEX CONCURRENTLY IF NOT EXISTS email_verifications_account_id_idx ON email_verifications(account_id);
+CREATE INDEX CONCURRENTLY IF NOT EXISTS list_invitations_inviter_id_idx ON list_invitations(inviter_id);
+CREATE INDEX CONCURRENTLY IF NOT EXISTS list_invitations_list_id_idx ON list_invitations(list_id);
+CREATE INDEX CONCURRENTLY IF NOT EXISTS oauth_codes_account_id_idx ON oauth_codes(account_id);
+CREATE INDEX CONCURRENTLY IF NOT EXISTS oauth_codes_client_id_idx ON oauth_codes(client_id);
+CREATE INDEX CONCURRENTLY IF NOT EXISTS oauth_refresh_tokens_client_id_idx ON oauth_refresh_tokens(client_id);
+CREATE INDEX CONCURRENTLY IF NOT EXISTS org_invitations_inviter_id_idx ON org_invitations(inviter_id);
+CREATE INDEX CONCURRENTLY IF NOT EXISTS org_invitations_org_id_idx ON org_invitations(org_id);
+CREATE INDEX CONCURRENTLY IF NOT EXISTS password_resets_account_id_idx ON password_resets(account_id);
+CREATE INDEX CONCURRENTLY IF NOT EXISTS tasks_account_id_idx ON tasks(account_id);
+
+-- add_foreign_key adds a foreign key named <tbl>_<col>_fkey from tbl.col to
+-- the id of ref without validating it.
+CREATE OR REPLACE FUNCTION add_foreign_key(tbl TEXT, col TEXT, ref TEXT, on_delete TEXT DEFAULT 'CASCADE') RETURNS void AS $$
+BEGIN
+    IF NOT EXISTS (
+        SELECT 1 FROM pg_constraint
+        WHERE conrelid = tbl::regclass AND conname = tbl || '_' || col || '_fkey'
+    ) THEN
+        EXECUTE format('ALTER TABLE %I ADD CONSTRAINT %I FOREIGN KEY (%I) REFERENCES %I(id) ON DELETE %s NOT VALID',
+            tbl, tbl || '_' || col || '_fkey', col, ref, on_delete);
+    END IF;
+END
+$$ LANGUAGE plpgsql;
+
+SELECT add_foreign_key('access_tokens', 'account_id', 'accounts');
+SELECT add_foreign_key('access_tokens', 'client_id', 'oauth_clients');
+SELECT add_foreign_key('account_identities', 'account_id', 'accounts');
+SELECT add_foreign_key('email_verifications', 'account_id', 'accounts');
+SELECT add_foreign_key('failed_logins', 'account_id', 'accounts');
+SELECT add_foreign_key('list_invitations', 'invitee_id', 'accounts');
+SELECT add_foreign_key('list_invitations', 'inviter_id', 'accounts');
+SELECT add_foreign_key('list_invitations', 'list_id', 'lists');
+SELECT add_foreign_key('list_members', 'account_id', 'accounts');
+SELECT add_foreign_key('list_members', 'list_id', 'lists');
+SELECT add_foreign_key('lists', 'account_id', 'accounts');
+SELECT add_foreign_key('oauth_clients', 'account_id', 'accounts');
+SELECT add_foreign_key('oauth_codes', 'account_id', 'accounts');
+SELECT add_foreign_key('oauth_codes', 'client_id', 'oauth_clients');
+SELECT add_foreign_key('oauth_refresh_tokens', 'account_id', 'accounts');
+SELECT add_foreign_key('oauth_refresh_tokens', 'client_id', 'oauth_clients');
+SELECT add_foreign_key('org_invitations', 'invitee_id', 'accounts');
+SELECT add_foreign_key('org_invitations', 'inviter_id', 'accounts');
+SELECT add_foreign_key('org_invitations', 'org_id', 'orgs');
+SELECT add_foreign_key('org_members', 'account_id', 'accounts');
+SELECT add_foreign_key('org_members', 'org_id', 'orgs');
+SELECT add_foreign_key('password_resets', 'account_id', 'accounts');
+SELECT add_foreign_key('recovery_codes', 'account_id', 'accounts');
+SELECT add_foreign_key('task_changes', 'account_id', 'accounts');
+SELECT add_foreign_key('tasks', 'account_id', 'accounts');
+SELECT add_foreign_key('tasks', 'assignee_id', 'accounts', 'SET NULL');
+SELECT add_foreign_key('tasks', 'list_id', 'lists');
+SELECT add_foreign_key('tasks', 'org_id', 'orgs');
+SELECT add_foreign_key('webhook_deliveries', 'webhook_id', 'webhooks');
+SELECT add_foreign_key('webhooks', 'account_id', 'accounts');
+
+-- Fail if any rows refer to rows which no longer exist, rather than deleting
+-- or changing them here.
+DO $$
+DECLARE
+    fk RECORD;
+    n BIGINT;
+    orphans TEXT := '';
+BEGIN
+    FOR fk IN
+        SELECT t.relname AS tbl, a.attname AS col, r.relname AS ref
+        FROM pg_constraint c
+        JOIN pg_class t ON t.oid = c.conrelid
+        JOIN pg_class r ON r.oid = c.confrelid
+        JOIN pg_attribute a ON a.attrelid = c.conrelid AND a.attnum = c.conkey[1]
+        WHERE c.contype = 'f'
+        AND NOT c.convalidated
+        AND t.relnamespace = current_schema()::regnamespace
+        ORDER BY 1, 2
+    LOOP
+        EXECUTE format('SELECT count(*) FROM %I WHERE %I IS NOT NULL AND NOT EXISTS (SELECT 1 FROM %I WHERE id = %I.%I)',
+            fk.tbl, fk.col, fk.ref, fk.tbl, fk.col) INTO n;
+        IF n > 0 THEN
+            orphans := orphans || format(E'\n    %s.%s: %s rows refer to missing %s', fk.tbl, fk.col, n, fk.ref);
+        END IF;
+    END LOOP;
+    IF orphans <> '' THEN
+        RAISE EXCEPTION 'rows refer to rows which no longer exist:%', orphans
+            USING HINT = 'Delete or fix these rows, then rerun the migration. Tasks in organisations whose accounts were deleted may be handed to the organisations'' owners, as the app does now.';
+    END IF;
+END
+$$;
+
+ALTER TABLE access_tokens VALIDATE CONSTRAINT access_tokens_account_id_fkey;
+ALTER TABLE access_tokens VALIDATE CONSTRAINT access_tokens_client_id_fkey;
+ALTER TABLE account_identities VALIDATE CONSTRAINT account_identities_account_id_fkey;
+ALTER TABLE email_verifications VALIDATE CONSTRAINT email_verifications_account_id_fkey;
+ALTER TABLE failed_logins VALIDATE CONSTRAINT failed_logins_account_id_fkey;
+ALTER TABLE list_invitations VALIDATE CONSTRAINT list_invitations_invitee_id_fkey;
+ALTER TABLE list_invitations VALIDATE CONSTRAINT list_invitations_inviter_id_fkey;
+ALTER TABLE list_invitations VALIDATE CONSTRAINT list_invitations_list_id_fkey;
+ALTER TABLE list_members VALIDATE CONSTRAINT list_members_account_id_fkey;
+ALTER TABLE list_members VALIDATE CONSTRAINT list_members_list_id_fkey;
+ALTER TABLE lists VALIDATE CONSTRAINT lists_account_id_fkey;
+ALTER TABLE oauth_clients VALIDATE CONSTRAINT oauth_clients_account_id_fkey;
+ALTER TABLE oauth_codes VALIDATE CONSTRAINT oauth_codes_account_id_fkey;
+ALTER TABLE oauth_codes VALIDATE CONSTRAINT oauth_codes_client_id_fkey;
+ALTER TABLE oauth_refresh_tokens VALIDATE CONSTRAINT oauth_refresh_tokens_account_id_fkey;
+ALTER TABLE oauth_refresh_tokens VALIDATE CONSTRAINT oauth_refresh_tokens_client_id_fkey;
+ALTER TABLE org_invitations VALIDATE CONSTRAINT org_invitations_invitee_id_fkey;
+ALTER TABLE org_invitations VALIDATE CONSTRAINT org_invitations_inviter_id_fkey;
+ALTER TABLE org_invitations VALIDATE CONSTRAINT org_invitations_org_id_fkey;
+ALTER TABLE org_members VALIDATE CONSTRAINT org_members_account_id_fkey;
+ALTER TABLE org_members VALIDATE CONSTRAINT org_members_org_id_fkey;
+ALTER TABLE password_resets VALIDATE CONSTRAINT password_resets_account_id_fkey;
+ALTER TABLE recovery_codes VALIDATE CONSTRAINT recovery_codes_account_id_fkey;
+ALTER TABLE task_changes VALIDATE CONSTRAINT task_changes_account_id_fkey;
+ALTER TABLE tasks VALIDATE CONSTRAINT tasks_account_id_fkey;
+ALTER TABLE tasks VALIDATE CONSTRAINT tasks_assignee_id_fkey;
+ALTER TABLE tasks VALIDATE CONSTRAINT tasks_list_id_fkey;
+ALTER TABLE tasks VALIDATE CONSTRAINT tasks_org_id_fkey;
+ALTER TABLE webhook_deliveries VALIDATE CONSTRAINT webhook_deliveries_webhook_id_fkey;
+ALTER TABLE webhooks VALIDATE CONSTRAINT webhooks_account_id_fkey;
+
+DROP FUNCTION IF EXISTS add_foreign_key(TEXT, TEXT, TEXT, TEXT);
+
+RESET lock_timeout;
@@ -0,0 +1,107 @@
+-- Adds public ids to accounts and tasks, which identify them in the API in
+-- place of their database ids, and to task changes, so that clients can be
+-- told which tasks were deleted. Public ids are version 7 UUIDs, generated by
+-- uuid_generate_v7 when rows are inserted. Existing rows get ids from when
+-- they were created, so that ids sort in the same order as before.
+--
+-- The columns are added without defaults for existing rows, which would
+-- rewrite the tables, filled in batches, and then indexed concurrently, so
+-- the migration runs outside a transaction. If an index build fails, drop the
+-- invalid index it leaves before rerunning.
+--
+-- The columns are made NOT NULL by way of check constraints, which are
+-- validated without blocking writes. Postgres 12 and later then set NOT NULL
+-- without scanning the tables, but earlier versions scan them under an
+-- exclusive lock, so the migration refuses to run on them.
+SET lock_timeout = '5s';
+
+DO $$
+BEGIN
+    IF current_setting('server_version_num')::integer < 120000 THEN
+        RAISE EXCEPTION 'Postgres 12 or later is required to add public ids without long locks, found %',
+            current_setting('server_version');
+    END IF;
+END
+$$;
+
+-- gen_random_uuid is built in from Postgres 13.
+CREATE EXTENSION IF NOT EXISTS pgcrypto;
+
+-- uuid_generate_v7 returns a version 7 UUID for time ts: a random UUID
+-- whose first 48 bits are ts in milliseconds since the Unix epoch.
+CREATE OR REPLACE FUNCTION uuid_generate_v7(ts TIMESTAMPTZ DEFAULT clock_timestamp()) RETURNS uuid AS $$
+    SELECT encode(
+        set_bit(
+            set_bit(
+                overlay(uuid_send(gen_random_uuid())
+                    PLACING substring(int8send(floor(extract(epoch FROM ts) * 1000)::bigint) FROM 3)
+                    FROM 1 FOR 6),
+                52, 1),
+            53, 1),
+        'hex')::uuid;
+$$ LANGUAGE sql VOLATILE;
+
+ALTER TABLE accounts ADD COLUMN IF NOT EXISTS public_id uuid;
+ALTER TABLE accounts ALTER COLUMN public_id SET DEFAULT uuid_generate_v7();
+ALTER TABLE tasks ADD COLUMN IF NOT EXISTS public_id uuid;
+ALTER TABLE tasks ALTER COLUMN public_id SET DEFAULT uuid_generate_v7();
+ALTER TABLE task_changes ADD COLUMN IF NOT EXISTS task_public_id uuid;
+
+-- fill_column sets col to val in the rows where it's NULL, committing each
+-- batch so that rows are only locked briefly.
+CREATE OR REPLACE PROCEDURE fill_column(tbl TEXT, col TEXT, val TEXT, batch_size INTEGER DEFAULT 1000) AS $$
+DECLARE
+    n BIGINT;
+BEGIN
+    LOOP
+        EXECUTE format('UPDATE %I SET %I = %s WHERE ctid = ANY(ARRAY(SELECT ctid FROM %I WHERE %I IS NULL LIMIT %s))',
+            tbl, col, val, tbl, col, batch_size);
+        GET DIAGNOSTICS n = ROW_COUNT;
+        COMMIT;
+        EXIT WHEN n = 0;
+    END LOOP;
+END
+$$ LANGUAGE plpgsql;
+
+CALL fill_column('accounts', 'public_id', 'uuid_generate_v7(created)');
+CALL fill_column('tasks', 'public_id', 'uuid_generate_v7(created)');
+-- Changes to tasks which have since been deleted get ids no client has seen,
+-- which clients ignore.
+CALL fill_column('task_changes', 'task_public_id',
+    'COALESCE((SELECT public_id FROM tasks WHERE id = task_changes.task_id), uuid_generate_v7(changed))');
+
+CREATE UNIQUE INDEX CONCURRENTLY IF NOT EXISTS accounts_public_id_idx ON accounts(public_id);
+CREATE UNIQUE INDEX CONCURRENTLY IF NOT EXISTS tasks_public_id_idx ON tasks(public_id);
+CREATE UNIQUE INDEX CONCURRENTLY IF NOT EXISTS task_changes_account_id_task_public_id_idx ON task_changes(account_id, task_public_id);
+
+CREATE OR REPLACE FUNCTION add_not_null_check(tbl TEXT, col TEXT) RETURNS void AS $$
+BEGIN
+    IF NOT EXISTS (
+        SELECT 1 FROM pg_constraint
+        WHERE conrelid = tbl::regclass AND conname = tbl || '_' || col || '_not_null'
+    ) THEN
+        EXECUTE format('ALTER TABLE %I ADD CONSTRAINT %I CHECK (%I IS NOT NULL) NOT VALID',
+            tbl, tbl || '_' || col || '_not_null', col);
+    END IF;
+END
+$$ LANGUAGE plpgsql;
+
+SELECT add_not_null_check('accounts', 'public_id');
+SELECT add_not_null_check('tasks', 'public_id');
+SELECT add_not_null_check('task_changes', 'task_public_id');
+
+ALTER TABLE accounts VALIDATE CONSTRAINT accounts_public_id_not_null;
+ALTER TABLE tasks VALIDATE CONSTRAINT tasks_public_id_not_null;
+ALTER TABLE task_changes VALIDATE CONSTRAINT task_changes_task_public_id_not_null;
+
+ALTER TABLE accounts ALTER COLUMN public_id SET NOT NULL;
+ALTER TABLE accounts DROP CONSTRAINT IF EXISTS accounts_public_id_not_null;
+ALTER TABLE tasks ALTER COLUMN public_id SET NOT NULL;
+ALTER TABLE tasks DROP CONSTRAINT IF EXISTS tasks_public_id_not_null;
+ALTER TABLE task_changes ALTER COLUMN task_public_id SET NOT NULL;
+ALTER TABLE task_changes DROP CONSTRAINT IF EXISTS task_changes_task_public_id_not_null;
+
+DROP PROCEDURE IF EXISTS fill_column(TEXT, TEXT, TEXT, INTEGER);
+DROP FUNCTION IF EXISTS add_not_null_check(TEXT, TEXT);
+
+RESET lock_timeout;
@@ -0,0 +1,3 @@
+-- Records the time step of the last TOTP code each account used, so that a
+-- code can't be replayed while it's still valid.
+ALTER TABLE accounts ADD COLUMN IF NOT EXISTS totp_last_step BIGINT;
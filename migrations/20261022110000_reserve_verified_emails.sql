@@ -0,0 +1,5 @@
+-- Only verified email addresses are unique, so that signing up with someone
+-- else's address can't stop them from using it.
+CREATE UNIQUE INDEX CONCURRENTLY IF NOT EXISTS accounts_verified_email_idx ON accounts(lower(email)) WHERE email <> '' AND verified_at IS NOT NULL;
+
+DROP INDEX CONCURRENTLY IF EXISTS accounts_email_idx;
@@ -0,0 +1,20 @@
+-- Events are published with the public ids of the entities which changed,
+-- rather than their database ids. Events which haven't been published yet are
+-- given the public ids of the entities which still exist.
+ALTER TABLE outbox ADD COLUMN IF NOT EXISTS aggregate_public_id TEXT NOT NULL DEFAULT '';
+
+UPDATE outbox
+SET aggregate_public_id = accounts.public_id::text
+FROM accounts
+WHERE outbox.aggregate = 'account'
+AND outbox.aggregate_id = accounts.id
+AND outbox.published IS NULL
+AND outbox.aggregate_public_id = '';
+
+UPDATE outbox
+SET aggregate_public_id = tasks.public_id::text
+FROM tasks
+WHERE outbox.aggregate = 'task'
+AND outbox.aggregate_id = tasks.id
+AND outbox.published IS NULL
+AND outbox.aggregate_public_id = '';
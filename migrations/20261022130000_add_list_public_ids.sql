@@ -0,0 +1,73 @@
+-- Adds public ids to lists and list invitations, which identify them in the
+-- API in place of their database ids, like those of accounts and tasks.
+-- Existing rows get ids from when they were created.
+--
+-- As in 20261021100000_add_public_ids.sql, the columns are filled in batches
+-- and indexed concurrently, so the migration runs outside a transaction. If
+-- an index build fails, drop the invalid index it leaves before rerunning.
+-- It also needs Postgres 12 or later to set NOT NULL without a table scan.
+SET lock_timeout = '5s';
+
+DO $$
+BEGIN
+    IF current_setting('server_version_num')::integer < 120000 THEN
+        RAISE EXCEPTION 'Postgres 12 or later is required to add public ids without long locks, found %',
+            current_setting('server_version');
+    END IF;
+END
+$$;
+
+ALTER TABLE lists ADD COLUMN IF NOT EXISTS public_id uuid;
+ALTER TABLE lists ALTER COLUMN public_id SET DEFAULT uuid_generate_v7();
+ALTER TABLE list_invitations ADD COLUMN IF NOT EXISTS public_id uuid;
+ALTER TABLE list_invitations ALTER COLUMN public_id SET DEFAULT uuid_generate_v7();
+
+-- fill_column sets col to val in the rows where it's NULL, committing each
+-- batch so that rows are only locked briefly.
+CREATE OR REPLACE PROCEDURE fill_column(tbl TEXT, col TEXT, val TEXT, batch_size INTEGER DEFAULT 1000) AS $$
+DECLARE
+    n BIGINT;
+BEGIN
+    LOOP
+        EXECUTE format('UPDATE %I SET %I = %s WHERE ctid = ANY(ARRAY(SELECT ctid FROM %I WHERE %I IS NULL LIMIT %s))',
+            tbl, col, val, tbl, col, batch_size);
+        GET DIAGNOSTICS n = ROW_COUNT;
+        COMMIT;
+        EXIT WHEN n = 0;
+    END LOOP;
+END
+$$ LANGUAGE plpgsql;
+
+CALL fill_column('lists', 'public_id', 'uuid_generate_v7(created)');
+CALL fill_column('list_invitations', 'public_id', 'uuid_generate_v7(created)');
+
+CREATE UNIQUE INDEX CONCURRENTLY IF NOT EXISTS lists_public_id_idx ON lists(public_id);
+CREATE UNIQUE INDEX CONCURRENTLY IF NOT EXISTS list_invitations_public_id_idx ON list_invitations(public_id);
+
+CREATE OR REPLACE FUNCTION add_not_null_check(tbl TEXT, col TEXT) RETURNS void AS $$
+BEGIN
+    IF NOT EXISTS (
+        SELECT 1 FROM pg_constraint
+        WHERE conrelid = tbl::regclass AND conname = tbl || '_' || col || '_not_null'
+    ) THEN
+        EXECUTE format('ALTER TABLE %I ADD CONSTRAINT %I CHECK (%I IS NOT NULL) NOT VALID',
+            tbl, tbl || '_' || col || '_not_null', col);
+    END IF;
+END
+$$ LANGUAGE plpgsql;
+
+SELECT add_not_null_check('lists', 'public_id');
+SELECT add_not_null_check('list_invitations', 'public_id');
+
+ALTER TABLE lists VALIDATE CONSTRAINT lists_public_id_not_null;
+ALTER TABLE list_invitations VALIDATE CONSTRAINT list_invitations_public_id_not_null;
+
+ALTER TABLE lists ALTER COLUMN public_id SET NOT NULL;
+ALTER TABLE lists DROP CONSTRAINT IF EXISTS lists_public_id_not_null;
+ALTER TABLE list_invitations ALTER COLUMN public_id SET NOT NULL;
+ALTER TABLE list_invitations DROP CONSTRAINT IF EXISTS list_invitations_public_id_not_null;
+
+DROP PROCEDURE IF EXISTS fill_column(TEXT, TEXT, TEXT, INTEGER);
+DROP FUNCTION IF EXISTS add_not_null_check(TEXT, TEXT);
+
+RESET lock_timeout;
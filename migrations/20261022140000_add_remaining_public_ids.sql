@@ -0,0 +1,122 @@
+-- Adds public ids to the remaining resources the API shows: organisations
+-- and their invitations, webhooks and their deliveries, access tokens,
+-- account identities, failed logins and audit entries. Existing rows get ids
+-- from when they were created.
+--
+-- As in 20261021100000_add_public_ids.sql, the columns are filled in batches
+-- and indexed concurrently, so the migration runs outside a transaction. If
+-- an index build fails, drop the invalid index it leaves before rerunning.
+-- It also needs Postgres 12 or later to set NOT NULL without a table scan.
+SET lock_timeout = '5s';
+
+DO $$
+BEGIN
+    IF current_setting('server_version_num')::integer < 120000 THEN
+        RAISE EXCEPTION 'Postgres 12 or later is required to add public ids without long locks, found %',
+            current_setting('server_version');
+    END IF;
+END
+$$;
+
+ALTER TABLE orgs ADD COLUMN IF NOT EXISTS public_id uuid;
+ALTER TABLE orgs ALTER COLUMN public_id SET DEFAULT uuid_generate_v7();
+ALTER TABLE org_invitations ADD COLUMN IF NOT EXISTS public_id uuid;
+ALTER TABLE org_invitations ALTER COLUMN public_id SET DEFAULT uuid_generate_v7();
+ALTER TABLE webhooks ADD COLUMN IF NOT EXISTS public_id uuid;
+ALTER TABLE webhooks ALTER COLUMN public_id SET DEFAULT uuid_generate_v7();
+ALTER TABLE webhook_deliveries ADD COLUMN IF NOT EXISTS public_id uuid;
+ALTER TABLE webhook_deliveries ALTER COLUMN public_id SET DEFAULT uuid_generate_v7();
+ALTER TABLE access_tokens ADD COLUMN IF NOT EXISTS public_id uuid;
+ALTER TABLE access_tokens ALTER COLUMN public_id SET DEFAULT uuid_generate_v7();
+ALTER TABLE account_identities ADD COLUMN IF NOT EXISTS public_id uuid;
+ALTER TABLE account_identities ALTER COLUMN public_id SET DEFAULT uuid_generate_v7();
+ALTER TABLE failed_logins ADD COLUMN IF NOT EXISTS public_id uuid;
+ALTER TABLE failed_logins ALTER COLUMN public_id SET DEFAULT uuid_generate_v7();
+ALTER TABLE audit_entries ADD COLUMN IF NOT EXISTS public_id uuid;
+ALTER TABLE audit_entries ALTER COLUMN public_id SET DEFAULT uuid_generate_v7();
+
+-- fill_column sets col to val in the rows where it's NULL, committing each
+-- batch so that rows are only locked briefly.
+CREATE OR REPLACE PROCEDURE fill_column(tbl TEXT, col TEXT, val TEXT, batch_size INTEGER DEFAULT 1000) AS $$
+DECLARE
+    n BIGINT;
+BEGIN
+    LOOP
+        EXECUTE format('UPDATE %I SET %I = %s WHERE ctid = ANY(ARRAY(SELECT ctid FROM %I WHERE %I IS NULL LIMIT %s))',
+            tbl, col, val, tbl, col, batch_size);
+        GET DIAGNOSTICS n = ROW_COUNT;
+        COMMIT;
+        EXIT WHEN n = 0;
+    END LOOP;
+END
+$$ LANGUAGE plpgsql;
+
+CALL fill_column('orgs', 'public_id', 'uuid_generate_v7(created)');
+CALL fill_column('org_invitations', 'public_id', 'uuid_generate_v7(created)');
+CALL fill_column('webhooks', 'public_id', 'uuid_generate_v7(created)');
+CALL fill_column('webhook_deliveries', 'public_id', 'uuid_generate_v7(created)');
+CALL fill_column('access_tokens', 'public_id', 'uuid_generate_v7(created)');
+CALL fill_column('account_identities', 'public_id', 'uuid_generate_v7(created)');
+CALL fill_column('failed_logins', 'public_id', 'uuid_generate_v7(created)');
+CALL fill_column('audit_entries', 'public_id', 'uuid_generate_v7(created)');
+
+CREATE UNIQUE INDEX CONCURRENTLY IF NOT EXISTS orgs_public_id_idx ON orgs(public_id);
+CREATE UNIQUE INDEX CONCURRENTLY IF NOT EXISTS org_invitations_public_id_idx ON org_invitations(public_id);
+CREATE UNIQUE INDEX CONCURRENTLY IF NOT EXISTS webhooks_public_id_idx ON webhooks(public_id);
+CREATE UNIQUE INDEX CONCURRENTLY IF NOT EXISTS webhook_deliveries_public_id_idx ON webhook_deliveries(public_id);
+CREATE UNIQUE INDEX CONCURRENTLY IF NOT EXISTS access_tokens_public_id_idx ON access_tokens(public_id);
+CREATE UNIQUE INDEX CONCURRENTLY IF NOT EXISTS account_identities_public_id_idx ON account_identities(public_id);
+CREATE UNIQUE INDEX CONCURRENTLY IF NOT EXISTS failed_logins_public_id_idx ON failed_logins(public_id);
+CREATE UNIQUE INDEX CONCURRENTLY IF NOT EXISTS audit_entries_public_id_idx ON audit_entries(public_id);
+
+CREATE OR REPLACE FUNCTION add_not_null_check(tbl TEXT, col TEXT) RETURNS void AS $$
+BEGIN
+    IF NOT EXISTS (
+        SELECT 1 FROM pg_constraint
+        WHERE conrelid = tbl::regclass AND conname = tbl || '_' || col || '_not_null'
+    ) THEN
+        EXECUTE format('ALTER TABLE %I ADD CONSTRAINT %I CHECK (%I IS NOT NULL) NOT VALID',
+            tbl, tbl || '_' || col || '_not_null', col);
+    END IF;
+END
+$$ LANGUAGE plpgsql;
+
+SELECT add_not_null_check('orgs', 'public_id');
+SELECT add_not_null_check('org_invitations', 'public_id');
+SELECT add_not_null_check('webhooks', 'public_id');
+SELECT add_not_null_check('webhook_deliveries', 'public_id');
+SELECT add_not_null_check('access_tokens', 'public_id');
+SELECT add_not_null_check('account_identities', 'public_id');
+SELECT add_not_null_check('failed_logins', 'public_id');
+SELECT add_not_null_check('audit_entries', 'public_id');
+
+ALTER TABLE orgs VALIDATE CONSTRAINT orgs_public_id_not_null;
+ALTER TABLE org_invitations VALIDATE CONSTRAINT org_invitations_public_id_not_null;
+ALTER TABLE webhooks VALIDATE CONSTRAINT webhooks_public_id_not_null;
+ALTER TABLE webhook_deliveries VALIDATE CONSTRAINT webhook_deliveries_public_id_not_null;
+ALTER TABLE access_tokens VALIDATE CONSTRAINT access_tokens_public_id_not_null;
+ALTER TABLE account_identities VALIDATE CONSTRAINT account_identities_public_id_not_null;
+ALTER TABLE failed_logins VALIDATE CONSTRAINT failed_logins_public_id_not_null;
+ALTER TABLE audit_entries VALIDATE CONSTRAINT audit_entries_public_id_not_null;
+
+ALTER TABLE orgs ALTER COLUMN public_id SET NOT NULL;
+ALTER TABLE orgs DROP CONSTRAINT IF EXISTS orgs_public_id_not_null;
+ALTER TABLE org_invitations ALTER COLUMN public_id SET NOT NULL;
+ALTER TABLE org_invitations DROP CONSTRAINT IF EXISTS org_invitations_public_id_not_null;
+ALTER TABLE webhooks ALTER COLUMN public_id SET NOT NULL;
+ALTER TABLE webhooks DROP CONSTRAINT IF EXISTS webhooks_public_id_not_null;
+ALTER TABLE webhook_deliveries ALTER COLUMN public_id SET NOT NULL;
+ALTER TABLE webhook_deliveries DROP CONSTRAINT IF EXISTS webhook_deliveries_public_id_not_null;
+ALTER TABLE access_tokens ALTER COLUMN public_id SET NOT NULL;
+ALTER TABLE access_tokens DROP CONSTRAINT IF EXISTS access_tokens_public_id_not_null;
+ALTER TABLE account_identities ALTER COLUMN public_id SET NOT NULL;
+ALTER TABLE account_identities DROP CONSTRAINT IF EXISTS account_identities_public_id_not_null;
+ALTER TABLE failed_logins ALTER COLUMN public_id SET NOT NULL;
+ALTER TABLE failed_logins DROP CONSTRAINT IF EXISTS failed_logins_public_id_not_null;
+ALTER TABLE audit_entries ALTER COLUMN public_id SET NOT NULL;
+ALTER TABLE audit_entries DROP CONSTRAINT IF EXISTS audit_entries_public_id_not_null;
+
+DROP PROCEDURE IF EXISTS fill_column(TEXT, TEXT, TEXT, INTEGER);
+DROP FUNCTION IF EXISTS add_not_null_check(TEXT, TEXT);
+
+RESET lock_timeout;
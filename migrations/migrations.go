@@ -0,0 +1,10 @@
+// Package migrations embeds the database migrations, so that the binary can
+// apply them without the source tree.
+package migrations
+
+import "embed"
+
+// FS holds the migration files.
+//
+//go:embed *.sql
+var FS embed.FS
@@ -0,0 +1,390 @@
+// Package migrate applies SQL migrations to a Postgres database.
+//
+// Migrations are .sql files named with a timestamp and a description, e.g.
+// 20200211111258_create_accounts.sql, and are applied in name order. A file
+// may end with a down section, after a line reading "-- migrate:down", which
+// reverts it.
+//
+// Applied migrations are recorded in a table, with a checksum of their file,
+// so that migrations which were edited after being applied are detected.
+// Migrations recorded before checksums were, by an earlier tool, have their
+// checksum recorded the first time they're seen.
+//
+// A migration runs in a single transaction with its record, unless it creates
+// or drops indexes concurrently, which Postgres doesn't allow in transactions.
+// Its statements are then run one at a time, and a failure may leave it
+// partly applied, so such migrations should be written to be rerun, e.g.
+// with IF NOT EXISTS.
+//
+// Runners take a session-level advisory lock, so that concurrent runners,
+// e.g. replicas migrating on start, apply each migration once.
+package migrate
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/jackc/pgx/v4"
+)
+
+// downMarker separates a migration's up and down sections.
+const downMarker = "-- migrate:down"
+
+// lockKey identifies the advisory lock held by runners.
+const lockKey int64 = 0x746f646f2d617069 // "todo-api"
+
+// namePattern matches migration file names.
+var namePattern = regexp.MustCompile(`^\d{14}_[a-z0-9_]+\.sql$`)
+
+// Migration is a migration file.
+type Migration struct {
+	// Name is the file name, without the .sql extension.
+	Name string
+
+	// Up is the SQL which applies the migration.
+	Up string
+
+	// Down is the SQL which reverts the migration, or empty if it can't be.
+	Down string
+
+	// Checksum is the hex encoded SHA-256 digest of the file.
+	Checksum string
+}
+
+// Load reads the migrations in the root of fsys, in the order they're applied.
+// Files which aren't .sql files are ignored.
+func Load(fsys fs.FS) ([]*Migration, error) {
+	names, err := fs.Glob(fsys, "*.sql")
+	if err != nil {
+		return nil, err
+	}
+	sort.Strings(names)
+	var result []*Migration
+	for _, name := range names {
+		if !namePattern.MatchString(name) {
+			return nil, fmt.Errorf("migration file name %q must be <timestamp>_<description>.sql", name)
+		}
+		b, err := fs.ReadFile(fsys, name)
+		if err != nil {
+			return nil, err
+		}
+		result = append(result, Parse(strings.TrimSuffix(name, ".sql"), b))
+	}
+	return result, nil
+}
+
+// Parse parses a migration file.
+func Parse(name string, b []byte) *Migration {
+	sum := sha256.Sum256(b)
+	m := &Migration{
+		Name:     name,
+		Up:       string(b),
+		Checksum: hex.EncodeToString(sum[:]),
+	}
+	for i := 0; i < len(m.Up); {
+		end := strings.IndexByte(m.Up[i:], '\n')
+		if end < 0 {
+			end = len(m.Up) - i
+		}
+		if strings.TrimSpace(m.Up[i:i+end]) == downMarker {
+			m.Up, m.Down = m.Up[:i], m.Up[i+end:]
+			break
+		}
+		i += end + 1
+	}
+	return m
+}
+
+// Create writes a new, empty migration to dir, returning its path.
+func Create(dir, description string, now time.Time) (string, error) {
+	description = strings.ToLower(strings.Join(strings.Fields(description), "_"))
+	name := now.UTC().Format("20060102150405") + "_" + description + ".sql"
+	if !namePattern.MatchString(name) {
+		return "", fmt.Errorf("migration description %q may only contain letters, digits and underscores", description)
+	}
+	p := filepath.Join(dir, name)
+	f, err := os.OpenFile(p, os.O_WRONLY|os.O_CREATE|os.O_EXCL, 0644)
+	if err != nil {
+		return "", err
+	}
+	if _, err := f.WriteString("\n\n" + downMarker + "\n"); err != nil {
+		f.Close()
+		return "", err
+	}
+	return p, f.Close()
+}
+
+// Status is whether a migration has been applied.
+type Status struct {
+	// Name is the migration's name.
+	Name string
+
+	// Applied is when the migration was applied, or nil if it hasn't been.
+	// It's the zero time for migrations recorded by an earlier tool.
+	Applied *time.Time
+
+	// Modified reports whether the migration's file changed after it was
+	// applied.
+	Modified bool
+
+	// Missing reports whether the migration was applied, but its file no
+	// longer exists.
+	Missing bool
+}
+
+// Migrator applies migrations to a database.
+type Migrator struct {
+	// Conn is the connection the migrations are applied with. It must not
+	// be shared, since the advisory lock belongs to its session.
+	Conn *pgx.Conn
+
+	// Migrations are the migrations, in the order they're applied.
+	Migrations []*Migration
+
+	// Table is the table applied migrations are recorded in, "migrations" if
+	// empty.
+	Table string
+}
+
+// applied is a migration recorded as applied.
+type applied struct {
+	checksum string
+	applied  time.Time
+}
+
+// table returns the quoted name of the table applied migrations are recorded
+// in.
+func (m *Migrator) table() string {
+	if m.Table == "" {
+		return `"migrations"`
+	}
+	return pgx.Identifier{m.Table}.Sanitize()
+}
+
+// Up applies the pending migrations, returning their names. It returns an
+// error before applying any if an applied migration was modified.
+func (m *Migrator) Up(ctx context.Context) ([]string, error) {
+	var names []string
+	err := m.withLock(ctx, func(done map[string]applied) error {
+		if err := m.checkModified(done); err != nil {
+			return err
+		}
+		for _, mig := range m.Migrations {
+			if _, ok := done[mig.Name]; ok {
+				continue
+			}
+			if err := m.run(ctx, mig.Up, `
+				INSERT INTO `+m.table()+` (name, checksum, applied)
+				VALUES ($1, $2, $3);
+			`, mig.Name, mig.Checksum, time.Now().UTC()); err != nil {
+				return fmt.Errorf("%s: %w", mig.Name, err)
+			}
+			names = append(names, mig.Name)
+		}
+		return nil
+	})
+	return names, err
+}
+
+// Down reverts the latest n applied migrations, newest first, returning their
+// names. It returns an error before reverting any if one of them was modified
+// or can't be reverted.
+func (m *Migrator) Down(ctx context.Context, n int) ([]string, error) {
+	var names []string
+	err := m.withLock(ctx, func(done map[string]applied) error {
+		var revert []*Migration
+		for i := len(m.Migrations) - 1; i >= 0 && len(revert) < n; i-- {
+			mig := m.Migrations[i]
+			a, ok := done[mig.Name]
+			if !ok {
+				continue
+			}
+			if a.checksum != mig.Checksum {
+				return fmt.Errorf("migration %s was modified after it was applied", mig.Name)
+			}
+			if strings.TrimSpace(mig.Down) == "" {
+				return fmt.Errorf("migration %s can't be reverted: it has no down section", mig.Name)
+			}
+			revert = append(revert, mig)
+		}
+		for _, mig := range revert {
+			if err := m.run(ctx, mig.Down, `DELETE FROM `+m.table()+` WHERE name = $1;`, mig.Name); err != nil {
+				return fmt.Errorf("%s: %w", mig.Name, err)
+			}
+			names = append(names, mig.Name)
+		}
+		return nil
+	})
+	return names, err
+}
+
+// Status reports whether each migration has been applied, followed by the
+// applied migrations whose files no longer exist.
+func (m *Migrator) Status(ctx context.Context) ([]Status, error) {
+	var result []Status
+	err := m.withLock(ctx, func(done map[string]applied) error {
+		known := make(map[string]bool, len(m.Migrations))
+		for _, mig := range m.Migrations {
+			known[mig.Name] = true
+			s := Status{Name: mig.Name}
+			if a, ok := done[mig.Name]; ok {
+				t := a.applied
+				s.Applied = &t
+				s.Modified = a.checksum != mig.Checksum
+			}
+			result = append(result, s)
+		}
+		var missing []Status
+		for name, a := range done {
+			if !known[name] {
+				t := a.applied
+				missing = append(missing, Status{Name: name, Applied: &t, Missing: true})
+			}
+		}
+		sort.Slice(missing, func(i, j int) bool { return missing[i].Name < missing[j].Name })
+		result = append(result, missing...)
+		return nil
+	})
+	return result, err
+}
+
+// checkModified returns an error if an applied migration was modified.
+func (m *Migrator) checkModified(done map[string]applied) error {
+	for _, mig := range m.Migrations {
+		if a, ok := done[mig.Name]; ok && a.checksum != mig.Checksum {
+			return fmt.Errorf("migration %s was modified after it was applied", mig.Name)
+		}
+	}
+	return nil
+}
+
+// withLock calls fn with the applied migrations while holding the advisory
+// lock, waiting for other runners to finish.
+func (m *Migrator) withLock(ctx context.Context, fn func(done map[string]applied) error) (err error) {
+	if _, err := m.Conn.Exec(ctx, `SELECT pg_advisory_lock($1);`, lockKey); err != nil {
+		return err
+	}
+	defer func() {
+		// The lock is released with the session if this fails.
+		if _, unlockErr := m.Conn.Exec(context.Background(), `SELECT pg_advisory_unlock($1);`, lockKey); err == nil {
+			err = unlockErr
+		}
+	}()
+	done, err := m.applied(ctx)
+	if err != nil {
+		return err
+	}
+	return fn(done)
+}
+
+// applied creates or upgrades the table applied migrations are recorded in,
+// and returns them by name.
+func (m *Migrator) applied(ctx context.Context) (map[string]applied, error) {
+	if _, err := m.Conn.Exec(ctx, `
+		CREATE TABLE IF NOT EXISTS `+m.table()+` (name TEXT);
+		ALTER TABLE `+m.table()+`
+			ADD COLUMN IF NOT EXISTS checksum TEXT,
+			ADD COLUMN IF NOT EXISTS applied TIMESTAMP WITHOUT TIME ZONE;
+	`); err != nil {
+		return nil, err
+	}
+	rows, err := m.Conn.Query(ctx, `SELECT name, checksum, applied FROM `+m.table()+`;`)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	result := make(map[string]applied)
+	for rows.Next() {
+		var (
+			name      string
+			checksum  *string
+			appliedAt *time.Time
+		)
+		if err := rows.Scan(&name, &checksum, &appliedAt); err != nil {
+			return nil, err
+		}
+		// Earlier tools may have recorded the file name with its extension.
+		name = strings.TrimSuffix(name, ".sql")
+		var a applied
+		if appliedAt != nil {
+			a.applied = *appliedAt
+		}
+		if checksum != nil {
+			a.checksum = *checksum
+		}
+		result[name] = a
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	rows.Close()
+	for _, mig := range m.Migrations {
+		a, ok := result[mig.Name]
+		if !ok || a.checksum != "" {
+			continue
+		}
+		if _, err := m.Conn.Exec(ctx, `
+			UPDATE `+m.table()+`
+			SET name = $1, checksum = $2
+			WHERE name IN ($1, $1 || '.sql');
+		`, mig.Name, mig.Checksum); err != nil {
+			return nil, err
+		}
+		a.checksum = mig.Checksum
+		result[mig.Name] = a
+	}
+	return result, nil
+}
+
+// run runs a migration's SQL and then record, which records that it was
+// applied or reverted.
+func (m *Migrator) run(ctx context.Context, sql, record string, args ...interface{}) error {
+	statements := Split(sql)
+	if !concurrent(statements) {
+		tx, err := m.Conn.Begin(ctx)
+		if err != nil {
+			return err
+		}
+		defer func() {
+			_ = tx.Rollback(ctx) // no-op once committed
+		}()
+		for _, s := range statements {
+			if _, err := tx.Exec(ctx, s); err != nil {
+				return err
+			}
+		}
+		if _, err := tx.Exec(ctx, record, args...); err != nil {
+			return err
+		}
+		return tx.Commit(ctx)
+	}
+	for _, s := range statements {
+		if _, err := m.Conn.Exec(ctx, s); err != nil {
+			return err
+		}
+	}
+	_, err := m.Conn.Exec(ctx, record, args...)
+	return err
+}
+
+// concurrentPattern matches statements which can't run in a transaction.
+var concurrentPattern = regexp.MustCompile(`(?i)^\s*(CREATE\s+(UNIQUE\s+)?INDEX|DROP\s+INDEX|REINDEX)\s+.*\bCONCURRENTLY\b`)
+
+// concurrent reports whether any of statements can't run in a transaction.
+func concurrent(statements []string) bool {
+	for _, s := range statements {
+		if concurrentPattern.MatchString(stripComments(s)) {
+			return true
+		}
+	}
+	return false
+}
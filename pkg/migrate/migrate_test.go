@@ -0,0 +1,187 @@
+package migrate_test
+
+import (
+	"context"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"testing/fstest"
+	"time"
+
+	"github.com/deliveroo/assert-go"
+	"github.com/deliveroo/todo-api/pkg/migrate"
+	"github.com/deliveroo/todo-api/selftest/deps/postgres"
+	"github.com/jackc/pgx/v4"
+)
+
+func TestSplit(t *testing.T) {
+	for _, tt := range []struct {
+		sql  string
+		want []string
+	}{
+		{"", nil},
+		{"-- just a comment\n", nil},
+		{"SELECT 1", []string{"SELECT 1"}},
+		{"SELECT 1;\nSELECT 2;\n", []string{"SELECT 1", "SELECT 2"}},
+		{"SELECT ';';", []string{"SELECT ';'"}},
+		{"SELECT 'it''s; fine';", []string{"SELECT 'it''s; fine'"}},
+		{`SELECT 1 AS "a;b";`, []string{`SELECT 1 AS "a;b"`}},
+		{"SELECT 1; -- a; comment\nSELECT 2;", []string{"SELECT 1", "-- a; comment\nSELECT 2"}},
+		{"SELECT /* a; /* nested; */ comment */ 1;", []string{"SELECT /* a; /* nested; */ comment */ 1"}},
+		{"CREATE FUNCTION f() RETURNS int AS $$ SELECT 1; $$ LANGUAGE sql;", []string{"CREATE FUNCTION f() RETURNS int AS $$ SELECT 1; $$ LANGUAGE sql"}},
+		{"SELECT $body$ a; $$ b; $body$;", []string{"SELECT $body$ a; $$ b; $body$"}},
+		{"PREPARE p AS SELECT $1; SELECT 2;", []string{"PREPARE p AS SELECT $1", "SELECT 2"}},
+	} {
+		assert.Equal(t, migrate.Split(tt.sql), tt.want)
+	}
+}
+
+func TestParse(t *testing.T) {
+	m := migrate.Parse("20200101000000_a", []byte("CREATE TABLE a ();\n\n-- migrate:down\nDROP TABLE a;\n"))
+	assert.Equal(t, m.Name, "20200101000000_a")
+	assert.Equal(t, m.Up, "CREATE TABLE a ();\n\n")
+	assert.Equal(t, m.Down, "\nDROP TABLE a;\n")
+	assert.Equal(t, len(m.Checksum), 64)
+
+	m = migrate.Parse("20200101000000_a", []byte("CREATE TABLE a ();\n"))
+	assert.Equal(t, m.Up, "CREATE TABLE a ();\n")
+	assert.Equal(t, m.Down, "")
+
+	edited := migrate.Parse("20200101000000_a", []byte("CREATE TABLE a (id int);\n"))
+	assert.True(t, edited.Checksum != m.Checksum)
+}
+
+func TestLoad(t *testing.T) {
+	migrations, err := migrate.Load(fstest.MapFS{
+		"20200102000000_b.sql": {Data: []byte("SELECT 2;")},
+		"20200101000000_a.sql": {Data: []byte("SELECT 1;")},
+		"README.md":            {Data: []byte("not a migration")},
+	})
+	assert.Must(t, err)
+	assert.Equal(t, len(migrations), 2)
+	assert.Equal(t, migrations[0].Name, "20200101000000_a")
+	assert.Equal(t, migrations[1].Name, "20200102000000_b")
+
+	_, err = migrate.Load(fstest.MapFS{
+		"a.sql": {Data: []byte("SELECT 1;")},
+	})
+	assert.True(t, err != nil)
+}
+
+func TestCreate(t *testing.T) {
+	dir, err := ioutil.TempDir("", "migrate")
+	assert.Must(t, err)
+	defer os.RemoveAll(dir)
+
+	now := time.Date(2020, 2, 11, 11, 12, 58, 0, time.UTC)
+	p, err := migrate.Create(dir, "Add Widgets", now)
+	assert.Must(t, err)
+	assert.Equal(t, p, filepath.Join(dir, "20200211111258_add_widgets.sql"))
+	b, err := ioutil.ReadFile(p)
+	assert.Must(t, err)
+	assert.True(t, strings.Contains(string(b), "-- migrate:down"))
+
+	_, err = migrate.Create(dir, "Add Widgets", now)
+	assert.True(t, err != nil)
+	_, err = migrate.Create(dir, "add-widgets!", now)
+	assert.True(t, err != nil)
+}
+
+func TestMigrator(t *testing.T) {
+	if testing.Short() || postgres.URL() == "" {
+		t.Skip("requires postgres")
+	}
+	ctx := context.Background()
+	conn, err := pgx.Connect(ctx, postgres.URL())
+	assert.Must(t, err)
+	defer conn.Close(ctx)
+
+	const table = "migrate_test_migrations"
+	cleanup := func() {
+		_, err := conn.Exec(ctx, `
+			DROP TABLE IF EXISTS migrate_test_migrations;
+			DROP TABLE IF EXISTS migrate_test_widgets;
+		`)
+		assert.Must(t, err)
+	}
+	cleanup()
+	defer cleanup()
+
+	migrations := []*migrate.Migration{
+		migrate.Parse("20200101000000_create_widgets", []byte(`
+			CREATE TABLE migrate_test_widgets (id bigserial PRIMARY KEY, name text);
+			-- migrate:down
+			DROP TABLE migrate_test_widgets;
+		`)),
+		migrate.Parse("20200102000000_index_widgets", []byte(`
+			CREATE INDEX CONCURRENTLY IF NOT EXISTS migrate_test_widgets_name ON migrate_test_widgets (name);
+			-- migrate:down
+			DROP INDEX CONCURRENTLY IF EXISTS migrate_test_widgets_name;
+		`)),
+	}
+	m := &migrate.Migrator{Conn: conn, Migrations: migrations, Table: table}
+
+	t.Run("up", func(t *testing.T) {
+		names, err := m.Up(ctx)
+		assert.Must(t, err)
+		assert.Equal(t, names, []string{"20200101000000_create_widgets", "20200102000000_index_widgets"})
+
+		names, err = m.Up(ctx)
+		assert.Must(t, err)
+		assert.Equal(t, len(names), 0)
+
+		status, err := m.Status(ctx)
+		assert.Must(t, err)
+		assert.Equal(t, len(status), 2)
+		assert.NotNil(t, status[0].Applied)
+		assert.False(t, status[0].Modified)
+	})
+	t.Run("modified", func(t *testing.T) {
+		edited := *migrations[0]
+		edited.Checksum = "edited"
+		m := &migrate.Migrator{Conn: conn, Migrations: []*migrate.Migration{&edited, migrations[1]}, Table: table}
+		_, err := m.Up(ctx)
+		assert.True(t, err != nil)
+
+		status, err := m.Status(ctx)
+		assert.Must(t, err)
+		assert.True(t, status[0].Modified)
+	})
+	t.Run("missing", func(t *testing.T) {
+		m := &migrate.Migrator{Conn: conn, Migrations: migrations[:1], Table: table}
+		status, err := m.Status(ctx)
+		assert.Must(t, err)
+		assert.Equal(t, len(status), 2)
+		assert.Equal(t, status[1].Name, "20200102000000_index_widgets")
+		assert.True(t, status[1].Missing)
+	})
+	t.Run("down", func(t *testing.T) {
+		names, err := m.Down(ctx, 2)
+		assert.Must(t, err)
+		assert.Equal(t, names, []string{"20200102000000_index_widgets", "20200101000000_create_widgets"})
+
+		status, err := m.Status(ctx)
+		assert.Must(t, err)
+		assert.Nil(t, status[0].Applied)
+		assert.Nil(t, status[1].Applied)
+	})
+	t.Run("adopt", func(t *testing.T) {
+		// Migrations recorded by the previous tool have no checksum, and
+		// their names may include the extension.
+		_, err := conn.Exec(ctx, `INSERT INTO migrate_test_migrations (name) VALUES ('20200101000000_create_widgets.sql');`)
+		assert.Must(t, err)
+		_, err = conn.Exec(ctx, `CREATE TABLE migrate_test_widgets (id bigserial PRIMARY KEY, name text);`)
+		assert.Must(t, err)
+
+		names, err := m.Up(ctx)
+		assert.Must(t, err)
+		assert.Equal(t, names, []string{"20200102000000_index_widgets"})
+
+		status, err := m.Status(ctx)
+		assert.Must(t, err)
+		assert.Equal(t, len(status), 2)
+		assert.False(t, status[0].Modified)
+	})
+}
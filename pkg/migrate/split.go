@@ -0,0 +1,139 @@
+package migrate
+
+import "strings"
+
+// Split splits sql into statements, on semicolons outside of string literals,
+// quoted identifiers, dollar-quoted strings and comments. Statements which are
+// empty, or only comments, are dropped.
+func Split(sql string) []string {
+	var (
+		result []string
+		start  int
+	)
+	add := func(s string) {
+		if strings.TrimSpace(stripComments(s)) != "" {
+			result = append(result, strings.TrimSpace(s))
+		}
+	}
+	for i := 0; i < len(sql); {
+		switch c := sql[i]; {
+		case c == ';':
+			add(sql[start:i])
+			i++
+			start = i
+		case c == '\'' || c == '"':
+			i = skipQuoted(sql, i, c)
+		case c == '-' && strings.HasPrefix(sql[i:], "--"):
+			i = skipLine(sql, i)
+		case c == '/' && strings.HasPrefix(sql[i:], "/*"):
+			i = skipBlockComment(sql, i)
+		case c == '$':
+			i = skipDollarQuoted(sql, i)
+		default:
+			i++
+		}
+	}
+	add(sql[start:])
+	return result
+}
+
+// stripComments returns s without its comments.
+func stripComments(s string) string {
+	var b strings.Builder
+	for i := 0; i < len(s); {
+		switch c := s[i]; {
+		case c == '\'' || c == '"':
+			end := skipQuoted(s, i, c)
+			b.WriteString(s[i:end])
+			i = end
+		case c == '-' && strings.HasPrefix(s[i:], "--"):
+			i = skipLine(s, i)
+			b.WriteByte('\n')
+		case c == '/' && strings.HasPrefix(s[i:], "/*"):
+			i = skipBlockComment(s, i)
+			b.WriteByte(' ')
+		case c == '$':
+			end := skipDollarQuoted(s, i)
+			b.WriteString(s[i:end])
+			i = end
+		default:
+			b.WriteByte(c)
+			i++
+		}
+	}
+	return b.String()
+}
+
+// skipQuoted returns the index after the literal or identifier quoted with q
+// starting at i. Doubled quotes are escaped quotes.
+func skipQuoted(s string, i int, q byte) int {
+	for i++; i < len(s); i++ {
+		if s[i] == q {
+			if i+1 < len(s) && s[i+1] == q {
+				i++
+				continue
+			}
+			return i + 1
+		}
+	}
+	return len(s)
+}
+
+// skipLine returns the index of the end of the line containing i.
+func skipLine(s string, i int) int {
+	if end := strings.IndexByte(s[i:], '\n'); end >= 0 {
+		return i + end
+	}
+	return len(s)
+}
+
+// skipBlockComment returns the index after the block comment starting at i.
+// Block comments nest.
+func skipBlockComment(s string, i int) int {
+	depth := 0
+	for i < len(s) {
+		switch {
+		case strings.HasPrefix(s[i:], "/*"):
+			depth++
+			i += 2
+		case strings.HasPrefix(s[i:], "*/"):
+			depth--
+			i += 2
+			if depth == 0 {
+				return i
+			}
+		default:
+			i++
+		}
+	}
+	return len(s)
+}
+
+// skipDollarQuoted returns the index after the dollar-quoted string starting
+// at i, e.g. $$...$$ or $body$...$body$, or i+1 if the $ doesn't start one,
+// e.g. in a parameter like $1.
+func skipDollarQuoted(s string, i int) int {
+	end := i + 1
+	for end < len(s) && isTagByte(s[end], end == i+1) {
+		end++
+	}
+	if end >= len(s) || s[end] != '$' {
+		return i + 1
+	}
+	tag := s[i : end+1]
+	if close := strings.Index(s[end+1:], tag); close >= 0 {
+		return end + 1 + close + len(tag)
+	}
+	return len(s)
+}
+
+// isTagByte reports whether c may appear in a dollar quote's tag.
+func isTagByte(c byte, first bool) bool {
+	switch {
+	case c == '_', 'a' <= c && c <= 'z', 'A' <= c && c <= 'Z', c >= 0x80:
+		return true
+	case '0' <= c && c <= '9':
+		return !first
+	}
+	return false
+}
@@ -0,0 +1,147 @@
+package oidc
+
+import (
+	"context"
+	"crypto"
+	"crypto/rsa"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"math/big"
+	"strings"
+	"time"
+)
+
+// keyRefreshInterval limits how often the provider's keys are fetched when a
+// token is signed by an unknown key.
+const keyRefreshInterval = time.Minute
+
+// keySet is the provider's signing keys.
+type keySet struct {
+	keys    map[string]*rsa.PublicKey
+	fetched time.Time
+}
+
+// jwk is a JSON Web Key (RFC 7517). Only RSA keys are supported.
+type jwk struct {
+	KeyType string `json:"kty"`
+	KeyID   string `json:"kid"`
+	Use     string `json:"use"`
+	N       string `json:"n"`
+	E       string `json:"e"`
+}
+
+// jwtHeader is the JOSE header of an ID token.
+type jwtHeader struct {
+	Algorithm string `json:"alg"`
+	KeyID     string `json:"kid"`
+}
+
+// verifySignature checks that the token is signed with RS256 by one of the
+// provider's keys, and decodes its claims into v.
+func (p *Provider) verifySignature(ctx context.Context, md *metadata, token string, v interface{}) error {
+	parts := strings.Split(token, ".")
+	if len(parts) != 3 {
+		return ErrInvalidToken
+	}
+	var header jwtHeader
+	if err := decodeSegment(parts[0], &header); err != nil {
+		return ErrInvalidToken
+	}
+	if header.Algorithm != "RS256" {
+		return fmt.Errorf("%w: unsupported algorithm %q", ErrInvalidToken, header.Algorithm)
+	}
+	key, err := p.key(ctx, md, header.KeyID)
+	if err != nil {
+		return err
+	}
+	sig, err := base64.RawURLEncoding.DecodeString(parts[2])
+	if err != nil {
+		return ErrInvalidToken
+	}
+	digest := sha256.Sum256([]byte(parts[0] + "." + parts[1]))
+	if err := rsa.VerifyPKCS1v15(key, crypto.SHA256, digest[:], sig); err != nil {
+		return fmt.Errorf("%w: bad signature", ErrInvalidToken)
+	}
+	if err := decodeSegment(parts[1], v); err != nil {
+		return ErrInvalidToken
+	}
+	return nil
+}
+
+// key returns the provider's key with the given id, fetching the provider's
+// keys if it's unknown, since the provider may have rotated them.
+func (p *Provider) key(ctx context.Context, md *metadata, kid string) (*rsa.PublicKey, error) {
+	p.mu.Lock()
+	ks := p.keys
+	p.mu.Unlock()
+	if ks != nil {
+		if key, ok := ks.lookup(kid); ok {
+			return key, nil
+		}
+		if time.Since(ks.fetched) < keyRefreshInterval {
+			return nil, fmt.Errorf("%w: unknown key %q", ErrInvalidToken, kid)
+		}
+	}
+	var doc struct {
+		Keys []jwk `json:"keys"`
+	}
+	if err := p.getJSON(ctx, md.JWKSURI, &doc); err != nil {
+		return nil, fmt.Errorf("oidc: keys: %w", err)
+	}
+	ks = &keySet{keys: make(map[string]*rsa.PublicKey), fetched: time.Now()}
+	for _, k := range doc.Keys {
+		if k.KeyType != "RSA" || (k.Use != "" && k.Use != "sig") {
+			continue
+		}
+		key, err := k.rsaPublicKey()
+		if err != nil {
+			return nil, fmt.Errorf("oidc: key %q: %w", k.KeyID, err)
+		}
+		ks.keys[k.KeyID] = key
+	}
+	p.mu.Lock()
+	p.keys = ks
+	p.mu.Unlock()
+	if key, ok := ks.lookup(kid); ok {
+		return key, nil
+	}
+	return nil, fmt.Errorf("%w: unknown key %q", ErrInvalidToken, kid)
+}
+
+// lookup finds a key by id. A token without a key id may be verified by the
+// provider's only key.
+func (ks *keySet) lookup(kid string) (*rsa.PublicKey, bool) {
+	if kid == "" && len(ks.keys) == 1 {
+		for _, key := range ks.keys {
+			return key, true
+		}
+	}
+	key, ok := ks.keys[kid]
+	return key, ok
+}
+
+func (k *jwk) rsaPublicKey() (*rsa.PublicKey, error) {
+	n, err := base64.RawURLEncoding.DecodeString(k.N)
+	if err != nil {
+		return nil, err
+	}
+	e, err := base64.RawURLEncoding.DecodeString(k.E)
+	if err != nil {
+		return nil, err
+	}
+	exp := new(big.Int).SetBytes(e)
+	if !exp.IsInt64() || exp.Int64() > 1<<31-1 || exp.Int64() < 3 {
+		return nil, fmt.Errorf("invalid exponent")
+	}
+	return &rsa.PublicKey{N: new(big.Int).SetBytes(n), E: int(exp.Int64())}, nil
+}
+
+func decodeSegment(s string, v interface{}) error {
+	b, err := base64.RawURLEncoding.DecodeString(s)
+	if err != nil {
+		return err
+	}
+	return json.Unmarshal(b, v)
+}
@@ -0,0 +1,269 @@
+// Package oidc implements the relying party side of the OpenID Connect
+// authorization code flow with PKCE, for logging in with an external identity
+// provider.
+package oidc
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"net/url"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Provider is an OpenID Connect identity provider, configured for a client.
+type Provider struct {
+	Issuer       string   // issuer URL, used to discover the provider's endpoints
+	ClientID     string   // client id registered with the provider
+	ClientSecret string   // client secret registered with the provider, if any
+	RedirectURL  string   // URL the provider redirects back to
+	Scopes       []string // scopes to request, which must include "openid"
+
+	// HTTPClient is used to make requests to the provider. If nil,
+	// http.DefaultClient is used.
+	HTTPClient *http.Client
+
+	mu       sync.Mutex
+	metadata *metadata
+	keys     *keySet
+}
+
+// metadata is the subset of the provider's discovery document that is used.
+type metadata struct {
+	Issuer                string `json:"issuer"`
+	AuthorizationEndpoint string `json:"authorization_endpoint"`
+	TokenEndpoint         string `json:"token_endpoint"`
+	JWKSURI               string `json:"jwks_uri"`
+}
+
+// Claims are the standard claims of an ID token which identify the user.
+type Claims struct {
+	Issuer            string `json:"iss"`
+	Subject           string `json:"sub"`
+	Email             string `json:"email"`
+	EmailVerified     bool   `json:"email_verified"`
+	Name              string `json:"name"`
+	PreferredUsername string `json:"preferred_username"`
+}
+
+// idTokenClaims are all of the claims of an ID token which are verified.
+type idTokenClaims struct {
+	Claims
+	Audience  audience `json:"aud"`
+	Nonce     string   `json:"nonce"`
+	ExpiresAt int64    `json:"exp"`
+	IssuedAt  int64    `json:"iat"`
+}
+
+// audience is the aud claim, which may be a string or an array of strings.
+type audience []string
+
+func (a *audience) UnmarshalJSON(b []byte) error {
+	var s string
+	if err := json.Unmarshal(b, &s); err == nil {
+		*a = audience{s}
+		return nil
+	}
+	var ss []string
+	if err := json.Unmarshal(b, &ss); err != nil {
+		return err
+	}
+	*a = ss
+	return nil
+}
+
+func (a audience) contains(s string) bool {
+	for _, v := range a {
+		if v == s {
+			return true
+		}
+	}
+	return false
+}
+
+// ErrInvalidToken is returned when an ID token can't be verified.
+var ErrInvalidToken = errors.New("oidc: invalid ID token")
+
+// NewState returns a random value for the state or nonce parameter.
+func NewState() string {
+	return randomString(32)
+}
+
+// NewVerifier returns a random PKCE code verifier (RFC 7636).
+func NewVerifier() string {
+	return randomString(32)
+}
+
+// Challenge returns the S256 PKCE code challenge for a code verifier.
+func Challenge(verifier string) string {
+	sum := sha256.Sum256([]byte(verifier))
+	return base64.RawURLEncoding.EncodeToString(sum[:])
+}
+
+// AuthCodeURL returns the URL of the provider's authorization endpoint, to
+// which the user is redirected to log in.
+func (p *Provider) AuthCodeURL(ctx context.Context, state, nonce, verifier string) (string, error) {
+	md, err := p.discover(ctx)
+	if err != nil {
+		return "", err
+	}
+	u, err := url.Parse(md.AuthorizationEndpoint)
+	if err != nil {
+		return "", err
+	}
+	q := u.Query()
+	q.Set("response_type", "code")
+	q.Set("client_id", p.ClientID)
+	q.Set("redirect_uri", p.RedirectURL)
+	q.Set("scope", strings.Join(p.Scopes, " "))
+	q.Set("state", state)
+	q.Set("nonce", nonce)
+	q.Set("code_challenge", Challenge(verifier))
+	q.Set("code_challenge_method", "S256")
+	u.RawQuery = q.Encode()
+	return u.String(), nil
+}
+
+// tokenResponse is the response from the token endpoint.
+type tokenResponse struct {
+	AccessToken      string `json:"access_token"`
+	IDToken          string `json:"id_token"`
+	Error            string `json:"error"`
+	ErrorDescription string `json:"error_description"`
+}
+
+// Exchange exchanges an authorization code for an ID token, and verifies the
+// ID token against the nonce of the original request.
+func (p *Provider) Exchange(ctx context.Context, code, verifier, nonce string) (*Claims, error) {
+	md, err := p.discover(ctx)
+	if err != nil {
+		return nil, err
+	}
+	form := url.Values{
+		"grant_type":    {"authorization_code"},
+		"code":          {code},
+		"redirect_uri":  {p.RedirectURL},
+		"client_id":     {p.ClientID},
+		"code_verifier": {verifier},
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, md.TokenEndpoint, strings.NewReader(form.Encode()))
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	req.Header.Set("Accept", "application/json")
+	if p.ClientSecret != "" {
+		req.SetBasicAuth(url.QueryEscape(p.ClientID), url.QueryEscape(p.ClientSecret))
+	}
+	resp, err := p.client().Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	var tr tokenResponse
+	if err := json.NewDecoder(io.LimitReader(resp.Body, 1<<20)).Decode(&tr); err != nil {
+		return nil, fmt.Errorf("oidc: token response: %w", err)
+	}
+	if tr.Error != "" {
+		return nil, fmt.Errorf("oidc: token error %s: %s", tr.Error, tr.ErrorDescription)
+	}
+	if resp.StatusCode != http.StatusOK || tr.IDToken == "" {
+		return nil, fmt.Errorf("oidc: token endpoint returned %s without an ID token", resp.Status)
+	}
+	return p.Verify(ctx, tr.IDToken, nonce)
+}
+
+// Verify verifies an ID token's signature, issuer, audience, expiry and
+// nonce, and returns its claims.
+func (p *Provider) Verify(ctx context.Context, token, nonce string) (*Claims, error) {
+	md, err := p.discover(ctx)
+	if err != nil {
+		return nil, err
+	}
+	var c idTokenClaims
+	if err := p.verifySignature(ctx, md, token, &c); err != nil {
+		return nil, err
+	}
+	now := time.Now().Unix()
+	switch {
+	case c.Issuer != md.Issuer:
+		return nil, fmt.Errorf("%w: unexpected issuer %q", ErrInvalidToken, c.Issuer)
+	case !c.Audience.contains(p.ClientID):
+		return nil, fmt.Errorf("%w: not issued for this client", ErrInvalidToken)
+	case now >= c.ExpiresAt+clockSkew:
+		return nil, fmt.Errorf("%w: expired", ErrInvalidToken)
+	case c.Nonce != nonce:
+		return nil, fmt.Errorf("%w: nonce mismatch", ErrInvalidToken)
+	case c.Subject == "":
+		return nil, fmt.Errorf("%w: no subject", ErrInvalidToken)
+	}
+	return &c.Claims, nil
+}
+
+// clockSkew is how many seconds an ID token is accepted after it expires, to
+// allow for clock drift.
+const clockSkew = 60
+
+// discover fetches and caches the provider's discovery document.
+func (p *Provider) discover(ctx context.Context) (*metadata, error) {
+	p.mu.Lock()
+	md := p.metadata
+	p.mu.Unlock()
+	if md != nil {
+		return md, nil
+	}
+	md = new(metadata)
+	wellKnown := strings.TrimSuffix(p.Issuer, "/") + "/.well-known/openid-configuration"
+	if err := p.getJSON(ctx, wellKnown, md); err != nil {
+		return nil, fmt.Errorf("oidc: discovery: %w", err)
+	}
+	if md.Issuer != p.Issuer {
+		return nil, fmt.Errorf("oidc: discovery: issuer %q doesn't match %q", md.Issuer, p.Issuer)
+	}
+	p.mu.Lock()
+	p.metadata = md
+	p.mu.Unlock()
+	return md, nil
+}
+
+func (p *Provider) getJSON(ctx context.Context, url string, v interface{}) error {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, url, nil)
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Accept", "application/json")
+	resp, err := p.client().Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		_, _ = io.Copy(ioutil.Discard, resp.Body)
+		return fmt.Errorf("GET %s: %s", url, resp.Status)
+	}
+	return json.NewDecoder(io.LimitReader(resp.Body, 1<<20)).Decode(v)
+}
+
+func (p *Provider) client() *http.Client {
+	if p.HTTPClient != nil {
+		return p.HTTPClient
+	}
+	return http.DefaultClient
+}
+
+func randomString(n int) string {
+	b := make([]byte, n)
+	if _, err := rand.Read(b); err != nil {
+		panic(err)
+	}
+	return base64.RawURLEncoding.EncodeToString(b)
+}
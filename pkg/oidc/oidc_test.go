@@ -0,0 +1,99 @@
+package oidc_test
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/url"
+	"testing"
+
+	"github.com/deliveroo/assert-go"
+	"github.com/deliveroo/todo-api/pkg/oidc"
+	"github.com/deliveroo/todo-api/pkg/oidc/oidctest"
+)
+
+// authorize follows the provider's redirect and returns the code and state
+// it would have sent to the redirect URL.
+func authorize(t *testing.T, authURL string) (code, state string) {
+	t.Helper()
+	client := &http.Client{
+		CheckRedirect: func(*http.Request, []*http.Request) error {
+			return http.ErrUseLastResponse
+		},
+	}
+	resp, err := client.Get(authURL)
+	assert.Must(t, err)
+	resp.Body.Close()
+	assert.Equal(t, resp.StatusCode, http.StatusFound)
+	loc, err := url.Parse(resp.Header.Get("Location"))
+	assert.Must(t, err)
+	return loc.Query().Get("code"), loc.Query().Get("state")
+}
+
+func TestAuthorizationCodeFlow(t *testing.T) {
+	idp, err := oidctest.NewServer("client", "secret")
+	assert.Must(t, err)
+	defer idp.Close()
+	idp.SetUser(oidc.Claims{
+		Subject:       "user-1",
+		Email:         "user@example.com",
+		EmailVerified: true,
+	})
+
+	var (
+		ctx = context.Background()
+		p   = &oidc.Provider{
+			Issuer:       idp.Issuer,
+			ClientID:     "client",
+			ClientSecret: "secret",
+			RedirectURL:  "http://localhost/callback",
+			Scopes:       []string{"openid", "email"},
+		}
+		nonce    = oidc.NewState()
+		verifier = oidc.NewVerifier()
+	)
+	authURL, err := p.AuthCodeURL(ctx, "state", nonce, verifier)
+	assert.Must(t, err)
+
+	t.Run("wrong verifier", func(t *testing.T) {
+		code, _ := authorize(t, authURL)
+		_, err := p.Exchange(ctx, code, oidc.NewVerifier(), nonce)
+		assert.NotNil(t, err)
+	})
+	t.Run("wrong nonce", func(t *testing.T) {
+		code, _ := authorize(t, authURL)
+		_, err := p.Exchange(ctx, code, verifier, "other")
+		assert.True(t, errors.Is(err, oidc.ErrInvalidToken))
+	})
+	t.Run("exchange", func(t *testing.T) {
+		code, state := authorize(t, authURL)
+		assert.Equal(t, state, "state")
+		claims, err := p.Exchange(ctx, code, verifier, nonce)
+		assert.Must(t, err)
+		assert.Equal(t, claims.Issuer, idp.Issuer)
+		assert.Equal(t, claims.Subject, "user-1")
+		assert.Equal(t, claims.Email, "user@example.com")
+		assert.True(t, claims.EmailVerified)
+
+		// Codes are single use.
+		_, err = p.Exchange(ctx, code, verifier, nonce)
+		assert.NotNil(t, err)
+	})
+	t.Run("wrong client", func(t *testing.T) {
+		other := &oidc.Provider{
+			Issuer:       idp.Issuer,
+			ClientID:     "client",
+			ClientSecret: "wrong",
+			RedirectURL:  p.RedirectURL,
+		}
+		code, _ := authorize(t, authURL)
+		_, err := other.Exchange(ctx, code, verifier, nonce)
+		assert.NotNil(t, err)
+	})
+}
+
+func TestChallenge(t *testing.T) {
+	// Example from RFC 7636, appendix B.
+	got := oidc.Challenge("dBjftJeZ4CVP-mB92K27uhbUJU1p1r_wW1gFWFOEjXk")
+	assert.Equal(t, got, "E9Melhoa2OwvFrEMTJguCHaoeK1t8URWbuGJSstw-cM")
+}
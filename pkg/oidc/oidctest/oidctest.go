@@ -0,0 +1,218 @@
+// Package oidctest provides an in-process OpenID Connect identity provider
+// for testing relying parties.
+package oidctest
+
+import (
+	"crypto"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"math/big"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"sync"
+	"time"
+
+	"github.com/deliveroo/todo-api/pkg/oidc"
+)
+
+// keyID identifies the server's signing key.
+const keyID = "oidctest"
+
+// Server is an identity provider which logs in whichever user was last set
+// with SetUser, without prompting.
+type Server struct {
+	Issuer       string // issuer URL of the server
+	ClientID     string // the only client the server accepts
+	ClientSecret string // the client's secret
+
+	server *httptest.Server
+	key    *rsa.PrivateKey
+
+	mu    sync.Mutex
+	user  oidc.Claims
+	codes map[string]*grant
+}
+
+// grant is an authorization code waiting to be exchanged.
+type grant struct {
+	claims      oidc.Claims
+	nonce       string
+	challenge   string
+	redirectURI string
+}
+
+// NewServer starts an identity provider for a single client.
+func NewServer(clientID, clientSecret string) (*Server, error) {
+	key, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		return nil, err
+	}
+	s := &Server{
+		ClientID:     clientID,
+		ClientSecret: clientSecret,
+		key:          key,
+		codes:        make(map[string]*grant),
+	}
+	mux := http.NewServeMux()
+	mux.HandleFunc("/.well-known/openid-configuration", s.discovery)
+	mux.HandleFunc("/authorize", s.authorize)
+	mux.HandleFunc("/token", s.token)
+	mux.HandleFunc("/jwks", s.jwks)
+	s.server = httptest.NewServer(mux)
+	s.Issuer = s.server.URL
+	return s, nil
+}
+
+// Close shuts down the server.
+func (s *Server) Close() {
+	s.server.Close()
+}
+
+// SetUser sets the user who is logged in to the identity provider. The
+// issuer claim is set by the server.
+func (s *Server) SetUser(c oidc.Claims) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	c.Issuer = s.Issuer
+	s.user = c
+}
+
+func (s *Server) discovery(w http.ResponseWriter, req *http.Request) {
+	writeJSON(w, http.StatusOK, map[string]string{
+		"issuer":                 s.Issuer,
+		"authorization_endpoint": s.Issuer + "/authorize",
+		"token_endpoint":         s.Issuer + "/token",
+		"jwks_uri":               s.Issuer + "/jwks",
+	})
+}
+
+func (s *Server) authorize(w http.ResponseWriter, req *http.Request) {
+	q := req.URL.Query()
+	redirectURI, err := url.Parse(q.Get("redirect_uri"))
+	if err != nil || q.Get("client_id") != s.ClientID {
+		http.Error(w, "invalid client", http.StatusBadRequest)
+		return
+	}
+	if q.Get("response_type") != "code" || q.Get("code_challenge_method") != "S256" || q.Get("code_challenge") == "" {
+		http.Error(w, "invalid request", http.StatusBadRequest)
+		return
+	}
+	code := oidc.NewState()
+	s.mu.Lock()
+	s.codes[code] = &grant{
+		claims:      s.user,
+		nonce:       q.Get("nonce"),
+		challenge:   q.Get("code_challenge"),
+		redirectURI: redirectURI.String(),
+	}
+	s.mu.Unlock()
+	params := redirectURI.Query()
+	params.Set("code", code)
+	params.Set("state", q.Get("state"))
+	redirectURI.RawQuery = params.Encode()
+	http.Redirect(w, req, redirectURI.String(), http.StatusFound)
+}
+
+func (s *Server) token(w http.ResponseWriter, req *http.Request) {
+	if err := req.ParseForm(); err != nil {
+		tokenError(w, "invalid_request")
+		return
+	}
+	id, secret, ok := req.BasicAuth()
+	if ok {
+		id, _ = url.QueryUnescape(id)
+		secret, _ = url.QueryUnescape(secret)
+	} else {
+		id, secret = req.PostForm.Get("client_id"), req.PostForm.Get("client_secret")
+	}
+	if id != s.ClientID || secret != s.ClientSecret {
+		tokenError(w, "invalid_client")
+		return
+	}
+	if req.PostForm.Get("grant_type") != "authorization_code" {
+		tokenError(w, "unsupported_grant_type")
+		return
+	}
+	s.mu.Lock()
+	g := s.codes[req.PostForm.Get("code")]
+	delete(s.codes, req.PostForm.Get("code"))
+	s.mu.Unlock()
+	if g == nil || g.redirectURI != req.PostForm.Get("redirect_uri") ||
+		oidc.Challenge(req.PostForm.Get("code_verifier")) != g.challenge {
+		tokenError(w, "invalid_grant")
+		return
+	}
+	idToken, err := s.sign(g)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	writeJSON(w, http.StatusOK, map[string]interface{}{
+		"access_token": oidc.NewState(),
+		"token_type":   "Bearer",
+		"expires_in":   3600,
+		"id_token":     idToken,
+	})
+}
+
+func (s *Server) jwks(w http.ResponseWriter, req *http.Request) {
+	pub := s.key.PublicKey
+	writeJSON(w, http.StatusOK, map[string]interface{}{
+		"keys": []map[string]string{{
+			"kty": "RSA",
+			"kid": keyID,
+			"use": "sig",
+			"alg": "RS256",
+			"n":   base64.RawURLEncoding.EncodeToString(pub.N.Bytes()),
+			"e":   base64.RawURLEncoding.EncodeToString(big.NewInt(int64(pub.E)).Bytes()),
+		}},
+	})
+}
+
+// sign issues an RS256 ID token for a grant.
+func (s *Server) sign(g *grant) (string, error) {
+	now := time.Now()
+	header, err := json.Marshal(map[string]string{"alg": "RS256", "typ": "JWT", "kid": keyID})
+	if err != nil {
+		return "", err
+	}
+	claims := map[string]interface{}{
+		"iss":            s.Issuer,
+		"sub":            g.claims.Subject,
+		"aud":            s.ClientID,
+		"iat":            now.Unix(),
+		"exp":            now.Add(time.Hour).Unix(),
+		"nonce":          g.nonce,
+		"email":          g.claims.Email,
+		"email_verified": g.claims.EmailVerified,
+		"name":           g.claims.Name,
+	}
+	if g.claims.PreferredUsername != "" {
+		claims["preferred_username"] = g.claims.PreferredUsername
+	}
+	payload, err := json.Marshal(claims)
+	if err != nil {
+		return "", err
+	}
+	signed := base64.RawURLEncoding.EncodeToString(header) + "." + base64.RawURLEncoding.EncodeToString(payload)
+	digest := sha256.Sum256([]byte(signed))
+	sig, err := rsa.SignPKCS1v15(rand.Reader, s.key, crypto.SHA256, digest[:])
+	if err != nil {
+		return "", err
+	}
+	return signed + "." + base64.RawURLEncoding.EncodeToString(sig), nil
+}
+
+func tokenError(w http.ResponseWriter, code string) {
+	writeJSON(w, http.StatusBadRequest, map[string]string{"error": code})
+}
+
+func writeJSON(w http.ResponseWriter, status int, v interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	_ = json.NewEncoder(w).Encode(v)
+}
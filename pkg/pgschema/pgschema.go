@@ -0,0 +1,115 @@
+// Package pgschema inspects the tables, columns, indexes and constraints of a
+// Postgres schema, so that two databases' schemas can be compared.
+package pgschema
+
+import (
+	"context"
+	"fmt"
+	"sort"
+
+	"github.com/jackc/pgx/v4"
+)
+
+// Schema is a schema's objects, each described by a line of text, e.g.
+// "column public.tasks.description text NOT NULL". Descriptions depend only on
+// the objects' definitions, so equal schemas have equal descriptions, whatever
+// order their objects were created in.
+type Schema []string
+
+// Inspect returns the schema named name, e.g. "public".
+func Inspect(ctx context.Context, conn *pgx.Conn, name string) (Schema, error) {
+	tx, err := conn.Begin(ctx)
+	if err != nil {
+		return nil, err
+	}
+	defer func() {
+		_ = tx.Rollback(ctx) // only used for SET LOCAL
+	}()
+	// Expressions name objects relative to the search path, so clear it, for
+	// descriptions which don't depend on the connection's.
+	if _, err := tx.Exec(ctx, `SET LOCAL search_path TO '';`); err != nil {
+		return nil, err
+	}
+	var result Schema
+	for _, q := range []string{
+		// Tables.
+		`
+			SELECT 'table ' || n.nspname || '.' || c.relname
+			FROM pg_class c
+			JOIN pg_namespace n ON n.oid = c.relnamespace
+			WHERE n.nspname = $1 AND c.relkind IN ('r', 'p');
+		`,
+		// Columns.
+		`
+			SELECT 'column ' || n.nspname || '.' || c.relname || '.' || a.attname || ' ' ||
+				format_type(a.atttypid, a.atttypmod) ||
+				CASE WHEN a.attnotnull THEN ' NOT NULL' ELSE '' END ||
+				COALESCE(' DEFAULT ' || pg_get_expr(d.adbin, d.adrelid), '')
+			FROM pg_attribute a
+			JOIN pg_class c ON c.oid = a.attrelid
+			JOIN pg_namespace n ON n.oid = c.relnamespace
+			LEFT JOIN pg_attrdef d ON d.adrelid = a.attrelid AND d.adnum = a.attnum
+			WHERE n.nspname = $1 AND c.relkind IN ('r', 'p')
+			  AND a.attnum > 0 AND NOT a.attisdropped;
+		`,
+		// Indexes.
+		`
+			SELECT 'index ' || schemaname || '.' || indexname || ': ' || indexdef
+			FROM pg_indexes
+			WHERE schemaname = $1;
+		`,
+		// Constraints.
+		`
+			SELECT 'constraint ' || n.nspname || '.' || c.relname || '.' || k.conname || ': ' ||
+				pg_get_constraintdef(k.oid)
+			FROM pg_constraint k
+			JOIN pg_class c ON c.oid = k.conrelid
+			JOIN pg_namespace n ON n.oid = c.relnamespace
+			WHERE n.nspname = $1;
+		`,
+	} {
+		rows, err := tx.Query(ctx, q, name)
+		if err != nil {
+			return nil, err
+		}
+		for rows.Next() {
+			var line string
+			if err := rows.Scan(&line); err != nil {
+				rows.Close()
+				return nil, err
+			}
+			result = append(result, line)
+		}
+		rows.Close()
+		if err := rows.Err(); err != nil {
+			return nil, err
+		}
+	}
+	sort.Strings(result)
+	return result, nil
+}
+
+// Diff returns the differences between want and got, as lines of a diff:
+// objects only in want are prefixed with "-", and objects only in got with "+".
+// It returns nil if the schemas are equal. Both must be sorted, as Inspect
+// returns them.
+func Diff(want, got Schema) []string {
+	var (
+		result []string
+		i, j   int
+	)
+	for i < len(want) || j < len(got) {
+		switch {
+		case j == len(got) || i < len(want) && want[i] < got[j]:
+			result = append(result, fmt.Sprintf("- %s", want[i]))
+			i++
+		case i == len(want) || got[j] < want[i]:
+			result = append(result, fmt.Sprintf("+ %s", got[j]))
+			j++
+		default:
+			i++
+			j++
+		}
+	}
+	return result
+}
@@ -0,0 +1,62 @@
+package pgschema_test
+
+import (
+	"context"
+	"testing"
+
+	"github.com/deliveroo/assert-go"
+	"github.com/deliveroo/todo-api/pkg/pgschema"
+	"github.com/deliveroo/todo-api/selftest/deps/postgres"
+	"github.com/jackc/pgx/v4"
+)
+
+func TestDiff(t *testing.T) {
+	want := pgschema.Schema{"column a.id bigint", "table a", "table b"}
+	assert.Equal(t, len(pgschema.Diff(want, want)), 0)
+	assert.Equal(t, pgschema.Diff(want, pgschema.Schema{"column a.id integer", "table a", "table c"}), []string{
+		"- column a.id bigint",
+		"+ column a.id integer",
+		"- table b",
+		"+ table c",
+	})
+	assert.Equal(t, pgschema.Diff(nil, pgschema.Schema{"table a"}), []string{"+ table a"})
+	assert.Equal(t, pgschema.Diff(pgschema.Schema{"table a"}, nil), []string{"- table a"})
+}
+
+func TestInspect(t *testing.T) {
+	if testing.Short() || postgres.URL() == "" {
+		t.Skip("requires postgres")
+	}
+	ctx := context.Background()
+	conn, err := pgx.Connect(ctx, postgres.URL())
+	assert.Must(t, err)
+	defer conn.Close(ctx)
+
+	_, err = conn.Exec(ctx, `
+		DROP SCHEMA IF EXISTS pgschema_test CASCADE;
+		CREATE SCHEMA pgschema_test;
+		CREATE TABLE pgschema_test.widgets (
+			id bigserial PRIMARY KEY,
+			name text NOT NULL DEFAULT '',
+			CONSTRAINT widgets_name_check CHECK (name <> 'bad')
+		);
+		CREATE INDEX widgets_name ON pgschema_test.widgets (name);
+	`)
+	assert.Must(t, err)
+	defer func() {
+		_, err := conn.Exec(ctx, `DROP SCHEMA pgschema_test CASCADE;`)
+		assert.Must(t, err)
+	}()
+
+	schema, err := pgschema.Inspect(ctx, conn, "pgschema_test")
+	assert.Must(t, err)
+	assert.Equal(t, schema, pgschema.Schema{
+		"column pgschema_test.widgets.id bigint NOT NULL DEFAULT nextval('pgschema_test.widgets_id_seq'::regclass)",
+		"column pgschema_test.widgets.name text NOT NULL DEFAULT ''::text",
+		"constraint pgschema_test.widgets.widgets_name_check: CHECK ((name <> 'bad'::text))",
+		"constraint pgschema_test.widgets.widgets_pkey: PRIMARY KEY (id)",
+		"index pgschema_test.widgets_name: CREATE INDEX widgets_name ON pgschema_test.widgets USING btree (name)",
+		"index pgschema_test.widgets_pkey: CREATE UNIQUE INDEX widgets_pkey ON pgschema_test.widgets USING btree (id)",
+		"table pgschema_test.widgets",
+	})
+}
@@ -0,0 +1,121 @@
+// Package totp implements time-based one-time passwords (RFC 6238), as used by
+// authenticator apps for two-factor authentication.
+package totp
+
+import (
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha1" // #nosec required by RFC 6238 and authenticator apps
+	"crypto/subtle"
+	"encoding/base32"
+	"encoding/binary"
+	"fmt"
+	"net/url"
+	"strings"
+	"time"
+)
+
+const (
+	// Digits is the number of digits in a code.
+	Digits = 6
+
+	// Period is how long each code is valid for.
+	Period = 30 * time.Second
+
+	// Skew is the number of periods either side of the current one for which
+	// codes are still accepted, to allow for clock drift.
+	Skew = 1
+)
+
+var encoding = base32.StdEncoding.WithPadding(base32.NoPadding)
+
+// NewSecret returns a new random base32 encoded secret.
+func NewSecret() string {
+	b := make([]byte, 20)
+	if _, err := rand.Read(b); err != nil {
+		panic(err)
+	}
+	return encoding.EncodeToString(b)
+}
+
+// Code returns the code for secret at time t.
+func Code(secret string, t time.Time) (string, error) {
+	key, err := decodeSecret(secret)
+	if err != nil {
+		return "", err
+	}
+	return generate(key, counter(t)), nil
+}
+
+// Validate reports whether code is valid for secret at time t.
+func Validate(secret, code string, t time.Time) bool {
+	_, ok := ValidateStep(secret, code, t)
+	return ok
+}
+
+// ValidateStep reports whether code is valid for secret at time t, and returns
+// the time step, the number of periods since the Unix epoch, it's the code
+// for. A code is valid for up to 2*Skew+1 periods, so callers must reject
+// codes for steps at or before the last one they accepted to stop codes being
+// replayed (RFC 6238, section 5.2).
+func ValidateStep(secret, code string, t time.Time) (int64, bool) {
+	key, err := decodeSecret(secret)
+	if err != nil || len(code) != Digits {
+		return 0, false
+	}
+	var (
+		step int64
+		ok   bool
+	)
+	c := int64(counter(t))
+	for i := int64(-Skew); i <= Skew; i++ {
+		want := generate(key, uint64(c+i))
+		if subtle.ConstantTimeCompare([]byte(want), []byte(code)) == 1 {
+			step, ok = c+i, true
+		}
+	}
+	return step, ok
+}
+
+// URI returns an otpauth:// key URI for secret, which authenticator apps can
+// import (typically by scanning it as a QR code).
+func URI(issuer, accountName, secret string) string {
+	v := url.Values{}
+	v.Set("secret", secret)
+	v.Set("issuer", issuer)
+	v.Set("algorithm", "SHA1")
+	v.Set("digits", fmt.Sprint(Digits))
+	v.Set("period", fmt.Sprint(int(Period.Seconds())))
+	u := url.URL{
+		Scheme:   "otpauth",
+		Host:     "totp",
+		Path:     "/" + issuer + ":" + accountName,
+		RawQuery: v.Encode(),
+	}
+	return u.String()
+}
+
+func decodeSecret(secret string) ([]byte, error) {
+	secret = strings.ToUpper(strings.ReplaceAll(secret, " ", ""))
+	return encoding.DecodeString(strings.TrimRight(secret, "="))
+}
+
+func counter(t time.Time) uint64 {
+	return uint64(t.Unix() / int64(Period.Seconds()))
+}
+
+// generate implements HOTP (RFC 4226).
+func generate(key []byte, c uint64) string {
+	var msg [8]byte
+	binary.BigEndian.PutUint64(msg[:], c)
+	h := hmac.New(sha1.New, key)
+	h.Write(msg[:])
+	sum := h.Sum(nil)
+	offset := sum[len(sum)-1] & 0x0f
+	v := binary.BigEndian.Uint32(sum[offset:offset+4]) & 0x7fffffff
+	mod := uint32(1)
+	for i := 0; i < Digits; i++ {
+		mod *= 10
+	}
+	return fmt.Sprintf("%0*d", Digits, v%mod)
+}
@@ -0,0 +1,63 @@
+package totp_test
+
+import (
+	"encoding/base32"
+	"net/url"
+	"testing"
+	"time"
+
+	"github.com/deliveroo/assert-go"
+	"github.com/deliveroo/todo-api/pkg/totp"
+)
+
+// rfcSecret is the SHA1 test seed from RFC 6238, appendix B.
+var rfcSecret = base32.StdEncoding.EncodeToString([]byte("12345678901234567890"))
+
+func TestCode(t *testing.T) {
+	for _, tt := range []struct {
+		unix int64
+		want string
+	}{
+		{59, "287082"},
+		{1111111109, "081804"},
+		{1111111111, "050471"},
+		{1234567890, "005924"},
+		{2000000000, "279037"},
+		{20000000000, "353130"},
+	} {
+		got, err := totp.Code(rfcSecret, time.Unix(tt.unix, 0))
+		assert.Must(t, err)
+		assert.Equal(t, got, tt.want)
+	}
+}
+
+func TestValidate(t *testing.T) {
+	var (
+		secret = totp.NewSecret()
+		now    = time.Now()
+	)
+	code, err := totp.Code(secret, now)
+	assert.Must(t, err)
+	assert.True(t, totp.Validate(secret, code, now))
+	assert.True(t, totp.Validate(secret, code, now.Add(totp.Period)))
+	assert.False(t, totp.Validate(secret, code, now.Add(3*totp.Period)))
+	assert.False(t, totp.Validate(secret, "", now))
+	assert.False(t, totp.Validate("not base32!", code, now))
+
+	step, ok := totp.ValidateStep(secret, code, now)
+	assert.True(t, ok)
+	assert.Equal(t, step, now.Unix()/int64(totp.Period.Seconds()))
+	later, ok := totp.ValidateStep(secret, code, now.Add(totp.Period))
+	assert.True(t, ok)
+	assert.Equal(t, later, step)
+}
+
+func TestURI(t *testing.T) {
+	u, err := url.Parse(totp.URI("todo-api", "alice", "SECRET"))
+	assert.Must(t, err)
+	assert.Equal(t, u.Scheme, "otpauth")
+	assert.Equal(t, u.Host, "totp")
+	assert.Equal(t, u.Path, "/todo-api:alice")
+	assert.Equal(t, u.Query().Get("secret"), "SECRET")
+	assert.Equal(t, u.Query().Get("issuer"), "todo-api")
+}
@@ -0,0 +1,323 @@
+// Package websocket implements the server side of the WebSocket protocol (RFC
+// 6455), for exchanging text messages with clients.
+package websocket
+
+import (
+	"bufio"
+	"crypto/sha1" // #nosec required by RFC 6455 for the handshake
+	"encoding/base64"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+	"unicode/utf8"
+)
+
+// Close status codes, sent when either side closes the connection.
+const (
+	CloseNormal          = 1000
+	CloseGoingAway       = 1001
+	CloseProtocolError   = 1002
+	CloseUnsupportedData = 1003
+	CloseInvalidPayload  = 1007
+	CloseMessageTooBig   = 1009
+	CloseInternalError   = 1011
+)
+
+const (
+	opContinuation = 0x0
+	opText         = 0x1
+	opBinary       = 0x2
+	opClose        = 0x8
+	opPing         = 0x9
+	opPong         = 0xa
+)
+
+// acceptGUID is combined with the client's key to accept a handshake.
+const acceptGUID = "258EAFA5-E914-47DA-95CA-C5AB0DC85B11"
+
+// writeTimeout is how long a client has to accept each frame written to it.
+const writeTimeout = 10 * time.Second
+
+// DefaultMaxMessageSize is the default limit on the size of messages read
+// from clients.
+const DefaultMaxMessageSize = 64 << 10
+
+var (
+	// ErrBadHandshake is returned by Upgrade if the request isn't a valid
+	// WebSocket handshake.
+	ErrBadHandshake = errors.New("websocket: bad handshake")
+
+	// ErrClosed is returned when reading from or writing to a closed
+	// connection.
+	ErrClosed = errors.New("websocket: connection closed")
+)
+
+// CloseError is returned by ReadMessage when the client closes the
+// connection, or breaks the protocol.
+type CloseError struct {
+	Code   int
+	Reason string
+}
+
+func (e *CloseError) Error() string {
+	return fmt.Sprintf("websocket: closed with status %d %s", e.Code, e.Reason)
+}
+
+// Conn is a WebSocket connection. ReadMessage must only be called by one
+// goroutine at a time; the other methods are safe for concurrent use.
+type Conn struct {
+	// MaxMessageSize is the limit on the size of messages read from the
+	// client, beyond which the connection is closed.
+	MaxMessageSize int
+
+	conn net.Conn
+	br   *bufio.Reader
+
+	mu     sync.Mutex // serialises writes
+	closed bool
+}
+
+// Upgrade upgrades an HTTP request to a WebSocket connection. If the request
+// isn't a valid handshake, it returns an error wrapping ErrBadHandshake, and
+// the caller should respond with an error.
+func Upgrade(w http.ResponseWriter, req *http.Request) (*Conn, error) {
+	if req.Method != http.MethodGet {
+		return nil, fmt.Errorf("%w: method must be GET", ErrBadHandshake)
+	}
+	if !headerContains(req.Header, "Connection", "upgrade") || !headerContains(req.Header, "Upgrade", "websocket") {
+		return nil, fmt.Errorf("%w: not a websocket upgrade", ErrBadHandshake)
+	}
+	if req.Header.Get("Sec-WebSocket-Version") != "13" {
+		return nil, fmt.Errorf("%w: unsupported version", ErrBadHandshake)
+	}
+	key := req.Header.Get("Sec-WebSocket-Key")
+	if b, err := base64.StdEncoding.DecodeString(key); err != nil || len(b) != 16 {
+		return nil, fmt.Errorf("%w: malformed Sec-WebSocket-Key", ErrBadHandshake)
+	}
+	h, ok := w.(http.Hijacker)
+	if !ok {
+		return nil, errors.New("websocket: response can't be hijacked")
+	}
+	conn, rw, err := h.Hijack()
+	if err != nil {
+		return nil, err
+	}
+	c := &Conn{MaxMessageSize: DefaultMaxMessageSize, conn: conn, br: rw.Reader}
+	resp := "HTTP/1.1 101 Switching Protocols\r\n" +
+		"Upgrade: websocket\r\n" +
+		"Connection: Upgrade\r\n" +
+		"Sec-WebSocket-Accept: " + acceptKey(key) + "\r\n\r\n"
+	if err := conn.SetWriteDeadline(time.Now().Add(writeTimeout)); err != nil {
+		_ = conn.Close()
+		return nil, err
+	}
+	if _, err := io.WriteString(conn, resp); err != nil {
+		_ = conn.Close()
+		return nil, err
+	}
+	return c, nil
+}
+
+// acceptKey returns the Sec-WebSocket-Accept header for a client's key.
+func acceptKey(key string) string {
+	h := sha1.New() // #nosec required by RFC 6455
+	_, _ = io.WriteString(h, key+acceptGUID)
+	return base64.StdEncoding.EncodeToString(h.Sum(nil))
+}
+
+// headerContains reports whether a comma separated header contains a token,
+// ignoring case.
+func headerContains(h http.Header, name, token string) bool {
+	for _, v := range h[http.CanonicalHeaderKey(name)] {
+		for _, t := range strings.Split(v, ",") {
+			if strings.EqualFold(strings.TrimSpace(t), token) {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// ReadMessage reads the next text message from the client, answering pings
+// while it waits. It returns a *CloseError once the client closes the
+// connection, or if it breaks the protocol, in which case the connection is
+// closed.
+func (c *Conn) ReadMessage() ([]byte, error) {
+	var (
+		msg     []byte
+		reading bool
+	)
+	for {
+		fin, op, payload, err := c.readFrame()
+		if err != nil {
+			if ce, ok := err.(*CloseError); ok {
+				_ = c.Close(ce.Code, ce.Reason)
+			}
+			return nil, err
+		}
+		switch op {
+		case opClose:
+			code := CloseNormal
+			if len(payload) >= 2 {
+				code = int(binary.BigEndian.Uint16(payload))
+			}
+			_ = c.Close(code, "")
+			return nil, &CloseError{Code: code, Reason: string(payload[min(len(payload), 2):])}
+		case opPing:
+			if err := c.writeFrame(opPong, payload); err != nil {
+				return nil, err
+			}
+			continue
+		case opPong:
+			continue
+		case opBinary:
+			return nil, c.fail(CloseUnsupportedData, "binary messages aren't supported")
+		case opText:
+			if reading {
+				return nil, c.fail(CloseProtocolError, "expected continuation frame")
+			}
+			reading = true
+		case opContinuation:
+			if !reading {
+				return nil, c.fail(CloseProtocolError, "unexpected continuation frame")
+			}
+		default:
+			return nil, c.fail(CloseProtocolError, "unknown opcode")
+		}
+		if len(msg)+len(payload) > c.MaxMessageSize {
+			return nil, c.fail(CloseMessageTooBig, "message too big")
+		}
+		msg = append(msg, payload...)
+		if fin {
+			if !utf8.Valid(msg) {
+				return nil, c.fail(CloseInvalidPayload, "message isn't valid UTF-8")
+			}
+			return msg, nil
+		}
+	}
+}
+
+// fail closes the connection because the client broke the protocol.
+func (c *Conn) fail(code int, reason string) error {
+	_ = c.Close(code, reason)
+	return &CloseError{Code: code, Reason: reason}
+}
+
+// readFrame reads a frame from the client, unmasking its payload.
+func (c *Conn) readFrame() (fin bool, op byte, payload []byte, err error) {
+	var head [2]byte
+	if _, err := io.ReadFull(c.br, head[:]); err != nil {
+		return false, 0, nil, err
+	}
+	fin, op = head[0]&0x80 != 0, head[0]&0x0f
+	if head[0]&0x70 != 0 {
+		return false, 0, nil, &CloseError{Code: CloseProtocolError, Reason: "reserved bits set"}
+	}
+	if head[1]&0x80 == 0 {
+		return false, 0, nil, &CloseError{Code: CloseProtocolError, Reason: "client frames must be masked"}
+	}
+	n := uint64(head[1] & 0x7f)
+	switch n {
+	case 126:
+		var b [2]byte
+		if _, err := io.ReadFull(c.br, b[:]); err != nil {
+			return false, 0, nil, err
+		}
+		n = uint64(binary.BigEndian.Uint16(b[:]))
+	case 127:
+		var b [8]byte
+		if _, err := io.ReadFull(c.br, b[:]); err != nil {
+			return false, 0, nil, err
+		}
+		n = binary.BigEndian.Uint64(b[:])
+	}
+	if op >= opClose && (!fin || n > 125) {
+		return false, 0, nil, &CloseError{Code: CloseProtocolError, Reason: "malformed control frame"}
+	}
+	if n > uint64(c.MaxMessageSize) {
+		return false, 0, nil, &CloseError{Code: CloseMessageTooBig, Reason: "message too big"}
+	}
+	var mask [4]byte
+	if _, err := io.ReadFull(c.br, mask[:]); err != nil {
+		return false, 0, nil, err
+	}
+	payload = make([]byte, n)
+	if _, err := io.ReadFull(c.br, payload); err != nil {
+		return false, 0, nil, err
+	}
+	for i := range payload {
+		payload[i] ^= mask[i%4]
+	}
+	return fin, op, payload, nil
+}
+
+// WriteMessage writes a text message to the client.
+func (c *Conn) WriteMessage(msg []byte) error {
+	return c.writeFrame(opText, msg)
+}
+
+// Ping pings the client, which should answer with a pong.
+func (c *Conn) Ping() error {
+	return c.writeFrame(opPing, nil)
+}
+
+func (c *Conn) writeFrame(op byte, payload []byte) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.writeFrameLocked(op, payload)
+}
+
+// writeFrameLocked writes a frame to the client. c.mu must be held.
+func (c *Conn) writeFrameLocked(op byte, payload []byte) error {
+	if c.closed {
+		return ErrClosed
+	}
+	frame := make([]byte, 0, len(payload)+10)
+	frame = append(frame, 0x80|op)
+	switch n := len(payload); {
+	case n <= 125:
+		frame = append(frame, byte(n))
+	case n <= 0xffff:
+		frame = append(frame, 126, byte(n>>8), byte(n))
+	default:
+		frame = append(frame, 127)
+		var b [8]byte
+		binary.BigEndian.PutUint64(b[:], uint64(n))
+		frame = append(frame, b[:]...)
+	}
+	frame = append(frame, payload...)
+	if err := c.conn.SetWriteDeadline(time.Now().Add(writeTimeout)); err != nil {
+		return err
+	}
+	_, err := c.conn.Write(frame)
+	return err
+}
+
+// Close sends the client a close frame with the status code and reason, and
+// closes the connection. It's a no-op if the connection is already closed.
+func (c *Conn) Close(code int, reason string) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.closed {
+		return nil
+	}
+	payload := make([]byte, 2, 2+len(reason))
+	binary.BigEndian.PutUint16(payload, uint16(code))
+	payload = append(payload, reason...)
+	_ = c.writeFrameLocked(opClose, payload) // the client may have gone away
+	c.closed = true
+	return c.conn.Close()
+}
+
+func min(a, b int) int {
+	if a < b {
+		return a
+	}
+	return b
+}
@@ -0,0 +1,84 @@
+package websocket_test
+
+import (
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/deliveroo/assert-go"
+	"github.com/deliveroo/todo-api/pkg/websocket"
+	"github.com/deliveroo/todo-api/pkg/websocket/websockettest"
+)
+
+// echoServer echoes messages back to clients, upper cased.
+func echoServer(t *testing.T) *httptest.Server {
+	return httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		conn, err := websocket.Upgrade(w, req)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		defer conn.Close(websocket.CloseNormal, "")
+		conn.MaxMessageSize = 1 << 10
+		for {
+			msg, err := conn.ReadMessage()
+			if err != nil {
+				return
+			}
+			if err := conn.WriteMessage([]byte(strings.ToUpper(string(msg)))); err != nil {
+				t.Error(err)
+				return
+			}
+		}
+	}))
+}
+
+func TestUpgrade(t *testing.T) {
+	server := echoServer(t)
+	defer server.Close()
+	url := "ws" + strings.TrimPrefix(server.URL, "http")
+
+	t.Run("bad handshake", func(t *testing.T) {
+		resp, err := http.Get(server.URL)
+		assert.Must(t, err)
+		resp.Body.Close()
+		assert.Equal(t, resp.StatusCode, http.StatusBadRequest)
+	})
+	t.Run("messages", func(t *testing.T) {
+		conn, _, err := websockettest.Dial(url, nil)
+		assert.Must(t, err)
+		defer conn.Close()
+
+		assert.Must(t, conn.WriteMessage([]byte("hello")))
+		msg, err := conn.ReadMessage(time.Second)
+		assert.Must(t, err)
+		assert.Equal(t, string(msg), "HELLO")
+
+		// Fragmented messages are reassembled, and pings answered in between.
+		assert.Must(t, conn.WriteFrame(false, 0x1, []byte("hello, ")))
+		assert.Must(t, conn.WriteFrame(true, 0x9, nil))
+		assert.Must(t, conn.WriteFrame(true, 0x0, []byte("world")))
+		msg, err = conn.ReadMessage(time.Second)
+		assert.Must(t, err)
+		assert.Equal(t, string(msg), "HELLO, WORLD")
+	})
+	t.Run("message too big", func(t *testing.T) {
+		conn, _, err := websockettest.Dial(url, nil)
+		assert.Must(t, err)
+		defer conn.Close()
+		assert.Must(t, conn.WriteMessage([]byte(strings.Repeat("x", 2<<10))))
+		_, err = conn.ReadMessage(time.Second)
+		assert.Equal(t, err, io.EOF)
+	})
+	t.Run("binary messages", func(t *testing.T) {
+		conn, _, err := websockettest.Dial(url, nil)
+		assert.Must(t, err)
+		defer conn.Close()
+		assert.Must(t, conn.WriteFrame(true, 0x2, []byte{0xff}))
+		_, err = conn.ReadMessage(time.Second)
+		assert.Equal(t, err, io.EOF)
+	})
+}
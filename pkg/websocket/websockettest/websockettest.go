@@ -0,0 +1,166 @@
+// Package websockettest provides a minimal WebSocket client for testing
+// servers.
+package websockettest
+
+import (
+	"bufio"
+	"crypto/rand"
+	"crypto/sha1" // #nosec required by RFC 6455 for the handshake
+	"encoding/base64"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"net/url"
+	"time"
+)
+
+// Conn is a client WebSocket connection.
+type Conn struct {
+	conn net.Conn
+	br   *bufio.Reader
+}
+
+// Dial opens a WebSocket connection to a ws:// URL, sending header with the
+// handshake. If the server refuses the handshake, its response is returned
+// with the error.
+func Dial(rawurl string, header http.Header) (*Conn, *http.Response, error) {
+	u, err := url.Parse(rawurl)
+	if err != nil {
+		return nil, nil, err
+	}
+	conn, err := net.Dial("tcp", u.Host)
+	if err != nil {
+		return nil, nil, err
+	}
+	key := make([]byte, 16)
+	if _, err := rand.Read(key); err != nil {
+		panic(err)
+	}
+	req, err := http.NewRequest(http.MethodGet, "http://"+u.Host+u.RequestURI(), nil)
+	if err != nil {
+		_ = conn.Close()
+		return nil, nil, err
+	}
+	for k, v := range header {
+		req.Header[k] = v
+	}
+	req.Header.Set("Connection", "Upgrade")
+	req.Header.Set("Upgrade", "websocket")
+	req.Header.Set("Sec-WebSocket-Version", "13")
+	req.Header.Set("Sec-WebSocket-Key", base64.StdEncoding.EncodeToString(key))
+	if err := req.Write(conn); err != nil {
+		_ = conn.Close()
+		return nil, nil, err
+	}
+	br := bufio.NewReader(conn)
+	resp, err := http.ReadResponse(br, req)
+	if err != nil {
+		_ = conn.Close()
+		return nil, nil, err
+	}
+	if resp.StatusCode != http.StatusSwitchingProtocols {
+		_ = conn.Close()
+		return nil, resp, fmt.Errorf("websockettest: handshake refused with status %d", resp.StatusCode)
+	}
+	h := sha1.New() // #nosec required by RFC 6455
+	_, _ = io.WriteString(h, req.Header.Get("Sec-WebSocket-Key")+"258EAFA5-E914-47DA-95CA-C5AB0DC85B11")
+	if resp.Header.Get("Sec-WebSocket-Accept") != base64.StdEncoding.EncodeToString(h.Sum(nil)) {
+		_ = conn.Close()
+		return nil, resp, errors.New("websockettest: bad Sec-WebSocket-Accept header")
+	}
+	return &Conn{conn: conn, br: br}, resp, nil
+}
+
+// WriteMessage writes a text message, masked as the protocol requires of
+// clients.
+func (c *Conn) WriteMessage(msg []byte) error {
+	return c.WriteFrame(true, 0x1, msg)
+}
+
+// ReadMessage reads the next message, answering pings. It returns io.EOF
+// once the server closes the connection.
+func (c *Conn) ReadMessage(timeout time.Duration) ([]byte, error) {
+	if err := c.conn.SetReadDeadline(time.Now().Add(timeout)); err != nil {
+		return nil, err
+	}
+	for {
+		var head [2]byte
+		if _, err := io.ReadFull(c.br, head[:]); err != nil {
+			return nil, err
+		}
+		n := uint64(head[1] & 0x7f)
+		switch n {
+		case 126:
+			var b [2]byte
+			if _, err := io.ReadFull(c.br, b[:]); err != nil {
+				return nil, err
+			}
+			n = uint64(binary.BigEndian.Uint16(b[:]))
+		case 127:
+			var b [8]byte
+			if _, err := io.ReadFull(c.br, b[:]); err != nil {
+				return nil, err
+			}
+			n = binary.BigEndian.Uint64(b[:])
+		}
+		payload := make([]byte, n)
+		if _, err := io.ReadFull(c.br, payload); err != nil {
+			return nil, err
+		}
+		switch op := head[0] & 0x0f; op {
+		case 0x1:
+			if head[0]&0x80 == 0 {
+				return nil, errors.New("websockettest: fragmented messages aren't supported")
+			}
+			return payload, nil
+		case 0x8:
+			return nil, io.EOF
+		case 0x9:
+			if err := c.WriteFrame(true, 0xa, payload); err != nil {
+				return nil, err
+			}
+		case 0xa:
+		default:
+			return nil, fmt.Errorf("websockettest: unexpected opcode %d", op)
+		}
+	}
+}
+
+// WriteFrame writes a single frame, for testing how servers handle frames
+// which WriteMessage doesn't send.
+func (c *Conn) WriteFrame(fin bool, op byte, payload []byte) error {
+	frame := []byte{op}
+	if fin {
+		frame[0] |= 0x80
+	}
+	switch n := len(payload); {
+	case n <= 125:
+		frame = append(frame, 0x80|byte(n))
+	case n <= 0xffff:
+		frame = append(frame, 0x80|126, byte(n>>8), byte(n))
+	default:
+		frame = append(frame, 0x80|127)
+		var b [8]byte
+		binary.BigEndian.PutUint64(b[:], uint64(n))
+		frame = append(frame, b[:]...)
+	}
+	var mask [4]byte
+	if _, err := rand.Read(mask[:]); err != nil {
+		panic(err)
+	}
+	frame = append(frame, mask[:]...)
+	for i, x := range payload {
+		frame = append(frame, x^mask[i%4])
+	}
+	_, err := c.conn.Write(frame)
+	return err
+}
+
+// Close closes the connection, without waiting for the server to answer.
+func (c *Conn) Close() error {
+	_ = c.WriteFrame(true, 0x8, []byte{0x03, 0xe8}) // 1000, normal closure
+	return c.conn.Close()
+}
@@ -0,0 +1,114 @@
+package repo
+
+import (
+	"context"
+	"time"
+
+	"github.com/deliveroo/todo-api/domain"
+	"github.com/jackc/pgx/v4"
+)
+
+// CreateAccessToken inserts an access token into the database.
+func (c *Client) CreateAccessToken(ctx context.Context, t *domain.AccessToken) (*domain.AccessToken, error) {
+	row := c.queryRow(ctx, `
+		INSERT INTO access_tokens (account_id, client_id, name, token_digest, scopes, expires)
+		VALUES ($1, $2, $3, $4, $5, $6)
+		RETURNING id, public_id, account_id, client_id, name, token_digest, scopes, expires, last_used, created;
+	`, t.AccountID, t.ClientID, t.Name, t.Digest, t.Scopes, t.Expires)
+	return scanAccessToken(row)
+}
+
+// GetAccessTokenByDigest fetches an access token by its digest from the
+// database, or returns nil if not found.
+func (c *Client) GetAccessTokenByDigest(ctx context.Context, digest string) (*domain.AccessToken, error) {
+	row := c.queryRow(ctx, `
+		SELECT id, public_id, account_id, client_id, name, token_digest, scopes, expires, last_used, created
+		FROM access_tokens
+		WHERE token_digest = $1;
+	`, digest)
+	t, err := scanAccessToken(row)
+	if err != nil {
+		if isErrNoRows(err) {
+			return nil, nil
+		}
+		return nil, err
+	}
+	return t, nil
+}
+
+// GetAllAccessTokensByAccountID fetches all personal access tokens by account
+// from the database. Tokens issued to OAuth clients aren't included.
+func (c *Client) GetAllAccessTokensByAccountID(ctx context.Context, accountID int64) ([]*domain.AccessToken, error) {
+	rows, err := c.query(ctx, `
+		SELECT id, public_id, account_id, client_id, name, token_digest, scopes, expires, last_used, created
+		FROM access_tokens
+		WHERE account_id = $1
+		AND client_id IS NULL
+		ORDER BY created DESC;
+	`, accountID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var result []*domain.AccessToken
+	for rows.Next() {
+		t, err := scanAccessToken(rows)
+		if err != nil {
+			return nil, err
+		}
+		result = append(result, t)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return result, nil
+}
+
+// TouchAccessToken records that an access token was used.
+func (c *Client) TouchAccessToken(ctx context.Context, id int64) error {
+	_, err := c.exec(ctx, `
+		UPDATE access_tokens
+		SET last_used = $2
+		WHERE id = $1;
+	`, id, time.Now().UTC())
+	return err
+}
+
+// DeleteAccessTokenByPublicIDAndAccountID deletes a personal access token
+// from the database. Tokens issued to OAuth clients are revoked with the
+// client's authorization instead, so they aren't deleted, and it returns
+// pgx.ErrNoRows for them.
+func (c *Client) DeleteAccessTokenByPublicIDAndAccountID(ctx context.Context, publicID string, accountID int64) error {
+	tag, err := c.exec(ctx, `
+		DELETE FROM access_tokens
+		WHERE public_id = $1
+		AND account_id = $2
+		AND client_id IS NULL;
+	`, publicID, accountID)
+	if err != nil {
+		return err
+	}
+	if tag.RowsAffected() == 0 {
+		return pgx.ErrNoRows
+	}
+	return nil
+}
+
+func scanAccessToken(row pgx.Row) (*domain.AccessToken, error) {
+	var result domain.AccessToken
+	if err := row.Scan(
+		&result.ID,
+		&result.PublicID,
+		&result.AccountID,
+		&result.ClientID,
+		&result.Name,
+		&result.Digest,
+		&result.Scopes,
+		&result.Expires,
+		&result.LastUsed,
+		&result.Created,
+	); err != nil {
+		return nil, err
+	}
+	return &result, nil
+}
@@ -0,0 +1,78 @@
+package repo_test
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/deliveroo/assert-go"
+	"github.com/deliveroo/todo-api/domain"
+	"github.com/deliveroo/todo-api/repo"
+)
+
+func TestCreateAndGetAccessToken(t *testing.T) {
+	var (
+		db        = getDB(t)
+		client    = &repo.Client{db.pool}
+		ctx       = context.Background()
+		accountID = db.createAccount("access-token-alpha")
+		expires   = time.Now().UTC().Add(time.Hour).Truncate(time.Second)
+	)
+	defer db.Close()
+
+	none, err := client.GetAccessTokenByDigest(ctx, "")
+	assert.Must(t, err)
+	assert.Nil(t, none)
+
+	token := &domain.AccessToken{
+		AccountID: accountID,
+		Name:      "ci",
+		Scopes:    []string{domain.ScopeTasksRead, domain.ScopeTasksWrite},
+		Expires:   &expires,
+	}
+	plain := token.NewToken()
+	created, err := client.CreateAccessToken(ctx, token)
+	assert.Must(t, err)
+	assert.True(t, created.ID != 0)
+	assert.True(t, domain.ValidPublicID(created.PublicID))
+	assert.Equal(t, created.Name, token.Name)
+	assert.Equal(t, created.Scopes, token.Scopes)
+	assert.Equal(t, created.Expires.Unix(), expires.Unix())
+	assert.Nil(t, created.LastUsed)
+
+	got, err := client.GetAccessTokenByDigest(ctx, domain.AccessTokenDigest(plain))
+	assert.Must(t, err)
+	assert.Equal(t, got, created)
+
+	assert.Must(t, client.TouchAccessToken(ctx, created.ID))
+	got, err = client.GetAccessTokenByDigest(ctx, created.Digest)
+	assert.Must(t, err)
+	assert.NotNil(t, got.LastUsed)
+
+	all, err := client.GetAllAccessTokensByAccountID(ctx, accountID)
+	assert.Must(t, err)
+	assert.Equal(t, len(all), 1)
+}
+
+func TestDeleteAccessToken(t *testing.T) {
+	var (
+		db        = getDB(t)
+		client    = &repo.Client{db.pool}
+		ctx       = context.Background()
+		accountID = db.createAccount("access-token-bravo")
+	)
+	defer db.Close()
+	token := &domain.AccessToken{
+		AccountID: accountID,
+		Name:      "ci",
+		Scopes:    []string{domain.ScopeTasksRead},
+	}
+	token.NewToken()
+	created, err := client.CreateAccessToken(ctx, token)
+	assert.Must(t, err)
+	assert.NotNil(t, client.DeleteAccessTokenByPublicIDAndAccountID(ctx, created.PublicID, accountID+1))
+	assert.Must(t, client.DeleteAccessTokenByPublicIDAndAccountID(ctx, created.PublicID, accountID))
+	got, err := client.GetAccessTokenByDigest(ctx, created.Digest)
+	assert.Must(t, err)
+	assert.Nil(t, got)
+}
@@ -2,73 +2,473 @@ package repo
 
 import (
 	"context"
+	"errors"
+	"time"
 
 	"github.com/deliveroo/todo-api/domain"
+	"github.com/jackc/pgx/v4"
 )
 
-// CreateAccount inserts an account into the database.
+var (
+	// ErrUsernameTaken is returned when an account's username is already in
+	// use by another account.
+	ErrUsernameTaken = errors.New("username is already taken")
+
+	// ErrEmailTaken is returned when an account's email address has already
+	// been verified by another account.
+	ErrEmailTaken = errors.New("email is already taken")
+
+	// ErrOwnsSharedOrg is returned when deleting an account which owns an
+	// organisation with other members, whose tasks would be deleted with it.
+	ErrOwnsSharedOrg = errors.New("account owns an organisation with other members")
+)
+
+// CreateAccount inserts an account into the database. It returns
+// ErrUsernameTaken if the username is in use, or ErrEmailTaken if the account
+// is verified and another account has verified the same email address.
 func (c *Client) CreateAccount(ctx context.Context, a *domain.Account) (*domain.Account, error) {
 	row := c.queryRow(ctx, `
-		INSERT INTO accounts (username, password_digest, password_salt)
-		VALUES ($1, $2, $3)
-		RETURNING id, username, password_digest, password_salt, created;
-	`, a.Username, a.PasswordDigest, a.PasswordSalt)
-	var result domain.Account
-	if err := row.Scan(
-		&result.ID,
-		&result.Username,
-		&result.PasswordDigest,
-		&result.PasswordSalt,
-		&result.Created,
-	); err != nil {
-		return nil, err
-	}
-	return &result, nil
+		WITH account AS (
+			INSERT INTO accounts (username, email, password_digest, password_salt, verified_at)
+			VALUES ($1, $2, $3, $4, $5)
+			RETURNING id, public_id, username, email, password_digest, password_salt, role, suspended_at,
+				suspension_reason, totp_secret, totp_enabled, verified_at, created
+		)`+outboxSQL(domain.AggregateAccount, domain.EventAccountCreated), a.Username, a.Email, a.PasswordDigest, a.PasswordSalt, a.VerifiedAt)
+	return checkAccountUniqueness(scanAccount(row))
 }
 
-// GetAccountByUsername fetches an account by username from the database or
-// returns nil if not found.
+// GetAccountByUsername fetches an account by username, ignoring case, from
+// the database or returns nil if not found.
 func (c *Client) GetAccountByUsername(ctx context.Context, username string) (*domain.Account, error) {
 	row := c.queryRow(ctx, `
-		SELECT id, username, password_digest, password_salt, created
+		SELECT id, public_id, username, email, password_digest, password_salt, role, suspended_at,
+			suspension_reason, totp_secret, totp_enabled, verified_at, created
 		FROM accounts
 		WHERE username = $1;
 	`, username)
-	var result domain.Account
-	if err := row.Scan(
-		&result.ID,
-		&result.Username,
-		&result.PasswordDigest,
-		&result.PasswordSalt,
-		&result.Created,
-	); err != nil {
+	a, err := scanAccount(row)
+	if err != nil {
 		if isErrNoRows(err) {
 			return nil, nil
 		}
 		return nil, err
 	}
-	return &result, nil
+	return a, nil
+}
+
+// GetAccountByEmail fetches the account which has verified an email address
+// from the database, or returns nil if not found. Email addresses are matched
+// case-insensitively.
+func (c *Client) GetAccountByEmail(ctx context.Context, email string) (*domain.Account, error) {
+	row := c.queryRow(ctx, `
+		SELECT id, public_id, username, email, password_digest, password_salt, role, suspended_at,
+			suspension_reason, totp_secret, totp_enabled, verified_at, created
+		FROM accounts
+		WHERE lower(email) = lower($1)
+		AND email <> ''
+		AND verified_at IS NOT NULL;
+	`, email)
+	a, err := scanAccount(row)
+	if err != nil {
+		if isErrNoRows(err) {
+			return nil, nil
+		}
+		return nil, err
+	}
+	return a, nil
 }
 
 // GetAccountByID fetches an account by id from the database or returns nil if
 // not found.
 func (c *Client) GetAccountByID(ctx context.Context, id int64) (*domain.Account, error) {
 	row := c.queryRow(ctx, `
-		SELECT id, username, password_digest, password_salt, created
+		SELECT id, public_id, username, email, password_digest, password_salt, role, suspended_at,
+			suspension_reason, totp_secret, totp_enabled, verified_at, created
 		FROM accounts
 		WHERE id = $1;
 	`, id)
+	a, err := scanAccount(row)
+	if err != nil {
+		if isErrNoRows(err) {
+			return nil, nil
+		}
+		return nil, err
+	}
+	return a, nil
+}
+
+// GetAccountByPublicID fetches an account by public id from the database or
+// returns nil if not found.
+func (c *Client) GetAccountByPublicID(ctx context.Context, publicID string) (*domain.Account, error) {
+	row := c.queryRow(ctx, `
+		SELECT id, public_id, username, email, password_digest, password_salt, role, suspended_at,
+			suspension_reason, totp_secret, totp_enabled, verified_at, created
+		FROM accounts
+		WHERE public_id = $1;
+	`, publicID)
+	a, err := scanAccount(row)
+	if err != nil {
+		if isErrNoRows(err) {
+			return nil, nil
+		}
+		return nil, err
+	}
+	return a, nil
+}
+
+// GetAccountsByIDs fetches the accounts with the given ids from the database,
+// ordered by id. Ids which don't match an account are ignored.
+func (c *Client) GetAccountsByIDs(ctx context.Context, ids []int64) ([]*domain.Account, error) {
+	rows, err := c.query(ctx, `
+		SELECT id, public_id, username, email, password_digest, password_salt, role, suspended_at,
+			suspension_reason, totp_secret, totp_enabled, verified_at, created
+		FROM accounts
+		WHERE id = ANY($1::bigint[])
+		ORDER BY id;
+	`, ids)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var result []*domain.Account
+	for rows.Next() {
+		a, err := scanAccount(rows)
+		if err != nil {
+			return nil, err
+		}
+		result = append(result, a)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return result, nil
+}
+
+// UpdateAccountTOTP updates an account's two-factor authentication settings in
+// the database.
+func (c *Client) UpdateAccountTOTP(ctx context.Context, a *domain.Account) (*domain.Account, error) {
+	row := c.queryRow(ctx, `
+		WITH account AS (
+			UPDATE accounts
+			SET totp_secret = $2, totp_enabled = $3
+			WHERE id = $1
+			RETURNING id, public_id, username, email, password_digest, password_salt, role, suspended_at,
+				suspension_reason, totp_secret, totp_enabled, verified_at, created
+		)`+outboxSQL(domain.AggregateAccount, domain.EventAccountUpdated), a.ID, a.TOTPSecret, a.TOTPEnabled)
+	return scanAccount(row)
+}
+
+// UseTOTPStep records that an account has used the TOTP code for a time step,
+// and reports whether the step is later than the last one it used. Codes for
+// earlier steps, or the same one, are rejected, so that a code can't be used
+// twice while it's valid.
+func (c *Client) UseTOTPStep(ctx context.Context, accountID, step int64) (bool, error) {
+	tag, err := c.exec(ctx, `
+		UPDATE accounts
+		SET totp_last_step = $2
+		WHERE id = $1
+		AND (totp_last_step IS NULL OR totp_last_step < $2);
+	`, accountID, step)
+	if err != nil {
+		return false, err
+	}
+	return tag.RowsAffected() > 0, nil
+}
+
+// UpdateAccountPassword updates an account's password digest and salt in the
+// database.
+func (c *Client) UpdateAccountPassword(ctx context.Context, a *domain.Account) (*domain.Account, error) {
+	row := c.queryRow(ctx, `
+		WITH account AS (
+			UPDATE accounts
+			SET password_digest = $2, password_salt = $3
+			WHERE id = $1
+			RETURNING id, public_id, username, email, password_digest, password_salt, role, suspended_at,
+				suspension_reason, totp_secret, totp_enabled, verified_at, created
+		)`+outboxSQL(domain.AggregateAccount, domain.EventAccountUpdated), a.ID, a.PasswordDigest, a.PasswordSalt)
+	return scanAccount(row)
+}
+
+// UpdateAccountUsername updates an account's username in the database. It
+// returns ErrUsernameTaken if the username is in use by another account.
+func (c *Client) UpdateAccountUsername(ctx context.Context, a *domain.Account) (*domain.Account, error) {
+	row := c.queryRow(ctx, `
+		WITH account AS (
+			UPDATE accounts
+			SET username = $2
+			WHERE id = $1
+			RETURNING id, public_id, username, email, password_digest, password_salt, role, suspended_at,
+				suspension_reason, totp_secret, totp_enabled, verified_at, created
+		)`+outboxSQL(domain.AggregateAccount, domain.EventAccountUpdated), a.ID, a.Username)
+	return checkAccountUniqueness(scanAccount(row))
+}
+
+// UpdateAccountRole updates an account's role in the database, or returns nil
+// if the account doesn't exist.
+func (c *Client) UpdateAccountRole(ctx context.Context, id int64, role string) (*domain.Account, error) {
+	row := c.queryRow(ctx, `
+		WITH account AS (
+			UPDATE accounts
+			SET role = $2
+			WHERE id = $1
+			RETURNING id, public_id, username, email, password_digest, password_salt, role, suspended_at,
+				suspension_reason, totp_secret, totp_enabled, verified_at, created
+		)`+outboxSQL(domain.AggregateAccount, domain.EventAccountUpdated), id, role)
+	return scanAccountOrNil(row)
+}
+
+// SuspendAccount suspends an account, recording the reason, or returns nil if
+// the account doesn't exist. Suspending an account which is already suspended
+// updates the reason, but not when it was suspended.
+func (c *Client) SuspendAccount(ctx context.Context, id int64, reason string) (*domain.Account, error) {
+	row := c.queryRow(ctx, `
+		WITH account AS (
+			UPDATE accounts
+			SET suspended_at = COALESCE(suspended_at, $3), suspension_reason = $2
+			WHERE id = $1
+			RETURNING id, public_id, username, email, password_digest, password_salt, role, suspended_at,
+				suspension_reason, totp_secret, totp_enabled, verified_at, created
+		)`+outboxSQL(domain.AggregateAccount, domain.EventAccountUpdated), id, reason, time.Now().UTC())
+	return scanAccountOrNil(row)
+}
+
+// UnsuspendAccount lifts an account's suspension, or returns nil if the
+// account doesn't exist.
+func (c *Client) UnsuspendAccount(ctx context.Context, id int64) (*domain.Account, error) {
+	row := c.queryRow(ctx, `
+		WITH account AS (
+			UPDATE accounts
+			SET suspended_at = NULL, suspension_reason = ''
+			WHERE id = $1
+			RETURNING id, public_id, username, email, password_digest, password_salt, role, suspended_at,
+				suspension_reason, totp_secret, totp_enabled, verified_at, created
+		)`+outboxSQL(domain.AggregateAccount, domain.EventAccountUpdated), id)
+	return scanAccountOrNil(row)
+}
+
+// SearchAccounts fetches accounts whose username or email address contains
+// query, ordered by id. An empty query matches every account.
+func (c *Client) SearchAccounts(ctx context.Context, query string, limit, offset int) ([]*domain.Account, error) {
+	rows, err := c.query(ctx, `
+		SELECT id, public_id, username, email, password_digest, password_salt, role, suspended_at,
+			suspension_reason, totp_secret, totp_enabled, verified_at, created
+		FROM accounts
+		WHERE strpos(lower(username), lower($1)) > 0
+		OR strpos(lower(email), lower($1)) > 0
+		ORDER BY id
+		LIMIT $2 OFFSET $3;
+	`, query, limit, offset)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var result []*domain.Account
+	for rows.Next() {
+		a, err := scanAccount(rows)
+		if err != nil {
+			return nil, err
+		}
+		result = append(result, a)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return result, nil
+}
+
+// UpdateAccountEmail updates an account's email address in the database, which
+// must be verified again. Unverified email addresses aren't unique, so callers
+// should check that no other account has verified the address first.
+func (c *Client) UpdateAccountEmail(ctx context.Context, a *domain.Account) (*domain.Account, error) {
+	row := c.queryRow(ctx, `
+		WITH account AS (
+			UPDATE accounts
+			SET email = $2, verified_at = NULL
+			WHERE id = $1
+			RETURNING id, public_id, username, email, password_digest, password_salt, role, suspended_at,
+				suspension_reason, totp_secret, totp_enabled, verified_at, created
+		)`+outboxSQL(domain.AggregateAccount, domain.EventAccountUpdated), a.ID, a.Email)
+	return scanAccount(row)
+}
+
+// DeleteAccount deletes an account and everything it owns from the database
+// in a single transaction. It returns ErrOwnsSharedOrg if the account owns an
+// organisation with other members, which must be handed to one of them first.
+func (c *Client) DeleteAccount(ctx context.Context, id int64) error {
+	tx, err := c.begin(ctx)
+	if err != nil {
+		return err
+	}
+	defer func() {
+		_ = tx.Rollback(ctx) // no-op once committed
+	}()
+	// Locking the account's organisations stops anyone joining them before
+	// they're deleted.
+	var shared bool
+	if err := tx.QueryRow(ctx, `
+		WITH owned AS (
+			SELECT id FROM orgs
+			WHERE id IN (SELECT org_id FROM org_members WHERE account_id = $1 AND role = 'owner')
+			FOR UPDATE
+		)
+		SELECT EXISTS (
+			SELECT 1 FROM org_members
+			WHERE org_id IN (SELECT id FROM owned)
+			AND account_id <> $1
+		);
+	`, id).Scan(&shared); err != nil {
+		return err
+	}
+	if shared {
+		return ErrOwnsSharedOrg
+	}
+	for _, table := range []string{
+		"access_tokens",
+		"account_identities",
+		"email_verifications",
+		"failed_logins",
+		"list_members",
+		"oauth_codes",
+		"oauth_refresh_tokens",
+		"password_resets",
+		"recovery_codes",
+	} {
+		if _, err := tx.Exec(ctx, `DELETE FROM `+table+` WHERE account_id = $1;`, id); err != nil {
+			return err
+		}
+	}
+	// Tasks in organisations belong to the organisation, so they are only
+	// deleted with organisations the account owns.
+	if err := recordTaskChanges(ctx, tx, true, `account_id = $1 AND org_id IS NULL`, id); err != nil {
+		return err
+	}
+	if _, err := tx.Exec(ctx, `
+		WITH task AS (
+			DELETE FROM tasks
+			WHERE account_id = $1
+			AND org_id IS NULL
+			RETURNING *
+		)`+outboxSQL(domain.AggregateTask, domain.EventTaskDeleted), id); err != nil {
+		return err
+	}
+	if _, err := deleteOrgs(ctx, tx, `id IN (SELECT org_id FROM org_members WHERE account_id = $1 AND role = 'owner')`, id); err != nil {
+		return err
+	}
+	// The account's tasks in other organisations are handed to their owners,
+	// since deleting the account would delete them too.
+	if err := recordTaskChanges(ctx, tx, false, `account_id = $1 AND org_id IS NOT NULL`, id); err != nil {
+		return err
+	}
+	if _, err := tx.Exec(ctx, `
+		WITH task AS (
+			UPDATE tasks
+			SET account_id = (
+				SELECT account_id FROM org_members
+				WHERE org_id = tasks.org_id
+				AND role = 'owner'
+			)
+			WHERE account_id = $1
+			AND org_id IS NOT NULL
+			RETURNING *
+		)`+outboxSQL(domain.AggregateTask, domain.EventTaskUpdated), id); err != nil {
+		return err
+	}
+	if _, err := tx.Exec(ctx, `DELETE FROM org_members WHERE account_id = $1;`, id); err != nil {
+		return err
+	}
+	if _, err := deleteOAuthClients(ctx, tx, `account_id = $1`, id); err != nil {
+		return err
+	}
+	if _, err := deleteWebhooks(ctx, tx, `account_id = $1`, id); err != nil {
+		return err
+	}
+	if _, err := deleteLists(ctx, tx, `account_id = $1`, id); err != nil {
+		return err
+	}
+	if err := recordTaskChanges(ctx, tx, false, `assignee_id = $1`, id); err != nil {
+		return err
+	}
+	if _, err := tx.Exec(ctx, `
+		WITH task AS (
+			UPDATE tasks
+			SET assignee_id = NULL
+			WHERE assignee_id = $1
+			RETURNING *
+		)`+outboxSQL(domain.AggregateTask, domain.EventTaskUpdated), id); err != nil {
+		return err
+	}
+	if _, err := tx.Exec(ctx, `DELETE FROM task_changes WHERE account_id = $1;`, id); err != nil {
+		return err
+	}
+	if _, err := tx.Exec(ctx, `
+		DELETE FROM list_invitations
+		WHERE inviter_id = $1
+		OR invitee_id = $1;
+	`, id); err != nil {
+		return err
+	}
+	if _, err := tx.Exec(ctx, `
+		DELETE FROM org_invitations
+		WHERE inviter_id = $1
+		OR invitee_id = $1;
+	`, id); err != nil {
+		return err
+	}
+	tag, err := tx.Exec(ctx, `
+		WITH account AS (
+			DELETE FROM accounts
+			WHERE id = $1
+			RETURNING *
+		)`+outboxSQL(domain.AggregateAccount, domain.EventAccountDeleted), id)
+	if err != nil {
+		return err
+	}
+	if tag.RowsAffected() == 0 {
+		return pgx.ErrNoRows
+	}
+	return tx.Commit(ctx)
+}
+
+// checkAccountUniqueness translates unique violations from an account insert
+// or update into ErrUsernameTaken or ErrEmailTaken.
+func checkAccountUniqueness(a *domain.Account, err error) (*domain.Account, error) {
+	switch {
+	case isUniqueViolation(err, "accounts_id_idx"):
+		return nil, ErrUsernameTaken
+	case isUniqueViolation(err, "accounts_verified_email_idx"):
+		return nil, ErrEmailTaken
+	}
+	return a, err
+}
+
+func scanAccountOrNil(row pgx.Row) (*domain.Account, error) {
+	a, err := scanAccount(row)
+	if err != nil {
+		if isErrNoRows(err) {
+			return nil, nil
+		}
+		return nil, err
+	}
+	return a, nil
+}
+
+func scanAccount(row pgx.Row) (*domain.Account, error) {
 	var result domain.Account
 	if err := row.Scan(
 		&result.ID,
+		&result.PublicID,
 		&result.Username,
+		&result.Email,
 		&result.PasswordDigest,
 		&result.PasswordSalt,
+		&result.Role,
+		&result.SuspendedAt,
+		&result.SuspensionReason,
+		&result.TOTPSecret,
+		&result.TOTPEnabled,
+		&result.VerifiedAt,
 		&result.Created,
 	); err != nil {
-		if isErrNoRows(err) {
-			return nil, nil
-		}
 		return nil, err
 	}
 	return &result, nil
@@ -0,0 +1,101 @@
+package repo
+
+import (
+	"context"
+	"errors"
+
+	"github.com/deliveroo/todo-api/domain"
+	"github.com/jackc/pgx/v4"
+)
+
+// ErrIdentityLinked is returned when an external identity is already linked
+// to an account.
+var ErrIdentityLinked = errors.New("identity is already linked to an account")
+
+// CreateAccountIdentity inserts an account identity into the database. It
+// returns ErrIdentityLinked if the identity is linked to an account already.
+func (c *Client) CreateAccountIdentity(ctx context.Context, i *domain.AccountIdentity) (*domain.AccountIdentity, error) {
+	row := c.queryRow(ctx, `
+		INSERT INTO account_identities (account_id, issuer, subject, email)
+		VALUES ($1, $2, $3, $4)
+		RETURNING id, public_id, account_id, issuer, subject, email, created;
+	`, i.AccountID, i.Issuer, i.Subject, i.Email)
+	result, err := scanAccountIdentity(row)
+	if isUniqueViolation(err, "account_identities_issuer_subject_idx") {
+		return nil, ErrIdentityLinked
+	}
+	return result, err
+}
+
+// GetAccountIdentity fetches an account identity by issuer and subject from
+// the database, or returns nil if not found.
+func (c *Client) GetAccountIdentity(ctx context.Context, issuer, subject string) (*domain.AccountIdentity, error) {
+	row := c.queryRow(ctx, `
+		SELECT id, public_id, account_id, issuer, subject, email, created
+		FROM account_identities
+		WHERE issuer = $1
+		AND subject = $2;
+	`, issuer, subject)
+	i, err := scanAccountIdentity(row)
+	if err != nil {
+		if isErrNoRows(err) {
+			return nil, nil
+		}
+		return nil, err
+	}
+	return i, nil
+}
+
+// UpdateAccountIdentityEmail updates the email address last given for an
+// account identity.
+func (c *Client) UpdateAccountIdentityEmail(ctx context.Context, id int64, email string) error {
+	_, err := c.exec(ctx, `
+		UPDATE account_identities
+		SET email = $2
+		WHERE id = $1;
+	`, id, email)
+	return err
+}
+
+// GetAllAccountIdentitiesByAccountID fetches all identities linked to an
+// account from the database.
+func (c *Client) GetAllAccountIdentitiesByAccountID(ctx context.Context, accountID int64) ([]*domain.AccountIdentity, error) {
+	rows, err := c.query(ctx, `
+		SELECT id, public_id, account_id, issuer, subject, email, created
+		FROM account_identities
+		WHERE account_id = $1
+		ORDER BY created;
+	`, accountID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var result []*domain.AccountIdentity
+	for rows.Next() {
+		i, err := scanAccountIdentity(rows)
+		if err != nil {
+			return nil, err
+		}
+		result = append(result, i)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return result, nil
+}
+
+func scanAccountIdentity(row pgx.Row) (*domain.AccountIdentity, error) {
+	var result domain.AccountIdentity
+	if err := row.Scan(
+		&result.ID,
+		&result.PublicID,
+		&result.AccountID,
+		&result.Issuer,
+		&result.Subject,
+		&result.Email,
+		&result.Created,
+	); err != nil {
+		return nil, err
+	}
+	return &result, nil
+}
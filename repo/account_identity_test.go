@@ -0,0 +1,61 @@
+package repo_test
+
+import (
+	"context"
+	"testing"
+
+	"github.com/deliveroo/assert-go"
+	"github.com/deliveroo/todo-api/domain"
+	"github.com/deliveroo/todo-api/repo"
+)
+
+func TestAccountIdentities(t *testing.T) {
+	var (
+		db        = getDB(t)
+		client    = &repo.Client{db.pool}
+		ctx       = context.Background()
+		accountID = db.createAccount("identity-alpha")
+		otherID   = db.createAccount("identity-bravo")
+		issuer    = "https://idp.example.com"
+	)
+	defer db.Close()
+
+	none, err := client.GetAccountIdentity(ctx, issuer, "subject")
+	assert.Must(t, err)
+	assert.Nil(t, none)
+
+	created, err := client.CreateAccountIdentity(ctx, &domain.AccountIdentity{
+		AccountID: accountID,
+		Issuer:    issuer,
+		Subject:   "subject",
+		Email:     "user@example.com",
+	})
+	assert.Must(t, err)
+	assert.True(t, created.ID != 0)
+	assert.True(t, domain.ValidPublicID(created.PublicID))
+
+	got, err := client.GetAccountIdentity(ctx, issuer, "subject")
+	assert.Must(t, err)
+	assert.Equal(t, got, created)
+
+	_, err = client.CreateAccountIdentity(ctx, &domain.AccountIdentity{
+		AccountID: otherID,
+		Issuer:    issuer,
+		Subject:   "subject",
+	})
+	assert.Equal(t, err, repo.ErrIdentityLinked)
+
+	// The same subject from another issuer is a different identity.
+	_, err = client.CreateAccountIdentity(ctx, &domain.AccountIdentity{
+		AccountID: accountID,
+		Issuer:    "https://other.example.com",
+		Subject:   "subject",
+	})
+	assert.Must(t, err)
+
+	assert.Must(t, client.UpdateAccountIdentityEmail(ctx, created.ID, "new@example.com"))
+	all, err := client.GetAllAccountIdentitiesByAccountID(ctx, accountID)
+	assert.Must(t, err)
+	assert.Equal(t, len(all), 2)
+	assert.Equal(t, all[0].Email, "new@example.com")
+}
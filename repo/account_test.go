@@ -3,6 +3,7 @@ package repo_test
 import (
 	"context"
 	"testing"
+	"time"
 
 	"github.com/deliveroo/assert-go"
 	"github.com/deliveroo/todo-api/domain"
@@ -39,3 +40,226 @@ func TestCreateAndGetAccount(t *testing.T) {
 	assert.Must(t, err)
 	assert.Equal(t, got, created)
 }
+
+func TestUpdateAccountTOTP(t *testing.T) {
+	var (
+		db     = getDB(t)
+		client = &repo.Client{db.pool}
+		ctx    = context.Background()
+		now    = time.Now().UTC()
+	)
+	defer db.Close()
+
+	account, err := client.CreateAccount(ctx, &domain.Account{
+		Username:       "totp-username",
+		PasswordDigest: "password-digest",
+		PasswordSalt:   "password-salt",
+	})
+	assert.Must(t, err)
+	assert.Equal(t, account.TOTPSecret, "")
+	assert.Nil(t, account.TOTPEnabled)
+
+	account.TOTPSecret = "secret"
+	account.TOTPEnabled = &now
+	updated, err := client.UpdateAccountTOTP(ctx, account)
+	assert.Must(t, err)
+	assert.Equal(t, updated.TOTPSecret, "secret")
+	assert.Equal(t, updated.TOTPEnabled.Truncate(time.Second), now.Truncate(time.Second))
+
+	got, err := client.GetAccountByID(ctx, account.ID)
+	assert.Must(t, err)
+	assert.Equal(t, got, updated)
+}
+
+func TestCreateAccountUsernameTaken(t *testing.T) {
+	var (
+		db     = getDB(t)
+		client = &repo.Client{db.pool}
+		ctx    = context.Background()
+	)
+	defer db.Close()
+	account := &domain.Account{
+		Username:       "taken-username",
+		PasswordDigest: "password-digest",
+		PasswordSalt:   "password-salt",
+	}
+	_, err := client.CreateAccount(ctx, account)
+	assert.Must(t, err)
+	_, err = client.CreateAccount(ctx, account)
+	assert.Equal(t, err, repo.ErrUsernameTaken)
+}
+
+func TestUpdateAccountPasswordAndUsername(t *testing.T) {
+	var (
+		db     = getDB(t)
+		client = &repo.Client{db.pool}
+		ctx    = context.Background()
+	)
+	defer db.Close()
+	newAccount := func(username string) *domain.Account {
+		account, err := client.CreateAccount(ctx, &domain.Account{
+			Username:       username,
+			PasswordDigest: "password-digest",
+			PasswordSalt:   "password-salt",
+		})
+		assert.Must(t, err)
+		return account
+	}
+	alpha := newAccount("update-alpha")
+	newAccount("update-bravo")
+
+	t.Run("password", func(t *testing.T) {
+		alpha.PasswordDigest = "new-digest"
+		alpha.PasswordSalt = "new-salt"
+		updated, err := client.UpdateAccountPassword(ctx, alpha)
+		assert.Must(t, err)
+		assert.Equal(t, updated, alpha)
+	})
+	t.Run("username", func(t *testing.T) {
+		alpha.Username = "update-charlie"
+		updated, err := client.UpdateAccountUsername(ctx, alpha)
+		assert.Must(t, err)
+		assert.Equal(t, updated.Username, "update-charlie")
+	})
+	t.Run("username taken", func(t *testing.T) {
+		alpha.Username = "update-bravo"
+		_, err := client.UpdateAccountUsername(ctx, alpha)
+		assert.Equal(t, err, repo.ErrUsernameTaken)
+	})
+}
+
+func TestDeleteAccount(t *testing.T) {
+	var (
+		db     = getDB(t)
+		client = &repo.Client{db.pool}
+		ctx    = context.Background()
+	)
+	defer db.Close()
+	account, err := client.CreateAccount(ctx, &domain.Account{
+		Username:       "delete-username",
+		PasswordDigest: "password-digest",
+		PasswordSalt:   "password-salt",
+	})
+	assert.Must(t, err)
+	_, err = client.CreateTask(ctx, &domain.Task{
+		CreatedBy:   account.ID,
+		Description: "alpha",
+	})
+	assert.Must(t, err)
+
+	assert.Must(t, client.DeleteAccount(ctx, account.ID))
+	got, err := client.GetAccountByID(ctx, account.ID)
+	assert.Must(t, err)
+	assert.Nil(t, got)
+	tasks, err := client.GetAllTasksForAccount(ctx, account.ID)
+	assert.Must(t, err)
+	assert.Equal(t, len(tasks), 0)
+
+	assert.NotNil(t, client.DeleteAccount(ctx, account.ID))
+}
+
+func TestGetAccountByEmail(t *testing.T) {
+	var (
+		db     = getDB(t)
+		client = &repo.Client{db.pool}
+		ctx    = context.Background()
+	)
+	defer db.Close()
+
+	none, err := client.GetAccountByEmail(ctx, "")
+	assert.Must(t, err)
+	assert.Nil(t, none)
+
+	account, err := client.CreateAccount(ctx, &domain.Account{
+		Username:       "email-username",
+		Email:          "Someone@Example.com",
+		PasswordDigest: "password-digest",
+		PasswordSalt:   "password-salt",
+	})
+	assert.Must(t, err)
+	assert.Equal(t, account.Email, "Someone@Example.com")
+
+	// Unverified email addresses can't be looked up, and don't conflict.
+	got, err := client.GetAccountByEmail(ctx, "someone@example.com")
+	assert.Must(t, err)
+	assert.Nil(t, got)
+	_, err = client.CreateAccount(ctx, &domain.Account{
+		Username: "unverified-email-username",
+		Email:    "someone@example.com",
+	})
+	assert.Must(t, err)
+
+	now := time.Now().UTC()
+	verified, err := client.CreateAccount(ctx, &domain.Account{
+		Username:   "verified-email-username",
+		Email:      "Someone@Example.com",
+		VerifiedAt: &now,
+	})
+	assert.Must(t, err)
+	got, err = client.GetAccountByEmail(ctx, "someone@example.com")
+	assert.Must(t, err)
+	assert.Equal(t, got.ID, verified.ID)
+
+	_, err = client.CreateAccount(ctx, &domain.Account{
+		Username:   "other-email-username",
+		Email:      "someone@example.COM",
+		VerifiedAt: &now,
+	})
+	assert.Equal(t, err, repo.ErrEmailTaken)
+
+	// Accounts without an email address don't conflict.
+	for _, username := range []string{"no-email-1", "no-email-2"} {
+		_, err := client.CreateAccount(ctx, &domain.Account{Username: username})
+		assert.Must(t, err)
+	}
+}
+
+func TestAdministerAccount(t *testing.T) {
+	var (
+		db     = getDB(t)
+		client = &repo.Client{db.pool}
+		ctx    = context.Background()
+	)
+	defer db.Close()
+
+	account, err := client.CreateAccount(ctx, &domain.Account{
+		Username: "admin-target",
+		Email:    "admin-target@example.com",
+	})
+	assert.Must(t, err)
+	assert.Equal(t, account.Role, domain.RoleUser)
+
+	t.Run("search", func(t *testing.T) {
+		found, err := client.SearchAccounts(ctx, "ADMIN-TARGET@", 10, 0)
+		assert.Must(t, err)
+		assert.Equal(t, len(found), 1)
+		assert.Equal(t, found[0].ID, account.ID)
+		none, err := client.SearchAccounts(ctx, "admin-target", 10, 1)
+		assert.Must(t, err)
+		assert.Equal(t, len(none), 0)
+	})
+	t.Run("role", func(t *testing.T) {
+		updated, err := client.UpdateAccountRole(ctx, account.ID, domain.RoleAdmin)
+		assert.Must(t, err)
+		assert.True(t, updated.IsAdmin())
+		missing, err := client.UpdateAccountRole(ctx, -1, domain.RoleAdmin)
+		assert.Must(t, err)
+		assert.Nil(t, missing)
+	})
+	t.Run("suspend", func(t *testing.T) {
+		suspended, err := client.SuspendAccount(ctx, account.ID, "spam")
+		assert.Must(t, err)
+		assert.True(t, suspended.Suspended())
+		assert.Equal(t, suspended.SuspensionReason, "spam")
+
+		again, err := client.SuspendAccount(ctx, account.ID, "more spam")
+		assert.Must(t, err)
+		assert.Equal(t, again.SuspendedAt, suspended.SuspendedAt)
+		assert.Equal(t, again.SuspensionReason, "more spam")
+
+		unsuspended, err := client.UnsuspendAccount(ctx, account.ID)
+		assert.Must(t, err)
+		assert.False(t, unsuspended.Suspended())
+		assert.Equal(t, unsuspended.SuspensionReason, "")
+	})
+}
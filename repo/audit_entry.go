@@ -0,0 +1,82 @@
+package repo
+
+import (
+	"context"
+
+	"github.com/deliveroo/todo-api/domain"
+	"github.com/jackc/pgx/v4"
+)
+
+// CreateAuditEntry inserts an audit entry into the database.
+func (c *Client) CreateAuditEntry(ctx context.Context, e *domain.AuditEntry) (*domain.AuditEntry, error) {
+	details := e.Details
+	if details == nil {
+		details = map[string]string{}
+	}
+	row := c.queryRow(ctx, `
+		WITH audit_entries AS (
+			INSERT INTO audit_entries (actor_id, action, account_id, details)
+			VALUES (NULLIF($1, 0), $2, $3, $4)
+			RETURNING *
+		)
+		SELECT `+auditEntryColumnsSQL+`;
+	`, e.ActorID, e.Action, e.AccountID, details)
+	return scanAuditEntry(row)
+}
+
+// GetRecentAuditEntries fetches the most recent audit entries from the
+// database, newest first. If accountID is non-zero, only entries for that
+// account are fetched.
+func (c *Client) GetRecentAuditEntries(ctx context.Context, accountID int64, limit int) ([]*domain.AuditEntry, error) {
+	rows, err := c.query(ctx, `
+		SELECT `+auditEntryColumnsSQL+`
+		WHERE $1 = 0 OR audit_entries.account_id = $1
+		ORDER BY audit_entries.created DESC, audit_entries.id DESC
+		LIMIT $2;
+	`, accountID, limit)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var result []*domain.AuditEntry
+	for rows.Next() {
+		e, err := scanAuditEntry(rows)
+		if err != nil {
+			return nil, err
+		}
+		result = append(result, e)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return result, nil
+}
+
+// auditEntryColumnsSQL selects the columns scanAuditEntry scans from
+// audit_entries, with the public ids of the accounts which still exist.
+const auditEntryColumnsSQL = `
+	audit_entries.id, audit_entries.public_id, COALESCE(audit_entries.actor_id, 0), COALESCE(actors.public_id::text, ''),
+		audit_entries.action, audit_entries.account_id, COALESCE(accounts.public_id::text, ''),
+		audit_entries.details, audit_entries.created
+	FROM audit_entries
+	LEFT JOIN accounts AS actors ON actors.id = audit_entries.actor_id
+	LEFT JOIN accounts ON accounts.id = audit_entries.account_id
+`
+
+func scanAuditEntry(row pgx.Row) (*domain.AuditEntry, error) {
+	var result domain.AuditEntry
+	if err := row.Scan(
+		&result.ID,
+		&result.PublicID,
+		&result.ActorID,
+		&result.ActorPublicID,
+		&result.Action,
+		&result.AccountID,
+		&result.AccountPublicID,
+		&result.Details,
+		&result.Created,
+	); err != nil {
+		return nil, err
+	}
+	return &result, nil
+}
@@ -0,0 +1,71 @@
+package repo_test
+
+import (
+	"context"
+	"testing"
+
+	"github.com/deliveroo/assert-go"
+	"github.com/deliveroo/todo-api/domain"
+	"github.com/deliveroo/todo-api/repo"
+)
+
+func TestAuditEntries(t *testing.T) {
+	var (
+		db        = getDB(t)
+		client    = &repo.Client{db.pool}
+		ctx       = context.Background()
+		accountID = int64(400)
+	)
+	defer db.Close()
+
+	created, err := client.CreateAuditEntry(ctx, &domain.AuditEntry{
+		ActorID:   1,
+		Action:    domain.AuditSuspend,
+		AccountID: accountID,
+		Details:   map[string]string{"reason": "spam"},
+	})
+	assert.Must(t, err)
+	assert.True(t, created.ID != 0)
+	assert.True(t, domain.ValidPublicID(created.PublicID))
+	assert.Equal(t, created.Details, map[string]string{"reason": "spam"})
+
+	_, err = client.CreateAuditEntry(ctx, &domain.AuditEntry{
+		Action:    domain.AuditUnsuspend,
+		AccountID: accountID,
+	})
+	assert.Must(t, err)
+
+	_, err = client.CreateAuditEntry(ctx, &domain.AuditEntry{
+		Action:    domain.AuditLogout,
+		AccountID: accountID + 1,
+	})
+	assert.Must(t, err)
+
+	entries, err := client.GetRecentAuditEntries(ctx, accountID, 10)
+	assert.Must(t, err)
+	assert.Equal(t, len(entries), 2)
+	assert.Equal(t, entries[0].Action, domain.AuditUnsuspend)
+	assert.Equal(t, entries[0].ActorID, int64(0))
+	assert.Equal(t, entries[1].Action, domain.AuditSuspend)
+
+	all, err := client.GetRecentAuditEntries(ctx, 0, 10)
+	assert.Must(t, err)
+	assert.True(t, len(all) >= 3)
+
+	// Entries carry the public ids of accounts which still exist.
+	actorID := db.createAccount("audit-actor")
+	targetID := db.createAccount("audit-target")
+	created, err = client.CreateAuditEntry(ctx, &domain.AuditEntry{
+		ActorID:   actorID,
+		Action:    domain.AuditRoleChange,
+		AccountID: targetID,
+	})
+	assert.Must(t, err)
+	actor, err := client.GetAccountByID(ctx, actorID)
+	assert.Must(t, err)
+	target, err := client.GetAccountByID(ctx, targetID)
+	assert.Must(t, err)
+	assert.Equal(t, created.ActorPublicID, actor.PublicID)
+	assert.Equal(t, created.AccountPublicID, target.PublicID)
+	assert.Equal(t, entries[0].AccountPublicID, "")
+}
@@ -0,0 +1,80 @@
+package repo_test
+
+import (
+	"context"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/deliveroo/todo-api/domain"
+	"github.com/deliveroo/todo-api/repo"
+	"github.com/deliveroo/todo-api/repo/repotest"
+)
+
+func TestConformance(t *testing.T) {
+	var (
+		db     = getDB(t)
+		client = &repo.Client{db.pool}
+	)
+	defer db.Close()
+	repotest.TestConformance(t, client, &memberships{
+		client:     client,
+		listOwners: make(map[int64]int64),
+		orgOwners:  make(map[int64]int64),
+	})
+}
+
+// memberships sets up lists and organisations in the database, adding members
+// by inviting them on behalf of the owner.
+type memberships struct {
+	client     *repo.Client
+	listOwners map[int64]int64
+	orgOwners  map[int64]int64
+}
+
+func (m *memberships) CreateList(ctx context.Context, ownerID int64) (int64, error) {
+	l, err := m.client.CreateList(ctx, &domain.List{AccountID: ownerID, Name: "conformance"})
+	if err != nil {
+		return 0, err
+	}
+	m.listOwners[l.ID] = ownerID
+	return l.ID, nil
+}
+
+func (m *memberships) AddListMember(ctx context.Context, listID, accountID int64, role string) error {
+	i, err := m.client.CreateListInvitation(ctx, &domain.ListInvitation{
+		ListID:    listID,
+		InviterID: m.listOwners[listID],
+		InviteeID: accountID,
+		Role:      role,
+	})
+	if err != nil {
+		return err
+	}
+	_, err = m.client.RespondToListInvitation(ctx, i.PublicID, accountID, true)
+	return err
+}
+
+func (m *memberships) CreateOrg(ctx context.Context, ownerID int64) (int64, error) {
+	slug := fmt.Sprintf("conformance-%d", time.Now().UnixNano())
+	o, err := m.client.CreateOrg(ctx, &domain.Org{Name: "Conformance", Slug: slug}, ownerID)
+	if err != nil {
+		return 0, err
+	}
+	m.orgOwners[o.ID] = ownerID
+	return o.ID, nil
+}
+
+func (m *memberships) AddOrgMember(ctx context.Context, orgID, accountID int64, role string) error {
+	i, err := m.client.CreateOrgInvitation(ctx, &domain.OrgInvitation{
+		OrgID:     orgID,
+		InviterID: m.orgOwners[orgID],
+		InviteeID: accountID,
+		Role:      role,
+	})
+	if err != nil {
+		return err
+	}
+	_, err = m.client.RespondToOrgInvitation(ctx, i.PublicID, accountID, true)
+	return err
+}
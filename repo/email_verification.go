@@ -0,0 +1,70 @@
+package repo
+
+import (
+	"context"
+	"time"
+
+	"github.com/deliveroo/todo-api/domain"
+	"github.com/jackc/pgx/v4"
+)
+
+// CreateEmailVerification inserts an email verification into the database.
+func (c *Client) CreateEmailVerification(ctx context.Context, v *domain.EmailVerification) (*domain.EmailVerification, error) {
+	row := c.queryRow(ctx, `
+		INSERT INTO email_verifications (account_id, email, token_digest, expires)
+		VALUES ($1, $2, $3, $4)
+		RETURNING id, account_id, email, token_digest, expires, used, created;
+	`, v.AccountID, v.Email, v.Digest, v.Expires)
+	return scanEmailVerification(row)
+}
+
+// UseEmailVerification marks an unused, unexpired email verification as used,
+// and marks its account verified, provided the account's email address hasn't
+// changed since the verification was sent. It returns the verified account, or
+// nil if no usable email verification was found, or ErrEmailTaken if another
+// account has verified the email address in the meantime.
+func (c *Client) UseEmailVerification(ctx context.Context, digest string) (*domain.Account, error) {
+	row := c.queryRow(ctx, `
+		WITH verification AS (
+			UPDATE email_verifications
+			SET used = $2
+			WHERE token_digest = $1
+			AND used IS NULL
+			AND expires > $2
+			RETURNING account_id, email
+		),
+		account AS (
+			UPDATE accounts
+			SET verified_at = $2
+			FROM verification
+			WHERE accounts.id = verification.account_id
+			AND accounts.email = verification.email
+			RETURNING accounts.id, accounts.public_id, accounts.username, accounts.email, accounts.password_digest, accounts.password_salt,
+				accounts.role, accounts.suspended_at, accounts.suspension_reason, accounts.totp_secret, accounts.totp_enabled,
+				accounts.verified_at, accounts.created
+		)`+outboxSQL(domain.AggregateAccount, domain.EventAccountUpdated), digest, time.Now().UTC())
+	a, err := checkAccountUniqueness(scanAccount(row))
+	if err != nil {
+		if isErrNoRows(err) {
+			return nil, nil
+		}
+		return nil, err
+	}
+	return a, nil
+}
+
+func scanEmailVerification(row pgx.Row) (*domain.EmailVerification, error) {
+	var result domain.EmailVerification
+	if err := row.Scan(
+		&result.ID,
+		&result.AccountID,
+		&result.Email,
+		&result.Digest,
+		&result.Expires,
+		&result.Used,
+		&result.Created,
+	); err != nil {
+		return nil, err
+	}
+	return &result, nil
+}
@@ -0,0 +1,87 @@
+package repo_test
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/deliveroo/assert-go"
+	"github.com/deliveroo/todo-api/domain"
+	"github.com/deliveroo/todo-api/repo"
+)
+
+func TestEmailVerifications(t *testing.T) {
+	var (
+		db     = getDB(t)
+		client = &repo.Client{db.pool}
+		ctx    = context.Background()
+		now    = time.Now().UTC()
+	)
+	defer db.Close()
+
+	account, err := client.CreateAccount(ctx, &domain.Account{
+		Username: "verify-username",
+		Email:    "verify@example.com",
+	})
+	assert.Must(t, err)
+	assert.Nil(t, account.VerifiedAt)
+
+	newVerification := func(email string, expires time.Time) string {
+		v := &domain.EmailVerification{
+			AccountID: account.ID,
+			Email:     email,
+			Expires:   expires,
+		}
+		token := v.NewToken()
+		_, err := client.CreateEmailVerification(ctx, v)
+		assert.Must(t, err)
+		return token
+	}
+
+	t.Run("expired", func(t *testing.T) {
+		token := newVerification(account.Email, now.Add(-time.Minute))
+		verified, err := client.UseEmailVerification(ctx, domain.EmailVerificationDigest(token))
+		assert.Must(t, err)
+		assert.Nil(t, verified)
+	})
+	t.Run("email changed", func(t *testing.T) {
+		token := newVerification("old@example.com", now.Add(time.Hour))
+		verified, err := client.UseEmailVerification(ctx, domain.EmailVerificationDigest(token))
+		assert.Must(t, err)
+		assert.Nil(t, verified)
+	})
+	t.Run("single use", func(t *testing.T) {
+		token := newVerification(account.Email, now.Add(time.Hour))
+		verified, err := client.UseEmailVerification(ctx, domain.EmailVerificationDigest(token))
+		assert.Must(t, err)
+		assert.Equal(t, verified.ID, account.ID)
+		assert.True(t, verified.Verified())
+		again, err := client.UseEmailVerification(ctx, domain.EmailVerificationDigest(token))
+		assert.Must(t, err)
+		assert.Nil(t, again)
+	})
+	t.Run("verified by another account", func(t *testing.T) {
+		other, err := client.CreateAccount(ctx, &domain.Account{
+			Username: "verify-other-username",
+			Email:    "VERIFY@example.com",
+		})
+		assert.Must(t, err)
+		v := &domain.EmailVerification{
+			AccountID: other.ID,
+			Email:     other.Email,
+			Expires:   now.Add(time.Hour),
+		}
+		token := v.NewToken()
+		_, err = client.CreateEmailVerification(ctx, v)
+		assert.Must(t, err)
+		_, err = client.UseEmailVerification(ctx, domain.EmailVerificationDigest(token))
+		assert.Equal(t, err, repo.ErrEmailTaken)
+	})
+	t.Run("update email", func(t *testing.T) {
+		account.Email = "new@example.com"
+		updated, err := client.UpdateAccountEmail(ctx, account)
+		assert.Must(t, err)
+		assert.Equal(t, updated.Email, "new@example.com")
+		assert.Nil(t, updated.VerifiedAt)
+	})
+}
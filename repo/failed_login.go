@@ -0,0 +1,61 @@
+package repo
+
+import (
+	"context"
+
+	"github.com/deliveroo/todo-api/domain"
+	"github.com/jackc/pgx/v4"
+)
+
+// CreateFailedLogin inserts a failed login into the database.
+func (c *Client) CreateFailedLogin(ctx context.Context, f *domain.FailedLogin) (*domain.FailedLogin, error) {
+	row := c.queryRow(ctx, `
+		INSERT INTO failed_logins (account_id, ip_address, user_agent)
+		VALUES ($1, $2, $3)
+		RETURNING id, public_id, account_id, ip_address, user_agent, created;
+	`, f.AccountID, f.IPAddress, f.UserAgent)
+	return scanFailedLogin(row)
+}
+
+// GetRecentFailedLoginsByAccountID fetches the most recent failed logins for an
+// account from the database, newest first.
+func (c *Client) GetRecentFailedLoginsByAccountID(ctx context.Context, accountID int64, limit int) ([]*domain.FailedLogin, error) {
+	rows, err := c.query(ctx, `
+		SELECT id, public_id, account_id, ip_address, user_agent, created
+		FROM failed_logins
+		WHERE account_id = $1
+		ORDER BY created DESC, id DESC
+		LIMIT $2;
+	`, accountID, limit)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var result []*domain.FailedLogin
+	for rows.Next() {
+		f, err := scanFailedLogin(rows)
+		if err != nil {
+			return nil, err
+		}
+		result = append(result, f)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return result, nil
+}
+
+func scanFailedLogin(row pgx.Row) (*domain.FailedLogin, error) {
+	var result domain.FailedLogin
+	if err := row.Scan(
+		&result.ID,
+		&result.PublicID,
+		&result.AccountID,
+		&result.IPAddress,
+		&result.UserAgent,
+		&result.Created,
+	); err != nil {
+		return nil, err
+	}
+	return &result, nil
+}
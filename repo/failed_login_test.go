@@ -0,0 +1,38 @@
+package repo_test
+
+import (
+	"context"
+	"testing"
+
+	"github.com/deliveroo/assert-go"
+	"github.com/deliveroo/todo-api/domain"
+	"github.com/deliveroo/todo-api/repo"
+)
+
+func TestFailedLogins(t *testing.T) {
+	var (
+		db        = getDB(t)
+		client    = &repo.Client{db.pool}
+		ctx       = context.Background()
+		accountID = db.createAccount("failed-login-alpha")
+	)
+	defer db.Close()
+
+	for _, ip := range []string{"10.0.0.1", "10.0.0.2", "10.0.0.3"} {
+		created, err := client.CreateFailedLogin(ctx, &domain.FailedLogin{
+			AccountID: accountID,
+			IPAddress: ip,
+			UserAgent: "curl/7.68.0",
+		})
+		assert.Must(t, err)
+		assert.True(t, created.ID != 0)
+		assert.True(t, domain.ValidPublicID(created.PublicID))
+		assert.False(t, created.Created.IsZero())
+	}
+
+	recent, err := client.GetRecentFailedLoginsByAccountID(ctx, accountID, 2)
+	assert.Must(t, err)
+	assert.Equal(t, len(recent), 2)
+	assert.Equal(t, recent[0].IPAddress, "10.0.0.3")
+	assert.Equal(t, recent[1].IPAddress, "10.0.0.2")
+}
@@ -0,0 +1,245 @@
+package repo
+
+import (
+	"context"
+
+	"github.com/deliveroo/todo-api/domain"
+	"github.com/jackc/pgx/v4"
+)
+
+// CreateList inserts a list into the database.
+func (c *Client) CreateList(ctx context.Context, l *domain.List) (*domain.List, error) {
+	row := c.queryRow(ctx, `
+		INSERT INTO lists (account_id, name)
+		VALUES ($1, $2)
+		RETURNING id, public_id, account_id, (SELECT public_id FROM accounts WHERE id = account_id), name, created, 'owner';
+	`, l.AccountID, l.Name)
+	return scanList(row)
+}
+
+// GetListForAccount fetches a list which the account can see from the
+// database, with the account's role on it, or returns nil if not found.
+func (c *Client) GetListForAccount(ctx context.Context, listID, accountID int64) (*domain.List, error) {
+	return c.getListForAccount(ctx, `lists.id = $2`, accountID, listID)
+}
+
+// GetListByPublicIDForAccount fetches a list which the account can see by
+// public id from the database, with the account's role on it, or returns nil
+// if not found.
+func (c *Client) GetListByPublicIDForAccount(ctx context.Context, publicID string, accountID int64) (*domain.List, error) {
+	return c.getListForAccount(ctx, `lists.public_id = $2`, accountID, publicID)
+}
+
+func (c *Client) getListForAccount(ctx context.Context, where string, accountID int64, id interface{}) (*domain.List, error) {
+	row := c.queryRow(ctx, `
+		SELECT lists.id, lists.public_id, lists.account_id, owners.public_id, lists.name, lists.created, access.role
+		FROM lists
+		JOIN (`+accountListsSQL+`) AS access ON access.list_id = lists.id
+		JOIN accounts AS owners ON owners.id = lists.account_id
+		WHERE `+where+`;
+	`, accountID, id)
+	l, err := scanList(row)
+	if err != nil {
+		if isErrNoRows(err) {
+			return nil, nil
+		}
+		return nil, err
+	}
+	return l, nil
+}
+
+// GetAllListsForAccount fetches all lists the account owns or is a member of
+// from the database, with the account's role on each.
+func (c *Client) GetAllListsForAccount(ctx context.Context, accountID int64) ([]*domain.List, error) {
+	rows, err := c.query(ctx, `
+		SELECT lists.id, lists.public_id, lists.account_id, owners.public_id, lists.name, lists.created, access.role
+		FROM lists
+		JOIN (`+accountListsSQL+`) AS access ON access.list_id = lists.id
+		JOIN accounts AS owners ON owners.id = lists.account_id
+		ORDER BY lists.name, lists.id;
+	`, accountID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var result []*domain.List
+	for rows.Next() {
+		l, err := scanList(rows)
+		if err != nil {
+			return nil, err
+		}
+		result = append(result, l)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return result, nil
+}
+
+// UpdateListName renames a list in the database.
+func (c *Client) UpdateListName(ctx context.Context, l *domain.List) (*domain.List, error) {
+	row := c.queryRow(ctx, `
+		UPDATE lists
+		SET name = $2
+		WHERE id = $1
+		RETURNING id, public_id, account_id, (SELECT public_id FROM accounts WHERE id = account_id), name, created, $3::text;
+	`, l.ID, l.Name, l.Role)
+	return scanList(row)
+}
+
+// DeleteList deletes a list, its tasks, members and invitations from the
+// database in a single transaction.
+func (c *Client) DeleteList(ctx context.Context, id int64) error {
+	tx, err := c.begin(ctx)
+	if err != nil {
+		return err
+	}
+	defer func() {
+		_ = tx.Rollback(ctx) // no-op once committed
+	}()
+	if _, err := deleteLists(ctx, tx, `id = $1`, id); err != nil {
+		return err
+	}
+	return tx.Commit(ctx)
+}
+
+// deleteLists deletes the lists matching where, and their tasks, members and
+// invitations. It returns the number of lists deleted.
+func deleteLists(ctx context.Context, tx pgx.Tx, where string, args ...interface{}) (int64, error) {
+	tasks := `list_id IN (SELECT id FROM lists WHERE ` + where + `)`
+	if err := recordTaskChanges(ctx, tx, true, tasks, args...); err != nil {
+		return 0, err
+	}
+	if err := writeTaskOutbox(ctx, tx, domain.EventTaskDeleted, tasks, args...); err != nil {
+		return 0, err
+	}
+	for _, table := range []string{
+		"list_invitations",
+		"list_members",
+		"tasks",
+	} {
+		if _, err := tx.Exec(ctx, `
+			DELETE FROM `+table+`
+			WHERE list_id IN (SELECT id FROM lists WHERE `+where+`);
+		`, args...); err != nil {
+			return 0, err
+		}
+	}
+	tag, err := tx.Exec(ctx, `DELETE FROM lists WHERE `+where+`;`, args...)
+	if err != nil {
+		return 0, err
+	}
+	return tag.RowsAffected(), nil
+}
+
+// GetListMembers fetches the owner and members of a list from the database,
+// owner first.
+func (c *Client) GetListMembers(ctx context.Context, listID int64) ([]*domain.ListMember, error) {
+	rows, err := c.query(ctx, `
+		SELECT members.list_id, members.account_id, accounts.public_id, members.role, accounts.username, members.created
+		FROM (
+			SELECT id AS list_id, account_id, 'owner' AS role, created FROM lists WHERE id = $1
+			UNION ALL
+			SELECT list_id, account_id, role, created FROM list_members WHERE list_id = $1
+		) AS members
+		JOIN accounts ON accounts.id = members.account_id
+		ORDER BY members.role = 'owner' DESC, accounts.username;
+	`, listID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var result []*domain.ListMember
+	for rows.Next() {
+		var m domain.ListMember
+		if err := rows.Scan(
+			&m.ListID,
+			&m.AccountID,
+			&m.AccountPublicID,
+			&m.Role,
+			&m.Username,
+			&m.Created,
+		); err != nil {
+			return nil, err
+		}
+		result = append(result, &m)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return result, nil
+}
+
+// UpdateListMemberRole changes a member's role on a list. It returns
+// pgx.ErrNoRows if the account isn't a member.
+func (c *Client) UpdateListMemberRole(ctx context.Context, listID, accountID int64, role string) error {
+	tag, err := c.exec(ctx, `
+		UPDATE list_members
+		SET role = $3
+		WHERE list_id = $1
+		AND account_id = $2;
+	`, listID, accountID, role)
+	if err != nil {
+		return err
+	}
+	if tag.RowsAffected() == 0 {
+		return pgx.ErrNoRows
+	}
+	return nil
+}
+
+// DeleteListMember removes a member from a list, and unassigns them from its
+// tasks. It returns pgx.ErrNoRows if the account isn't a member.
+func (c *Client) DeleteListMember(ctx context.Context, listID, accountID int64) error {
+	tx, err := c.begin(ctx)
+	if err != nil {
+		return err
+	}
+	defer func() {
+		_ = tx.Rollback(ctx) // no-op once committed
+	}()
+	tag, err := tx.Exec(ctx, `
+		DELETE FROM list_members
+		WHERE list_id = $1
+		AND account_id = $2;
+	`, listID, accountID)
+	if err != nil {
+		return err
+	}
+	if tag.RowsAffected() == 0 {
+		return pgx.ErrNoRows
+	}
+	if err := recordTaskChanges(ctx, tx, false, `list_id = $1 AND assignee_id = $2`, listID, accountID); err != nil {
+		return err
+	}
+	if err := recordAccountTaskChanges(ctx, tx, true, accountID, `list_id = $1`, listID); err != nil {
+		return err
+	}
+	if _, err := tx.Exec(ctx, `
+		WITH task AS (
+			UPDATE tasks
+			SET assignee_id = NULL
+			WHERE list_id = $1
+			AND assignee_id = $2
+			RETURNING *
+		)`+outboxSQL(domain.AggregateTask, domain.EventTaskUpdated), listID, accountID); err != nil {
+		return err
+	}
+	return tx.Commit(ctx)
+}
+
+func scanList(row pgx.Row) (*domain.List, error) {
+	var result domain.List
+	if err := row.Scan(
+		&result.ID,
+		&result.PublicID,
+		&result.AccountID,
+		&result.AccountPublicID,
+		&result.Name,
+		&result.Created,
+		&result.Role,
+	); err != nil {
+		return nil, err
+	}
+	return &result, nil
+}
@@ -0,0 +1,201 @@
+package repo
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"github.com/deliveroo/todo-api/domain"
+	"github.com/jackc/pgx/v4"
+)
+
+// ErrInvitationPending is returned when an account already has a pending
+// invitation to a list or organisation.
+var ErrInvitationPending = errors.New("invitation is already pending")
+
+// listInvitationColumns are the columns selected for list invitations, which
+// must be joined to lists as l, and to accounts as inviter and invitee.
+const listInvitationColumns = `
+	list_invitations.id, list_invitations.public_id, list_invitations.list_id,
+	l.public_id, l.name,
+	list_invitations.inviter_id, inviter.username,
+	list_invitations.invitee_id, invitee.username,
+	list_invitations.role, list_invitations.status, list_invitations.responded,
+	list_invitations.created`
+
+const listInvitationJoins = `
+	JOIN lists AS l ON l.id = list_invitations.list_id
+	JOIN accounts AS inviter ON inviter.id = list_invitations.inviter_id
+	JOIN accounts AS invitee ON invitee.id = list_invitations.invitee_id`
+
+// CreateListInvitation inserts a list invitation into the database. It
+// returns ErrInvitationPending if the invitee has already been invited.
+func (c *Client) CreateListInvitation(ctx context.Context, i *domain.ListInvitation) (*domain.ListInvitation, error) {
+	var id int64
+	err := c.queryRow(ctx, `
+		INSERT INTO list_invitations (list_id, inviter_id, invitee_id, role)
+		VALUES ($1, $2, $3, $4)
+		RETURNING id;
+	`, i.ListID, i.InviterID, i.InviteeID, i.Role).Scan(&id)
+	if isUniqueViolation(err, "list_invitations_pending_idx") {
+		return nil, ErrInvitationPending
+	}
+	if err != nil {
+		return nil, err
+	}
+	return c.GetListInvitationByID(ctx, id)
+}
+
+// GetListInvitationByID fetches a list invitation from the database, or
+// returns nil if not found.
+func (c *Client) GetListInvitationByID(ctx context.Context, id int64) (*domain.ListInvitation, error) {
+	row := c.queryRow(ctx, `
+		SELECT `+listInvitationColumns+`
+		FROM list_invitations`+listInvitationJoins+`
+		WHERE list_invitations.id = $1;
+	`, id)
+	i, err := scanListInvitation(row)
+	if err != nil {
+		if isErrNoRows(err) {
+			return nil, nil
+		}
+		return nil, err
+	}
+	return i, nil
+}
+
+// GetPendingListInvitationsByListID fetches the pending invitations to a list
+// from the database.
+func (c *Client) GetPendingListInvitationsByListID(ctx context.Context, listID int64) ([]*domain.ListInvitation, error) {
+	return c.queryListInvitations(ctx, `
+		SELECT `+listInvitationColumns+`
+		FROM list_invitations`+listInvitationJoins+`
+		WHERE list_invitations.list_id = $1
+		AND list_invitations.status = 'pending'
+		ORDER BY list_invitations.created DESC, list_invitations.id DESC;
+	`, listID)
+}
+
+// GetPendingListInvitationsByInviteeID fetches an account's pending
+// invitations from the database.
+func (c *Client) GetPendingListInvitationsByInviteeID(ctx context.Context, inviteeID int64) ([]*domain.ListInvitation, error) {
+	return c.queryListInvitations(ctx, `
+		SELECT `+listInvitationColumns+`
+		FROM list_invitations`+listInvitationJoins+`
+		WHERE list_invitations.invitee_id = $1
+		AND list_invitations.status = 'pending'
+		ORDER BY list_invitations.created DESC, list_invitations.id DESC;
+	`, inviteeID)
+}
+
+// RespondToListInvitation accepts or declines a pending invitation sent to an
+// account. Accepting it makes the account a member of the list, or changes
+// its role if it's a member already. The invitation is identified by its
+// public id. It returns nil if no pending invitation was found.
+func (c *Client) RespondToListInvitation(ctx context.Context, publicID string, inviteeID int64, accept bool) (*domain.ListInvitation, error) {
+	tx, err := c.begin(ctx)
+	if err != nil {
+		return nil, err
+	}
+	defer func() {
+		_ = tx.Rollback(ctx) // no-op once committed
+	}()
+	status := domain.InvitationDeclined
+	if accept {
+		status = domain.InvitationAccepted
+	}
+	var (
+		id, listID int64
+		role       string
+	)
+	err = tx.QueryRow(ctx, `
+		UPDATE list_invitations
+		SET status = $3, responded = $4
+		WHERE public_id = $1
+		AND invitee_id = $2
+		AND status = 'pending'
+		RETURNING id, list_id, role;
+	`, publicID, inviteeID, status, time.Now().UTC()).Scan(&id, &listID, &role)
+	if err != nil {
+		if isErrNoRows(err) {
+			return nil, nil
+		}
+		return nil, err
+	}
+	if accept {
+		if _, err := tx.Exec(ctx, `
+			INSERT INTO list_members (list_id, account_id, role)
+			VALUES ($1, $2, $3)
+			ON CONFLICT (list_id, account_id) DO UPDATE SET role = EXCLUDED.role;
+		`, listID, inviteeID, role); err != nil {
+			return nil, err
+		}
+		if err := recordAccountTaskChanges(ctx, tx, false, inviteeID, `list_id = $1`, listID); err != nil {
+			return nil, err
+		}
+	}
+	if err := tx.Commit(ctx); err != nil {
+		return nil, err
+	}
+	return c.GetListInvitationByID(ctx, id)
+}
+
+// DeleteListInvitationByPublicIDAndListID cancels a pending invitation to a
+// list. It returns pgx.ErrNoRows if no pending invitation was found.
+func (c *Client) DeleteListInvitationByPublicIDAndListID(ctx context.Context, publicID string, listID int64) error {
+	tag, err := c.exec(ctx, `
+		DELETE FROM list_invitations
+		WHERE public_id = $1
+		AND list_id = $2
+		AND status = 'pending';
+	`, publicID, listID)
+	if err != nil {
+		return err
+	}
+	if tag.RowsAffected() == 0 {
+		return pgx.ErrNoRows
+	}
+	return nil
+}
+
+func (c *Client) queryListInvitations(ctx context.Context, sql string, args ...interface{}) ([]*domain.ListInvitation, error) {
+	rows, err := c.query(ctx, sql, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var result []*domain.ListInvitation
+	for rows.Next() {
+		i, err := scanListInvitation(rows)
+		if err != nil {
+			return nil, err
+		}
+		result = append(result, i)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return result, nil
+}
+
+func scanListInvitation(row pgx.Row) (*domain.ListInvitation, error) {
+	var result domain.ListInvitation
+	if err := row.Scan(
+		&result.ID,
+		&result.PublicID,
+		&result.ListID,
+		&result.ListPublicID,
+		&result.ListName,
+		&result.InviterID,
+		&result.InviterUsername,
+		&result.InviteeID,
+		&result.InviteeUsername,
+		&result.Role,
+		&result.Status,
+		&result.Responded,
+		&result.Created,
+	); err != nil {
+		return nil, err
+	}
+	return &result, nil
+}
@@ -0,0 +1,162 @@
+package repo_test
+
+import (
+	"context"
+	"testing"
+
+	"github.com/deliveroo/assert-go"
+	"github.com/deliveroo/todo-api/domain"
+	"github.com/deliveroo/todo-api/repo"
+	"github.com/jackc/pgx/v4"
+)
+
+func TestListSharing(t *testing.T) {
+	var (
+		db     = getDB(t)
+		client = &repo.Client{db.pool}
+		ctx    = context.Background()
+	)
+	defer db.Close()
+
+	newAccount := func(username string) int64 {
+		a, err := client.CreateAccount(ctx, &domain.Account{
+			Username:       username,
+			PasswordDigest: "password-digest",
+			PasswordSalt:   "password-salt",
+		})
+		assert.Must(t, err)
+		return a.ID
+	}
+	var (
+		owner    = newAccount("list-owner")
+		viewer   = newAccount("list-viewer")
+		editor   = newAccount("list-editor")
+		outsider = newAccount("list-outsider")
+	)
+
+	list, err := client.CreateList(ctx, &domain.List{AccountID: owner, Name: "groceries"})
+	assert.Must(t, err)
+	assert.Equal(t, list.Role, domain.ListRoleOwner)
+
+	invite := func(invitee int64, role string) *domain.ListInvitation {
+		i, err := client.CreateListInvitation(ctx, &domain.ListInvitation{
+			ListID:    list.ID,
+			InviterID: owner,
+			InviteeID: invitee,
+			Role:      role,
+		})
+		assert.Must(t, err)
+		assert.Equal(t, i.Status, domain.InvitationPending)
+		assert.Equal(t, i.ListName, "groceries")
+		assert.Equal(t, i.ListPublicID, list.PublicID)
+		return i
+	}
+
+	t.Run("invitations", func(t *testing.T) {
+		i := invite(viewer, domain.ListRoleViewer)
+		_, err := client.CreateListInvitation(ctx, i)
+		assert.Equal(t, err, repo.ErrInvitationPending)
+
+		pending, err := client.GetPendingListInvitationsByInviteeID(ctx, viewer)
+		assert.Must(t, err)
+		assert.Equal(t, len(pending), 1)
+
+		// Only the invitee can respond.
+		none, err := client.RespondToListInvitation(ctx, i.PublicID, outsider, true)
+		assert.Must(t, err)
+		assert.Nil(t, none)
+
+		accepted, err := client.RespondToListInvitation(ctx, i.PublicID, viewer, true)
+		assert.Must(t, err)
+		assert.Equal(t, accepted.Status, domain.InvitationAccepted)
+		again, err := client.RespondToListInvitation(ctx, i.PublicID, viewer, false)
+		assert.Must(t, err)
+		assert.Nil(t, again)
+
+		declined, err := client.RespondToListInvitation(ctx, invite(outsider, domain.ListRoleEditor).PublicID, outsider, false)
+		assert.Must(t, err)
+		assert.Equal(t, declined.Status, domain.InvitationDeclined)
+
+		_, err = client.RespondToListInvitation(ctx, invite(editor, domain.ListRoleEditor).PublicID, editor, true)
+		assert.Must(t, err)
+
+		members, err := client.GetListMembers(ctx, list.ID)
+		assert.Must(t, err)
+		assert.Equal(t, len(members), 3)
+		assert.Equal(t, members[0].Role, domain.ListRoleOwner)
+	})
+	t.Run("lists", func(t *testing.T) {
+		got, err := client.GetListForAccount(ctx, list.ID, viewer)
+		assert.Must(t, err)
+		assert.Equal(t, got.Role, domain.ListRoleViewer)
+		none, err := client.GetListForAccount(ctx, list.ID, outsider)
+		assert.Must(t, err)
+		assert.Nil(t, none)
+		got, err = client.GetListByPublicIDForAccount(ctx, list.PublicID, viewer)
+		assert.Must(t, err)
+		assert.Equal(t, got.ID, list.ID)
+		none, err = client.GetListByPublicIDForAccount(ctx, list.PublicID, outsider)
+		assert.Must(t, err)
+		assert.Nil(t, none)
+		lists, err := client.GetAllListsForAccount(ctx, editor)
+		assert.Must(t, err)
+		assert.Equal(t, len(lists), 1)
+	})
+	t.Run("tasks", func(t *testing.T) {
+		task, err := client.CreateTask(ctx, &domain.Task{CreatedBy: owner, ListID: &list.ID, Description: "milk"})
+		assert.Must(t, err)
+		assert.Equal(t, *task.ListID, list.ID)
+		assert.Equal(t, *task.ListPublicID, list.PublicID)
+
+		for _, id := range []int64{owner, viewer, editor} {
+			got, err := client.GetTaskByIDForAccount(ctx, task.ID, id)
+			assert.Must(t, err)
+			assert.NotNil(t, got)
+			tasks, err := client.GetAllTasksByListIDForAccount(ctx, list.ID, id)
+			assert.Must(t, err)
+			assert.Equal(t, len(tasks), 1)
+		}
+		got, err := client.GetTaskByIDForAccount(ctx, task.ID, outsider)
+		assert.Must(t, err)
+		assert.Nil(t, got)
+
+		task.Description = "oat milk"
+		_, err = client.UpdateTaskForAccount(ctx, task, viewer)
+		assert.Equal(t, err, pgx.ErrNoRows)
+		_, err = client.UpdateTaskForAccount(ctx, task, editor)
+		assert.Must(t, err)
+
+		_, err = client.CreateTask(ctx, &domain.Task{CreatedBy: viewer, ListID: &list.ID, Description: "eggs"})
+		assert.Equal(t, err, pgx.ErrNoRows)
+		eggs, err := client.CreateTask(ctx, &domain.Task{CreatedBy: editor, ListID: &list.ID, Description: "eggs"})
+		assert.Must(t, err)
+		assert.Equal(t, eggs.AccountID, owner)
+		assert.Equal(t, eggs.CreatedBy, editor)
+
+		assert.Equal(t, client.DeleteTaskByIDForAccount(ctx, task.ID, viewer), pgx.ErrNoRows)
+		assert.Must(t, client.DeleteTaskByIDForAccount(ctx, task.ID, editor))
+	})
+	t.Run("members", func(t *testing.T) {
+		assert.Must(t, client.UpdateListMemberRole(ctx, list.ID, viewer, domain.ListRoleEditor))
+		got, err := client.GetListForAccount(ctx, list.ID, viewer)
+		assert.Must(t, err)
+		assert.Equal(t, got.Role, domain.ListRoleEditor)
+		tasks, err := client.GetAllTasksByListIDForAccount(ctx, list.ID, owner)
+		assert.Must(t, err)
+		_, err = client.UpdateTaskAssigneeForAccount(ctx, tasks[0].ID, owner, &viewer)
+		assert.Must(t, err)
+
+		// Leaving a list unassigns its tasks.
+		assert.Must(t, client.DeleteListMember(ctx, list.ID, viewer))
+		task, err := client.GetTaskByIDForAccount(ctx, tasks[0].ID, owner)
+		assert.Must(t, err)
+		assert.Nil(t, task.AssigneeID)
+		assert.Equal(t, client.DeleteListMember(ctx, list.ID, viewer), pgx.ErrNoRows)
+	})
+	t.Run("delete", func(t *testing.T) {
+		assert.Must(t, client.DeleteList(ctx, list.ID))
+		tasks, err := client.GetAllTasksForAccount(ctx, editor)
+		assert.Must(t, err)
+		assert.Equal(t, len(tasks), 0)
+	})
+}
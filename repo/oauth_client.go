@@ -0,0 +1,194 @@
+package repo
+
+import (
+	"context"
+	"time"
+
+	"github.com/deliveroo/todo-api/domain"
+	"github.com/jackc/pgx/v4"
+)
+
+// CreateOAuthClient inserts an OAuth client into the database.
+func (c *Client) CreateOAuthClient(ctx context.Context, oc *domain.OAuthClient) (*domain.OAuthClient, error) {
+	row := c.queryRow(ctx, `
+		INSERT INTO oauth_clients (account_id, client_id, name, redirect_uris, secret_digest)
+		VALUES ($1, $2, $3, $4, $5)
+		RETURNING id, account_id, client_id, name, redirect_uris, secret_digest, created;
+	`, oc.AccountID, oc.ClientID, oc.Name, oc.RedirectURIs, oc.SecretDigest)
+	return scanOAuthClient(row)
+}
+
+// GetOAuthClientByClientID fetches an OAuth client by its public identifier
+// from the database, or returns nil if not found.
+func (c *Client) GetOAuthClientByClientID(ctx context.Context, clientID string) (*domain.OAuthClient, error) {
+	row := c.queryRow(ctx, `
+		SELECT id, account_id, client_id, name, redirect_uris, secret_digest, created
+		FROM oauth_clients
+		WHERE client_id = $1;
+	`, clientID)
+	return scanOAuthClientOrNil(row)
+}
+
+// GetOAuthClientByID fetches an OAuth client by id from the database, or
+// returns nil if not found.
+func (c *Client) GetOAuthClientByID(ctx context.Context, id int64) (*domain.OAuthClient, error) {
+	row := c.queryRow(ctx, `
+		SELECT id, account_id, client_id, name, redirect_uris, secret_digest, created
+		FROM oauth_clients
+		WHERE id = $1;
+	`, id)
+	return scanOAuthClientOrNil(row)
+}
+
+// GetAllOAuthClientsByAccountID fetches the OAuth clients an account has
+// registered from the database.
+func (c *Client) GetAllOAuthClientsByAccountID(ctx context.Context, accountID int64) ([]*domain.OAuthClient, error) {
+	return c.queryOAuthClients(ctx, `
+		SELECT id, account_id, client_id, name, redirect_uris, secret_digest, created
+		FROM oauth_clients
+		WHERE account_id = $1
+		ORDER BY created DESC, id DESC;
+	`, accountID)
+}
+
+// GetAuthorizedOAuthClientsByAccountID fetches the OAuth clients which hold
+// usable tokens for an account from the database.
+func (c *Client) GetAuthorizedOAuthClientsByAccountID(ctx context.Context, accountID int64) ([]*domain.OAuthClient, error) {
+	return c.queryOAuthClients(ctx, `
+		SELECT id, account_id, client_id, name, redirect_uris, secret_digest, created
+		FROM oauth_clients
+		WHERE id IN (
+			SELECT client_id
+			FROM oauth_refresh_tokens
+			WHERE account_id = $1
+			AND revoked IS NULL
+			AND expires > $2
+			UNION
+			SELECT client_id
+			FROM access_tokens
+			WHERE account_id = $1
+			AND client_id IS NOT NULL
+			AND (expires IS NULL OR expires > $2)
+		)
+		ORDER BY name, id;
+	`, accountID, time.Now().UTC())
+}
+
+// RevokeOAuthAuthorization deletes the access tokens an OAuth client holds for
+// an account, and revokes its refresh tokens, so the account must authorize
+// the client again.
+func (c *Client) RevokeOAuthAuthorization(ctx context.Context, clientID, accountID int64) error {
+	tx, err := c.begin(ctx)
+	if err != nil {
+		return err
+	}
+	defer func() {
+		_ = tx.Rollback(ctx) // no-op once committed
+	}()
+	if _, err := tx.Exec(ctx, `
+		DELETE FROM access_tokens
+		WHERE client_id = $1
+		AND account_id = $2;
+	`, clientID, accountID); err != nil {
+		return err
+	}
+	if _, err := tx.Exec(ctx, `
+		UPDATE oauth_refresh_tokens
+		SET revoked = $3
+		WHERE client_id = $1
+		AND account_id = $2
+		AND revoked IS NULL;
+	`, clientID, accountID, time.Now().UTC()); err != nil {
+		return err
+	}
+	return tx.Commit(ctx)
+}
+
+// DeleteOAuthClientByIDAndAccountID deletes an OAuth client, and every code
+// and token issued to it, from the database.
+func (c *Client) DeleteOAuthClientByIDAndAccountID(ctx context.Context, id, accountID int64) error {
+	tx, err := c.begin(ctx)
+	if err != nil {
+		return err
+	}
+	defer func() {
+		_ = tx.Rollback(ctx) // no-op once committed
+	}()
+	n, err := deleteOAuthClients(ctx, tx, `id = $1 AND account_id = $2`, id, accountID)
+	if err != nil {
+		return err
+	}
+	if n == 0 {
+		return pgx.ErrNoRows
+	}
+	return tx.Commit(ctx)
+}
+
+// deleteOAuthClients deletes the OAuth clients matching where, and every code
+// and token issued to them. It returns the number of clients deleted.
+func deleteOAuthClients(ctx context.Context, tx pgx.Tx, where string, args ...interface{}) (int64, error) {
+	for _, table := range []string{
+		"access_tokens",
+		"oauth_codes",
+		"oauth_refresh_tokens",
+	} {
+		if _, err := tx.Exec(ctx, `
+			DELETE FROM `+table+`
+			WHERE client_id IN (SELECT id FROM oauth_clients WHERE `+where+`);
+		`, args...); err != nil {
+			return 0, err
+		}
+	}
+	tag, err := tx.Exec(ctx, `DELETE FROM oauth_clients WHERE `+where+`;`, args...)
+	if err != nil {
+		return 0, err
+	}
+	return tag.RowsAffected(), nil
+}
+
+func (c *Client) queryOAuthClients(ctx context.Context, sql string, args ...interface{}) ([]*domain.OAuthClient, error) {
+	rows, err := c.query(ctx, sql, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var result []*domain.OAuthClient
+	for rows.Next() {
+		oc, err := scanOAuthClient(rows)
+		if err != nil {
+			return nil, err
+		}
+		result = append(result, oc)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return result, nil
+}
+
+func scanOAuthClientOrNil(row pgx.Row) (*domain.OAuthClient, error) {
+	oc, err := scanOAuthClient(row)
+	if err != nil {
+		if isErrNoRows(err) {
+			return nil, nil
+		}
+		return nil, err
+	}
+	return oc, nil
+}
+
+func scanOAuthClient(row pgx.Row) (*domain.OAuthClient, error) {
+	var result domain.OAuthClient
+	if err := row.Scan(
+		&result.ID,
+		&result.AccountID,
+		&result.ClientID,
+		&result.Name,
+		&result.RedirectURIs,
+		&result.SecretDigest,
+		&result.Created,
+	); err != nil {
+		return nil, err
+	}
+	return &result, nil
+}
@@ -0,0 +1,110 @@
+package repo_test
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/deliveroo/assert-go"
+	"github.com/deliveroo/todo-api/domain"
+	"github.com/deliveroo/todo-api/repo"
+)
+
+func TestOAuthClients(t *testing.T) {
+	var (
+		db        = getDB(t)
+		client    = &repo.Client{db.pool}
+		ctx       = context.Background()
+		ownerID   = db.createAccount("oauth-client-owner")
+		accountID = db.createAccount("oauth-client-user")
+		expires   = time.Now().UTC().Add(time.Hour)
+	)
+	defer db.Close()
+
+	oc := &domain.OAuthClient{
+		AccountID:    ownerID,
+		Name:         "Calendar",
+		RedirectURIs: []string{"https://calendar.example.com/callback"},
+	}
+	oc.NewClientID()
+	secret := oc.NewSecret()
+	created, err := client.CreateOAuthClient(ctx, oc)
+	assert.Must(t, err)
+	assert.True(t, created.ID != 0)
+	assert.Equal(t, created.RedirectURIs, oc.RedirectURIs)
+	assert.True(t, created.AuthenticateSecret(secret))
+
+	got, err := client.GetOAuthClientByClientID(ctx, oc.ClientID)
+	assert.Must(t, err)
+	assert.Equal(t, got, created)
+	got, err = client.GetOAuthClientByID(ctx, created.ID)
+	assert.Must(t, err)
+	assert.Equal(t, got, created)
+	none, err := client.GetOAuthClientByClientID(ctx, "unknown")
+	assert.Must(t, err)
+	assert.Nil(t, none)
+
+	owned, err := client.GetAllOAuthClientsByAccountID(ctx, ownerID)
+	assert.Must(t, err)
+	assert.Equal(t, len(owned), 1)
+
+	issue := func() *domain.AccessToken {
+		at := &domain.AccessToken{
+			AccountID: accountID,
+			ClientID:  &created.ID,
+			Name:      created.Name,
+			Scopes:    []string{domain.ScopeTasksRead},
+			Expires:   &expires,
+		}
+		at.NewToken()
+		at, err := client.CreateAccessToken(ctx, at)
+		assert.Must(t, err)
+		_, err = client.CreateOAuthRefreshToken(ctx, &domain.OAuthRefreshToken{
+			AccountID: accountID,
+			ClientID:  created.ID,
+			Digest:    at.Digest + "-refresh",
+			Scopes:    at.Scopes,
+			Expires:   expires,
+		})
+		assert.Must(t, err)
+		return at
+	}
+
+	t.Run("authorizations", func(t *testing.T) {
+		at := issue()
+		authorized, err := client.GetAuthorizedOAuthClientsByAccountID(ctx, accountID)
+		assert.Must(t, err)
+		assert.Equal(t, len(authorized), 1)
+
+		// OAuth access tokens aren't listed with personal access tokens.
+		personal, err := client.GetAllAccessTokensByAccountID(ctx, accountID)
+		assert.Must(t, err)
+		assert.Equal(t, len(personal), 0)
+
+		// Nor can they be deleted like personal access tokens.
+		assert.NotNil(t, client.DeleteAccessTokenByPublicIDAndAccountID(ctx, at.PublicID, accountID))
+		kept, err := client.GetAccessTokenByDigest(ctx, at.Digest)
+		assert.Must(t, err)
+		assert.NotNil(t, kept)
+
+		assert.Must(t, client.RevokeOAuthAuthorization(ctx, created.ID, accountID))
+		authorized, err = client.GetAuthorizedOAuthClientsByAccountID(ctx, accountID)
+		assert.Must(t, err)
+		assert.Equal(t, len(authorized), 0)
+		gone, err := client.GetAccessTokenByDigest(ctx, at.Digest)
+		assert.Must(t, err)
+		assert.Nil(t, gone)
+	})
+	t.Run("delete", func(t *testing.T) {
+		at := issue()
+		err := client.DeleteOAuthClientByIDAndAccountID(ctx, created.ID, accountID)
+		assert.NotNil(t, err)
+		assert.Must(t, client.DeleteOAuthClientByIDAndAccountID(ctx, created.ID, ownerID))
+		gone, err := client.GetAccessTokenByDigest(ctx, at.Digest)
+		assert.Must(t, err)
+		assert.Nil(t, gone)
+		none, err := client.GetOAuthClientByID(ctx, created.ID)
+		assert.Must(t, err)
+		assert.Nil(t, none)
+	})
+}
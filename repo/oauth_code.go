@@ -0,0 +1,60 @@
+package repo
+
+import (
+	"context"
+	"time"
+
+	"github.com/deliveroo/todo-api/domain"
+	"github.com/jackc/pgx/v4"
+)
+
+// CreateOAuthCode inserts an authorization code into the database.
+func (c *Client) CreateOAuthCode(ctx context.Context, code *domain.OAuthCode) (*domain.OAuthCode, error) {
+	row := c.queryRow(ctx, `
+		INSERT INTO oauth_codes (account_id, client_id, code_digest, code_challenge, redirect_uri, scopes, expires)
+		VALUES ($1, $2, $3, $4, $5, $6, $7)
+		RETURNING id, account_id, client_id, code_digest, code_challenge, redirect_uri, scopes, expires, used, created;
+	`, code.AccountID, code.ClientID, code.Digest, code.Challenge, code.RedirectURI, code.Scopes, code.Expires)
+	return scanOAuthCode(row)
+}
+
+// UseOAuthCode marks an unused, unexpired authorization code issued to a
+// client as used, and returns it. It returns nil if no usable code was found.
+func (c *Client) UseOAuthCode(ctx context.Context, digest string, clientID int64) (*domain.OAuthCode, error) {
+	row := c.queryRow(ctx, `
+		UPDATE oauth_codes
+		SET used = $3
+		WHERE code_digest = $1
+		AND client_id = $2
+		AND used IS NULL
+		AND expires > $3
+		RETURNING id, account_id, client_id, code_digest, code_challenge, redirect_uri, scopes, expires, used, created;
+	`, digest, clientID, time.Now().UTC())
+	code, err := scanOAuthCode(row)
+	if err != nil {
+		if isErrNoRows(err) {
+			return nil, nil
+		}
+		return nil, err
+	}
+	return code, nil
+}
+
+func scanOAuthCode(row pgx.Row) (*domain.OAuthCode, error) {
+	var result domain.OAuthCode
+	if err := row.Scan(
+		&result.ID,
+		&result.AccountID,
+		&result.ClientID,
+		&result.Digest,
+		&result.Challenge,
+		&result.RedirectURI,
+		&result.Scopes,
+		&result.Expires,
+		&result.Used,
+		&result.Created,
+	); err != nil {
+		return nil, err
+	}
+	return &result, nil
+}
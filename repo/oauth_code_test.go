@@ -0,0 +1,64 @@
+package repo_test
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/deliveroo/assert-go"
+	"github.com/deliveroo/todo-api/domain"
+	"github.com/deliveroo/todo-api/repo"
+)
+
+func TestOAuthCodes(t *testing.T) {
+	var (
+		db       = getDB(t)
+		client   = &repo.Client{db.pool}
+		ctx      = context.Background()
+		ownerID  = db.createAccount("oauth-code-owner")
+		clientID = db.createOAuthClient(ownerID)
+		userID   = db.createAccount("oauth-code-user")
+		now      = time.Now().UTC()
+	)
+	defer db.Close()
+
+	newCode := func(expires time.Time) string {
+		c := &domain.OAuthCode{
+			AccountID:   userID,
+			ClientID:    clientID,
+			Challenge:   "challenge",
+			RedirectURI: "https://example.com/callback",
+			Scopes:      []string{domain.ScopeTasksRead},
+			Expires:     expires,
+		}
+		code := c.NewCode()
+		created, err := client.CreateOAuthCode(ctx, c)
+		assert.Must(t, err)
+		assert.True(t, created.ID != 0)
+		assert.Nil(t, created.Used)
+		return code
+	}
+
+	t.Run("single use", func(t *testing.T) {
+		code := newCode(now.Add(time.Minute))
+		used, err := client.UseOAuthCode(ctx, domain.OAuthCodeDigest(code), clientID)
+		assert.Must(t, err)
+		assert.Equal(t, used.Scopes, []string{domain.ScopeTasksRead})
+		assert.NotNil(t, used.Used)
+		again, err := client.UseOAuthCode(ctx, domain.OAuthCodeDigest(code), clientID)
+		assert.Must(t, err)
+		assert.Nil(t, again)
+	})
+	t.Run("other client", func(t *testing.T) {
+		code := newCode(now.Add(time.Minute))
+		used, err := client.UseOAuthCode(ctx, domain.OAuthCodeDigest(code), clientID+1)
+		assert.Must(t, err)
+		assert.Nil(t, used)
+	})
+	t.Run("expired", func(t *testing.T) {
+		code := newCode(now.Add(-time.Second))
+		used, err := client.UseOAuthCode(ctx, domain.OAuthCodeDigest(code), clientID)
+		assert.Must(t, err)
+		assert.Nil(t, used)
+	})
+}
@@ -0,0 +1,59 @@
+package repo
+
+import (
+	"context"
+	"time"
+
+	"github.com/deliveroo/todo-api/domain"
+	"github.com/jackc/pgx/v4"
+)
+
+// CreateOAuthRefreshToken inserts a refresh token into the database.
+func (c *Client) CreateOAuthRefreshToken(ctx context.Context, t *domain.OAuthRefreshToken) (*domain.OAuthRefreshToken, error) {
+	row := c.queryRow(ctx, `
+		INSERT INTO oauth_refresh_tokens (account_id, client_id, token_digest, scopes, expires)
+		VALUES ($1, $2, $3, $4, $5)
+		RETURNING id, account_id, client_id, token_digest, scopes, expires, revoked, created;
+	`, t.AccountID, t.ClientID, t.Digest, t.Scopes, t.Expires)
+	return scanOAuthRefreshToken(row)
+}
+
+// UseOAuthRefreshToken revokes an unrevoked, unexpired refresh token issued to
+// a client, and returns it. It returns nil if no usable refresh token was
+// found.
+func (c *Client) UseOAuthRefreshToken(ctx context.Context, digest string, clientID int64) (*domain.OAuthRefreshToken, error) {
+	row := c.queryRow(ctx, `
+		UPDATE oauth_refresh_tokens
+		SET revoked = $3
+		WHERE token_digest = $1
+		AND client_id = $2
+		AND revoked IS NULL
+		AND expires > $3
+		RETURNING id, account_id, client_id, token_digest, scopes, expires, revoked, created;
+	`, digest, clientID, time.Now().UTC())
+	t, err := scanOAuthRefreshToken(row)
+	if err != nil {
+		if isErrNoRows(err) {
+			return nil, nil
+		}
+		return nil, err
+	}
+	return t, nil
+}
+
+func scanOAuthRefreshToken(row pgx.Row) (*domain.OAuthRefreshToken, error) {
+	var result domain.OAuthRefreshToken
+	if err := row.Scan(
+		&result.ID,
+		&result.AccountID,
+		&result.ClientID,
+		&result.Digest,
+		&result.Scopes,
+		&result.Expires,
+		&result.Revoked,
+		&result.Created,
+	); err != nil {
+		return nil, err
+	}
+	return &result, nil
+}
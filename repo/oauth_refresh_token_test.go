@@ -0,0 +1,61 @@
+package repo_test
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/deliveroo/assert-go"
+	"github.com/deliveroo/todo-api/domain"
+	"github.com/deliveroo/todo-api/repo"
+)
+
+func TestOAuthRefreshTokens(t *testing.T) {
+	var (
+		db       = getDB(t)
+		client   = &repo.Client{db.pool}
+		ctx      = context.Background()
+		ownerID  = db.createAccount("oauth-refresh-token-owner")
+		clientID = db.createOAuthClient(ownerID)
+		userID   = db.createAccount("oauth-refresh-token-user")
+		now      = time.Now().UTC()
+	)
+	defer db.Close()
+
+	newToken := func(expires time.Time) string {
+		rt := &domain.OAuthRefreshToken{
+			AccountID: userID,
+			ClientID:  clientID,
+			Scopes:    []string{domain.ScopeTasksRead},
+			Expires:   expires,
+		}
+		token := rt.NewToken()
+		created, err := client.CreateOAuthRefreshToken(ctx, rt)
+		assert.Must(t, err)
+		assert.True(t, created.ID != 0)
+		assert.Nil(t, created.Revoked)
+		return token
+	}
+
+	t.Run("rotated", func(t *testing.T) {
+		token := newToken(now.Add(time.Hour))
+		used, err := client.UseOAuthRefreshToken(ctx, domain.OAuthRefreshTokenDigest(token), clientID)
+		assert.Must(t, err)
+		assert.NotNil(t, used.Revoked)
+		again, err := client.UseOAuthRefreshToken(ctx, domain.OAuthRefreshTokenDigest(token), clientID)
+		assert.Must(t, err)
+		assert.Nil(t, again)
+	})
+	t.Run("other client", func(t *testing.T) {
+		token := newToken(now.Add(time.Hour))
+		used, err := client.UseOAuthRefreshToken(ctx, domain.OAuthRefreshTokenDigest(token), clientID+1)
+		assert.Must(t, err)
+		assert.Nil(t, used)
+	})
+	t.Run("expired", func(t *testing.T) {
+		token := newToken(now.Add(-time.Second))
+		used, err := client.UseOAuthRefreshToken(ctx, domain.OAuthRefreshTokenDigest(token), clientID)
+		assert.Must(t, err)
+		assert.Nil(t, used)
+	})
+}
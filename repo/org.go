@@ -0,0 +1,351 @@
+package repo
+
+import (
+	"context"
+	"errors"
+
+	"github.com/deliveroo/todo-api/domain"
+	"github.com/jackc/pgx/v4"
+)
+
+// ErrOrgSlugTaken is returned when creating an organisation with a slug which
+// is already in use.
+var ErrOrgSlugTaken = errors.New("slug is already taken")
+
+// CreateOrg inserts an organisation into the database, with an account as its
+// owner, in a single transaction. It returns ErrOrgSlugTaken if the slug is
+// in use.
+func (c *Client) CreateOrg(ctx context.Context, o *domain.Org, ownerID int64) (*domain.Org, error) {
+	tx, err := c.begin(ctx)
+	if err != nil {
+		return nil, err
+	}
+	defer func() {
+		_ = tx.Rollback(ctx) // no-op once committed
+	}()
+	row := tx.QueryRow(ctx, `
+		INSERT INTO orgs (name, slug)
+		VALUES ($1, $2)
+		RETURNING id, public_id, name, slug, created, 'owner';
+	`, o.Name, o.Slug)
+	result, err := scanOrg(row)
+	if isUniqueViolation(err, "orgs_slug_idx") {
+		return nil, ErrOrgSlugTaken
+	}
+	if err != nil {
+		return nil, err
+	}
+	if _, err := tx.Exec(ctx, `
+		INSERT INTO org_members (org_id, account_id, role)
+		VALUES ($1, $2, 'owner');
+	`, result.ID, ownerID); err != nil {
+		return nil, err
+	}
+	if err := tx.Commit(ctx); err != nil {
+		return nil, err
+	}
+	return result, nil
+}
+
+// GetOrgBySlugForAccount fetches an organisation the account belongs to from
+// the database, with the account's role in it, or returns nil if not found.
+func (c *Client) GetOrgBySlugForAccount(ctx context.Context, slug string, accountID int64) (*domain.Org, error) {
+	row := c.queryRow(ctx, `
+		SELECT orgs.id, orgs.public_id, orgs.name, orgs.slug, orgs.created, org_members.role
+		FROM orgs
+		JOIN org_members ON org_members.org_id = orgs.id
+		WHERE orgs.slug = $1
+		AND org_members.account_id = $2;
+	`, slug, accountID)
+	o, err := scanOrg(row)
+	if err != nil {
+		if isErrNoRows(err) {
+			return nil, nil
+		}
+		return nil, err
+	}
+	return o, nil
+}
+
+// GetAllOrgsForAccount fetches all organisations the account belongs to from
+// the database, with the account's role in each.
+func (c *Client) GetAllOrgsForAccount(ctx context.Context, accountID int64) ([]*domain.Org, error) {
+	rows, err := c.query(ctx, `
+		SELECT orgs.id, orgs.public_id, orgs.name, orgs.slug, orgs.created, org_members.role
+		FROM orgs
+		JOIN org_members ON org_members.org_id = orgs.id
+		WHERE org_members.account_id = $1
+		ORDER BY orgs.name, orgs.id;
+	`, accountID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var result []*domain.Org
+	for rows.Next() {
+		o, err := scanOrg(rows)
+		if err != nil {
+			return nil, err
+		}
+		result = append(result, o)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return result, nil
+}
+
+// UpdateOrgName renames an organisation in the database.
+func (c *Client) UpdateOrgName(ctx context.Context, o *domain.Org) (*domain.Org, error) {
+	row := c.queryRow(ctx, `
+		UPDATE orgs
+		SET name = $2
+		WHERE id = $1
+		RETURNING id, public_id, name, slug, created, $3::text;
+	`, o.ID, o.Name, o.Role)
+	return scanOrg(row)
+}
+
+// DeleteOrg deletes an organisation, its tasks, members and invitations from
+// the database in a single transaction.
+func (c *Client) DeleteOrg(ctx context.Context, id int64) error {
+	tx, err := c.begin(ctx)
+	if err != nil {
+		return err
+	}
+	defer func() {
+		_ = tx.Rollback(ctx) // no-op once committed
+	}()
+	if _, err := deleteOrgs(ctx, tx, `id = $1`, id); err != nil {
+		return err
+	}
+	return tx.Commit(ctx)
+}
+
+// deleteOrgs deletes the organisations matching where, and their tasks,
+// members and invitations. The organisations are found first, so where may
+// refer to their members. It returns the number of organisations deleted.
+func deleteOrgs(ctx context.Context, tx pgx.Tx, where string, args ...interface{}) (int64, error) {
+	rows, err := tx.Query(ctx, `SELECT id FROM orgs WHERE `+where+`;`, args...)
+	if err != nil {
+		return 0, err
+	}
+	var ids []int64
+	for rows.Next() {
+		var id int64
+		if err := rows.Scan(&id); err != nil {
+			rows.Close()
+			return 0, err
+		}
+		ids = append(ids, id)
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return 0, err
+	}
+	if err := recordTaskChanges(ctx, tx, true, `org_id = ANY($1::bigint[])`, ids); err != nil {
+		return 0, err
+	}
+	if err := writeTaskOutbox(ctx, tx, domain.EventTaskDeleted, `org_id = ANY($1::bigint[])`, ids); err != nil {
+		return 0, err
+	}
+	for _, table := range []string{
+		"org_invitations",
+		"org_members",
+		"tasks",
+	} {
+		if _, err := tx.Exec(ctx, `
+			DELETE FROM `+table+`
+			WHERE org_id = ANY($1::bigint[]);
+		`, ids); err != nil {
+			return 0, err
+		}
+	}
+	tag, err := tx.Exec(ctx, `DELETE FROM orgs WHERE id = ANY($1::bigint[]);`, ids)
+	if err != nil {
+		return 0, err
+	}
+	return tag.RowsAffected(), nil
+}
+
+// GetOrgMembers fetches the members of an organisation from the database,
+// owner first.
+func (c *Client) GetOrgMembers(ctx context.Context, orgID int64) ([]*domain.OrgMember, error) {
+	rows, err := c.query(ctx, `
+		SELECT org_members.org_id, org_members.account_id, accounts.public_id, org_members.role, accounts.username, org_members.created
+		FROM org_members
+		JOIN accounts ON accounts.id = org_members.account_id
+		WHERE org_members.org_id = $1
+		ORDER BY org_members.role = 'owner' DESC, accounts.username;
+	`, orgID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var result []*domain.OrgMember
+	for rows.Next() {
+		m, err := scanOrgMember(rows)
+		if err != nil {
+			return nil, err
+		}
+		result = append(result, m)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return result, nil
+}
+
+// GetOrgMember fetches a member of an organisation from the database, or
+// returns nil if the account isn't a member.
+func (c *Client) GetOrgMember(ctx context.Context, orgID, accountID int64) (*domain.OrgMember, error) {
+	row := c.queryRow(ctx, `
+		SELECT org_members.org_id, org_members.account_id, accounts.public_id, org_members.role, accounts.username, org_members.created
+		FROM org_members
+		JOIN accounts ON accounts.id = org_members.account_id
+		WHERE org_members.org_id = $1
+		AND org_members.account_id = $2;
+	`, orgID, accountID)
+	m, err := scanOrgMember(row)
+	if err != nil {
+		if isErrNoRows(err) {
+			return nil, nil
+		}
+		return nil, err
+	}
+	return m, nil
+}
+
+// UpdateOrgMemberRole changes a member's role in an organisation. The owner's
+// role can't be changed. It returns pgx.ErrNoRows if the account isn't a
+// member, or is the owner.
+func (c *Client) UpdateOrgMemberRole(ctx context.Context, orgID, accountID int64, role string) error {
+	tag, err := c.exec(ctx, `
+		UPDATE org_members
+		SET role = $3
+		WHERE org_id = $1
+		AND account_id = $2
+		AND role <> 'owner';
+	`, orgID, accountID, role)
+	if err != nil {
+		return err
+	}
+	if tag.RowsAffected() == 0 {
+		return pgx.ErrNoRows
+	}
+	return nil
+}
+
+// TransferOrgOwnership makes a member the owner of an organisation in a single
+// transaction, and makes the previous owner an admin. It returns
+// pgx.ErrNoRows if ownerID isn't the owner or memberID isn't another member.
+func (c *Client) TransferOrgOwnership(ctx context.Context, orgID, ownerID, memberID int64) error {
+	if ownerID == memberID {
+		return pgx.ErrNoRows
+	}
+	tx, err := c.begin(ctx)
+	if err != nil {
+		return err
+	}
+	defer func() {
+		_ = tx.Rollback(ctx) // no-op once committed
+	}()
+	tag, err := tx.Exec(ctx, `
+		UPDATE org_members
+		SET role = 'admin'
+		WHERE org_id = $1
+		AND account_id = $2
+		AND role = 'owner';
+	`, orgID, ownerID)
+	if err != nil {
+		return err
+	}
+	if tag.RowsAffected() == 0 {
+		return pgx.ErrNoRows
+	}
+	tag, err = tx.Exec(ctx, `
+		UPDATE org_members
+		SET role = 'owner'
+		WHERE org_id = $1
+		AND account_id = $2;
+	`, orgID, memberID)
+	if err != nil {
+		return err
+	}
+	if tag.RowsAffected() == 0 {
+		return pgx.ErrNoRows
+	}
+	return tx.Commit(ctx)
+}
+
+// DeleteOrgMember removes a member from an organisation, and unassigns them
+// from its tasks. The owner can't be removed. It returns pgx.ErrNoRows if the
+// account isn't a member, or is the owner.
+func (c *Client) DeleteOrgMember(ctx context.Context, orgID, accountID int64) error {
+	tx, err := c.begin(ctx)
+	if err != nil {
+		return err
+	}
+	defer func() {
+		_ = tx.Rollback(ctx) // no-op once committed
+	}()
+	tag, err := tx.Exec(ctx, `
+		DELETE FROM org_members
+		WHERE org_id = $1
+		AND account_id = $2
+		AND role <> 'owner';
+	`, orgID, accountID)
+	if err != nil {
+		return err
+	}
+	if tag.RowsAffected() == 0 {
+		return pgx.ErrNoRows
+	}
+	if err := recordTaskChanges(ctx, tx, false, `org_id = $1 AND assignee_id = $2`, orgID, accountID); err != nil {
+		return err
+	}
+	if err := recordAccountTaskChanges(ctx, tx, true, accountID, `org_id = $1`, orgID); err != nil {
+		return err
+	}
+	if _, err := tx.Exec(ctx, `
+		WITH task AS (
+			UPDATE tasks
+			SET assignee_id = NULL
+			WHERE org_id = $1
+			AND assignee_id = $2
+			RETURNING *
+		)`+outboxSQL(domain.AggregateTask, domain.EventTaskUpdated), orgID, accountID); err != nil {
+		return err
+	}
+	return tx.Commit(ctx)
+}
+
+func scanOrg(row pgx.Row) (*domain.Org, error) {
+	var result domain.Org
+	if err := row.Scan(
+		&result.ID,
+		&result.PublicID,
+		&result.Name,
+		&result.Slug,
+		&result.Created,
+		&result.Role,
+	); err != nil {
+		return nil, err
+	}
+	return &result, nil
+}
+
+func scanOrgMember(row pgx.Row) (*domain.OrgMember, error) {
+	var result domain.OrgMember
+	if err := row.Scan(
+		&result.OrgID,
+		&result.AccountID,
+		&result.AccountPublicID,
+		&result.Role,
+		&result.Username,
+		&result.Created,
+	); err != nil {
+		return nil, err
+	}
+	return &result, nil
+}
@@ -0,0 +1,197 @@
+package repo
+
+import (
+	"context"
+	"time"
+
+	"github.com/deliveroo/todo-api/domain"
+	"github.com/jackc/pgx/v4"
+)
+
+// orgInvitationColumns are the columns selected for organisation invitations,
+// which must be joined to orgs as o, and to accounts as inviter and invitee.
+const orgInvitationColumns = `
+	org_invitations.id, org_invitations.public_id, org_invitations.org_id, o.name, o.slug,
+	org_invitations.inviter_id, inviter.username,
+	org_invitations.invitee_id, invitee.username,
+	org_invitations.role, org_invitations.status, org_invitations.responded,
+	org_invitations.created`
+
+const orgInvitationJoins = `
+	JOIN orgs AS o ON o.id = org_invitations.org_id
+	JOIN accounts AS inviter ON inviter.id = org_invitations.inviter_id
+	JOIN accounts AS invitee ON invitee.id = org_invitations.invitee_id`
+
+// CreateOrgInvitation inserts an organisation invitation into the database. It
+// returns ErrInvitationPending if the invitee has already been invited.
+func (c *Client) CreateOrgInvitation(ctx context.Context, i *domain.OrgInvitation) (*domain.OrgInvitation, error) {
+	var id int64
+	err := c.queryRow(ctx, `
+		INSERT INTO org_invitations (org_id, inviter_id, invitee_id, role)
+		VALUES ($1, $2, $3, $4)
+		RETURNING id;
+	`, i.OrgID, i.InviterID, i.InviteeID, i.Role).Scan(&id)
+	if isUniqueViolation(err, "org_invitations_pending_idx") {
+		return nil, ErrInvitationPending
+	}
+	if err != nil {
+		return nil, err
+	}
+	return c.GetOrgInvitationByID(ctx, id)
+}
+
+// GetOrgInvitationByID fetches an organisation invitation from the database,
+// or returns nil if not found.
+func (c *Client) GetOrgInvitationByID(ctx context.Context, id int64) (*domain.OrgInvitation, error) {
+	row := c.queryRow(ctx, `
+		SELECT `+orgInvitationColumns+`
+		FROM org_invitations`+orgInvitationJoins+`
+		WHERE org_invitations.id = $1;
+	`, id)
+	i, err := scanOrgInvitation(row)
+	if err != nil {
+		if isErrNoRows(err) {
+			return nil, nil
+		}
+		return nil, err
+	}
+	return i, nil
+}
+
+// GetPendingOrgInvitationsByOrgID fetches the pending invitations to an
+// organisation from the database.
+func (c *Client) GetPendingOrgInvitationsByOrgID(ctx context.Context, orgID int64) ([]*domain.OrgInvitation, error) {
+	return c.queryOrgInvitations(ctx, `
+		SELECT `+orgInvitationColumns+`
+		FROM org_invitations`+orgInvitationJoins+`
+		WHERE org_invitations.org_id = $1
+		AND org_invitations.status = 'pending'
+		ORDER BY org_invitations.created DESC, org_invitations.id DESC;
+	`, orgID)
+}
+
+// GetPendingOrgInvitationsByInviteeID fetches an account's pending
+// organisation invitations from the database.
+func (c *Client) GetPendingOrgInvitationsByInviteeID(ctx context.Context, inviteeID int64) ([]*domain.OrgInvitation, error) {
+	return c.queryOrgInvitations(ctx, `
+		SELECT `+orgInvitationColumns+`
+		FROM org_invitations`+orgInvitationJoins+`
+		WHERE org_invitations.invitee_id = $1
+		AND org_invitations.status = 'pending'
+		ORDER BY org_invitations.created DESC, org_invitations.id DESC;
+	`, inviteeID)
+}
+
+// RespondToOrgInvitation accepts or declines a pending invitation sent to an
+// account. Accepting it makes the account a member of the organisation, or
+// changes its role if it's a member already, unless it's the owner. The
+// invitation is identified by its public id. It returns nil if no pending
+// invitation was found.
+func (c *Client) RespondToOrgInvitation(ctx context.Context, publicID string, inviteeID int64, accept bool) (*domain.OrgInvitation, error) {
+	tx, err := c.begin(ctx)
+	if err != nil {
+		return nil, err
+	}
+	defer func() {
+		_ = tx.Rollback(ctx) // no-op once committed
+	}()
+	status := domain.InvitationDeclined
+	if accept {
+		status = domain.InvitationAccepted
+	}
+	var (
+		id, orgID int64
+		role      string
+	)
+	err = tx.QueryRow(ctx, `
+		UPDATE org_invitations
+		SET status = $3, responded = $4
+		WHERE public_id = $1
+		AND invitee_id = $2
+		AND status = 'pending'
+		RETURNING id, org_id, role;
+	`, publicID, inviteeID, status, time.Now().UTC()).Scan(&id, &orgID, &role)
+	if err != nil {
+		if isErrNoRows(err) {
+			return nil, nil
+		}
+		return nil, err
+	}
+	if accept {
+		if _, err := tx.Exec(ctx, `
+			INSERT INTO org_members (org_id, account_id, role)
+			VALUES ($1, $2, $3)
+			ON CONFLICT (org_id, account_id) DO UPDATE SET role = EXCLUDED.role
+			WHERE org_members.role <> 'owner';
+		`, orgID, inviteeID, role); err != nil {
+			return nil, err
+		}
+		if err := recordAccountTaskChanges(ctx, tx, false, inviteeID, `org_id = $1`, orgID); err != nil {
+			return nil, err
+		}
+	}
+	if err := tx.Commit(ctx); err != nil {
+		return nil, err
+	}
+	return c.GetOrgInvitationByID(ctx, id)
+}
+
+// DeleteOrgInvitationByPublicIDAndOrgID cancels a pending invitation to an
+// organisation. It returns pgx.ErrNoRows if no pending invitation was found.
+func (c *Client) DeleteOrgInvitationByPublicIDAndOrgID(ctx context.Context, publicID string, orgID int64) error {
+	tag, err := c.exec(ctx, `
+		DELETE FROM org_invitations
+		WHERE public_id = $1
+		AND org_id = $2
+		AND status = 'pending';
+	`, publicID, orgID)
+	if err != nil {
+		return err
+	}
+	if tag.RowsAffected() == 0 {
+		return pgx.ErrNoRows
+	}
+	return nil
+}
+
+func (c *Client) queryOrgInvitations(ctx context.Context, sql string, args ...interface{}) ([]*domain.OrgInvitation, error) {
+	rows, err := c.query(ctx, sql, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var result []*domain.OrgInvitation
+	for rows.Next() {
+		i, err := scanOrgInvitation(rows)
+		if err != nil {
+			return nil, err
+		}
+		result = append(result, i)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return result, nil
+}
+
+func scanOrgInvitation(row pgx.Row) (*domain.OrgInvitation, error) {
+	var result domain.OrgInvitation
+	if err := row.Scan(
+		&result.ID,
+		&result.PublicID,
+		&result.OrgID,
+		&result.OrgName,
+		&result.OrgSlug,
+		&result.InviterID,
+		&result.InviterUsername,
+		&result.InviteeID,
+		&result.InviteeUsername,
+		&result.Role,
+		&result.Status,
+		&result.Responded,
+		&result.Created,
+	); err != nil {
+		return nil, err
+	}
+	return &result, nil
+}
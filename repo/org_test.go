@@ -0,0 +1,157 @@
+package repo_test
+
+import (
+	"context"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/deliveroo/assert-go"
+	"github.com/deliveroo/todo-api/domain"
+	"github.com/deliveroo/todo-api/repo"
+	"github.com/jackc/pgx/v4"
+)
+
+func TestOrgs(t *testing.T) {
+	var (
+		db     = getDB(t)
+		client = &repo.Client{db.pool}
+		ctx    = context.Background()
+		slug   = fmt.Sprintf("acme-%d", time.Now().UnixNano())
+	)
+	defer db.Close()
+
+	newAccount := func(username string) int64 {
+		a, err := client.CreateAccount(ctx, &domain.Account{
+			Username:       username,
+			PasswordDigest: "password-digest",
+			PasswordSalt:   "password-salt",
+		})
+		assert.Must(t, err)
+		return a.ID
+	}
+	var (
+		owner    = newAccount("org-owner")
+		member   = newAccount("org-member")
+		outsider = newAccount("org-outsider")
+	)
+
+	org, err := client.CreateOrg(ctx, &domain.Org{Name: "Acme", Slug: slug}, owner)
+	assert.Must(t, err)
+	assert.Equal(t, org.Role, domain.OrgRoleOwner)
+	assert.True(t, domain.ValidPublicID(org.PublicID))
+	_, err = client.CreateOrg(ctx, &domain.Org{Name: "Acme", Slug: slug}, outsider)
+	assert.Equal(t, err, repo.ErrOrgSlugTaken)
+
+	t.Run("invitations", func(t *testing.T) {
+		i, err := client.CreateOrgInvitation(ctx, &domain.OrgInvitation{
+			OrgID:     org.ID,
+			InviterID: owner,
+			InviteeID: member,
+			Role:      domain.OrgRoleMember,
+		})
+		assert.Must(t, err)
+		assert.Equal(t, i.OrgSlug, slug)
+		assert.True(t, domain.ValidPublicID(i.PublicID))
+		_, err = client.CreateOrgInvitation(ctx, i)
+		assert.Equal(t, err, repo.ErrInvitationPending)
+
+		none, err := client.RespondToOrgInvitation(ctx, i.PublicID, outsider, true)
+		assert.Must(t, err)
+		assert.Nil(t, none)
+		accepted, err := client.RespondToOrgInvitation(ctx, i.PublicID, member, true)
+		assert.Must(t, err)
+		assert.Equal(t, accepted.Status, domain.InvitationAccepted)
+
+		members, err := client.GetOrgMembers(ctx, org.ID)
+		assert.Must(t, err)
+		assert.Equal(t, len(members), 2)
+		assert.Equal(t, members[0].Role, domain.OrgRoleOwner)
+
+		got, err := client.GetOrgBySlugForAccount(ctx, slug, member)
+		assert.Must(t, err)
+		assert.Equal(t, got.Role, domain.OrgRoleMember)
+		hidden, err := client.GetOrgBySlugForAccount(ctx, slug, outsider)
+		assert.Must(t, err)
+		assert.Nil(t, hidden)
+	})
+	t.Run("tenant scope", func(t *testing.T) {
+		orgCtx := repo.WithOrgID(ctx, org.ID)
+		task, err := client.CreateTask(orgCtx, &domain.Task{CreatedBy: member, Description: "ship it"})
+		assert.Must(t, err)
+		assert.Equal(t, *task.OrgID, org.ID)
+		personal, err := client.CreateTask(ctx, &domain.Task{CreatedBy: member, Description: "personal"})
+		assert.Must(t, err)
+		assert.Nil(t, personal.OrgID)
+
+		// Outsiders can't create tasks in the organisation.
+		_, err = client.CreateTask(orgCtx, &domain.Task{CreatedBy: outsider, Description: "intrude"})
+		assert.Equal(t, err, pgx.ErrNoRows)
+
+		// Organisation tasks are only visible in the organisation, and personal
+		// tasks only outside it.
+		got, err := client.GetTaskByIDForAccount(orgCtx, task.ID, owner)
+		assert.Must(t, err)
+		assert.NotNil(t, got)
+		got, err = client.GetTaskByIDForAccount(ctx, task.ID, member)
+		assert.Must(t, err)
+		assert.Nil(t, got)
+		got, err = client.GetTaskByIDForAccount(orgCtx, personal.ID, member)
+		assert.Must(t, err)
+		assert.Nil(t, got)
+		got, err = client.GetTaskByIDForAccount(orgCtx, task.ID, outsider)
+		assert.Must(t, err)
+		assert.Nil(t, got)
+
+		tasks, err := client.GetAllTasksForAccount(orgCtx, owner)
+		assert.Must(t, err)
+		assert.Equal(t, len(tasks), 1)
+		tasks, err = client.GetAllTasksForAccount(ctx, member)
+		assert.Must(t, err)
+		assert.Equal(t, len(tasks), 1)
+		assert.Equal(t, tasks[0].ID, personal.ID)
+
+		_, err = client.UpdateTaskAssigneeForAccount(orgCtx, task.ID, owner, &member)
+		assert.Must(t, err)
+	})
+	t.Run("transfer ownership", func(t *testing.T) {
+		assert.Equal(t, client.TransferOrgOwnership(ctx, org.ID, member, owner), pgx.ErrNoRows)
+		assert.Equal(t, client.TransferOrgOwnership(ctx, org.ID, owner, owner), pgx.ErrNoRows)
+		assert.Equal(t, client.TransferOrgOwnership(ctx, org.ID, owner, outsider), pgx.ErrNoRows)
+		m, err := client.GetOrgMember(ctx, org.ID, owner)
+		assert.Must(t, err)
+		assert.Equal(t, m.Role, domain.OrgRoleOwner)
+
+		assert.Must(t, client.TransferOrgOwnership(ctx, org.ID, owner, member))
+		m, err = client.GetOrgMember(ctx, org.ID, member)
+		assert.Must(t, err)
+		assert.Equal(t, m.Role, domain.OrgRoleOwner)
+		m, err = client.GetOrgMember(ctx, org.ID, owner)
+		assert.Must(t, err)
+		assert.Equal(t, m.Role, domain.OrgRoleAdmin)
+
+		assert.Must(t, client.TransferOrgOwnership(ctx, org.ID, member, owner))
+		assert.Must(t, client.UpdateOrgMemberRole(ctx, org.ID, member, domain.OrgRoleMember))
+	})
+	t.Run("members", func(t *testing.T) {
+		assert.Equal(t, client.UpdateOrgMemberRole(ctx, org.ID, owner, domain.OrgRoleMember), pgx.ErrNoRows)
+		assert.Must(t, client.UpdateOrgMemberRole(ctx, org.ID, member, domain.OrgRoleAdmin))
+
+		assert.Equal(t, client.DeleteOrgMember(ctx, org.ID, owner), pgx.ErrNoRows)
+		assert.Must(t, client.DeleteOrgMember(ctx, org.ID, member))
+		m, err := client.GetOrgMember(ctx, org.ID, member)
+		assert.Must(t, err)
+		assert.Nil(t, m)
+
+		tasks, err := client.GetAllTasksForAccount(repo.WithOrgID(ctx, org.ID), owner)
+		assert.Must(t, err)
+		assert.Equal(t, len(tasks), 1)
+		assert.Nil(t, tasks[0].AssigneeID)
+	})
+	t.Run("delete", func(t *testing.T) {
+		assert.Must(t, client.DeleteOrg(ctx, org.ID))
+		orgs, err := client.GetAllOrgsForAccount(ctx, owner)
+		assert.Must(t, err)
+		assert.Equal(t, len(orgs), 0)
+	})
+}
@@ -0,0 +1,149 @@
+package repo
+
+import (
+	"context"
+	"time"
+
+	"github.com/deliveroo/todo-api/domain"
+	"github.com/jackc/pgx/v4"
+)
+
+const outboxColumns = `
+	id, aggregate, aggregate_id, aggregate_public_id, event, payload, attempts,
+	next_attempt, last_error, published, created`
+
+// Payloads of the events written to the outbox, selected from the changed
+// rows. Like the API, they identify accounts, tasks and lists by their public
+// ids, and leave out accounts' secrets.
+const (
+	accountPayloadSQL = `json_build_object(
+		'id', public_id, 'username', username, 'email', email, 'role', role,
+		'suspended_at', suspended_at, 'verified_at', verified_at, 'created', created)::text`
+
+	taskPayloadSQL = `json_build_object(
+		'id', public_id,
+		'account_id', (SELECT a.public_id FROM accounts a WHERE a.id = task.account_id),
+		'assignee_id', (SELECT a.public_id FROM accounts a WHERE a.id = task.assignee_id),
+		'created_by', (SELECT a.public_id FROM accounts a WHERE a.id = task.created_by),
+		'list_id', (SELECT l.public_id FROM lists l WHERE l.id = task.list_id),
+		'description', description, 'created', created, 'completed', completed)::text`
+)
+
+// outboxSQL writes an event to the outbox for each row changed by a
+// statement, in the same statement, so that the events are written if and
+// only if the change is. It must follow a common table expression named after
+// the aggregate, e.g. task, which holds the changed rows, and it selects them.
+func outboxSQL(aggregate, event string) string {
+	payload := taskPayloadSQL
+	if aggregate == domain.AggregateAccount {
+		payload = accountPayloadSQL
+	}
+	return `,
+		outboxed AS (
+			INSERT INTO outbox (aggregate, aggregate_id, aggregate_public_id, event, payload)
+			SELECT '` + aggregate + `', id, public_id::text, '` + event + `', ` + payload + `
+			FROM ` + aggregate + `
+		)
+		SELECT * FROM ` + aggregate + `;`
+}
+
+// writeTaskOutbox writes an event to the outbox for each task matching where.
+// Deletions must be written before the tasks are deleted.
+func writeTaskOutbox(ctx context.Context, tx pgx.Tx, event, where string, args ...interface{}) error {
+	_, err := tx.Exec(ctx, `
+		WITH task AS (
+			SELECT * FROM tasks WHERE `+where+`
+		)`+outboxSQL(domain.AggregateTask, event), args...)
+	return err
+}
+
+// ClaimOutboxEvents fetches up to limit unpublished events which are due from
+// the database, oldest first, and postpones their next attempt by lease so
+// that no other dispatcher claims them meanwhile. If the dispatcher stops
+// before recording the outcome, the events are published again once the lease
+// expires.
+func (c *Client) ClaimOutboxEvents(ctx context.Context, limit int, lease time.Duration) ([]*domain.OutboxEvent, error) {
+	now := time.Now().UTC()
+	rows, err := c.query(ctx, `
+		WITH claimed AS (
+			UPDATE outbox
+			SET next_attempt = $3
+			WHERE id IN (
+				SELECT id
+				FROM outbox
+				WHERE published IS NULL
+				AND next_attempt <= $2
+				ORDER BY id
+				LIMIT $1
+				FOR UPDATE SKIP LOCKED
+			)
+			RETURNING `+outboxColumns+`
+		)
+		SELECT * FROM claimed ORDER BY id;
+	`, limit, now, now.Add(lease))
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var result []*domain.OutboxEvent
+	for rows.Next() {
+		e, err := scanOutboxEvent(rows)
+		if err != nil {
+			return nil, err
+		}
+		result = append(result, e)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return result, nil
+}
+
+// UpdateOutboxEvent records the outcome of publishing an event in the
+// database.
+func (c *Client) UpdateOutboxEvent(ctx context.Context, e *domain.OutboxEvent) (*domain.OutboxEvent, error) {
+	row := c.queryRow(ctx, `
+		UPDATE outbox
+		SET attempts = $2, next_attempt = $3, last_error = $4, published = $5
+		WHERE id = $1
+		RETURNING `+outboxColumns+`;
+	`, e.ID, e.Attempts, e.NextAttempt, e.LastError, e.Published)
+	return scanOutboxEvent(row)
+}
+
+// DeletePublishedOutboxEvents deletes the events published before a time from
+// the database, returning how many were deleted.
+func (c *Client) DeletePublishedOutboxEvents(ctx context.Context, before time.Time) (int64, error) {
+	tag, err := c.exec(ctx, `
+		DELETE FROM outbox
+		WHERE published < $1;
+	`, before)
+	if err != nil {
+		return 0, err
+	}
+	return tag.RowsAffected(), nil
+}
+
+func scanOutboxEvent(row pgx.Row) (*domain.OutboxEvent, error) {
+	var (
+		result  domain.OutboxEvent
+		payload string
+	)
+	if err := row.Scan(
+		&result.ID,
+		&result.Aggregate,
+		&result.AggregateID,
+		&result.AggregatePublicID,
+		&result.Event,
+		&payload,
+		&result.Attempts,
+		&result.NextAttempt,
+		&result.LastError,
+		&result.Published,
+		&result.Created,
+	); err != nil {
+		return nil, err
+	}
+	result.Payload = []byte(payload)
+	return &result, nil
+}
@@ -0,0 +1,92 @@
+package repo_test
+
+import (
+	"context"
+	"encoding/json"
+	"testing"
+	"time"
+
+	"github.com/deliveroo/assert-go"
+	"github.com/deliveroo/todo-api/domain"
+	"github.com/deliveroo/todo-api/repo"
+)
+
+func TestOutbox(t *testing.T) {
+	var (
+		db     = getDB(t)
+		client = &repo.Client{db.pool}
+		ctx    = context.Background()
+	)
+	defer db.Close()
+
+	account, err := client.CreateAccount(ctx, &domain.Account{
+		Username:       "outbox-owner",
+		PasswordDigest: "password-digest",
+		PasswordSalt:   "password-salt",
+	})
+	assert.Must(t, err)
+	task, err := client.CreateTask(ctx, &domain.Task{
+		AccountID:   account.ID,
+		CreatedBy:   account.ID,
+		Description: "outbox task",
+	})
+	assert.Must(t, err)
+	assert.Must(t, client.DeleteTaskByIDForAccount(ctx, task.ID, account.ID))
+
+	// Other tests write to the outbox too, so claim everything and keep the
+	// events for this test's account and task.
+	claimed, err := client.ClaimOutboxEvents(ctx, 1000, time.Minute)
+	assert.Must(t, err)
+	var events []*domain.OutboxEvent
+	for _, e := range claimed {
+		if (e.Aggregate == domain.AggregateAccount && e.AggregateID == account.ID) ||
+			(e.Aggregate == domain.AggregateTask && e.AggregateID == task.ID) {
+			events = append(events, e)
+		}
+	}
+	assert.Equal(t, len(events), 3)
+	assert.Equal(t, events[0].Event, domain.EventAccountCreated)
+	assert.Equal(t, events[1].Event, domain.EventTaskCreated)
+	assert.Equal(t, events[2].Event, domain.EventTaskDeleted)
+
+	t.Run("payload", func(t *testing.T) {
+		var payload map[string]interface{}
+		assert.Must(t, json.Unmarshal(events[0].Payload, &payload))
+		assert.Equal(t, payload["id"], account.PublicID)
+		assert.Equal(t, payload["username"], "outbox-owner")
+		_, ok := payload["password_digest"]
+		assert.False(t, ok)
+
+		payload = nil
+		assert.Must(t, json.Unmarshal(events[2].Payload, &payload))
+		assert.Equal(t, payload["id"], task.PublicID)
+		assert.Equal(t, payload["account_id"], account.PublicID)
+		assert.Equal(t, payload["created_by"], account.PublicID)
+		assert.Equal(t, payload["assignee_id"], nil)
+		assert.Equal(t, payload["description"], "outbox task")
+		assert.Equal(t, payload["list_id"], nil)
+	})
+	t.Run("public ids", func(t *testing.T) {
+		assert.Equal(t, events[0].AggregatePublicID, account.PublicID)
+		assert.Equal(t, events[1].AggregatePublicID, task.PublicID)
+		assert.Equal(t, events[2].AggregatePublicID, task.PublicID)
+	})
+	t.Run("claimed", func(t *testing.T) {
+		again, err := client.ClaimOutboxEvents(ctx, 1000, time.Minute)
+		assert.Must(t, err)
+		for _, e := range again {
+			assert.True(t, e.ID != events[0].ID)
+		}
+	})
+	t.Run("published", func(t *testing.T) {
+		published := time.Now().UTC().Add(-time.Hour)
+		e := events[0]
+		e.Published = &published
+		updated, err := client.UpdateOutboxEvent(ctx, e)
+		assert.Must(t, err)
+		assert.True(t, updated.Published != nil)
+		n, err := client.DeletePublishedOutboxEvents(ctx, time.Now().UTC())
+		assert.Must(t, err)
+		assert.True(t, n >= 1)
+	})
+}
@@ -0,0 +1,67 @@
+package repo
+
+import (
+	"context"
+	"time"
+
+	"github.com/deliveroo/todo-api/domain"
+	"github.com/jackc/pgx/v4"
+)
+
+// CreatePasswordReset inserts a password reset into the database.
+func (c *Client) CreatePasswordReset(ctx context.Context, r *domain.PasswordReset) (*domain.PasswordReset, error) {
+	row := c.queryRow(ctx, `
+		INSERT INTO password_resets (account_id, token_digest, expires)
+		VALUES ($1, $2, $3)
+		RETURNING id, account_id, token_digest, expires, used, created;
+	`, r.AccountID, r.Digest, r.Expires)
+	return scanPasswordReset(row)
+}
+
+// UsePasswordReset marks an unused, unexpired password reset as used, along
+// with any other outstanding resets for the same account, and returns it. It
+// returns nil if no usable password reset was found.
+func (c *Client) UsePasswordReset(ctx context.Context, digest string) (*domain.PasswordReset, error) {
+	row := c.queryRow(ctx, `
+		WITH reset AS (
+			UPDATE password_resets
+			SET used = $2
+			WHERE token_digest = $1
+			AND used IS NULL
+			AND expires > $2
+			RETURNING id, account_id, token_digest, expires, used, created
+		), others AS (
+			UPDATE password_resets
+			SET used = $2
+			FROM reset
+			WHERE password_resets.account_id = reset.account_id
+			AND password_resets.id <> reset.id
+			AND password_resets.used IS NULL
+		)
+		SELECT id, account_id, token_digest, expires, used, created
+		FROM reset;
+	`, digest, time.Now().UTC())
+	r, err := scanPasswordReset(row)
+	if err != nil {
+		if isErrNoRows(err) {
+			return nil, nil
+		}
+		return nil, err
+	}
+	return r, nil
+}
+
+func scanPasswordReset(row pgx.Row) (*domain.PasswordReset, error) {
+	var result domain.PasswordReset
+	if err := row.Scan(
+		&result.ID,
+		&result.AccountID,
+		&result.Digest,
+		&result.Expires,
+		&result.Used,
+		&result.Created,
+	); err != nil {
+		return nil, err
+	}
+	return &result, nil
+}
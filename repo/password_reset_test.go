@@ -0,0 +1,62 @@
+package repo_test
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/deliveroo/assert-go"
+	"github.com/deliveroo/todo-api/domain"
+	"github.com/deliveroo/todo-api/repo"
+)
+
+func TestPasswordResets(t *testing.T) {
+	var (
+		db        = getDB(t)
+		client    = &repo.Client{db.pool}
+		ctx       = context.Background()
+		accountID = db.createAccount("password-reset-alpha")
+		now       = time.Now().UTC()
+	)
+	defer db.Close()
+
+	newReset := func(expires time.Time) string {
+		r := &domain.PasswordReset{
+			AccountID: accountID,
+			Expires:   expires,
+		}
+		token := r.NewToken()
+		created, err := client.CreatePasswordReset(ctx, r)
+		assert.Must(t, err)
+		assert.True(t, created.ID != 0)
+		assert.Nil(t, created.Used)
+		return token
+	}
+
+	t.Run("single use", func(t *testing.T) {
+		token := newReset(now.Add(time.Hour))
+		used, err := client.UsePasswordReset(ctx, domain.PasswordResetDigest(token))
+		assert.Must(t, err)
+		assert.Equal(t, used.AccountID, accountID)
+		assert.NotNil(t, used.Used)
+		again, err := client.UsePasswordReset(ctx, domain.PasswordResetDigest(token))
+		assert.Must(t, err)
+		assert.Nil(t, again)
+	})
+	t.Run("expired", func(t *testing.T) {
+		token := newReset(now.Add(-time.Minute))
+		used, err := client.UsePasswordReset(ctx, domain.PasswordResetDigest(token))
+		assert.Must(t, err)
+		assert.Nil(t, used)
+	})
+	t.Run("invalidates others", func(t *testing.T) {
+		first := newReset(now.Add(time.Hour))
+		second := newReset(now.Add(time.Hour))
+		used, err := client.UsePasswordReset(ctx, domain.PasswordResetDigest(second))
+		assert.Must(t, err)
+		assert.NotNil(t, used)
+		used, err = client.UsePasswordReset(ctx, domain.PasswordResetDigest(first))
+		assert.Must(t, err)
+		assert.Nil(t, used)
+	})
+}
@@ -0,0 +1,45 @@
+package repo
+
+import (
+	"context"
+	"time"
+)
+
+// ReplaceRecoveryCodes replaces all recovery codes for an account with the
+// given code digests.
+func (c *Client) ReplaceRecoveryCodes(ctx context.Context, accountID int64, digests []string) error {
+	_, err := c.exec(ctx, `
+		WITH deleted AS (
+			DELETE FROM recovery_codes
+			WHERE account_id = $1
+		)
+		INSERT INTO recovery_codes (account_id, code_digest)
+		SELECT $1, unnest($2::text[]);
+	`, accountID, digests)
+	return err
+}
+
+// UseRecoveryCode marks an unused recovery code as used, and reports whether
+// one was found.
+func (c *Client) UseRecoveryCode(ctx context.Context, accountID int64, digest string) (bool, error) {
+	tag, err := c.exec(ctx, `
+		UPDATE recovery_codes
+		SET used = $3
+		WHERE account_id = $1
+		AND code_digest = $2
+		AND used IS NULL;
+	`, accountID, digest, time.Now().UTC())
+	if err != nil {
+		return false, err
+	}
+	return tag.RowsAffected() > 0, nil
+}
+
+// DeleteRecoveryCodesByAccountID deletes all recovery codes for an account.
+func (c *Client) DeleteRecoveryCodesByAccountID(ctx context.Context, accountID int64) error {
+	_, err := c.exec(ctx, `
+		DELETE FROM recovery_codes
+		WHERE account_id = $1;
+	`, accountID)
+	return err
+}
@@ -0,0 +1,47 @@
+package repo_test
+
+import (
+	"context"
+	"testing"
+
+	"github.com/deliveroo/assert-go"
+	"github.com/deliveroo/todo-api/domain"
+	"github.com/deliveroo/todo-api/repo"
+)
+
+func TestRecoveryCodes(t *testing.T) {
+	var (
+		db        = getDB(t)
+		client    = &repo.Client{db.pool}
+		ctx       = context.Background()
+		accountID = db.createAccount("recovery-code-alpha")
+	)
+	defer db.Close()
+
+	codes, digests := domain.NewRecoveryCodes(2)
+	assert.Must(t, client.ReplaceRecoveryCodes(ctx, accountID, digests))
+
+	t.Run("single use", func(t *testing.T) {
+		ok, err := client.UseRecoveryCode(ctx, accountID, domain.RecoveryCodeDigest(codes[0]))
+		assert.Must(t, err)
+		assert.True(t, ok)
+		ok, err = client.UseRecoveryCode(ctx, accountID, domain.RecoveryCodeDigest(codes[0]))
+		assert.Must(t, err)
+		assert.False(t, ok)
+	})
+	t.Run("scoped to account", func(t *testing.T) {
+		ok, err := client.UseRecoveryCode(ctx, accountID+1, domain.RecoveryCodeDigest(codes[1]))
+		assert.Must(t, err)
+		assert.False(t, ok)
+	})
+	t.Run("replace", func(t *testing.T) {
+		_, newDigests := domain.NewRecoveryCodes(2)
+		assert.Must(t, client.ReplaceRecoveryCodes(ctx, accountID, newDigests))
+		ok, err := client.UseRecoveryCode(ctx, accountID, domain.RecoveryCodeDigest(codes[1]))
+		assert.Must(t, err)
+		assert.False(t, ok)
+	})
+	t.Run("delete", func(t *testing.T) {
+		assert.Must(t, client.DeleteRecoveryCodesByAccountID(ctx, accountID))
+	})
+}
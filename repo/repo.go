@@ -3,6 +3,7 @@ package repo
 import (
 	"context"
 	"errors"
+	"strconv"
 
 	"github.com/jackc/pgconn"
 	"github.com/jackc/pgx/v4"
@@ -11,7 +12,7 @@ import (
 
 // Client provides access to all supported database interactions.
 type Client struct {
-	Database *pgxpool.Pool
+	Database DB
 }
 
 // NewClient returns a new repo client.
@@ -19,19 +20,95 @@ func NewClient(pool *pgxpool.Pool) *Client {
 	return &Client{Database: pool}
 }
 
-// queryRow executes the provided query as a prepared statement.
+// queryRow executes the provided query as a prepared statement. If ctx is
+// scoped to an organisation, the query runs in a transaction which lasts until
+// the row is scanned.
 func (c *Client) queryRow(ctx context.Context, sql string, args ...interface{}) pgx.Row {
-	return c.Database.QueryRow(ctx, sql, args...)
+	if _, ok := OrgIDFromContext(ctx); !ok {
+		return c.Database.QueryRow(ctx, sql, args...)
+	}
+	tx, err := c.begin(ctx)
+	if err != nil {
+		return errRow{err}
+	}
+	return &tenantRow{ctx: ctx, tx: tx, row: tx.QueryRow(ctx, sql, args...)}
 }
 
-// query executes the provided query as a prepared statement.
+// query executes the provided query as a prepared statement. If ctx is scoped
+// to an organisation, the query runs in a transaction which lasts until the
+// rows are closed.
 func (c *Client) query(ctx context.Context, sql string, args ...interface{}) (pgx.Rows, error) {
-	return c.Database.Query(ctx, sql, args...)
+	if _, ok := OrgIDFromContext(ctx); !ok {
+		return c.Database.Query(ctx, sql, args...)
+	}
+	tx, err := c.begin(ctx)
+	if err != nil {
+		return nil, err
+	}
+	rows, err := tx.Query(ctx, sql, args...)
+	if err != nil {
+		_ = tx.Rollback(ctx)
+		return nil, err
+	}
+	return &tenantRows{Rows: rows, ctx: ctx, tx: tx}, nil
 }
 
 // exec executes the provided query.
 func (c *Client) exec(ctx context.Context, sql string, args ...interface{}) (pgconn.CommandTag, error) {
-	return c.Database.Exec(ctx, sql, args...)
+	if _, ok := OrgIDFromContext(ctx); !ok {
+		return c.Database.Exec(ctx, sql, args...)
+	}
+	tx, err := c.begin(ctx)
+	if err != nil {
+		return nil, err
+	}
+	defer func() {
+		_ = tx.Rollback(ctx) // no-op once committed
+	}()
+	tag, err := tx.Exec(ctx, sql, args...)
+	if err != nil {
+		return nil, err
+	}
+	return tag, tx.Commit(ctx)
+}
+
+// begin starts a transaction, scoped to the organisation ctx is scoped to, if
+// any. If the client is already in a transaction, it starts a savepoint.
+func (c *Client) begin(ctx context.Context) (pgx.Tx, error) {
+	return c.beginTx(ctx, pgx.TxOptions{})
+}
+
+// beginTx is begin with transaction options, which are ignored when starting
+// a savepoint.
+func (c *Client) beginTx(ctx context.Context, opts pgx.TxOptions) (pgx.Tx, error) {
+	var (
+		tx  pgx.Tx
+		err error
+	)
+	if db, ok := c.Database.(interface {
+		BeginTx(context.Context, pgx.TxOptions) (pgx.Tx, error)
+	}); ok {
+		tx, err = db.BeginTx(ctx, opts)
+	} else {
+		tx, err = c.Database.Begin(ctx)
+	}
+	if err != nil {
+		return nil, err
+	}
+	if orgID, ok := OrgIDFromContext(ctx); ok {
+		if _, err := tx.Exec(ctx, `SELECT set_config('todo.org_id', $1, true);`, strconv.FormatInt(orgID, 10)); err != nil {
+			_ = tx.Rollback(ctx)
+			return nil, err
+		}
+	}
+	return tx, nil
+}
+
+// isUniqueViolation reports whether err is a violation of the named unique
+// constraint or index.
+func isUniqueViolation(err error, constraint string) bool {
+	var pgErr *pgconn.PgError
+	return errors.As(err, &pgErr) && pgErr.Code == "23505" && pgErr.ConstraintName == constraint
 }
 
 func isErrNoRows(err error) bool {
@@ -1,12 +1,15 @@
 package repo_test
 
 import (
+	"context"
 	"flag"
 	"log"
 	"os"
 	"testing"
 
 	"github.com/deliveroo/assert-go"
+	"github.com/deliveroo/todo-api/domain"
+	"github.com/deliveroo/todo-api/repo"
 	"github.com/deliveroo/todo-api/selftest/deps/postgres"
 	"github.com/jackc/pgx/v4/pgxpool"
 )
@@ -49,6 +52,34 @@ func (db *testDB) Close() {
 	db.pool.Close()
 }
 
+// createAccount creates an account for rows to refer to, and returns its id.
+func (db *testDB) createAccount(username string) int64 {
+	client := &repo.Client{db.pool}
+	account, err := client.CreateAccount(context.Background(), &domain.Account{
+		Username:       username,
+		PasswordDigest: "password-digest",
+		PasswordSalt:   "password-salt",
+	})
+	assert.Must(db.t, err)
+	return account.ID
+}
+
+// createOAuthClient creates an OAuth client owned by an account for rows to
+// refer to, and returns its id.
+func (db *testDB) createOAuthClient(accountID int64) int64 {
+	client := &repo.Client{db.pool}
+	oc := &domain.OAuthClient{
+		AccountID:    accountID,
+		Name:         "Test",
+		RedirectURIs: []string{"https://example.com/callback"},
+	}
+	oc.NewClientID()
+	oc.NewSecret()
+	created, err := client.CreateOAuthClient(context.Background(), oc)
+	assert.Must(db.t, err)
+	return created.ID
+}
+
 // must calls log.Fatal if the error is non-nil.
 func must(err error, msg string) {
 	if err != nil {
@@ -0,0 +1,56 @@
+package repo
+
+import (
+	"context"
+	"time"
+
+	"github.com/deliveroo/todo-api/domain"
+)
+
+// TaskRepo stores tasks. Client implements it with the database, and
+// repotest.Memory in memory, for tests which don't need a database. The
+// methods behave as documented on Client.
+type TaskRepo interface {
+	CreateTask(ctx context.Context, t *domain.Task) (*domain.Task, error)
+	DeleteTaskByIDForAccount(ctx context.Context, taskID, accountID int64) error
+	UpdateTaskForAccount(ctx context.Context, t *domain.Task, accountID int64) (*domain.Task, error)
+	UpdateTaskCompletedForAccount(ctx context.Context, taskID, accountID int64, completed *time.Time) (*domain.Task, error)
+	UpdateTaskAssigneeForAccount(ctx context.Context, taskID, accountID int64, assigneeID *int64) (*domain.Task, error)
+	GetTaskByIDForAccount(ctx context.Context, taskID, accountID int64) (*domain.Task, error)
+	GetTaskByPublicIDForAccount(ctx context.Context, publicID string, accountID int64) (*domain.Task, error)
+	MarkIncompleteTasksCompleteByAccountID(ctx context.Context, accountID int64) (int64, error)
+	CountTasksByAccountID(ctx context.Context, accountID int64) (int64, error)
+	GetTaskStatsByAccountID(ctx context.Context, accountID int64) (*domain.TaskStats, error)
+	GetAllTasksForAccount(ctx context.Context, accountID int64) ([]*domain.Task, error)
+	GetAllTasksByAssigneeIDForAccount(ctx context.Context, assigneeID, accountID int64) ([]*domain.Task, error)
+	GetAllTasksByListIDForAccount(ctx context.Context, listID, accountID int64) ([]*domain.Task, error)
+	GetTasksByIDsForAccount(ctx context.Context, taskIDs []int64, accountID int64) ([]*domain.Task, error)
+	GetAccountIDsForTask(ctx context.Context, t *domain.Task) ([]int64, error)
+}
+
+// AccountRepo stores accounts. Client implements it with the database, and
+// repotest.Memory in memory, for tests which don't need a database. The
+// methods behave as documented on Client.
+type AccountRepo interface {
+	CreateAccount(ctx context.Context, a *domain.Account) (*domain.Account, error)
+	GetAccountByUsername(ctx context.Context, username string) (*domain.Account, error)
+	GetAccountByEmail(ctx context.Context, email string) (*domain.Account, error)
+	GetAccountByID(ctx context.Context, id int64) (*domain.Account, error)
+	GetAccountByPublicID(ctx context.Context, publicID string) (*domain.Account, error)
+	GetAccountsByIDs(ctx context.Context, ids []int64) ([]*domain.Account, error)
+	UpdateAccountTOTP(ctx context.Context, a *domain.Account) (*domain.Account, error)
+	UseTOTPStep(ctx context.Context, accountID, step int64) (bool, error)
+	UpdateAccountPassword(ctx context.Context, a *domain.Account) (*domain.Account, error)
+	UpdateAccountUsername(ctx context.Context, a *domain.Account) (*domain.Account, error)
+	UpdateAccountRole(ctx context.Context, id int64, role string) (*domain.Account, error)
+	SuspendAccount(ctx context.Context, id int64, reason string) (*domain.Account, error)
+	UnsuspendAccount(ctx context.Context, id int64) (*domain.Account, error)
+	SearchAccounts(ctx context.Context, query string, limit, offset int) ([]*domain.Account, error)
+	UpdateAccountEmail(ctx context.Context, a *domain.Account) (*domain.Account, error)
+	DeleteAccount(ctx context.Context, id int64) error
+}
+
+var (
+	_ TaskRepo    = (*Client)(nil)
+	_ AccountRepo = (*Client)(nil)
+)
@@ -0,0 +1,589 @@
+package repotest
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/deliveroo/assert-go"
+	"github.com/deliveroo/todo-api/domain"
+	"github.com/deliveroo/todo-api/repo"
+	"github.com/jackc/pgx/v4"
+)
+
+// Repo stores tasks and accounts.
+type Repo interface {
+	repo.TaskRepo
+	repo.AccountRepo
+}
+
+// Memberships sets up the lists and organisations which decide who can see
+// and change tasks, for the conformance suite.
+type Memberships interface {
+	// CreateList creates a list owned by an account, returning its id.
+	CreateList(ctx context.Context, ownerID int64) (int64, error)
+
+	// AddListMember adds an account to a list with a role, either
+	// domain.ListRoleEditor or domain.ListRoleViewer.
+	AddListMember(ctx context.Context, listID, accountID int64, role string) error
+
+	// CreateOrg creates an organisation owned by an account, returning its
+	// id.
+	CreateOrg(ctx context.Context, ownerID int64) (int64, error)
+
+	// AddOrgMember adds an account to an organisation with a role.
+	AddOrgMember(ctx context.Context, orgID, accountID int64, role string) error
+}
+
+// TestConformance checks that r behaves like the database. It may be run
+// against a repo which already holds data, and leaves the data it creates
+// behind.
+func TestConformance(t *testing.T, r Repo, m Memberships) {
+	c := &conformance{
+		r:      r,
+		m:      m,
+		ctx:    context.Background(),
+		prefix: fmt.Sprintf("conformance%d", time.Now().UnixNano()),
+	}
+	t.Run("accounts", c.testAccounts)
+	t.Run("account updates", c.testAccountUpdates)
+	t.Run("search accounts", c.testSearchAccounts)
+	t.Run("delete account", c.testDeleteAccount)
+	t.Run("tasks", c.testTasks)
+	t.Run("task assignees", c.testTaskAssignees)
+	t.Run("list tasks", c.testListTasks)
+	t.Run("org tasks", c.testOrgTasks)
+	t.Run("task counts", c.testTaskCounts)
+}
+
+type conformance struct {
+	r      Repo
+	m      Memberships
+	ctx    context.Context
+	prefix string
+	n      int
+}
+
+// account creates an account with a unique username and email address.
+func (c *conformance) account(t *testing.T) *domain.Account {
+	c.n++
+	name := fmt.Sprintf("%s-%d", c.prefix, c.n)
+	a, err := c.r.CreateAccount(c.ctx, &domain.Account{
+		Username:       name,
+		Email:          name + "@example.com",
+		PasswordDigest: "password-digest",
+		PasswordSalt:   "password-salt",
+	})
+	assert.Must(t, err)
+	return a
+}
+
+// task creates a task outside any list or organisation.
+func (c *conformance) task(ctx context.Context, t *testing.T, createdBy int64, description string) *domain.Task {
+	task, err := c.r.CreateTask(ctx, &domain.Task{CreatedBy: createdBy, Description: description})
+	assert.Must(t, err)
+	return task
+}
+
+func (c *conformance) testAccounts(t *testing.T) {
+	a := c.account(t)
+	assert.True(t, a.ID != 0)
+	assert.True(t, domain.ValidPublicID(a.PublicID))
+	assert.Equal(t, a.Role, domain.RoleUser)
+	assert.False(t, a.Created.IsZero())
+	assert.Nil(t, a.SuspendedAt)
+
+	_, err := c.r.CreateAccount(c.ctx, &domain.Account{Username: a.Username, Email: "other-" + a.Email})
+	assert.Equal(t, err, repo.ErrUsernameTaken)
+	_, err = c.r.CreateAccount(c.ctx, &domain.Account{Username: strings.ToUpper(a.Username), Email: "other-" + a.Email})
+	assert.Equal(t, err, repo.ErrUsernameTaken)
+
+	// Only verified email addresses are unique.
+	verifiedAt := time.Date(2026, 10, 19, 12, 0, 0, 0, time.UTC)
+	verified, err := c.r.CreateAccount(c.ctx, &domain.Account{Username: "other-" + a.Username, Email: "Other-" + a.Email, VerifiedAt: &verifiedAt})
+	assert.Must(t, err)
+	_, err = c.r.CreateAccount(c.ctx, &domain.Account{Username: "upper-" + a.Username, Email: "OTHER-" + a.Email, VerifiedAt: &verifiedAt})
+	assert.Equal(t, err, repo.ErrEmailTaken)
+	_, err = c.r.CreateAccount(c.ctx, &domain.Account{Username: "unverified-" + a.Username, Email: "OTHER-" + a.Email})
+	assert.Must(t, err)
+	_, err = c.r.CreateAccount(c.ctx, &domain.Account{Username: "unverified-2-" + a.Username, Email: a.Email})
+	assert.Must(t, err)
+
+	// Any number of accounts may have no email address.
+	for _, username := range []string{"no-email-1-" + a.Username, "no-email-2-" + a.Username} {
+		_, err := c.r.CreateAccount(c.ctx, &domain.Account{Username: username})
+		assert.Must(t, err)
+	}
+
+	got, err := c.r.GetAccountByID(c.ctx, a.ID)
+	assert.Must(t, err)
+	assert.Equal(t, got, a)
+	got, err = c.r.GetAccountByUsername(c.ctx, a.Username)
+	assert.Must(t, err)
+	assert.Equal(t, got, a)
+	got, err = c.r.GetAccountByUsername(c.ctx, strings.ToUpper(a.Username))
+	assert.Must(t, err)
+	assert.Equal(t, got, a)
+	got, err = c.r.GetAccountByEmail(c.ctx, "  "+a.Email)
+	assert.Must(t, err)
+	assert.Nil(t, got)
+	got, err = c.r.GetAccountByEmail(c.ctx, a.Username+"@EXAMPLE.com")
+	assert.Must(t, err)
+	assert.Nil(t, got)
+	got, err = c.r.GetAccountByEmail(c.ctx, "other-"+a.Username+"@EXAMPLE.com")
+	assert.Must(t, err)
+	assert.Equal(t, got, verified)
+	got, err = c.r.GetAccountByEmail(c.ctx, "")
+	assert.Must(t, err)
+	assert.Nil(t, got)
+	got, err = c.r.GetAccountByUsername(c.ctx, "missing-"+a.Username)
+	assert.Must(t, err)
+	assert.Nil(t, got)
+	got, err = c.r.GetAccountByID(c.ctx, -1)
+	assert.Must(t, err)
+	assert.Nil(t, got)
+	got, err = c.r.GetAccountByPublicID(c.ctx, a.PublicID)
+	assert.Must(t, err)
+	assert.Equal(t, got, a)
+	got, err = c.r.GetAccountByPublicID(c.ctx, domain.NewPublicID())
+	assert.Must(t, err)
+	assert.Nil(t, got)
+
+	b := c.account(t)
+	assert.True(t, b.PublicID != a.PublicID)
+	accounts, err := c.r.GetAccountsByIDs(c.ctx, []int64{b.ID, -1, a.ID})
+	assert.Must(t, err)
+	assert.Equal(t, accounts, []*domain.Account{a, b})
+	accounts, err = c.r.GetAccountsByIDs(c.ctx, []int64{-1})
+	assert.Must(t, err)
+	assert.Equal(t, len(accounts), 0)
+}
+
+func (c *conformance) testAccountUpdates(t *testing.T) {
+	a := c.account(t)
+	other := c.account(t)
+	missing := &domain.Account{ID: -1, Username: "missing-" + a.Username}
+
+	enabled := time.Date(2026, 10, 19, 12, 0, 0, 123456789, time.UTC)
+	a.TOTPSecret = "totp-secret"
+	a.TOTPEnabled = &enabled
+	updated, err := c.r.UpdateAccountTOTP(c.ctx, a)
+	assert.Must(t, err)
+	assert.Equal(t, updated.TOTPSecret, "totp-secret")
+	assert.Equal(t, *updated.TOTPEnabled, enabled.Truncate(time.Microsecond))
+	_, err = c.r.UpdateAccountTOTP(c.ctx, missing)
+	assert.Equal(t, err, pgx.ErrNoRows)
+
+	// Each TOTP step can only be used once, and only after the last one.
+	ok, err := c.r.UseTOTPStep(c.ctx, a.ID, 1000)
+	assert.Must(t, err)
+	assert.True(t, ok)
+	ok, err = c.r.UseTOTPStep(c.ctx, a.ID, 1000)
+	assert.Must(t, err)
+	assert.False(t, ok)
+	ok, err = c.r.UseTOTPStep(c.ctx, a.ID, 999)
+	assert.Must(t, err)
+	assert.False(t, ok)
+	ok, err = c.r.UseTOTPStep(c.ctx, a.ID, 1001)
+	assert.Must(t, err)
+	assert.True(t, ok)
+	ok, err = c.r.UseTOTPStep(c.ctx, other.ID, 1000)
+	assert.Must(t, err)
+	assert.True(t, ok)
+
+	a.PasswordDigest = "new-digest"
+	a.PasswordSalt = "new-salt"
+	updated, err = c.r.UpdateAccountPassword(c.ctx, a)
+	assert.Must(t, err)
+	assert.Equal(t, updated.PasswordDigest, "new-digest")
+	assert.Equal(t, updated.PasswordSalt, "new-salt")
+	_, err = c.r.UpdateAccountPassword(c.ctx, missing)
+	assert.Equal(t, err, pgx.ErrNoRows)
+
+	_, err = c.r.UpdateAccountUsername(c.ctx, &domain.Account{ID: a.ID, Username: other.Username})
+	assert.Equal(t, err, repo.ErrUsernameTaken)
+	updated, err = c.r.UpdateAccountUsername(c.ctx, &domain.Account{ID: a.ID, Username: "renamed-" + a.Username})
+	assert.Must(t, err)
+	assert.Equal(t, updated.Username, "renamed-"+a.Username)
+	_, err = c.r.UpdateAccountUsername(c.ctx, missing)
+	assert.Equal(t, err, pgx.ErrNoRows)
+
+	updated, err = c.r.UpdateAccountEmail(c.ctx, &domain.Account{ID: a.ID, Email: other.Email})
+	assert.Must(t, err)
+	assert.Equal(t, updated.Email, other.Email)
+	updated, err = c.r.UpdateAccountEmail(c.ctx, &domain.Account{ID: a.ID, Email: "new-" + a.Email})
+	assert.Must(t, err)
+	assert.Equal(t, updated.Email, "new-"+a.Email)
+	assert.Nil(t, updated.VerifiedAt)
+	_, err = c.r.UpdateAccountEmail(c.ctx, &domain.Account{ID: -1, Email: "missing-" + a.Email})
+	assert.Equal(t, err, pgx.ErrNoRows)
+
+	updated, err = c.r.UpdateAccountRole(c.ctx, a.ID, domain.RoleAdmin)
+	assert.Must(t, err)
+	assert.Equal(t, updated.Role, domain.RoleAdmin)
+	updated, err = c.r.UpdateAccountRole(c.ctx, -1, domain.RoleAdmin)
+	assert.Must(t, err)
+	assert.Nil(t, updated)
+
+	suspended, err := c.r.SuspendAccount(c.ctx, a.ID, "spam")
+	assert.Must(t, err)
+	assert.NotNil(t, suspended.SuspendedAt)
+	assert.Equal(t, suspended.SuspensionReason, "spam")
+	again, err := c.r.SuspendAccount(c.ctx, a.ID, "more spam")
+	assert.Must(t, err)
+	assert.Equal(t, again.SuspendedAt, suspended.SuspendedAt)
+	assert.Equal(t, again.SuspensionReason, "more spam")
+	unsuspended, err := c.r.UnsuspendAccount(c.ctx, a.ID)
+	assert.Must(t, err)
+	assert.Nil(t, unsuspended.SuspendedAt)
+	assert.Equal(t, unsuspended.SuspensionReason, "")
+	none, err := c.r.SuspendAccount(c.ctx, -1, "spam")
+	assert.Must(t, err)
+	assert.Nil(t, none)
+	none, err = c.r.UnsuspendAccount(c.ctx, -1)
+	assert.Must(t, err)
+	assert.Nil(t, none)
+
+	got, err := c.r.GetAccountByID(c.ctx, a.ID)
+	assert.Must(t, err)
+	assert.Equal(t, got, unsuspended)
+}
+
+func (c *conformance) testSearchAccounts(t *testing.T) {
+	query := fmt.Sprintf("%s-search", c.prefix)
+	var want []*domain.Account
+	for i := 0; i < 3; i++ {
+		a, err := c.r.CreateAccount(c.ctx, &domain.Account{Username: fmt.Sprintf("%s-%d", query, i)})
+		assert.Must(t, err)
+		want = append(want, a)
+	}
+	byEmail, err := c.r.CreateAccount(c.ctx, &domain.Account{Username: c.prefix + "-by-email", Email: query + "@example.com"})
+	assert.Must(t, err)
+	want = append(want, byEmail)
+
+	got, err := c.r.SearchAccounts(c.ctx, "  "+query, 10, 0)
+	assert.Must(t, err)
+	assert.Equal(t, len(got), 0)
+	got, err = c.r.SearchAccounts(c.ctx, fmt.Sprintf("%s-SEARCH", c.prefix), 10, 0)
+	assert.Must(t, err)
+	assert.Equal(t, got, want)
+	got, err = c.r.SearchAccounts(c.ctx, query, 2, 1)
+	assert.Must(t, err)
+	assert.Equal(t, got, want[1:3])
+	got, err = c.r.SearchAccounts(c.ctx, query, 10, 4)
+	assert.Must(t, err)
+	assert.Equal(t, len(got), 0)
+}
+
+func (c *conformance) testDeleteAccount(t *testing.T) {
+	a := c.account(t)
+	other := c.account(t)
+	own := c.task(c.ctx, t, a.ID, "own task")
+	assigned := c.task(c.ctx, t, other.ID, "assigned task")
+	_, err := c.r.UpdateTaskAssigneeForAccount(c.ctx, assigned.ID, other.ID, &a.ID)
+	assert.Must(t, err)
+	listID, err := c.m.CreateList(c.ctx, a.ID)
+	assert.Must(t, err)
+	assert.Must(t, c.m.AddListMember(c.ctx, listID, other.ID, domain.ListRoleEditor))
+	inList, err := c.r.CreateTask(c.ctx, &domain.Task{CreatedBy: other.ID, ListID: &listID, Description: "list task"})
+	assert.Must(t, err)
+	orgID, err := c.m.CreateOrg(c.ctx, other.ID)
+	assert.Must(t, err)
+	assert.Must(t, c.m.AddOrgMember(c.ctx, orgID, a.ID, domain.OrgRoleMember))
+	orgCtx := repo.WithOrgID(c.ctx, orgID)
+	inOrg := c.task(orgCtx, t, a.ID, "org task")
+	ownOrgID, err := c.m.CreateOrg(c.ctx, a.ID)
+	assert.Must(t, err)
+	inOwnOrg := c.task(repo.WithOrgID(c.ctx, ownOrgID), t, a.ID, "own org task")
+
+	// Organisations with other members must be handed over first.
+	owner := c.account(t)
+	sharedOrgID, err := c.m.CreateOrg(c.ctx, owner.ID)
+	assert.Must(t, err)
+	assert.Must(t, c.m.AddOrgMember(c.ctx, sharedOrgID, other.ID, domain.OrgRoleMember))
+	assert.Equal(t, c.r.DeleteAccount(c.ctx, owner.ID), repo.ErrOwnsSharedOrg)
+	got, err := c.r.GetAccountByID(c.ctx, owner.ID)
+	assert.Must(t, err)
+	assert.NotNil(t, got)
+
+	assert.Must(t, c.r.DeleteAccount(c.ctx, a.ID))
+	assert.Equal(t, c.r.DeleteAccount(c.ctx, a.ID), pgx.ErrNoRows)
+	got, err = c.r.GetAccountByID(c.ctx, a.ID)
+	assert.Must(t, err)
+	assert.Nil(t, got)
+	task, err := c.r.GetTaskByIDForAccount(c.ctx, own.ID, a.ID)
+	assert.Must(t, err)
+	assert.Nil(t, task)
+	task, err = c.r.GetTaskByIDForAccount(c.ctx, assigned.ID, other.ID)
+	assert.Must(t, err)
+	assert.Nil(t, task.AssigneeID)
+	task, err = c.r.GetTaskByIDForAccount(c.ctx, inList.ID, other.ID)
+	assert.Must(t, err)
+	assert.Nil(t, task)
+	// Tasks in other organisations are handed to the organisation's owner.
+	task, err = c.r.GetTaskByIDForAccount(orgCtx, inOrg.ID, other.ID)
+	assert.Must(t, err)
+	assert.Equal(t, task.AccountID, other.ID)
+	assert.Equal(t, task.CreatedBy, a.ID)
+	// Organisations without other members are deleted with their tasks.
+	task, err = c.r.GetTaskByIDForAccount(repo.WithOrgID(c.ctx, ownOrgID), inOwnOrg.ID, a.ID)
+	assert.Must(t, err)
+	assert.Nil(t, task)
+	stats, err := c.r.GetTaskStatsByAccountID(c.ctx, a.ID)
+	assert.Must(t, err)
+	assert.Equal(t, stats.Total, int64(0))
+}
+
+func (c *conformance) testTasks(t *testing.T) {
+	a := c.account(t)
+	other := c.account(t)
+	completed := time.Date(2026, 10, 19, 12, 0, 0, 123456789, time.FixedZone("CEST", 2*60*60))
+	first, err := c.r.CreateTask(c.ctx, &domain.Task{
+		AccountID:   other.ID, // ignored
+		CreatedBy:   a.ID,
+		Description: "first",
+		Completed:   &completed,
+	})
+	assert.Must(t, err)
+	assert.True(t, first.ID != 0)
+	assert.True(t, domain.ValidPublicID(first.PublicID))
+	assert.Equal(t, first.AccountID, a.ID)
+	assert.Equal(t, first.CreatedBy, a.ID)
+	assert.False(t, first.Created.IsZero())
+	// Timestamps are read back in UTC, to the microsecond.
+	assert.Equal(t, *first.Completed, time.Date(2026, 10, 19, 10, 0, 0, 123456000, time.UTC))
+	second := c.task(c.ctx, t, a.ID, "second")
+
+	got, err := c.r.GetTaskByIDForAccount(c.ctx, first.ID, a.ID)
+	assert.Must(t, err)
+	assert.Equal(t, got, first)
+	got, err = c.r.GetTaskByIDForAccount(c.ctx, first.ID, other.ID)
+	assert.Must(t, err)
+	assert.Nil(t, got)
+	got, err = c.r.GetTaskByIDForAccount(c.ctx, -1, a.ID)
+	assert.Must(t, err)
+	assert.Nil(t, got)
+	got, err = c.r.GetTaskByPublicIDForAccount(c.ctx, first.PublicID, a.ID)
+	assert.Must(t, err)
+	assert.Equal(t, got, first)
+	got, err = c.r.GetTaskByPublicIDForAccount(c.ctx, first.PublicID, other.ID)
+	assert.Must(t, err)
+	assert.Nil(t, got)
+	got, err = c.r.GetTaskByPublicIDForAccount(c.ctx, domain.NewPublicID(), a.ID)
+	assert.Must(t, err)
+	assert.Nil(t, got)
+
+	all, err := c.r.GetAllTasksForAccount(c.ctx, a.ID)
+	assert.Must(t, err)
+	assert.Equal(t, all, []*domain.Task{second, first})
+	all, err = c.r.GetAllTasksForAccount(c.ctx, other.ID)
+	assert.Must(t, err)
+	assert.Equal(t, len(all), 0)
+	byIDs, err := c.r.GetTasksByIDsForAccount(c.ctx, []int64{first.ID, -1, second.ID}, a.ID)
+	assert.Must(t, err)
+	assert.Equal(t, byIDs, []*domain.Task{second, first})
+
+	_, err = c.r.UpdateTaskForAccount(c.ctx, &domain.Task{ID: first.ID, Description: "stolen"}, other.ID)
+	assert.Equal(t, err, pgx.ErrNoRows)
+	_, err = c.r.UpdateTaskForAccount(c.ctx, &domain.Task{ID: -1, Description: "missing"}, a.ID)
+	assert.Equal(t, err, pgx.ErrNoRows)
+	updated, err := c.r.UpdateTaskForAccount(c.ctx, &domain.Task{ID: first.ID, Description: "first, again"}, a.ID)
+	assert.Must(t, err)
+	assert.Equal(t, updated.Description, "first, again")
+	assert.Nil(t, updated.Completed)
+	assert.Equal(t, updated.Created, first.Created)
+
+	updated, err = c.r.UpdateTaskCompletedForAccount(c.ctx, first.ID, a.ID, &completed)
+	assert.Must(t, err)
+	assert.Equal(t, updated.Description, "first, again")
+	assert.NotNil(t, updated.Completed)
+	_, err = c.r.UpdateTaskCompletedForAccount(c.ctx, first.ID, other.ID, nil)
+	assert.Equal(t, err, pgx.ErrNoRows)
+
+	assert.Equal(t, c.r.DeleteTaskByIDForAccount(c.ctx, first.ID, other.ID), pgx.ErrNoRows)
+	assert.Must(t, c.r.DeleteTaskByIDForAccount(c.ctx, first.ID, a.ID))
+	assert.Equal(t, c.r.DeleteTaskByIDForAccount(c.ctx, first.ID, a.ID), pgx.ErrNoRows)
+	all, err = c.r.GetAllTasksForAccount(c.ctx, a.ID)
+	assert.Must(t, err)
+	assert.Equal(t, all, []*domain.Task{second})
+}
+
+func (c *conformance) testTaskAssignees(t *testing.T) {
+	owner := c.account(t)
+	assignee := c.account(t)
+	other := c.account(t)
+	task := c.task(c.ctx, t, owner.ID, "assigned")
+
+	_, err := c.r.UpdateTaskAssigneeForAccount(c.ctx, task.ID, assignee.ID, &assignee.ID)
+	assert.Equal(t, err, pgx.ErrNoRows)
+	assigned, err := c.r.UpdateTaskAssigneeForAccount(c.ctx, task.ID, owner.ID, &assignee.ID)
+	assert.Must(t, err)
+	assert.Equal(t, *assigned.AssigneeID, assignee.ID)
+
+	got, err := c.r.GetTaskByIDForAccount(c.ctx, task.ID, assignee.ID)
+	assert.Must(t, err)
+	assert.Equal(t, got, assigned)
+	byAssignee, err := c.r.GetAllTasksByAssigneeIDForAccount(c.ctx, assignee.ID, assignee.ID)
+	assert.Must(t, err)
+	assert.Equal(t, byAssignee, []*domain.Task{assigned})
+	byAssignee, err = c.r.GetAllTasksByAssigneeIDForAccount(c.ctx, assignee.ID, other.ID)
+	assert.Must(t, err)
+	assert.Equal(t, len(byAssignee), 0)
+	ids, err := c.r.GetAccountIDsForTask(c.ctx, assigned)
+	assert.Must(t, err)
+	assert.Equal(t, ids, []int64{owner.ID, assignee.ID})
+
+	// The assignee may only mark the task complete or incomplete.
+	_, err = c.r.UpdateTaskForAccount(c.ctx, &domain.Task{ID: task.ID, Description: "changed"}, assignee.ID)
+	assert.Equal(t, err, pgx.ErrNoRows)
+	completed := time.Now()
+	updated, err := c.r.UpdateTaskCompletedForAccount(c.ctx, task.ID, assignee.ID, &completed)
+	assert.Must(t, err)
+	assert.NotNil(t, updated.Completed)
+	assert.Equal(t, c.r.DeleteTaskByIDForAccount(c.ctx, task.ID, assignee.ID), pgx.ErrNoRows)
+
+	unassigned, err := c.r.UpdateTaskAssigneeForAccount(c.ctx, task.ID, owner.ID, nil)
+	assert.Must(t, err)
+	assert.Nil(t, unassigned.AssigneeID)
+	got, err = c.r.GetTaskByIDForAccount(c.ctx, task.ID, assignee.ID)
+	assert.Must(t, err)
+	assert.Nil(t, got)
+}
+
+func (c *conformance) testListTasks(t *testing.T) {
+	owner := c.account(t)
+	editor := c.account(t)
+	viewer := c.account(t)
+	outsider := c.account(t)
+	listID, err := c.m.CreateList(c.ctx, owner.ID)
+	assert.Must(t, err)
+	assert.Must(t, c.m.AddListMember(c.ctx, listID, editor.ID, domain.ListRoleEditor))
+	assert.Must(t, c.m.AddListMember(c.ctx, listID, viewer.ID, domain.ListRoleViewer))
+
+	for _, a := range []*domain.Account{viewer, outsider} {
+		_, err := c.r.CreateTask(c.ctx, &domain.Task{CreatedBy: a.ID, ListID: &listID, Description: "not allowed"})
+		assert.Equal(t, err, pgx.ErrNoRows)
+	}
+	task, err := c.r.CreateTask(c.ctx, &domain.Task{CreatedBy: editor.ID, ListID: &listID, Description: "in a list"})
+	assert.Must(t, err)
+	// Tasks in a list are owned by the list's owner.
+	assert.Equal(t, task.AccountID, owner.ID)
+	assert.Equal(t, task.CreatedBy, editor.ID)
+	assert.Equal(t, *task.ListID, listID)
+	assert.True(t, domain.ValidPublicID(*task.ListPublicID))
+
+	for _, a := range []*domain.Account{owner, editor, viewer} {
+		tasks, err := c.r.GetAllTasksByListIDForAccount(c.ctx, listID, a.ID)
+		assert.Must(t, err)
+		assert.Equal(t, tasks, []*domain.Task{task})
+	}
+	tasks, err := c.r.GetAllTasksByListIDForAccount(c.ctx, listID, outsider.ID)
+	assert.Must(t, err)
+	assert.Equal(t, len(tasks), 0)
+	got, err := c.r.GetTaskByIDForAccount(c.ctx, task.ID, outsider.ID)
+	assert.Must(t, err)
+	assert.Nil(t, got)
+
+	_, err = c.r.UpdateTaskForAccount(c.ctx, &domain.Task{ID: task.ID, Description: "viewed"}, viewer.ID)
+	assert.Equal(t, err, pgx.ErrNoRows)
+	updated, err := c.r.UpdateTaskForAccount(c.ctx, &domain.Task{ID: task.ID, Description: "edited"}, editor.ID)
+	assert.Must(t, err)
+	assert.Equal(t, updated.Description, "edited")
+
+	ids, err := c.r.GetAccountIDsForTask(c.ctx, task)
+	assert.Must(t, err)
+	assert.Equal(t, ids, []int64{owner.ID, editor.ID, viewer.ID})
+}
+
+func (c *conformance) testOrgTasks(t *testing.T) {
+	owner := c.account(t)
+	member := c.account(t)
+	outsider := c.account(t)
+	orgID, err := c.m.CreateOrg(c.ctx, owner.ID)
+	assert.Must(t, err)
+	assert.Must(t, c.m.AddOrgMember(c.ctx, orgID, member.ID, domain.OrgRoleMember))
+	orgCtx := repo.WithOrgID(c.ctx, orgID)
+
+	personal := c.task(c.ctx, t, member.ID, "personal")
+	_, err = c.r.CreateTask(orgCtx, &domain.Task{CreatedBy: outsider.ID, Description: "not allowed"})
+	assert.Equal(t, err, pgx.ErrNoRows)
+	listID, err := c.m.CreateList(c.ctx, member.ID)
+	assert.Must(t, err)
+	_, err = c.r.CreateTask(orgCtx, &domain.Task{CreatedBy: member.ID, ListID: &listID, Description: "not allowed"})
+	assert.Equal(t, err, pgx.ErrNoRows)
+	task := c.task(orgCtx, t, member.ID, "in an organisation")
+	assert.Equal(t, *task.OrgID, orgID)
+
+	// Tasks are scoped to the organisation, or to tasks outside any.
+	tasks, err := c.r.GetAllTasksForAccount(orgCtx, owner.ID)
+	assert.Must(t, err)
+	assert.Equal(t, tasks, []*domain.Task{task})
+	tasks, err = c.r.GetAllTasksForAccount(c.ctx, member.ID)
+	assert.Must(t, err)
+	assert.Equal(t, tasks, []*domain.Task{personal})
+	got, err := c.r.GetTaskByIDForAccount(c.ctx, task.ID, member.ID)
+	assert.Must(t, err)
+	assert.Nil(t, got)
+	got, err = c.r.GetTaskByIDForAccount(orgCtx, personal.ID, member.ID)
+	assert.Must(t, err)
+	assert.Nil(t, got)
+	got, err = c.r.GetTaskByIDForAccount(orgCtx, task.ID, outsider.ID)
+	assert.Must(t, err)
+	assert.Nil(t, got)
+
+	// Every member can change the organisation's tasks.
+	updated, err := c.r.UpdateTaskForAccount(orgCtx, &domain.Task{ID: task.ID, Description: "edited"}, owner.ID)
+	assert.Must(t, err)
+	assert.Equal(t, updated.Description, "edited")
+	_, err = c.r.UpdateTaskForAccount(c.ctx, &domain.Task{ID: task.ID, Description: "out of scope"}, owner.ID)
+	assert.Equal(t, err, pgx.ErrNoRows)
+
+	ids, err := c.r.GetAccountIDsForTask(c.ctx, task)
+	assert.Must(t, err)
+	assert.Equal(t, ids, []int64{owner.ID, member.ID})
+
+	assert.Equal(t, c.r.DeleteTaskByIDForAccount(c.ctx, task.ID, owner.ID), pgx.ErrNoRows)
+	assert.Must(t, c.r.DeleteTaskByIDForAccount(orgCtx, task.ID, owner.ID))
+}
+
+func (c *conformance) testTaskCounts(t *testing.T) {
+	a := c.account(t)
+	orgID, err := c.m.CreateOrg(c.ctx, a.ID)
+	assert.Must(t, err)
+	orgCtx := repo.WithOrgID(c.ctx, orgID)
+
+	stats, err := c.r.GetTaskStatsByAccountID(c.ctx, a.ID)
+	assert.Must(t, err)
+	assert.Equal(t, stats, &domain.TaskStats{})
+
+	c.task(c.ctx, t, a.ID, "one")
+	last := c.task(c.ctx, t, a.ID, "two")
+	inOrg := c.task(orgCtx, t, a.ID, "three")
+
+	// Counts include tasks in organisations, whatever the current one.
+	count, err := c.r.CountTasksByAccountID(orgCtx, a.ID)
+	assert.Must(t, err)
+	assert.Equal(t, count, int64(3))
+	stats, err = c.r.GetTaskStatsByAccountID(c.ctx, a.ID)
+	assert.Must(t, err)
+	assert.Equal(t, stats.Total, int64(3))
+	assert.Equal(t, stats.Completed, int64(0))
+	assert.Equal(t, *stats.LastCreated, inOrg.Created)
+	assert.False(t, stats.LastCreated.Before(last.Created))
+
+	// Marking tasks complete is scoped to the current organisation.
+	n, err := c.r.MarkIncompleteTasksCompleteByAccountID(c.ctx, a.ID)
+	assert.Must(t, err)
+	assert.Equal(t, n, int64(2))
+	n, err = c.r.MarkIncompleteTasksCompleteByAccountID(c.ctx, a.ID)
+	assert.Must(t, err)
+	assert.Equal(t, n, int64(0))
+	stats, err = c.r.GetTaskStatsByAccountID(c.ctx, a.ID)
+	assert.Must(t, err)
+	assert.Equal(t, stats.Completed, int64(2))
+	got, err := c.r.GetTaskByIDForAccount(orgCtx, inOrg.ID, a.ID)
+	assert.Must(t, err)
+	assert.Nil(t, got.Completed)
+}
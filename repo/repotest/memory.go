@@ -0,0 +1,780 @@
+// Package repotest provides an in-memory implementation of the repo
+// interfaces, for testing code which uses them without a database, and a
+// conformance suite which checks that an implementation behaves like the
+// database.
+package repotest
+
+import (
+	"context"
+	"errors"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/deliveroo/todo-api/domain"
+	"github.com/deliveroo/todo-api/repo"
+	"github.com/jackc/pgx/v4"
+)
+
+// Memory stores tasks and accounts in memory. It implements repo.TaskRepo and
+// repo.AccountRepo with the same semantics as repo.Client, including scoping
+// tasks to the organisation set by repo.WithOrgID, but without recording
+// changes for sync or writing events to the outbox. Lists and organisations
+// aren't stored by those interfaces, so the memberships which let accounts
+// see and change tasks in them are set up with the Memberships methods.
+//
+// Memory is safe for concurrent use.
+type Memory struct {
+	mu            sync.Mutex
+	accounts      map[int64]*domain.Account
+	tasks         map[int64]*domain.Task
+	lists         map[int64]int64            // list id to owner id
+	listPublicIDs map[int64]string           // list id to public id
+	listMembers   map[int64]map[int64]string // list id to member id to role
+	orgMembers    map[int64]map[int64]string // org id to member id to role
+	totpSteps     map[int64]int64            // account id to last TOTP step used
+	lastAccountID int64
+	lastListID    int64
+	lastOrgID     int64
+	lastTaskID    int64
+}
+
+var (
+	_ repo.TaskRepo    = (*Memory)(nil)
+	_ repo.AccountRepo = (*Memory)(nil)
+	_ Memberships      = (*Memory)(nil)
+)
+
+// NewMemory returns an empty in-memory repo.
+func NewMemory() *Memory {
+	return &Memory{
+		accounts:      make(map[int64]*domain.Account),
+		tasks:         make(map[int64]*domain.Task),
+		lists:         make(map[int64]int64),
+		listPublicIDs: make(map[int64]string),
+		listMembers:   make(map[int64]map[int64]string),
+		orgMembers:    make(map[int64]map[int64]string),
+		totpSteps:     make(map[int64]int64),
+	}
+}
+
+// CreateList implements the Memberships interface.
+func (m *Memory) CreateList(ctx context.Context, ownerID int64) (int64, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.lastListID++
+	m.lists[m.lastListID] = ownerID
+	m.listPublicIDs[m.lastListID] = domain.NewPublicID()
+	return m.lastListID, nil
+}
+
+// AddListMember implements the Memberships interface.
+func (m *Memory) AddListMember(ctx context.Context, listID, accountID int64, role string) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if m.listMembers[listID] == nil {
+		m.listMembers[listID] = make(map[int64]string)
+	}
+	m.listMembers[listID][accountID] = role
+	return nil
+}
+
+// CreateOrg implements the Memberships interface.
+func (m *Memory) CreateOrg(ctx context.Context, ownerID int64) (int64, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.lastOrgID++
+	m.orgMembers[m.lastOrgID] = map[int64]string{ownerID: domain.OrgRoleOwner}
+	return m.lastOrgID, nil
+}
+
+// AddOrgMember implements the Memberships interface.
+func (m *Memory) AddOrgMember(ctx context.Context, orgID, accountID int64, role string) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if m.orgMembers[orgID] == nil {
+		m.orgMembers[orgID] = make(map[int64]string)
+	}
+	m.orgMembers[orgID][accountID] = role
+	return nil
+}
+
+// CreateTask implements the repo.TaskRepo interface.
+func (m *Memory) CreateTask(ctx context.Context, t *domain.Task) (*domain.Task, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	orgID := currentOrg(ctx)
+	switch {
+	case orgID == nil && t.ListID == nil:
+	case orgID == nil && domain.CanEditList(m.listRole(*t.ListID, t.CreatedBy)):
+	case orgID != nil && t.ListID == nil && m.isOrgMember(*orgID, t.CreatedBy):
+	default:
+		return nil, pgx.ErrNoRows
+	}
+	m.lastTaskID++
+	created := &domain.Task{
+		ID:          m.lastTaskID,
+		PublicID:    domain.NewPublicID(),
+		AccountID:   t.CreatedBy,
+		AssigneeID:  copyInt64(t.AssigneeID),
+		Completed:   normalizeTime(t.Completed),
+		Created:     now(),
+		CreatedBy:   t.CreatedBy,
+		Description: t.Description,
+		ListID:      copyInt64(t.ListID),
+		OrgID:       orgID,
+	}
+	if t.ListID != nil {
+		if ownerID, ok := m.lists[*t.ListID]; ok {
+			created.AccountID = ownerID
+		}
+		publicID := m.listPublicIDs[*t.ListID]
+		created.ListPublicID = &publicID
+	}
+	m.tasks[created.ID] = created
+	return copyTask(created), nil
+}
+
+// DeleteTaskByIDForAccount implements the repo.TaskRepo interface.
+func (m *Memory) DeleteTaskByIDForAccount(ctx context.Context, taskID, accountID int64) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	t := m.tasks[taskID]
+	if t == nil || !m.taskEditable(ctx, t, accountID) {
+		return pgx.ErrNoRows
+	}
+	delete(m.tasks, taskID)
+	return nil
+}
+
+// UpdateTaskForAccount implements the repo.TaskRepo interface.
+func (m *Memory) UpdateTaskForAccount(ctx context.Context, t *domain.Task, accountID int64) (*domain.Task, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	stored := m.tasks[t.ID]
+	if stored == nil || !m.taskEditable(ctx, stored, accountID) {
+		return nil, pgx.ErrNoRows
+	}
+	stored.Description = t.Description
+	stored.Completed = normalizeTime(t.Completed)
+	return copyTask(stored), nil
+}
+
+// UpdateTaskCompletedForAccount implements the repo.TaskRepo interface.
+func (m *Memory) UpdateTaskCompletedForAccount(ctx context.Context, taskID, accountID int64, completed *time.Time) (*domain.Task, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	stored := m.tasks[taskID]
+	if stored == nil {
+		return nil, pgx.ErrNoRows
+	}
+	if !m.taskEditable(ctx, stored, accountID) && !(m.taskVisible(ctx, stored, accountID) && stored.AssignedTo(accountID)) {
+		return nil, pgx.ErrNoRows
+	}
+	stored.Completed = normalizeTime(completed)
+	return copyTask(stored), nil
+}
+
+// UpdateTaskAssigneeForAccount implements the repo.TaskRepo interface.
+func (m *Memory) UpdateTaskAssigneeForAccount(ctx context.Context, taskID, accountID int64, assigneeID *int64) (*domain.Task, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	stored := m.tasks[taskID]
+	if stored == nil || !m.taskEditable(ctx, stored, accountID) {
+		return nil, pgx.ErrNoRows
+	}
+	stored.AssigneeID = copyInt64(assigneeID)
+	return copyTask(stored), nil
+}
+
+// GetTaskByIDForAccount implements the repo.TaskRepo interface.
+func (m *Memory) GetTaskByIDForAccount(ctx context.Context, taskID, accountID int64) (*domain.Task, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	t := m.tasks[taskID]
+	if t == nil || !m.taskVisible(ctx, t, accountID) {
+		return nil, nil
+	}
+	return copyTask(t), nil
+}
+
+// GetTaskByPublicIDForAccount implements the repo.TaskRepo interface.
+func (m *Memory) GetTaskByPublicIDForAccount(ctx context.Context, publicID string, accountID int64) (*domain.Task, error) {
+	tasks := m.findTasks(ctx, accountID, func(t *domain.Task) bool {
+		return t.PublicID == publicID
+	})
+	if len(tasks) == 0 {
+		return nil, nil
+	}
+	return tasks[0], nil
+}
+
+// MarkIncompleteTasksCompleteByAccountID implements the repo.TaskRepo
+// interface.
+func (m *Memory) MarkIncompleteTasksCompleteByAccountID(ctx context.Context, accountID int64) (int64, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	completed := now()
+	var n int64
+	for _, t := range m.tasks {
+		if t.AccountID == accountID && t.Completed == nil && inScope(ctx, t) {
+			t.Completed = normalizeTime(&completed)
+			n++
+		}
+	}
+	return n, nil
+}
+
+// CountTasksByAccountID implements the repo.TaskRepo interface.
+func (m *Memory) CountTasksByAccountID(ctx context.Context, accountID int64) (int64, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	var n int64
+	for _, t := range m.tasks {
+		if t.CreatedBy == accountID {
+			n++
+		}
+	}
+	return n, nil
+}
+
+// GetTaskStatsByAccountID implements the repo.TaskRepo interface.
+func (m *Memory) GetTaskStatsByAccountID(ctx context.Context, accountID int64) (*domain.TaskStats, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	var result domain.TaskStats
+	for _, t := range m.tasks {
+		if t.AccountID != accountID {
+			continue
+		}
+		result.Total++
+		if t.Completed != nil {
+			result.Completed++
+		}
+		if result.LastCreated == nil || t.Created.After(*result.LastCreated) {
+			result.LastCreated = normalizeTime(&t.Created)
+		}
+	}
+	return &result, nil
+}
+
+// GetAllTasksForAccount implements the repo.TaskRepo interface.
+func (m *Memory) GetAllTasksForAccount(ctx context.Context, accountID int64) ([]*domain.Task, error) {
+	return m.findTasks(ctx, accountID, func(t *domain.Task) bool {
+		return true
+	}), nil
+}
+
+// GetAllTasksByAssigneeIDForAccount implements the repo.TaskRepo interface.
+func (m *Memory) GetAllTasksByAssigneeIDForAccount(ctx context.Context, assigneeID, accountID int64) ([]*domain.Task, error) {
+	return m.findTasks(ctx, accountID, func(t *domain.Task) bool {
+		return t.AssignedTo(assigneeID)
+	}), nil
+}
+
+// GetAllTasksByListIDForAccount implements the repo.TaskRepo interface.
+func (m *Memory) GetAllTasksByListIDForAccount(ctx context.Context, listID, accountID int64) ([]*domain.Task, error) {
+	return m.findTasks(ctx, accountID, func(t *domain.Task) bool {
+		return t.ListID != nil && *t.ListID == listID
+	}), nil
+}
+
+// GetTasksByIDsForAccount implements the repo.TaskRepo interface.
+func (m *Memory) GetTasksByIDsForAccount(ctx context.Context, taskIDs []int64, accountID int64) ([]*domain.Task, error) {
+	ids := make(map[int64]bool, len(taskIDs))
+	for _, id := range taskIDs {
+		ids[id] = true
+	}
+	return m.findTasks(ctx, accountID, func(t *domain.Task) bool {
+		return ids[t.ID]
+	}), nil
+}
+
+// GetAccountIDsForTask implements the repo.TaskRepo interface.
+func (m *Memory) GetAccountIDsForTask(ctx context.Context, t *domain.Task) ([]int64, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	ids := map[int64]bool{t.AccountID: true}
+	if t.AssigneeID != nil {
+		ids[*t.AssigneeID] = true
+	}
+	if t.ListID != nil {
+		if ownerID, ok := m.lists[*t.ListID]; ok {
+			ids[ownerID] = true
+		}
+		for id := range m.listMembers[*t.ListID] {
+			ids[id] = true
+		}
+	}
+	if t.OrgID != nil {
+		for id := range m.orgMembers[*t.OrgID] {
+			ids[id] = true
+		}
+	}
+	result := make([]int64, 0, len(ids))
+	for id := range ids {
+		result = append(result, id)
+	}
+	sort.Slice(result, func(i, j int) bool { return result[i] < result[j] })
+	return result, nil
+}
+
+// findTasks returns copies of the tasks which the account can see and which
+// match, newest first.
+func (m *Memory) findTasks(ctx context.Context, accountID int64, match func(t *domain.Task) bool) []*domain.Task {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	var result []*domain.Task
+	for _, t := range m.tasks {
+		if match(t) && m.taskVisible(ctx, t, accountID) {
+			result = append(result, copyTask(t))
+		}
+	}
+	sort.Slice(result, func(i, j int) bool {
+		if !result[i].Created.Equal(result[j].Created) {
+			return result[i].Created.After(result[j].Created)
+		}
+		return result[i].ID > result[j].ID
+	})
+	return result
+}
+
+// taskVisible reports whether an account can see a task: it's in the current
+// organisation, or outside any, and the account is a member of its
+// organisation, can see its list, or owns or is assigned it.
+func (m *Memory) taskVisible(ctx context.Context, t *domain.Task, accountID int64) bool {
+	if !inScope(ctx, t) {
+		return false
+	}
+	switch {
+	case t.OrgID != nil && m.isOrgMember(*t.OrgID, accountID):
+		return true
+	case t.ListID == nil:
+		return t.AccountID == accountID || t.AssignedTo(accountID)
+	default:
+		return m.listRole(*t.ListID, accountID) != ""
+	}
+}
+
+// taskEditable reports whether an account can change a task: it's in the
+// current organisation, or outside any, and the account is a member of its
+// organisation, can edit its list, or owns it.
+func (m *Memory) taskEditable(ctx context.Context, t *domain.Task, accountID int64) bool {
+	if !inScope(ctx, t) {
+		return false
+	}
+	switch {
+	case t.OrgID != nil && m.isOrgMember(*t.OrgID, accountID):
+		return true
+	case t.ListID == nil:
+		return t.AccountID == accountID
+	default:
+		return domain.CanEditList(m.listRole(*t.ListID, accountID))
+	}
+}
+
+// listRole returns an account's role on a list, or "" if it can't see it.
+func (m *Memory) listRole(listID, accountID int64) string {
+	if ownerID, ok := m.lists[listID]; ok && ownerID == accountID {
+		return domain.ListRoleOwner
+	}
+	return m.listMembers[listID][accountID]
+}
+
+func (m *Memory) isOrgMember(orgID, accountID int64) bool {
+	_, ok := m.orgMembers[orgID][accountID]
+	return ok
+}
+
+// CreateAccount implements the repo.AccountRepo interface.
+func (m *Memory) CreateAccount(ctx context.Context, a *domain.Account) (*domain.Account, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if err := m.checkUsername(0, a.Username); err != nil {
+		return nil, err
+	}
+	if a.VerifiedAt != nil {
+		if err := m.checkEmail(0, a.Email); err != nil {
+			return nil, err
+		}
+	}
+	m.lastAccountID++
+	created := &domain.Account{
+		ID:             m.lastAccountID,
+		PublicID:       domain.NewPublicID(),
+		Created:        now(),
+		Email:          a.Email,
+		PasswordDigest: a.PasswordDigest,
+		PasswordSalt:   a.PasswordSalt,
+		Role:           domain.RoleUser,
+		Username:       a.Username,
+		VerifiedAt:     normalizeTime(a.VerifiedAt),
+	}
+	m.accounts[created.ID] = created
+	return copyAccount(created), nil
+}
+
+// GetAccountByUsername implements the repo.AccountRepo interface.
+func (m *Memory) GetAccountByUsername(ctx context.Context, username string) (*domain.Account, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	for _, a := range m.accounts {
+		if strings.EqualFold(a.Username, username) {
+			return copyAccount(a), nil
+		}
+	}
+	return nil, nil
+}
+
+// GetAccountByEmail implements the repo.AccountRepo interface.
+func (m *Memory) GetAccountByEmail(ctx context.Context, email string) (*domain.Account, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	for _, a := range m.accounts {
+		if a.Email != "" && a.VerifiedAt != nil && strings.EqualFold(a.Email, email) {
+			return copyAccount(a), nil
+		}
+	}
+	return nil, nil
+}
+
+// GetAccountByID implements the repo.AccountRepo interface.
+func (m *Memory) GetAccountByID(ctx context.Context, id int64) (*domain.Account, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if a := m.accounts[id]; a != nil {
+		return copyAccount(a), nil
+	}
+	return nil, nil
+}
+
+// GetAccountByPublicID implements the repo.AccountRepo interface.
+func (m *Memory) GetAccountByPublicID(ctx context.Context, publicID string) (*domain.Account, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	for _, a := range m.accounts {
+		if a.PublicID == publicID {
+			return copyAccount(a), nil
+		}
+	}
+	return nil, nil
+}
+
+// GetAccountsByIDs implements the repo.AccountRepo interface.
+func (m *Memory) GetAccountsByIDs(ctx context.Context, ids []int64) ([]*domain.Account, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	seen := make(map[int64]bool, len(ids))
+	var result []*domain.Account
+	for _, id := range ids {
+		if a := m.accounts[id]; a != nil && !seen[id] {
+			seen[id] = true
+			result = append(result, copyAccount(a))
+		}
+	}
+	sortAccounts(result)
+	return result, nil
+}
+
+// UpdateAccountTOTP implements the repo.AccountRepo interface.
+func (m *Memory) UpdateAccountTOTP(ctx context.Context, a *domain.Account) (*domain.Account, error) {
+	return m.updateAccount(a.ID, func(stored *domain.Account) error {
+		stored.TOTPSecret = a.TOTPSecret
+		stored.TOTPEnabled = normalizeTime(a.TOTPEnabled)
+		return nil
+	})
+}
+
+// UseTOTPStep implements the repo.AccountRepo interface.
+func (m *Memory) UseTOTPStep(ctx context.Context, accountID, step int64) (bool, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if m.accounts[accountID] == nil {
+		return false, nil
+	}
+	if last, ok := m.totpSteps[accountID]; ok && step <= last {
+		return false, nil
+	}
+	m.totpSteps[accountID] = step
+	return true, nil
+}
+
+// UpdateAccountPassword implements the repo.AccountRepo interface.
+func (m *Memory) UpdateAccountPassword(ctx context.Context, a *domain.Account) (*domain.Account, error) {
+	return m.updateAccount(a.ID, func(stored *domain.Account) error {
+		stored.PasswordDigest = a.PasswordDigest
+		stored.PasswordSalt = a.PasswordSalt
+		return nil
+	})
+}
+
+// UpdateAccountUsername implements the repo.AccountRepo interface.
+func (m *Memory) UpdateAccountUsername(ctx context.Context, a *domain.Account) (*domain.Account, error) {
+	return m.updateAccount(a.ID, func(stored *domain.Account) error {
+		if err := m.checkUsername(a.ID, a.Username); err != nil {
+			return err
+		}
+		stored.Username = a.Username
+		return nil
+	})
+}
+
+// UpdateAccountRole implements the repo.AccountRepo interface.
+func (m *Memory) UpdateAccountRole(ctx context.Context, id int64, role string) (*domain.Account, error) {
+	return orNil(m.updateAccount(id, func(stored *domain.Account) error {
+		stored.Role = role
+		return nil
+	}))
+}
+
+// SuspendAccount implements the repo.AccountRepo interface.
+func (m *Memory) SuspendAccount(ctx context.Context, id int64, reason string) (*domain.Account, error) {
+	return orNil(m.updateAccount(id, func(stored *domain.Account) error {
+		if stored.SuspendedAt == nil {
+			suspended := now()
+			stored.SuspendedAt = &suspended
+		}
+		stored.SuspensionReason = reason
+		return nil
+	}))
+}
+
+// UnsuspendAccount implements the repo.AccountRepo interface.
+func (m *Memory) UnsuspendAccount(ctx context.Context, id int64) (*domain.Account, error) {
+	return orNil(m.updateAccount(id, func(stored *domain.Account) error {
+		stored.SuspendedAt = nil
+		stored.SuspensionReason = ""
+		return nil
+	}))
+}
+
+// SearchAccounts implements the repo.AccountRepo interface.
+func (m *Memory) SearchAccounts(ctx context.Context, query string, limit, offset int) ([]*domain.Account, error) {
+	if limit < 0 || offset < 0 {
+		return nil, errors.New("limit and offset must not be negative")
+	}
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	query = strings.ToLower(query)
+	var result []*domain.Account
+	for _, a := range m.accounts {
+		if strings.Contains(strings.ToLower(a.Username), query) || strings.Contains(strings.ToLower(a.Email), query) {
+			result = append(result, a)
+		}
+	}
+	sortAccounts(result)
+	if offset >= len(result) {
+		return nil, nil
+	}
+	result = result[offset:]
+	if limit < len(result) {
+		result = result[:limit]
+	}
+	if len(result) == 0 {
+		return nil, nil
+	}
+	copies := make([]*domain.Account, len(result))
+	for i, a := range result {
+		copies[i] = copyAccount(a)
+	}
+	return copies, nil
+}
+
+// UpdateAccountEmail implements the repo.AccountRepo interface.
+func (m *Memory) UpdateAccountEmail(ctx context.Context, a *domain.Account) (*domain.Account, error) {
+	return m.updateAccount(a.ID, func(stored *domain.Account) error {
+		stored.Email = a.Email
+		stored.VerifiedAt = nil
+		return nil
+	})
+}
+
+// DeleteAccount implements the repo.AccountRepo interface. Like the database,
+// it refuses to delete an account which owns an organisation with other
+// members, and otherwise deletes the account's tasks outside organisations,
+// the organisations it owns with their tasks, and the lists it owns with
+// their tasks, removes it from other lists and organisations, and unassigns
+// the tasks assigned to it.
+func (m *Memory) DeleteAccount(ctx context.Context, id int64) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if m.accounts[id] == nil {
+		return pgx.ErrNoRows
+	}
+	for _, members := range m.orgMembers {
+		if members[id] == domain.OrgRoleOwner && len(members) > 1 {
+			return repo.ErrOwnsSharedOrg
+		}
+	}
+	for taskID, t := range m.tasks {
+		if t.AccountID == id && t.OrgID == nil {
+			delete(m.tasks, taskID)
+		}
+	}
+	for orgID, members := range m.orgMembers {
+		if members[id] != domain.OrgRoleOwner {
+			continue
+		}
+		for taskID, t := range m.tasks {
+			if t.OrgID != nil && *t.OrgID == orgID {
+				delete(m.tasks, taskID)
+			}
+		}
+		delete(m.orgMembers, orgID)
+	}
+	for _, t := range m.tasks {
+		if t.AccountID != id || t.OrgID == nil {
+			continue
+		}
+		for accountID, role := range m.orgMembers[*t.OrgID] {
+			if role == domain.OrgRoleOwner {
+				t.AccountID = accountID
+			}
+		}
+	}
+	for _, members := range m.orgMembers {
+		delete(members, id)
+	}
+	for listID, ownerID := range m.lists {
+		if ownerID != id {
+			continue
+		}
+		for taskID, t := range m.tasks {
+			if t.ListID != nil && *t.ListID == listID {
+				delete(m.tasks, taskID)
+			}
+		}
+		delete(m.listMembers, listID)
+		delete(m.lists, listID)
+		delete(m.listPublicIDs, listID)
+	}
+	for _, members := range m.listMembers {
+		delete(members, id)
+	}
+	for _, t := range m.tasks {
+		if t.AssignedTo(id) {
+			t.AssigneeID = nil
+		}
+	}
+	delete(m.accounts, id)
+	return nil
+}
+
+// updateAccount applies an update to an account, returning pgx.ErrNoRows if
+// the account doesn't exist. The update is discarded if it returns an error.
+func (m *Memory) updateAccount(id int64, update func(stored *domain.Account) error) (*domain.Account, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	stored := m.accounts[id]
+	if stored == nil {
+		return nil, pgx.ErrNoRows
+	}
+	updated := copyAccount(stored)
+	if err := update(updated); err != nil {
+		return nil, err
+	}
+	m.accounts[id] = updated
+	return copyAccount(updated), nil
+}
+
+// checkUsername returns repo.ErrUsernameTaken if an account other than id
+// has the username, ignoring case.
+func (m *Memory) checkUsername(id int64, username string) error {
+	for _, a := range m.accounts {
+		if a.ID != id && strings.EqualFold(a.Username, username) {
+			return repo.ErrUsernameTaken
+		}
+	}
+	return nil
+}
+
+// checkEmail returns repo.ErrEmailTaken if an account other than id has
+// verified the email address, ignoring case. Any number of accounts may have
+// no email address, or the same unverified one.
+func (m *Memory) checkEmail(id int64, email string) error {
+	if email == "" {
+		return nil
+	}
+	for _, a := range m.accounts {
+		if a.ID != id && a.VerifiedAt != nil && strings.EqualFold(a.Email, email) {
+			return repo.ErrEmailTaken
+		}
+	}
+	return nil
+}
+
+// orNil translates pgx.ErrNoRows into a nil account, for the updates which
+// return nil when the account doesn't exist.
+func orNil(a *domain.Account, err error) (*domain.Account, error) {
+	if err == pgx.ErrNoRows {
+		return nil, nil
+	}
+	return a, err
+}
+
+// currentOrg returns the organisation ctx is scoped to, or nil.
+func currentOrg(ctx context.Context) *int64 {
+	if orgID, ok := repo.OrgIDFromContext(ctx); ok {
+		return &orgID
+	}
+	return nil
+}
+
+// inScope reports whether a task is in the organisation ctx is scoped to, or
+// outside any organisation if ctx isn't scoped to one.
+func inScope(ctx context.Context, t *domain.Task) bool {
+	orgID, ok := repo.OrgIDFromContext(ctx)
+	if !ok {
+		return t.OrgID == nil
+	}
+	return t.OrgID != nil && *t.OrgID == orgID
+}
+
+func sortAccounts(accounts []*domain.Account) {
+	sort.Slice(accounts, func(i, j int) bool { return accounts[i].ID < accounts[j].ID })
+}
+
+// now returns the current time as the database stores it.
+func now() time.Time {
+	return time.Now().UTC().Truncate(time.Microsecond)
+}
+
+// normalizeTime returns a copy of a time as it's read back from a timestamptz
+// column: in UTC, to the microsecond.
+func normalizeTime(t *time.Time) *time.Time {
+	if t == nil {
+		return nil
+	}
+	result := t.UTC().Truncate(time.Microsecond)
+	return &result
+}
+
+func copyInt64(v *int64) *int64 {
+	if v == nil {
+		return nil
+	}
+	result := *v
+	return &result
+}
+
+func copyTask(t *domain.Task) *domain.Task {
+	result := *t
+	result.AssigneeID = copyInt64(t.AssigneeID)
+	result.Completed = normalizeTime(t.Completed)
+	result.ListID = copyInt64(t.ListID)
+	if t.ListPublicID != nil {
+		publicID := *t.ListPublicID
+		result.ListPublicID = &publicID
+	}
+	result.OrgID = copyInt64(t.OrgID)
+	return &result
+}
+
+func copyAccount(a *domain.Account) *domain.Account {
+	result := *a
+	result.SuspendedAt = normalizeTime(a.SuspendedAt)
+	result.TOTPEnabled = normalizeTime(a.TOTPEnabled)
+	result.VerifiedAt = normalizeTime(a.VerifiedAt)
+	return &result
+}
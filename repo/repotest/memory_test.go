@@ -0,0 +1,44 @@
+package repotest_test
+
+import (
+	"context"
+	"sync"
+	"testing"
+
+	"github.com/deliveroo/assert-go"
+	"github.com/deliveroo/todo-api/domain"
+	"github.com/deliveroo/todo-api/repo/repotest"
+)
+
+func TestMemory(t *testing.T) {
+	m := repotest.NewMemory()
+	repotest.TestConformance(t, m, m)
+}
+
+func TestMemoryConcurrency(t *testing.T) {
+	var (
+		m   = repotest.NewMemory()
+		ctx = context.Background()
+		wg  sync.WaitGroup
+	)
+	a, err := m.CreateAccount(ctx, &domain.Account{Username: "concurrent"})
+	assert.Must(t, err)
+	for i := 0; i < 10; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			task, err := m.CreateTask(ctx, &domain.Task{CreatedBy: a.ID, Description: "concurrent"})
+			if err != nil {
+				t.Error(err)
+				return
+			}
+			if _, err := m.UpdateTaskForAccount(ctx, &domain.Task{ID: task.ID, Description: "updated"}, a.ID); err != nil {
+				t.Error(err)
+			}
+		}()
+	}
+	wg.Wait()
+	count, err := m.CountTasksByAccountID(ctx, a.ID)
+	assert.Must(t, err)
+	assert.Equal(t, count, int64(10))
+}
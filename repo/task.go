@@ -8,33 +8,93 @@ import (
 	"github.com/jackc/pgx/v4"
 )
 
-// CreateTask inserts a task into the database.
+// Tasks are private to the account which owns them, and the account they are
+// assigned to, unless they belong to a list or an organisation. The queries
+// below authorize an account, always passed as $1, with these conditions
+// rather than by matching tasks.account_id.
+//
+// Queries on tasks are also scoped to the organisation set by WithOrgID, or to
+// tasks outside any organisation, except for the per-account counts.
+const (
+	// accountListsSQL selects the lists account $1 can see, with its role
+	// on each.
+	accountListsSQL = `
+		SELECT id AS list_id, 'owner' AS role FROM lists WHERE account_id = $1
+		UNION ALL
+		SELECT list_id, role FROM list_members WHERE account_id = $1`
+
+	// accountOrgsSQL selects the organisations account $1 belongs to.
+	accountOrgsSQL = `
+		SELECT org_id FROM org_members WHERE account_id = $1`
+
+	// taskScopeSQL matches the tasks in the current organisation, or outside
+	// any organisation.
+	taskScopeSQL = `tasks.org_id IS NOT DISTINCT FROM ` + currentOrgSQL
+
+	// taskVisibleSQL matches the tasks account $1 can see. Assignees see
+	// tasks in a list through their membership of it, so that leaving the
+	// list hides them.
+	taskVisibleSQL = `(
+		` + taskScopeSQL + `
+		AND (
+			tasks.org_id IN (` + accountOrgsSQL + `)
+			OR (tasks.list_id IS NULL AND (tasks.account_id = $1 OR tasks.assignee_id = $1))
+			OR tasks.list_id IN (SELECT list_id FROM (` + accountListsSQL + `) AS visible)
+		)
+	)`
+
+	// editableListsSQL selects the lists in which account $1 can change
+	// tasks.
+	editableListsSQL = `
+		SELECT list_id FROM (` + accountListsSQL + `) AS editable
+		WHERE role IN ('owner', 'editor')`
+
+	// taskEditableSQL matches the tasks account $1 can change. Every member
+	// of an organisation can change its tasks.
+	taskEditableSQL = `(
+		` + taskScopeSQL + `
+		AND (
+			tasks.org_id IN (` + accountOrgsSQL + `)
+			OR (tasks.list_id IS NULL AND tasks.account_id = $1)
+			OR tasks.list_id IN (` + editableListsSQL + `)
+		)
+	)`
+)
+
+// taskColumns are the columns selected for tasks, and returned by changes to
+// them, with the public id of the task's list.
+const taskColumns = `
+	id, public_id, account_id, assignee_id, created_by, list_id,
+	(SELECT l.public_id FROM lists l WHERE l.id = tasks.list_id) AS list_public_id,
+	org_id, description, created, completed`
+
+// CreateTask inserts a task created by t.CreatedBy into the database, in the
+// current organisation if there is one. Tasks in a list are owned by the
+// list's owner, and the creator must be able to edit the list, or
+// pgx.ErrNoRows is returned. Other tasks are owned by their creator, and
+// t.AccountID is ignored. Tasks in an organisation can't belong to a list.
 func (c *Client) CreateTask(ctx context.Context, t *domain.Task) (*domain.Task, error) {
 	row := c.queryRow(ctx, `
-		INSERT INTO tasks (account_id, description, completed)
-		VALUES ($1, $2, $3)
-		RETURNING id, account_id, description, created, completed;
-	`, t.AccountID, t.Description, t.Completed)
-	var result domain.Task
-	if err := row.Scan(
-		&result.ID,
-		&result.AccountID,
-		&result.Description,
-		&result.Created,
-		&result.Completed,
-	); err != nil {
-		return nil, err
-	}
-	return &result, nil
+		WITH task AS (
+			INSERT INTO tasks (account_id, created_by, list_id, assignee_id, description, completed, org_id)
+			SELECT COALESCE((SELECT account_id FROM lists WHERE id = $2), $1), $1, $2, $3::bigint, $4::text, $5::timestamptz, `+currentOrgSQL+`
+			WHERE (`+currentOrgSQL+` IS NULL AND ($2::bigint IS NULL OR $2 IN (`+editableListsSQL+`)))
+			OR ($2::bigint IS NULL AND `+currentOrgSQL+` IN (`+accountOrgsSQL+`))
+			RETURNING `+taskColumns+`
+		)`+outboxSQL(domain.AggregateTask, domain.EventTaskCreated), t.CreatedBy, t.ListID, t.AssigneeID, t.Description, t.Completed)
+	return scanTask(row)
 }
 
-// DeleteTask deletes a task from the database.
-func (c *Client) DeleteTaskByIDAndAccountID(ctx context.Context, taskID, accountID int64) error {
+// DeleteTaskByIDForAccount deletes a task from the database. It returns
+// pgx.ErrNoRows if the task doesn't exist or the account can't change it.
+func (c *Client) DeleteTaskByIDForAccount(ctx context.Context, taskID, accountID int64) error {
 	tag, err := c.exec(ctx, `
-		DELETE FROM tasks
-		WHERE id = $1
-		AND account_id = $2;
-	`, taskID, accountID)
+		WITH task AS (
+			DELETE FROM tasks
+			WHERE id = $2
+			AND `+taskEditableSQL+`
+			RETURNING *
+		)`+outboxSQL(domain.AggregateTask, domain.EventTaskDeleted), accountID, taskID)
 	if err != nil {
 		return err
 	}
@@ -44,89 +104,264 @@ func (c *Client) DeleteTaskByIDAndAccountID(ctx context.Context, taskID, account
 	return nil
 }
 
-// UpdateTask updates a task in the database.
-func (c *Client) UpdateTask(ctx context.Context, t *domain.Task) (*domain.Task, error) {
+// UpdateTaskForAccount updates a task in the database. It returns
+// pgx.ErrNoRows if the task doesn't exist or the account can't change it.
+func (c *Client) UpdateTaskForAccount(ctx context.Context, t *domain.Task, accountID int64) (*domain.Task, error) {
 	row := c.queryRow(ctx, `
-		UPDATE tasks
-		SET description = $2, completed = $3
-		WHERE id = $1
-		RETURNING id, account_id, description, created, completed;
-	`, t.ID, t.Description, t.Completed)
-	var result domain.Task
-	if err := row.Scan(
-		&result.ID,
-		&result.AccountID,
-		&result.Description,
-		&result.Created,
-		&result.Completed,
-	); err != nil {
+		WITH task AS (
+			UPDATE tasks
+			SET description = $3, completed = $4
+			WHERE id = $2
+			AND `+taskEditableSQL+`
+			RETURNING `+taskColumns+`
+		)`+outboxSQL(domain.AggregateTask, domain.EventTaskUpdated), accountID, t.ID, t.Description, t.Completed)
+	return scanTask(row)
+}
+
+// UpdateTaskCompletedForAccount marks a task completed, or incomplete if
+// completed is nil. Unlike other changes, the task's assignee may make it. It
+// returns pgx.ErrNoRows if the task doesn't exist or the account can't change
+// it.
+func (c *Client) UpdateTaskCompletedForAccount(ctx context.Context, taskID, accountID int64, completed *time.Time) (*domain.Task, error) {
+	row := c.queryRow(ctx, `
+		WITH task AS (
+			UPDATE tasks
+			SET completed = $3
+			WHERE id = $2
+			AND (`+taskEditableSQL+` OR (`+taskVisibleSQL+` AND tasks.assignee_id = $1))
+			RETURNING `+taskColumns+`
+		)`+outboxSQL(domain.AggregateTask, domain.EventTaskUpdated), accountID, taskID, completed)
+	return scanTask(row)
+}
+
+// UpdateTaskAssigneeForAccount assigns a task to an account, or unassigns it
+// if assigneeID is nil. It returns pgx.ErrNoRows if the task doesn't exist or
+// the account can't change it.
+func (c *Client) UpdateTaskAssigneeForAccount(ctx context.Context, taskID, accountID int64, assigneeID *int64) (*domain.Task, error) {
+	row := c.queryRow(ctx, `
+		WITH task AS (
+			UPDATE tasks
+			SET assignee_id = $3
+			WHERE id = $2
+			AND `+taskEditableSQL+`
+			RETURNING `+taskColumns+`
+		)`+outboxSQL(domain.AggregateTask, domain.EventTaskUpdated), accountID, taskID, assigneeID)
+	return scanTask(row)
+}
+
+// GetTaskByIDForAccount fetches a task which the account can see from the
+// database, or returns nil if not found.
+func (c *Client) GetTaskByIDForAccount(ctx context.Context, taskID, accountID int64) (*domain.Task, error) {
+	row := c.queryRow(ctx, `
+		SELECT `+taskColumns+`
+		FROM tasks
+		WHERE id = $2
+		AND `+taskVisibleSQL+`;
+	`, accountID, taskID)
+	t, err := scanTask(row)
+	if err != nil {
+		if isErrNoRows(err) {
+			return nil, nil
+		}
 		return nil, err
 	}
-	return &result, nil
+	return t, nil
 }
 
-// GetTaskByIDAndAccountID fetches a task by ID and account from the database,
-// or returns nil if not found.
-func (c *Client) GetTaskByIDAndAccountID(ctx context.Context, taskID, accountID int64) (*domain.Task, error) {
+// GetTaskByPublicIDForAccount fetches a task which the account can see by
+// public id from the database, or returns nil if not found.
+func (c *Client) GetTaskByPublicIDForAccount(ctx context.Context, publicID string, accountID int64) (*domain.Task, error) {
 	row := c.queryRow(ctx, `
-		SELECT id, account_id, description, created, completed
+		SELECT `+taskColumns+`
 		FROM tasks
-		WHERE id = $1
-		AND account_id = $2;
-	`, taskID, accountID)
-	var result domain.Task
-	if err := row.Scan(
-		&result.ID,
-		&result.AccountID,
-		&result.Description,
-		&result.Created,
-		&result.Completed,
-	); err != nil {
+		WHERE public_id = $2
+		AND `+taskVisibleSQL+`;
+	`, accountID, publicID)
+	t, err := scanTask(row)
+	if err != nil {
 		if isErrNoRows(err) {
 			return nil, nil
 		}
 		return nil, err
 	}
-	return &result, nil
+	return t, nil
 }
 
-// MarkIncompleteTasksCompleteByAccountID marks all incomplete tasks for an account complete.
+// MarkIncompleteTasksCompleteByAccountID marks all incomplete tasks owned by an account complete.
 func (c *Client) MarkIncompleteTasksCompleteByAccountID(ctx context.Context, accountID int64) (int64, error) {
-	tag, err := c.exec(ctx, `
-		UPDATE tasks
-		SET completed = $2
-		WHERE account_id = $1
-		AND completed IS NULL;
-	`, accountID, time.Now().UTC())
-	return tag.RowsAffected(), err
+	tx, err := c.begin(ctx)
+	if err != nil {
+		return 0, err
+	}
+	defer func() {
+		_ = tx.Rollback(ctx) // no-op once committed
+	}()
+	where := `account_id = $1 AND completed IS NULL AND ` + taskScopeSQL
+	if err := recordTaskChanges(ctx, tx, false, where, accountID); err != nil {
+		return 0, err
+	}
+	tag, err := tx.Exec(ctx, `
+		WITH task AS (
+			UPDATE tasks
+			SET completed = $2
+			WHERE `+where+`
+			RETURNING *
+		)`+outboxSQL(domain.AggregateTask, domain.EventTaskUpdated), accountID, time.Now().UTC())
+	if err != nil {
+		return 0, err
+	}
+	return tag.RowsAffected(), tx.Commit(ctx)
 }
 
-// GetAllTasksByAccountID fetches all tasks by account from the database.
-func (c *Client) GetAllTasksByAccountID(ctx context.Context, accountID int64) ([]*domain.Task, error) {
-	rows, err := c.query(ctx, `
-		SELECT id, account_id, description, created, completed
+// CountTasksByAccountID counts the tasks created by an account, including
+// those it added to other accounts' lists and to organisations, whatever the
+// current organisation.
+func (c *Client) CountTasksByAccountID(ctx context.Context, accountID int64) (int64, error) {
+	var count int64
+	err := c.queryRow(ctx, `
+		SELECT count(*)
+		FROM tasks
+		WHERE created_by = $1;
+	`, accountID).Scan(&count)
+	return count, err
+}
+
+// GetTaskStatsByAccountID summarises the tasks owned by an account, including
+// those in organisations, whatever the current organisation.
+func (c *Client) GetTaskStatsByAccountID(ctx context.Context, accountID int64) (*domain.TaskStats, error) {
+	var result domain.TaskStats
+	err := c.queryRow(ctx, `
+		SELECT count(*), count(completed), max(created)
 		FROM tasks
-		WHERE account_id = $1
+		WHERE account_id = $1;
+	`, accountID).Scan(&result.Total, &result.Completed, &result.LastCreated)
+	if err != nil {
+		return nil, err
+	}
+	return &result, nil
+}
+
+// GetAllTasksForAccount fetches all tasks the account can see from the
+// database, including those in lists shared with it.
+func (c *Client) GetAllTasksForAccount(ctx context.Context, accountID int64) ([]*domain.Task, error) {
+	return c.queryTasks(ctx, `
+		SELECT `+taskColumns+`
+		FROM tasks
+		WHERE `+taskVisibleSQL+`
 		ORDER BY created DESC;
 	`, accountID)
+}
+
+// GetAllTasksByAssigneeIDForAccount fetches the tasks assigned to an account
+// from the database, if the account fetching them can see them.
+func (c *Client) GetAllTasksByAssigneeIDForAccount(ctx context.Context, assigneeID, accountID int64) ([]*domain.Task, error) {
+	return c.queryTasks(ctx, `
+		SELECT `+taskColumns+`
+		FROM tasks
+		WHERE assignee_id = $2
+		AND `+taskVisibleSQL+`
+		ORDER BY created DESC;
+	`, accountID, assigneeID)
+}
+
+// GetAllTasksByListIDForAccount fetches all tasks in a list from the
+// database, if the account can see the list.
+func (c *Client) GetAllTasksByListIDForAccount(ctx context.Context, listID, accountID int64) ([]*domain.Task, error) {
+	return c.queryTasks(ctx, `
+		SELECT `+taskColumns+`
+		FROM tasks
+		WHERE list_id = $2
+		AND `+taskVisibleSQL+`
+		ORDER BY created DESC;
+	`, accountID, listID)
+}
+
+// GetTasksByIDsForAccount fetches the tasks with the given ids which the
+// account can see from the database. Tasks which don't exist or which it
+// can't see are left out.
+func (c *Client) GetTasksByIDsForAccount(ctx context.Context, taskIDs []int64, accountID int64) ([]*domain.Task, error) {
+	return c.queryTasks(ctx, `
+		SELECT `+taskColumns+`
+		FROM tasks
+		WHERE id = ANY($2::bigint[])
+		AND `+taskVisibleSQL+`
+		ORDER BY created DESC;
+	`, accountID, taskIDs)
+}
+
+// GetAccountIDsForTask fetches the ids of the accounts which can see a task
+// from the database: its owner and assignee, and the members of its list or
+// organisation. It only reads the task's fields, so it can be used once the
+// task has been deleted.
+func (c *Client) GetAccountIDsForTask(ctx context.Context, t *domain.Task) ([]int64, error) {
+	ids := []int64{t.AccountID}
+	if t.AssigneeID != nil {
+		ids = append(ids, *t.AssigneeID)
+	}
+	rows, err := c.query(ctx, `
+		SELECT unnest($1::bigint[])
+		UNION
+		SELECT account_id FROM lists WHERE id = $2
+		UNION
+		SELECT account_id FROM list_members WHERE list_id = $2
+		UNION
+		SELECT account_id FROM org_members WHERE org_id = $3
+		ORDER BY 1;
+	`, ids, t.ListID, t.OrgID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var result []int64
+	for rows.Next() {
+		var id int64
+		if err := rows.Scan(&id); err != nil {
+			return nil, err
+		}
+		result = append(result, id)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return result, nil
+}
+
+func (c *Client) queryTasks(ctx context.Context, sql string, args ...interface{}) ([]*domain.Task, error) {
+	rows, err := c.query(ctx, sql, args...)
 	if err != nil {
 		return nil, err
 	}
 	defer rows.Close()
 	var result []*domain.Task
 	for rows.Next() {
-		var t domain.Task
-		_ = rows.Scan(
-			&t.ID,
-			&t.AccountID,
-			&t.Description,
-			&t.Created,
-			&t.Completed,
-		)
-		result = append(result, &t)
+		t, err := scanTask(rows)
+		if err != nil {
+			return nil, err
+		}
+		result = append(result, t)
 	}
 	if err := rows.Err(); err != nil {
 		return nil, err
 	}
 	return result, nil
 }
+
+func scanTask(row pgx.Row) (*domain.Task, error) {
+	var result domain.Task
+	if err := row.Scan(
+		&result.ID,
+		&result.PublicID,
+		&result.AccountID,
+		&result.AssigneeID,
+		&result.CreatedBy,
+		&result.ListID,
+		&result.ListPublicID,
+		&result.OrgID,
+		&result.Description,
+		&result.Created,
+		&result.Completed,
+	); err != nil {
+		return nil, err
+	}
+	return &result, nil
+}
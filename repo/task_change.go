@@ -0,0 +1,174 @@
+package repo
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+
+	"github.com/deliveroo/todo-api/domain"
+	"github.com/jackc/pgx/v4"
+)
+
+// changesSQL records changes to tasks in the sequences of accounts. It must
+// follow a common table expression named audience, which selects the task_id,
+// task_public_id and org_id of each task which changed, and the account_id of
+// each account whose sequence the change belongs in. Accounts are locked in
+// id order, so that concurrent changes can't deadlock.
+func changesSQL(deleted bool) string {
+	return fmt.Sprintf(`
+		seqs AS (
+			UPDATE accounts
+			SET change_seq = change_seq + (SELECT count(*) FROM audience WHERE audience.account_id = accounts.id)
+			WHERE id IN (
+				SELECT id FROM accounts
+				WHERE id IN (SELECT account_id FROM audience)
+				ORDER BY id
+				FOR UPDATE
+			)
+			RETURNING id, change_seq
+		)
+		INSERT INTO task_changes (account_id, task_id, task_public_id, org_id, seq, deleted, changed)
+		SELECT audience.account_id, audience.task_id, audience.task_public_id, audience.org_id,
+			seqs.change_seq + 1 - row_number() OVER (PARTITION BY audience.account_id ORDER BY audience.task_id DESC),
+			%t, CURRENT_TIMESTAMP
+		FROM audience
+		JOIN seqs ON seqs.id = audience.account_id
+		ON CONFLICT (account_id, task_id) DO UPDATE
+		SET org_id = excluded.org_id, seq = excluded.seq, deleted = excluded.deleted, changed = excluded.changed;`, deleted)
+}
+
+// taskAudienceSQL selects the accounts which can see the tasks selected by a
+// preceding common table expression named changed, which must select their
+// id, public_id, account_id, assignee_id, list_id and org_id: their owners
+// and assignees, and the members of their lists and organisations.
+const taskAudienceSQL = `
+	audience AS (
+		SELECT changed.id AS task_id, changed.public_id AS task_public_id, changed.org_id, visible.account_id
+		FROM changed, LATERAL (
+			SELECT changed.account_id
+			UNION SELECT changed.assignee_id WHERE changed.assignee_id IS NOT NULL
+			UNION SELECT account_id FROM lists WHERE id = changed.list_id
+			UNION SELECT account_id FROM list_members WHERE list_id = changed.list_id
+			UNION SELECT account_id FROM org_members WHERE org_id = changed.org_id
+		) AS visible (account_id)
+	),`
+
+// CreateTaskChange records a change to a task in the sequences of the
+// accounts which can see it. It only reads the task's fields, so it can be
+// used once the task has been deleted.
+func (c *Client) CreateTaskChange(ctx context.Context, t *domain.Task, deleted bool) error {
+	_, err := c.exec(ctx, `
+		WITH changed AS (
+			SELECT $1::bigint AS id, $2::uuid AS public_id, $3::bigint AS account_id,
+				$4::bigint AS assignee_id, $5::bigint AS list_id, $6::bigint AS org_id
+		),`+taskAudienceSQL+changesSQL(deleted), t.ID, t.PublicID, t.AccountID, t.AssigneeID, t.ListID, t.OrgID)
+	return err
+}
+
+// recordTaskChanges records changes to the tasks matching where in the
+// sequences of the accounts which can see them. Deletions must be recorded
+// before the tasks are deleted, and changes to who can see the tasks before
+// the change if it hides them from anyone.
+func recordTaskChanges(ctx context.Context, tx pgx.Tx, deleted bool, where string, args ...interface{}) error {
+	_, err := tx.Exec(ctx, `
+		WITH changed AS (
+			SELECT id, public_id, account_id, assignee_id, list_id, org_id
+			FROM tasks
+			WHERE `+where+`
+		),`+taskAudienceSQL+changesSQL(deleted), args...)
+	return err
+}
+
+// recordAccountTaskChanges records changes to the tasks matching where in the
+// sequence of a single account, which has started or, if deleted is true,
+// stopped being able to see them. The account's id is passed after args.
+func recordAccountTaskChanges(ctx context.Context, tx pgx.Tx, deleted bool, accountID int64, where string, args ...interface{}) error {
+	_, err := tx.Exec(ctx, `
+		WITH audience AS (
+			SELECT id AS task_id, public_id AS task_public_id, org_id, $`+strconv.Itoa(len(args)+1)+`::bigint AS account_id
+			FROM tasks
+			WHERE `+where+`
+		),`+changesSQL(deleted), append(args, accountID)...)
+	return err
+}
+
+// GetChangeSeq fetches the number of the latest change in an account's
+// sequence from the database, or zero if there haven't been any.
+func (c *Client) GetChangeSeq(ctx context.Context, accountID int64) (int64, error) {
+	var seq int64
+	err := c.queryRow(ctx, `SELECT change_seq FROM accounts WHERE id = $1;`, accountID).Scan(&seq)
+	if isErrNoRows(err) {
+		return 0, nil
+	}
+	return seq, err
+}
+
+// GetTaskChange fetches the latest change to a task, by its public id, in an
+// account's sequence from the database, or nil if there hasn't been one. The
+// change is kept once the task has been deleted.
+func (c *Client) GetTaskChange(ctx context.Context, accountID int64, taskPublicID string) (*domain.TaskChange, error) {
+	row := c.queryRow(ctx, `
+		SELECT account_id, task_id, task_public_id, org_id, seq, deleted, changed
+		FROM task_changes
+		WHERE account_id = $1
+		AND task_public_id = $2;
+	`, accountID, taskPublicID)
+	return scanTaskChangeOrNil(row)
+}
+
+// GetTaskChangesSince fetches the latest changes to tasks in an account's
+// sequence after since from the database, in order, in the current
+// organisation or outside any organisation.
+func (c *Client) GetTaskChangesSince(ctx context.Context, accountID, since int64) ([]*domain.TaskChange, error) {
+	rows, err := c.query(ctx, `
+		SELECT account_id, task_id, task_public_id, org_id, seq, deleted, changed
+		FROM task_changes
+		WHERE account_id = $1
+		AND seq > $2
+		AND org_id IS NOT DISTINCT FROM `+currentOrgSQL+`
+		ORDER BY seq;
+	`, accountID, since)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var result []*domain.TaskChange
+	for rows.Next() {
+		tc, err := scanTaskChange(rows)
+		if err != nil {
+			return nil, err
+		}
+		result = append(result, tc)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return result, nil
+}
+
+func scanTaskChangeOrNil(row pgx.Row) (*domain.TaskChange, error) {
+	tc, err := scanTaskChange(row)
+	if err != nil {
+		if isErrNoRows(err) {
+			return nil, nil
+		}
+		return nil, err
+	}
+	return tc, nil
+}
+
+func scanTaskChange(row pgx.Row) (*domain.TaskChange, error) {
+	var result domain.TaskChange
+	if err := row.Scan(
+		&result.AccountID,
+		&result.TaskID,
+		&result.TaskPublicID,
+		&result.OrgID,
+		&result.Seq,
+		&result.Deleted,
+		&result.Changed,
+	); err != nil {
+		return nil, err
+	}
+	return &result, nil
+}
@@ -0,0 +1,110 @@
+package repo_test
+
+import (
+	"context"
+	"testing"
+
+	"github.com/deliveroo/assert-go"
+	"github.com/deliveroo/todo-api/domain"
+	"github.com/deliveroo/todo-api/repo"
+)
+
+func TestTaskChanges(t *testing.T) {
+	var (
+		db     = getDB(t)
+		client = &repo.Client{db.pool}
+		ctx    = context.Background()
+	)
+	defer db.Close()
+
+	newAccount := func(username string) int64 {
+		a, err := client.CreateAccount(ctx, &domain.Account{
+			Username:       username,
+			PasswordDigest: "password-digest",
+			PasswordSalt:   "password-salt",
+		})
+		assert.Must(t, err)
+		return a.ID
+	}
+	var (
+		owner  = newAccount("changes-owner")
+		member = newAccount("changes-member")
+	)
+	list, err := client.CreateList(ctx, &domain.List{AccountID: owner, Name: "chores"})
+	assert.Must(t, err)
+	i, err := client.CreateListInvitation(ctx, &domain.ListInvitation{
+		ListID:    list.ID,
+		InviterID: owner,
+		InviteeID: member,
+		Role:      domain.ListRoleEditor,
+	})
+	assert.Must(t, err)
+	_, err = client.RespondToListInvitation(ctx, i.PublicID, member, true)
+	assert.Must(t, err)
+
+	seq, err := client.GetChangeSeq(ctx, owner)
+	assert.Must(t, err)
+	assert.Equal(t, seq, int64(0))
+
+	private, err := client.CreateTask(ctx, &domain.Task{CreatedBy: owner, Description: "private"})
+	assert.Must(t, err)
+	assert.Must(t, client.CreateTaskChange(ctx, private, false))
+	shared, err := client.CreateTask(ctx, &domain.Task{CreatedBy: member, ListID: &list.ID, Description: "shared"})
+	assert.Must(t, err)
+	assert.Must(t, client.CreateTaskChange(ctx, shared, false))
+
+	t.Run("sequences", func(t *testing.T) {
+		changes, err := client.GetTaskChangesSince(ctx, owner, 0)
+		assert.Must(t, err)
+		assert.Equal(t, len(changes), 2)
+		assert.Equal(t, changes[0].TaskID, private.ID)
+		assert.Equal(t, changes[0].TaskPublicID, private.PublicID)
+		assert.Equal(t, changes[0].Seq, int64(1))
+		assert.Equal(t, changes[1].TaskID, shared.ID)
+		assert.Equal(t, changes[1].Seq, int64(2))
+
+		changes, err = client.GetTaskChangesSince(ctx, member, 0)
+		assert.Must(t, err)
+		assert.Equal(t, len(changes), 1)
+		assert.Equal(t, changes[0].TaskID, shared.ID)
+
+		changes, err = client.GetTaskChangesSince(ctx, owner, 2)
+		assert.Must(t, err)
+		assert.Equal(t, len(changes), 0)
+	})
+	t.Run("latest change only", func(t *testing.T) {
+		assert.Must(t, client.CreateTaskChange(ctx, private, false))
+		changes, err := client.GetTaskChangesSince(ctx, owner, 0)
+		assert.Must(t, err)
+		assert.Equal(t, len(changes), 2)
+		assert.Equal(t, changes[1].TaskID, private.ID)
+		assert.Equal(t, changes[1].Seq, int64(3))
+
+		change, err := client.GetTaskChange(ctx, owner, private.PublicID)
+		assert.Must(t, err)
+		assert.Equal(t, change.Seq, int64(3))
+		change, err = client.GetTaskChange(ctx, member, private.PublicID)
+		assert.Must(t, err)
+		assert.Nil(t, change)
+	})
+	t.Run("leaving a list", func(t *testing.T) {
+		assert.Must(t, client.DeleteListMember(ctx, list.ID, member))
+		change, err := client.GetTaskChange(ctx, member, shared.PublicID)
+		assert.Must(t, err)
+		assert.True(t, change.Deleted)
+	})
+	t.Run("deleting a list", func(t *testing.T) {
+		assert.Must(t, client.DeleteList(ctx, list.ID))
+		change, err := client.GetTaskChange(ctx, owner, shared.PublicID)
+		assert.Must(t, err)
+		assert.True(t, change.Deleted)
+		seq, err := client.GetChangeSeq(ctx, owner)
+		assert.Must(t, err)
+		assert.Equal(t, seq, change.Seq)
+
+		tasks, err := client.GetTasksByIDsForAccount(ctx, []int64{private.ID, shared.ID}, owner)
+		assert.Must(t, err)
+		assert.Equal(t, len(tasks), 1)
+		assert.Equal(t, tasks[0].ID, private.ID)
+	})
+}
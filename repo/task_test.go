@@ -9,6 +9,7 @@ import (
 	"github.com/deliveroo/assert-go"
 	"github.com/deliveroo/todo-api/domain"
 	"github.com/deliveroo/todo-api/repo"
+	"github.com/jackc/pgx/v4"
 )
 
 func TestCreateTask(t *testing.T) {
@@ -20,7 +21,7 @@ func TestCreateTask(t *testing.T) {
 	)
 	defer db.Close()
 	task := domain.Task{
-		AccountID:   1,
+		CreatedBy:   db.createAccount("task-create"),
 		Description: "alpha",
 		Completed:   &now,
 	}
@@ -41,7 +42,7 @@ func TestUpdateTask(t *testing.T) {
 	)
 	defer db.Close()
 	task := &domain.Task{
-		AccountID:   1,
+		CreatedBy:   db.createAccount("task-update"),
 		Description: "alpha",
 		Completed:   &now,
 	}
@@ -50,57 +51,67 @@ func TestUpdateTask(t *testing.T) {
 	task.Description = "bravo"
 	task.Completed = nil
 	{
-		updated, err := client.UpdateTask(ctx, task)
+		updated, err := client.UpdateTaskForAccount(ctx, task, task.AccountID)
 		assert.Must(t, err)
 		assert.Equal(t, task.Description, updated.Description)
 		assert.Nil(t, updated.Completed)
 	}
 }
 
-func TestGetTaskByIDAndAccountID(t *testing.T) {
+func TestGetTaskByIDForAccount(t *testing.T) {
 	var (
 		db     = getDB(t)
 		client = &repo.Client{db.pool}
 		ctx    = context.Background()
 	)
 
-	none, err := client.GetTaskByIDAndAccountID(ctx, 0, 0)
+	none, err := client.GetTaskByIDForAccount(ctx, 0, 0)
 	assert.Must(t, err)
 	assert.Nil(t, none)
 
 	task := &domain.Task{
-		AccountID:   2,
+		CreatedBy:   db.createAccount("task-get"),
 		Description: "bravo",
 		Completed:   nil,
 	}
 	result, err := client.CreateTask(ctx, task)
 	assert.Must(t, err)
-	got, err := client.GetTaskByIDAndAccountID(ctx, result.ID, result.AccountID)
+	got, err := client.GetTaskByIDForAccount(ctx, result.ID, result.AccountID)
 	assert.Must(t, err)
 	assert.Equal(t, result, got)
 	defer db.Close()
 }
 
-func TestGetAllTasksByAccountID(t *testing.T) {
+func TestGetAllTasksForAccount(t *testing.T) {
 	var (
 		db        = getDB(t)
 		client    = &repo.Client{db.pool}
 		ctx       = context.Background()
-		accountID = int64(3)
+		accountID = db.createAccount("task-get-all")
 	)
 	defer db.Close()
 	for i := 0; i < 10; i++ {
 		task := domain.Task{
-			AccountID:   accountID,
+			CreatedBy:   accountID,
 			Description: strconv.Itoa(i),
 		}
 		_, err := client.CreateTask(ctx, &task)
 		assert.Must(t, err)
 	}
 
-	tasks, err := client.GetAllTasksByAccountID(ctx, accountID)
+	tasks, err := client.GetAllTasksForAccount(ctx, accountID)
 	assert.Must(t, err)
 	assert.Equal(t, len(tasks), 10)
+
+	count, err := client.CountTasksByAccountID(ctx, accountID)
+	assert.Must(t, err)
+	assert.Equal(t, count, int64(10))
+
+	stats, err := client.GetTaskStatsByAccountID(ctx, accountID)
+	assert.Must(t, err)
+	assert.Equal(t, stats.Total, int64(10))
+	assert.Equal(t, stats.Completed, int64(0))
+	assert.NotNil(t, stats.LastCreated)
 }
 
 func TestMarkIncompleteTasksCompleteByAccountID(t *testing.T) {
@@ -108,12 +119,12 @@ func TestMarkIncompleteTasksCompleteByAccountID(t *testing.T) {
 		db        = getDB(t)
 		client    = &repo.Client{db.pool}
 		ctx       = context.Background()
-		accountID = int64(4)
+		accountID = db.createAccount("task-mark-complete")
 	)
 	defer db.Close()
 	for i := 0; i < 10; i++ {
 		task := domain.Task{
-			AccountID:   accountID,
+			CreatedBy:   accountID,
 			Description: strconv.Itoa(i),
 			Completed:   nil,
 		}
@@ -123,7 +134,7 @@ func TestMarkIncompleteTasksCompleteByAccountID(t *testing.T) {
 	count, err := client.MarkIncompleteTasksCompleteByAccountID(ctx, accountID)
 	assert.Must(t, err)
 	assert.Equal(t, count, int64(10))
-	tasks, err := client.GetAllTasksByAccountID(ctx, accountID)
+	tasks, err := client.GetAllTasksForAccount(ctx, accountID)
 	assert.Must(t, err)
 	for _, tt := range tasks {
 		assert.NotNil(t, tt.Completed)
@@ -139,14 +150,66 @@ func TestDeleteTask(t *testing.T) {
 	)
 	defer db.Close()
 	task := domain.Task{
-		AccountID:   1,
+		CreatedBy:   db.createAccount("task-delete"),
 		Description: "alpha",
 		Completed:   &now,
 	}
 	result, err := client.CreateTask(ctx, &task)
 	assert.Must(t, err)
-	assert.Must(t, client.DeleteTaskByIDAndAccountID(ctx, result.ID, result.AccountID))
-	got, err := client.GetTaskByIDAndAccountID(ctx, result.ID, result.AccountID)
+	assert.Must(t, client.DeleteTaskByIDForAccount(ctx, result.ID, result.AccountID))
+	got, err := client.GetTaskByIDForAccount(ctx, result.ID, result.AccountID)
+	assert.Must(t, err)
+	assert.Nil(t, got)
+}
+
+func TestTaskAssignment(t *testing.T) {
+	var (
+		db       = getDB(t)
+		client   = &repo.Client{db.pool}
+		ctx      = context.Background()
+		now      = time.Now().UTC()
+		owner    = db.createAccount("task-assignment-owner")
+		assignee = db.createAccount("task-assignment-assignee")
+		outsider = db.createAccount("task-assignment-outsider")
+	)
+	defer db.Close()
+	task, err := client.CreateTask(ctx, &domain.Task{
+		CreatedBy:   owner,
+		Description: "alpha",
+	})
+	assert.Must(t, err)
+	assert.Equal(t, task.AccountID, owner)
+	assert.Nil(t, task.AssigneeID)
+
+	_, err = client.UpdateTaskAssigneeForAccount(ctx, task.ID, assignee, &assignee)
+	assert.Equal(t, err, pgx.ErrNoRows)
+	task, err = client.UpdateTaskAssigneeForAccount(ctx, task.ID, owner, &assignee)
+	assert.Must(t, err)
+	assert.True(t, task.AssignedTo(assignee))
+
+	got, err := client.GetTaskByIDForAccount(ctx, task.ID, assignee)
+	assert.Must(t, err)
+	assert.NotNil(t, got)
+	tasks, err := client.GetAllTasksByAssigneeIDForAccount(ctx, assignee, assignee)
+	assert.Must(t, err)
+	assert.Equal(t, len(tasks), 1)
+	tasks, err = client.GetAllTasksByAssigneeIDForAccount(ctx, assignee, outsider)
+	assert.Must(t, err)
+	assert.Equal(t, len(tasks), 0)
+
+	task.Description = "bravo"
+	_, err = client.UpdateTaskForAccount(ctx, task, assignee)
+	assert.Equal(t, err, pgx.ErrNoRows)
+	_, err = client.UpdateTaskCompletedForAccount(ctx, task.ID, outsider, &now)
+	assert.Equal(t, err, pgx.ErrNoRows)
+	completed, err := client.UpdateTaskCompletedForAccount(ctx, task.ID, assignee, &now)
+	assert.Must(t, err)
+	assert.NotNil(t, completed.Completed)
+	assert.Equal(t, completed.Description, "alpha")
+
+	_, err = client.UpdateTaskAssigneeForAccount(ctx, task.ID, owner, nil)
+	assert.Must(t, err)
+	got, err = client.GetTaskByIDForAccount(ctx, task.ID, assignee)
 	assert.Must(t, err)
 	assert.Nil(t, got)
 }
@@ -0,0 +1,92 @@
+package repo
+
+import (
+	"context"
+
+	"github.com/jackc/pgx/v4"
+)
+
+// orgIDKey is the context key for the organisation queries are scoped to.
+type orgIDKey struct{}
+
+// WithOrgID returns a context which scopes the queries made with it to an
+// organisation. The organisation is set as todo.org_id for the transaction
+// each query runs in, and queries on tasks only match the organisation's
+// tasks. Without it they only match tasks which don't belong to an
+// organisation.
+//
+// The tasks table also has a row-level security policy based on todo.org_id,
+// which is enforced when connecting as a role which doesn't own the table.
+func WithOrgID(ctx context.Context, orgID int64) context.Context {
+	return context.WithValue(ctx, orgIDKey{}, orgID)
+}
+
+// OrgIDFromContext returns the organisation which ctx scopes queries to, and
+// whether there is one.
+func OrgIDFromContext(ctx context.Context) (int64, bool) {
+	orgID, ok := ctx.Value(orgIDKey{}).(int64)
+	return orgID, ok
+}
+
+// currentOrgSQL is the organisation the current transaction is scoped to, or
+// NULL.
+const currentOrgSQL = `nullif(current_setting('todo.org_id', true), '')::bigint`
+
+// tenantRow is a row from a query made in an organisation scoped transaction,
+// which ends when the row is scanned.
+type tenantRow struct {
+	ctx context.Context
+	tx  pgx.Tx
+	row pgx.Row
+}
+
+// Scan implements the pgx.Row interface.
+func (r *tenantRow) Scan(dest ...interface{}) error {
+	if err := r.row.Scan(dest...); err != nil {
+		_ = r.tx.Rollback(r.ctx)
+		return err
+	}
+	return r.tx.Commit(r.ctx)
+}
+
+// tenantRows are rows from a query made in an organisation scoped
+// transaction, which ends when the rows are closed.
+type tenantRows struct {
+	pgx.Rows
+	ctx    context.Context
+	tx     pgx.Tx
+	closed bool
+}
+
+// Next implements the pgx.Rows interface.
+func (r *tenantRows) Next() bool {
+	if r.Rows.Next() {
+		return true
+	}
+	r.Close()
+	return false
+}
+
+// Close implements the pgx.Rows interface.
+func (r *tenantRows) Close() {
+	if r.closed {
+		return
+	}
+	r.closed = true
+	r.Rows.Close()
+	if r.Rows.Err() != nil {
+		_ = r.tx.Rollback(r.ctx)
+		return
+	}
+	_ = r.tx.Commit(r.ctx)
+}
+
+// errRow is a row which failed before its query was made.
+type errRow struct {
+	err error
+}
+
+// Scan implements the pgx.Row interface.
+func (r errRow) Scan(dest ...interface{}) error {
+	return r.err
+}
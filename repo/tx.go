@@ -0,0 +1,92 @@
+package repo
+
+import (
+	"context"
+	"errors"
+	"math/rand"
+	"time"
+
+	"github.com/jackc/pgconn"
+	"github.com/jackc/pgx/v4"
+)
+
+// DB is the connection a Client makes its queries with: a *pgxpool.Pool, or
+// the pgx.Tx of a transaction started by WithTx.
+type DB interface {
+	Begin(ctx context.Context) (pgx.Tx, error)
+	Exec(ctx context.Context, sql string, args ...interface{}) (pgconn.CommandTag, error)
+	Query(ctx context.Context, sql string, args ...interface{}) (pgx.Rows, error)
+	QueryRow(ctx context.Context, sql string, args ...interface{}) pgx.Row
+}
+
+// maxTxAttempts limits how many times WithTx runs a transaction which fails
+// with a serialization failure or deadlock.
+const maxTxAttempts = 5
+
+// txRetryDelay is the base delay before a failed transaction is retried. The
+// delay grows with each attempt and is jittered, so that transactions which
+// conflicted don't conflict again.
+const txRetryDelay = 10 * time.Millisecond
+
+// WithTx calls fn with a client whose queries run in a transaction, which is
+// committed if fn returns nil and rolled back otherwise. It's WithTxOptions
+// with the database's default isolation level, read committed.
+func (c *Client) WithTx(ctx context.Context, fn func(tx *Client) error) error {
+	return c.WithTxOptions(ctx, pgx.TxOptions{}, fn)
+}
+
+// WithTxOptions calls fn with a client whose queries run in a transaction
+// started with opts, which is committed if fn returns nil and rolled back
+// otherwise. If the transaction fails with a serialization failure or a
+// deadlock, which the serializable and repeatable read isolation levels
+// report for concurrent changes, it's rolled back and fn is called again, up
+// to maxTxAttempts times, so fn shouldn't have side effects outside the
+// database.
+//
+// Called on a client which is already in a transaction, it runs fn in a
+// savepoint instead, which is released if fn returns nil and rolled back to
+// otherwise. The savepoint is part of the outer transaction, so opts are
+// ignored and failures are retried by the outermost WithTxOptions.
+//
+// The client passed to fn must only be used by one goroutine at a time, and
+// not after fn returns.
+func (c *Client) WithTxOptions(ctx context.Context, opts pgx.TxOptions, fn func(tx *Client) error) error {
+	if _, ok := c.Database.(pgx.Tx); ok {
+		return c.runTx(ctx, opts, fn)
+	}
+	for attempt := 1; ; attempt++ {
+		err := c.runTx(ctx, opts, fn)
+		if err == nil || !isRetryable(err) || attempt == maxTxAttempts {
+			return err
+		}
+		delay := time.Duration(attempt) * txRetryDelay
+		delay += time.Duration(rand.Int63n(int64(delay)))
+		select {
+		case <-ctx.Done():
+			return err
+		case <-time.After(delay):
+		}
+	}
+}
+
+// runTx runs fn in a single transaction or savepoint.
+func (c *Client) runTx(ctx context.Context, opts pgx.TxOptions, fn func(tx *Client) error) error {
+	tx, err := c.beginTx(ctx, opts)
+	if err != nil {
+		return err
+	}
+	defer func() {
+		_ = tx.Rollback(ctx) // no-op once committed
+	}()
+	if err := fn(&Client{Database: tx}); err != nil {
+		return err
+	}
+	return tx.Commit(ctx)
+}
+
+// isRetryable reports whether err is a serialization failure or deadlock,
+// after which the transaction can be retried.
+func isRetryable(err error) bool {
+	var pgErr *pgconn.PgError
+	return errors.As(err, &pgErr) && (pgErr.Code == "40001" || pgErr.Code == "40P01")
+}
@@ -0,0 +1,112 @@
+package repo_test
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/deliveroo/assert-go"
+	"github.com/deliveroo/todo-api/domain"
+	"github.com/deliveroo/todo-api/repo"
+	"github.com/jackc/pgconn"
+	"github.com/jackc/pgx/v4"
+)
+
+func TestWithTx(t *testing.T) {
+	var (
+		db     = getDB(t)
+		client = &repo.Client{db.pool}
+		ctx    = context.Background()
+	)
+	defer db.Close()
+
+	account, err := client.CreateAccount(ctx, &domain.Account{
+		Username:       "tx-owner",
+		PasswordDigest: "password-digest",
+		PasswordSalt:   "password-salt",
+	})
+	assert.Must(t, err)
+	createTask := func(tx *repo.Client, description string) *domain.Task {
+		task, err := tx.CreateTask(ctx, &domain.Task{
+			AccountID:   account.ID,
+			CreatedBy:   account.ID,
+			Description: description,
+		})
+		assert.Must(t, err)
+		return task
+	}
+	getTask := func(id int64) *domain.Task {
+		task, err := client.GetTaskByIDForAccount(ctx, id, account.ID)
+		assert.Must(t, err)
+		return task
+	}
+	errFailed := errors.New("failed")
+
+	t.Run("commit", func(t *testing.T) {
+		var task *domain.Task
+		assert.Must(t, client.WithTx(ctx, func(tx *repo.Client) error {
+			task = createTask(tx, "committed")
+			return nil
+		}))
+		assert.NotNil(t, getTask(task.ID))
+	})
+	t.Run("rollback", func(t *testing.T) {
+		var task *domain.Task
+		err := client.WithTx(ctx, func(tx *repo.Client) error {
+			task = createTask(tx, "rolled back")
+			return errFailed
+		})
+		assert.Equal(t, err, errFailed)
+		assert.Nil(t, getTask(task.ID))
+	})
+	t.Run("savepoint", func(t *testing.T) {
+		var outer, inner *domain.Task
+		assert.Must(t, client.WithTx(ctx, func(tx *repo.Client) error {
+			outer = createTask(tx, "outer")
+			err := tx.WithTx(ctx, func(tx *repo.Client) error {
+				inner = createTask(tx, "inner")
+				return errFailed
+			})
+			assert.Equal(t, err, errFailed)
+			return nil
+		}))
+		assert.NotNil(t, getTask(outer.ID))
+		assert.Nil(t, getTask(inner.ID))
+	})
+	t.Run("retry", func(t *testing.T) {
+		var calls int
+		err := client.WithTxOptions(ctx, pgx.TxOptions{IsoLevel: pgx.Serializable}, func(tx *repo.Client) error {
+			calls++
+			if calls == 1 {
+				return &pgconn.PgError{Code: "40001"}
+			}
+			return nil
+		})
+		assert.Must(t, err)
+		assert.Equal(t, calls, 2)
+	})
+	t.Run("retry limit", func(t *testing.T) {
+		var calls int
+		err := client.WithTx(ctx, func(tx *repo.Client) error {
+			calls++
+			return &pgconn.PgError{Code: "40P01"}
+		})
+		assert.True(t, err != nil)
+		assert.Equal(t, calls, 5)
+	})
+	t.Run("nested retry", func(t *testing.T) {
+		// Failures in savepoints are retried by the outermost transaction.
+		var calls int
+		err := client.WithTx(ctx, func(tx *repo.Client) error {
+			return tx.WithTx(ctx, func(tx *repo.Client) error {
+				calls++
+				if calls == 1 {
+					return &pgconn.PgError{Code: "40001"}
+				}
+				return nil
+			})
+		})
+		assert.Must(t, err)
+		assert.Equal(t, calls, 2)
+	})
+}
@@ -0,0 +1,37 @@
+package repo
+
+import (
+	"context"
+
+	"github.com/jackc/pgtype"
+	"github.com/jackc/pgx/v4"
+)
+
+// AfterConnect prepares a new connection for use by the Client. It should be
+// set as the AfterConnect hook of the pool the Client uses. Times are read in
+// UTC, and the session's time zone is UTC, so that times formatted by the
+// database, e.g. in outbox payloads, don't depend on the server's.
+func AfterConnect(ctx context.Context, conn *pgx.Conn) error {
+	conn.ConnInfo().RegisterDataType(pgtype.DataType{
+		Value: &utcTimestamptz{},
+		Name:  "timestamptz",
+		OID:   pgtype.TimestamptzOID,
+	})
+	_, err := conn.Exec(ctx, `SET TIME ZONE 'UTC';`)
+	return err
+}
+
+// utcTimestamptz is a timestamptz which scans in UTC, rather than the local
+// time zone, so that times read from the database are in UTC as they were
+// before columns had time zones.
+type utcTimestamptz struct {
+	pgtype.Timestamptz
+}
+
+// AssignTo implements the pgtype.Value interface.
+func (t *utcTimestamptz) AssignTo(dst interface{}) error {
+	if t.Status == pgtype.Present {
+		t.Time = t.Time.UTC()
+	}
+	return t.Timestamptz.AssignTo(dst)
+}
@@ -0,0 +1,146 @@
+package repo
+
+import (
+	"context"
+
+	"github.com/deliveroo/todo-api/domain"
+	"github.com/jackc/pgx/v4"
+)
+
+// CreateWebhook inserts a webhook into the database.
+func (c *Client) CreateWebhook(ctx context.Context, w *domain.Webhook) (*domain.Webhook, error) {
+	row := c.queryRow(ctx, `
+		INSERT INTO webhooks (account_id, url, events, secret)
+		VALUES ($1, $2, $3, $4)
+		RETURNING id, public_id, account_id, url, events, secret, created;
+	`, w.AccountID, w.URL, w.Events, w.Secret)
+	return scanWebhook(row)
+}
+
+// GetWebhookByID fetches a webhook from the database, or returns nil if not
+// found.
+func (c *Client) GetWebhookByID(ctx context.Context, id int64) (*domain.Webhook, error) {
+	row := c.queryRow(ctx, `
+		SELECT id, public_id, account_id, url, events, secret, created
+		FROM webhooks
+		WHERE id = $1;
+	`, id)
+	return scanWebhookOrNil(row)
+}
+
+// GetWebhookByPublicIDAndAccountID fetches a webhook registered by an
+// account from the database, or returns nil if not found.
+func (c *Client) GetWebhookByPublicIDAndAccountID(ctx context.Context, publicID string, accountID int64) (*domain.Webhook, error) {
+	row := c.queryRow(ctx, `
+		SELECT id, public_id, account_id, url, events, secret, created
+		FROM webhooks
+		WHERE public_id = $1
+		AND account_id = $2;
+	`, publicID, accountID)
+	return scanWebhookOrNil(row)
+}
+
+// GetAllWebhooksByAccountID fetches the webhooks an account has registered
+// from the database.
+func (c *Client) GetAllWebhooksByAccountID(ctx context.Context, accountID int64) ([]*domain.Webhook, error) {
+	rows, err := c.query(ctx, `
+		SELECT id, public_id, account_id, url, events, secret, created
+		FROM webhooks
+		WHERE account_id = $1
+		ORDER BY created DESC, id DESC;
+	`, accountID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var result []*domain.Webhook
+	for rows.Next() {
+		w, err := scanWebhook(rows)
+		if err != nil {
+			return nil, err
+		}
+		result = append(result, w)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return result, nil
+}
+
+// UpdateWebhook changes a webhook's URL and events in the database. The
+// webhook is identified by its public id. It returns pgx.ErrNoRows if the
+// account hasn't registered the webhook.
+func (c *Client) UpdateWebhook(ctx context.Context, w *domain.Webhook) (*domain.Webhook, error) {
+	row := c.queryRow(ctx, `
+		UPDATE webhooks
+		SET url = $3, events = $4
+		WHERE public_id = $1
+		AND account_id = $2
+		RETURNING id, public_id, account_id, url, events, secret, created;
+	`, w.PublicID, w.AccountID, w.URL, w.Events)
+	return scanWebhook(row)
+}
+
+// DeleteWebhookByPublicIDAndAccountID deletes a webhook, and its deliveries,
+// from the database. It returns pgx.ErrNoRows if the account hasn't
+// registered the webhook.
+func (c *Client) DeleteWebhookByPublicIDAndAccountID(ctx context.Context, publicID string, accountID int64) error {
+	tx, err := c.begin(ctx)
+	if err != nil {
+		return err
+	}
+	defer func() {
+		_ = tx.Rollback(ctx) // no-op once committed
+	}()
+	n, err := deleteWebhooks(ctx, tx, `public_id = $1 AND account_id = $2`, publicID, accountID)
+	if err != nil {
+		return err
+	}
+	if n == 0 {
+		return pgx.ErrNoRows
+	}
+	return tx.Commit(ctx)
+}
+
+// deleteWebhooks deletes the webhooks matching where, and their deliveries.
+// It returns the number of webhooks deleted.
+func deleteWebhooks(ctx context.Context, tx pgx.Tx, where string, args ...interface{}) (int64, error) {
+	if _, err := tx.Exec(ctx, `
+		DELETE FROM webhook_deliveries
+		WHERE webhook_id IN (SELECT id FROM webhooks WHERE `+where+`);
+	`, args...); err != nil {
+		return 0, err
+	}
+	tag, err := tx.Exec(ctx, `DELETE FROM webhooks WHERE `+where+`;`, args...)
+	if err != nil {
+		return 0, err
+	}
+	return tag.RowsAffected(), nil
+}
+
+func scanWebhookOrNil(row pgx.Row) (*domain.Webhook, error) {
+	w, err := scanWebhook(row)
+	if err != nil {
+		if isErrNoRows(err) {
+			return nil, nil
+		}
+		return nil, err
+	}
+	return w, nil
+}
+
+func scanWebhook(row pgx.Row) (*domain.Webhook, error) {
+	var result domain.Webhook
+	if err := row.Scan(
+		&result.ID,
+		&result.PublicID,
+		&result.AccountID,
+		&result.URL,
+		&result.Events,
+		&result.Secret,
+		&result.Created,
+	); err != nil {
+		return nil, err
+	}
+	return &result, nil
+}
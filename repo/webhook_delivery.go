@@ -0,0 +1,132 @@
+package repo
+
+import (
+	"context"
+	"time"
+
+	"github.com/deliveroo/todo-api/domain"
+	"github.com/jackc/pgx/v4"
+)
+
+const webhookDeliveryColumns = `
+	id, public_id, webhook_id, event, payload, status, attempts, next_attempt,
+	response_status, last_error, delivered, created`
+
+// CreateWebhookDeliveries queues an event for delivery to each of an
+// account's webhooks which is subscribed to it. It returns the number of
+// deliveries queued.
+func (c *Client) CreateWebhookDeliveries(ctx context.Context, accountID int64, event string, payload []byte) (int64, error) {
+	tag, err := c.exec(ctx, `
+		INSERT INTO webhook_deliveries (webhook_id, event, payload, next_attempt)
+		SELECT id, $2, $3, $4
+		FROM webhooks
+		WHERE account_id = $1
+		AND $2 = ANY(events);
+	`, accountID, event, string(payload), time.Now().UTC())
+	if err != nil {
+		return 0, err
+	}
+	return tag.RowsAffected(), nil
+}
+
+// CreateWebhookDelivery queues an event for delivery to a webhook, whatever
+// events it's subscribed to.
+func (c *Client) CreateWebhookDelivery(ctx context.Context, d *domain.WebhookDelivery) (*domain.WebhookDelivery, error) {
+	row := c.queryRow(ctx, `
+		INSERT INTO webhook_deliveries (webhook_id, event, payload, next_attempt)
+		VALUES ($1, $2, $3, $4)
+		RETURNING `+webhookDeliveryColumns+`;
+	`, d.WebhookID, d.Event, string(d.Payload), time.Now().UTC())
+	return scanWebhookDelivery(row)
+}
+
+// ClaimWebhookDeliveries fetches up to limit pending deliveries which are due
+// from the database, and postpones their next attempt by lease so that no
+// other worker claims them meanwhile. If the worker stops before recording the
+// outcome, the deliveries are attempted again once the lease expires.
+func (c *Client) ClaimWebhookDeliveries(ctx context.Context, limit int, lease time.Duration) ([]*domain.WebhookDelivery, error) {
+	now := time.Now().UTC()
+	return c.queryWebhookDeliveries(ctx, `
+		UPDATE webhook_deliveries
+		SET next_attempt = $3
+		WHERE id IN (
+			SELECT id
+			FROM webhook_deliveries
+			WHERE status = 'pending'
+			AND next_attempt <= $2
+			ORDER BY next_attempt
+			LIMIT $1
+			FOR UPDATE SKIP LOCKED
+		)
+		RETURNING `+webhookDeliveryColumns+`;
+	`, limit, now, now.Add(lease))
+}
+
+// UpdateWebhookDelivery records the outcome of attempting a delivery in the
+// database.
+func (c *Client) UpdateWebhookDelivery(ctx context.Context, d *domain.WebhookDelivery) (*domain.WebhookDelivery, error) {
+	row := c.queryRow(ctx, `
+		UPDATE webhook_deliveries
+		SET status = $2, attempts = $3, next_attempt = $4, response_status = $5, last_error = $6, delivered = $7
+		WHERE id = $1
+		RETURNING `+webhookDeliveryColumns+`;
+	`, d.ID, d.Status, d.Attempts, d.NextAttempt, d.ResponseStatus, d.LastError, d.Delivered)
+	return scanWebhookDelivery(row)
+}
+
+// GetWebhookDeliveriesByWebhookID fetches a webhook's most recent deliveries
+// from the database, newest first.
+func (c *Client) GetWebhookDeliveriesByWebhookID(ctx context.Context, webhookID int64, limit int) ([]*domain.WebhookDelivery, error) {
+	return c.queryWebhookDeliveries(ctx, `
+		SELECT `+webhookDeliveryColumns+`
+		FROM webhook_deliveries
+		WHERE webhook_id = $1
+		ORDER BY created DESC, id DESC
+		LIMIT $2;
+	`, webhookID, limit)
+}
+
+func (c *Client) queryWebhookDeliveries(ctx context.Context, sql string, args ...interface{}) ([]*domain.WebhookDelivery, error) {
+	rows, err := c.query(ctx, sql, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var result []*domain.WebhookDelivery
+	for rows.Next() {
+		d, err := scanWebhookDelivery(rows)
+		if err != nil {
+			return nil, err
+		}
+		result = append(result, d)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return result, nil
+}
+
+func scanWebhookDelivery(row pgx.Row) (*domain.WebhookDelivery, error) {
+	var (
+		result  domain.WebhookDelivery
+		payload string
+	)
+	if err := row.Scan(
+		&result.ID,
+		&result.PublicID,
+		&result.WebhookID,
+		&result.Event,
+		&payload,
+		&result.Status,
+		&result.Attempts,
+		&result.NextAttempt,
+		&result.ResponseStatus,
+		&result.LastError,
+		&result.Delivered,
+		&result.Created,
+	); err != nil {
+		return nil, err
+	}
+	result.Payload = []byte(payload)
+	return &result, nil
+}
@@ -0,0 +1,100 @@
+package repo_test
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/deliveroo/assert-go"
+	"github.com/deliveroo/todo-api/domain"
+	"github.com/deliveroo/todo-api/repo"
+	"github.com/jackc/pgx/v4"
+)
+
+func TestWebhooks(t *testing.T) {
+	var (
+		db     = getDB(t)
+		client = &repo.Client{db.pool}
+		ctx    = context.Background()
+	)
+	defer db.Close()
+
+	account, err := client.CreateAccount(ctx, &domain.Account{
+		Username:       "webhook-owner",
+		PasswordDigest: "password-digest",
+		PasswordSalt:   "password-salt",
+	})
+	assert.Must(t, err)
+
+	w := &domain.Webhook{
+		AccountID: account.ID,
+		Events:    []string{domain.EventTaskCreated},
+		URL:       "https://example.com/hooks",
+	}
+	w.NewSecret()
+	w, err = client.CreateWebhook(ctx, w)
+	assert.Must(t, err)
+
+	t.Run("get", func(t *testing.T) {
+		assert.True(t, domain.ValidPublicID(w.PublicID))
+		got, err := client.GetWebhookByPublicIDAndAccountID(ctx, w.PublicID, account.ID)
+		assert.Must(t, err)
+		assert.Equal(t, got, w)
+		none, err := client.GetWebhookByPublicIDAndAccountID(ctx, w.PublicID, account.ID+1)
+		assert.Must(t, err)
+		assert.Nil(t, none)
+		all, err := client.GetAllWebhooksByAccountID(ctx, account.ID)
+		assert.Must(t, err)
+		assert.Equal(t, len(all), 1)
+	})
+	t.Run("update", func(t *testing.T) {
+		_, err := client.UpdateWebhook(ctx, &domain.Webhook{PublicID: w.PublicID, AccountID: account.ID + 1, URL: w.URL})
+		assert.Equal(t, err, pgx.ErrNoRows)
+		w.Events = []string{domain.EventTaskCreated, domain.EventTaskDeleted}
+		updated, err := client.UpdateWebhook(ctx, w)
+		assert.Must(t, err)
+		assert.Equal(t, updated.Events, w.Events)
+	})
+	t.Run("deliveries", func(t *testing.T) {
+		n, err := client.CreateWebhookDeliveries(ctx, account.ID, domain.EventTaskUpdated, []byte(`{}`))
+		assert.Must(t, err)
+		assert.Equal(t, n, int64(0))
+		n, err = client.CreateWebhookDeliveries(ctx, account.ID, domain.EventTaskCreated, []byte(`{"event":"task.created"}`))
+		assert.Must(t, err)
+		assert.Equal(t, n, int64(1))
+
+		claimed, err := client.ClaimWebhookDeliveries(ctx, 10, time.Minute)
+		assert.Must(t, err)
+		assert.Equal(t, len(claimed), 1)
+		assert.True(t, domain.ValidPublicID(claimed[0].PublicID))
+		assert.Equal(t, string(claimed[0].Payload), `{"event":"task.created"}`)
+
+		// Claimed deliveries aren't claimed again until the lease expires.
+		again, err := client.ClaimWebhookDeliveries(ctx, 10, time.Minute)
+		assert.Must(t, err)
+		assert.Equal(t, len(again), 0)
+
+		d := claimed[0]
+		status := 200
+		now := time.Now().UTC()
+		d.Attempts++
+		d.Status = domain.DeliveryDelivered
+		d.ResponseStatus = &status
+		d.Delivered = &now
+		_, err = client.UpdateWebhookDelivery(ctx, d)
+		assert.Must(t, err)
+
+		log, err := client.GetWebhookDeliveriesByWebhookID(ctx, w.ID, 10)
+		assert.Must(t, err)
+		assert.Equal(t, len(log), 1)
+		assert.Equal(t, log[0].Status, domain.DeliveryDelivered)
+		assert.Equal(t, *log[0].ResponseStatus, 200)
+	})
+	t.Run("delete", func(t *testing.T) {
+		assert.Equal(t, client.DeleteWebhookByPublicIDAndAccountID(ctx, w.PublicID, account.ID+1), pgx.ErrNoRows)
+		assert.Must(t, client.DeleteWebhookByPublicIDAndAccountID(ctx, w.PublicID, account.ID))
+		log, err := client.GetWebhookDeliveriesByWebhookID(ctx, w.ID, 10)
+		assert.Must(t, err)
+		assert.Equal(t, len(log), 0)
+	})
+}
@@ -16,20 +16,148 @@ SET xmloption = content;
 SET client_min_messages = warning;
 SET row_security = off;
 
+--
+-- Name: citext; Type: EXTENSION; Schema: -; Owner: -
+--
+
+CREATE EXTENSION IF NOT EXISTS citext WITH SCHEMA public;
+
+
+--
+-- Name: EXTENSION citext; Type: COMMENT; Schema: -; Owner: -
+--
+
+COMMENT ON EXTENSION citext IS 'data type for case-insensitive character strings';
+
+
+--
+-- Name: pgcrypto; Type: EXTENSION; Schema: -; Owner: -
+--
+
+CREATE EXTENSION IF NOT EXISTS pgcrypto WITH SCHEMA public;
+
+
+--
+-- Name: EXTENSION pgcrypto; Type: COMMENT; Schema: -; Owner: -
+--
+
+COMMENT ON EXTENSION pgcrypto IS 'cryptographic functions';
+
+
+--
+-- Name: uuid_generate_v7(timestamp with time zone); Type: FUNCTION; Schema: public; Owner: -
+--
+
+CREATE FUNCTION public.uuid_generate_v7(ts timestamp with time zone DEFAULT clock_timestamp()) RETURNS uuid
+    LANGUAGE sql
+    AS $$
+    SELECT encode(
+        set_bit(
+            set_bit(
+                overlay(uuid_send(gen_random_uuid())
+                    PLACING substring(int8send(floor(extract(epoch FROM ts) * 1000)::bigint) FROM 3)
+                    FROM 1 FOR 6),
+                52, 1),
+            53, 1),
+        'hex')::uuid;
+$$;
+
+
 SET default_tablespace = '';
 
 SET default_with_oids = false;
 
+--
+-- Name: access_tokens; Type: TABLE; Schema: public; Owner: -
+--
+
+CREATE TABLE public.access_tokens (
+    id bigint NOT NULL,
+    account_id bigint NOT NULL,
+    name text NOT NULL,
+    token_digest text NOT NULL,
+    scopes text[] NOT NULL,
+    expires timestamp with time zone,
+    last_used timestamp with time zone,
+    created timestamp with time zone DEFAULT CURRENT_TIMESTAMP NOT NULL,
+    client_id bigint,
+    public_id uuid DEFAULT public.uuid_generate_v7() NOT NULL
+);
+
+
+--
+-- Name: access_tokens_id_seq; Type: SEQUENCE; Schema: public; Owner: -
+--
+
+CREATE SEQUENCE public.access_tokens_id_seq
+    START WITH 1
+    INCREMENT BY 1
+    NO MINVALUE
+    NO MAXVALUE
+    CACHE 1;
+
+
+--
+-- Name: access_tokens_id_seq; Type: SEQUENCE OWNED BY; Schema: public; Owner: -
+--
+
+ALTER SEQUENCE public.access_tokens_id_seq OWNED BY public.access_tokens.id;
+
+
+--
+-- Name: account_identities; Type: TABLE; Schema: public; Owner: -
+--
+
+CREATE TABLE public.account_identities (
+    id bigint NOT NULL,
+    account_id bigint NOT NULL,
+    issuer text NOT NULL,
+    subject text NOT NULL,
+    email text DEFAULT ''::text NOT NULL,
+    created timestamp with time zone DEFAULT CURRENT_TIMESTAMP NOT NULL,
+    public_id uuid DEFAULT public.uuid_generate_v7() NOT NULL
+);
+
+
+--
+-- Name: account_identities_id_seq; Type: SEQUENCE; Schema: public; Owner: -
+--
+
+CREATE SEQUENCE public.account_identities_id_seq
+    START WITH 1
+    INCREMENT BY 1
+    NO MINVALUE
+    NO MAXVALUE
+    CACHE 1;
+
+
+--
+-- Name: account_identities_id_seq; Type: SEQUENCE OWNED BY; Schema: public; Owner: -
+--
+
+ALTER SEQUENCE public.account_identities_id_seq OWNED BY public.account_identities.id;
+
+
 --
 -- Name: accounts; Type: TABLE; Schema: public; Owner: -
 --
 
 CREATE TABLE public.accounts (
-    id integer NOT NULL,
-    username text,
+    id bigint NOT NULL,
+    username public.citext NOT NULL,
     password_digest text NOT NULL,
     password_salt text NOT NULL,
-    created timestamp without time zone DEFAULT CURRENT_TIMESTAMP NOT NULL
+    created timestamp with time zone DEFAULT CURRENT_TIMESTAMP NOT NULL,
+    totp_secret text DEFAULT ''::text NOT NULL,
+    totp_enabled timestamp with time zone,
+    email text DEFAULT ''::text NOT NULL,
+    verified_at timestamp with time zone,
+    role text DEFAULT 'user'::text NOT NULL,
+    suspended_at timestamp with time zone,
+    suspension_reason text DEFAULT ''::text NOT NULL,
+    change_seq bigint DEFAULT 0 NOT NULL,
+    public_id uuid DEFAULT public.uuid_generate_v7() NOT NULL,
+    totp_last_step bigint
 );
 
 
@@ -38,7 +166,6 @@ CREATE TABLE public.accounts (
 --
 
 CREATE SEQUENCE public.accounts_id_seq
-    AS integer
     START WITH 1
     INCREMENT BY 1
     NO MINVALUE
@@ -53,34 +180,238 @@ CREATE SEQUENCE public.accounts_id_seq
 ALTER SEQUENCE public.accounts_id_seq OWNED BY public.accounts.id;
 
 
+--
+-- Name: audit_entries; Type: TABLE; Schema: public; Owner: -
+--
+
+CREATE TABLE public.audit_entries (
+    id bigint NOT NULL,
+    actor_id bigint,
+    action text NOT NULL,
+    account_id bigint NOT NULL,
+    details jsonb DEFAULT '{}'::jsonb NOT NULL,
+    created timestamp with time zone DEFAULT CURRENT_TIMESTAMP NOT NULL,
+    public_id uuid DEFAULT public.uuid_generate_v7() NOT NULL
+);
+
+
+--
+-- Name: audit_entries_id_seq; Type: SEQUENCE; Schema: public; Owner: -
+--
+
+CREATE SEQUENCE public.audit_entries_id_seq
+    START WITH 1
+    INCREMENT BY 1
+    NO MINVALUE
+    NO MAXVALUE
+    CACHE 1;
+
+
+--
+-- Name: audit_entries_id_seq; Type: SEQUENCE OWNED BY; Schema: public; Owner: -
+--
+
+ALTER SEQUENCE public.audit_entries_id_seq OWNED BY public.audit_entries.id;
+
+
+--
+-- Name: email_verifications; Type: TABLE; Schema: public; Owner: -
+--
+
+CREATE TABLE public.email_verifications (
+    id bigint NOT NULL,
+    account_id bigint NOT NULL,
+    email text NOT NULL,
+    token_digest text NOT NULL,
+    expires timestamp with time zone NOT NULL,
+    used timestamp with time zone,
+    created timestamp with time zone DEFAULT CURRENT_TIMESTAMP NOT NULL
+);
+
+
+--
+-- Name: email_verifications_id_seq; Type: SEQUENCE; Schema: public; Owner: -
+--
+
+CREATE SEQUENCE public.email_verifications_id_seq
+    START WITH 1
+    INCREMENT BY 1
+    NO MINVALUE
+    NO MAXVALUE
+    CACHE 1;
+
+
+--
+-- Name: email_verifications_id_seq; Type: SEQUENCE OWNED BY; Schema: public; Owner: -
+--
+
+ALTER SEQUENCE public.email_verifications_id_seq OWNED BY public.email_verifications.id;
+
+
+--
+-- Name: failed_logins; Type: TABLE; Schema: public; Owner: -
+--
+
+CREATE TABLE public.failed_logins (
+    id bigint NOT NULL,
+    account_id bigint NOT NULL,
+    ip_address text NOT NULL,
+    user_agent text NOT NULL,
+    created timestamp with time zone DEFAULT CURRENT_TIMESTAMP NOT NULL,
+    public_id uuid DEFAULT public.uuid_generate_v7() NOT NULL
+);
+
+
+--
+-- Name: failed_logins_id_seq; Type: SEQUENCE; Schema: public; Owner: -
+--
+
+CREATE SEQUENCE public.failed_logins_id_seq
+    START WITH 1
+    INCREMENT BY 1
+    NO MINVALUE
+    NO MAXVALUE
+    CACHE 1;
+
+
+--
+-- Name: failed_logins_id_seq; Type: SEQUENCE OWNED BY; Schema: public; Owner: -
+--
+
+ALTER SEQUENCE public.failed_logins_id_seq OWNED BY public.failed_logins.id;
+
+
+--
+-- Name: list_invitations; Type: TABLE; Schema: public; Owner: -
+--
+
+CREATE TABLE public.list_invitations (
+    id bigint NOT NULL,
+    list_id bigint NOT NULL,
+    inviter_id bigint NOT NULL,
+    invitee_id bigint NOT NULL,
+    role text NOT NULL,
+    status text DEFAULT 'pending'::text NOT NULL,
+    responded timestamp with time zone,
+    created timestamp with time zone DEFAULT CURRENT_TIMESTAMP NOT NULL,
+    public_id uuid DEFAULT public.uuid_generate_v7() NOT NULL
+);
+
+
+--
+-- Name: list_invitations_id_seq; Type: SEQUENCE; Schema: public; Owner: -
+--
+
+CREATE SEQUENCE public.list_invitations_id_seq
+    START WITH 1
+    INCREMENT BY 1
+    NO MINVALUE
+    NO MAXVALUE
+    CACHE 1;
+
+
+--
+-- Name: list_invitations_id_seq; Type: SEQUENCE OWNED BY; Schema: public; Owner: -
+--
+
+ALTER SEQUENCE public.list_invitations_id_seq OWNED BY public.list_invitations.id;
+
+
+--
+-- Name: list_members; Type: TABLE; Schema: public; Owner: -
+--
+
+CREATE TABLE public.list_members (
+    id bigint NOT NULL,
+    list_id bigint NOT NULL,
+    account_id bigint NOT NULL,
+    role text NOT NULL,
+    created timestamp with time zone DEFAULT CURRENT_TIMESTAMP NOT NULL
+);
+
+
+--
+-- Name: list_members_id_seq; Type: SEQUENCE; Schema: public; Owner: -
+--
+
+CREATE SEQUENCE public.list_members_id_seq
+    START WITH 1
+    INCREMENT BY 1
+    NO MINVALUE
+    NO MAXVALUE
+    CACHE 1;
+
+
+--
+-- Name: list_members_id_seq; Type: SEQUENCE OWNED BY; Schema: public; Owner: -
+--
+
+ALTER SEQUENCE public.list_members_id_seq OWNED BY public.list_members.id;
+
+
+--
+-- Name: lists; Type: TABLE; Schema: public; Owner: -
+--
+
+CREATE TABLE public.lists (
+    id bigint NOT NULL,
+    account_id bigint NOT NULL,
+    name text NOT NULL,
+    created timestamp with time zone DEFAULT CURRENT_TIMESTAMP NOT NULL,
+    public_id uuid DEFAULT public.uuid_generate_v7() NOT NULL
+);
+
+
+--
+-- Name: lists_id_seq; Type: SEQUENCE; Schema: public; Owner: -
+--
+
+CREATE SEQUENCE public.lists_id_seq
+    START WITH 1
+    INCREMENT BY 1
+    NO MINVALUE
+    NO MAXVALUE
+    CACHE 1;
+
+
+--
+-- Name: lists_id_seq; Type: SEQUENCE OWNED BY; Schema: public; Owner: -
+--
+
+ALTER SEQUENCE public.lists_id_seq OWNED BY public.lists.id;
+
+
 --
 -- Name: migrations; Type: TABLE; Schema: public; Owner: -
 --
 
 CREATE TABLE public.migrations (
-    name text
+    name text,
+    checksum text,
+    applied timestamp without time zone
 );
 
 
 --
--- Name: tasks; Type: TABLE; Schema: public; Owner: -
+-- Name: oauth_clients; Type: TABLE; Schema: public; Owner: -
 --
 
-CREATE TABLE public.tasks (
-    id integer NOT NULL,
-    account_id integer NOT NULL,
-    description text NOT NULL,
-    created timestamp without time zone DEFAULT CURRENT_TIMESTAMP NOT NULL,
-    completed timestamp without time zone
+CREATE TABLE public.oauth_clients (
+    id bigint NOT NULL,
+    account_id bigint NOT NULL,
+    client_id text NOT NULL,
+    name text NOT NULL,
+    redirect_uris text[] NOT NULL,
+    secret_digest text DEFAULT ''::text NOT NULL,
+    created timestamp with time zone DEFAULT CURRENT_TIMESTAMP NOT NULL
 );
 
 
 --
--- Name: tasks_id_seq; Type: SEQUENCE; Schema: public; Owner: -
+-- Name: oauth_clients_id_seq; Type: SEQUENCE; Schema: public; Owner: -
 --
 
-CREATE SEQUENCE public.tasks_id_seq
-    AS integer
+CREATE SEQUENCE public.oauth_clients_id_seq
     START WITH 1
     INCREMENT BY 1
     NO MINVALUE
@@ -89,47 +420,1407 @@ CREATE SEQUENCE public.tasks_id_seq
 
 
 --
--- Name: tasks_id_seq; Type: SEQUENCE OWNED BY; Schema: public; Owner: -
+-- Name: oauth_clients_id_seq; Type: SEQUENCE OWNED BY; Schema: public; Owner: -
 --
 
-ALTER SEQUENCE public.tasks_id_seq OWNED BY public.tasks.id;
+ALTER SEQUENCE public.oauth_clients_id_seq OWNED BY public.oauth_clients.id;
 
 
 --
--- Name: accounts id; Type: DEFAULT; Schema: public; Owner: -
+-- Name: oauth_codes; Type: TABLE; Schema: public; Owner: -
 --
 
-ALTER TABLE ONLY public.accounts ALTER COLUMN id SET DEFAULT nextval('public.accounts_id_seq'::regclass);
+CREATE TABLE public.oauth_codes (
+    id bigint NOT NULL,
+    account_id bigint NOT NULL,
+    client_id bigint NOT NULL,
+    code_digest text NOT NULL,
+    code_challenge text NOT NULL,
+    redirect_uri text NOT NULL,
+    scopes text[] NOT NULL,
+    expires timestamp with time zone NOT NULL,
+    used timestamp with time zone,
+    created timestamp with time zone DEFAULT CURRENT_TIMESTAMP NOT NULL
+);
 
 
 --
--- Name: tasks id; Type: DEFAULT; Schema: public; Owner: -
+-- Name: oauth_codes_id_seq; Type: SEQUENCE; Schema: public; Owner: -
 --
 
-ALTER TABLE ONLY public.tasks ALTER COLUMN id SET DEFAULT nextval('public.tasks_id_seq'::regclass);
+CREATE SEQUENCE public.oauth_codes_id_seq
+    START WITH 1
+    INCREMENT BY 1
+    NO MINVALUE
+    NO MAXVALUE
+    CACHE 1;
 
 
 --
--- Name: accounts accounts_pkey; Type: CONSTRAINT; Schema: public; Owner: -
+-- Name: oauth_codes_id_seq; Type: SEQUENCE OWNED BY; Schema: public; Owner: -
 --
 
-ALTER TABLE ONLY public.accounts
-    ADD CONSTRAINT accounts_pkey PRIMARY KEY (id);
+ALTER SEQUENCE public.oauth_codes_id_seq OWNED BY public.oauth_codes.id;
 
 
 --
--- Name: tasks tasks_pkey; Type: CONSTRAINT; Schema: public; Owner: -
+-- Name: oauth_refresh_tokens; Type: TABLE; Schema: public; Owner: -
 --
 
-ALTER TABLE ONLY public.tasks
-    ADD CONSTRAINT tasks_pkey PRIMARY KEY (id);
+CREATE TABLE public.oauth_refresh_tokens (
+    id bigint NOT NULL,
+    account_id bigint NOT NULL,
+    client_id bigint NOT NULL,
+    token_digest text NOT NULL,
+    scopes text[] NOT NULL,
+    expires timestamp with time zone NOT NULL,
+    revoked timestamp with time zone,
+    created timestamp with time zone DEFAULT CURRENT_TIMESTAMP NOT NULL
+);
 
 
 --
--- Name: accounts_id_idx; Type: INDEX; Schema: public; Owner: -
+-- Name: oauth_refresh_tokens_id_seq; Type: SEQUENCE; Schema: public; Owner: -
 --
 
-CREATE UNIQUE INDEX accounts_id_idx ON public.accounts USING btree (username);
+CREATE SEQUENCE public.oauth_refresh_tokens_id_seq
+    START WITH 1
+    INCREMENT BY 1
+    NO MINVALUE
+    NO MAXVALUE
+    CACHE 1;
+
+
+--
+-- Name: oauth_refresh_tokens_id_seq; Type: SEQUENCE OWNED BY; Schema: public; Owner: -
+--
+
+ALTER SEQUENCE public.oauth_refresh_tokens_id_seq OWNED BY public.oauth_refresh_tokens.id;
+
+
+--
+-- Name: org_invitations; Type: TABLE; Schema: public; Owner: -
+--
+
+CREATE TABLE public.org_invitations (
+    id bigint NOT NULL,
+    org_id bigint NOT NULL,
+    inviter_id bigint NOT NULL,
+    invitee_id bigint NOT NULL,
+    role text NOT NULL,
+    status text DEFAULT 'pending'::text NOT NULL,
+    responded timestamp with time zone,
+    created timestamp with time zone DEFAULT CURRENT_TIMESTAMP NOT NULL,
+    public_id uuid DEFAULT public.uuid_generate_v7() NOT NULL
+);
+
+
+--
+-- Name: org_invitations_id_seq; Type: SEQUENCE; Schema: public; Owner: -
+--
+
+CREATE SEQUENCE public.org_invitations_id_seq
+    START WITH 1
+    INCREMENT BY 1
+    NO MINVALUE
+    NO MAXVALUE
+    CACHE 1;
+
+
+--
+-- Name: org_invitations_id_seq; Type: SEQUENCE OWNED BY; Schema: public; Owner: -
+--
+
+ALTER SEQUENCE public.org_invitations_id_seq OWNED BY public.org_invitations.id;
+
+
+--
+-- Name: org_members; Type: TABLE; Schema: public; Owner: -
+--
+
+CREATE TABLE public.org_members (
+    id bigint NOT NULL,
+    org_id bigint NOT NULL,
+    account_id bigint NOT NULL,
+    role text NOT NULL,
+    created timestamp with time zone DEFAULT CURRENT_TIMESTAMP NOT NULL
+);
+
+
+--
+-- Name: org_members_id_seq; Type: SEQUENCE; Schema: public; Owner: -
+--
+
+CREATE SEQUENCE public.org_members_id_seq
+    START WITH 1
+    INCREMENT BY 1
+    NO MINVALUE
+    NO MAXVALUE
+    CACHE 1;
+
+
+--
+-- Name: org_members_id_seq; Type: SEQUENCE OWNED BY; Schema: public; Owner: -
+--
+
+ALTER SEQUENCE public.org_members_id_seq OWNED BY public.org_members.id;
+
+
+--
+-- Name: orgs; Type: TABLE; Schema: public; Owner: -
+--
+
+CREATE TABLE public.orgs (
+    id bigint NOT NULL,
+    name text NOT NULL,
+    slug text NOT NULL,
+    created timestamp with time zone DEFAULT CURRENT_TIMESTAMP NOT NULL,
+    public_id uuid DEFAULT public.uuid_generate_v7() NOT NULL
+);
+
+
+--
+-- Name: orgs_id_seq; Type: SEQUENCE; Schema: public; Owner: -
+--
+
+CREATE SEQUENCE public.orgs_id_seq
+    START WITH 1
+    INCREMENT BY 1
+    NO MINVALUE
+    NO MAXVALUE
+    CACHE 1;
+
+
+--
+-- Name: orgs_id_seq; Type: SEQUENCE OWNED BY; Schema: public; Owner: -
+--
+
+ALTER SEQUENCE public.orgs_id_seq OWNED BY public.orgs.id;
+
+
+--
+-- Name: outbox; Type: TABLE; Schema: public; Owner: -
+--
+
+CREATE TABLE public.outbox (
+    id bigint NOT NULL,
+    aggregate text NOT NULL,
+    aggregate_id bigint NOT NULL,
+    event text NOT NULL,
+    payload text NOT NULL,
+    attempts integer DEFAULT 0 NOT NULL,
+    next_attempt timestamp with time zone DEFAULT CURRENT_TIMESTAMP NOT NULL,
+    last_error text DEFAULT ''::text NOT NULL,
+    published timestamp with time zone,
+    created timestamp with time zone DEFAULT CURRENT_TIMESTAMP NOT NULL,
+    aggregate_public_id text DEFAULT ''::text NOT NULL
+);
+
+
+--
+-- Name: outbox_id_seq; Type: SEQUENCE; Schema: public; Owner: -
+--
+
+CREATE SEQUENCE public.outbox_id_seq
+    START WITH 1
+    INCREMENT BY 1
+    NO MINVALUE
+    NO MAXVALUE
+    CACHE 1;
+
+
+--
+-- Name: outbox_id_seq; Type: SEQUENCE OWNED BY; Schema: public; Owner: -
+--
+
+ALTER SEQUENCE public.outbox_id_seq OWNED BY public.outbox.id;
+
+
+--
+-- Name: password_resets; Type: TABLE; Schema: public; Owner: -
+--
+
+CREATE TABLE public.password_resets (
+    id bigint NOT NULL,
+    account_id bigint NOT NULL,
+    token_digest text NOT NULL,
+    expires timestamp with time zone NOT NULL,
+    used timestamp with time zone,
+    created timestamp with time zone DEFAULT CURRENT_TIMESTAMP NOT NULL
+);
+
+
+--
+-- Name: password_resets_id_seq; Type: SEQUENCE; Schema: public; Owner: -
+--
+
+CREATE SEQUENCE public.password_resets_id_seq
+    START WITH 1
+    INCREMENT BY 1
+    NO MINVALUE
+    NO MAXVALUE
+    CACHE 1;
+
+
+--
+-- Name: password_resets_id_seq; Type: SEQUENCE OWNED BY; Schema: public; Owner: -
+--
+
+ALTER SEQUENCE public.password_resets_id_seq OWNED BY public.password_resets.id;
+
+
+--
+-- Name: recovery_codes; Type: TABLE; Schema: public; Owner: -
+--
+
+CREATE TABLE public.recovery_codes (
+    id bigint NOT NULL,
+    account_id bigint NOT NULL,
+    code_digest text NOT NULL,
+    used timestamp with time zone,
+    created timestamp with time zone DEFAULT CURRENT_TIMESTAMP NOT NULL
+);
+
+
+--
+-- Name: recovery_codes_id_seq; Type: SEQUENCE; Schema: public; Owner: -
+--
+
+CREATE SEQUENCE public.recovery_codes_id_seq
+    START WITH 1
+    INCREMENT BY 1
+    NO MINVALUE
+    NO MAXVALUE
+    CACHE 1;
+
+
+--
+-- Name: recovery_codes_id_seq; Type: SEQUENCE OWNED BY; Schema: public; Owner: -
+--
+
+ALTER SEQUENCE public.recovery_codes_id_seq OWNED BY public.recovery_codes.id;
+
+
+--
+-- Name: task_changes; Type: TABLE; Schema: public; Owner: -
+--
+
+CREATE TABLE public.task_changes (
+    account_id bigint NOT NULL,
+    task_id bigint NOT NULL,
+    org_id bigint,
+    seq bigint NOT NULL,
+    deleted boolean DEFAULT false NOT NULL,
+    changed timestamp with time zone DEFAULT CURRENT_TIMESTAMP NOT NULL,
+    task_public_id uuid NOT NULL
+);
+
+
+--
+-- Name: tasks; Type: TABLE; Schema: public; Owner: -
+--
+
+CREATE TABLE public.tasks (
+    id bigint NOT NULL,
+    account_id bigint NOT NULL,
+    description text NOT NULL,
+    created timestamp with time zone DEFAULT CURRENT_TIMESTAMP NOT NULL,
+    completed timestamp with time zone,
+    list_id bigint,
+    created_by bigint NOT NULL,
+    assignee_id bigint,
+    org_id bigint,
+    public_id uuid DEFAULT public.uuid_generate_v7() NOT NULL
+);
+
+
+--
+-- Name: tasks_id_seq; Type: SEQUENCE; Schema: public; Owner: -
+--
+
+CREATE SEQUENCE public.tasks_id_seq
+    START WITH 1
+    INCREMENT BY 1
+    NO MINVALUE
+    NO MAXVALUE
+    CACHE 1;
+
+
+--
+-- Name: tasks_id_seq; Type: SEQUENCE OWNED BY; Schema: public; Owner: -
+--
+
+ALTER SEQUENCE public.tasks_id_seq OWNED BY public.tasks.id;
+
+
+--
+-- Name: webhook_deliveries; Type: TABLE; Schema: public; Owner: -
+--
+
+CREATE TABLE public.webhook_deliveries (
+    id bigint NOT NULL,
+    webhook_id bigint NOT NULL,
+    event text NOT NULL,
+    payload text NOT NULL,
+    status text DEFAULT 'pending'::text NOT NULL,
+    attempts integer DEFAULT 0 NOT NULL,
+    next_attempt timestamp with time zone NOT NULL,
+    response_status integer,
+    last_error text DEFAULT ''::text NOT NULL,
+    delivered timestamp with time zone,
+    created timestamp with time zone DEFAULT CURRENT_TIMESTAMP NOT NULL,
+    public_id uuid DEFAULT public.uuid_generate_v7() NOT NULL
+);
+
+
+--
+-- Name: webhook_deliveries_id_seq; Type: SEQUENCE; Schema: public; Owner: -
+--
+
+CREATE SEQUENCE public.webhook_deliveries_id_seq
+    START WITH 1
+    INCREMENT BY 1
+    NO MINVALUE
+    NO MAXVALUE
+    CACHE 1;
+
+
+--
+-- Name: webhook_deliveries_id_seq; Type: SEQUENCE OWNED BY; Schema: public; Owner: -
+--
+
+ALTER SEQUENCE public.webhook_deliveries_id_seq OWNED BY public.webhook_deliveries.id;
+
+
+--
+-- Name: webhooks; Type: TABLE; Schema: public; Owner: -
+--
+
+CREATE TABLE public.webhooks (
+    id bigint NOT NULL,
+    account_id bigint NOT NULL,
+    url text NOT NULL,
+    events text[] NOT NULL,
+    secret text NOT NULL,
+    created timestamp with time zone DEFAULT CURRENT_TIMESTAMP NOT NULL,
+    public_id uuid DEFAULT public.uuid_generate_v7() NOT NULL
+);
+
+
+--
+-- Name: webhooks_id_seq; Type: SEQUENCE; Schema: public; Owner: -
+--
+
+CREATE SEQUENCE public.webhooks_id_seq
+    START WITH 1
+    INCREMENT BY 1
+    NO MINVALUE
+    NO MAXVALUE
+    CACHE 1;
+
+
+--
+-- Name: webhooks_id_seq; Type: SEQUENCE OWNED BY; Schema: public; Owner: -
+--
+
+ALTER SEQUENCE public.webhooks_id_seq OWNED BY public.webhooks.id;
+
+
+--
+-- Name: access_tokens id; Type: DEFAULT; Schema: public; Owner: -
+--
+
+ALTER TABLE ONLY public.access_tokens ALTER COLUMN id SET DEFAULT nextval('public.access_tokens_id_seq'::regclass);
+
+
+--
+-- Name: account_identities id; Type: DEFAULT; Schema: public; Owner: -
+--
+
+ALTER TABLE ONLY public.account_identities ALTER COLUMN id SET DEFAULT nextval('public.account_identities_id_seq'::regclass);
+
+
+--
+-- Name: accounts id; Type: DEFAULT; Schema: public; Owner: -
+--
+
+ALTER TABLE ONLY public.accounts ALTER COLUMN id SET DEFAULT nextval('public.accounts_id_seq'::regclass);
+
+
+--
+-- Name: audit_entries id; Type: DEFAULT; Schema: public; Owner: -
+--
+
+ALTER TABLE ONLY public.audit_entries ALTER COLUMN id SET DEFAULT nextval('public.audit_entries_id_seq'::regclass);
+
+
+--
+-- Name: email_verifications id; Type: DEFAULT; Schema: public; Owner: -
+--
+
+ALTER TABLE ONLY public.email_verifications ALTER COLUMN id SET DEFAULT nextval('public.email_verifications_id_seq'::regclass);
+
+
+--
+-- Name: failed_logins id; Type: DEFAULT; Schema: public; Owner: -
+--
+
+ALTER TABLE ONLY public.failed_logins ALTER COLUMN id SET DEFAULT nextval('public.failed_logins_id_seq'::regclass);
+
+
+--
+-- Name: list_invitations id; Type: DEFAULT; Schema: public; Owner: -
+--
+
+ALTER TABLE ONLY public.list_invitations ALTER COLUMN id SET DEFAULT nextval('public.list_invitations_id_seq'::regclass);
+
+
+--
+-- Name: list_members id; Type: DEFAULT; Schema: public; Owner: -
+--
+
+ALTER TABLE ONLY public.list_members ALTER COLUMN id SET DEFAULT nextval('public.list_members_id_seq'::regclass);
+
+
+--
+-- Name: lists id; Type: DEFAULT; Schema: public; Owner: -
+--
+
+ALTER TABLE ONLY public.lists ALTER COLUMN id SET DEFAULT nextval('public.lists_id_seq'::regclass);
+
+
+--
+-- Name: oauth_clients id; Type: DEFAULT; Schema: public; Owner: -
+--
+
+ALTER TABLE ONLY public.oauth_clients ALTER COLUMN id SET DEFAULT nextval('public.oauth_clients_id_seq'::regclass);
+
+
+--
+-- Name: oauth_codes id; Type: DEFAULT; Schema: public; Owner: -
+--
+
+ALTER TABLE ONLY public.oauth_codes ALTER COLUMN id SET DEFAULT nextval('public.oauth_codes_id_seq'::regclass);
+
+
+--
+-- Name: oauth_refresh_tokens id; Type: DEFAULT; Schema: public; Owner: -
+--
+
+ALTER TABLE ONLY public.oauth_refresh_tokens ALTER COLUMN id SET DEFAULT nextval('public.oauth_refresh_tokens_id_seq'::regclass);
+
+
+--
+-- Name: org_invitations id; Type: DEFAULT; Schema: public; Owner: -
+--
+
+ALTER TABLE ONLY public.org_invitations ALTER COLUMN id SET DEFAULT nextval('public.org_invitations_id_seq'::regclass);
+
+
+--
+-- Name: org_members id; Type: DEFAULT; Schema: public; Owner: -
+--
+
+ALTER TABLE ONLY public.org_members ALTER COLUMN id SET DEFAULT nextval('public.org_members_id_seq'::regclass);
+
+
+--
+-- Name: orgs id; Type: DEFAULT; Schema: public; Owner: -
+--
+
+ALTER TABLE ONLY public.orgs ALTER COLUMN id SET DEFAULT nextval('public.orgs_id_seq'::regclass);
+
+
+--
+-- Name: outbox id; Type: DEFAULT; Schema: public; Owner: -
+--
+
+ALTER TABLE ONLY public.outbox ALTER COLUMN id SET DEFAULT nextval('public.outbox_id_seq'::regclass);
+
+
+--
+-- Name: password_resets id; Type: DEFAULT; Schema: public; Owner: -
+--
+
+ALTER TABLE ONLY public.password_resets ALTER COLUMN id SET DEFAULT nextval('public.password_resets_id_seq'::regclass);
+
+
+--
+-- Name: recovery_codes id; Type: DEFAULT; Schema: public; Owner: -
+--
+
+ALTER TABLE ONLY public.recovery_codes ALTER COLUMN id SET DEFAULT nextval('public.recovery_codes_id_seq'::regclass);
+
+
+--
+-- Name: tasks id; Type: DEFAULT; Schema: public; Owner: -
+--
+
+ALTER TABLE ONLY public.tasks ALTER COLUMN id SET DEFAULT nextval('public.tasks_id_seq'::regclass);
+
+
+--
+-- Name: webhook_deliveries id; Type: DEFAULT; Schema: public; Owner: -
+--
+
+ALTER TABLE ONLY public.webhook_deliveries ALTER COLUMN id SET DEFAULT nextval('public.webhook_deliveries_id_seq'::regclass);
+
+
+--
+-- Name: webhooks id; Type: DEFAULT; Schema: public; Owner: -
+--
+
+ALTER TABLE ONLY public.webhooks ALTER COLUMN id SET DEFAULT nextval('public.webhooks_id_seq'::regclass);
+
+
+--
+-- Name: access_tokens access_tokens_pkey; Type: CONSTRAINT; Schema: public; Owner: -
+--
+
+ALTER TABLE ONLY public.access_tokens
+    ADD CONSTRAINT access_tokens_pkey PRIMARY KEY (id);
+
+
+--
+-- Name: account_identities account_identities_pkey; Type: CONSTRAINT; Schema: public; Owner: -
+--
+
+ALTER TABLE ONLY public.account_identities
+    ADD CONSTRAINT account_identities_pkey PRIMARY KEY (id);
+
+
+--
+-- Name: accounts accounts_pkey; Type: CONSTRAINT; Schema: public; Owner: -
+--
+
+ALTER TABLE ONLY public.accounts
+    ADD CONSTRAINT accounts_pkey PRIMARY KEY (id);
+
+
+--
+-- Name: audit_entries audit_entries_pkey; Type: CONSTRAINT; Schema: public; Owner: -
+--
+
+ALTER TABLE ONLY public.audit_entries
+    ADD CONSTRAINT audit_entries_pkey PRIMARY KEY (id);
+
+
+--
+-- Name: email_verifications email_verifications_pkey; Type: CONSTRAINT; Schema: public; Owner: -
+--
+
+ALTER TABLE ONLY public.email_verifications
+    ADD CONSTRAINT email_verifications_pkey PRIMARY KEY (id);
+
+
+--
+-- Name: failed_logins failed_logins_pkey; Type: CONSTRAINT; Schema: public; Owner: -
+--
+
+ALTER TABLE ONLY public.failed_logins
+    ADD CONSTRAINT failed_logins_pkey PRIMARY KEY (id);
+
+
+--
+-- Name: list_invitations list_invitations_pkey; Type: CONSTRAINT; Schema: public; Owner: -
+--
+
+ALTER TABLE ONLY public.list_invitations
+    ADD CONSTRAINT list_invitations_pkey PRIMARY KEY (id);
+
+
+--
+-- Name: list_members list_members_pkey; Type: CONSTRAINT; Schema: public; Owner: -
+--
+
+ALTER TABLE ONLY public.list_members
+    ADD CONSTRAINT list_members_pkey PRIMARY KEY (id);
+
+
+--
+-- Name: lists lists_pkey; Type: CONSTRAINT; Schema: public; Owner: -
+--
+
+ALTER TABLE ONLY public.lists
+    ADD CONSTRAINT lists_pkey PRIMARY KEY (id);
+
+
+--
+-- Name: oauth_clients oauth_clients_pkey; Type: CONSTRAINT; Schema: public; Owner: -
+--
+
+ALTER TABLE ONLY public.oauth_clients
+    ADD CONSTRAINT oauth_clients_pkey PRIMARY KEY (id);
+
+
+--
+-- Name: oauth_codes oauth_codes_pkey; Type: CONSTRAINT; Schema: public; Owner: -
+--
+
+ALTER TABLE ONLY public.oauth_codes
+    ADD CONSTRAINT oauth_codes_pkey PRIMARY KEY (id);
+
+
+--
+-- Name: oauth_refresh_tokens oauth_refresh_tokens_pkey; Type: CONSTRAINT; Schema: public; Owner: -
+--
+
+ALTER TABLE ONLY public.oauth_refresh_tokens
+    ADD CONSTRAINT oauth_refresh_tokens_pkey PRIMARY KEY (id);
+
+
+--
+-- Name: org_invitations org_invitations_pkey; Type: CONSTRAINT; Schema: public; Owner: -
+--
+
+ALTER TABLE ONLY public.org_invitations
+    ADD CONSTRAINT org_invitations_pkey PRIMARY KEY (id);
+
+
+--
+-- Name: org_members org_members_pkey; Type: CONSTRAINT; Schema: public; Owner: -
+--
+
+ALTER TABLE ONLY public.org_members
+    ADD CONSTRAINT org_members_pkey PRIMARY KEY (id);
+
+
+--
+-- Name: orgs orgs_pkey; Type: CONSTRAINT; Schema: public; Owner: -
+--
+
+ALTER TABLE ONLY public.orgs
+    ADD CONSTRAINT orgs_pkey PRIMARY KEY (id);
+
+
+--
+-- Name: outbox outbox_pkey; Type: CONSTRAINT; Schema: public; Owner: -
+--
+
+ALTER TABLE ONLY public.outbox
+    ADD CONSTRAINT outbox_pkey PRIMARY KEY (id);
+
+
+--
+-- Name: password_resets password_resets_pkey; Type: CONSTRAINT; Schema: public; Owner: -
+--
+
+ALTER TABLE ONLY public.password_resets
+    ADD CONSTRAINT password_resets_pkey PRIMARY KEY (id);
+
+
+--
+-- Name: recovery_codes recovery_codes_pkey; Type: CONSTRAINT; Schema: public; Owner: -
+--
+
+ALTER TABLE ONLY public.recovery_codes
+    ADD CONSTRAINT recovery_codes_pkey PRIMARY KEY (id);
+
+
+--
+-- Name: task_changes task_changes_pkey; Type: CONSTRAINT; Schema: public; Owner: -
+--
+
+ALTER TABLE ONLY public.task_changes
+    ADD CONSTRAINT task_changes_pkey PRIMARY KEY (account_id, task_id);
+
+
+--
+-- Name: tasks tasks_pkey; Type: CONSTRAINT; Schema: public; Owner: -
+--
+
+ALTER TABLE ONLY public.tasks
+    ADD CONSTRAINT tasks_pkey PRIMARY KEY (id);
+
+
+--
+-- Name: webhook_deliveries webhook_deliveries_pkey; Type: CONSTRAINT; Schema: public; Owner: -
+--
+
+ALTER TABLE ONLY public.webhook_deliveries
+    ADD CONSTRAINT webhook_deliveries_pkey PRIMARY KEY (id);
+
+
+--
+-- Name: webhooks webhooks_pkey; Type: CONSTRAINT; Schema: public; Owner: -
+--
+
+ALTER TABLE ONLY public.webhooks
+    ADD CONSTRAINT webhooks_pkey PRIMARY KEY (id);
+
+
+--
+-- Name: access_tokens_account_id_idx; Type: INDEX; Schema: public; Owner: -
+--
+
+CREATE INDEX access_tokens_account_id_idx ON public.access_tokens USING btree (account_id);
+
+
+--
+-- Name: access_tokens_client_id_idx; Type: INDEX; Schema: public; Owner: -
+--
+
+CREATE INDEX access_tokens_client_id_idx ON public.access_tokens USING btree (client_id);
+
+
+--
+-- Name: access_tokens_public_id_idx; Type: INDEX; Schema: public; Owner: -
+--
+
+CREATE UNIQUE INDEX access_tokens_public_id_idx ON public.access_tokens USING btree (public_id);
+
+
+--
+-- Name: access_tokens_token_digest_idx; Type: INDEX; Schema: public; Owner: -
+--
+
+CREATE UNIQUE INDEX access_tokens_token_digest_idx ON public.access_tokens USING btree (token_digest);
+
+
+--
+-- Name: account_identities_account_id_idx; Type: INDEX; Schema: public; Owner: -
+--
+
+CREATE INDEX account_identities_account_id_idx ON public.account_identities USING btree (account_id);
+
+
+--
+-- Name: account_identities_issuer_subject_idx; Type: INDEX; Schema: public; Owner: -
+--
+
+CREATE UNIQUE INDEX account_identities_issuer_subject_idx ON public.account_identities USING btree (issuer, subject);
+
+
+--
+-- Name: account_identities_public_id_idx; Type: INDEX; Schema: public; Owner: -
+--
+
+CREATE UNIQUE INDEX account_identities_public_id_idx ON public.account_identities USING btree (public_id);
+
+
+--
+-- Name: accounts_id_idx; Type: INDEX; Schema: public; Owner: -
+--
+
+CREATE UNIQUE INDEX accounts_id_idx ON public.accounts USING btree (username);
+
+
+--
+-- Name: accounts_public_id_idx; Type: INDEX; Schema: public; Owner: -
+--
+
+CREATE UNIQUE INDEX accounts_public_id_idx ON public.accounts USING btree (public_id);
+
+
+--
+-- Name: accounts_verified_email_idx; Type: INDEX; Schema: public; Owner: -
+--
+
+CREATE UNIQUE INDEX accounts_verified_email_idx ON public.accounts USING btree (lower(email)) WHERE ((email <> ''::text) AND (verified_at IS NOT NULL));
+
+
+--
+-- Name: audit_entries_account_id_idx; Type: INDEX; Schema: public; Owner: -
+--
+
+CREATE INDEX audit_entries_account_id_idx ON public.audit_entries USING btree (account_id);
+
+
+--
+-- Name: audit_entries_public_id_idx; Type: INDEX; Schema: public; Owner: -
+--
+
+CREATE UNIQUE INDEX audit_entries_public_id_idx ON public.audit_entries USING btree (public_id);
+
+
+--
+-- Name: email_verifications_account_id_idx; Type: INDEX; Schema: public; Owner: -
+--
+
+CREATE INDEX email_verifications_account_id_idx ON public.email_verifications USING btree (account_id);
+
+
+--
+-- Name: email_verifications_token_digest_idx; Type: INDEX; Schema: public; Owner: -
+--
+
+CREATE UNIQUE INDEX email_verifications_token_digest_idx ON public.email_verifications USING btree (token_digest);
+
+
+--
+-- Name: failed_logins_account_id_created_idx; Type: INDEX; Schema: public; Owner: -
+--
+
+CREATE INDEX failed_logins_account_id_created_idx ON public.failed_logins USING btree (account_id, created);
+
+
+--
+-- Name: failed_logins_public_id_idx; Type: INDEX; Schema: public; Owner: -
+--
+
+CREATE UNIQUE INDEX failed_logins_public_id_idx ON public.failed_logins USING btree (public_id);
+
+
+--
+-- Name: list_invitations_invitee_id_idx; Type: INDEX; Schema: public; Owner: -
+--
+
+CREATE INDEX list_invitations_invitee_id_idx ON public.list_invitations USING btree (invitee_id);
+
+
+--
+-- Name: list_invitations_inviter_id_idx; Type: INDEX; Schema: public; Owner: -
+--
+
+CREATE INDEX list_invitations_inviter_id_idx ON public.list_invitations USING btree (inviter_id);
+
+
+--
+-- Name: list_invitations_list_id_idx; Type: INDEX; Schema: public; Owner: -
+--
+
+CREATE INDEX list_invitations_list_id_idx ON public.list_invitations USING btree (list_id);
+
+
+--
+-- Name: list_invitations_pending_idx; Type: INDEX; Schema: public; Owner: -
+--
+
+CREATE UNIQUE INDEX list_invitations_pending_idx ON public.list_invitations USING btree (list_id, invitee_id) WHERE (status = 'pending'::text);
+
+
+--
+-- Name: list_invitations_public_id_idx; Type: INDEX; Schema: public; Owner: -
+--
+
+CREATE UNIQUE INDEX list_invitations_public_id_idx ON public.list_invitations USING btree (public_id);
+
+
+--
+-- Name: list_members_account_id_idx; Type: INDEX; Schema: public; Owner: -
+--
+
+CREATE INDEX list_members_account_id_idx ON public.list_members USING btree (account_id);
+
+
+--
+-- Name: list_members_list_id_account_id_idx; Type: INDEX; Schema: public; Owner: -
+--
+
+CREATE UNIQUE INDEX list_members_list_id_account_id_idx ON public.list_members USING btree (list_id, account_id);
+
+
+--
+-- Name: lists_account_id_idx; Type: INDEX; Schema: public; Owner: -
+--
+
+CREATE INDEX lists_account_id_idx ON public.lists USING btree (account_id);
+
+
+--
+-- Name: lists_public_id_idx; Type: INDEX; Schema: public; Owner: -
+--
+
+CREATE UNIQUE INDEX lists_public_id_idx ON public.lists USING btree (public_id);
+
+
+--
+-- Name: oauth_clients_account_id_idx; Type: INDEX; Schema: public; Owner: -
+--
+
+CREATE INDEX oauth_clients_account_id_idx ON public.oauth_clients USING btree (account_id);
+
+
+--
+-- Name: oauth_clients_client_id_idx; Type: INDEX; Schema: public; Owner: -
+--
+
+CREATE UNIQUE INDEX oauth_clients_client_id_idx ON public.oauth_clients USING btree (client_id);
+
+
+--
+-- Name: oauth_codes_account_id_idx; Type: INDEX; Schema: public; Owner: -
+--
+
+CREATE INDEX oauth_codes_account_id_idx ON public.oauth_codes USING btree (account_id);
+
+
+--
+-- Name: oauth_codes_client_id_idx; Type: INDEX; Schema: public; Owner: -
+--
+
+CREATE INDEX oauth_codes_client_id_idx ON public.oauth_codes USING btree (client_id);
+
+
+--
+-- Name: oauth_codes_code_digest_idx; Type: INDEX; Schema: public; Owner: -
+--
+
+CREATE UNIQUE INDEX oauth_codes_code_digest_idx ON public.oauth_codes USING btree (code_digest);
+
+
+--
+-- Name: oauth_refresh_tokens_account_id_idx; Type: INDEX; Schema: public; Owner: -
+--
+
+CREATE INDEX oauth_refresh_tokens_account_id_idx ON public.oauth_refresh_tokens USING btree (account_id);
+
+
+--
+-- Name: oauth_refresh_tokens_client_id_idx; Type: INDEX; Schema: public; Owner: -
+--
+
+CREATE INDEX oauth_refresh_tokens_client_id_idx ON public.oauth_refresh_tokens USING btree (client_id);
+
+
+--
+-- Name: oauth_refresh_tokens_token_digest_idx; Type: INDEX; Schema: public; Owner: -
+--
+
+CREATE UNIQUE INDEX oauth_refresh_tokens_token_digest_idx ON public.oauth_refresh_tokens USING btree (token_digest);
+
+
+--
+-- Name: org_invitations_invitee_id_idx; Type: INDEX; Schema: public; Owner: -
+--
+
+CREATE INDEX org_invitations_invitee_id_idx ON public.org_invitations USING btree (invitee_id);
+
+
+--
+-- Name: org_invitations_inviter_id_idx; Type: INDEX; Schema: public; Owner: -
+--
+
+CREATE INDEX org_invitations_inviter_id_idx ON public.org_invitations USING btree (inviter_id);
+
+
+--
+-- Name: org_invitations_org_id_idx; Type: INDEX; Schema: public; Owner: -
+--
+
+CREATE INDEX org_invitations_org_id_idx ON public.org_invitations USING btree (org_id);
+
+
+--
+-- Name: org_invitations_pending_idx; Type: INDEX; Schema: public; Owner: -
+--
+
+CREATE UNIQUE INDEX org_invitations_pending_idx ON public.org_invitations USING btree (org_id, invitee_id) WHERE (status = 'pending'::text);
+
+
+--
+-- Name: org_invitations_public_id_idx; Type: INDEX; Schema: public; Owner: -
+--
+
+CREATE UNIQUE INDEX org_invitations_public_id_idx ON public.org_invitations USING btree (public_id);
+
+
+--
+-- Name: org_members_account_id_idx; Type: INDEX; Schema: public; Owner: -
+--
+
+CREATE INDEX org_members_account_id_idx ON public.org_members USING btree (account_id);
+
+
+--
+-- Name: org_members_org_id_account_id_idx; Type: INDEX; Schema: public; Owner: -
+--
+
+CREATE UNIQUE INDEX org_members_org_id_account_id_idx ON public.org_members USING btree (org_id, account_id);
+
+
+--
+-- Name: orgs_public_id_idx; Type: INDEX; Schema: public; Owner: -
+--
+
+CREATE UNIQUE INDEX orgs_public_id_idx ON public.orgs USING btree (public_id);
+
+
+--
+-- Name: orgs_slug_idx; Type: INDEX; Schema: public; Owner: -
+--
+
+CREATE UNIQUE INDEX orgs_slug_idx ON public.orgs USING btree (slug);
+
+
+--
+-- Name: outbox_pending_idx; Type: INDEX; Schema: public; Owner: -
+--
+
+CREATE INDEX outbox_pending_idx ON public.outbox USING btree (next_attempt) WHERE (published IS NULL);
+
+
+--
+-- Name: outbox_published_idx; Type: INDEX; Schema: public; Owner: -
+--
+
+CREATE INDEX outbox_published_idx ON public.outbox USING btree (published) WHERE (published IS NOT NULL);
+
+
+--
+-- Name: password_resets_account_id_idx; Type: INDEX; Schema: public; Owner: -
+--
+
+CREATE INDEX password_resets_account_id_idx ON public.password_resets USING btree (account_id);
+
+
+--
+-- Name: password_resets_token_digest_idx; Type: INDEX; Schema: public; Owner: -
+--
+
+CREATE UNIQUE INDEX password_resets_token_digest_idx ON public.password_resets USING btree (token_digest);
+
+
+--
+-- Name: recovery_codes_account_id_code_digest_idx; Type: INDEX; Schema: public; Owner: -
+--
+
+CREATE UNIQUE INDEX recovery_codes_account_id_code_digest_idx ON public.recovery_codes USING btree (account_id, code_digest);
+
+
+--
+-- Name: task_changes_account_id_seq_idx; Type: INDEX; Schema: public; Owner: -
+--
+
+CREATE INDEX task_changes_account_id_seq_idx ON public.task_changes USING btree (account_id, seq);
+
+
+--
+-- Name: task_changes_account_id_task_public_id_idx; Type: INDEX; Schema: public; Owner: -
+--
+
+CREATE UNIQUE INDEX task_changes_account_id_task_public_id_idx ON public.task_changes USING btree (account_id, task_public_id);
+
+
+--
+-- Name: tasks_account_id_idx; Type: INDEX; Schema: public; Owner: -
+--
+
+CREATE INDEX tasks_account_id_idx ON public.tasks USING btree (account_id);
+
+
+--
+-- Name: tasks_assignee_id_idx; Type: INDEX; Schema: public; Owner: -
+--
+
+CREATE INDEX tasks_assignee_id_idx ON public.tasks USING btree (assignee_id);
+
+
+--
+-- Name: tasks_created_by_idx; Type: INDEX; Schema: public; Owner: -
+--
+
+CREATE INDEX tasks_created_by_idx ON public.tasks USING btree (created_by);
+
+
+--
+-- Name: tasks_list_id_idx; Type: INDEX; Schema: public; Owner: -
+--
+
+CREATE INDEX tasks_list_id_idx ON public.tasks USING btree (list_id);
+
+
+--
+-- Name: tasks_org_id_idx; Type: INDEX; Schema: public; Owner: -
+--
+
+CREATE INDEX tasks_org_id_idx ON public.tasks USING btree (org_id);
+
+
+--
+-- Name: tasks_public_id_idx; Type: INDEX; Schema: public; Owner: -
+--
+
+CREATE UNIQUE INDEX tasks_public_id_idx ON public.tasks USING btree (public_id);
+
+
+--
+-- Name: webhook_deliveries_pending_idx; Type: INDEX; Schema: public; Owner: -
+--
+
+CREATE INDEX webhook_deliveries_pending_idx ON public.webhook_deliveries USING btree (next_attempt) WHERE (status = 'pending'::text);
+
+
+--
+-- Name: webhook_deliveries_public_id_idx; Type: INDEX; Schema: public; Owner: -
+--
+
+CREATE UNIQUE INDEX webhook_deliveries_public_id_idx ON public.webhook_deliveries USING btree (public_id);
+
+
+--
+-- Name: webhook_deliveries_webhook_id_idx; Type: INDEX; Schema: public; Owner: -
+--
+
+CREATE INDEX webhook_deliveries_webhook_id_idx ON public.webhook_deliveries USING btree (webhook_id, created);
+
+
+--
+-- Name: webhooks_account_id_idx; Type: INDEX; Schema: public; Owner: -
+--
+
+CREATE INDEX webhooks_account_id_idx ON public.webhooks USING btree (account_id);
+
+
+--
+-- Name: webhooks_public_id_idx; Type: INDEX; Schema: public; Owner: -
+--
+
+CREATE UNIQUE INDEX webhooks_public_id_idx ON public.webhooks USING btree (public_id);
+
+
+--
+-- Name: access_tokens access_tokens_account_id_fkey; Type: FK CONSTRAINT; Schema: public; Owner: -
+--
+
+ALTER TABLE ONLY public.access_tokens
+    ADD CONSTRAINT access_tokens_account_id_fkey FOREIGN KEY (account_id) REFERENCES public.accounts(id) ON DELETE CASCADE;
+
+
+--
+-- Name: access_tokens access_tokens_client_id_fkey; Type: FK CONSTRAINT; Schema: public; Owner: -
+--
+
+ALTER TABLE ONLY public.access_tokens
+    ADD CONSTRAINT access_tokens_client_id_fkey FOREIGN KEY (client_id) REFERENCES public.oauth_clients(id) ON DELETE CASCADE;
+
+
+--
+-- Name: account_identities account_identities_account_id_fkey; Type: FK CONSTRAINT; Schema: public; Owner: -
+--
+
+ALTER TABLE ONLY public.account_identities
+    ADD CONSTRAINT account_identities_account_id_fkey FOREIGN KEY (account_id) REFERENCES public.accounts(id) ON DELETE CASCADE;
+
+
+--
+-- Name: email_verifications email_verifications_account_id_fkey; Type: FK CONSTRAINT; Schema: public; Owner: -
+--
+
+ALTER TABLE ONLY public.email_verifications
+    ADD CONSTRAINT email_verifications_account_id_fkey FOREIGN KEY (account_id) REFERENCES public.accounts(id) ON DELETE CASCADE;
+
+
+--
+-- Name: failed_logins failed_logins_account_id_fkey; Type: FK CONSTRAINT; Schema: public; Owner: -
+--
+
+ALTER TABLE ONLY public.failed_logins
+    ADD CONSTRAINT failed_logins_account_id_fkey FOREIGN KEY (account_id) REFERENCES public.accounts(id) ON DELETE CASCADE;
+
+
+--
+-- Name: list_invitations list_invitations_invitee_id_fkey; Type: FK CONSTRAINT; Schema: public; Owner: -
+--
+
+ALTER TABLE ONLY public.list_invitations
+    ADD CONSTRAINT list_invitations_invitee_id_fkey FOREIGN KEY (invitee_id) REFERENCES public.accounts(id) ON DELETE CASCADE;
+
+
+--
+-- Name: list_invitations list_invitations_inviter_id_fkey; Type: FK CONSTRAINT; Schema: public; Owner: -
+--
+
+ALTER TABLE ONLY public.list_invitations
+    ADD CONSTRAINT list_invitations_inviter_id_fkey FOREIGN KEY (inviter_id) REFERENCES public.accounts(id) ON DELETE CASCADE;
+
+
+--
+-- Name: list_invitations list_invitations_list_id_fkey; Type: FK CONSTRAINT; Schema: public; Owner: -
+--
+
+ALTER TABLE ONLY public.list_invitations
+    ADD CONSTRAINT list_invitations_list_id_fkey FOREIGN KEY (list_id) REFERENCES public.lists(id) ON DELETE CASCADE;
+
+
+--
+-- Name: list_members list_members_account_id_fkey; Type: FK CONSTRAINT; Schema: public; Owner: -
+--
+
+ALTER TABLE ONLY public.list_members
+    ADD CONSTRAINT list_members_account_id_fkey FOREIGN KEY (account_id) REFERENCES public.accounts(id) ON DELETE CASCADE;
+
+
+--
+-- Name: list_members list_members_list_id_fkey; Type: FK CONSTRAINT; Schema: public; Owner: -
+--
+
+ALTER TABLE ONLY public.list_members
+    ADD CONSTRAINT list_members_list_id_fkey FOREIGN KEY (list_id) REFERENCES public.lists(id) ON DELETE CASCADE;
+
+
+--
+-- Name: lists lists_account_id_fkey; Type: FK CONSTRAINT; Schema: public; Owner: -
+--
+
+ALTER TABLE ONLY public.lists
+    ADD CONSTRAINT lists_account_id_fkey FOREIGN KEY (account_id) REFERENCES public.accounts(id) ON DELETE CASCADE;
+
+
+--
+-- Name: oauth_clients oauth_clients_account_id_fkey; Type: FK CONSTRAINT; Schema: public; Owner: -
+--
+
+ALTER TABLE ONLY public.oauth_clients
+    ADD CONSTRAINT oauth_clients_account_id_fkey FOREIGN KEY (account_id) REFERENCES public.accounts(id) ON DELETE CASCADE;
+
+
+--
+-- Name: oauth_codes oauth_codes_account_id_fkey; Type: FK CONSTRAINT; Schema: public; Owner: -
+--
+
+ALTER TABLE ONLY public.oauth_codes
+    ADD CONSTRAINT oauth_codes_account_id_fkey FOREIGN KEY (account_id) REFERENCES public.accounts(id) ON DELETE CASCADE;
+
+
+--
+-- Name: oauth_codes oauth_codes_client_id_fkey; Type: FK CONSTRAINT; Schema: public; Owner: -
+--
+
+ALTER TABLE ONLY public.oauth_codes
+    ADD CONSTRAINT oauth_codes_client_id_fkey FOREIGN KEY (client_id) REFERENCES public.oauth_clients(id) ON DELETE CASCADE;
+
+
+--
+-- Name: oauth_refresh_tokens oauth_refresh_tokens_account_id_fkey; Type: FK CONSTRAINT; Schema: public; Owner: -
+--
+
+ALTER TABLE ONLY public.oauth_refresh_tokens
+    ADD CONSTRAINT oauth_refresh_tokens_account_id_fkey FOREIGN KEY (account_id) REFERENCES public.accounts(id) ON DELETE CASCADE;
+
+
+--
+-- Name: oauth_refresh_tokens oauth_refresh_tokens_client_id_fkey; Type: FK CONSTRAINT; Schema: public; Owner: -
+--
+
+ALTER TABLE ONLY public.oauth_refresh_tokens
+    ADD CONSTRAINT oauth_refresh_tokens_client_id_fkey FOREIGN KEY (client_id) REFERENCES public.oauth_clients(id) ON DELETE CASCADE;
+
+
+--
+-- Name: org_invitations org_invitations_invitee_id_fkey; Type: FK CONSTRAINT; Schema: public; Owner: -
+--
+
+ALTER TABLE ONLY public.org_invitations
+    ADD CONSTRAINT org_invitations_invitee_id_fkey FOREIGN KEY (invitee_id) REFERENCES public.accounts(id) ON DELETE CASCADE;
+
+
+--
+-- Name: org_invitations org_invitations_inviter_id_fkey; Type: FK CONSTRAINT; Schema: public; Owner: -
+--
+
+ALTER TABLE ONLY public.org_invitations
+    ADD CONSTRAINT org_invitations_inviter_id_fkey FOREIGN KEY (inviter_id) REFERENCES public.accounts(id) ON DELETE CASCADE;
+
+
+--
+-- Name: org_invitations org_invitations_org_id_fkey; Type: FK CONSTRAINT; Schema: public; Owner: -
+--
+
+ALTER TABLE ONLY public.org_invitations
+    ADD CONSTRAINT org_invitations_org_id_fkey FOREIGN KEY (org_id) REFERENCES public.orgs(id) ON DELETE CASCADE;
+
+
+--
+-- Name: org_members org_members_account_id_fkey; Type: FK CONSTRAINT; Schema: public; Owner: -
+--
+
+ALTER TABLE ONLY public.org_members
+    ADD CONSTRAINT org_members_account_id_fkey FOREIGN KEY (account_id) REFERENCES public.accounts(id) ON DELETE CASCADE;
+
+
+--
+-- Name: org_members org_members_org_id_fkey; Type: FK CONSTRAINT; Schema: public; Owner: -
+--
+
+ALTER TABLE ONLY public.org_members
+    ADD CONSTRAINT org_members_org_id_fkey FOREIGN KEY (org_id) REFERENCES public.orgs(id) ON DELETE CASCADE;
+
+
+--
+-- Name: password_resets password_resets_account_id_fkey; Type: FK CONSTRAINT; Schema: public; Owner: -
+--
+
+ALTER TABLE ONLY public.password_resets
+    ADD CONSTRAINT password_resets_account_id_fkey FOREIGN KEY (account_id) REFERENCES public.accounts(id) ON DELETE CASCADE;
+
+
+--
+-- Name: recovery_codes recovery_codes_account_id_fkey; Type: FK CONSTRAINT; Schema: public; Owner: -
+--
+
+ALTER TABLE ONLY public.recovery_codes
+    ADD CONSTRAINT recovery_codes_account_id_fkey FOREIGN KEY (account_id) REFERENCES public.accounts(id) ON DELETE CASCADE;
+
+
+--
+-- Name: task_changes task_changes_account_id_fkey; Type: FK CONSTRAINT; Schema: public; Owner: -
+--
+
+ALTER TABLE ONLY public.task_changes
+    ADD CONSTRAINT task_changes_account_id_fkey FOREIGN KEY (account_id) REFERENCES public.accounts(id) ON DELETE CASCADE;
+
+
+--
+-- Name: tasks tasks_account_id_fkey; Type: FK CONSTRAINT; Schema: public; Owner: -
+--
+
+ALTER TABLE ONLY public.tasks
+    ADD CONSTRAINT tasks_account_id_fkey FOREIGN KEY (account_id) REFERENCES public.accounts(id) ON DELETE CASCADE;
+
+
+--
+-- Name: tasks tasks_assignee_id_fkey; Type: FK CONSTRAINT; Schema: public; Owner: -
+--
+
+ALTER TABLE ONLY public.tasks
+    ADD CONSTRAINT tasks_assignee_id_fkey FOREIGN KEY (assignee_id) REFERENCES public.accounts(id) ON DELETE SET NULL;
+
+
+--
+-- Name: tasks tasks_list_id_fkey; Type: FK CONSTRAINT; Schema: public; Owner: -
+--
+
+ALTER TABLE ONLY public.tasks
+    ADD CONSTRAINT tasks_list_id_fkey FOREIGN KEY (list_id) REFERENCES public.lists(id) ON DELETE CASCADE;
+
+
+--
+-- Name: tasks tasks_org_id_fkey; Type: FK CONSTRAINT; Schema: public; Owner: -
+--
+
+ALTER TABLE ONLY public.tasks
+    ADD CONSTRAINT tasks_org_id_fkey FOREIGN KEY (org_id) REFERENCES public.orgs(id) ON DELETE CASCADE;
+
+
+--
+-- Name: webhook_deliveries webhook_deliveries_webhook_id_fkey; Type: FK CONSTRAINT; Schema: public; Owner: -
+--
+
+ALTER TABLE ONLY public.webhook_deliveries
+    ADD CONSTRAINT webhook_deliveries_webhook_id_fkey FOREIGN KEY (webhook_id) REFERENCES public.webhooks(id) ON DELETE CASCADE;
+
+
+--
+-- Name: webhooks webhooks_account_id_fkey; Type: FK CONSTRAINT; Schema: public; Owner: -
+--
+
+ALTER TABLE ONLY public.webhooks
+    ADD CONSTRAINT webhooks_account_id_fkey FOREIGN KEY (account_id) REFERENCES public.accounts(id) ON DELETE CASCADE;
+
+
+--
+-- Name: tasks; Type: ROW SECURITY; Schema: public; Owner: -
+--
+
+ALTER TABLE public.tasks ENABLE ROW LEVEL SECURITY;
+
+
+--
+-- Name: tasks tasks_org_isolation; Type: POLICY; Schema: public; Owner: -
+--
+
+CREATE POLICY tasks_org_isolation ON public.tasks USING (((NULLIF(current_setting('todo.org_id'::text, true), ''::text) IS NULL) OR (org_id = (NULLIF(current_setting('todo.org_id'::text, true), ''::text))::bigint)));
 
 
 --
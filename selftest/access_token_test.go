@@ -0,0 +1,78 @@
+package selftest
+
+import (
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/deliveroo/assert-go"
+)
+
+func TestAccessTokens(t *testing.T) {
+	withAccount(t, func(api *API) {
+		var (
+			id    interface{}
+			token string
+		)
+		t.Run("create", func(t *testing.T) {
+			resp := api.Post(t, "/account/tokens", m{
+				"name":    "ci",
+				"scopes":  []string{"tasks:read"},
+				"expires": time.Now().Add(time.Hour).UTC().Format(time.RFC3339),
+			})
+			resp.AssertStatusCode(t, 200)
+			resp.JSONPathEqual(t, "name", "ci")
+			resp.JSONPathEqual(t, "last_used", nil)
+			id = resp.JSONPath(t, "id")
+			token = resp.JSONPathString(t, "token")
+		})
+		t.Run("authenticates with scope", func(t *testing.T) {
+			script := &API{Bearer: token}
+			script.Get(t, "/tasks").AssertStatusCode(t, 200)
+		})
+		t.Run("rejects missing scope", func(t *testing.T) {
+			script := &API{Bearer: token}
+			resp := script.Post(t, "/tasks", m{"description": "from a script"})
+			resp.AssertStatusCode(t, 403)
+			assert.Equal(t, resp.ErrorCode(t), "insufficient_scope")
+			script.Get(t, "/account").AssertStatusCode(t, 403)
+		})
+		t.Run("cannot manage tokens", func(t *testing.T) {
+			script := &API{Bearer: token}
+			script.Get(t, "/account/tokens").AssertStatusCode(t, 403)
+		})
+		t.Run("list", func(t *testing.T) {
+			resp := api.Get(t, "/account/tokens")
+			resp.AssertStatusCode(t, 200)
+			resp.JSONPathEqual(t, "[0].id", id)
+			assert.NotNil(t, resp.JSONPath(t, "[0].last_used"))
+		})
+		t.Run("delete", func(t *testing.T) {
+			api.Delete(t, "/account/tokens/1", nil).AssertStatusCode(t, 400)
+			api.Delete(t, "/account/tokens/"+fmt.Sprint(id), nil).AssertStatusCode(t, 200)
+			script := &API{Bearer: token}
+			script.Get(t, "/tasks").AssertStatusCode(t, 401)
+		})
+	})
+}
+
+func TestAccessTokenValidation(t *testing.T) {
+	withAccount(t, func(api *API) {
+		t.Run("unknown scope", func(t *testing.T) {
+			resp := api.Post(t, "/account/tokens", m{
+				"name":   "ci",
+				"scopes": []string{"tasks:delete"},
+			})
+			resp.AssertStatusCode(t, 400)
+			assert.Contains(t, resp.ErrorMessage(t), "unknown scope")
+		})
+		t.Run("expired", func(t *testing.T) {
+			resp := api.Post(t, "/account/tokens", m{
+				"name":    "ci",
+				"scopes":  []string{"tasks:read"},
+				"expires": time.Now().Add(-time.Hour).UTC().Format(time.RFC3339),
+			})
+			resp.AssertStatusCode(t, 400)
+		})
+	})
+}
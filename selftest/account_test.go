@@ -88,3 +88,89 @@ func TestGetAccount(t *testing.T) {
 		})
 	})
 }
+
+func TestLogout(t *testing.T) {
+	withAccount(t, func(api *API) {
+		api.Post(t, "/account/logout", nil).AssertStatusCode(t, 200)
+		api.Get(t, "/account").AssertStatusCode(t, 401)
+	})
+}
+
+func TestCreateAccountUsernameTaken(t *testing.T) {
+	withAccount(t, func(api *API) {
+		resp := (&API{}).Post(t, "/account", m{
+			"username": api.Username,
+			"password": fakePassword(),
+		})
+		resp.AssertStatusCode(t, 409)
+		assert.Equal(t, resp.ErrorCode(t), "username_taken")
+	})
+}
+
+func TestUpdatePassword(t *testing.T) {
+	withAccount(t, func(api *API) {
+		password := fakePassword()
+		t.Run("wrong current password", func(t *testing.T) {
+			resp := api.Put(t, "/account/password", m{
+				"current_password": "wrong-" + api.Password,
+				"password":         password,
+			})
+			resp.AssertStatusCode(t, 400)
+		})
+		t.Run("update", func(t *testing.T) {
+			other := &API{}
+			resp := other.Post(t, "/account/login", m{"username": api.Username, "password": api.Password})
+			resp.AssertStatusCode(t, 200)
+			other.Token = resp.JSONPathString(t, "token")
+
+			resp = api.Put(t, "/account/password", m{
+				"current_password": api.Password,
+				"password":         password,
+			})
+			resp.AssertStatusCode(t, 200)
+			token := resp.JSONPathString(t, "token")
+
+			api.Get(t, "/account").AssertStatusCode(t, 401)
+			other.Get(t, "/account").AssertStatusCode(t, 401)
+			api.Token = token
+			api.Get(t, "/account").AssertStatusCode(t, 200)
+		})
+		t.Run("login with new password", func(t *testing.T) {
+			resp := (&API{}).Post(t, "/account/login", m{"username": api.Username, "password": password})
+			resp.AssertStatusCode(t, 200)
+		})
+	})
+}
+
+func TestUpdateUsername(t *testing.T) {
+	withAccount(t, func(other *API) {
+		withAccount(t, func(api *API) {
+			t.Run("taken", func(t *testing.T) {
+				resp := api.Put(t, "/account/username", m{"username": other.Username})
+				resp.AssertStatusCode(t, 409)
+			})
+			t.Run("update", func(t *testing.T) {
+				username := "renamed-" + api.Username
+				resp := api.Put(t, "/account/username", m{"username": username})
+				resp.AssertStatusCode(t, 200)
+				resp.JSONPathEqual(t, "username", username)
+				api.Get(t, "/account").JSONPathEqual(t, "username", username)
+			})
+		})
+	})
+}
+
+func TestDeleteAccount(t *testing.T) {
+	withAccount(t, func(api *API) {
+		api.Post(t, "/tasks", m{"description": "alpha"}).AssertStatusCode(t, 200)
+		t.Run("wrong password", func(t *testing.T) {
+			api.Delete(t, "/account", m{"password": "wrong-" + api.Password}).AssertStatusCode(t, 400)
+		})
+		t.Run("delete", func(t *testing.T) {
+			api.Delete(t, "/account", m{"password": api.Password}).AssertStatusCode(t, 200)
+			api.Get(t, "/account").AssertStatusCode(t, 401)
+			resp := (&API{}).Post(t, "/account/login", m{"username": api.Username, "password": api.Password})
+			resp.AssertStatusCode(t, 400)
+		})
+	})
+}
@@ -0,0 +1,107 @@
+package selftest
+
+import (
+	"context"
+	"fmt"
+	"testing"
+
+	"github.com/deliveroo/assert-go"
+	"github.com/deliveroo/todo-api/selftest/deps/postgres"
+)
+
+// withAdmin runs fn with a logged in admin account. There's no API to create
+// the first admin, so the account is promoted in the database directly.
+func withAdmin(t *testing.T, fn func(*API)) {
+	t.Helper()
+	withAccount(t, func(api *API) {
+		pool, err := postgres.GetPool()
+		assert.Must(t, err)
+		defer pool.Close()
+		_, err = pool.Exec(context.Background(), `UPDATE accounts SET role = 'admin' WHERE username = $1`, api.Username)
+		assert.Must(t, err)
+		fn(api)
+	})
+}
+
+// accountID returns the public id of an API's account.
+func accountID(t *testing.T, api *API) string {
+	t.Helper()
+	resp := api.Get(t, "/account")
+	resp.AssertStatusCode(t, 200)
+	var account struct {
+		ID string `json:"id"`
+	}
+	resp.BindBody(t, &account)
+	return account.ID
+}
+
+func TestAdmin(t *testing.T) {
+	withAdmin(t, func(admin *API) {
+		withAccount(t, func(user *API) {
+			userID := accountID(t, user)
+			userPath := fmt.Sprintf("/admin/accounts/%s", userID)
+
+			t.Run("not an admin", func(t *testing.T) {
+				resp := user.Get(t, "/admin/accounts")
+				resp.AssertStatusCode(t, 403)
+				assert.Equal(t, resp.ErrorCode(t), "admin_required")
+			})
+			t.Run("search", func(t *testing.T) {
+				resp := admin.Get(t, "/admin/accounts?q="+user.Username)
+				resp.AssertStatusCode(t, 200)
+				resp.JSONPathEqual(t, "[0].username", user.Username)
+			})
+			t.Run("bad id", func(t *testing.T) {
+				resp := admin.Get(t, "/admin/accounts/abc")
+				resp.AssertStatusCode(t, 400)
+			})
+			t.Run("get", func(t *testing.T) {
+				resp := admin.Get(t, userPath)
+				resp.AssertStatusCode(t, 200)
+				resp.JSONPathEqual(t, "role", "user")
+			})
+			t.Run("task stats", func(t *testing.T) {
+				user.Post(t, "/tasks", m{"description": "alpha"}).AssertStatusCode(t, 200)
+				resp := admin.Get(t, userPath+"/task-stats")
+				resp.AssertStatusCode(t, 200)
+				resp.JSONPathEqual(t, "total", 1.0)
+				resp.JSONPathEqual(t, "incomplete", 1.0)
+			})
+			t.Run("force logout", func(t *testing.T) {
+				resp := admin.Post(t, userPath+"/logout", nil)
+				resp.AssertStatusCode(t, 200)
+				user.Get(t, "/account").AssertStatusCode(t, 401)
+			})
+			t.Run("reset password", func(t *testing.T) {
+				user.Password = fakePassword()
+				resp := admin.Post(t, userPath+"/password-reset", m{"password": user.Password})
+				resp.AssertStatusCode(t, 200)
+				resp = user.Post(t, "/account/login", m{
+					"username": user.Username,
+					"password": user.Password,
+				})
+				resp.AssertStatusCode(t, 200)
+			})
+			t.Run("suspend", func(t *testing.T) {
+				resp := admin.Post(t, userPath+"/suspend", m{"reason": "spam"})
+				resp.AssertStatusCode(t, 200)
+				assert.NotNil(t, resp.JSONPath(t, "suspended_at"))
+				resp.JSONPathEqual(t, "suspension_reason", "spam")
+			})
+			t.Run("unsuspend", func(t *testing.T) {
+				resp := admin.Post(t, userPath+"/unsuspend", nil)
+				resp.AssertStatusCode(t, 200)
+				resp.JSONPathEqual(t, "suspended_at", nil)
+			})
+			t.Run("audit log", func(t *testing.T) {
+				resp := admin.Get(t, fmt.Sprintf("/admin/audit-log?account_id=%s", userID))
+				resp.AssertStatusCode(t, 200)
+				resp.JSONPathEqual(t, "[0].action", "unsuspend")
+				resp.JSONPathEqual(t, "[1].action", "suspend")
+				resp.JSONPathEqual(t, "[1].details.reason", "spam")
+				resp.JSONPathEqual(t, "[2].action", "password_reset")
+				resp.JSONPathEqual(t, "[3].action", "logout")
+			})
+		})
+	})
+}
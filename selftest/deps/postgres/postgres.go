@@ -8,6 +8,7 @@ import (
 	"os"
 	"time"
 
+	"github.com/deliveroo/todo-api/repo"
 	"github.com/jackc/pgx/v4"
 	"github.com/jackc/pgx/v4/pgxpool"
 )
@@ -17,6 +18,7 @@ func getPool(ctx context.Context) (*pgxpool.Pool, error) {
 	if err != nil {
 		return nil, err
 	}
+	cfg.AfterConnect = repo.AfterConnect
 	return pgxpool.ConnectConfig(ctx, cfg)
 }
 
@@ -2,12 +2,12 @@
 Package selftest implements an automated integration test strategy that launches
 the application under as realistic conditions as possible:
 
-- It provides a real Redis and database connection
-- It starts the actual API server listening on a port in the same manner as the
-  application's package main
-- It provides configuration via environmental variables
-- It may provide external resources (e.g. 3rd party APIs) as fake
-  implementations (not demonstrated by this project)
+  - It provides a real Redis and database connection
+  - It starts the actual API server listening on a port in the same manner as the
+    application's package main
+  - It provides configuration via environmental variables
+  - It may provide external resources (e.g. 3rd party APIs) as fake
+    implementations (not demonstrated by this project)
 
 Once the API server is running, it's primarily tested as a black box; requests
 are made via HTTP, and responses are validated. The black box is only penetrated
@@ -0,0 +1,130 @@
+package selftest
+
+import (
+	"fmt"
+	"testing"
+
+	"github.com/deliveroo/assert-go"
+	"github.com/deliveroo/todo-api/conf"
+	"github.com/icrowley/fake"
+)
+
+// withUnverifiedAccount creates an account with an unverified email address
+// on the server at serverURL, and logs in.
+func withUnverifiedAccount(t *testing.T, serverURL string, fn func(api *API, email string)) {
+	t.Helper()
+	api := &API{
+		URL:      serverURL,
+		Username: fake.UserName(),
+		Password: fakePassword(),
+	}
+	email := api.Username + "@example.com"
+	resp := api.Post(t, "/account", m{
+		"username": api.Username,
+		"password": api.Password,
+		"email":    email,
+	})
+	resp.AssertStatusCode(t, 200)
+	resp = api.Post(t, "/account/login", m{
+		"username": api.Username,
+		"password": api.Password,
+	})
+	resp.AssertStatusCode(t, 200)
+	api.Token = resp.JSONPathString(t, "token")
+	fn(api, email)
+}
+
+func TestVerifyEmail(t *testing.T) {
+	withUnverifiedAccount(t, url, func(api *API, email string) {
+		token := waitForEmailToken(t, email, "Verify your")
+		t.Run("unverified", func(t *testing.T) {
+			resp := api.Get(t, "/account")
+			resp.AssertStatusCode(t, 200)
+			resp.JSONPathEqual(t, "verified_at", nil)
+		})
+		t.Run("invalid token", func(t *testing.T) {
+			resp := (&API{}).Post(t, "/account/verify", m{"token": "not-a-token"})
+			resp.AssertStatusCode(t, 400)
+		})
+		t.Run("verify", func(t *testing.T) {
+			resp := (&API{}).Post(t, "/account/verify", m{"token": token})
+			resp.AssertStatusCode(t, 200)
+			assert.NotNil(t, resp.JSONPath(t, "verified_at"))
+		})
+		t.Run("already verified", func(t *testing.T) {
+			resp := api.Post(t, "/account/verify/resend", nil)
+			resp.AssertStatusCode(t, 400)
+		})
+		t.Run("change email", func(t *testing.T) {
+			newEmail := "new-" + email
+			resp := api.Put(t, "/account/email", m{
+				"email":    newEmail,
+				"password": api.Password,
+			})
+			resp.AssertStatusCode(t, 200)
+			resp.JSONPathEqual(t, "email", newEmail)
+			resp.JSONPathEqual(t, "verified_at", nil)
+			token := waitForEmailToken(t, newEmail, "Verify your")
+			resp = (&API{}).Post(t, "/account/verify", m{"token": token})
+			resp.AssertStatusCode(t, 200)
+		})
+		t.Run("change email wrong password", func(t *testing.T) {
+			resp := api.Put(t, "/account/email", m{
+				"email":    "other-" + email,
+				"password": "wrong-" + api.Password,
+			})
+			resp.AssertStatusCode(t, 400)
+		})
+	})
+}
+
+func TestUnverifiedReadOnly(t *testing.T) {
+	serverURL, stop := startServer(t, func(cfg *conf.Config) {
+		cfg.UnverifiedAccess = "read-only"
+	})
+	defer stop()
+
+	withUnverifiedAccount(t, serverURL, func(api *API, email string) {
+		t.Run("clear email", func(t *testing.T) {
+			resp := api.Put(t, "/account/email", m{
+				"email":    "",
+				"password": api.Password,
+			})
+			resp.AssertStatusCode(t, 400)
+		})
+		t.Run("read", func(t *testing.T) {
+			resp := api.Get(t, "/tasks")
+			resp.AssertStatusCode(t, 200)
+		})
+		t.Run("write", func(t *testing.T) {
+			resp := api.Post(t, "/tasks", m{"description": "alpha"})
+			resp.AssertStatusCode(t, 403)
+			assert.Equal(t, resp.ErrorCode(t), "verification_required")
+		})
+		t.Run("verified", func(t *testing.T) {
+			token := waitForEmailToken(t, email, "Verify your")
+			resp := api.Post(t, "/account/verify", m{"token": token})
+			resp.AssertStatusCode(t, 200)
+			resp = api.Post(t, "/tasks", m{"description": "alpha"})
+			resp.AssertStatusCode(t, 200)
+		})
+	})
+}
+
+func TestUnverifiedLimited(t *testing.T) {
+	serverURL, stop := startServer(t, func(cfg *conf.Config) {
+		cfg.UnverifiedAccess = "limited"
+		cfg.UnverifiedTaskLimit = 2
+	})
+	defer stop()
+
+	withUnverifiedAccount(t, serverURL, func(api *API, email string) {
+		for i := 0; i < 2; i++ {
+			resp := api.Post(t, "/tasks", m{"description": fmt.Sprint(i)})
+			resp.AssertStatusCode(t, 200)
+		}
+		resp := api.Post(t, "/tasks", m{"description": "over the limit"})
+		resp.AssertStatusCode(t, 403)
+		assert.Equal(t, resp.ErrorCode(t), "verification_required")
+	})
+}
@@ -0,0 +1,125 @@
+package selftest
+
+import (
+	"bufio"
+	"context"
+	"fmt"
+	"net/http"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/deliveroo/assert-go"
+)
+
+func TestEvents(t *testing.T) {
+	withAccount(t, func(api *API) {
+		t.Run("malformed Last-Event-ID", func(t *testing.T) {
+			bad := &API{Token: api.Token, Header: http.Header{"Last-Event-Id": {"latest"}}}
+			bad.Get(t, "/events").AssertStatusCode(t, 400)
+		})
+
+		events, stop := openEventStream(t, api, "")
+		start := nextEvent(t, events)
+		assert.Equal(t, start.id, "0")
+		assert.Equal(t, start.event, "")
+
+		resp := api.Post(t, "/tasks", m{"description": "stream it"})
+		resp.AssertStatusCode(t, 200)
+		taskPath := fmt.Sprintf("/tasks/%v", resp.JSONPath(t, "id"))
+		created := nextEvent(t, events)
+		assert.Equal(t, created.id, "1")
+		assert.Equal(t, created.event, "task.created")
+		assert.True(t, strings.Contains(created.data, `"description":"stream it"`))
+		stop()
+
+		t.Run("resume", func(t *testing.T) {
+			api.Put(t, taskPath, m{"description": "stream it again"}).AssertStatusCode(t, 200)
+			api.Delete(t, taskPath, nil).AssertStatusCode(t, 200)
+
+			events, stop := openEventStream(t, api, created.id)
+			defer stop()
+			assert.Equal(t, nextEvent(t, events).event, "task.updated")
+			deleted := nextEvent(t, events)
+			assert.Equal(t, deleted.id, "3")
+			assert.Equal(t, deleted.event, "task.deleted")
+		})
+		t.Run("reset", func(t *testing.T) {
+			events, stop := openEventStream(t, api, "1000")
+			defer stop()
+			reset := nextEvent(t, events)
+			assert.Equal(t, reset.id, "3")
+			assert.Equal(t, reset.event, "reset")
+		})
+	})
+}
+
+type streamEvent struct {
+	id, event, data string
+}
+
+// openEventStream opens the account's event stream, returning a channel of
+// the events received and a function which closes the stream.
+func openEventStream(t *testing.T, api *API, lastEventID string) (<-chan streamEvent, func()) {
+	t.Helper()
+	ctx, cancel := context.WithCancel(context.Background())
+	req, err := http.NewRequest(http.MethodGet, api.baseURL()+"/events", nil)
+	assert.Must(t, err)
+	req = req.WithContext(ctx)
+	api.authorize(req)
+	if lastEventID != "" {
+		req.Header.Set("Last-Event-ID", lastEventID)
+	}
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		cancel()
+		t.Fatal(err)
+	}
+	if resp.StatusCode != 200 {
+		cancel()
+		t.Fatalf("unexpected status code %d", resp.StatusCode)
+	}
+	assert.Equal(t, resp.Header.Get("Content-Type"), "text/event-stream")
+
+	events := make(chan streamEvent, 10)
+	go func() {
+		defer close(events)
+		var e streamEvent
+		scanner := bufio.NewScanner(resp.Body)
+		for scanner.Scan() {
+			line := scanner.Text()
+			switch {
+			case line == "":
+				if e.id != "" {
+					events <- e
+				}
+				e = streamEvent{}
+			case strings.HasPrefix(line, "id: "):
+				e.id = strings.TrimPrefix(line, "id: ")
+			case strings.HasPrefix(line, "event: "):
+				e.event = strings.TrimPrefix(line, "event: ")
+			case strings.HasPrefix(line, "data: "):
+				e.data += strings.TrimPrefix(line, "data: ")
+			}
+		}
+	}()
+	return events, func() {
+		cancel()
+		resp.Body.Close()
+	}
+}
+
+// nextEvent waits for the next event on a stream.
+func nextEvent(t *testing.T, events <-chan streamEvent) streamEvent {
+	t.Helper()
+	select {
+	case e, ok := <-events:
+		if !ok {
+			t.Fatal("event stream closed")
+		}
+		return e
+	case <-time.After(5 * time.Second):
+		t.Fatal("timed out waiting for event")
+	}
+	return streamEvent{}
+}
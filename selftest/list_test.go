@@ -0,0 +1,114 @@
+package selftest
+
+import (
+	"fmt"
+	"testing"
+
+	"github.com/deliveroo/assert-go"
+)
+
+func TestSharedLists(t *testing.T) {
+	withAccount(t, func(owner *API) {
+		withAccount(t, func(member *API) {
+			withAccount(t, func(outsider *API) {
+				resp := owner.Post(t, "/lists", m{"name": "groceries"})
+				resp.AssertStatusCode(t, 200)
+				resp.JSONPathEqual(t, "role", "owner")
+				listPath := fmt.Sprintf("/lists/%v", resp.JSONPath(t, "id"))
+				listID := resp.JSONPath(t, "id")
+
+				resp = owner.Post(t, "/tasks", m{"description": "milk", "list_id": listID})
+				resp.AssertStatusCode(t, 200)
+				resp.JSONPathEqual(t, "list_id", listID)
+				taskPath := fmt.Sprintf("/tasks/%v", resp.JSONPath(t, "id"))
+
+				t.Run("malformed id", func(t *testing.T) {
+					// Lists are only identified by their public ids.
+					owner.Get(t, "/lists/1").AssertStatusCode(t, 400)
+					owner.Post(t, "/tasks", m{"description": "milk", "list_id": 1}).AssertStatusCode(t, 400)
+					owner.Post(t, "/tasks", m{"description": "milk", "list_id": "1"}).AssertStatusCode(t, 400)
+				})
+				t.Run("not shared yet", func(t *testing.T) {
+					member.Get(t, listPath).AssertStatusCode(t, 404)
+					member.Get(t, taskPath).AssertStatusCode(t, 404)
+				})
+				t.Run("invite as viewer", func(t *testing.T) {
+					resp := owner.Post(t, listPath+"/invitations", m{"username": member.Username, "role": "viewer"})
+					resp.AssertStatusCode(t, 200)
+					resp.JSONPathEqual(t, "status", "pending")
+
+					resp = owner.Post(t, listPath+"/invitations", m{"username": member.Username, "role": "viewer"})
+					resp.AssertStatusCode(t, 409)
+
+					resp = member.Get(t, "/account/invitations")
+					resp.AssertStatusCode(t, 200)
+					resp.JSONPathEqual(t, "[0].list_name", "groceries")
+					resp.JSONPathEqual(t, "[0].inviter", owner.Username)
+					id := resp.JSONPath(t, "[0].id")
+
+					resp = member.Post(t, fmt.Sprintf("/account/invitations/%v/accept", id), nil)
+					resp.AssertStatusCode(t, 200)
+					resp.JSONPathEqual(t, "status", "accepted")
+				})
+				t.Run("viewer can read", func(t *testing.T) {
+					resp := member.Get(t, listPath)
+					resp.AssertStatusCode(t, 200)
+					resp.JSONPathEqual(t, "role", "viewer")
+					resp = member.Get(t, listPath+"/tasks")
+					resp.AssertStatusCode(t, 200)
+					resp.JSONPathEqual(t, "[0].description", "milk")
+					member.Get(t, taskPath).AssertStatusCode(t, 200)
+					resp = member.Get(t, listPath+"/members")
+					resp.AssertStatusCode(t, 200)
+					resp.JSONPathEqual(t, "[0].username", owner.Username)
+				})
+				t.Run("viewer can't write", func(t *testing.T) {
+					resp := member.Put(t, taskPath, m{"description": "oat milk"})
+					resp.AssertStatusCode(t, 403)
+					assert.Equal(t, resp.ErrorCode(t), "list_read_only")
+					member.Delete(t, taskPath, nil).AssertStatusCode(t, 403)
+					member.Post(t, "/tasks", m{"description": "eggs", "list_id": listID}).AssertStatusCode(t, 403)
+					resp = member.Post(t, listPath+"/invitations", m{"username": outsider.Username, "role": "viewer"})
+					resp.AssertStatusCode(t, 403)
+					assert.Equal(t, resp.ErrorCode(t), "list_owner_required")
+				})
+				t.Run("promote to editor", func(t *testing.T) {
+					path := fmt.Sprintf("%s/members/%s", listPath, accountID(t, member))
+					owner.Put(t, path, m{"role": "editor"}).AssertStatusCode(t, 200)
+					member.Put(t, taskPath, m{"description": "oat milk"}).AssertStatusCode(t, 200)
+					member.Post(t, "/tasks", m{"description": "eggs", "list_id": listID}).AssertStatusCode(t, 200)
+
+					resp := owner.Get(t, listPath+"/tasks")
+					resp.AssertStatusCode(t, 200)
+					var tasks []m
+					resp.BindBody(t, &tasks)
+					assert.Equal(t, len(tasks), 2)
+				})
+				t.Run("outsider", func(t *testing.T) {
+					outsider.Get(t, listPath+"/tasks").AssertStatusCode(t, 404)
+					outsider.Put(t, taskPath, m{"description": "spam"}).AssertStatusCode(t, 404)
+					outsider.Post(t, "/tasks", m{"description": "spam", "list_id": listID}).AssertStatusCode(t, 404)
+				})
+				t.Run("decline", func(t *testing.T) {
+					owner.Post(t, listPath+"/invitations", m{"username": outsider.Username, "role": "editor"}).AssertStatusCode(t, 200)
+					resp := outsider.Get(t, "/account/invitations")
+					resp.AssertStatusCode(t, 200)
+					id := resp.JSONPath(t, "[0].id")
+					resp = outsider.Post(t, fmt.Sprintf("/account/invitations/%v/decline", id), nil)
+					resp.AssertStatusCode(t, 200)
+					resp.JSONPathEqual(t, "status", "declined")
+					outsider.Get(t, listPath).AssertStatusCode(t, 404)
+				})
+				t.Run("leave", func(t *testing.T) {
+					path := fmt.Sprintf("%s/members/%s", listPath, accountID(t, member))
+					member.Delete(t, path, nil).AssertStatusCode(t, 200)
+					member.Get(t, listPath).AssertStatusCode(t, 404)
+				})
+				t.Run("delete", func(t *testing.T) {
+					owner.Delete(t, listPath, nil).AssertStatusCode(t, 200)
+					owner.Get(t, taskPath).AssertStatusCode(t, 404)
+				})
+			})
+		})
+	})
+}
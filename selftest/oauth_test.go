@@ -0,0 +1,249 @@
+package selftest
+
+import (
+	"net/http"
+	neturl "net/url"
+	"strings"
+	"testing"
+
+	"github.com/deliveroo/assert-go"
+)
+
+const (
+	// PKCE test vector from RFC 7636 appendix B.
+	codeVerifier  = "dBjftJeZ4CVP-mB92K27uhbUJU1p1r_wW1gFWFOEjXk"
+	codeChallenge = "E9Melhoa2OwvFrEMTJguCHaoeK1t8URWbuGJSstw-cM"
+
+	redirectURI = "https://client.example.com/callback"
+)
+
+// oauthClient is a third-party client registered with the API.
+type oauthClient struct {
+	ID     string
+	Secret string
+}
+
+// postForm posts a form to the OAuth endpoints, authenticating as the client.
+func (c *oauthClient) postForm(t *testing.T, path string, form neturl.Values) *TestResponse {
+	t.Helper()
+	req, err := http.NewRequest(http.MethodPost, url+path, strings.NewReader(form.Encode()))
+	assert.Must(t, err)
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	req.SetBasicAuth(neturl.QueryEscape(c.ID), neturl.QueryEscape(c.Secret))
+	resp, err := http.DefaultClient.Do(req)
+	assert.Must(t, err)
+	return &TestResponse{resp: resp}
+}
+
+func registerOAuthClient(t *testing.T, owner *API, confidential bool) *oauthClient {
+	t.Helper()
+	resp := owner.Post(t, "/oauth/clients", m{
+		"name":          "Calendar sync",
+		"redirect_uris": []string{redirectURI},
+		"confidential":  confidential,
+	})
+	resp.AssertStatusCode(t, 200)
+	c := &oauthClient{ID: resp.JSONPathString(t, "client_id")}
+	if confidential {
+		c.Secret = resp.JSONPathString(t, "client_secret")
+	}
+	return c
+}
+
+// authorize approves an authorization request as the user, and returns the
+// authorization code sent to the redirect URI.
+func authorize(t *testing.T, user *API, c *oauthClient, scope string) string {
+	t.Helper()
+	resp := user.Post(t, "/oauth/authorize", m{
+		"response_type":         "code",
+		"client_id":             c.ID,
+		"redirect_uri":          redirectURI,
+		"scope":                 scope,
+		"state":                 "xyz",
+		"code_challenge":        codeChallenge,
+		"code_challenge_method": "S256",
+		"approve":               true,
+	})
+	resp.AssertStatusCode(t, 200)
+	u, err := neturl.Parse(resp.JSONPathString(t, "redirect_uri"))
+	assert.Must(t, err)
+	assert.Equal(t, u.Query().Get("state"), "xyz")
+	return u.Query().Get("code")
+}
+
+func exchangeCode(t *testing.T, c *oauthClient, code, verifier string) *TestResponse {
+	t.Helper()
+	return c.postForm(t, "/oauth/token", neturl.Values{
+		"grant_type":    {"authorization_code"},
+		"code":          {code},
+		"redirect_uri":  {redirectURI},
+		"code_verifier": {verifier},
+	})
+}
+
+func TestOAuthAuthorizationCodeFlow(t *testing.T) {
+	withAccount(t, func(owner *API) {
+		withAccount(t, func(user *API) {
+			c := registerOAuthClient(t, owner, true)
+
+			t.Run("consent", func(t *testing.T) {
+				q := neturl.Values{
+					"response_type":         {"code"},
+					"client_id":             {c.ID},
+					"scope":                 {"tasks:read account:read"},
+					"state":                 {"xyz"},
+					"code_challenge":        {codeChallenge},
+					"code_challenge_method": {"S256"},
+				}
+				resp := user.Get(t, "/oauth/authorize?"+q.Encode())
+				resp.AssertStatusCode(t, 200)
+				resp.JSONPathEqual(t, "client.name", "Calendar sync")
+				resp.JSONPathEqual(t, "redirect_uri", redirectURI)
+				resp.JSONPathEqual(t, "scopes", []interface{}{"tasks:read", "account:read"})
+			})
+
+			code := authorize(t, user, c, "tasks:read")
+			resp := exchangeCode(t, c, code, codeVerifier)
+			resp.AssertStatusCode(t, 200)
+			resp.JSONPathEqual(t, "token_type", "Bearer")
+			resp.JSONPathEqual(t, "scope", "tasks:read")
+			accessToken := resp.JSONPathString(t, "access_token")
+			refreshToken := resp.JSONPathString(t, "refresh_token")
+
+			t.Run("code is single use", func(t *testing.T) {
+				resp := exchangeCode(t, c, code, codeVerifier)
+				resp.AssertStatusCode(t, 400)
+				resp.JSONPathEqual(t, "error", "invalid_grant")
+			})
+			t.Run("scoped access token", func(t *testing.T) {
+				app := &API{Bearer: accessToken}
+				app.Get(t, "/tasks").AssertStatusCode(t, 200)
+				app.Post(t, "/tasks", m{"description": "from an app"}).AssertStatusCode(t, 403)
+				app.Get(t, "/oauth/clients").AssertStatusCode(t, 403)
+			})
+			t.Run("introspect", func(t *testing.T) {
+				resp := c.postForm(t, "/oauth/introspect", neturl.Values{"token": {accessToken}})
+				resp.AssertStatusCode(t, 200)
+				resp.JSONPathEqual(t, "active", true)
+				resp.JSONPathEqual(t, "scope", "tasks:read")
+				resp.JSONPathEqual(t, "username", user.Username)
+
+				other := registerOAuthClient(t, owner, true)
+				resp = other.postForm(t, "/oauth/introspect", neturl.Values{"token": {accessToken}})
+				resp.AssertStatusCode(t, 200)
+				resp.JSONPathEqual(t, "active", false)
+			})
+			t.Run("refresh", func(t *testing.T) {
+				resp := c.postForm(t, "/oauth/token", neturl.Values{
+					"grant_type":    {"refresh_token"},
+					"refresh_token": {refreshToken},
+				})
+				resp.AssertStatusCode(t, 200)
+				refreshed := &API{Bearer: resp.JSONPathString(t, "access_token")}
+				refreshed.Get(t, "/tasks").AssertStatusCode(t, 200)
+
+				resp = c.postForm(t, "/oauth/token", neturl.Values{
+					"grant_type":    {"refresh_token"},
+					"refresh_token": {refreshToken},
+				})
+				resp.AssertStatusCode(t, 400)
+				resp.JSONPathEqual(t, "error", "invalid_grant")
+			})
+			t.Run("revoke", func(t *testing.T) {
+				resp := user.Get(t, "/account/authorizations")
+				resp.AssertStatusCode(t, 200)
+				resp.JSONPathEqual(t, "[0].client_id", c.ID)
+				user.Delete(t, "/account/authorizations/"+c.ID, nil).AssertStatusCode(t, 200)
+				(&API{Bearer: accessToken}).Get(t, "/tasks").AssertStatusCode(t, 401)
+			})
+		})
+	})
+}
+
+func TestOAuthPublicClient(t *testing.T) {
+	withAccount(t, func(user *API) {
+		c := registerOAuthClient(t, user, false)
+		code := authorize(t, user, c, "tasks:read tasks:write")
+
+		t.Run("wrong verifier", func(t *testing.T) {
+			resp := exchangeCode(t, c, code, strings.Repeat("a", 43))
+			resp.AssertStatusCode(t, 400)
+			resp.JSONPathEqual(t, "error", "invalid_grant")
+		})
+		t.Run("code burnt after a failed exchange", func(t *testing.T) {
+			resp := exchangeCode(t, c, code, codeVerifier)
+			resp.AssertStatusCode(t, 400)
+		})
+		t.Run("success", func(t *testing.T) {
+			code := authorize(t, user, c, "tasks:write")
+			resp := exchangeCode(t, c, code, codeVerifier)
+			resp.AssertStatusCode(t, 200)
+			app := &API{Bearer: resp.JSONPathString(t, "access_token")}
+			app.Post(t, "/tasks", m{"description": "from an app"}).AssertStatusCode(t, 200)
+		})
+	})
+}
+
+func TestOAuthErrors(t *testing.T) {
+	withAccount(t, func(user *API) {
+		c := registerOAuthClient(t, user, true)
+		t.Run("denied", func(t *testing.T) {
+			resp := user.Post(t, "/oauth/authorize", m{
+				"response_type":         "code",
+				"client_id":             c.ID,
+				"scope":                 "tasks:read",
+				"code_challenge":        codeChallenge,
+				"code_challenge_method": "S256",
+				"approve":               false,
+			})
+			resp.AssertStatusCode(t, 200)
+			u, err := neturl.Parse(resp.JSONPathString(t, "redirect_uri"))
+			assert.Must(t, err)
+			assert.Equal(t, u.Query().Get("error"), "access_denied")
+			assert.Equal(t, u.Query().Get("code"), "")
+		})
+		for name, tc := range map[string]struct {
+			params m
+			code   string
+		}{
+			"unknown client":        {m{"client_id": "unknown"}, "invalid_client"},
+			"unregistered redirect": {m{"redirect_uri": "https://evil.example.com/"}, "invalid_redirect_uri"},
+			"missing pkce":          {m{"code_challenge": ""}, "invalid_request"},
+			"plain pkce":            {m{"code_challenge_method": "plain"}, "invalid_request"},
+			"unknown scope":         {m{"scope": "tasks:delete"}, "invalid_scope"},
+			"implicit grant":        {m{"response_type": "token"}, "unsupported_response_type"},
+		} {
+			t.Run(name, func(t *testing.T) {
+				params := m{
+					"response_type":         "code",
+					"client_id":             c.ID,
+					"scope":                 "tasks:read",
+					"code_challenge":        codeChallenge,
+					"code_challenge_method": "S256",
+					"approve":               true,
+				}
+				for k, v := range tc.params {
+					params[k] = v
+				}
+				resp := user.Post(t, "/oauth/authorize", params)
+				resp.AssertStatusCode(t, 400)
+				assert.Equal(t, resp.ErrorCode(t), tc.code)
+			})
+		}
+		t.Run("wrong secret", func(t *testing.T) {
+			wrong := &oauthClient{ID: c.ID, Secret: "wrong"}
+			resp := exchangeCode(t, wrong, "code", codeVerifier)
+			resp.AssertStatusCode(t, 401)
+			resp.JSONPathEqual(t, "error", "invalid_client")
+		})
+		t.Run("access tokens can't authorize clients", func(t *testing.T) {
+			resp := user.Post(t, "/account/tokens", m{
+				"name":   "script",
+				"scopes": []string{"tasks:read", "tasks:write", "account:read"},
+			})
+			resp.AssertStatusCode(t, 200)
+			script := &API{Bearer: resp.JSONPathString(t, "token")}
+			script.Post(t, "/oauth/authorize", m{}).AssertStatusCode(t, 403)
+		})
+	})
+}
@@ -0,0 +1,94 @@
+package selftest
+
+import (
+	"testing"
+	"time"
+
+	"github.com/deliveroo/assert-go"
+	"github.com/deliveroo/todo-api/conf"
+	"github.com/deliveroo/todo-api/pkg/oidc"
+)
+
+// loginOIDC logs in with the identity provider as the given user, following
+// the redirects from GET /auth/oidc/start.
+func loginOIDC(t *testing.T, api *API, user oidc.Claims) *TestResponse {
+	t.Helper()
+	idp.SetUser(user)
+	return api.Get(t, "/auth/oidc/start")
+}
+
+func TestOIDCLogin(t *testing.T) {
+	id := time.Now().Format("20060102150405.000000000")
+	user := oidc.Claims{
+		Subject:           "subject-" + id,
+		Email:             "oidc-" + id + "@example.com",
+		EmailVerified:     true,
+		PreferredUsername: "oidc-" + id,
+	}
+
+	resp := loginOIDC(t, &API{}, user)
+	resp.AssertStatusCode(t, 200)
+	api := &API{Token: resp.JSONPathString(t, "token")}
+
+	resp = api.Get(t, "/account")
+	resp.AssertStatusCode(t, 200)
+	resp.JSONPathEqual(t, "username", user.PreferredUsername)
+	resp.JSONPathEqual(t, "email", user.Email)
+	assert.NotNil(t, resp.JSONPath(t, "verified_at"))
+
+	resp = api.Get(t, "/account/identities")
+	resp.AssertStatusCode(t, 200)
+	resp.JSONPathEqual(t, "[0].issuer", idp.Issuer)
+	resp.JSONPathEqual(t, "[0].subject", user.Subject)
+
+	t.Run("returning user", func(t *testing.T) {
+		resp := loginOIDC(t, &API{}, user)
+		resp.AssertStatusCode(t, 200)
+		again := &API{Token: resp.JSONPathString(t, "token")}
+		assert.Equal(t, accountID(t, again), accountID(t, api))
+	})
+	t.Run("unverified email isn't used", func(t *testing.T) {
+		resp := loginOIDC(t, &API{}, oidc.Claims{
+			Subject: "unverified-" + id,
+			Email:   "unverified-" + id + "@example.com",
+		})
+		resp.AssertStatusCode(t, 200)
+		other := &API{Token: resp.JSONPathString(t, "token")}
+		resp = other.Get(t, "/account")
+		resp.AssertStatusCode(t, 200)
+		resp.JSONPathEqual(t, "username", "unverified-"+id)
+		resp.JSONPathEqual(t, "email", "")
+	})
+}
+
+func TestOIDCUsernameTaken(t *testing.T) {
+	withAccount(t, func(api *API) {
+		resp := loginOIDC(t, &API{}, oidc.Claims{
+			Subject:           "taken-" + api.Username,
+			PreferredUsername: api.Username,
+		})
+		resp.AssertStatusCode(t, 200)
+		other := &API{Token: resp.JSONPathString(t, "token")}
+		resp = other.Get(t, "/account")
+		resp.AssertStatusCode(t, 200)
+		resp.JSONPathEqual(t, "username", api.Username+"-2")
+	})
+}
+
+func TestOIDCInvalidState(t *testing.T) {
+	resp := (&API{}).Get(t, "/auth/oidc/callback?state=bogus&code=bogus")
+	resp.AssertStatusCode(t, 400)
+}
+
+func TestOIDCSignupDisabled(t *testing.T) {
+	serverURL, stop := startServer(t, func(cfg *conf.Config) {
+		cfg.OIDCRedirectURL = "http://" + cfg.Addr + "/auth/oidc/callback"
+		cfg.OIDCSignup = false
+	})
+	defer stop()
+	resp := loginOIDC(t, &API{URL: serverURL}, oidc.Claims{
+		Subject: "disabled-" + time.Now().Format(time.RFC3339Nano),
+	})
+	resp.AssertStatusCode(t, 403)
+	assert.Equal(t, resp.ErrorCode(t), "signup_disabled")
+}
@@ -0,0 +1,106 @@
+package selftest
+
+import (
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/deliveroo/assert-go"
+)
+
+func TestOrgs(t *testing.T) {
+	withAccount(t, func(owner *API) {
+		withAccount(t, func(member *API) {
+			withAccount(t, func(outsider *API) {
+				slug := fmt.Sprintf("acme-%d", time.Now().UnixNano())
+				orgPath := "/orgs/" + slug
+
+				resp := owner.Post(t, "/orgs", m{"name": "Acme", "slug": slug})
+				resp.AssertStatusCode(t, 200)
+				resp.JSONPathEqual(t, "role", "owner")
+				outsider.Post(t, "/orgs", m{"name": "Acme", "slug": slug}).AssertStatusCode(t, 409)
+				owner.Post(t, "/orgs", m{"name": "Acme", "slug": "Not A Slug"}).AssertStatusCode(t, 400)
+
+				t.Run("not a member yet", func(t *testing.T) {
+					member.Get(t, orgPath).AssertStatusCode(t, 404)
+					member.Get(t, orgPath+"/tasks").AssertStatusCode(t, 404)
+				})
+				t.Run("invite", func(t *testing.T) {
+					resp := owner.Post(t, orgPath+"/invitations", m{"username": member.Username, "role": "member"})
+					resp.AssertStatusCode(t, 200)
+					resp.JSONPathEqual(t, "status", "pending")
+
+					resp = member.Get(t, "/account/org-invitations")
+					resp.AssertStatusCode(t, 200)
+					resp.JSONPathEqual(t, "[0].org_slug", slug)
+					id := resp.JSONPath(t, "[0].id")
+
+					member.Post(t, "/account/org-invitations/1/accept", nil).AssertStatusCode(t, 400)
+					resp = member.Post(t, fmt.Sprintf("/account/org-invitations/%v/accept", id), nil)
+					resp.AssertStatusCode(t, 200)
+					resp.JSONPathEqual(t, "status", "accepted")
+
+					resp = member.Get(t, orgPath)
+					resp.AssertStatusCode(t, 200)
+					resp.JSONPathEqual(t, "role", "member")
+				})
+				t.Run("members can't manage", func(t *testing.T) {
+					member.Put(t, orgPath, m{"name": "Mine"}).AssertStatusCode(t, 403)
+					resp := member.Post(t, orgPath+"/invitations", m{"username": outsider.Username, "role": "member"})
+					resp.AssertStatusCode(t, 403)
+				})
+				t.Run("tasks", func(t *testing.T) {
+					resp := member.Post(t, orgPath+"/tasks", m{"description": "ship it"})
+					resp.AssertStatusCode(t, 200)
+					taskID := resp.JSONPath(t, "id")
+
+					resp = owner.Get(t, orgPath+"/tasks")
+					resp.AssertStatusCode(t, 200)
+					resp.JSONPathEqual(t, "[0].description", "ship it")
+					owner.Get(t, fmt.Sprintf("%s/tasks/%v", orgPath, taskID)).AssertStatusCode(t, 200)
+
+					// Organisation tasks aren't visible outside the organisation.
+					owner.Get(t, fmt.Sprintf("/tasks/%v", taskID)).AssertStatusCode(t, 404)
+					member.Put(t, fmt.Sprintf("/tasks/%v", taskID), m{"description": "leak"}).AssertStatusCode(t, 404)
+
+					resp = owner.Put(t, fmt.Sprintf("%s/tasks/%v/assignee", orgPath, taskID), m{"assignee_id": accountID(t, outsider)})
+					resp.AssertStatusCode(t, 400)
+					resp = owner.Put(t, fmt.Sprintf("%s/tasks/%v/assignee", orgPath, taskID), m{"assignee_id": accountID(t, member)})
+					resp.AssertStatusCode(t, 200)
+				})
+				t.Run("promote", func(t *testing.T) {
+					path := fmt.Sprintf("%s/members/%v", orgPath, accountID(t, member))
+					owner.Put(t, path, m{"role": "admin"}).AssertStatusCode(t, 200)
+					member.Put(t, orgPath, m{"name": "Acme Ltd"}).AssertStatusCode(t, 200)
+					member.Delete(t, orgPath, nil).AssertStatusCode(t, 403)
+				})
+				t.Run("transfer ownership", func(t *testing.T) {
+					ownerPath := orgPath + "/owner"
+					member.Put(t, ownerPath, m{"account_id": accountID(t, member)}).AssertStatusCode(t, 403)
+					owner.Put(t, ownerPath, m{"account_id": accountID(t, outsider)}).AssertStatusCode(t, 404)
+
+					// The owner can't delete their account while the
+					// organisation has other members.
+					resp := owner.Delete(t, "/account", m{"password": owner.Password})
+					resp.AssertStatusCode(t, 409)
+					assert.Equal(t, resp.ErrorCode(t), "org_owner")
+
+					owner.Put(t, ownerPath, m{"account_id": accountID(t, member)}).AssertStatusCode(t, 200)
+					owner.Get(t, orgPath).JSONPathEqual(t, "role", "admin")
+					member.Get(t, orgPath).JSONPathEqual(t, "role", "owner")
+					member.Put(t, ownerPath, m{"account_id": accountID(t, owner)}).AssertStatusCode(t, 200)
+					owner.Get(t, orgPath).JSONPathEqual(t, "role", "owner")
+				})
+				t.Run("leave", func(t *testing.T) {
+					path := fmt.Sprintf("%s/members/%v", orgPath, accountID(t, member))
+					member.Delete(t, path, nil).AssertStatusCode(t, 200)
+					member.Get(t, orgPath).AssertStatusCode(t, 404)
+				})
+				t.Run("delete", func(t *testing.T) {
+					owner.Delete(t, orgPath, nil).AssertStatusCode(t, 200)
+					owner.Get(t, orgPath).AssertStatusCode(t, 404)
+				})
+			})
+		})
+	})
+}
@@ -0,0 +1,157 @@
+package selftest
+
+import (
+	"context"
+	"regexp"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/deliveroo/assert-go"
+	"github.com/icrowley/fake"
+)
+
+// emailTokenPattern matches the token in a password reset or email
+// verification email.
+var emailTokenPattern = regexp.MustCompile(`(?m)^[A-Za-z0-9_-]{43}$`)
+
+// waitForEmailToken waits for an email with the given subject to be sent to
+// email, and returns the token in it.
+func waitForEmailToken(t *testing.T, email, subject string) string {
+	t.Helper()
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	for {
+		msg, err := mailServer.WaitFor(ctx, email)
+		assert.Must(t, err)
+		if strings.Contains(msg.Subject, subject) {
+			token := emailTokenPattern.FindString(msg.Body)
+			assert.True(t, token != "")
+			return token
+		}
+		select {
+		case <-time.After(50 * time.Millisecond):
+		case <-ctx.Done():
+			t.Fatalf("no %q email sent to %s", subject, email)
+		}
+	}
+}
+
+func TestPasswordReset(t *testing.T) {
+	var (
+		username = fake.UserName()
+		email    = username + "@example.com"
+		password = fakePassword()
+	)
+	resp := (&API{}).Post(t, "/account", m{
+		"username": username,
+		"password": password,
+		"email":    email,
+	})
+	resp.AssertStatusCode(t, 200)
+	resp.JSONPathEqual(t, "email", email)
+
+	var token string
+	t.Run("unverified email", func(t *testing.T) {
+		// No token is sent, but the response doesn't say so.
+		resp := (&API{}).Post(t, "/account/password-reset", m{"email": email})
+		resp.AssertStatusCode(t, 200)
+		verification := waitForEmailToken(t, email, "Verify your")
+		(&API{}).Post(t, "/account/verify", m{"token": verification}).AssertStatusCode(t, 200)
+	})
+	t.Run("request", func(t *testing.T) {
+		resp := (&API{}).Post(t, "/account/password-reset", m{"email": email})
+		resp.AssertStatusCode(t, 200)
+		token = waitForEmailToken(t, email, "Reset your")
+	})
+	t.Run("unknown email", func(t *testing.T) {
+		resp := (&API{}).Post(t, "/account/password-reset", m{"email": "unknown-" + email})
+		resp.AssertStatusCode(t, 200)
+	})
+	t.Run("invalid token", func(t *testing.T) {
+		resp := (&API{}).Post(t, "/account/password-reset/confirm", m{
+			"token":    "not-a-token",
+			"password": fakePassword(),
+		})
+		resp.AssertStatusCode(t, 400)
+	})
+
+	newPassword := fakePassword()
+	t.Run("confirm", func(t *testing.T) {
+		resp := (&API{}).Post(t, "/account/password-reset/confirm", m{
+			"token":    token,
+			"password": newPassword,
+		})
+		resp.AssertStatusCode(t, 200)
+	})
+	t.Run("token is single use", func(t *testing.T) {
+		resp := (&API{}).Post(t, "/account/password-reset/confirm", m{
+			"token":    token,
+			"password": fakePassword(),
+		})
+		resp.AssertStatusCode(t, 400)
+	})
+	t.Run("old password", func(t *testing.T) {
+		resp := (&API{}).Post(t, "/account/login", m{
+			"username": username,
+			"password": password,
+		})
+		resp.AssertStatusCode(t, 400)
+	})
+	t.Run("new password", func(t *testing.T) {
+		resp := (&API{}).Post(t, "/account/login", m{
+			"username": username,
+			"password": newPassword,
+		})
+		resp.AssertStatusCode(t, 200)
+	})
+}
+
+func TestCreateAccountEmail(t *testing.T) {
+	email := fake.UserName() + "@example.com"
+	t.Run("invalid", func(t *testing.T) {
+		resp := (&API{}).Post(t, "/account", m{
+			"username": fake.UserName(),
+			"password": fakePassword(),
+			"email":    "Someone <" + email + ">",
+		})
+		resp.AssertStatusCode(t, 400)
+	})
+	var first, second string
+	t.Run("create", func(t *testing.T) {
+		resp := (&API{}).Post(t, "/account", m{
+			"username": fake.UserName(),
+			"password": fakePassword(),
+			"email":    email,
+		})
+		resp.AssertStatusCode(t, 200)
+		first = waitForEmailToken(t, email, "Verify your")
+	})
+	t.Run("unverified isn't reserved", func(t *testing.T) {
+		resp := (&API{}).Post(t, "/account", m{
+			"username": fake.UserName(),
+			"password": fakePassword(),
+			"email":    email,
+		})
+		resp.AssertStatusCode(t, 200)
+		second = waitForEmailToken(t, email, "Verify your")
+		assert.True(t, second != first)
+	})
+	t.Run("verify", func(t *testing.T) {
+		(&API{}).Post(t, "/account/verify", m{"token": first}).AssertStatusCode(t, 200)
+	})
+	t.Run("taken", func(t *testing.T) {
+		resp := (&API{}).Post(t, "/account", m{
+			"username": fake.UserName(),
+			"password": fakePassword(),
+			"email":    email,
+		})
+		resp.AssertStatusCode(t, 409)
+		assert.Equal(t, resp.ErrorCode(t), "email_taken")
+	})
+	t.Run("verified by another account", func(t *testing.T) {
+		resp := (&API{}).Post(t, "/account/verify", m{"token": second})
+		resp.AssertStatusCode(t, 409)
+		assert.Equal(t, resp.ErrorCode(t), "email_taken")
+	})
+}
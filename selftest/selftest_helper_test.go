@@ -20,9 +20,12 @@ import (
 type m map[string]interface{}
 
 type API struct {
+	URL      string // defaults to the test server's URL
 	Username string
 	Password string
 	Token    string
+	Bearer   string
+	Header   http.Header
 }
 
 func (a *API) Delete(t *testing.T, path string, body interface{}) *TestResponse {
@@ -42,11 +45,9 @@ func (a *API) Put(t *testing.T, path string, body interface{}) *TestResponse {
 
 func (a *API) Get(t *testing.T, path string) *TestResponse {
 	t.Helper()
-	req, err := http.NewRequest(http.MethodGet, url+path, nil)
+	req, err := http.NewRequest(http.MethodGet, a.baseURL()+path, nil)
 	assert.Must(t, err)
-	if a.Token != "" {
-		req.Header.Set("x-todo-token", a.Token)
-	}
+	a.authorize(req)
 	resp, err := http.DefaultClient.Do(req)
 	assert.Must(t, err)
 	return &TestResponse{resp: resp}
@@ -59,16 +60,33 @@ func (a *API) do(t *testing.T, httpMethod string, path string, body interface{})
 		assert.Must(t, err)
 		reader = bytes.NewBuffer(json)
 	}
-	req, err := http.NewRequest(httpMethod, url+path, reader)
+	req, err := http.NewRequest(httpMethod, a.baseURL()+path, reader)
 	assert.Must(t, err)
-	if a.Token != "" {
-		req.Header.Set("x-todo-token", a.Token)
-	}
+	a.authorize(req)
 	resp, err := http.DefaultClient.Do(req)
 	assert.Must(t, err)
 	return &TestResponse{resp: resp}
 }
 
+func (a *API) baseURL() string {
+	if a.URL != "" {
+		return a.URL
+	}
+	return url
+}
+
+func (a *API) authorize(req *http.Request) {
+	for k, v := range a.Header {
+		req.Header[k] = v
+	}
+	if a.Token != "" {
+		req.Header.Set("x-todo-token", a.Token)
+	}
+	if a.Bearer != "" {
+		req.Header.Set("Authorization", "Bearer "+a.Bearer)
+	}
+}
+
 func fakePassword() string {
 	return fake.Password(8, 32, true, true, true)
 }
@@ -10,13 +10,21 @@ import (
 	"testing"
 	"time"
 
+	"github.com/deliveroo/assert-go"
 	"github.com/deliveroo/todo-api/cmd/todo-api/apicmd"
 	"github.com/deliveroo/todo-api/conf"
+	"github.com/deliveroo/todo-api/pkg/oidc/oidctest"
 	"github.com/deliveroo/todo-api/selftest/deps/postgres"
 	"github.com/deliveroo/todo-api/selftest/deps/redis"
+	"github.com/deliveroo/todo-api/service/mail/mailtest"
 )
 
-var url string
+var (
+	url        string
+	config     *conf.Config
+	mailServer *mailtest.Server
+	idp        *oidctest.Server
+)
 
 func TestMain(m *testing.M) {
 	if flag.Parse(); testing.Short() {
@@ -33,22 +41,52 @@ func TestMain(m *testing.M) {
 	must(postgres.Reset(), "error resetting database")
 	must(redis.Reset(), "error resettiig redis")
 
+	// Start a local SMTP server to receive emails.
+	var err error
+	mailServer, err = mailtest.NewServer()
+	must(err, "error starting smtp server")
+
+	// Start a local identity provider to log in with.
+	idp, err = oidctest.NewServer("todo-api-test", "todo-api-test-secret")
+	must(err, "error starting identity provider")
+
 	var addr string
 	addr, url = tempAddr()
 
-	cfg := &conf.Config{
-		Addr:                addr,
-		DatabaseConnTimeout: 5 * time.Second,
-		DatabaseMaxConn:     10,
-		DatabaseURL:         postgres.URL(),
-		Debug:               true,
-		MaxSessionDuration:  1 * time.Minute,
-		RedisURL:            redis.URL(),
-		SuppressLogging:     true,
+	config = &conf.Config{
+		Addr:                    addr,
+		ClientIPHeader:          "X-Client-IP",
+		DatabaseConnTimeout:     5 * time.Second,
+		DatabaseMaxConn:         10,
+		DatabaseURL:             postgres.URL(),
+		Debug:                   true,
+		EmailVerificationExpiry: 1 * time.Minute,
+		LoginAttemptWindow:      1 * time.Minute,
+		LoginLockout:            2 * time.Second,
+		LoginMaxAttempts:        3,
+		LoginMaxAttemptsIP:      20,
+		LoginMaxLockout:         10 * time.Second,
+		MailFrom:                "todo-api@example.com",
+		MailerURL:               mailServer.URL(),
+		MaxSessionDuration:      1 * time.Minute,
+		OAuthAccessTokenExpiry:  1 * time.Minute,
+		OAuthCodeExpiry:         1 * time.Minute,
+		OAuthRefreshTokenExpiry: 1 * time.Hour,
+		OIDCClientID:            idp.ClientID,
+		OIDCClientSecret:        idp.ClientSecret,
+		OIDCIssuer:              idp.Issuer,
+		OIDCRedirectURL:         url + "/auth/oidc/callback",
+		OIDCScopes:              []string{"openid", "email", "profile"},
+		OIDCSignup:              true,
+		PasswordResetExpiry:     1 * time.Minute,
+		RedisURL:                redis.URL(),
+		SuppressLogging:         true,
+		TOTPIssuer:              "todo-api-test",
+		WebhookAllowPrivate:     true,
 	}
 
 	// Configure and start API server.
-	api, err := apicmd.New(cfg)
+	api, err := apicmd.New(config)
 	must(err, "error calling apicmd.New")
 	go mustDo(api.Run, "error closing server")
 
@@ -62,10 +100,32 @@ func TestMain(m *testing.M) {
 	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
 	defer cancel()
 	must(api.Shutdown(ctx), "error shutting down api server")
+	must(mailServer.Close(), "error closing smtp server")
+	idp.Close()
 
 	os.Exit(result)
 }
 
+// startServer starts another API server, with the test config changed by fn.
+// It returns the server's URL and a function which shuts it down.
+func startServer(t *testing.T, fn func(*conf.Config)) (string, func()) {
+	t.Helper()
+	cfg := *config
+	addr, serverURL := tempAddr()
+	cfg.Addr = addr
+	fn(&cfg)
+	api, err := apicmd.New(&cfg)
+	assert.Must(t, err)
+	go api.Run()
+	stop := func() {
+		ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+		defer cancel()
+		assert.Must(t, api.Shutdown(ctx))
+	}
+	assert.Must(t, waitForURL(serverURL+"/ping"))
+	return serverURL, stop
+}
+
 // must calls log.Fatal if the error is non-nil.
 func must(err error, msg string) {
 	if err != nil {
@@ -0,0 +1,117 @@
+package selftest
+
+import (
+	"encoding/json"
+	"net/http"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/deliveroo/assert-go"
+	"github.com/deliveroo/todo-api/pkg/websocket/websockettest"
+)
+
+func TestSocket(t *testing.T) {
+	wsURL := "ws" + strings.TrimPrefix(url, "http") + "/ws"
+
+	t.Run("unauthenticated", func(t *testing.T) {
+		_, resp, err := websockettest.Dial(wsURL, nil)
+		assert.True(t, err != nil)
+		assert.Equal(t, resp.StatusCode, 401)
+	})
+
+	withAccount(t, func(api *API) {
+		conn, _, err := websockettest.Dial(wsURL, http.Header{"X-Todo-Token": {api.Token}})
+		assert.Must(t, err)
+		defer conn.Close()
+		c := &socketClient{conn: conn}
+
+		t.Run("subscribe", func(t *testing.T) {
+			result := c.call(t, m{"id": "1", "type": "subscribe", "collection": "tasks"})
+			assert.Equal(t, result["status"], float64(200))
+			result = c.call(t, m{"id": "2", "type": "subscribe", "collection": "lists/01890a5d-ac96-774b-bcce-b302099a8057"})
+			assert.Equal(t, result["status"], float64(404))
+			result = c.call(t, m{"id": "3", "type": "subscribe", "collection": "everything"})
+			assert.Equal(t, result["status"], float64(400))
+			// Lists are only identified by their public ids.
+			result = c.call(t, m{"id": "3a", "type": "subscribe", "collection": "lists/1"})
+			assert.Equal(t, result["status"], float64(400))
+		})
+		t.Run("task changes", func(t *testing.T) {
+			result := c.call(t, m{"id": "4", "type": "create_task", "body": m{"description": "over the socket"}})
+			assert.Equal(t, result["status"], float64(200))
+			task := result["body"].(map[string]interface{})
+			assert.Equal(t, task["description"], "over the socket")
+			event := c.event(t)
+			assert.Equal(t, event["collection"], "tasks")
+			assert.Equal(t, event["event"], "task.created")
+			assert.Equal(t, event["task"].(map[string]interface{})["id"], task["id"])
+
+			// Changes are validated like REST requests.
+			result = c.call(t, m{"id": "5", "type": "update_task", "task_id": task["id"], "body": m{"description": ""}})
+			assert.Equal(t, result["status"], float64(400))
+			result = c.call(t, m{"id": "6", "type": "delete_task", "task_id": "01890a5d-ac96-774b-bcce-b302099a8057"})
+			assert.Equal(t, result["status"], float64(404))
+			result = c.call(t, m{"id": "6a", "type": "delete_task", "task_id": "0"})
+			assert.Equal(t, result["status"], float64(400))
+
+			result = c.call(t, m{"id": "7", "type": "delete_task", "task_id": task["id"]})
+			assert.Equal(t, result["status"], float64(200))
+			assert.Equal(t, c.event(t)["event"], "task.deleted")
+		})
+		t.Run("unsubscribe", func(t *testing.T) {
+			result := c.call(t, m{"id": "8", "type": "unsubscribe", "collection": "tasks"})
+			assert.Equal(t, result["status"], float64(200))
+			api.Post(t, "/tasks", m{"description": "quietly"}).AssertStatusCode(t, 200)
+			result = c.call(t, m{"id": "9", "type": "ping"})
+			assert.Equal(t, result["status"], float64(400))
+			assert.Equal(t, len(c.events), 0)
+		})
+	})
+}
+
+// socketClient sends messages over a socket, setting aside the events
+// received while waiting for results.
+type socketClient struct {
+	conn   *websockettest.Conn
+	events []m
+}
+
+// call sends a message, and waits for its result.
+func (c *socketClient) call(t *testing.T, msg m) m {
+	t.Helper()
+	b, err := json.Marshal(msg)
+	assert.Must(t, err)
+	assert.Must(t, c.conn.WriteMessage(b))
+	for {
+		v := c.read(t)
+		if v["type"] == "event" {
+			c.events = append(c.events, v)
+			continue
+		}
+		assert.Equal(t, v["id"], msg["id"])
+		return v
+	}
+}
+
+// event returns the next event.
+func (c *socketClient) event(t *testing.T) m {
+	t.Helper()
+	if len(c.events) > 0 {
+		v := c.events[0]
+		c.events = c.events[1:]
+		return v
+	}
+	v := c.read(t)
+	assert.Equal(t, v["type"], "event")
+	return v
+}
+
+func (c *socketClient) read(t *testing.T) m {
+	t.Helper()
+	b, err := c.conn.ReadMessage(5 * time.Second)
+	assert.Must(t, err)
+	var v m
+	assert.Must(t, json.Unmarshal(b, &v))
+	return v
+}
@@ -0,0 +1,89 @@
+package selftest
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"testing"
+
+	"github.com/deliveroo/assert-go"
+	"github.com/deliveroo/todo-api/cmd/todo-api/accountscmd"
+)
+
+func TestSuspension(t *testing.T) {
+	withAdmin(t, func(admin *API) {
+		withAccount(t, func(user *API) {
+			userPath := fmt.Sprintf("/admin/accounts/%s", accountID(t, user))
+			resp := user.Post(t, "/account/tokens", m{
+				"name":   "script",
+				"scopes": []string{"tasks:read"},
+			})
+			resp.AssertStatusCode(t, 200)
+			script := &API{Bearer: resp.JSONPathString(t, "token")}
+
+			admin.Post(t, userPath+"/suspend", m{"reason": "spam"}).AssertStatusCode(t, 200)
+
+			t.Run("existing credentials rejected", func(t *testing.T) {
+				resp := script.Get(t, "/tasks")
+				resp.AssertStatusCode(t, 403)
+				assert.Equal(t, resp.ErrorCode(t), "account_suspended")
+			})
+			t.Run("login refused", func(t *testing.T) {
+				resp := user.Post(t, "/account/login", m{
+					"username": user.Username,
+					"password": user.Password,
+				})
+				resp.AssertStatusCode(t, 403)
+				assert.Equal(t, resp.ErrorCode(t), "account_suspended")
+			})
+			t.Run("wrong password doesn't reveal suspension", func(t *testing.T) {
+				resp := user.Post(t, "/account/login", m{
+					"username": user.Username,
+					"password": "wrong-" + user.Password,
+				})
+				resp.AssertStatusCode(t, 400)
+			})
+			t.Run("unsuspend", func(t *testing.T) {
+				admin.Post(t, userPath+"/unsuspend", nil).AssertStatusCode(t, 200)
+				script.Get(t, "/tasks").AssertStatusCode(t, 200)
+			})
+		})
+	})
+}
+
+func TestAccountsCommand(t *testing.T) {
+	withAccount(t, func(api *API) {
+		ctx := context.Background()
+		run := func(args ...string) error {
+			var out bytes.Buffer
+			return accountscmd.Run(ctx, config, args, &out)
+		}
+		login := func() *TestResponse {
+			return api.Post(t, "/account/login", m{
+				"username": api.Username,
+				"password": api.Password,
+			})
+		}
+
+		t.Run("usage", func(t *testing.T) {
+			assert.NotNil(t, run("suspend", api.Username))
+			assert.NotNil(t, run("suspend", "-reason", "spam", "no-such-user"))
+		})
+		t.Run("suspend", func(t *testing.T) {
+			assert.Must(t, run("suspend", "-reason", "spam", api.Username))
+			resp := api.Get(t, "/account")
+			resp.AssertStatusCode(t, 401)
+			resp = login()
+			resp.AssertStatusCode(t, 403)
+			assert.Equal(t, resp.ErrorCode(t), "account_suspended")
+		})
+		t.Run("unsuspend", func(t *testing.T) {
+			assert.Must(t, run("unsuspend", api.Username))
+			login().AssertStatusCode(t, 200)
+		})
+		t.Run("set role", func(t *testing.T) {
+			assert.NotNil(t, run("set-role", api.Username, "superuser"))
+			assert.Must(t, run("set-role", api.Username, "admin"))
+		})
+	})
+}
@@ -0,0 +1,116 @@
+package selftest
+
+import (
+	"testing"
+	"time"
+
+	"github.com/deliveroo/assert-go"
+)
+
+type syncResponse struct {
+	Token string `json:"token"`
+	Tasks []struct {
+		ID          string `json:"id"`
+		Description string `json:"description"`
+	} `json:"tasks"`
+	Deleted []string `json:"deleted"`
+	Results []struct {
+		Ref      string `json:"ref"`
+		Status   string `json:"status"`
+		Conflict *struct {
+			Fields     []string `json:"fields"`
+			Resolution string   `json:"resolution"`
+		} `json:"conflict"`
+		Error string `json:"error"`
+	} `json:"results"`
+}
+
+func TestSync(t *testing.T) {
+	withAccount(t, func(api *API) {
+		sync := func(t *testing.T, since string) syncResponse {
+			t.Helper()
+			resp := api.Get(t, "/sync?since="+since)
+			resp.AssertStatusCode(t, 200)
+			var v syncResponse
+			resp.BindBody(t, &v)
+			return v
+		}
+
+		var (
+			first  = api.Post(t, "/tasks", m{"description": "first"}).JSONPathString(t, "id")
+			second = api.Post(t, "/tasks", m{"description": "second"}).JSONPathString(t, "id")
+		)
+		full := sync(t, "")
+		assert.Equal(t, full.Token, "2")
+		assert.Equal(t, len(full.Tasks), 2)
+		assert.Equal(t, len(full.Deleted), 0)
+
+		t.Run("malformed token", func(t *testing.T) {
+			api.Get(t, "/sync?since=abc").AssertStatusCode(t, 400)
+			api.Get(t, "/sync?since=-1").AssertStatusCode(t, 400)
+			api.Get(t, "/sync?since=1000").AssertStatusCode(t, 400)
+		})
+		t.Run("delta", func(t *testing.T) {
+			api.Put(t, "/tasks/"+first, m{"description": "first, again"}).AssertStatusCode(t, 200)
+			api.Delete(t, "/tasks/"+second, nil).AssertStatusCode(t, 200)
+			delta := sync(t, full.Token)
+			assert.Equal(t, delta.Token, "4")
+			assert.Equal(t, len(delta.Tasks), 1)
+			assert.Equal(t, delta.Tasks[0].Description, "first, again")
+			assert.Equal(t, delta.Deleted, []string{second})
+			assert.Equal(t, len(sync(t, delta.Token).Tasks), 0)
+		})
+		t.Run("push", func(t *testing.T) {
+			token := sync(t, "").Token
+			api.Put(t, "/tasks/"+first, m{"description": "changed on the server"}).AssertStatusCode(t, 200)
+
+			api.Post(t, "/sync", m{"changes": []m{{"ref": "a", "op": "move"}}}).AssertStatusCode(t, 400)
+			api.Post(t, "/sync", m{"changes": []m{{"ref": "a", "op": "delete"}}}).AssertStatusCode(t, 400)
+			api.Post(t, "/sync", m{"changes": []m{{"ref": "a", "op": "delete", "id": "42", "modified": time.Now()}}}).AssertStatusCode(t, 400)
+
+			earlier := time.Now().Add(-time.Hour)
+			resp := api.Post(t, "/sync", m{
+				"since": token,
+				"changes": []m{
+					{"ref": "a", "op": "create", "description": "created offline"},
+					{"ref": "b", "op": "update", "id": first, "description": "changed offline", "modified": earlier},
+					{"ref": "c", "op": "update", "id": "01890a5d-ac96-774b-bcce-b302099a8057", "description": "missing", "modified": earlier},
+				},
+			})
+			resp.AssertStatusCode(t, 200)
+			var v syncResponse
+			resp.BindBody(t, &v)
+			assert.Equal(t, len(v.Results), 3)
+			assert.Equal(t, v.Results[0].Status, "applied")
+			// The server's change was made later, so it wins.
+			assert.Equal(t, v.Results[1].Status, "conflict")
+			assert.Equal(t, v.Results[1].Conflict.Fields, []string{"description"})
+			assert.Equal(t, v.Results[1].Conflict.Resolution, "server")
+			assert.Equal(t, v.Results[2].Status, "rejected")
+			assert.Equal(t, len(v.Tasks), 2)
+		})
+		t.Run("merge", func(t *testing.T) {
+			token := sync(t, "").Token
+			api.Put(t, "/tasks/"+first, m{"description": "merged", "completed": nil}).AssertStatusCode(t, 200)
+			resp := api.Post(t, "/sync", m{
+				"since":    token,
+				"strategy": "merge",
+				"changes": []m{{
+					"ref":         "a",
+					"op":          "update",
+					"id":          first,
+					"description": "changed on the server",
+					"completed":   time.Now(),
+					"base":        m{"description": "changed on the server"},
+				}},
+			})
+			resp.AssertStatusCode(t, 200)
+			var v syncResponse
+			resp.BindBody(t, &v)
+			assert.Equal(t, v.Results[0].Status, "applied")
+			task := api.Get(t, "/tasks/"+first)
+			task.JSONPathEqual(t, "description", "merged")
+			assert.True(t, task.JSONPath(t, "completed") != nil)
+		})
+	})
+}
@@ -2,6 +2,7 @@ package selftest
 
 import (
 	"fmt"
+	"strings"
 	"testing"
 	"time"
 
@@ -49,6 +50,15 @@ func TestCreateGetUpdateDeleteTask(t *testing.T) {
 			resp.AssertStatusCode(t, 200)
 			api.Get(t, "/tasks/"+fmt.Sprint(id)).AssertStatusCode(t, 404)
 		})
+		t.Run("malformed id", func(t *testing.T) {
+			// Tasks are only identified by their public ids.
+			for _, bad := range []string{"42", "not-an-id", strings.ToUpper(fmt.Sprint(id))} {
+				api.Get(t, "/tasks/"+bad).AssertStatusCode(t, 400)
+				api.Put(t, "/tasks/"+bad, m{"description": description}).AssertStatusCode(t, 400)
+				api.Delete(t, "/tasks/"+bad, nil).AssertStatusCode(t, 400)
+			}
+			api.Get(t, "/tasks?assignee=42").AssertStatusCode(t, 400)
+		})
 	})
 }
 
@@ -93,3 +103,64 @@ func TestGetAllTasks(t *testing.T) {
 		})
 	})
 }
+
+func TestTaskAssignment(t *testing.T) {
+	withAccount(t, func(owner *API) {
+		withAccount(t, func(assignee *API) {
+			withAccount(t, func(outsider *API) {
+				assigneeID := accountID(t, assignee)
+				resp := owner.Post(t, "/tasks", m{"description": "walk the dog", "assignee_id": assigneeID})
+				resp.AssertStatusCode(t, 200)
+				resp.JSONPathEqual(t, "created_by.username", owner.Username)
+				resp.JSONPathEqual(t, "assignee.username", assignee.Username)
+				taskPath := fmt.Sprintf("/tasks/%v", resp.JSONPath(t, "id"))
+
+				t.Run("assignee can see", func(t *testing.T) {
+					assignee.Get(t, taskPath).AssertStatusCode(t, 200)
+					resp := assignee.Get(t, "/tasks?assignee=me")
+					resp.AssertStatusCode(t, 200)
+					resp.JSONPathEqual(t, "[0].description", "walk the dog")
+					resp = owner.Get(t, "/tasks?assignee=me")
+					resp.AssertStatusCode(t, 200)
+					var tasks []m
+					resp.BindBody(t, &tasks)
+					assert.Equal(t, len(tasks), 0)
+					owner.Get(t, "/tasks?assignee=nobody").AssertStatusCode(t, 400)
+					outsider.Get(t, taskPath).AssertStatusCode(t, 404)
+				})
+				t.Run("assignee can complete", func(t *testing.T) {
+					when := time.Now().UTC().Format(time.RFC3339)
+					resp := assignee.Put(t, taskPath, m{"description": "walk the dog", "completed": when})
+					resp.AssertStatusCode(t, 200)
+					resp.JSONPathEqual(t, "completed", when)
+					resp = assignee.Put(t, taskPath, m{"description": "walk the cat"})
+					resp.AssertStatusCode(t, 403)
+					assert.Equal(t, resp.ErrorCode(t), "assignee_read_only")
+					assignee.Delete(t, taskPath, nil).AssertStatusCode(t, 403)
+					assignee.Put(t, taskPath+"/assignee", m{"assignee_id": nil}).AssertStatusCode(t, 403)
+				})
+				t.Run("reassign", func(t *testing.T) {
+					owner.Put(t, taskPath+"/assignee", m{"assignee_id": "42"}).AssertStatusCode(t, 400)
+					resp := owner.Put(t, taskPath+"/assignee", m{"assignee_id": accountID(t, outsider)})
+					resp.AssertStatusCode(t, 200)
+					resp.JSONPathEqual(t, "assignee.username", outsider.Username)
+					outsider.Get(t, taskPath).AssertStatusCode(t, 200)
+					assignee.Get(t, taskPath).AssertStatusCode(t, 404)
+				})
+				t.Run("unassign", func(t *testing.T) {
+					resp := owner.Put(t, taskPath+"/assignee", m{"assignee_id": nil})
+					resp.AssertStatusCode(t, 200)
+					resp.JSONPathEqual(t, "assignee", nil)
+					outsider.Get(t, taskPath).AssertStatusCode(t, 404)
+				})
+				t.Run("list members only", func(t *testing.T) {
+					resp := owner.Post(t, "/lists", m{"name": "chores"})
+					resp.AssertStatusCode(t, 200)
+					listID := resp.JSONPath(t, "id")
+					resp = owner.Post(t, "/tasks", m{"description": "hoover", "list_id": listID, "assignee_id": assigneeID})
+					resp.AssertStatusCode(t, 400)
+				})
+			})
+		})
+	})
+}
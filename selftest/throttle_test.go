@@ -0,0 +1,78 @@
+package selftest
+
+import (
+	"fmt"
+	"net/http"
+	"strconv"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/deliveroo/assert-go"
+)
+
+var lastClientIP uint32
+
+// clientIP returns an API client which appears to make requests from a unique
+// IP address, so that tests don't share login lockouts.
+func clientIP() *API {
+	n := atomic.AddUint32(&lastClientIP, 1)
+	ip := fmt.Sprintf("10.%d.%d.%d", n>>16&0xff, n>>8&0xff, n&0xff)
+	return &API{Header: http.Header{"X-Client-Ip": {ip}}}
+}
+
+func TestLoginLockout(t *testing.T) {
+	withAccount(t, func(api *API) {
+		var (
+			client = clientIP()
+			wrong  = m{"username": api.Username, "password": "wrong-" + api.Password}
+			right  = m{"username": api.Username, "password": api.Password}
+		)
+		t.Run("allows a few failures", func(t *testing.T) {
+			for i := 0; i < 3; i++ {
+				client.Post(t, "/account/login", wrong).AssertStatusCode(t, 400)
+			}
+		})
+		t.Run("locks out the username", func(t *testing.T) {
+			resp := client.Post(t, "/account/login", right)
+			resp.AssertStatusCode(t, 429)
+			assert.Equal(t, resp.ErrorCode(t), "too_many_attempts")
+			retry, err := strconv.Atoi(resp.resp.Header.Get("Retry-After"))
+			assert.Must(t, err)
+			assert.True(t, retry > 0)
+
+			other := clientIP()
+			other.Post(t, "/account/login", right).AssertStatusCode(t, 429)
+		})
+		t.Run("lockout expires", func(t *testing.T) {
+			time.Sleep(2 * time.Second)
+			client.Post(t, "/account/login", right).AssertStatusCode(t, 200)
+		})
+		t.Run("failed logins are recorded", func(t *testing.T) {
+			resp := api.Get(t, "/account/failed-logins")
+			resp.AssertStatusCode(t, 200)
+			var logins []struct {
+				IPAddress string `json:"ip_address"`
+			}
+			resp.BindBody(t, &logins)
+			assert.Equal(t, len(logins), 3)
+			assert.Equal(t, logins[0].IPAddress, client.Header.Get("X-Client-Ip"))
+		})
+	})
+}
+
+func TestLoginLockoutByIP(t *testing.T) {
+	client := clientIP()
+	for i := 0; i < 20; i++ {
+		resp := client.Post(t, "/account/login", m{
+			"username": fmt.Sprintf("no-such-user-%d-%d", i, time.Now().UnixNano()),
+			"password": fakePassword(),
+		})
+		resp.AssertStatusCode(t, 400)
+	}
+	resp := client.Post(t, "/account/login", m{
+		"username": "no-such-user",
+		"password": fakePassword(),
+	})
+	resp.AssertStatusCode(t, 429)
+}
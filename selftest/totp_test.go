@@ -0,0 +1,111 @@
+package selftest
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/deliveroo/assert-go"
+	"github.com/deliveroo/todo-api/pkg/totp"
+)
+
+func TestTOTP(t *testing.T) {
+	withAccount(t, func(api *API) {
+		var (
+			secret        string
+			recoveryCodes []interface{}
+			login         = m{"username": api.Username, "password": api.Password}
+		)
+		t.Run("enrol", func(t *testing.T) {
+			resp := api.Post(t, "/account/totp", nil)
+			resp.AssertStatusCode(t, 200)
+			secret = resp.JSONPathString(t, "secret")
+			uri := resp.JSONPathString(t, "uri")
+			assert.True(t, strings.HasPrefix(uri, "otpauth://totp/todo-api-test:"))
+			assert.Contains(t, uri, "secret="+secret)
+		})
+		t.Run("login before confirmation", func(t *testing.T) {
+			resp := (&API{}).Post(t, "/account/login", login)
+			resp.AssertStatusCode(t, 200)
+			assert.True(t, resp.JSONPathString(t, "token") != "")
+		})
+		t.Run("confirm with wrong code", func(t *testing.T) {
+			resp := api.Post(t, "/account/totp/confirm", m{"code": "000000"})
+			resp.AssertStatusCode(t, 400)
+		})
+		t.Run("confirm", func(t *testing.T) {
+			// The previous period's code is still valid, and leaves the
+			// current one unused.
+			resp := api.Post(t, "/account/totp/confirm", m{"code": totpCode(t, secret, -1)})
+			resp.AssertStatusCode(t, 200)
+			recoveryCodes = resp.JSONPath(t, "recovery_codes").([]interface{})
+			assert.Equal(t, len(recoveryCodes), 10)
+		})
+		t.Run("login with code", func(t *testing.T) {
+			resp := (&API{}).Post(t, "/account/login", login)
+			resp.AssertStatusCode(t, 200)
+			challenge := resp.JSONPathString(t, "challenge")
+			resp = (&API{}).Post(t, "/account/login/totp", m{
+				"challenge": challenge,
+				"code":      totpCode(t, secret, 0),
+			})
+			resp.AssertStatusCode(t, 200)
+			token := resp.JSONPathString(t, "token")
+			(&API{Token: token}).Get(t, "/account").AssertStatusCode(t, 200)
+		})
+		t.Run("code can't be replayed", func(t *testing.T) {
+			resp := (&API{}).Post(t, "/account/login", login)
+			resp.AssertStatusCode(t, 200)
+			resp = (&API{}).Post(t, "/account/login/totp", m{
+				"challenge": resp.JSONPathString(t, "challenge"),
+				"code":      totpCode(t, secret, 0),
+			})
+			resp.AssertStatusCode(t, 400)
+		})
+		t.Run("challenge is single use", func(t *testing.T) {
+			resp := (&API{}).Post(t, "/account/login", login)
+			resp.AssertStatusCode(t, 200)
+			challenge := resp.JSONPathString(t, "challenge")
+			resp = (&API{}).Post(t, "/account/login/totp", m{
+				"challenge": challenge,
+				"code":      "000000",
+			})
+			resp.AssertStatusCode(t, 400)
+			resp = (&API{}).Post(t, "/account/login/totp", m{
+				"challenge": challenge,
+				"code":      totpCode(t, secret, 1),
+			})
+			resp.AssertStatusCode(t, 400)
+		})
+		t.Run("login with recovery code", func(t *testing.T) {
+			code := recoveryCodes[0].(string)
+			for i, want := range []int{200, 400} {
+				resp := (&API{}).Post(t, "/account/login", login)
+				resp.AssertStatusCode(t, 200)
+				resp = (&API{}).Post(t, "/account/login/totp", m{
+					"challenge":     resp.JSONPathString(t, "challenge"),
+					"recovery_code": code,
+				})
+				resp.AssertStatusCode(t, want)
+				if i == 0 {
+					assert.True(t, resp.JSONPathString(t, "token") != "")
+				}
+			}
+		})
+		t.Run("disable", func(t *testing.T) {
+			api.Delete(t, "/account/totp", m{"password": "wrong"}).AssertStatusCode(t, 400)
+			api.Delete(t, "/account/totp", m{"password": api.Password}).AssertStatusCode(t, 200)
+			resp := (&API{}).Post(t, "/account/login", login)
+			resp.AssertStatusCode(t, 200)
+			assert.True(t, resp.JSONPathString(t, "token") != "")
+		})
+	})
+}
+
+// totpCode returns the code for secret the given number of periods from now.
+func totpCode(t *testing.T, secret string, periods int) string {
+	t.Helper()
+	code, err := totp.Code(secret, time.Now().Add(time.Duration(periods)*totp.Period))
+	assert.Must(t, err)
+	return code
+}
@@ -0,0 +1,85 @@
+package selftest
+
+import (
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/deliveroo/assert-go"
+	"github.com/deliveroo/todo-api/service/webhook"
+)
+
+func TestWebhooks(t *testing.T) {
+	withAccount(t, func(api *API) {
+		var (
+			received = make(chan *http.Request, 10)
+			bodies   = make(chan []byte, 10)
+			status   = int32(http.StatusOK)
+		)
+		endpoint := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+			body, _ := ioutil.ReadAll(req.Body)
+			received <- req
+			bodies <- body
+			w.WriteHeader(int(atomic.LoadInt32(&status)))
+		}))
+		defer endpoint.Close()
+
+		api.Post(t, "/webhooks", m{"url": endpoint.URL, "events": []string{"task.everything"}}).AssertStatusCode(t, 400)
+		api.Post(t, "/webhooks", m{"url": "http://example.com/hooks", "events": []string{"task.created"}}).AssertStatusCode(t, 400)
+
+		resp := api.Post(t, "/webhooks", m{"url": endpoint.URL, "events": []string{"task.created", "task.completed"}})
+		resp.AssertStatusCode(t, 200)
+		secret := resp.JSONPathString(t, "secret")
+		assert.True(t, secret != "")
+		webhookPath := fmt.Sprintf("/webhooks/%v", resp.JSONPath(t, "id"))
+
+		t.Run("malformed id", func(t *testing.T) {
+			api.Get(t, "/webhooks/1").AssertStatusCode(t, 400)
+		})
+		t.Run("secret is only shown once", func(t *testing.T) {
+			resp := api.Get(t, webhookPath)
+			resp.AssertStatusCode(t, 200)
+			assert.False(t, strings.Contains(resp.JSONBody(t), "secret"))
+		})
+		t.Run("test event", func(t *testing.T) {
+			resp := api.Post(t, webhookPath+"/test", nil)
+			resp.AssertStatusCode(t, 200)
+			resp.JSONPathEqual(t, "status", "delivered")
+			resp.JSONPathEqual(t, "event", "ping")
+
+			req, body := <-received, <-bodies
+			assert.Equal(t, req.Header.Get(webhook.EventHeader), "ping")
+			assert.Equal(t, req.Header.Get(webhook.DeliveryHeader), resp.JSONPathString(t, "id"))
+			assert.Must(t, webhook.Verify(secret, req.Header.Get(webhook.SignatureHeader), body, time.Minute))
+		})
+		t.Run("failed test event is retried", func(t *testing.T) {
+			atomic.StoreInt32(&status, http.StatusServiceUnavailable)
+			defer atomic.StoreInt32(&status, http.StatusOK)
+			resp := api.Post(t, webhookPath+"/test", nil)
+			resp.AssertStatusCode(t, 200)
+			resp.JSONPathEqual(t, "status", "pending")
+			resp.JSONPathEqual(t, "response_status", float64(503))
+			<-received
+			<-bodies
+		})
+		t.Run("task events are queued", func(t *testing.T) {
+			api.Post(t, "/tasks", m{"description": "ship it"}).AssertStatusCode(t, 200)
+			resp := api.Get(t, webhookPath+"/deliveries")
+			resp.AssertStatusCode(t, 200)
+			resp.JSONPathEqual(t, "[0].event", "task.created")
+			resp.JSONPathEqual(t, "[0].status", "pending")
+		})
+		t.Run("update and delete", func(t *testing.T) {
+			resp := api.Put(t, webhookPath, m{"url": endpoint.URL, "events": []string{"task.deleted"}})
+			resp.AssertStatusCode(t, 200)
+			resp.JSONPathEqual(t, "events", []interface{}{"task.deleted"})
+			api.Delete(t, webhookPath, nil).AssertStatusCode(t, 200)
+			api.Get(t, webhookPath).AssertStatusCode(t, 404)
+		})
+	})
+}
@@ -0,0 +1,295 @@
+// Package events streams changes to accounts' tasks to connected clients.
+// Events are published through Redis pub/sub, so that clients connected to
+// any server receive them, and kept in a bounded per-account log in Redis, so
+// that clients which reconnect can catch up on the events they missed.
+package events
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/gomodule/redigo/redis"
+	"go.uber.org/zap"
+)
+
+// Event is a change streamed to an account.
+type Event struct {
+	// ID increases with each event published to an account.
+	ID int64
+
+	// Type is the kind of change, e.g. domain.EventTaskCreated.
+	Type string
+
+	// Data is the JSON encoded event data.
+	Data []byte
+}
+
+// Service is the event stream service.
+type Service struct {
+	Redis *redis.Pool
+
+	// LogSize is how many of each account's most recent events are kept for
+	// clients to catch up on.
+	LogSize int
+
+	// LogTTL is how long an account's events are kept for once it stops
+	// receiving new ones.
+	LogTTL time.Duration
+
+	mu   sync.Mutex
+	subs map[int64]map[chan *Event]struct{}
+}
+
+const (
+	// channelPrefix prefixes the pub/sub channel of each account's events.
+	channelPrefix = "events:account:"
+
+	// pingPeriod is how often the subscription connection is pinged, which
+	// must be less than the pool's read timeout.
+	pingPeriod = 30 * time.Second
+
+	// subscriberBuffer is how many events may be waiting for a subscriber
+	// before it's considered too slow, and dropped.
+	subscriberBuffer = 64
+)
+
+func channel(accountID int64) string {
+	return channelPrefix + strconv.FormatInt(accountID, 10)
+}
+
+func logKey(accountID int64) string {
+	return "events:log:" + strconv.FormatInt(accountID, 10)
+}
+
+func seqKey(accountID int64) string {
+	return "events:seq:" + strconv.FormatInt(accountID, 10)
+}
+
+// publishScript numbers an event, adds it to the account's log, trims the log,
+// and publishes it, atomically so that events are logged and published in
+// order.
+var publishScript = redis.NewScript(2, `
+	local id = redis.call('INCR', KEYS[1])
+	local msg = id .. '\n' .. ARGV[1] .. '\n' .. ARGV[2]
+	redis.call('ZADD', KEYS[2], id, msg)
+	redis.call('ZREMRANGEBYRANK', KEYS[2], 0, -tonumber(ARGV[3]) - 1)
+	redis.call('EXPIRE', KEYS[2], ARGV[4])
+	redis.call('PUBLISH', ARGV[5], msg)
+	return id
+`)
+
+// Publish publishes an event to each of the accounts.
+func (s *Service) Publish(ctx context.Context, accountIDs []int64, typ string, data []byte) error {
+	conn, err := s.Redis.GetContext(ctx)
+	if err != nil {
+		return err
+	}
+	defer conn.Close()
+	ttl := int64(s.LogTTL / time.Second)
+	for _, id := range accountIDs {
+		if _, err := publishScript.Do(conn, seqKey(id), logKey(id), typ, data, s.LogSize, ttl, channel(id)); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Latest returns the id of the latest event published to an account, or zero
+// if there haven't been any.
+func (s *Service) Latest(ctx context.Context, accountID int64) (int64, error) {
+	conn, err := s.Redis.GetContext(ctx)
+	if err != nil {
+		return 0, err
+	}
+	defer conn.Close()
+	id, err := redis.Int64(conn.Do("GET", seqKey(accountID)))
+	if err == redis.ErrNil {
+		return 0, nil
+	}
+	return id, err
+}
+
+// Since returns the logged events published to an account after lastID, and
+// the id of the latest event published to it. If the first event returned
+// doesn't follow lastID, and lastID isn't the latest, events were missed.
+func (s *Service) Since(ctx context.Context, accountID, lastID int64) ([]*Event, int64, error) {
+	conn, err := s.Redis.GetContext(ctx)
+	if err != nil {
+		return nil, 0, err
+	}
+	defer conn.Close()
+	if err := conn.Send("MULTI"); err != nil {
+		return nil, 0, err
+	}
+	if err := conn.Send("GET", seqKey(accountID)); err != nil {
+		return nil, 0, err
+	}
+	if err := conn.Send("ZRANGEBYSCORE", logKey(accountID), "("+strconv.FormatInt(lastID, 10), "+inf"); err != nil {
+		return nil, 0, err
+	}
+	replies, err := redis.Values(conn.Do("EXEC"))
+	if err != nil {
+		return nil, 0, err
+	}
+	latest, err := redis.Int64(replies[0], nil)
+	if err != nil && err != redis.ErrNil {
+		return nil, 0, err
+	}
+	msgs, err := redis.ByteSlices(replies[1], nil)
+	if err != nil {
+		return nil, 0, err
+	}
+	result := make([]*Event, 0, len(msgs))
+	for _, msg := range msgs {
+		e, err := parseEvent(msg)
+		if err != nil {
+			return nil, 0, err
+		}
+		result = append(result, e)
+	}
+	return result, latest, nil
+}
+
+// Subscribe returns a channel of the events published to an account, and a
+// function which unsubscribes. The channel is closed if the subscriber falls
+// too far behind, or the connection to Redis is lost, after which it should
+// catch up with Since.
+func (s *Service) Subscribe(accountID int64) (<-chan *Event, func()) {
+	ch := make(chan *Event, subscriberBuffer)
+	s.mu.Lock()
+	if s.subs == nil {
+		s.subs = make(map[int64]map[chan *Event]struct{})
+	}
+	if s.subs[accountID] == nil {
+		s.subs[accountID] = make(map[chan *Event]struct{})
+	}
+	s.subs[accountID][ch] = struct{}{}
+	s.mu.Unlock()
+	return ch, func() {
+		s.mu.Lock()
+		defer s.mu.Unlock()
+		s.drop(accountID, ch)
+	}
+}
+
+// drop removes a subscriber and closes its channel. s.mu must be held.
+func (s *Service) drop(accountID int64, ch chan *Event) {
+	if _, ok := s.subs[accountID][ch]; !ok {
+		return
+	}
+	delete(s.subs[accountID], ch)
+	if len(s.subs[accountID]) == 0 {
+		delete(s.subs, accountID)
+	}
+	close(ch)
+}
+
+// Run receives events from Redis and passes them to subscribers until ctx is
+// cancelled, reconnecting if the connection is lost. Subscribers are dropped
+// whenever it disconnects, since they may have missed events.
+func (s *Service) Run(ctx context.Context) error {
+	for {
+		err := s.receive(ctx)
+		s.dropAll()
+		if ctx.Err() != nil {
+			return nil
+		}
+		zap.L().Error("events.Run", zap.Error(err))
+		select {
+		case <-time.After(time.Second):
+		case <-ctx.Done():
+			return nil
+		}
+	}
+}
+
+func (s *Service) receive(ctx context.Context) error {
+	conn, err := s.Redis.Dial()
+	if err != nil {
+		return err
+	}
+	psc := redis.PubSubConn{Conn: conn}
+	defer psc.Close()
+	if err := psc.PSubscribe(channelPrefix + "*"); err != nil {
+		return err
+	}
+	done := make(chan struct{})
+	defer close(done)
+	go func() {
+		ticker := time.NewTicker(pingPeriod)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-ticker.C:
+				if err := psc.Ping(""); err != nil {
+					return
+				}
+			case <-ctx.Done():
+				_ = psc.Close() // unblocks Receive
+				return
+			case <-done:
+				return
+			}
+		}
+	}()
+	for {
+		switch v := psc.Receive().(type) {
+		case redis.Message:
+			s.dispatch(v.Channel, v.Data)
+		case error:
+			return v
+		}
+	}
+}
+
+// dispatch passes a message received from Redis to the account's
+// subscribers, dropping any which are too far behind.
+func (s *Service) dispatch(ch string, msg []byte) {
+	accountID, err := strconv.ParseInt(strings.TrimPrefix(ch, channelPrefix), 10, 64)
+	if err != nil {
+		return
+	}
+	e, err := parseEvent(msg)
+	if err != nil {
+		zap.L().Error("events.dispatch", zap.Error(err))
+		return
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for sub := range s.subs[accountID] {
+		select {
+		case sub <- e:
+		default:
+			s.drop(accountID, sub)
+		}
+	}
+}
+
+func (s *Service) dropAll() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for accountID, subs := range s.subs {
+		for sub := range subs {
+			s.drop(accountID, sub)
+		}
+	}
+}
+
+// parseEvent parses an event as logged and published by publishScript: its
+// id, type and data separated by newlines.
+func parseEvent(msg []byte) (*Event, error) {
+	parts := strings.SplitN(string(msg), "\n", 3)
+	if len(parts) != 3 {
+		return nil, errors.New("malformed event")
+	}
+	id, err := strconv.ParseInt(parts[0], 10, 64)
+	if err != nil {
+		return nil, fmt.Errorf("malformed event id: %w", err)
+	}
+	return &Event{ID: id, Type: parts[1], Data: []byte(parts[2])}, nil
+}
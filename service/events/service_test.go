@@ -0,0 +1,113 @@
+package events_test
+
+import (
+	"context"
+	"flag"
+	"log"
+	"os"
+	"testing"
+	"time"
+
+	"github.com/deliveroo/assert-go"
+	"github.com/deliveroo/todo-api/selftest/deps/redis"
+	"github.com/deliveroo/todo-api/service/events"
+)
+
+func TestMain(m *testing.M) {
+	if flag.Parse(); testing.Short() {
+		return // skip in short mode
+	}
+
+	// Connect to Redis.
+	must(redis.Connect(), "could not connect to redis")
+
+	// Run tests.
+	result := m.Run()
+
+	// Reset the database.
+	must(redis.Reset(), "error resetting redis")
+
+	os.Exit(result)
+}
+
+func TestLog(t *testing.T) {
+	var (
+		ctx       = context.Background()
+		s         = &events.Service{Redis: redis.Pool(), LogSize: 3, LogTTL: time.Minute}
+		accountID = time.Now().UnixNano()
+	)
+	latest, err := s.Latest(ctx, accountID)
+	assert.Must(t, err)
+	assert.Equal(t, latest, int64(0))
+
+	for i := 0; i < 5; i++ {
+		assert.Must(t, s.Publish(ctx, []int64{accountID}, "task.created", []byte(`{}`)))
+	}
+	latest, err = s.Latest(ctx, accountID)
+	assert.Must(t, err)
+	assert.Equal(t, latest, int64(5))
+
+	got, latest, err := s.Since(ctx, accountID, 3)
+	assert.Must(t, err)
+	assert.Equal(t, latest, int64(5))
+	assert.Equal(t, len(got), 2)
+	assert.Equal(t, got[0].ID, int64(4))
+	assert.Equal(t, got[0].Type, "task.created")
+	assert.Equal(t, string(got[0].Data), `{}`)
+
+	// Only the last three events are kept.
+	got, _, err = s.Since(ctx, accountID, 0)
+	assert.Must(t, err)
+	assert.Equal(t, len(got), 3)
+	assert.Equal(t, got[0].ID, int64(3))
+}
+
+func TestSubscribe(t *testing.T) {
+	var (
+		ctx, cancel = context.WithCancel(context.Background())
+		s           = &events.Service{Redis: redis.Pool(), LogSize: 10, LogTTL: time.Minute}
+		accountID   = time.Now().UnixNano()
+		stopped     = make(chan struct{})
+	)
+	go func() {
+		if err := s.Run(ctx); err != nil {
+			t.Error(err)
+		}
+		close(stopped)
+	}()
+
+	ch, unsubscribe := s.Subscribe(accountID)
+	defer unsubscribe()
+	other, unsubscribeOther := s.Subscribe(accountID + 1)
+	defer unsubscribeOther()
+
+	// The subscription to Redis may not be ready yet, so keep publishing until
+	// an event arrives.
+	var got *events.Event
+	for got == nil {
+		assert.Must(t, s.Publish(ctx, []int64{accountID}, "task.updated", []byte(`{"id":1}`)))
+		select {
+		case got = <-ch:
+		case <-time.After(100 * time.Millisecond):
+		}
+	}
+	assert.Equal(t, got.Type, "task.updated")
+	select {
+	case e := <-other:
+		t.Fatalf("unexpected event %+v", e)
+	default:
+	}
+
+	// Subscribers are dropped when the service stops.
+	cancel()
+	<-stopped
+	for range ch {
+	}
+}
+
+// must calls log.Fatal if the error is non-nil.
+func must(err error, msg string) {
+	if err != nil {
+		log.Fatalln(msg + ": " + err.Error())
+	}
+}
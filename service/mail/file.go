@@ -0,0 +1,49 @@
+package mail
+
+import (
+	"context"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"time"
+
+	"go.uber.org/zap"
+)
+
+// FileMailer writes each email to a .eml file in a directory, which is useful
+// for development.
+type FileMailer struct {
+	Dir  string // directory to write emails to
+	From string // sender address
+}
+
+// Send implements the Mailer interface.
+func (m *FileMailer) Send(ctx context.Context, msg *Message) error {
+	now := time.Now()
+	data, err := msg.encode(m.From, now)
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(m.Dir, 0700); err != nil {
+		return err
+	}
+	name := filepath.Join(m.Dir, now.UTC().Format("20060102T150405")+"-"+randomID()[:8]+".eml")
+	return ioutil.WriteFile(name, data, 0600)
+}
+
+// LogMailer writes each email to the log, which is useful for development. It
+// must not be used in production, since emails may contain secrets.
+type LogMailer struct {
+	From string // sender address
+}
+
+// Send implements the Mailer interface.
+func (m *LogMailer) Send(ctx context.Context, msg *Message) error {
+	zap.L().Info("mail.Send",
+		zap.String("from", m.From),
+		zap.String("to", msg.To),
+		zap.String("subject", msg.Subject),
+		zap.String("body", msg.Body),
+	)
+	return nil
+}
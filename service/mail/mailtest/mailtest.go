@@ -0,0 +1,204 @@
+// Package mailtest provides a local SMTP server which stands in for a real mail
+// server in tests.
+package mailtest
+
+import (
+	"bufio"
+	"context"
+	"io/ioutil"
+	"mime"
+	"mime/quotedprintable"
+	"net"
+	"net/mail"
+	"net/textproto"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Message is an email received by the server.
+type Message struct {
+	From    string
+	To      []string
+	Subject string
+	Body    string
+}
+
+// Server is an SMTP server which accepts every email sent to it, and keeps it
+// in memory.
+type Server struct {
+	listener net.Listener
+
+	mu       sync.Mutex
+	messages []*Message
+	received chan struct{}
+}
+
+// NewServer starts a server listening on a local port.
+func NewServer() (*Server, error) {
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		return nil, err
+	}
+	s := &Server{
+		listener: l,
+		received: make(chan struct{}),
+	}
+	go s.serve()
+	return s, nil
+}
+
+// Addr returns the host:port the server is listening on.
+func (s *Server) Addr() string {
+	return s.listener.Addr().String()
+}
+
+// URL returns a mailer URL for the server.
+func (s *Server) URL() string {
+	return "smtp://" + s.Addr()
+}
+
+// Close stops the server.
+func (s *Server) Close() error {
+	return s.listener.Close()
+}
+
+// Messages returns every email received so far.
+func (s *Server) Messages() []*Message {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return append([]*Message(nil), s.messages...)
+}
+
+// WaitFor waits for an email to be received for a recipient, and returns the
+// most recent one.
+func (s *Server) WaitFor(ctx context.Context, to string) (*Message, error) {
+	for {
+		s.mu.Lock()
+		received := s.received
+		for i := len(s.messages) - 1; i >= 0; i-- {
+			for _, rcpt := range s.messages[i].To {
+				if strings.EqualFold(rcpt, to) {
+					s.mu.Unlock()
+					return s.messages[i], nil
+				}
+			}
+		}
+		s.mu.Unlock()
+		select {
+		case <-received:
+		case <-ctx.Done():
+			return nil, ctx.Err()
+		}
+	}
+}
+
+func (s *Server) serve() {
+	for {
+		conn, err := s.listener.Accept()
+		if err != nil {
+			return
+		}
+		go s.handle(conn)
+	}
+}
+
+func (s *Server) deliver(m *Message) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.messages = append(s.messages, m)
+	close(s.received)
+	s.received = make(chan struct{})
+}
+
+// handle implements enough of RFC 5321 for net/smtp.
+func (s *Server) handle(conn net.Conn) {
+	defer conn.Close()
+	conn.SetDeadline(time.Now().Add(time.Minute))
+	tp := textproto.NewConn(conn)
+	tp.PrintfLine("220 mailtest ESMTP")
+	var m *Message
+	for {
+		line, err := tp.ReadLine()
+		if err != nil {
+			return
+		}
+		verb, arg := line, ""
+		if i := strings.IndexByte(line, ' '); i >= 0 {
+			verb, arg = line[:i], line[i+1:]
+		}
+		switch strings.ToUpper(verb) {
+		case "EHLO", "HELO":
+			tp.PrintfLine("250 mailtest")
+		case "MAIL":
+			m = &Message{From: address(arg)}
+			tp.PrintfLine("250 OK")
+		case "RCPT":
+			if m == nil {
+				tp.PrintfLine("503 MAIL first")
+				continue
+			}
+			m.To = append(m.To, address(arg))
+			tp.PrintfLine("250 OK")
+		case "DATA":
+			if m == nil || len(m.To) == 0 {
+				tp.PrintfLine("503 RCPT first")
+				continue
+			}
+			tp.PrintfLine("354 go ahead")
+			data, err := ioutil.ReadAll(tp.DotReader())
+			if err != nil {
+				return
+			}
+			if err := parse(m, string(data)); err != nil {
+				tp.PrintfLine("554 %s", err)
+				continue
+			}
+			s.deliver(m)
+			m = nil
+			tp.PrintfLine("250 OK")
+		case "RSET":
+			m = nil
+			tp.PrintfLine("250 OK")
+		case "NOOP":
+			tp.PrintfLine("250 OK")
+		case "QUIT":
+			tp.PrintfLine("221 bye")
+			return
+		default:
+			tp.PrintfLine("502 not implemented")
+		}
+	}
+}
+
+// address extracts the address from a MAIL FROM:<...> or RCPT TO:<...> argument.
+func address(arg string) string {
+	if i := strings.IndexByte(arg, '<'); i >= 0 {
+		arg = arg[i+1:]
+		if j := strings.IndexByte(arg, '>'); j >= 0 {
+			arg = arg[:j]
+		}
+	}
+	return arg
+}
+
+func parse(m *Message, data string) error {
+	msg, err := mail.ReadMessage(bufio.NewReader(strings.NewReader(data)))
+	if err != nil {
+		return err
+	}
+	var dec mime.WordDecoder
+	if m.Subject, err = dec.DecodeHeader(msg.Header.Get("Subject")); err != nil {
+		return err
+	}
+	body := msg.Body
+	if strings.EqualFold(msg.Header.Get("Content-Transfer-Encoding"), "quoted-printable") {
+		body = quotedprintable.NewReader(body)
+	}
+	b, err := ioutil.ReadAll(body)
+	if err != nil {
+		return err
+	}
+	m.Body = string(b)
+	return nil
+}
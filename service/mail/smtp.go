@@ -0,0 +1,97 @@
+package mail
+
+import (
+	"context"
+	"crypto/tls"
+	"net"
+	"net/smtp"
+	"net/url"
+	"time"
+)
+
+// SMTPMailer sends email through an SMTP server.
+type SMTPMailer struct {
+	Addr     string // host:port of the SMTP server
+	From     string // sender address
+	Username string // optional username for PLAIN authentication
+	Password string // optional password for PLAIN authentication
+	TLS      bool   // connect over TLS rather than using STARTTLS
+}
+
+func newSMTPMailer(u *url.URL, from string) *SMTPMailer {
+	m := &SMTPMailer{
+		Addr: u.Host,
+		From: from,
+		TLS:  u.Scheme == "smtps",
+	}
+	if u.Port() == "" {
+		if m.TLS {
+			m.Addr = net.JoinHostPort(u.Hostname(), "465")
+		} else {
+			m.Addr = net.JoinHostPort(u.Hostname(), "25")
+		}
+	}
+	if u.User != nil {
+		m.Username = u.User.Username()
+		m.Password, _ = u.User.Password()
+	}
+	return m
+}
+
+// Send implements the Mailer interface.
+func (m *SMTPMailer) Send(ctx context.Context, msg *Message) error {
+	data, err := msg.encode(m.From, time.Now())
+	if err != nil {
+		return err
+	}
+	host, _, err := net.SplitHostPort(m.Addr)
+	if err != nil {
+		return err
+	}
+	var d net.Dialer
+	conn, err := d.DialContext(ctx, "tcp", m.Addr)
+	if err != nil {
+		return err
+	}
+	if deadline, ok := ctx.Deadline(); ok {
+		conn.SetDeadline(deadline)
+	}
+	if m.TLS {
+		conn = tls.Client(conn, &tls.Config{ServerName: host})
+	}
+	c, err := smtp.NewClient(conn, host)
+	if err != nil {
+		conn.Close()
+		return err
+	}
+	defer c.Close()
+	if !m.TLS {
+		if ok, _ := c.Extension("STARTTLS"); ok {
+			if err := c.StartTLS(&tls.Config{ServerName: host}); err != nil {
+				return err
+			}
+		}
+	}
+	if m.Username != "" {
+		if err := c.Auth(smtp.PlainAuth("", m.Username, m.Password, host)); err != nil {
+			return err
+		}
+	}
+	if err := c.Mail(m.From); err != nil {
+		return err
+	}
+	if err := c.Rcpt(msg.To); err != nil {
+		return err
+	}
+	w, err := c.Data()
+	if err != nil {
+		return err
+	}
+	if _, err := w.Write(data); err != nil {
+		return err
+	}
+	if err := w.Close(); err != nil {
+		return err
+	}
+	return c.Quit()
+}
@@ -0,0 +1,75 @@
+// Package notify tells accounts about things other accounts did which affect
+// them, such as assigning them a task.
+package notify
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/deliveroo/todo-api/domain"
+	"github.com/deliveroo/todo-api/service/mail"
+	"go.uber.org/zap"
+)
+
+// TaskAssignment describes a task being assigned to an account.
+type TaskAssignment struct {
+	Task       *domain.Task
+	Assignee   *domain.Account
+	AssignedBy *domain.Account
+}
+
+// Notifier is a hook which is told about events accounts may want to hear
+// about. An error from a notifier doesn't undo the event.
+type Notifier interface {
+	TaskAssigned(ctx context.Context, a *TaskAssignment) error
+}
+
+// Notifiers passes each event to every notifier in turn, and returns the first
+// error.
+type Notifiers []Notifier
+
+// TaskAssigned implements the Notifier interface.
+func (nn Notifiers) TaskAssigned(ctx context.Context, a *TaskAssignment) error {
+	var first error
+	for _, n := range nn {
+		if err := n.TaskAssigned(ctx, a); err != nil && first == nil {
+			first = err
+		}
+	}
+	return first
+}
+
+// LogNotifier writes each event to the log.
+type LogNotifier struct{}
+
+// TaskAssigned implements the Notifier interface.
+func (LogNotifier) TaskAssigned(ctx context.Context, a *TaskAssignment) error {
+	zap.L().Info("notify.TaskAssigned",
+		zap.Int64("task_id", a.Task.ID),
+		zap.Int64("assignee_id", a.Assignee.ID),
+		zap.Int64("assigned_by", a.AssignedBy.ID),
+	)
+	return nil
+}
+
+// MailNotifier emails accounts which have a verified email address.
+type MailNotifier struct {
+	Mailer mail.Mailer
+}
+
+// TaskAssigned implements the Notifier interface.
+func (n *MailNotifier) TaskAssigned(ctx context.Context, a *TaskAssignment) error {
+	if !a.Assignee.Verified() {
+		return nil
+	}
+	return n.Mailer.Send(ctx, &mail.Message{
+		To:      a.Assignee.Email,
+		Subject: "You've been assigned a task",
+		Body: fmt.Sprintf(`Hi %s,
+
+%s assigned you a task on todo-api:
+
+%s
+`, a.Assignee.Username, a.AssignedBy.Username, a.Task.Description),
+	})
+}
@@ -0,0 +1,69 @@
+package notify_test
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/deliveroo/assert-go"
+	"github.com/deliveroo/todo-api/domain"
+	"github.com/deliveroo/todo-api/service/mail"
+	"github.com/deliveroo/todo-api/service/notify"
+)
+
+func TestNotifiers(t *testing.T) {
+	var (
+		ctx    = context.Background()
+		failed = errors.New("failed")
+		calls  []string
+	)
+	nn := notify.Notifiers{
+		notifierFunc(func() error { calls = append(calls, "a"); return failed }),
+		notifierFunc(func() error { calls = append(calls, "b"); return errors.New("later") }),
+	}
+	err := nn.TaskAssigned(ctx, &notify.TaskAssignment{})
+	assert.Equal(t, err, failed)
+	assert.Equal(t, calls, []string{"a", "b"})
+}
+
+func TestMailNotifier(t *testing.T) {
+	var (
+		ctx    = context.Background()
+		now    = time.Now()
+		mailer = &recordingMailer{}
+		n      = &notify.MailNotifier{Mailer: mailer}
+		by     = &domain.Account{Username: "alice"}
+		task   = &domain.Task{Description: "buy milk"}
+	)
+	assert.Must(t, n.TaskAssigned(ctx, &notify.TaskAssignment{
+		Task:       task,
+		Assignee:   &domain.Account{Username: "bob", Email: "bob@example.com"},
+		AssignedBy: by,
+	}))
+	assert.Equal(t, len(mailer.sent), 0)
+
+	assert.Must(t, n.TaskAssigned(ctx, &notify.TaskAssignment{
+		Task:       task,
+		Assignee:   &domain.Account{Username: "bob", Email: "bob@example.com", VerifiedAt: &now},
+		AssignedBy: by,
+	}))
+	assert.Equal(t, len(mailer.sent), 1)
+	assert.Equal(t, mailer.sent[0].To, "bob@example.com")
+	assert.Equal(t, mailer.sent[0].Body, "Hi bob,\n\nalice assigned you a task on todo-api:\n\nbuy milk\n")
+}
+
+type notifierFunc func() error
+
+func (f notifierFunc) TaskAssigned(ctx context.Context, a *notify.TaskAssignment) error {
+	return f()
+}
+
+type recordingMailer struct {
+	sent []*mail.Message
+}
+
+func (m *recordingMailer) Send(ctx context.Context, msg *mail.Message) error {
+	m.sent = append(m.sent, msg)
+	return nil
+}
@@ -0,0 +1,158 @@
+// Package outbox publishes the events written to the outbox table, in the same
+// transactions as the changes to tasks and accounts they describe, to sinks
+// such as a log, a Redis stream or an HTTP endpoint. Events are published at
+// least once: they're retried until every sink accepts them, so sinks may
+// receive an event more than once, and should ignore repeats by its dedup key.
+// Events are published roughly in the order they were written, but an event
+// which is retried may be published after later ones.
+package outbox
+
+import (
+	"context"
+	"encoding/json"
+	"time"
+
+	"github.com/deliveroo/todo-api/domain"
+	"github.com/deliveroo/todo-api/repo"
+	"go.uber.org/zap"
+)
+
+// Sink receives the events published from the outbox.
+type Sink interface {
+	// Publish publishes an event, returning an error if it should be
+	// retried.
+	Publish(ctx context.Context, e *domain.OutboxEvent) error
+}
+
+// Message is an event as it's published to sinks which encode it as JSON.
+type Message struct {
+	DedupKey    string          `json:"dedup_key"`
+	Event       string          `json:"event"`
+	Aggregate   string          `json:"aggregate"`
+	AggregateID string          `json:"aggregate_id"`
+	Created     time.Time       `json:"created"`
+	Payload     json.RawMessage `json:"payload"`
+}
+
+// NewMessage returns the message an event is published as.
+func NewMessage(e *domain.OutboxEvent) Message {
+	return Message{
+		DedupKey:    e.DedupKey(),
+		Event:       e.Event,
+		Aggregate:   e.Aggregate,
+		AggregateID: e.AggregatePublicID,
+		Created:     e.Created,
+		Payload:     e.Payload,
+	}
+}
+
+// Service is the outbox dispatcher.
+type Service struct {
+	Repo  *repo.Client
+	Sinks []Sink
+
+	// Backoff is the delay before an event which failed to publish is
+	// retried. It doubles with each further attempt, up to MaxBackoff.
+	Backoff    time.Duration
+	MaxBackoff time.Duration
+
+	// PollInterval is how often Run checks for events to publish.
+	PollInterval time.Duration
+
+	// Retention is how long published events are kept for, or zero to keep
+	// them forever.
+	Retention time.Duration
+
+	// Timeout limits how long publishing an event can take.
+	Timeout time.Duration
+}
+
+// batchSize limits how many events a dispatcher claims at once.
+const batchSize = 20
+
+// Run publishes events until ctx is cancelled.
+func (s *Service) Run(ctx context.Context) error {
+	ticker := time.NewTicker(s.PollInterval)
+	defer ticker.Stop()
+	for {
+		for {
+			n, err := s.DispatchDue(ctx)
+			if err != nil && ctx.Err() == nil {
+				zap.L().Error("outbox.Run", zap.Error(err))
+			}
+			if n < batchSize {
+				break // caught up
+			}
+		}
+		if s.Retention > 0 {
+			if _, err := s.Repo.DeletePublishedOutboxEvents(ctx, time.Now().UTC().Add(-s.Retention)); err != nil && ctx.Err() == nil {
+				zap.L().Error("outbox.Run", zap.Error(err))
+			}
+		}
+		select {
+		case <-ticker.C:
+		case <-ctx.Done():
+			return nil
+		}
+	}
+}
+
+// DispatchDue publishes a batch of the events which are due, and returns how
+// many were attempted.
+func (s *Service) DispatchDue(ctx context.Context) (int, error) {
+	// Claim events for longer than publishing them can take, so they aren't
+	// published twice at once.
+	events, err := s.Repo.ClaimOutboxEvents(ctx, batchSize, batchSize*s.Timeout+time.Minute)
+	if err != nil {
+		return 0, err
+	}
+	for _, e := range events {
+		if _, err := s.Dispatch(ctx, e); err != nil {
+			return 0, err
+		}
+	}
+	return len(events), nil
+}
+
+// Dispatch publishes an event to every sink, and records the outcome. If any
+// sink fails, the event is retried later, and published again to every sink.
+func (s *Service) Dispatch(ctx context.Context, e *domain.OutboxEvent) (*domain.OutboxEvent, error) {
+	err := s.publish(ctx, e)
+	now := time.Now().UTC()
+	if err != nil {
+		e.Attempts++
+		e.NextAttempt = now.Add(s.RetryDelay(e.Attempts))
+		e.LastError = err.Error()
+	} else {
+		e.Published = &now
+		e.LastError = ""
+	}
+	return s.Repo.UpdateOutboxEvent(ctx, e)
+}
+
+func (s *Service) publish(ctx context.Context, e *domain.OutboxEvent) error {
+	if s.Timeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, s.Timeout)
+		defer cancel()
+	}
+	for _, sink := range s.Sinks {
+		if err := sink.Publish(ctx, e); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// RetryDelay returns how long to wait before retrying an event which has
+// failed to publish the given number of times.
+func (s *Service) RetryDelay(attempts int) time.Duration {
+	d := s.Backoff
+	for i := 1; i < attempts && d < s.MaxBackoff; i++ {
+		d *= 2
+	}
+	if s.MaxBackoff > 0 && d > s.MaxBackoff {
+		d = s.MaxBackoff
+	}
+	return d
+}
@@ -0,0 +1,47 @@
+package outbox_test
+
+import (
+	"context"
+	"encoding/json"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/deliveroo/assert-go"
+	"github.com/deliveroo/todo-api/domain"
+	"github.com/deliveroo/todo-api/service/outbox"
+)
+
+func TestRetryDelay(t *testing.T) {
+	s := &outbox.Service{Backoff: time.Second, MaxBackoff: 10 * time.Second}
+	assert.Equal(t, s.RetryDelay(1), time.Second)
+	assert.Equal(t, s.RetryDelay(3), 4*time.Second)
+	assert.Equal(t, s.RetryDelay(50), 10*time.Second)
+}
+
+func TestHTTPSink(t *testing.T) {
+	var (
+		ctx    = context.Background()
+		e      = &domain.OutboxEvent{ID: 7, Aggregate: domain.AggregateTask, AggregateID: 3, AggregatePublicID: "task-public-id", Event: domain.EventTaskCreated, Payload: []byte(`{"id":"task-public-id"}`)}
+		status = http.StatusAccepted
+	)
+	srv := httptest.NewServer(http.HandlerFunc(func(rw http.ResponseWriter, req *http.Request) {
+		assert.Equal(t, req.Header.Get(outbox.EventHeader), domain.EventTaskCreated)
+		assert.Equal(t, req.Header.Get(outbox.IdempotencyKeyHeader), "outbox:7")
+		body, _ := ioutil.ReadAll(req.Body)
+		var m outbox.Message
+		assert.Must(t, json.Unmarshal(body, &m))
+		assert.Equal(t, m.DedupKey, "outbox:7")
+		assert.Equal(t, m.AggregateID, "task-public-id")
+		assert.Equal(t, string(m.Payload), `{"id":"task-public-id"}`)
+		rw.WriteHeader(status)
+	}))
+	defer srv.Close()
+	s := &outbox.HTTPSink{URL: srv.URL}
+
+	assert.Must(t, s.Publish(ctx, e))
+	status = http.StatusServiceUnavailable
+	assert.NotNil(t, s.Publish(ctx, e))
+}
@@ -0,0 +1,139 @@
+package outbox
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"time"
+
+	"github.com/deliveroo/todo-api/domain"
+	"github.com/gomodule/redigo/redis"
+	"go.uber.org/zap"
+)
+
+// Headers sent with each event posted by HTTPSink.
+const (
+	EventHeader          = "X-Todo-Event"
+	IdempotencyKeyHeader = "Idempotency-Key"
+)
+
+// LogSink logs events.
+type LogSink struct{}
+
+// Publish implements the Sink interface.
+func (LogSink) Publish(ctx context.Context, e *domain.OutboxEvent) error {
+	zap.L().Info("outbox.LogSink",
+		zap.String("dedup_key", e.DedupKey()),
+		zap.String("event", e.Event),
+		zap.String("aggregate", e.Aggregate),
+		zap.String("aggregate_id", e.AggregatePublicID),
+		zap.ByteString("payload", e.Payload),
+	)
+	return nil
+}
+
+// RedisSink adds events to a Redis stream, which requires Redis 5 or later.
+// Each entry's fields are those of Message. Events are added once, unless
+// they're published again after DedupTTL.
+type RedisSink struct {
+	Redis  *redis.Pool
+	Stream string
+
+	// MaxLen approximately limits the length of the stream, or is zero for
+	// no limit.
+	MaxLen int64
+
+	// DedupTTL is how long the dedup keys of added events are remembered.
+	DedupTTL time.Duration
+}
+
+// xaddScript adds an event to a stream unless its dedup key is remembered,
+// then remembers it. The key is only remembered once the event is added, so an
+// event which fails to be added is added when it's retried.
+var xaddScript = redis.NewScript(2, `
+	if redis.call('EXISTS', KEYS[2]) == 1 then
+		return 0
+	end
+	local args = {'XADD', KEYS[1]}
+	if tonumber(ARGV[1]) > 0 then
+		table.insert(args, 'MAXLEN')
+		table.insert(args, '~')
+		table.insert(args, ARGV[1])
+	end
+	table.insert(args, '*')
+	for i = 3, #ARGV do
+		table.insert(args, ARGV[i])
+	end
+	redis.call(unpack(args))
+	redis.call('SET', KEYS[2], '1', 'EX', ARGV[2])
+	return 1
+`)
+
+// Publish implements the Sink interface.
+func (s *RedisSink) Publish(ctx context.Context, e *domain.OutboxEvent) error {
+	conn, err := s.Redis.GetContext(ctx)
+	if err != nil {
+		return fmt.Errorf("redis: %w", err)
+	}
+	defer conn.Close()
+	m := NewMessage(e)
+	ttl := int64(s.DedupTTL / time.Second)
+	if ttl < 1 {
+		ttl = 1
+	}
+	if _, err := xaddScript.Do(conn,
+		s.Stream, s.Stream+":dedup:"+m.DedupKey, s.MaxLen, ttl,
+		"dedup_key", m.DedupKey,
+		"event", m.Event,
+		"aggregate", m.Aggregate,
+		"aggregate_id", m.AggregateID,
+		"created", m.Created.Format(time.RFC3339Nano),
+		"payload", []byte(m.Payload),
+	); err != nil {
+		return fmt.Errorf("redis: %w", err)
+	}
+	return nil
+}
+
+// HTTPSink posts events to an HTTP endpoint as Messages, with the dedup key in
+// the Idempotency-Key header. Any response status other than 2xx is an error.
+type HTTPSink struct {
+	URL        string
+	HTTPClient *http.Client
+}
+
+// Publish implements the Sink interface.
+func (s *HTTPSink) Publish(ctx context.Context, e *domain.OutboxEvent) error {
+	m := NewMessage(e)
+	body, err := json.Marshal(m)
+	if err != nil {
+		return err
+	}
+	req, err := http.NewRequest(http.MethodPost, s.URL, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	req = req.WithContext(ctx)
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("User-Agent", "todo-api-outbox")
+	req.Header.Set(EventHeader, m.Event)
+	req.Header.Set(IdempotencyKeyHeader, m.DedupKey)
+	client := s.HTTPClient
+	if client == nil {
+		client = http.DefaultClient
+	}
+	resp, err := client.Do(req)
+	if err != nil {
+		return fmt.Errorf("http: %w", err)
+	}
+	defer resp.Body.Close()
+	_, _ = io.Copy(ioutil.Discard, io.LimitReader(resp.Body, 64<<10))
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		return fmt.Errorf("http: unexpected response status %d", resp.StatusCode)
+	}
+	return nil
+}
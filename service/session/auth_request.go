@@ -0,0 +1,79 @@
+package session
+
+import (
+	"context"
+	"encoding/json"
+	"time"
+
+	"github.com/gomodule/redigo/redis"
+)
+
+// AuthRequestDuration is how long a login with an external identity provider
+// can be completed for.
+const AuthRequestDuration = 10 * time.Minute
+
+// AuthRequest is a login with an external identity provider which is waiting
+// for the provider to redirect back.
+type AuthRequest struct {
+	Nonce    string `json:"nonce"`    // binds the ID token to this request
+	Verifier string `json:"verifier"` // PKCE code verifier
+}
+
+// NewAuthRequest stores an auth request, and returns the state parameter
+// which identifies it when the identity provider redirects back.
+func (s *Service) NewAuthRequest(ctx context.Context, r *AuthRequest) (string, error) {
+	state := newSessionID()
+	data, err := json.Marshal(r)
+	if err != nil {
+		return "", err
+	}
+	conn, err := s.Redis.GetContext(ctx)
+	if err != nil {
+		return "", err
+	}
+	defer conn.Close()
+	ms := AuthRequestDuration.Milliseconds()
+	if _, err := conn.Do("SET", authRequestKey(state), data, "PX", ms); err != nil {
+		return "", err
+	}
+	return state, nil
+}
+
+// ConsumeAuthRequest returns the auth request identified by state, if it
+// exists and hasn't expired. An auth request can only be consumed once.
+func (s *Service) ConsumeAuthRequest(ctx context.Context, state string) (*AuthRequest, error) {
+	if len(state) < 32 {
+		return nil, errInvalidToken
+	}
+	conn, err := s.Redis.GetContext(ctx)
+	if err != nil {
+		return nil, err
+	}
+	defer conn.Close()
+	if err := conn.Send("MULTI"); err != nil {
+		return nil, err
+	}
+	if err := conn.Send("GET", authRequestKey(state)); err != nil {
+		return nil, err
+	}
+	if err := conn.Send("DEL", authRequestKey(state)); err != nil {
+		return nil, err
+	}
+	replies, err := redis.Values(conn.Do("EXEC"))
+	if err != nil {
+		return nil, err
+	}
+	data, err := redis.Bytes(replies[0], nil)
+	if err != nil {
+		return nil, errInvalidToken
+	}
+	var r AuthRequest
+	if err := json.Unmarshal(data, &r); err != nil {
+		return nil, err
+	}
+	return &r, nil
+}
+
+func authRequestKey(state string) string {
+	return "auth-request:" + state
+}
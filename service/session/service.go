@@ -6,32 +6,55 @@ import (
 	"crypto/rand"
 	"encoding/base64"
 	"encoding/json"
-	"errors"
+	"fmt"
 	"time"
 
 	"github.com/gomodule/redigo/redis"
 )
 
 // Service is the session service, which manages sessions in Redis.
+//
+// When Keys is set, sessions are instead encoded in stateless tokens signed
+// with those keys, and Redis only holds a denylist of revoked tokens.
 type Service struct {
 	Redis              *redis.Pool
 	MaxSessionDuration time.Duration
+	Keys               *KeySet
 }
 
+// ChallengeDuration is how long a login challenge can be completed for.
+const ChallengeDuration = 5 * time.Minute
+
 // Session stores session data.
 type Session struct {
 	AccountID int64
 }
 
+// storedSession is a session as stored in Redis.
+type storedSession struct {
+	Session
+
+	// Generation is the account's session generation when the session was
+	// created. Sessions from earlier generations have been revoked.
+	Generation int64
+}
+
 // New creates and persists a new session.
 func (s *Service) New(ctx context.Context, sess *Session) (string, error) {
+	if s.Keys != nil {
+		return s.newSigned(ctx, sess)
+	}
 	key := newSessionID()
 	conn, err := s.Redis.GetContext(ctx)
 	if err != nil {
 		return "", err
 	}
 	defer conn.Close()
-	b, err := json.Marshal(sess)
+	gen, err := generation(conn, sess.AccountID)
+	if err != nil {
+		return "", err
+	}
+	b, err := json.Marshal(storedSession{Session: *sess, Generation: gen})
 	if err != nil {
 		return "", err
 	}
@@ -45,7 +68,10 @@ func (s *Service) New(ctx context.Context, sess *Session) (string, error) {
 // Get fetches an existing session by token, if it exists or hasn't expired.
 func (s *Service) Get(ctx context.Context, token string) (*Session, error) {
 	if len(token) < 32 {
-		return nil, errors.New("invalid token")
+		return nil, errInvalidToken
+	}
+	if s.Keys != nil {
+		return s.getSigned(ctx, token)
 	}
 	conn, err := s.Redis.GetContext(ctx)
 	if err != nil {
@@ -57,11 +83,175 @@ func (s *Service) Get(ctx context.Context, token string) (*Session, error) {
 		return nil, err
 	}
 	v, _ := redis.String(reply, nil)
-	var sess Session
-	if err := json.Unmarshal([]byte(v), &sess); err != nil {
+	var stored storedSession
+	if err := json.Unmarshal([]byte(v), &stored); err != nil {
+		return nil, err
+	}
+	gen, err := generation(conn, stored.AccountID)
+	if err != nil {
+		return nil, err
+	}
+	if stored.Generation < gen {
+		return nil, errInvalidToken
+	}
+	return &stored.Session, nil
+}
+
+// Revoke ends the session identified by token. Revoking an unknown or expired
+// session is not an error.
+func (s *Service) Revoke(ctx context.Context, token string) error {
+	if s.Keys != nil {
+		return s.revokeSigned(ctx, token)
+	}
+	conn, err := s.Redis.GetContext(ctx)
+	if err != nil {
+		return err
+	}
+	defer conn.Close()
+	_, err = conn.Do("DEL", token)
+	return err
+}
+
+// RevokeAll ends every existing session for an account. Sessions created
+// afterwards are unaffected.
+func (s *Service) RevokeAll(ctx context.Context, accountID int64) error {
+	conn, err := s.Redis.GetContext(ctx)
+	if err != nil {
+		return err
+	}
+	defer conn.Close()
+	_, err = conn.Do("INCR", generationKey(accountID))
+	return err
+}
+
+// generation returns the account's current session generation, which is
+// incremented each time all of its sessions are revoked.
+func generation(conn redis.Conn, accountID int64) (int64, error) {
+	gen, err := redis.Int64(conn.Do("GET", generationKey(accountID)))
+	if err == redis.ErrNil {
+		return 0, nil
+	}
+	return gen, err
+}
+
+// NewChallenge creates a short-lived login challenge for an account that must
+// complete a second authentication step before it is given a session.
+func (s *Service) NewChallenge(ctx context.Context, accountID int64) (string, error) {
+	token := newSessionID()
+	conn, err := s.Redis.GetContext(ctx)
+	if err != nil {
+		return "", err
+	}
+	defer conn.Close()
+	ms := ChallengeDuration.Milliseconds()
+	if _, err := conn.Do("SET", challengeKey(token), accountID, "PX", ms); err != nil {
+		return "", err
+	}
+	return token, nil
+}
+
+// ConsumeChallenge returns the account id of a login challenge, if it exists
+// or hasn't expired. A challenge can only be consumed once, whether or not the
+// second authentication step then succeeds.
+func (s *Service) ConsumeChallenge(ctx context.Context, token string) (int64, error) {
+	if len(token) < 32 {
+		return 0, errInvalidToken
+	}
+	conn, err := s.Redis.GetContext(ctx)
+	if err != nil {
+		return 0, err
+	}
+	defer conn.Close()
+	if err := conn.Send("MULTI"); err != nil {
+		return 0, err
+	}
+	if err := conn.Send("GET", challengeKey(token)); err != nil {
+		return 0, err
+	}
+	if err := conn.Send("DEL", challengeKey(token)); err != nil {
+		return 0, err
+	}
+	replies, err := redis.Values(conn.Do("EXEC"))
+	if err != nil {
+		return 0, err
+	}
+	accountID, err := redis.Int64(replies[0], nil)
+	if err != nil {
+		return 0, errInvalidToken
+	}
+	return accountID, nil
+}
+
+func (s *Service) newSigned(ctx context.Context, sess *Session) (string, error) {
+	conn, err := s.Redis.GetContext(ctx)
+	if err != nil {
+		return "", err
+	}
+	defer conn.Close()
+	gen, err := generation(conn, sess.AccountID)
+	if err != nil {
+		return "", err
+	}
+	now := time.Now()
+	return s.Keys.sign(&tokenClaims{
+		Subject:    sess.AccountID,
+		ID:         base64.RawURLEncoding.EncodeToString(randomBytes(16)),
+		IssuedAt:   now.Unix(),
+		ExpiresAt:  now.Add(s.MaxSessionDuration).Unix(),
+		Generation: gen,
+	})
+}
+
+func (s *Service) getSigned(ctx context.Context, token string) (*Session, error) {
+	c, err := s.Keys.verify(token, time.Now())
+	if err != nil {
+		return nil, err
+	}
+	conn, err := s.Redis.GetContext(ctx)
+	if err != nil {
+		return nil, err
+	}
+	defer conn.Close()
+	replies, err := redis.Values(conn.Do("MGET", denylistKey(c.ID), generationKey(c.Subject)))
+	if err != nil {
 		return nil, err
 	}
-	return &sess, nil
+	if replies[0] != nil {
+		return nil, errInvalidToken // revoked
+	}
+	gen, _ := redis.Int64(replies[1], nil)
+	if c.Generation < gen {
+		return nil, errInvalidToken // revoked by RevokeAll
+	}
+	return &Session{AccountID: c.Subject}, nil
+}
+
+func (s *Service) revokeSigned(ctx context.Context, token string) error {
+	c, err := s.Keys.verify(token, time.Now())
+	if err != nil {
+		return nil // already unusable
+	}
+	conn, err := s.Redis.GetContext(ctx)
+	if err != nil {
+		return err
+	}
+	defer conn.Close()
+	// The denylist entry only needs to outlive the token itself.
+	ms := time.Until(time.Unix(c.ExpiresAt, 0)).Milliseconds() + 1000
+	_, err = conn.Do("SET", denylistKey(c.ID), "1", "PX", ms)
+	return err
+}
+
+func challengeKey(token string) string {
+	return "login-challenge:" + token
+}
+
+func generationKey(accountID int64) string {
+	return fmt.Sprintf("session-generation:%d", accountID)
+}
+
+func denylistKey(id string) string {
+	return "session-denylist:" + id
 }
 
 func newSessionID() string {
@@ -2,9 +2,12 @@ package session_test
 
 import (
 	"context"
+	"crypto/rand"
+	"encoding/base64"
 	"flag"
 	"log"
 	"os"
+	"strings"
 	"testing"
 	"time"
 
@@ -59,6 +62,186 @@ func TestSessionPersistence(t *testing.T) {
 	})
 }
 
+func TestSessionRevoke(t *testing.T) {
+	var (
+		ctx = context.Background()
+		s   = &session.Service{
+			Redis:              redis.Pool(),
+			MaxSessionDuration: time.Minute,
+		}
+	)
+	token, err := s.New(ctx, &session.Session{AccountID: 1})
+	assert.Must(t, err)
+	assert.Must(t, s.Revoke(ctx, token))
+	got, err := s.Get(ctx, token)
+	assert.NotNil(t, err)
+	assert.Nil(t, got)
+}
+
+func TestSessionRevokeAll(t *testing.T) {
+	var (
+		ctx       = context.Background()
+		accountID = time.Now().UnixNano()
+		redisMode = &session.Service{
+			Redis:              redis.Pool(),
+			MaxSessionDuration: time.Minute,
+		}
+		signedMode = &session.Service{
+			Redis:              redis.Pool(),
+			MaxSessionDuration: time.Minute,
+			Keys:               mustParseKeySet(t, "k1:"+newSecret()),
+		}
+	)
+	for name, s := range map[string]*session.Service{"redis": redisMode, "signed": signedMode} {
+		t.Run(name, func(t *testing.T) {
+			before, err := s.New(ctx, &session.Session{AccountID: accountID})
+			assert.Must(t, err)
+			other, err := s.New(ctx, &session.Session{AccountID: accountID + 1})
+			assert.Must(t, err)
+
+			assert.Must(t, s.RevokeAll(ctx, accountID))
+			after, err := s.New(ctx, &session.Session{AccountID: accountID})
+			assert.Must(t, err)
+
+			_, err = s.Get(ctx, before)
+			assert.NotNil(t, err)
+			_, err = s.Get(ctx, other)
+			assert.Must(t, err)
+			_, err = s.Get(ctx, after)
+			assert.Must(t, err)
+		})
+	}
+}
+
+func TestChallenge(t *testing.T) {
+	var (
+		ctx = context.Background()
+		s   = &session.Service{
+			Redis:              redis.Pool(),
+			MaxSessionDuration: time.Minute,
+		}
+		accountID = time.Now().Unix()
+	)
+	token, err := s.NewChallenge(ctx, accountID)
+	assert.Must(t, err)
+	got, err := s.ConsumeChallenge(ctx, token)
+	assert.Must(t, err)
+	assert.Equal(t, got, accountID)
+	_, err = s.ConsumeChallenge(ctx, token)
+	assert.NotNil(t, err)
+}
+
+func TestAuthRequest(t *testing.T) {
+	var (
+		ctx = context.Background()
+		s   = &session.Service{
+			Redis:              redis.Pool(),
+			MaxSessionDuration: time.Minute,
+		}
+		want = &session.AuthRequest{Nonce: "nonce", Verifier: "verifier"}
+	)
+	state, err := s.NewAuthRequest(ctx, want)
+	assert.Must(t, err)
+	got, err := s.ConsumeAuthRequest(ctx, state)
+	assert.Must(t, err)
+	assert.Equal(t, got, want)
+	_, err = s.ConsumeAuthRequest(ctx, state)
+	assert.NotNil(t, err)
+}
+
+func TestSignedSessionPersistence(t *testing.T) {
+	var (
+		ctx     = context.Background()
+		current = "k1:" + newSecret()
+		s       = &session.Service{
+			Redis:              redis.Pool(),
+			MaxSessionDuration: 2 * time.Second,
+			Keys:               mustParseKeySet(t, current),
+		}
+		sess  = &session.Session{AccountID: time.Now().Unix()}
+		token string
+	)
+	t.Run("new", func(t *testing.T) {
+		var err error
+		token, err = s.New(ctx, sess)
+		assert.Must(t, err)
+		assert.True(t, token != "")
+	})
+	t.Run("get", func(t *testing.T) {
+		got, err := s.Get(ctx, token)
+		assert.Must(t, err)
+		assert.Equal(t, sess, got)
+	})
+	t.Run("tampered", func(t *testing.T) {
+		// The replacement must differ from the signature's last characters.
+		suffix := "AA"
+		if strings.HasSuffix(token, suffix) {
+			suffix = "BB"
+		}
+		got, err := s.Get(ctx, token[:len(token)-2]+suffix)
+		assert.NotNil(t, err)
+		assert.Nil(t, got)
+	})
+	t.Run("rotated", func(t *testing.T) {
+		rotated := *s
+		rotated.Keys = mustParseKeySet(t, "k2:"+newSecret(), current)
+		got, err := rotated.Get(ctx, token)
+		assert.Must(t, err)
+		assert.Equal(t, sess, got)
+
+		retired := *s
+		retired.Keys = mustParseKeySet(t, "k2:"+newSecret())
+		got, err = retired.Get(ctx, token)
+		assert.NotNil(t, err)
+		assert.Nil(t, got)
+	})
+	t.Run("revoke", func(t *testing.T) {
+		assert.Must(t, s.Revoke(ctx, token))
+		got, err := s.Get(ctx, token)
+		assert.NotNil(t, err)
+		assert.Nil(t, got)
+	})
+	t.Run("expires", func(t *testing.T) {
+		token, err := s.New(ctx, sess)
+		assert.Must(t, err)
+		time.Sleep(2100 * time.Millisecond)
+		got, err := s.Get(ctx, token)
+		assert.NotNil(t, err)
+		assert.Nil(t, got)
+	})
+}
+
+func TestParseKeySet(t *testing.T) {
+	short := base64.StdEncoding.EncodeToString([]byte("short"))
+	for _, specs := range [][]string{
+		nil,
+		{"k1"},
+		{":" + newSecret()},
+		{"k1:not-base64!"},
+		{"k1:" + short},
+		{"k1:" + newSecret(), "k1:" + newSecret()},
+	} {
+		_, err := session.ParseKeySet(specs)
+		assert.NotNil(t, err)
+	}
+}
+
+func mustParseKeySet(t *testing.T, specs ...string) *session.KeySet {
+	t.Helper()
+	keys, err := session.ParseKeySet(specs)
+	assert.Must(t, err)
+	return keys
+}
+
+// newSecret returns a random base64 encoded signing secret.
+func newSecret() string {
+	b := make([]byte, 32)
+	if _, err := rand.Read(b); err != nil {
+		panic(err)
+	}
+	return base64.StdEncoding.EncodeToString(b)
+}
+
 // must calls log.Fatal if the error is non-nil.
 func must(err error, msg string) {
 	if err != nil {
@@ -0,0 +1,147 @@
+package session
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+)
+
+var errInvalidToken = errors.New("invalid token")
+
+// KeySet holds the keys used to sign and verify stateless session tokens. The
+// first key signs new tokens and every key verifies them, so keys are rotated
+// by prepending a new key and removing the old one once its tokens expire.
+type KeySet struct {
+	keys []signingKey
+}
+
+type signingKey struct {
+	id     string
+	secret []byte
+}
+
+// ParseKeySet parses signing keys in the form "<key id>:<base64 secret>".
+func ParseKeySet(specs []string) (*KeySet, error) {
+	var ks KeySet
+	seen := make(map[string]bool)
+	for _, spec := range specs {
+		parts := strings.SplitN(spec, ":", 2)
+		if len(parts) != 2 || parts[0] == "" {
+			return nil, errors.New("signing key must be in the form <key id>:<base64 secret>")
+		}
+		id := parts[0]
+		if seen[id] {
+			return nil, fmt.Errorf("duplicate signing key id %q", id)
+		}
+		secret, err := base64.StdEncoding.DecodeString(parts[1])
+		if err != nil {
+			return nil, fmt.Errorf("signing key %q: %w", id, err)
+		}
+		if len(secret) < 32 {
+			return nil, fmt.Errorf("signing key %q must be at least 32 bytes", id)
+		}
+		seen[id] = true
+		ks.keys = append(ks.keys, signingKey{id: id, secret: secret})
+	}
+	if len(ks.keys) == 0 {
+		return nil, errors.New("at least one signing key is required")
+	}
+	return &ks, nil
+}
+
+func (ks *KeySet) lookup(id string) (signingKey, bool) {
+	for _, k := range ks.keys {
+		if k.id == id {
+			return k, true
+		}
+	}
+	return signingKey{}, false
+}
+
+// tokenHeader is the JOSE header of a session token.
+type tokenHeader struct {
+	Algorithm string `json:"alg"`
+	Type      string `json:"typ"`
+	KeyID     string `json:"kid"`
+}
+
+// tokenClaims are the JWT claims of a session token.
+type tokenClaims struct {
+	Subject    int64  `json:"sub,string"`
+	ID         string `json:"jti"`
+	IssuedAt   int64  `json:"iat"`
+	ExpiresAt  int64  `json:"exp"`
+	Generation int64  `json:"gen,omitempty"`
+}
+
+// sign encodes the claims as a JWT signed with HS256 using the current key.
+func (ks *KeySet) sign(c *tokenClaims) (string, error) {
+	k := ks.keys[0]
+	header, err := json.Marshal(tokenHeader{Algorithm: "HS256", Type: "JWT", KeyID: k.id})
+	if err != nil {
+		return "", err
+	}
+	payload, err := json.Marshal(c)
+	if err != nil {
+		return "", err
+	}
+	signed := encodeSegment(header) + "." + encodeSegment(payload)
+	return signed + "." + encodeSegment(mac(k.secret, signed)), nil
+}
+
+// verify checks the token signature and expiry and returns its claims.
+func (ks *KeySet) verify(token string, now time.Time) (*tokenClaims, error) {
+	parts := strings.Split(token, ".")
+	if len(parts) != 3 {
+		return nil, errInvalidToken
+	}
+	var header tokenHeader
+	if err := decodeSegment(parts[0], &header); err != nil {
+		return nil, errInvalidToken
+	}
+	if header.Algorithm != "HS256" {
+		return nil, errInvalidToken
+	}
+	k, ok := ks.lookup(header.KeyID)
+	if !ok {
+		return nil, errInvalidToken
+	}
+	sig, err := base64.RawURLEncoding.DecodeString(parts[2])
+	if err != nil {
+		return nil, errInvalidToken
+	}
+	if !hmac.Equal(sig, mac(k.secret, parts[0]+"."+parts[1])) {
+		return nil, errInvalidToken
+	}
+	var c tokenClaims
+	if err := decodeSegment(parts[1], &c); err != nil {
+		return nil, errInvalidToken
+	}
+	if c.ID == "" || now.Unix() >= c.ExpiresAt {
+		return nil, errInvalidToken
+	}
+	return &c, nil
+}
+
+func mac(secret []byte, s string) []byte {
+	h := hmac.New(sha256.New, secret)
+	h.Write([]byte(s))
+	return h.Sum(nil)
+}
+
+func encodeSegment(b []byte) string {
+	return base64.RawURLEncoding.EncodeToString(b)
+}
+
+func decodeSegment(s string, v interface{}) error {
+	b, err := base64.RawURLEncoding.DecodeString(s)
+	if err != nil {
+		return err
+	}
+	return json.Unmarshal(b, v)
+}
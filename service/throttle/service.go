@@ -0,0 +1,200 @@
+// Package throttle counts failed attempts at an action (e.g. logging in) and
+// locks out callers that fail too often. Counters are kept in Redis so that they
+// are shared between servers, with an in-memory fallback for when Redis is
+// unavailable.
+package throttle
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"github.com/gomodule/redigo/redis"
+	"go.uber.org/zap"
+)
+
+// Policy configures when and for how long a key is locked out.
+type Policy struct {
+	// MaxAttempts is the number of failures allowed before a key is locked
+	// out. Zero disables lockouts.
+	MaxAttempts int
+
+	// Lockout is how long a key is locked out for once it reaches
+	// MaxAttempts. It doubles with each further failure, up to MaxLockout.
+	Lockout    time.Duration
+	MaxLockout time.Duration
+
+	// Window is how long failures are remembered for.
+	Window time.Duration
+}
+
+// lockout returns the lockout after the given number of failures.
+func (p Policy) lockout(failures int) time.Duration {
+	if p.MaxAttempts <= 0 || failures < p.MaxAttempts {
+		return 0
+	}
+	d := p.Lockout
+	for i := p.MaxAttempts; i < failures && d < p.MaxLockout; i++ {
+		d *= 2
+	}
+	if p.MaxLockout > 0 && d > p.MaxLockout {
+		d = p.MaxLockout
+	}
+	return d
+}
+
+// Service is the throttle service.
+type Service struct {
+	Redis *redis.Pool
+
+	mu     sync.Mutex
+	memory map[string]*entry
+}
+
+type entry struct {
+	failures int
+	until    time.Time // locked out until
+	expires  time.Time
+}
+
+// Check returns how much longer key is locked out for, or zero if it isn't.
+func (s *Service) Check(ctx context.Context, key string) (time.Duration, error) {
+	if s.Redis != nil {
+		d, err := s.checkRedis(ctx, key)
+		if err == nil {
+			return d, nil
+		}
+		zap.L().Warn("throttle: redis unavailable, using memory", zap.Error(err))
+	}
+	return s.checkMemory(key, time.Now()), nil
+}
+
+// Fail records a failed attempt for key, and returns how long key is now
+// locked out for, or zero if it isn't.
+func (s *Service) Fail(ctx context.Context, key string, p Policy) (time.Duration, error) {
+	if s.Redis != nil {
+		d, err := s.failRedis(ctx, key, p)
+		if err == nil {
+			return d, nil
+		}
+		zap.L().Warn("throttle: redis unavailable, using memory", zap.Error(err))
+	}
+	return s.failMemory(key, p, time.Now()), nil
+}
+
+// Reset forgets all failed attempts for key.
+func (s *Service) Reset(ctx context.Context, key string) error {
+	s.mu.Lock()
+	delete(s.memory, key)
+	s.mu.Unlock()
+	if s.Redis == nil {
+		return nil
+	}
+	conn, err := s.Redis.GetContext(ctx)
+	if err != nil {
+		return err
+	}
+	defer conn.Close()
+	_, err = conn.Do("DEL", redisKey(key))
+	return err
+}
+
+func (s *Service) checkRedis(ctx context.Context, key string) (time.Duration, error) {
+	conn, err := s.Redis.GetContext(ctx)
+	if err != nil {
+		return 0, err
+	}
+	defer conn.Close()
+	until, err := redis.Int64(conn.Do("HGET", redisKey(key), "until"))
+	if err == redis.ErrNil {
+		return 0, nil
+	}
+	if err != nil {
+		return 0, err
+	}
+	return remaining(time.Unix(0, until*int64(time.Millisecond)), time.Now()), nil
+}
+
+func (s *Service) failRedis(ctx context.Context, key string, p Policy) (time.Duration, error) {
+	conn, err := s.Redis.GetContext(ctx)
+	if err != nil {
+		return 0, err
+	}
+	defer conn.Close()
+	k := redisKey(key)
+	failures, err := redis.Int(conn.Do("HINCRBY", k, "failures", 1))
+	if err != nil {
+		return 0, err
+	}
+	lockout := p.lockout(failures)
+	ttl := p.Window
+	if lockout > ttl {
+		ttl = lockout
+	}
+	if lockout > 0 {
+		until := time.Now().Add(lockout).UnixNano() / int64(time.Millisecond)
+		if _, err := conn.Do("HSET", k, "until", until); err != nil {
+			return 0, err
+		}
+	}
+	if _, err := conn.Do("PEXPIRE", k, ttl.Milliseconds()); err != nil {
+		return 0, err
+	}
+	return lockout, nil
+}
+
+func (s *Service) checkMemory(key string, now time.Time) time.Duration {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	e, ok := s.memory[key]
+	if !ok || now.After(e.expires) {
+		return 0
+	}
+	return remaining(e.until, now)
+}
+
+// maxMemoryEntries is the number of in-memory entries above which expired
+// entries are swept.
+const maxMemoryEntries = 10000
+
+func (s *Service) failMemory(key string, p Policy, now time.Time) time.Duration {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.memory == nil {
+		s.memory = make(map[string]*entry)
+	}
+	if len(s.memory) > maxMemoryEntries {
+		for k, e := range s.memory {
+			if now.After(e.expires) {
+				delete(s.memory, k)
+			}
+		}
+	}
+	e, ok := s.memory[key]
+	if !ok || now.After(e.expires) {
+		e = &entry{}
+		s.memory[key] = e
+	}
+	e.failures++
+	lockout := p.lockout(e.failures)
+	ttl := p.Window
+	if lockout > ttl {
+		ttl = lockout
+	}
+	if lockout > 0 {
+		e.until = now.Add(lockout)
+	}
+	e.expires = now.Add(ttl)
+	return lockout
+}
+
+func remaining(until, now time.Time) time.Duration {
+	if d := until.Sub(now); d > 0 {
+		return d
+	}
+	return 0
+}
+
+func redisKey(key string) string {
+	return "throttle:" + key
+}
@@ -0,0 +1,88 @@
+package throttle_test
+
+import (
+	"context"
+	"flag"
+	"log"
+	"os"
+	"testing"
+	"time"
+
+	"github.com/deliveroo/assert-go"
+	"github.com/deliveroo/todo-api/selftest/deps/redis"
+	"github.com/deliveroo/todo-api/service/throttle"
+)
+
+func TestMain(m *testing.M) {
+	if flag.Parse(); testing.Short() {
+		return // skip in short mode
+	}
+
+	// Connect to Redis.
+	must(redis.Connect(), "could not connect to redis")
+
+	// Run tests.
+	result := m.Run()
+
+	// Reset the database.
+	must(redis.Reset(), "error resetting redis")
+
+	os.Exit(result)
+}
+
+func TestThrottle(t *testing.T) {
+	policy := throttle.Policy{
+		MaxAttempts: 2,
+		Lockout:     100 * time.Millisecond,
+		MaxLockout:  300 * time.Millisecond,
+		Window:      time.Minute,
+	}
+	for name, s := range map[string]*throttle.Service{
+		"redis":  {Redis: redis.Pool()},
+		"memory": {},
+	} {
+		t.Run(name, func(t *testing.T) {
+			var (
+				ctx = context.Background()
+				key = name + time.Now().Format(time.RFC3339Nano)
+			)
+			t.Run("allows attempts up to the limit", func(t *testing.T) {
+				d, err := s.Fail(ctx, key, policy)
+				assert.Must(t, err)
+				assert.Equal(t, d, time.Duration(0))
+				d, err = s.Check(ctx, key)
+				assert.Must(t, err)
+				assert.Equal(t, d, time.Duration(0))
+			})
+			t.Run("locks out with exponential backoff", func(t *testing.T) {
+				for _, want := range []time.Duration{100, 200, 300, 300} {
+					d, err := s.Fail(ctx, key, policy)
+					assert.Must(t, err)
+					assert.Equal(t, d, want*time.Millisecond)
+				}
+				d, err := s.Check(ctx, key)
+				assert.Must(t, err)
+				assert.True(t, d > 0)
+			})
+			t.Run("lockout expires", func(t *testing.T) {
+				time.Sleep(310 * time.Millisecond)
+				d, err := s.Check(ctx, key)
+				assert.Must(t, err)
+				assert.Equal(t, d, time.Duration(0))
+			})
+			t.Run("reset", func(t *testing.T) {
+				assert.Must(t, s.Reset(ctx, key))
+				d, err := s.Fail(ctx, key, policy)
+				assert.Must(t, err)
+				assert.Equal(t, d, time.Duration(0))
+			})
+		})
+	}
+}
+
+// must calls log.Fatal if the error is non-nil.
+func must(err error, msg string) {
+	if err != nil {
+		log.Fatalln(msg + ": " + err.Error())
+	}
+}
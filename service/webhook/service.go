@@ -0,0 +1,238 @@
+// Package webhook delivers task events to the webhooks accounts register.
+// Events are queued in Postgres, and a worker posts them, signed with each
+// webhook's secret, retrying failures with exponential backoff.
+package webhook
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net"
+	"net/http"
+	"net/url"
+	"syscall"
+	"time"
+
+	"github.com/deliveroo/todo-api/domain"
+	"github.com/deliveroo/todo-api/repo"
+	"go.uber.org/zap"
+)
+
+// Headers sent with each delivery, besides SignatureHeader.
+const (
+	DeliveryHeader = "X-Todo-Delivery"
+	EventHeader    = "X-Todo-Event"
+)
+
+// Service is the webhook delivery service.
+type Service struct {
+	Repo *repo.Client
+
+	// HTTPClient posts deliveries. Unless AllowPrivate is true, it should
+	// be made by NewHTTPClient, so that it refuses private addresses.
+	HTTPClient *http.Client
+
+	// AllowPrivate allows webhooks to be registered for private and reserved
+	// addresses, such as localhost, during development.
+	AllowPrivate bool
+
+	// MaxAttempts is how many times a delivery is attempted before it's
+	// given up on.
+	MaxAttempts int
+
+	// Backoff is the delay before the first retry. It doubles with each
+	// further attempt, up to MaxBackoff.
+	Backoff    time.Duration
+	MaxBackoff time.Duration
+
+	// PollInterval is how often Run checks for deliveries which are due.
+	PollInterval time.Duration
+
+	// Timeout limits how long an endpoint has to respond.
+	Timeout time.Duration
+}
+
+// batchSize limits how many deliveries a worker claims at once.
+const batchSize = 20
+
+// Run delivers queued events until ctx is cancelled.
+func (s *Service) Run(ctx context.Context) error {
+	ticker := time.NewTicker(s.PollInterval)
+	defer ticker.Stop()
+	for {
+		for {
+			n, err := s.DeliverDue(ctx)
+			if err != nil && ctx.Err() == nil {
+				zap.L().Error("webhook.Run", zap.Error(err))
+			}
+			if n < batchSize {
+				break // caught up
+			}
+		}
+		select {
+		case <-ticker.C:
+		case <-ctx.Done():
+			return nil
+		}
+	}
+}
+
+// DeliverDue attempts a batch of the deliveries which are due, and returns how
+// many were attempted.
+func (s *Service) DeliverDue(ctx context.Context) (int, error) {
+	// Claim deliveries for longer than attempting them can take, so they
+	// aren't attempted twice at once.
+	deliveries, err := s.Repo.ClaimWebhookDeliveries(ctx, batchSize, 2*s.Timeout+time.Minute)
+	if err != nil {
+		return 0, err
+	}
+	for _, d := range deliveries {
+		if _, err := s.Deliver(ctx, d); err != nil {
+			return 0, err
+		}
+	}
+	return len(deliveries), nil
+}
+
+// Deliver attempts a delivery, and records the outcome. If the attempt fails,
+// the delivery is retried later, unless it has been attempted too many times.
+func (s *Service) Deliver(ctx context.Context, d *domain.WebhookDelivery) (*domain.WebhookDelivery, error) {
+	w, err := s.Repo.GetWebhookByID(ctx, d.WebhookID)
+	if err != nil {
+		return nil, err
+	}
+	now := time.Now().UTC()
+	d.Attempts++
+	d.ResponseStatus = nil
+	if w == nil {
+		err = fmt.Errorf("webhook not found, id=%d", d.WebhookID)
+	} else {
+		var status int
+		status, err = s.Send(ctx, w, d)
+		if status != 0 {
+			d.ResponseStatus = &status
+		}
+	}
+	switch {
+	case err == nil:
+		d.Status = domain.DeliveryDelivered
+		d.Delivered = &now
+		d.LastError = ""
+	case w == nil || d.Attempts >= s.MaxAttempts:
+		d.Status = domain.DeliveryFailed
+		d.LastError = err.Error()
+	default:
+		d.NextAttempt = now.Add(s.RetryDelay(d.Attempts))
+		d.LastError = err.Error()
+	}
+	return s.Repo.UpdateWebhookDelivery(ctx, d)
+}
+
+// RetryDelay returns how long to wait before retrying a delivery which has
+// failed the given number of times.
+func (s *Service) RetryDelay(attempts int) time.Duration {
+	d := s.Backoff
+	for i := 1; i < attempts && d < s.MaxBackoff; i++ {
+		d *= 2
+	}
+	if s.MaxBackoff > 0 && d > s.MaxBackoff {
+		d = s.MaxBackoff
+	}
+	return d
+}
+
+// Send posts a delivery to a webhook once, without recording the outcome. It
+// returns the response status, or zero if there was no response, and an error
+// unless the status is 2xx.
+func (s *Service) Send(ctx context.Context, w *domain.Webhook, d *domain.WebhookDelivery) (int, error) {
+	if s.Timeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, s.Timeout)
+		defer cancel()
+	}
+	req, err := http.NewRequest(http.MethodPost, w.URL, bytes.NewReader(d.Payload))
+	if err != nil {
+		return 0, err
+	}
+	req = req.WithContext(ctx)
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("User-Agent", "todo-api-webhooks")
+	req.Header.Set(DeliveryHeader, d.PublicID)
+	req.Header.Set(EventHeader, d.Event)
+	req.Header.Set(SignatureHeader, Sign(w.Secret, time.Now(), d.Payload))
+	resp, err := s.httpClient().Do(req)
+	if err != nil {
+		return 0, err
+	}
+	defer resp.Body.Close()
+	_, _ = io.Copy(ioutil.Discard, io.LimitReader(resp.Body, 64<<10))
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		return resp.StatusCode, fmt.Errorf("unexpected response status %d", resp.StatusCode)
+	}
+	return resp.StatusCode, nil
+}
+
+// ValidateURL checks that uri may be registered as a webhook. Unless
+// AllowPrivate is true, its host must only resolve to public addresses. The
+// addresses are checked again when deliveries are sent, since they may have
+// changed since.
+func (s *Service) ValidateURL(ctx context.Context, uri string) error {
+	if err := domain.ValidateWebhookURL(uri, s.AllowPrivate); err != nil || s.AllowPrivate {
+		return err
+	}
+	u, _ := url.Parse(uri) // already validated
+	if net.ParseIP(u.Hostname()) != nil {
+		return nil
+	}
+	addrs, err := net.DefaultResolver.LookupIPAddr(ctx, u.Hostname())
+	if err != nil {
+		return fmt.Errorf("webhook URL %q has a host which can't be resolved", uri)
+	}
+	for _, addr := range addrs {
+		if !domain.PublicAddress(addr.IP) {
+			return fmt.Errorf("webhook URL %q must not refer to a private address", uri)
+		}
+	}
+	return nil
+}
+
+// NewHTTPClient returns a client for posting deliveries, which gives up after
+// timeout. Unless allowPrivate is true, it refuses to connect to private and
+// reserved addresses. They're checked once host names are resolved, so that a
+// host can't be pointed at one after its webhook is registered, and for each
+// redirect. Proxies aren't used, since they'd connect on the client's behalf.
+func NewHTTPClient(timeout time.Duration, allowPrivate bool) *http.Client {
+	dialer := &net.Dialer{
+		Timeout:   30 * time.Second,
+		KeepAlive: 30 * time.Second,
+	}
+	if !allowPrivate {
+		dialer.Control = refusePrivate
+	}
+	transport := http.DefaultTransport.(*http.Transport).Clone()
+	transport.Proxy = nil
+	transport.DialContext = dialer.DialContext
+	return &http.Client{Timeout: timeout, Transport: transport}
+}
+
+// refusePrivate is a net.Dialer Control function which refuses connections to
+// addresses webhooks may not be delivered to.
+func refusePrivate(network, address string, _ syscall.RawConn) error {
+	host, _, err := net.SplitHostPort(address)
+	if err != nil {
+		return err
+	}
+	if ip := net.ParseIP(host); ip == nil || !domain.PublicAddress(ip) {
+		return fmt.Errorf("webhook address %s is not public", host)
+	}
+	return nil
+}
+
+func (s *Service) httpClient() *http.Client {
+	if s.HTTPClient == nil {
+		return http.DefaultClient
+	}
+	return s.HTTPClient
+}
@@ -0,0 +1,61 @@
+package webhook
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// SignatureHeader is the header a delivery's signature is sent in.
+const SignatureHeader = "X-Todo-Signature"
+
+// Sign returns the signature header value for a payload sent at time t. It's
+// of the form "t=<unix time>,v1=<hex HMAC-SHA256>", where the HMAC is of the
+// time, a dot, and the payload, keyed with the webhook's secret. Signing the
+// time lets endpoints reject replayed deliveries.
+func Sign(secret string, t time.Time, payload []byte) string {
+	ts := strconv.FormatInt(t.Unix(), 10)
+	return "t=" + ts + ",v1=" + hex.EncodeToString(mac(secret, ts, payload))
+}
+
+// Verify checks a signature header value, as an endpoint would. Signatures
+// older than tolerance are rejected, unless tolerance is zero.
+func Verify(secret, header string, payload []byte, tolerance time.Duration) error {
+	var ts, sig string
+	for _, part := range strings.Split(header, ",") {
+		kv := strings.SplitN(part, "=", 2)
+		if len(kv) != 2 {
+			continue
+		}
+		switch kv[0] {
+		case "t":
+			ts = kv[1]
+		case "v1":
+			sig = kv[1]
+		}
+	}
+	unix, err := strconv.ParseInt(ts, 10, 64)
+	if err != nil || sig == "" {
+		return errors.New("malformed signature")
+	}
+	got, err := hex.DecodeString(sig)
+	if err != nil || !hmac.Equal(got, mac(secret, ts, payload)) {
+		return errors.New("signature mismatch")
+	}
+	if tolerance > 0 && time.Since(time.Unix(unix, 0)) > tolerance {
+		return errors.New("signature expired")
+	}
+	return nil
+}
+
+func mac(secret, ts string, payload []byte) []byte {
+	h := hmac.New(sha256.New, []byte(secret))
+	h.Write([]byte(ts))
+	h.Write([]byte("."))
+	h.Write(payload)
+	return h.Sum(nil)
+}
@@ -0,0 +1,93 @@
+package webhook_test
+
+import (
+	"context"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/deliveroo/assert-go"
+	"github.com/deliveroo/todo-api/domain"
+	"github.com/deliveroo/todo-api/service/webhook"
+)
+
+func TestSignature(t *testing.T) {
+	payload := []byte(`{"event":"ping"}`)
+	sig := webhook.Sign("secret", time.Now(), payload)
+	assert.Must(t, webhook.Verify("secret", sig, payload, time.Minute))
+	assert.NotNil(t, webhook.Verify("other", sig, payload, time.Minute))
+	assert.NotNil(t, webhook.Verify("secret", sig, []byte(`{"event":"pong"}`), time.Minute))
+	assert.NotNil(t, webhook.Verify("secret", "v1=abc", payload, time.Minute))
+
+	old := webhook.Sign("secret", time.Now().Add(-time.Hour), payload)
+	assert.NotNil(t, webhook.Verify("secret", old, payload, time.Minute))
+	assert.Must(t, webhook.Verify("secret", old, payload, 0))
+}
+
+func TestRetryDelay(t *testing.T) {
+	s := &webhook.Service{Backoff: time.Second, MaxBackoff: 10 * time.Second}
+	assert.Equal(t, s.RetryDelay(1), time.Second)
+	assert.Equal(t, s.RetryDelay(2), 2*time.Second)
+	assert.Equal(t, s.RetryDelay(4), 8*time.Second)
+	assert.Equal(t, s.RetryDelay(5), 10*time.Second)
+	assert.Equal(t, s.RetryDelay(50), 10*time.Second)
+}
+
+func TestSend(t *testing.T) {
+	var (
+		ctx    = context.Background()
+		w      = &domain.Webhook{Secret: "secret"}
+		d      = &domain.WebhookDelivery{PublicID: "01890a5d-ac96-774b-bcce-b302099a8057", Event: domain.EventPing, Payload: []byte(`{"event":"ping"}`)}
+		s      = &webhook.Service{Timeout: time.Second}
+		status = http.StatusNoContent
+	)
+	srv := httptest.NewServer(http.HandlerFunc(func(rw http.ResponseWriter, req *http.Request) {
+		body, _ := ioutil.ReadAll(req.Body)
+		assert.Equal(t, req.Header.Get(webhook.EventHeader), domain.EventPing)
+		assert.Equal(t, req.Header.Get(webhook.DeliveryHeader), d.PublicID)
+		assert.Must(t, webhook.Verify("secret", req.Header.Get(webhook.SignatureHeader), body, time.Minute))
+		rw.WriteHeader(status)
+	}))
+	defer srv.Close()
+	w.URL = srv.URL
+
+	got, err := s.Send(ctx, w, d)
+	assert.Must(t, err)
+	assert.Equal(t, got, http.StatusNoContent)
+
+	status = http.StatusInternalServerError
+	got, err = s.Send(ctx, w, d)
+	assert.NotNil(t, err)
+	assert.Equal(t, got, http.StatusInternalServerError)
+}
+
+func TestValidateURL(t *testing.T) {
+	ctx := context.Background()
+	s := &webhook.Service{}
+	assert.Must(t, s.ValidateURL(ctx, "https://93.184.216.34/hooks"))
+	assert.NotNil(t, s.ValidateURL(ctx, "https://169.254.169.254/latest/meta-data"))
+	assert.NotNil(t, s.ValidateURL(ctx, "http://localhost:8080/hooks"))
+
+	s.AllowPrivate = true
+	assert.Must(t, s.ValidateURL(ctx, "http://localhost:8080/hooks"))
+}
+
+func TestNewHTTPClient(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(rw http.ResponseWriter, req *http.Request) {
+		rw.WriteHeader(http.StatusNoContent)
+	}))
+	defer srv.Close()
+
+	// Private addresses are refused when connecting, whatever the URL's host
+	// resolves to.
+	_, err := webhook.NewHTTPClient(time.Second, false).Get(srv.URL)
+	assert.NotNil(t, err)
+	assert.Contains(t, err.Error(), "is not public")
+
+	resp, err := webhook.NewHTTPClient(time.Second, true).Get(srv.URL)
+	assert.Must(t, err)
+	resp.Body.Close()
+	assert.Equal(t, resp.StatusCode, http.StatusNoContent)
+}
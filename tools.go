@@ -1,3 +1,4 @@
+//go:build tools
 // +build tools
 
 // Package tools records build-time dependencies that aren't used by the
@@ -8,7 +9,6 @@ package tools
 import (
 	_ "github.com/cortesi/modd/cmd/modd"
 	_ "github.com/golangci/golangci-lint/cmd/golangci-lint"
-	_ "github.com/johngibb/migrate/cmd/migrate"
 	_ "github.com/joho/godotenv/cmd/godotenv"
 	_ "github.com/jstemmer/go-junit-report"
 )